@@ -0,0 +1,204 @@
+// Package apperr defines the errors handlers return to clients. Every error
+// carries a stable machine-readable code and an HTTP status; the cause is
+// kept for logging and never rendered.
+package apperr
+
+import (
+	"net/http"
+)
+
+// Code is a stable identifier clients can switch on. Codes are part of the
+// public API: add new ones freely, never rename or reuse existing ones.
+type Code string
+
+const (
+	CodeValidationFailed   Code = "VALIDATION_FAILED"
+	CodeUnauthorized       Code = "UNAUTHORIZED"
+	CodeForbidden          Code = "FORBIDDEN"
+	CodeInvalidCredentials Code = "INVALID_CREDENTIALS"
+	CodeInvalidResetToken  Code = "INVALID_RESET_TOKEN"
+	CodeAccountExists      Code = "ACCOUNT_EXISTS"
+	CodeRouteNotFound      Code = "ROUTE_NOT_FOUND"
+	CodeUserNotFound       Code = "USER_NOT_FOUND"
+	CodePostNotFound       Code = "POST_NOT_FOUND"
+	CodeCommentNotFound    Code = "COMMENT_NOT_FOUND"
+	CodeAnimalNotFound     Code = "ANIMAL_NOT_FOUND"
+	CodeOrderNotFound      Code = "ORDER_NOT_FOUND"
+	CodeVetNotFound        Code = "VETERINARIAN_NOT_FOUND"
+	CodeConsultNotFound    Code = "CONSULTATION_NOT_FOUND"
+	CodeInsufficientStock  Code = "INSUFFICIENT_STOCK"
+	CodeAnimalUnavailable  Code = "ANIMAL_UNAVAILABLE"
+	CodeInvalidTransition  Code = "INVALID_ORDER_TRANSITION"
+	CodeCartItemNotFound   Code = "CART_ITEM_NOT_FOUND"
+	CodeCartEmpty          Code = "CART_EMPTY"
+	CodeCartChanged        Code = "CART_CHANGED"
+	CodeOrderNotPayable    Code = "ORDER_NOT_PAYABLE"
+	CodePaymentPending     Code = "PAYMENT_PENDING"
+	CodePaymentNotFound    Code = "PAYMENT_NOT_FOUND"
+	CodeInvalidSignature   Code = "INVALID_SIGNATURE"
+	CodePaymentGateway     Code = "PAYMENT_GATEWAY_ERROR"
+	CodePaymentsDisabled   Code = "PAYMENTS_DISABLED"
+	CodeVoucherNotFound    Code = "VOUCHER_NOT_FOUND"
+	CodeVoucherInvalid     Code = "VOUCHER_NOT_APPLICABLE"
+	CodeVoucherExists      Code = "VOUCHER_CODE_TAKEN"
+	CodeVoucherClaimed     Code = "VOUCHER_ALREADY_CLAIMED"
+	CodeAddressNotFound    Code = "ADDRESS_NOT_FOUND"
+	CodeShipmentNotFound   Code = "SHIPMENT_NOT_FOUND"
+	CodeNoShipping         Code = "SHIPPING_UNAVAILABLE"
+	CodeCarrier            Code = "CARRIER_ERROR"
+	CodeShopNotFound       Code = "SHOP_NOT_FOUND"
+	CodeShopExists         Code = "SHOP_ALREADY_EXISTS"
+	CodeShopSlugTaken      Code = "SHOP_SLUG_TAKEN"
+	CodeNotReviewable      Code = "ORDER_NOT_REVIEWABLE"
+	CodeReviewExists       Code = "REVIEW_ALREADY_EXISTS"
+	CodeReviewNotFound     Code = "REVIEW_NOT_FOUND"
+	CodeReviewReported     Code = "REVIEW_ALREADY_REPORTED"
+	CodeReportNotFound     Code = "REPORT_NOT_FOUND"
+	CodeReportResolved     Code = "REPORT_ALREADY_RESOLVED"
+	CodeMediaNotFound      Code = "MEDIA_NOT_FOUND"
+	CodeProductNotFound    Code = "PRODUCT_NOT_FOUND"
+	CodeVariantNotFound    Code = "VARIANT_NOT_FOUND"
+	CodeProductUnavailable Code = "PRODUCT_UNAVAILABLE"
+	CodeProductSKUTaken    Code = "PRODUCT_SKU_TAKEN"
+	CodeCategoryNotFound   Code = "CATEGORY_NOT_FOUND"
+	CodeCategoryExists     Code = "CATEGORY_NAME_TAKEN"
+	CodeCategoryInUse      Code = "CATEGORY_HAS_SUBCATEGORIES"
+	CodeFileTooLarge       Code = "FILE_TOO_LARGE"
+	CodeUnsupportedMedia   Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodeIdempotencyReused  Code = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyPending Code = "IDEMPOTENCY_IN_PROGRESS"
+	CodeInternal           Code = "INTERNAL_ERROR"
+)
+
+// FieldError describes a single invalid input field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error that can be rendered to a client.
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	Details []FieldError
+	cause   error
+}
+
+// New returns an error with the given status, code and client-facing message.
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.cause.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error { return e.cause }
+
+// Is reports whether target is an *Error with the same code, so the
+// predefined values below can be used with errors.Is.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e that records cause for logging.
+func (e *Error) Wrap(cause error) *Error {
+	c := *e
+	c.cause = cause
+	return &c
+}
+
+// WithMessage returns a copy of e with a different client-facing message.
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+// Predefined errors for the common cases.
+var (
+	ErrUnauthorized       = New(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+	ErrInvalidCredentials = New(http.StatusUnauthorized, CodeInvalidCredentials, "Invalid email or password")
+	ErrInvalidResetToken  = New(http.StatusBadRequest, CodeInvalidResetToken, "Invalid or expired token")
+	ErrAccountExists      = New(http.StatusBadRequest, CodeAccountExists, "Email or username already exists")
+	ErrRouteNotFound      = New(http.StatusNotFound, CodeRouteNotFound, "Resource not found")
+	ErrUserNotFound       = New(http.StatusNotFound, CodeUserNotFound, "User not found")
+	ErrPostNotFound       = New(http.StatusNotFound, CodePostNotFound, "Post not found")
+	ErrCommentNotFound    = New(http.StatusNotFound, CodeCommentNotFound, "Comment not found")
+	ErrAnimalNotFound     = New(http.StatusNotFound, CodeAnimalNotFound, "Animal not found")
+	ErrOrderNotFound      = New(http.StatusNotFound, CodeOrderNotFound, "Order not found")
+	ErrVetNotFound        = New(http.StatusNotFound, CodeVetNotFound, "Veterinarian not found")
+	ErrConsultNotFound    = New(http.StatusNotFound, CodeConsultNotFound, "Consultation not found")
+	ErrInsufficientStock  = New(http.StatusBadRequest, CodeInsufficientStock, "Insufficient stock")
+	ErrAnimalUnavailable  = New(http.StatusConflict, CodeAnimalUnavailable, "Animal is not available for sale")
+	ErrNotListingOwner    = New(http.StatusForbidden, CodeForbidden, "You can only manage your own listings")
+	ErrInvalidTransition  = New(http.StatusConflict, CodeInvalidTransition, "The order cannot move to that status from its current one")
+	ErrOrderForbidden     = New(http.StatusForbidden, CodeForbidden, "Only the other party can make this change to the order")
+	ErrCartItemNotFound   = New(http.StatusNotFound, CodeCartItemNotFound, "Animal is not in your cart")
+	ErrCartEmpty          = New(http.StatusBadRequest, CodeCartEmpty, "Your cart is empty")
+	ErrCartChanged        = New(http.StatusConflict, CodeCartChanged, "Some items in your cart changed; review your cart and check out again")
+	ErrOrderNotPayable    = New(http.StatusConflict, CodeOrderNotPayable, "Only pending orders can be paid, and a checkout is paid as a whole")
+	ErrPaymentPending     = New(http.StatusConflict, CodePaymentPending, "A payment for this order is already waiting to be completed")
+	ErrPaymentNotFound    = New(http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+	ErrInvalidSignature   = New(http.StatusUnauthorized, CodeInvalidSignature, "Webhook signature is invalid")
+	ErrPaymentGateway     = New(http.StatusBadGateway, CodePaymentGateway, "The payment gateway is unavailable; try again")
+	ErrPaymentsDisabled   = New(http.StatusServiceUnavailable, CodePaymentsDisabled, "Payments are not available on this server")
+	ErrVoucherNotFound    = New(http.StatusNotFound, CodeVoucherNotFound, "Voucher not found")
+	ErrVoucherInvalid     = New(http.StatusBadRequest, CodeVoucherInvalid, "The voucher cannot be used on this cart")
+	ErrVoucherExists      = New(http.StatusConflict, CodeVoucherExists, "A voucher with this code already exists")
+	ErrVoucherClaimed     = New(http.StatusConflict, CodeVoucherClaimed, "You have already claimed this voucher")
+	ErrAddressNotFound    = New(http.StatusNotFound, CodeAddressNotFound, "Address not found")
+	ErrShipmentNotFound   = New(http.StatusNotFound, CodeShipmentNotFound, "This order has no delivery booked")
+	ErrNoShipping         = New(http.StatusUnprocessableEntity, CodeNoShipping, "No courier delivers between these cities")
+	ErrCarrier            = New(http.StatusBadGateway, CodeCarrier, "The courier is unavailable; try again")
+	ErrShopNotFound       = New(http.StatusNotFound, CodeShopNotFound, "Shop not found")
+	ErrShopExists         = New(http.StatusConflict, CodeShopExists, "You already have a shop")
+	ErrShopSlugTaken      = New(http.StatusConflict, CodeShopSlugTaken, "Another shop already uses this address")
+	ErrNotReviewable      = New(http.StatusConflict, CodeNotReviewable, "Orders can be reviewed once they are completed")
+	ErrReviewExists       = New(http.StatusConflict, CodeReviewExists, "You have already reviewed this item on this order")
+	ErrReviewNotFound     = New(http.StatusNotFound, CodeReviewNotFound, "Review not found")
+	ErrNotReviewSeller    = New(http.StatusForbidden, CodeForbidden, "Only the seller of the listing can reply to its reviews")
+	ErrOwnReview          = New(http.StatusForbidden, CodeForbidden, "You cannot mark your own review helpful")
+	ErrReviewReported     = New(http.StatusConflict, CodeReviewReported, "You have already reported this review")
+	ErrReportNotFound     = New(http.StatusNotFound, CodeReportNotFound, "Report not found")
+	ErrReportResolved     = New(http.StatusConflict, CodeReportResolved, "The report has already been resolved")
+	ErrNotModerator       = New(http.StatusForbidden, CodeForbidden, "Only moderators can do this")
+	ErrMediaNotFound      = New(http.StatusNotFound, CodeMediaNotFound, "Media not found")
+	ErrMediaLinkInvalid   = New(http.StatusForbidden, CodeForbidden, "Media link is invalid or has expired")
+	ErrProductNotFound    = New(http.StatusNotFound, CodeProductNotFound, "Product not found")
+	ErrVariantNotFound    = New(http.StatusNotFound, CodeVariantNotFound, "Product variant not found")
+	ErrProductUnavailable = New(http.StatusConflict, CodeProductUnavailable, "Product is not available for sale")
+	ErrVariantNotInCart   = New(http.StatusNotFound, CodeCartItemNotFound, "Product variant is not in your cart")
+	ErrProductSKUTaken    = New(http.StatusConflict, CodeProductSKUTaken, "You already have a product with this SKU")
+	ErrCategoryNotFound   = New(http.StatusNotFound, CodeCategoryNotFound, "Category not found")
+	ErrCategoryExists     = New(http.StatusConflict, CodeCategoryExists, "Another category already has this name")
+	ErrCategoryInUse      = New(http.StatusConflict, CodeCategoryInUse, "Remove or move the category's subcategories first")
+	ErrFileTooLarge       = New(http.StatusRequestEntityTooLarge, CodeFileTooLarge, "File is too large")
+	ErrUnsupportedMedia   = New(http.StatusUnsupportedMediaType, CodeUnsupportedMedia, "Only JPEG, PNG and GIF images are accepted")
+
+	ErrIdempotencyKeyReused  = New(http.StatusUnprocessableEntity, CodeIdempotencyReused, "Idempotency-Key was already used with a different request")
+	ErrIdempotencyInProgress = New(http.StatusConflict, CodeIdempotencyPending, "A request with this Idempotency-Key is still being processed")
+)
+
+// Internal wraps an unexpected error. message is shown to the client; cause
+// is only logged.
+func Internal(message string, cause error) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, message).Wrap(cause)
+}
+
+// Invalid reports a single invalid field.
+func Invalid(field, message string) *Error {
+	return Fields(FieldError{Field: field, Message: message})
+}
+
+// Fields reports one or more invalid fields.
+func Fields(details ...FieldError) *Error {
+	e := New(http.StatusBadRequest, CodeValidationFailed, "Invalid request")
+	e.Details = details
+	return e
+}
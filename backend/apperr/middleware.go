@@ -0,0 +1,62 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware renders the last error attached with c.Error once the handler
+// chain returns. *Error values are rendered as-is; anything else, including
+// panics, is logged and reported as INTERNAL_ERROR without exposing its
+// text. It must run before any middleware that reports errors.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				Render(c, Internal("Internal server error", fmt.Errorf("panic: %v", r)))
+			}
+		}()
+
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		Render(c, c.Errors.Last().Err)
+	}
+}
+
+// Render writes err using the standard envelope.
+func Render(c *gin.Context, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = Internal("Internal server error", err)
+	}
+	if e.Status >= http.StatusInternalServerError {
+		log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	}
+
+	resp := utils.ErrorResponse(e.Message, "")
+	resp.Code = string(e.Code)
+	if len(e.Details) > 0 {
+		resp.Details = e.Details
+	}
+	c.AbortWithStatusJSON(e.Status, resp)
+}
+
+// Abort attaches err to the context and stops the chain. Middleware uses it
+// in place of writing a response directly.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// NoRoute is the engine's 404 handler.
+func NoRoute(c *gin.Context) {
+	Render(c, ErrRouteNotFound)
+}
@@ -1,11 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
 
+	"github.com/TerraPaw/backend/config"
 	"github.com/TerraPaw/backend/db"
+	"github.com/TerraPaw/backend/geo"
+	"github.com/TerraPaw/backend/jobs"
+	"github.com/TerraPaw/backend/notify"
+	"github.com/TerraPaw/backend/payments"
 	"github.com/TerraPaw/backend/routes"
+	"github.com/TerraPaw/backend/shipping"
+	"github.com/TerraPaw/backend/storage"
+	"github.com/TerraPaw/backend/store"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
@@ -16,29 +27,84 @@ func main() {
 
 	// Initialize database
 	db.InitDB()
+	store.Default = store.NewPostgres(db.DB)
 
+	// Initialize upload storage
+	blob, signer, err := storage.Open(config.LoadConfig())
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	storage.Default, storage.DefaultSigner = blob, signer
+
+	// Initialize the payment gateway, and the local stand-in for the fake one
+	cfg := config.LoadConfig()
+	gateway, err := payments.Open(cfg)
+	switch {
+	case errors.Is(err, payments.ErrDisabled):
+		log.Printf("Payments disabled: %v", err)
+	case err != nil:
+		log.Fatalf("Failed to open payment gateway: %v", err)
+	}
+	payments.Default = gateway
+	if gateway != nil && cfg.PaymentProvider == "fake" {
+		go func() {
+			fake := payments.NewFakeGateway([]byte(cfg.PaymentSecret), cfg.PaymentWebhookURL)
+			log.Printf("Fake payment gateway on %s", cfg.PaymentFakeAddr)
+			if err := http.ListenAndServe(cfg.PaymentFakeAddr, fake); err != nil {
+				log.Printf("Fake payment gateway stopped: %v", err)
+			}
+		}()
+	}
+
+	// Initialize the shipping carrier
+	carrier, err := shipping.Open(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open shipping carrier: %v", err)
+	}
+	shipping.Default = carrier
+
+	// Initialize email and push delivery
+	sender, err := notify.Open(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open notification sender: %v", err)
+	}
+	notify.Default = sender
+
+	// Initialize the geocoder
+	geocoder, err := geo.Open(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open geocoder: %v", err)
+	}
+	geo.Default = geocoder
+
+	// Ensure Categories exist, before anything is filed under them
+	db.SeedCategories()
+	// Move food listed as animals into the product catalog
+	db.MoveFoodListings()
 	// Patch Dummy Data (4000 records)
 	db.PatchLargeData()
 	// Ensure Food Data exists (if skipped by PatchLargeData)
 	db.EnsureFoodData()
-	// Ensure Categories exist
-	db.SeedCategories()
+	// Ensure platform vouchers exist
+	db.SeedVouchers()
 
 	// Seed data if empty
 	db.SeedData()
+	// Ratings come from reviews, not from the seeders
+	db.SyncRatings()
+
+	// Background jobs
+	go jobs.Every(context.Background(), "expire-unpaid-orders", cfg.PaymentJobInterval, jobs.ExpireUnpaidOrders(cfg.PaymentWindow))
+	go jobs.Every(context.Background(), "send-refunds", cfg.PaymentJobInterval, jobs.SendRefunds)
+	go jobs.Every(context.Background(), "track-shipments", cfg.ShippingJobInterval, jobs.TrackShipments)
+	go jobs.Every(context.Background(), "refresh-analytics", cfg.AnalyticsJobInterval, jobs.RefreshAnalytics)
+	go jobs.Every(context.Background(), "wishlist-alerts", cfg.AlertJobInterval, jobs.SendWishlistAlerts)
+	go jobs.Every(context.Background(), "recommendations", cfg.RecommendationJobInterval, jobs.RecomputeRecommendations)
+	go jobs.Every(context.Background(), "locate-listings", cfg.GeocodeJobInterval, jobs.LocateListings)
 
 	// Create Gin router
 	router := gin.Default()
 
-	// 404 Handler
-	router.NoRoute(func(c *gin.Context) {
-		c.JSON(404, gin.H{
-			"status":  "error",
-			"message": "Resource not found",
-			"code":    404,
-		})
-	})
-
 	// Add middleware
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
@@ -47,8 +113,8 @@ func main() {
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -1,8 +1,20 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultPaymentSecret is the webhook secret used when PAYMENT_SECRET is
+// unset. It is public, so it is only accepted in development.
+const DefaultPaymentSecret = "fake-payment-secret"
 
 type Config struct {
+	// Dev is set when APP_ENV is "development". Stand-ins that must not
+	// take real traffic, such as the fake payment gateway, only run then.
+	Dev bool
+
 	DBHost     string
 	DBPort     string
 	DBUser     string
@@ -10,10 +22,63 @@ type Config struct {
 	DBName     string
 	JWTSecret  string
 	ServerPort string
+
+	// ValidateRequests enables the OpenAPI request validator middleware.
+	ValidateRequests bool
+
+	// Uploads (see package storage). StorageDriver is "local", which keeps
+	// files under StorageDir, or "s3" for an S3-compatible service.
+	StorageDriver  string
+	StorageDir     string
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	MediaSecret    string        // signs media links; defaults to JWTSecret
+	MediaURLTTL    time.Duration // how long a media link stays valid
+	MaxUploadBytes int64
+
+	// Payments (see package payments). PaymentProvider "fake" talks to the
+	// local stand-in gateway at PaymentFakeURL, which main starts on
+	// PaymentFakeAddr; it and the default PaymentSecret need Dev. Orders
+	// not paid within PaymentWindow are cancelled by a job that runs every
+	// PaymentJobInterval.
+	PaymentProvider    string
+	PaymentSecret      string // verifies webhook signatures
+	PaymentFakeURL     string
+	PaymentFakeAddr    string
+	PaymentWebhookURL  string // where the fake gateway delivers webhooks
+	PaymentWindow      time.Duration
+	PaymentJobInterval time.Duration
+
+	// Shipping (see package shipping). Shipments with a label are tracked
+	// by a job that runs every ShippingJobInterval.
+	ShippingCarrier     string
+	ShippingJobInterval time.Duration
+
+	// Seller analytics rollups are rebuilt every AnalyticsJobInterval.
+	AnalyticsJobInterval time.Duration
+
+	// Wishlist alerts are matched every AlertJobInterval and sent by email
+	// or push through NotifyDriver (see package notify).
+	AlertJobInterval time.Duration
+	NotifyDriver     string
+
+	// Similar listings are recomputed every RecommendationJobInterval.
+	RecommendationJobInterval time.Duration
+
+	// Listing and user locations are geocoded through GeocoderDriver (see
+	// package geo). Listings without coordinates are retried every
+	// GeocodeJobInterval.
+	GeocoderDriver     string
+	GeocodeJobInterval time.Duration
 }
 
 func LoadConfig() *Config {
 	return &Config{
+		Dev: os.Getenv("APP_ENV") == "development",
+
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "postgres"),
@@ -21,6 +86,40 @@ func LoadConfig() *Config {
 		DBName:     getEnv("DB_NAME", "terrapaw"),
 		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
 		ServerPort: getEnv("PORT", "8080"),
+
+		ValidateRequests: getEnv("OPENAPI_VALIDATE", "false") == "true",
+
+		StorageDriver:  getEnv("STORAGE_DRIVER", "local"),
+		StorageDir:     getEnv("STORAGE_DIR", "./uploads"),
+		S3Endpoint:     getEnv("S3_ENDPOINT", "http://localhost:9000"),
+		S3Region:       getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:       getEnv("S3_BUCKET", "terrapaw"),
+		S3AccessKey:    os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:    os.Getenv("S3_SECRET_KEY"),
+		MediaSecret:    getEnv("MEDIA_SECRET", getEnv("JWT_SECRET", "your-secret-key")),
+		MediaURLTTL:    getDuration("MEDIA_URL_TTL", time.Hour),
+		MaxUploadBytes: int64(getInt("MAX_UPLOAD_BYTES", 10<<20)),
+
+		PaymentProvider:    getEnv("PAYMENT_PROVIDER", "fake"),
+		PaymentSecret:      getEnv("PAYMENT_SECRET", DefaultPaymentSecret),
+		PaymentFakeURL:     getEnv("PAYMENT_FAKE_URL", "http://localhost:8091"),
+		PaymentFakeAddr:    getEnv("PAYMENT_FAKE_ADDR", "localhost:8091"),
+		PaymentWebhookURL:  getEnv("PAYMENT_WEBHOOK_URL", "http://localhost:"+getEnv("PORT", "8080")+"/api/payments/webhooks/fake"),
+		PaymentWindow:      getDuration("PAYMENT_WINDOW", 24*time.Hour),
+		PaymentJobInterval: getDuration("PAYMENT_JOB_INTERVAL", time.Minute),
+
+		ShippingCarrier:     getEnv("SHIPPING_CARRIER", "fake"),
+		ShippingJobInterval: getDuration("SHIPPING_JOB_INTERVAL", 5*time.Minute),
+
+		AnalyticsJobInterval: getDuration("ANALYTICS_JOB_INTERVAL", 15*time.Minute),
+
+		AlertJobInterval: getDuration("ALERT_JOB_INTERVAL", 10*time.Minute),
+		NotifyDriver:     getEnv("NOTIFY_DRIVER", "log"),
+
+		RecommendationJobInterval: getDuration("RECOMMENDATION_JOB_INTERVAL", time.Hour),
+
+		GeocoderDriver:     getEnv("GEOCODER", "offline"),
+		GeocodeJobInterval: getDuration("GEOCODE_JOB_INTERVAL", time.Hour),
 	}
 }
 
@@ -31,3 +130,17 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getInt(key string, defaultValue int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return n
+	}
+	return defaultValue
+}
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return defaultValue
+}
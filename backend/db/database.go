@@ -29,10 +29,14 @@ func InitDB() {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
 
-	DB = db
 	log.Println("Database connected successfully")
+	Migrate(db)
+}
 
-	// Create tables
+// Migrate makes conn the package's connection and brings its schema up to
+// date, as InitDB does on start. Store tests use it on a scratch database.
+func Migrate(conn *sql.DB) {
+	DB = conn
 	createTables()
 }
 
@@ -142,7 +146,7 @@ func createTables() {
 		status VARCHAR(50) DEFAULT 'available',
         color VARCHAR(50),
         gender VARCHAR(20),
-        stock INTEGER DEFAULT 1,
+        stock INTEGER DEFAULT 1 CHECK (stock >= 0),
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);`
@@ -312,6 +316,365 @@ func createTables() {
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);`
 
+	// Listing price edits, newest last (see store.UpdateAnimal)
+	createAnimalPriceHistoryTable := `
+	CREATE TABLE IF NOT EXISTS animal_price_history (
+		id SERIAL PRIMARY KEY,
+		animal_id INTEGER NOT NULL REFERENCES animals(id) ON DELETE CASCADE,
+		old_price DECIMAL(10, 2),
+		new_price DECIMAL(10, 2),
+		changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Carts: one per buyer, priced at what the buyer last saw (see store.CartStore)
+	createCartsTable := `
+	CREATE TABLE IF NOT EXISTS carts (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	createCartItemsTable := `
+	CREATE TABLE IF NOT EXISTS cart_items (
+		id SERIAL PRIMARY KEY,
+		cart_id INTEGER NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+		animal_id INTEGER NOT NULL REFERENCES animals(id) ON DELETE CASCADE,
+		quantity INTEGER NOT NULL CHECK (quantity > 0),
+		price DECIMAL(10, 2) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (cart_id, animal_id)
+	);`
+
+	// Order lines. Checkout sub-orders hold one line per listing or
+	// product variant; single-item orders hold exactly one.
+	createOrderItemsTable := `
+	CREATE TABLE IF NOT EXISTS order_items (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		animal_id INTEGER NOT NULL REFERENCES animals(id) ON DELETE CASCADE,
+		seller_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		quantity INTEGER NOT NULL CHECK (quantity > 0),
+		unit_price DECIMAL(10, 2) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Order lifecycle, one row per status change (see store.OrderStore).
+	// changed_by is NULL for system changes.
+	createOrderStatusHistoryTable := `
+	CREATE TABLE IF NOT EXISTS order_status_history (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		from_status VARCHAR(50) NOT NULL,
+		to_status VARCHAR(50) NOT NULL,
+		changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		role VARCHAR(20) NOT NULL,
+		note TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Gateway payments (see package payments). reference is what the
+	// gateway knows a payment by; charge_id is the gateway's own id.
+	createPaymentsTable := `
+	CREATE TABLE IF NOT EXISTS payments (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		provider VARCHAR(50) NOT NULL,
+		reference VARCHAR(100) UNIQUE,
+		charge_id VARCHAR(255),
+		method VARCHAR(50) NOT NULL,
+		bank VARCHAR(50),
+		va_number VARCHAR(50),
+		qr_string TEXT,
+		amount DECIMAL(12, 2) NOT NULL,
+		refunded_amount DECIMAL(12, 2) NOT NULL DEFAULT 0,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		expires_at TIMESTAMP,
+		paid_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Webhook events already applied, so redeliveries are ignored
+	createPaymentEventsTable := `
+	CREATE TABLE IF NOT EXISTS payment_events (
+		id SERIAL PRIMARY KEY,
+		provider VARCHAR(50) NOT NULL,
+		event_id VARCHAR(255) NOT NULL,
+		charge_id VARCHAR(255) NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		amount DECIMAL(12, 2),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (provider, event_id)
+	);`
+
+	// Refunds owed on paid payments, sent to the gateway by the payments job
+	createPaymentRefundsTable := `
+	CREATE TABLE IF NOT EXISTS payment_refunds (
+		id SERIAL PRIMARY KEY,
+		payment_id INTEGER NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		amount DECIMAL(12, 2) NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		refunded_at TIMESTAMP
+	);`
+
+	// Discount vouchers (see store.VoucherStore). seller_id limits a
+	// voucher to one seller's listings; platform vouchers have none.
+	createVouchersTable := `
+	CREATE TABLE IF NOT EXISTS vouchers (
+		id SERIAL PRIMARY KEY,
+		code VARCHAR(50) NOT NULL UNIQUE,
+		description TEXT,
+		discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percentage', 'fixed')),
+		value DECIMAL(12, 2) NOT NULL CHECK (value > 0),
+		max_discount DECIMAL(12, 2),
+		min_spend DECIMAL(12, 2) NOT NULL DEFAULT 0,
+		usage_limit INTEGER,
+		per_user_limit INTEGER NOT NULL DEFAULT 1,
+		used_count INTEGER NOT NULL DEFAULT 0,
+		seller_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		category_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+		public BOOLEAN NOT NULL DEFAULT FALSE,
+		starts_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		ends_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Vouchers users have saved to their wallet
+	createUserVouchersTable := `
+	CREATE TABLE IF NOT EXISTS user_vouchers (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		voucher_id INTEGER NOT NULL REFERENCES vouchers(id) ON DELETE CASCADE,
+		claimed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, voucher_id)
+	);`
+
+	// One row per checkout a voucher was used on; deleted when the
+	// checkout is cancelled
+	createVoucherRedemptionsTable := `
+	CREATE TABLE IF NOT EXISTS voucher_redemptions (
+		id SERIAL PRIMARY KEY,
+		voucher_id INTEGER NOT NULL REFERENCES vouchers(id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		order_id INTEGER NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Buyers' address books. The partial unique index below keeps one
+	// default per user.
+	createAddressesTable := `
+	CREATE TABLE IF NOT EXISTS addresses (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		label VARCHAR(50) NOT NULL,
+		recipient_name VARCHAR(100) NOT NULL,
+		phone VARCHAR(20) NOT NULL,
+		street TEXT NOT NULL,
+		city VARCHAR(100) NOT NULL,
+		province VARCHAR(100),
+		postal_code VARCHAR(10) NOT NULL,
+		is_default BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Deliveries of sub-orders (see store.ShippingStore). The address is
+	// copied so later address book edits leave booked shipments alone.
+	createShipmentsTable := `
+	CREATE TABLE IF NOT EXISTS shipments (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+		carrier VARCHAR(50) NOT NULL,
+		service VARCHAR(50) NOT NULL,
+		live_animals BOOLEAN NOT NULL DEFAULT FALSE,
+		cost DECIMAL(12, 2) NOT NULL,
+		min_days INTEGER NOT NULL,
+		max_days INTEGER NOT NULL,
+		origin VARCHAR(100) NOT NULL,
+		recipient_name VARCHAR(100) NOT NULL,
+		phone VARCHAR(20) NOT NULL,
+		address TEXT NOT NULL,
+		city VARCHAR(100) NOT NULL,
+		postal_code VARCHAR(10) NOT NULL,
+		tracking_number VARCHAR(100),
+		status VARCHAR(30) NOT NULL DEFAULT 'pending',
+		shipped_at TIMESTAMP,
+		delivered_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Carrier tracking scans, one per status
+	createShipmentEventsTable := `
+	CREATE TABLE IF NOT EXISTS shipment_events (
+		id SERIAL PRIMARY KEY,
+		shipment_id INTEGER NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+		status VARCHAR(30) NOT NULL,
+		description TEXT NOT NULL,
+		location VARCHAR(100),
+		occurred_at TIMESTAMP NOT NULL,
+		UNIQUE (shipment_id, status)
+	);`
+
+	// Listing detail views per day (see store.RecordAnimalView)
+	createAnimalViewsTable := `
+	CREATE TABLE IF NOT EXISTS animal_views (
+		animal_id INTEGER NOT NULL REFERENCES animals(id) ON DELETE CASCADE,
+		day DATE NOT NULL,
+		views INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (animal_id, day)
+	);`
+
+	// When the analytics rollups were last refreshed; at most one row
+	createAnalyticsRefreshesTable := `
+	CREATE TABLE IF NOT EXISTS analytics_refreshes (
+		id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+		refreshed_at TIMESTAMP NOT NULL
+	);`
+
+	// Sellers' storefronts (see store.ShopStore); one per seller.
+	// opening_hours is a JSON list of models.OpeningHours.
+	createShopsTable := `
+	CREATE TABLE IF NOT EXISTS shops (
+		id SERIAL PRIMARY KEY,
+		seller_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+		slug VARCHAR(50) NOT NULL UNIQUE,
+		name VARCHAR(100) NOT NULL,
+		description TEXT,
+		logo_url VARCHAR(500),
+		banner_url VARCHAR(500),
+		address TEXT,
+		city VARCHAR(100),
+		phone VARCHAR(20),
+		opening_hours JSONB NOT NULL DEFAULT '[]',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	createShopFollowsTable := `
+	CREATE TABLE IF NOT EXISTS shop_follows (
+		shop_id INTEGER NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (shop_id, user_id)
+	);`
+
+	// Each seller's rating across all of their listings, kept current by
+	// store.CreateReview
+	createSellerRatingsTable := `
+	CREATE TABLE IF NOT EXISTS seller_ratings (
+		seller_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		rating DECIMAL(3, 2) NOT NULL DEFAULT 0,
+		review_count INTEGER NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Review interactions: the seller's reply, helpful votes and abuse
+	// reports waiting for moderators (see store.ReviewStore)
+	createReviewRepliesTable := `
+	CREATE TABLE IF NOT EXISTS review_replies (
+		review_id INTEGER PRIMARY KEY REFERENCES reviews(id) ON DELETE CASCADE,
+		seller_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		body TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	createReviewVotesTable := `
+	CREATE TABLE IF NOT EXISTS review_votes (
+		review_id INTEGER NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (review_id, user_id)
+	);`
+
+	// Which wishlist alerts each user wants and where (see
+	// store.AlertStore); users without a row get the defaults
+	createAlertPreferencesTable := `
+	CREATE TABLE IF NOT EXISTS alert_preferences (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		price_drop BOOLEAN NOT NULL DEFAULT TRUE,
+		back_in_stock BOOLEAN NOT NULL DEFAULT TRUE,
+		min_drop_percent INTEGER NOT NULL DEFAULT 0 CHECK (min_drop_percent BETWEEN 0 AND 90),
+		email BOOLEAN NOT NULL DEFAULT FALSE,
+		push BOOLEAN NOT NULL DEFAULT FALSE,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Similar listings, recomputed in batch by the recommendation job (see
+	// package recommend); reasons is a comma-separated list
+	createListingSimilaritiesTable := `
+	CREATE TABLE IF NOT EXISTS listing_similarities (
+		animal_id INTEGER NOT NULL REFERENCES animals(id) ON DELETE CASCADE,
+		similar_id INTEGER NOT NULL REFERENCES animals(id) ON DELETE CASCADE,
+		score DOUBLE PRECISION NOT NULL,
+		reasons TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (animal_id, similar_id)
+	);`
+
+	createReviewReportsTable := `
+	CREATE TABLE IF NOT EXISTS review_reports (
+		id SERIAL PRIMARY KEY,
+		review_id INTEGER NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+		reporter_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		reason VARCHAR(30) NOT NULL,
+		details TEXT,
+		status VARCHAR(20) NOT NULL DEFAULT 'open',
+		resolved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		resolved_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (review_id, reporter_id)
+	);`
+
+	// Pet supplies, sold in variants with their own SKU, price, weight and
+	// stock (see store.ProductStore). legacy_animal_id is the listing a
+	// product was moved from by MoveFoodListings.
+	createProductsTable := `
+	CREATE TABLE IF NOT EXISTS products (
+		id SERIAL PRIMARY KEY,
+		seller_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		category_id INTEGER NOT NULL REFERENCES categories(id),
+		sku VARCHAR(64) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		brand VARCHAR(100) NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		image_url TEXT NOT NULL DEFAULT '',
+		location VARCHAR(255) NOT NULL DEFAULT '',
+		status VARCHAR(20) NOT NULL DEFAULT 'active',
+		legacy_animal_id INTEGER UNIQUE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (seller_id, sku)
+	);`
+
+	createProductVariantsTable := `
+	CREATE TABLE IF NOT EXISTS product_variants (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		sku VARCHAR(64) NOT NULL,
+		size VARCHAR(50) NOT NULL DEFAULT '',
+		flavour VARCHAR(50) NOT NULL DEFAULT '',
+		price DECIMAL(12, 2) NOT NULL CHECK (price >= 0),
+		weight_grams INTEGER NOT NULL DEFAULT 0 CHECK (weight_grams >= 0),
+		stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+		UNIQUE (product_id, sku)
+	);`
+
+	// Idempotency keys for retried POSTs (see middleware.Idempotency)
+	createIdempotencyKeysTable := `
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		user_id INTEGER NOT NULL,
+		idem_key VARCHAR(255) NOT NULL,
+		fingerprint VARCHAR(64) NOT NULL,
+		response_status INTEGER NOT NULL DEFAULT 0,
+		response_body BYTEA,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, idem_key)
+	);`
+
 	tables := []string{
 		createUserTable,
 		createCategoriesTable,
@@ -324,6 +687,7 @@ func createTables() {
 		createPostSharesTable,
 		createAnimalsTable,
 		createAnimalMediaTable,
+		createAnimalPriceHistoryTable,
 		createOrdersTable,
 		createVetsTable,
 		createConsultationsTable,
@@ -335,11 +699,37 @@ func createTables() {
 		createWishlistsTable,
 		createReviewsTable,
 		createSplashEventsTable,
+		createIdempotencyKeysTable,
+		createCartsTable,
+		createCartItemsTable,
+		createOrderItemsTable,
+		createOrderStatusHistoryTable,
+		createPaymentsTable,
+		createPaymentEventsTable,
+		createPaymentRefundsTable,
+		createVouchersTable,
+		createUserVouchersTable,
+		createVoucherRedemptionsTable,
+		createAddressesTable,
+		createShipmentsTable,
+		createShipmentEventsTable,
+		createAnimalViewsTable,
+		createAnalyticsRefreshesTable,
+		createShopsTable,
+		createShopFollowsTable,
+		createSellerRatingsTable,
+		createReviewRepliesTable,
+		createReviewVotesTable,
+		createReviewReportsTable,
+		createAlertPreferencesTable,
+		createListingSimilaritiesTable,
+		createProductsTable,
+		createProductVariantsTable,
 	}
 
 	for _, tableSQL := range tables {
 		if _, err := DB.Exec(tableSQL); err != nil {
-			log.Printf("Error creating table: %v", err)
+			log.Fatalf("Failed to create table: %v", err)
 		}
 	}
 
@@ -362,13 +752,261 @@ func createTables() {
 
 		// Community migrations
 		"ALTER TABLE posts ADD COLUMN IF NOT EXISTS shares_count INTEGER DEFAULT 0;",
+
+		"CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);",
+
+		// Checkout: a header order (no listing, no seller) with one
+		// sub-order per seller. Older orders get their lines from
+		// backfillOrderItems below.
+		"ALTER TABLE orders ALTER COLUMN animal_id DROP NOT NULL;",
+		"ALTER TABLE orders ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES orders(id) ON DELETE CASCADE;",
+		"ALTER TABLE orders ADD COLUMN IF NOT EXISTS seller_id INTEGER REFERENCES users(id);",
+		"CREATE INDEX IF NOT EXISTS idx_orders_parent ON orders(parent_id);",
+		"CREATE INDEX IF NOT EXISTS idx_orders_buyer_created ON orders(buyer_id, created_at DESC);",
+		"CREATE INDEX IF NOT EXISTS idx_order_items_order ON order_items(order_id);",
+		"CREATE INDEX IF NOT EXISTS idx_order_items_animal ON order_items(animal_id);",
+		"CREATE INDEX IF NOT EXISTS idx_orders_seller_created ON orders(seller_id, created_at DESC);",
+		"CREATE INDEX IF NOT EXISTS idx_order_status_history_order ON order_status_history(order_id);",
+		"CREATE INDEX IF NOT EXISTS idx_payments_order ON payments(order_id);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_one_pending ON payments(order_id) WHERE status = 'pending';",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_charge ON payments(provider, charge_id);",
+		"CREATE INDEX IF NOT EXISTS idx_payment_refunds_pending ON payment_refunds(id) WHERE status = 'pending';",
+		"ALTER TABLE payment_events ADD COLUMN IF NOT EXISTS currency VARCHAR(3);",
+		"CREATE INDEX IF NOT EXISTS idx_orders_pending_created ON orders(created_at) WHERE status = 'pending' AND parent_id IS NULL;",
+		"ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount DECIMAL(12, 2) NOT NULL DEFAULT 0;",
+		"ALTER TABLE orders ADD COLUMN IF NOT EXISTS voucher_id INTEGER REFERENCES vouchers(id) ON DELETE SET NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_voucher_redemptions_voucher_user ON voucher_redemptions(voucher_id, user_id);",
+		"CREATE INDEX IF NOT EXISTS idx_vouchers_public_created ON vouchers(created_at DESC) WHERE public;",
+		"ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_cost DECIMAL(12, 2) NOT NULL DEFAULT 0;",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_addresses_one_default ON addresses(user_id) WHERE is_default;",
+		"CREATE INDEX IF NOT EXISTS idx_shipments_active ON shipments(id) WHERE tracking_number IS NOT NULL AND status <> 'delivered';",
+		"CREATE INDEX IF NOT EXISTS idx_shop_follows_user ON shop_follows(user_id, created_at DESC);",
+		"CREATE INDEX IF NOT EXISTS idx_messages_receiver_created ON messages(receiver_id, created_at);",
+
+		// Verified-purchase reviews: one per completed order line, with the
+		// listing's rating and review count kept from them.
+		"ALTER TABLE reviews ADD COLUMN IF NOT EXISTS order_item_id INTEGER REFERENCES order_items(id) ON DELETE SET NULL;",
+		"ALTER TABLE reviews ADD COLUMN IF NOT EXISTS verified BOOLEAN NOT NULL DEFAULT FALSE;",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_order_item ON reviews(order_item_id);",
+		"CREATE INDEX IF NOT EXISTS idx_reviews_animal_created ON reviews(animal_id, created_at DESC);",
+		"ALTER TABLE animals ADD COLUMN IF NOT EXISTS review_count INTEGER NOT NULL DEFAULT 0;",
+
+		// Review interactions: helpful counts kept with the votes, reviews
+		// taken down by moderators, and who the moderators are.
+		"ALTER TABLE reviews ADD COLUMN IF NOT EXISTS helpful_count INTEGER NOT NULL DEFAULT 0;",
+		"ALTER TABLE reviews ADD COLUMN IF NOT EXISTS removed_at TIMESTAMP;",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS is_moderator BOOLEAN NOT NULL DEFAULT FALSE;",
+		"CREATE INDEX IF NOT EXISTS idx_review_reports_status ON review_reports(status, created_at);",
+
+		// Wishlist alerts: each entry remembers the price and availability
+		// its user last heard of, starting from the listing as it is now,
+		// and alert notifications are written once per dedupe key.
+		"ALTER TABLE wishlists ADD COLUMN IF NOT EXISTS alert_price DECIMAL(10, 2);",
+		"ALTER TABLE wishlists ADD COLUMN IF NOT EXISTS alert_in_stock BOOLEAN;",
+		`UPDATE wishlists w SET alert_price = COALESCE(a.price, 0), alert_in_stock = (a.status = 'available' AND COALESCE(a.stock, 0) > 0)
+		FROM animals a WHERE a.id = w.animal_id AND w.alert_price IS NULL;`,
+		"ALTER TABLE notifications ADD COLUMN IF NOT EXISTS dedupe_key VARCHAR(100);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_dedupe ON notifications(user_id, dedupe_key) WHERE dedupe_key IS NOT NULL;",
+
+		// Orders lock the listing and decrement stock in one transaction
+		// (see store.CreateOrder); the constraint backs that up.
+		"UPDATE animals SET stock = 0 WHERE stock < 0;",
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'animals_stock_check') THEN
+				ALTER TABLE animals ADD CONSTRAINT animals_stock_check CHECK (stock >= 0);
+			END IF;
+		END $$;`,
+
+		// Seller listing management: deleted listings keep their row for
+		// order history and are hidden by status.
+		"ALTER TABLE animals ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;",
+		"CREATE INDEX IF NOT EXISTS idx_animals_seller_status ON animals(seller_id, status);",
+		"CREATE INDEX IF NOT EXISTS idx_animal_price_history_animal ON animal_price_history(animal_id, changed_at DESC);",
+
+		// Marketplace search (see store.ListAnimals): a full-text vector over
+		// the listing text plus trigram indexes for typo-tolerant matching.
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm;",
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'terrapaw') THEN
+				IF EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'indonesian') THEN
+					CREATE TEXT SEARCH CONFIGURATION terrapaw (COPY = indonesian);
+				ELSE
+					CREATE TEXT SEARCH CONFIGURATION terrapaw (COPY = simple);
+				END IF;
+			END IF;
+		END $$;`,
+		`ALTER TABLE animals ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('terrapaw', COALESCE(name, '')), 'A') ||
+			setweight(to_tsvector('terrapaw', COALESCE(breed, '') || ' ' || COALESCE(animal_type, '')), 'B') ||
+			setweight(to_tsvector('terrapaw', COALESCE(description, '')), 'C')
+		) STORED;`,
+		"CREATE INDEX IF NOT EXISTS idx_animals_search_vector ON animals USING gin(search_vector);",
+		"CREATE INDEX IF NOT EXISTS idx_animals_search_trgm ON animals USING gin((name || ' ' || COALESCE(breed, '')) gin_trgm_ops);",
+		"CREATE INDEX IF NOT EXISTS idx_animals_name_trgm ON animals USING gin(name gin_trgm_ops);",
+		"CREATE INDEX IF NOT EXISTS idx_animals_breed_trgm ON animals USING gin(breed gin_trgm_ops);",
+		"CREATE INDEX IF NOT EXISTS idx_animals_type_trgm ON animals USING gin(animal_type gin_trgm_ops);",
+
+		// Distance search (see store.ListAnimals): listings and users are
+		// placed by the geocoder, and listings are found near a point
+		// through earthdistance's cube index. jobs.LocateListings fills in
+		// listings created before.
+		"CREATE EXTENSION IF NOT EXISTS cube;",
+		"CREATE EXTENSION IF NOT EXISTS earthdistance;",
+		"ALTER TABLE animals ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90);",
+		"ALTER TABLE animals ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180);",
+		"CREATE INDEX IF NOT EXISTS idx_animals_earth ON animals USING gist(ll_to_earth(latitude, longitude));",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS location VARCHAR(255);",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90);",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180);",
+
+		// Product catalog: listings are filed under the category their type
+		// names, by key (SeedCategories fills it in for older rows), and
+		// supplies are browsed by category and brand.
+		"ALTER TABLE animals ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_animals_category_status ON animals(category_id, status);",
+		"CREATE INDEX IF NOT EXISTS idx_products_category_status ON products(category_id, status, created_at DESC);",
+		"CREATE INDEX IF NOT EXISTS idx_products_seller ON products(seller_id);",
+		"CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING gin((name || ' ' || brand) gin_trgm_ops);",
+		"CREATE INDEX IF NOT EXISTS idx_product_variants_product ON product_variants(product_id);",
+
+		// Category tree: categories nest, are ordered among their siblings,
+		// carry translated names and the attributes their listings give
+		// (see models.Category).
+		"ALTER TABLE categories ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES categories(id);",
+		"ALTER TABLE categories ADD COLUMN IF NOT EXISTS sort_order INTEGER NOT NULL DEFAULT 0;",
+		"ALTER TABLE categories ADD COLUMN IF NOT EXISTS names JSONB NOT NULL DEFAULT '{}';",
+		"ALTER TABLE categories ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '[]';",
+		"CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id);",
+		"ALTER TABLE animals ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';",
+
+		// Supplies in carts and orders: each line sells either a listing or
+		// a product variant (see store.CartStore).
+		"ALTER TABLE cart_items ALTER COLUMN animal_id DROP NOT NULL;",
+		"ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE;",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_cart_items_variant ON cart_items(cart_id, variant_id);",
+		"ALTER TABLE order_items ALTER COLUMN animal_id DROP NOT NULL;",
+		"ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES product_variants(id);",
+		"CREATE INDEX IF NOT EXISTS idx_order_items_variant ON order_items(variant_id);",
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'cart_items_line_check') THEN
+				ALTER TABLE cart_items ADD CONSTRAINT cart_items_line_check CHECK ((animal_id IS NULL) <> (variant_id IS NULL));
+			END IF;
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'order_items_line_check') THEN
+				ALTER TABLE order_items ADD CONSTRAINT order_items_line_check CHECK ((animal_id IS NULL) <> (variant_id IS NULL));
+			END IF;
+		END $$;`,
 	}
 
+	// Every migration can be rerun, so one that fails means the schema
+	// (or a required extension such as pg_trgm or earthdistance) is not
+	// what the queries expect; don't serve on it.
 	for _, migration := range migrations {
 		if _, err := DB.Exec(migration); err != nil {
-			log.Printf("Migration warning (might be already applied): %v", err)
+			log.Fatalf("Migration failed: %v\n%s", err, migration)
 		}
 	}
+	backfillOrderItems()
+	createAnalyticsRollups()
 
 	log.Println("Tables created successfully")
 }
+
+// createAnalyticsRollups creates the materialized views behind seller
+// analytics (see store.AnalyticsStore), so the dashboard never scans orders
+// live. Sales are orders paid and not called off. Each view has a unique
+// index so the refresh job can use REFRESH ... CONCURRENTLY.
+func createAnalyticsRollups() {
+	const sales = "('paid', 'processing', 'shipped', 'delivered', 'completed')"
+	steps := []string{
+		`CREATE MATERIALIZED VIEW IF NOT EXISTS seller_sales_daily AS
+			SELECT o.seller_id, o.created_at::date AS day, COUNT(*) AS orders,
+			       SUM(COALESCE(o.quantity, 1)) AS units,
+			       SUM(COALESCE(o.total_price, 0) - COALESCE(o.shipping_cost, 0)) AS revenue
+			FROM orders o
+			WHERE o.seller_id IS NOT NULL AND o.status IN ` + sales + `
+			GROUP BY o.seller_id, o.created_at::date;`,
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_seller_sales_daily ON seller_sales_daily(seller_id, day);",
+
+		`CREATE MATERIALIZED VIEW IF NOT EXISTS listing_sales_daily AS
+			SELECT oi.animal_id, oi.seller_id, o.created_at::date AS day, COUNT(DISTINCT oi.order_id) AS orders,
+			       SUM(oi.quantity) AS units, SUM(oi.quantity * oi.unit_price) AS revenue
+			FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			WHERE o.status IN ` + sales + `
+			GROUP BY oi.animal_id, oi.seller_id, o.created_at::date;`,
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_listing_sales_daily ON listing_sales_daily(animal_id, day);",
+		"CREATE INDEX IF NOT EXISTS idx_listing_sales_daily_seller ON listing_sales_daily(seller_id, day);",
+
+		// Views from before reviews could be removed are rebuilt.
+		`DO $$ BEGIN
+			IF EXISTS (SELECT 1 FROM pg_matviews WHERE matviewname = 'listing_stats' AND definition NOT LIKE '%removed_at%') THEN
+				DROP MATERIALIZED VIEW listing_stats;
+			END IF;
+		END $$;`,
+		`CREATE MATERIALIZED VIEW IF NOT EXISTS listing_stats AS
+			SELECT a.id AS animal_id, a.seller_id,
+			       COALESCE(v.views, 0) AS views, COALESCE(w.wishlists, 0) AS wishlists,
+			       COALESCE(s.orders, 0) AS orders, COALESCE(s.units_30d, 0) AS units_30d,
+			       COALESCE(r.reviews, 0) AS reviews, COALESCE(r.rating_sum, 0) AS rating_sum
+			FROM animals a
+			LEFT JOIN (SELECT animal_id, SUM(views) AS views FROM animal_views GROUP BY animal_id) v ON v.animal_id = a.id
+			LEFT JOIN (SELECT animal_id, COUNT(*) AS wishlists FROM wishlists GROUP BY animal_id) w ON w.animal_id = a.id
+			LEFT JOIN (SELECT animal_id, SUM(orders) AS orders, SUM(units) FILTER (WHERE day > CURRENT_DATE - 30) AS units_30d
+			           FROM listing_sales_daily GROUP BY animal_id) s ON s.animal_id = a.id
+			LEFT JOIN (SELECT animal_id, COUNT(*) AS reviews, SUM(rating) AS rating_sum FROM reviews
+			           WHERE removed_at IS NULL GROUP BY animal_id) r ON r.animal_id = a.id
+			WHERE a.status <> 'deleted';`,
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_listing_stats ON listing_stats(animal_id);",
+		"CREATE INDEX IF NOT EXISTS idx_listing_stats_seller ON listing_stats(seller_id);",
+	}
+	for _, step := range steps {
+		if _, err := DB.Exec(step); err != nil {
+			log.Printf("Error creating analytics rollups: %v", err)
+			return
+		}
+	}
+}
+
+// SyncRatings recomputes every listing's and seller's rating from their
+// reviews, replacing the made-up ratings the seeders insert. From then on
+// store.CreateReview keeps them current.
+func SyncRatings() {
+	steps := []string{
+		`UPDATE animals a SET
+			rating = COALESCE((SELECT ROUND(AVG(r.rating), 2) FROM reviews r WHERE r.animal_id = a.id AND r.removed_at IS NULL), 0),
+			review_count = (SELECT COUNT(*) FROM reviews r WHERE r.animal_id = a.id AND r.removed_at IS NULL);`,
+		`INSERT INTO seller_ratings (seller_id, rating, review_count)
+			SELECT a.seller_id, ROUND(AVG(r.rating), 2), COUNT(*)
+			FROM reviews r JOIN animals a ON a.id = r.animal_id
+			WHERE r.removed_at IS NULL
+			GROUP BY a.seller_id
+			ON CONFLICT (seller_id) DO UPDATE SET
+				rating = EXCLUDED.rating, review_count = EXCLUDED.review_count, updated_at = CURRENT_TIMESTAMP;`,
+	}
+	for _, step := range steps {
+		if _, err := DB.Exec(step); err != nil {
+			log.Printf("Error syncing ratings: %v", err)
+			return
+		}
+	}
+}
+
+// backfillOrderItems gives single-listing orders written without lines,
+// by older code or the seeders, their seller and one order_items line.
+func backfillOrderItems() {
+	steps := []string{
+		"UPDATE orders o SET seller_id = a.seller_id FROM animals a WHERE o.animal_id = a.id AND o.seller_id IS NULL;",
+		`INSERT INTO order_items (order_id, animal_id, seller_id, quantity, unit_price, created_at)
+			SELECT o.id, o.animal_id, o.seller_id, COALESCE(NULLIF(o.quantity, 0), 1),
+			       COALESCE(o.total_price, 0) / COALESCE(NULLIF(o.quantity, 0), 1), o.created_at
+			FROM orders o
+			WHERE o.animal_id IS NOT NULL AND o.seller_id IS NOT NULL
+			  AND NOT EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id);`,
+	}
+	for _, step := range steps {
+		if _, err := DB.Exec(step); err != nil {
+			log.Printf("Error backfilling order items: %v", err)
+			return
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -14,7 +15,7 @@ func PatchLargeData() {
 
 	// Check if we already have enough data
 	var count int
-	DB.QueryRow("SELECT COUNT(*) FROM animals").Scan(&count)
+	DB.QueryRow("SELECT (SELECT COUNT(*) FROM animals) + (SELECT COUNT(*) FROM products)").Scan(&count)
 	if count > 3500 {
 		log.Println("Data seems already populated (3500+). Skipping large patch.")
 		return
@@ -67,7 +68,7 @@ func PatchLargeData() {
 			vetUserID, fmt.Sprintf("Klinik Hewan %d", i), fmt.Sprintf("LIC-%d", i), "General Vet", "08123456789", "Jl. Hewan No. "+fmt.Sprint(i), "Expert Vet", 4.0+rand.Float64())
 	}
 
-	// --- 3. ANIMALS (4000 Items) ---
+	// --- 3. MARKETPLACE (2000 Animals, 2000 Products) ---
 	log.Println("Creating 4000 Marketplace Items (Live & Food)...")
 
 	// Live Animal Breeds
@@ -81,21 +82,10 @@ func PatchLargeData() {
 		"Serangga": {"Kumbang Tanduk", "Tarantula", "Kalajengking", "Belalang Sembah"},
 	}
 
-	// Food Types (Category is "Makanan X")
-	foodMap := map[string][]string{
-		"Makanan Kucing":   {"Whiskas Tuna", "Royal Canin Kitten", "Me-O Salmon", "Friskies Seafood", "Pro Plan"},
-		"Makanan Anjing":   {"Pedigree Chicken", "Royal Canin Puppy", "Alpo Beef", "Science Diet", "Cesar"},
-		"Makanan Burung":   {"Pakan Kenari", "Millet Putih", "Voer Burung Juara", "Jangkrik Kering"},
-		"Makanan Hamster":  {"Vitakraft Menu", "Biji Bunga Matahari", "Hamster Mix", "Snack Hamster"},
-		"Makanan Kelinci":  {"Nova Rabbit Food", "Hay Timothy", "Pelet Kelinci", "Alfafa Hay"},
-		"Makanan Reptil":   {"Jangkrik Kering", "Pelet Kura-kura", "Ulat Hongkong Kering", "Calcium Powder"},
-		"Makanan Serangga": {"Jelly Pot", "Beetle Jelly", "Protein Mix", "Buah Segar"},
-	}
-
 	locations := []string{"Jakarta", "Bandung", "Surabaya", "Medan", "Bali", "Yogyakarta"}
 
-	stmt, _ := DB.Prepare(`INSERT INTO animals (seller_id, animal_type, breed, name, age, description, price, image_url, location, rating, status, color, gender, stock) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 'available', $11, $12, $13)`)
+	stmt, _ := DB.Prepare(`INSERT INTO animals (seller_id, animal_type, breed, name, age, description, price, image_url, location, rating, status, color, gender, stock, category_id) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 'available', $11, $12, $13, (SELECT id FROM categories WHERE name = $2))`)
 
 	// Generate 2000 Live Animals
 	baseTypes := []string{"Kucing", "Anjing", "Burung", "Hamster", "Kelinci", "Reptil", "Serangga"}
@@ -137,37 +127,11 @@ func PatchLargeData() {
 		_, _ = stmt.Exec(sellerID, aType, breed, name, rand.Intn(5)+1, desc, price, imgUrl, loc, 4.0+rand.Float64(), "Mixed", gender, rand.Intn(10)+1)
 	}
 
-	// Generate 2000 Food Items
-	foodTypes := []string{"Makanan Kucing", "Makanan Anjing", "Makanan Burung", "Makanan Hamster", "Makanan Kelinci", "Makanan Reptil", "Makanan Serangga"}
-	for i := 0; i < 2000; i++ {
-		sellerID := userIDs[rand.Intn(len(userIDs))]
-		aType := foodTypes[rand.Intn(len(foodTypes))]
-		foodList := foodMap[aType]
-		productName := foodList[rand.Intn(len(foodList))]
-
-		name := productName
-		breed := "Makanan/Aksesoris"
-		desc := "Makanan berkualitas tinggi untuk hewan kesayangan Anda."
-		price := float64(rand.Intn(500000) + 15000)
-
-		imgUrl := "https://via.placeholder.com/300?text=Food"
-		switch aType {
-		case "Makanan Kucing":
-			imgUrl = "https://images.unsplash.com/photo-1583337130417-3346a1be7dee"
-		case "Makanan Anjing":
-			imgUrl = "https://images.unsplash.com/photo-1589924691195-41432c84c161"
-		case "Makanan Burung":
-			imgUrl = "https://images.unsplash.com/photo-1623366302587-b38b1ddaefd9"
-		default:
-			imgUrl = "https://images.unsplash.com/photo-1601004890684-d8cbf643f5f2"
-		}
-
-		loc := locations[rand.Intn(len(locations))]
-
-		_, _ = stmt.Exec(sellerID, aType, breed, name, 0, desc, price, imgUrl, loc, 4.0+rand.Float64(), "-", "-", rand.Intn(50)+1)
-	}
 	stmt.Close()
 
+	// Generate 2000 Food Products
+	seedFoodProducts(userIDs, 2000, "BULK")
+
 	// --- 4. COMMUNITY POSTS (1000 Posts) ---
 	log.Println("Creating 1000 Community Posts...")
 	stmtPost, _ := DB.Prepare(`INSERT INTO posts (user_id, content, image_url, likes) VALUES ($1, $2, $3, $4)`)
@@ -201,6 +165,7 @@ func PatchLargeData() {
 		_, _ = stmtOrder.Exec(buyerID, animalID, rand.Float64()*500000, status, 1, date)
 	}
 	stmtOrder.Close()
+	backfillOrderItems()
 
 	log.Println("LARGE Data patching completed!")
 }
@@ -208,46 +173,158 @@ func PatchLargeData() {
 func SeedCategories() {
 	log.Println("Seeding Categories table...")
 
+	// Parents come before their children. Names and Attributes are JSON
+	// for the columns of the same name (see models.Category).
 	categories := []struct {
-		Name string
-		Icon string
-		Type string
+		Name       string
+		Icon       string
+		Type       string
+		Parent     string
+		Names      string
+		Attributes string
 	}{
-		{"Kucing", "🐱", "animal"},
-		{"Anjing", "🐶", "animal"},
-		{"Burung", "🐦", "animal"},
-		{"Hamster", "🐹", "animal"},
-		{"Kelinci", "🐰", "animal"},
-		{"Reptil", "🦎", "animal"},
-		{"Serangga", "🦗", "animal"},
-		{"Makanan Kucing", "🥫", "food"},
-		{"Makanan Anjing", "🦴", "food"},
-		{"Makanan Burung", "🌾", "food"},
-		{"Makanan Kelinci", "🥕", "food"},
-		{"Makanan Hamster", "🌻", "food"},
-		{"Makanan Reptil", "🦗", "food"},
+		{"Kucing", "🐱", "animal", "", `{"en": "Cats"}`, `[{"key": "vaccinated", "label": "Vaccinated", "type": "boolean", "required": false}]`},
+		{"Anjing", "🐶", "animal", "", `{"en": "Dogs"}`, `[{"key": "vaccinated", "label": "Vaccinated", "type": "boolean", "required": false}]`},
+		{"Burung", "🐦", "animal", "", `{"en": "Birds"}`, `[]`},
+		{"Hamster", "🐹", "animal", "", `{"en": "Hamsters"}`, `[]`},
+		{"Kelinci", "🐰", "animal", "", `{"en": "Rabbits"}`, `[]`},
+		{"Reptil", "🦎", "animal", "", `{"en": "Reptiles"}`, `[{"key": "morph", "label": "Morph", "type": "text", "required": true}]`},
+		{"Gecko", "🦎", "animal", "Reptil", `{"en": "Geckos"}`, `[]`},
+		{"Leopard Gecko", "🦎", "animal", "Gecko", `{"en": "Leopard Geckos"}`,
+			`[{"key": "morph", "label": "Morph", "type": "enum", "required": true, "options": ["Normal", "Tangerine", "Albino", "Mack Snow", "Blizzard"]}]`},
+		{"Serangga", "🦗", "animal", "", `{"en": "Insects"}`, `[]`},
+		{"Makanan Kucing", "🥫", "food", "", `{"en": "Cat Food"}`, `[]`},
+		{"Makanan Anjing", "🦴", "food", "", `{"en": "Dog Food"}`, `[]`},
+		{"Makanan Burung", "🌾", "food", "", `{"en": "Bird Food"}`, `[]`},
+		{"Makanan Kelinci", "🥕", "food", "", `{"en": "Rabbit Food"}`, `[]`},
+		{"Makanan Hamster", "🌻", "food", "", `{"en": "Hamster Food"}`, `[]`},
+		{"Makanan Reptil", "🦗", "food", "", `{"en": "Reptile Food"}`, `[]`},
+		{"Makanan Serangga", "🍯", "food", "", `{"en": "Insect Food"}`, `[]`},
 	}
 
-	for _, cat := range categories {
-		_, err := DB.Exec("INSERT INTO categories (name, icon, type) VALUES ($1, $2, $3) ON CONFLICT (name) DO UPDATE SET icon = $2, type = $3", cat.Name, cat.Icon, cat.Type)
+	// Moderators manage categories once they are seeded, so a row is only
+	// filled in while it has no translated names, as rows from before the
+	// category tree do not.
+	for i, cat := range categories {
+		_, err := DB.Exec(
+			`INSERT INTO categories (name, icon, type, parent_id, sort_order, names, attributes)
+			VALUES ($1, $2, $3, (SELECT id FROM categories WHERE name = $4), $5, $6, $7)
+			ON CONFLICT (name) DO UPDATE SET icon = $2, type = $3, parent_id = EXCLUDED.parent_id, sort_order = $5,
+				names = $6, attributes = $7
+			WHERE categories.names = '{}'`,
+			cat.Name, cat.Icon, cat.Type, cat.Parent, (i+1)*10, cat.Names, cat.Attributes,
+		)
 		if err != nil {
 			log.Printf("Error seeding category %s: %v", cat.Name, err)
 		}
 	}
+
+	fileLegacyListings()
 }
 
+// legacyAnimalTypes maps types sellers typed before listings were filed by
+// key, lowercased, to the category they mean, where neither its name nor
+// its English name matches.
+var legacyAnimalTypes = map[string]string{
+	"cat":     "Kucing",
+	"dog":     "Anjing",
+	"bird":    "Burung",
+	"rabbit":  "Kelinci",
+	"reptile": "Reptil",
+	"insect":  "Serangga",
+	"kitten":  "Kucing",
+	"puppy":   "Anjing",
+}
+
+// fileLegacyListings files listings from before they were filed by key
+// under the category their type names, ignoring case and spacing, in
+// Indonesian or English, or as legacyAnimalTypes spells it. Listings left
+// without a category are counted in the log, by type, so they can be filed
+// by hand.
+func fileLegacyListings() {
+	_, err := DB.Exec(`UPDATE animals a SET category_id = c.id FROM categories c
+		WHERE a.category_id IS NULL
+		  AND LOWER(TRIM(a.animal_type)) IN (LOWER(c.name), LOWER(c.names->>'en'))`)
+	if err != nil {
+		log.Printf("Error filing listings under categories: %v", err)
+	}
+	for animalType, name := range legacyAnimalTypes {
+		_, err := DB.Exec(`UPDATE animals SET category_id = (SELECT id FROM categories WHERE name = $2)
+			WHERE category_id IS NULL AND LOWER(TRIM(animal_type)) = $1`, animalType, name)
+		if err != nil {
+			log.Printf("Error filing %q listings under %s: %v", animalType, name, err)
+		}
+	}
+
+	rows, err := DB.Query(`SELECT animal_type, COUNT(*) FROM animals
+		WHERE category_id IS NULL AND status <> 'deleted'
+		GROUP BY animal_type ORDER BY COUNT(*) DESC, animal_type`)
+	if err != nil {
+		log.Printf("Error counting uncategorised listings: %v", err)
+		return
+	}
+	defer rows.Close()
+	total := 0
+	var types []string
+	for rows.Next() {
+		var animalType string
+		var n int
+		if err := rows.Scan(&animalType, &n); err != nil {
+			log.Printf("Error counting uncategorised listings: %v", err)
+			return
+		}
+		total += n
+		types = append(types, fmt.Sprintf("%q (%d)", animalType, n))
+	}
+	if total > 0 {
+		log.Printf("%d listings match no category and are left uncategorised: %s",
+			total, strings.Join(types, ", "))
+	}
+}
+
+// SeedVouchers adds the platform's standing promo codes. Existing codes
+// are left as they are.
+func SeedVouchers() {
+	log.Println("Seeding Vouchers table...")
+
+	vouchers := []struct {
+		Code         string
+		Description  string
+		DiscountType string
+		Value        float64
+		MaxDiscount  interface{}
+		MinSpend     float64
+	}{
+		{"TERRAPAW10", "10% off your order, up to Rp50.000", "percentage", 10, 50000, 100000},
+		{"HEMAT25K", "Rp25.000 off orders from Rp250.000", "fixed", 25000, nil, 250000},
+	}
+
+	for _, v := range vouchers {
+		_, err := DB.Exec(
+			`INSERT INTO vouchers (code, description, discount_type, value, max_discount, min_spend, per_user_limit, public)
+			VALUES ($1, $2, $3, $4, $5, $6, 1, TRUE) ON CONFLICT (code) DO NOTHING`,
+			v.Code, v.Description, v.DiscountType, v.Value, v.MaxDiscount, v.MinSpend,
+		)
+		if err != nil {
+			log.Printf("Error seeding voucher %s: %v", v.Code, err)
+		}
+	}
+}
+
+// EnsureFoodData stocks the supplies catalog when PatchLargeData was
+// skipped.
 func EnsureFoodData() {
 	log.Println("Checking for Food Data...")
 
 	var foodCount int
-	DB.QueryRow("SELECT COUNT(*) FROM animals WHERE animal_type LIKE 'Makanan %'").Scan(&foodCount)
+	DB.QueryRow("SELECT COUNT(*) FROM products").Scan(&foodCount)
 
 	if foodCount > 500 {
 		log.Println("Food data already exists. Skipping.")
 		return
 	}
 
-	log.Println("Injecting 1000 Food Items...")
+	log.Println("Injecting 1000 Food Products...")
 
 	// Get User IDs to assign as sellers
 	rows, _ := DB.Query("SELECT id FROM users LIMIT 100")
@@ -263,49 +340,143 @@ func EnsureFoodData() {
 		return
 	}
 
-	foodMap := map[string][]string{
-		"Makanan Kucing":   {"Whiskas Tuna", "Royal Canin Kitten", "Me-O Salmon", "Friskies Seafood", "Pro Plan"},
-		"Makanan Anjing":   {"Pedigree Chicken", "Royal Canin Puppy", "Alpo Beef", "Science Diet", "Cesar"},
-		"Makanan Burung":   {"Pakan Kenari", "Millet Putih", "Voer Burung Juara", "Jangkrik Kering"},
-		"Makanan Hamster":  {"Vitakraft Menu", "Biji Bunga Matahari", "Hamster Mix", "Snack Hamster"},
-		"Makanan Kelinci":  {"Nova Rabbit Food", "Hay Timothy", "Pelet Kelinci", "Alfafa Hay"},
-		"Makanan Reptil":   {"Jangkrik Kering", "Pelet Kura-kura", "Ulat Hongkong Kering", "Calcium Powder"},
-		"Makanan Serangga": {"Jelly Pot", "Beetle Jelly", "Protein Mix", "Buah Segar"},
-	}
+	seedFoodProducts(userIDs, 1000, "FOOD")
+	log.Println("Food Data Injected Successfully!")
+}
 
-	locations := []string{"Jakarta", "Bandung", "Surabaya", "Medan", "Bali", "Yogyakarta"}
-	foodTypes := []string{"Makanan Kucing", "Makanan Anjing", "Makanan Burung", "Makanan Hamster", "Makanan Kelinci", "Makanan Reptil", "Makanan Serangga"}
+// foodCatalog is what the seeders stock, by category: each product's brand
+// and name.
+var foodCatalog = map[string][][2]string{
+	"Makanan Kucing":   {{"Whiskas", "Whiskas Tuna"}, {"Royal Canin", "Royal Canin Kitten"}, {"Me-O", "Me-O Salmon"}, {"Friskies", "Friskies Seafood"}, {"Purina", "Pro Plan"}},
+	"Makanan Anjing":   {{"Pedigree", "Pedigree Chicken"}, {"Royal Canin", "Royal Canin Puppy"}, {"Purina", "Alpo Beef"}, {"Hill's", "Science Diet"}, {"Cesar", "Cesar"}},
+	"Makanan Burung":   {{"", "Pakan Kenari"}, {"", "Millet Putih"}, {"Juara", "Voer Burung Juara"}, {"", "Jangkrik Kering"}},
+	"Makanan Hamster":  {{"Vitakraft", "Vitakraft Menu"}, {"", "Biji Bunga Matahari"}, {"", "Hamster Mix"}, {"", "Snack Hamster"}},
+	"Makanan Kelinci":  {{"Nova", "Nova Rabbit Food"}, {"", "Hay Timothy"}, {"", "Pelet Kelinci"}, {"", "Alfafa Hay"}},
+	"Makanan Reptil":   {{"", "Jangkrik Kering"}, {"", "Pelet Kura-kura"}, {"", "Ulat Hongkong Kering"}, {"", "Calcium Powder"}},
+	"Makanan Serangga": {{"", "Jelly Pot"}, {"", "Beetle Jelly"}, {"", "Protein Mix"}, {"", "Buah Segar"}},
+}
 
-	stmt, _ := DB.Prepare(`INSERT INTO animals (seller_id, animal_type, breed, name, age, description, price, image_url, location, rating, status, color, gender, stock) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 'available', $11, $12, $13)`)
+// foodSizes are the pack sizes seeded products come in, smallest first,
+// with the price of each relative to the smallest.
+var foodSizes = []struct {
+	Size   string
+	Code   string
+	Grams  int
+	Factor float64
+}{
+	{"500 g", "500G", 500, 1},
+	{"1 kg", "1KG", 1000, 1.8},
+	{"5 kg", "5KG", 5000, 8},
+}
 
-	for i := 0; i < 1000; i++ {
-		sellerID := userIDs[rand.Intn(len(userIDs))]
-		aType := foodTypes[rand.Intn(len(foodTypes))]
-		foodList := foodMap[aType]
-		productName := foodList[rand.Intn(len(foodList))]
+// seedFoodProducts adds n random products from foodCatalog, in one to three
+// pack sizes each, to the food categories. SKUs start with prefix.
+func seedFoodProducts(sellerIDs []int, n int, prefix string) {
+	categoryIDs := map[string]int{}
+	rows, err := DB.Query("SELECT id, name FROM categories WHERE type = 'food'")
+	if err != nil {
+		log.Printf("Error seeding products: %v", err)
+		return
+	}
+	for rows.Next() {
+		var id int
+		var name string
+		rows.Scan(&id, &name)
+		if _, ok := foodCatalog[name]; ok {
+			categoryIDs[name] = id
+		}
+	}
+	rows.Close()
+	var categories []string
+	for name := range categoryIDs {
+		categories = append(categories, name)
+	}
+	if len(categories) == 0 {
+		log.Println("No food categories found, skipping products.")
+		return
+	}
 
-		name := fmt.Sprintf("%s - %d", productName, i)
-		breed := "Makanan/Aksesoris"
-		desc := "Makanan berkualitas tinggi untuk hewan kesayangan Anda. Stok selalu baru dan higienis."
-		price := float64(rand.Intn(500000) + 15000)
+	locations := []string{"Jakarta", "Bandung", "Surabaya", "Medan", "Bali", "Yogyakarta"}
+	for i := 0; i < n; i++ {
+		category := categories[rand.Intn(len(categories))]
+		item := foodCatalog[category][rand.Intn(len(foodCatalog[category]))]
+		sku := fmt.Sprintf("%s-%05d", prefix, i)
 
-		imgUrl := "https://via.placeholder.com/300?text=Food"
-		switch aType {
+		imgUrl := "https://images.unsplash.com/photo-1601004890684-d8cbf643f5f2"
+		switch category {
 		case "Makanan Kucing":
 			imgUrl = "https://images.unsplash.com/photo-1583337130417-3346a1be7dee"
 		case "Makanan Anjing":
 			imgUrl = "https://images.unsplash.com/photo-1589924691195-41432c84c161"
 		case "Makanan Burung":
 			imgUrl = "https://images.unsplash.com/photo-1623366302587-b38b1ddaefd9"
-		default:
-			imgUrl = "https://images.unsplash.com/photo-1601004890684-d8cbf643f5f2"
 		}
 
-		loc := locations[rand.Intn(len(locations))]
+		var productID int
+		err := DB.QueryRow(
+			`INSERT INTO products (seller_id, category_id, sku, name, brand, description, image_url, location)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (seller_id, sku) DO NOTHING RETURNING id`,
+			sellerIDs[rand.Intn(len(sellerIDs))], categoryIDs[category], sku, item[1], item[0],
+			"Makanan berkualitas tinggi untuk hewan kesayangan Anda. Stok selalu baru dan higienis.",
+			imgUrl, locations[rand.Intn(len(locations))],
+		).Scan(&productID)
+		if err != nil {
+			continue
+		}
 
-		_, _ = stmt.Exec(sellerID, aType, breed, name, 0, desc, price, imgUrl, loc, 4.0+rand.Float64(), "-", "-", rand.Intn(50)+1)
+		price := float64(rand.Intn(100)+15) * 1000
+		for _, size := range foodSizes[:rand.Intn(len(foodSizes))+1] {
+			_, _ = DB.Exec(
+				`INSERT INTO product_variants (product_id, sku, size, price, weight_grams, stock)
+				VALUES ($1, $2, $3, $4, $5, $6)`,
+				productID, sku+"-"+size.Code, size.Size, price*size.Factor, size.Grams, rand.Intn(50)+1,
+			)
+		}
+	}
+}
+
+// MoveFoodListings moves the food the seeders used to list as animals into
+// the product catalog, each as a product with one "Standard" variant, and
+// carts holding one of them over to its variant. The old rows are
+// soft-deleted rather than removed, so the order lines, reviews, wishlist
+// entries and media that point at them stay, as for any deleted listing;
+// products.legacy_animal_id leads from them to the product. Food that
+// sellers listed themselves is theirs to relist as products.
+func MoveFoodListings() {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Error moving food listings: %v", err)
+		return
+	}
+	defer tx.Rollback()
+
+	steps := []string{
+		`INSERT INTO products (seller_id, category_id, sku, name, description, image_url, location, status, legacy_animal_id, created_at, updated_at)
+			SELECT a.seller_id, a.category_id, 'LEGACY-' || a.id, a.name, COALESCE(a.description, ''),
+			       COALESCE(a.image_url, ''), COALESCE(a.location, ''),
+			       CASE WHEN a.status = 'available' THEN 'active' ELSE 'archived' END, a.id, a.created_at, a.updated_at
+			FROM animals a JOIN categories c ON c.id = a.category_id
+			WHERE c.type = 'food' AND a.breed = 'Makanan/Aksesoris' AND a.status <> 'deleted'
+			ON CONFLICT DO NOTHING;`,
+		`INSERT INTO product_variants (product_id, sku, size, price, stock)
+			SELECT p.id, p.sku, 'Standard', COALESCE(a.price, 0), GREATEST(COALESCE(a.stock, 0), 0)
+			FROM products p JOIN animals a ON a.id = p.legacy_animal_id
+			WHERE NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id);`,
+		`UPDATE cart_items ci SET variant_id = v.id, animal_id = NULL
+			FROM products p JOIN product_variants v ON v.product_id = p.id
+			WHERE ci.animal_id = p.legacy_animal_id
+			  AND NOT EXISTS (SELECT 1 FROM cart_items other WHERE other.cart_id = ci.cart_id AND other.variant_id = v.id);`,
+		`UPDATE animals a SET status = 'deleted', deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			FROM products p
+			WHERE p.legacy_animal_id = a.id AND a.status <> 'deleted';`,
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(step); err != nil {
+			log.Printf("Error moving food listings: %v", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error moving food listings: %v", err)
 	}
-	stmt.Close()
-	log.Println("Food Data Injected Successfully!")
 }
@@ -108,8 +108,8 @@ func SeedData() {
 		stock := rand.Intn(10) + 1
 
 		// Check duplicates roughly or just insert
-		_, _ = DB.Exec(`INSERT INTO animals (seller_id, animal_type, breed, name, age, description, price, image_url, location, rating, status, color, gender, stock) 
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		_, _ = DB.Exec(`INSERT INTO animals (seller_id, animal_type, breed, name, age, description, price, image_url, location, rating, status, color, gender, stock, category_id) 
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, (SELECT id FROM categories WHERE name = $2))`,
 			sellerID, aType, "Breed "+fmt.Sprint(i), name, rand.Intn(5)+1, "Description for "+name,
 			float64(rand.Intn(5000000)+50000), "https://via.placeholder.com/300", "Jakarta", 4.0+rand.Float64(), statuses[rand.Intn(len(statuses))],
 			color, gender, stock)
@@ -195,6 +195,7 @@ func SeedData() {
 				VALUES ($1, $2, $3, 'completed')`,
 				buyerID, animalID, rand.Float64()*1000000)
 		}
+		backfillOrderItems()
 	}
 
 	// --- 10. CONSULTATIONS ---
@@ -0,0 +1,120 @@
+// Package geo places listings and users on the map. Geocoder is
+// implemented offline from a bundled table of Indonesian cities and
+// regencies; online services such as Nominatim or Google plug in behind the
+// same interface.
+package geo
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TerraPaw/backend/config"
+	"github.com/TerraPaw/backend/models"
+)
+
+// ErrUnknownPlace is returned by Geocode for places it cannot find.
+var ErrUnknownPlace = errors.New("geo: unknown place")
+
+// Geocoder finds the coordinates of a free-text place, such as a listing
+// location or an address.
+type Geocoder interface {
+	Geocode(ctx context.Context, place string) (*models.GeoPoint, error)
+}
+
+// Default is the geocoder used by the handlers and jobs. It is set in main
+// from the configuration.
+var Default Geocoder
+
+// Open returns the geocoder described by cfg.
+func Open(cfg *config.Config) (Geocoder, error) {
+	switch cfg.GeocoderDriver {
+	case "offline":
+		return NewOffline(), nil
+	}
+	return nil, fmt.Errorf("geo: unknown geocoder %q", cfg.GeocoderDriver)
+}
+
+//go:embed places.csv
+var placesCSV []byte
+
+// Place is a city or regency of the bundled table.
+type Place struct {
+	Name     string
+	Province string
+	Point    models.GeoPoint
+}
+
+// Offline geocodes against the bundled table. A place is found when its
+// name or one of its aliases appears as whole words in the text; the
+// longest such mention wins, then the first one, so "Jakarta Selatan"
+// beats "Jakarta" and "Kuta, Bali" is Kuta rather than Denpasar.
+type Offline struct {
+	names    map[string]*Place // normalized name or alias
+	maxWords int
+}
+
+// NewOffline loads the bundled table.
+func NewOffline() *Offline {
+	rows, err := csv.NewReader(bytes.NewReader(placesCSV)).ReadAll()
+	if err != nil {
+		panic("geo: bundled places: " + err.Error())
+	}
+	o := &Offline{names: map[string]*Place{}}
+	for _, row := range rows[1:] {
+		lat, errLat := strconv.ParseFloat(row[2], 64)
+		lng, errLng := strconv.ParseFloat(row[3], 64)
+		if errLat != nil || errLng != nil {
+			panic("geo: bundled places: bad coordinates for " + row[0])
+		}
+		p := &Place{Name: row[0], Province: row[1], Point: models.GeoPoint{Latitude: lat, Longitude: lng}}
+		o.add(row[0], p)
+		for _, alias := range strings.Split(row[4], "|") {
+			o.add(alias, p)
+		}
+	}
+	return o
+}
+
+func (o *Offline) add(name string, p *Place) {
+	words := normalize(name)
+	if len(words) == 0 {
+		return
+	}
+	o.names[strings.Join(words, " ")] = p
+	o.maxWords = max(o.maxWords, len(words))
+}
+
+// Lookup returns the place mentioned in text, if any.
+func (o *Offline) Lookup(text string) (*Place, bool) {
+	words := normalize(text)
+	for n := min(o.maxWords, len(words)); n > 0; n-- {
+		for i := 0; i+n <= len(words); i++ {
+			if p, ok := o.names[strings.Join(words[i:i+n], " ")]; ok {
+				return p, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (o *Offline) Geocode(ctx context.Context, place string) (*models.GeoPoint, error) {
+	p, ok := o.Lookup(place)
+	if !ok {
+		return nil, ErrUnknownPlace
+	}
+	point := p.Point
+	return &point, nil
+}
+
+// normalize splits text into lower-case words of letters.
+func normalize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z')
+	})
+}
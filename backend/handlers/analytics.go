@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTopListings = 10
+	maxTopListings     = 50
+	// maxAnalyticsDays caps the range of a sales report.
+	maxAnalyticsDays = 731
+)
+
+// defaultBuckets is how many periods a sales report covers when from is
+// not given.
+var defaultBuckets = map[string]int{"day": 30, "week": 12, "month": 12}
+
+// GetSellerAnalytics reports the seller's sales by day, week or month,
+// their best-selling listings, the view to wishlist to order funnel,
+// listings running out of stock and their average rating. Figures come from
+// rollups refreshed by a background job, as of refreshed_at.
+func GetSellerAnalytics(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	params := &queryParams{c: c}
+	period := params.oneOf("period", store.AnalyticsPeriods)
+	from, to := params.date("from"), params.date("to")
+	top := params.integer("top", 1)
+	if !params.done() {
+		return
+	}
+	q := store.AnalyticsQuery{Period: period, Top: defaultTopListings}
+	if q.Period == "" {
+		q.Period = "day"
+	}
+	if top != nil {
+		q.Top = min(*top, maxTopListings)
+	}
+	// to is inclusive in the query string and exclusive in the store.
+	q.To = store.Day(time.Now()).AddDate(0, 0, 1)
+	if to != nil {
+		q.To = to.AddDate(0, 0, 1)
+	}
+	q.From = store.BucketStart(q.Period, q.To.AddDate(0, 0, -1))
+	for i := 1; i < defaultBuckets[q.Period]; i++ {
+		q.From = store.BucketStart(q.Period, q.From.AddDate(0, 0, -1))
+	}
+	if from != nil {
+		q.From = *from
+	}
+	switch {
+	case !q.From.Before(q.To):
+		c.Error(apperr.Invalid("from", "must not be after to"))
+		return
+	case q.To.Sub(q.From) > maxAnalyticsDays*24*time.Hour:
+		c.Error(apperr.Invalid("from", "must be at most two years before to"))
+		return
+	}
+
+	analytics, err := store.Default.SellerAnalytics(userID.(int), q)
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch analytics", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Seller analytics", analytics))
+}
@@ -2,14 +2,15 @@ package handlers
 
 import (
 	"crypto/sha256"
-	"database/sql"
 	"encoding/hex"
-	"fmt"
+	"errors"
 	"net/http"
-    "strings"
+	"strings"
+	"time"
 
-	"github.com/TerraPaw/backend/db"
+	"github.com/TerraPaw/backend/apperr"
 	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/store"
 	"github.com/TerraPaw/backend/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -29,76 +30,73 @@ type LoginRequest struct {
 func Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request", err.Error()))
+		c.Error(apperr.Validation(err))
 		return
 	}
-    
-    // Normalize email
-    req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+
+	// Normalize email
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
 
 	// Hash password
 	hash := sha256.Sum256([]byte(req.Password))
 	hashedPassword := hex.EncodeToString(hash[:])
 
 	// Insert user into database
-	var userID int
-	err := db.DB.QueryRow(
-		"INSERT INTO users (username, email, password, fullname) VALUES ($1, $2, $3, $4) RETURNING id",
-		req.Username, req.Email, hashedPassword, req.FullName,
-	).Scan(&userID)
+	userID, err := store.Default.CreateUser(&models.User{
+		Username: req.Username,
+		Email:    req.Email,
+		Password: hashedPassword,
+		FullName: req.FullName,
+	})
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("Registration failed", "Email or username already exists"))
+		if errors.Is(err, store.ErrConflict) {
+			c.Error(apperr.ErrAccountExists)
+		} else {
+			c.Error(apperr.Internal("Registration failed", err))
+		}
 		return
 	}
 
 	// Generate token
 	token, err := utils.GenerateToken(userID, req.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Token generation failed", err.Error()))
+		c.Error(apperr.Internal("Token generation failed", err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, utils.SuccessResponse("Registration successful", gin.H{
-		"user_id":    userID,
-		"username":   req.Username,
-		"email":      req.Email,
-		"fullname":   req.FullName,
-		"avatar_url": "", // Default empty or placeholder if set in DB default
-		"user_type":  "customer",
-		"token":      token,
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Registration successful", AuthResponse{
+		UserID:    userID,
+		Username:  req.Username,
+		Email:     req.Email,
+		FullName:  req.FullName,
+		AvatarURL: "", // Default empty or placeholder if set in DB default
+		UserType:  "customer",
+		Token:     token,
 	}))
 }
 
 func Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request", err.Error()))
+		c.Error(apperr.Validation(err))
 		return
 	}
-    
-    // Normalize email
-    req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+
+	// Normalize email
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
 
 	// Hash password
 	hash := sha256.Sum256([]byte(req.Password))
 	hashedPassword := hex.EncodeToString(hash[:])
 
-    fmt.Printf("Login attempt: Email=%s Hash=%s\n", req.Email, hashedPassword)
-
 	// Get user from database (case-insensitive email check for robustness)
-	var user models.User
-	err := db.DB.QueryRow(
-		"SELECT id, username, email, fullname, COALESCE(avatar_url, ''), COALESCE(bio, ''), user_type FROM users WHERE LOWER(email) = LOWER($1) AND password = $2",
-		req.Email, hashedPassword,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.FullName, &user.AvatarURL, &user.Bio, &user.UserType)
-
+	user, err := store.Default.AuthenticateUser(req.Email, hashedPassword)
 	if err != nil {
-        fmt.Println("Login error:", err)
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Login failed", "Invalid email or password"))
+		if errors.Is(err, store.ErrNotFound) {
+			c.Error(apperr.ErrInvalidCredentials)
 		} else {
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Login failed", err.Error()))
+			c.Error(apperr.Internal("Login failed", err))
 		}
 		return
 	}
@@ -106,37 +104,36 @@ func Login(c *gin.Context) {
 	// Generate token
 	token, err := utils.GenerateToken(user.ID, user.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Token generation failed", err.Error()))
+		c.Error(apperr.Internal("Token generation failed", err))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse("Login successful", gin.H{
-		"user_id":    user.ID,
-		"username":   user.Username,
-		"email":      user.Email,
-		"fullname":   user.FullName,
-		"avatar_url": user.AvatarURL,
-		"bio":        user.Bio,
-		"user_type":  user.UserType,
-		"token":      token,
+	c.JSON(http.StatusOK, utils.SuccessResponse("Login successful", AuthResponse{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		FullName:  user.FullName,
+		AvatarURL: user.AvatarURL,
+		Bio:       user.Bio,
+		UserType:  user.UserType,
+		Token:     token,
 	}))
 }
 
 func GetUserProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized", "User ID not found in context"))
+		c.Error(apperr.ErrUnauthorized)
 		return
 	}
 
-	var user models.User
-	err := db.DB.QueryRow(
-		"SELECT id, username, email, fullname, COALESCE(avatar_url, ''), COALESCE(bio, ''), user_type FROM users WHERE id = $1",
-		userID,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.FullName, &user.AvatarURL, &user.Bio, &user.UserType)
-
+	user, err := store.Default.GetUser(userID.(int))
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrUserNotFound)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, utils.ErrorResponse("User not found", err.Error()))
+		c.Error(apperr.Internal("Failed to fetch user", err))
 		return
 	}
 
@@ -156,60 +153,41 @@ type ResetPasswordRequest struct {
 func ForgotPassword(c *gin.Context) {
 	var req ForgotPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request", err.Error()))
-		return
-	}
-
-	// Check if user exists
-	var email string
-	err := db.DB.QueryRow("SELECT email FROM users WHERE email = $1", req.Email).Scan(&email)
-	if err != nil {
-		// Don't reveal if email exists or not for security, just say email sent if exists
-		c.JSON(http.StatusOK, utils.SuccessResponse("If your email is registered, you will receive a password reset link.", nil))
+		c.Error(apperr.Validation(err))
 		return
 	}
 
 	// Generate reset token (simple random string for demo)
 	resetToken, _ := utils.GenerateToken(0, req.Email) // reusing JWT gen for convenience, or simple random string
-    // Ideally use crypto/rand for a short code
-    
-    // For this demo, let's use a simple 6 digit code for easy testing
-    resetToken = "123456" // In production, generate random: strconv.Itoa(rand.Intn(999999-100000)+100000)
+	// Ideally use crypto/rand for a short code
+
+	// For this demo, let's use a simple 6 digit code for easy testing
+	resetToken = "123456" // In production, generate random: strconv.Itoa(rand.Intn(999999-100000)+100000)
 
 	// Save token to DB with expiry (15 mins)
-	_, err = db.DB.Exec(
-		"UPDATE users SET reset_token = $1, reset_token_expiry = NOW() + INTERVAL '15 minutes' WHERE email = $2",
-		resetToken, req.Email,
-	)
+	err := store.Default.SetResetToken(req.Email, resetToken, 15*time.Minute)
+	if errors.Is(err, store.ErrNotFound) {
+		// Don't reveal if email exists or not for security, just say email sent if exists
+		c.JSON(http.StatusOK, utils.SuccessResponse("If your email is registered, you will receive a password reset link.", nil))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to process request", err.Error()))
+		c.Error(apperr.Internal("Failed to process request", err))
 		return
 	}
 
 	// Mock Send Email
 	// In real app: sendEmail(req.Email, resetToken)
-    // For demo purposes, we return the token in response so you can test it
-	c.JSON(http.StatusOK, utils.SuccessResponse("Password reset code sent (Mock: Code is 123456)", gin.H{
-        "mock_code": resetToken,
-    }))
+	// For demo purposes, we return the token in response so you can test it
+	c.JSON(http.StatusOK, utils.SuccessResponse("Password reset code sent (Mock: Code is 123456)", ForgotPasswordResponse{
+		MockCode: resetToken,
+	}))
 }
 
 func ResetPassword(c *gin.Context) {
 	var req ResetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request", err.Error()))
-		return
-	}
-
-	// Verify token and expiry
-	var id int
-	err := db.DB.QueryRow(
-		"SELECT id FROM users WHERE email = $1 AND reset_token = $2 AND reset_token_expiry > NOW()",
-		req.Email, req.Token,
-	).Scan(&id)
-
-	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid or expired token", "Please request a new password reset"))
+		c.Error(apperr.Validation(err))
 		return
 	}
 
@@ -217,13 +195,14 @@ func ResetPassword(c *gin.Context) {
 	hash := sha256.Sum256([]byte(req.NewPassword))
 	hashedPassword := hex.EncodeToString(hash[:])
 
-	// Update password and clear token
-	_, err = db.DB.Exec(
-		"UPDATE users SET password = $1, reset_token = NULL, reset_token_expiry = NULL WHERE id = $2",
-		hashedPassword, id,
-	)
+	// Verify token and expiry, then update password and clear token
+	err := store.Default.ResetPassword(req.Email, req.Token, hashedPassword)
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrInvalidResetToken)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to reset password", err.Error()))
+		c.Error(apperr.Internal("Failed to reset password", err))
 		return
 	}
 
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AddCartItemRequest adds a listing or a product variant to the cart.
+type AddCartItemRequest struct {
+	AnimalID  int `json:"animal_id" binding:"omitempty,min=1"`
+	VariantID int `json:"variant_id" binding:"omitempty,min=1"`
+	Quantity  int `json:"quantity" binding:"omitempty,min=1,max=100"`
+}
+
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity" binding:"required,min=1,max=100"`
+}
+
+// CheckoutRequest is optional; without a body the cart is bought at full
+// price with no delivery booked. With an address, Shipping must choose a
+// quoted service for every seller in the cart.
+type CheckoutRequest struct {
+	VoucherCode string           `json:"voucher_code" binding:"max=50"`
+	AddressID   int              `json:"address_id"`
+	Shipping    []ShippingChoice `json:"shipping" binding:"max=50,dive"`
+}
+
+// itemChosen reports a request that names both or neither of a listing
+// and a product variant.
+func itemChosen(c *gin.Context, animalID, variantID int) bool {
+	switch {
+	case animalID == 0 && variantID == 0:
+		c.Error(apperr.Invalid("animal_id", "is required unless variant_id is given"))
+		return false
+	case animalID != 0 && variantID != 0:
+		c.Error(apperr.Invalid("variant_id", "cannot be given with animal_id"))
+		return false
+	}
+	return true
+}
+
+// cartError reports a failed change to the cart. missing is the error for
+// a listing or variant that does not exist, which differs between adding
+// and updating, and unavailable the one for it not being on sale.
+func cartError(c *gin.Context, message string, err error, missing, unavailable *apperr.Error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(missing)
+	case errors.Is(err, store.ErrUnavailable):
+		c.Error(unavailable)
+	case errors.Is(err, store.ErrInsufficientStock):
+		c.Error(apperr.ErrInsufficientStock)
+	default:
+		c.Error(apperr.Internal(message, err))
+	}
+}
+
+// respondCart writes the buyer's current cart.
+func respondCart(c *gin.Context, status int, message string) {
+	userID, _ := c.Get("user_id")
+	cart, err := store.Default.GetCart(userID.(int))
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch cart", err))
+		return
+	}
+	signCart(cart)
+
+	c.JSON(status, utils.SuccessResponse(message, cart))
+}
+
+// cartProblem describes why a cart item stopped checkout.
+func cartProblem(item models.CartItem) string {
+	price := func(p float64) string { return strconv.FormatFloat(p, 'f', -1, 64) }
+	switch item.Problem {
+	case models.CartUnavailable:
+		return "is no longer available"
+	case models.CartInsufficientStock:
+		if item.Variant != nil {
+			return fmt.Sprintf("only %d left in stock", item.Variant.Stock)
+		}
+		return fmt.Sprintf("only %d left in stock", item.Animal.Stock)
+	default:
+		return "price changed from " + price(item.AddedPrice) + " to " + price(item.Price)
+	}
+}
+
+func GetCart(c *gin.Context) {
+	respondCart(c, http.StatusOK, "Cart retrieved")
+}
+
+func AddCartItem(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var req AddCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+	if !itemChosen(c, req.AnimalID, req.VariantID) {
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	if req.VariantID != 0 {
+		err := store.Default.AddCartVariant(userID.(int), req.VariantID, req.Quantity)
+		if err != nil {
+			cartError(c, "Failed to add to cart", err, apperr.ErrVariantNotFound, apperr.ErrProductUnavailable)
+			return
+		}
+	} else {
+		err := store.Default.AddCartItem(userID.(int), req.AnimalID, req.Quantity)
+		if err != nil {
+			cartError(c, "Failed to add to cart", err, apperr.ErrAnimalNotFound, apperr.ErrAnimalUnavailable)
+			return
+		}
+	}
+
+	respondCart(c, http.StatusOK, "Added to cart")
+}
+
+func UpdateCartItem(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	animalID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	err := store.Default.UpdateCartItem(userID.(int), animalID, req.Quantity)
+	if err != nil {
+		cartError(c, "Failed to update cart", err, apperr.ErrCartItemNotFound, apperr.ErrAnimalUnavailable)
+		return
+	}
+
+	respondCart(c, http.StatusOK, "Cart updated")
+}
+
+// UpdateCartVariant changes the quantity of a product variant in the cart.
+func UpdateCartVariant(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	variantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	err := store.Default.UpdateCartVariant(userID.(int), variantID, req.Quantity)
+	if err != nil {
+		cartError(c, "Failed to update cart", err, apperr.ErrVariantNotInCart, apperr.ErrProductUnavailable)
+		return
+	}
+
+	respondCart(c, http.StatusOK, "Cart updated")
+}
+
+func RemoveCartItem(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	animalID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := store.Default.RemoveCartItem(userID.(int), animalID); err != nil {
+		c.Error(apperr.Internal("Failed to remove from cart", err))
+		return
+	}
+
+	respondCart(c, http.StatusOK, "Removed from cart")
+}
+
+// RemoveCartVariant takes a product variant out of the cart.
+func RemoveCartVariant(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	variantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := store.Default.RemoveCartVariant(userID.(int), variantID); err != nil {
+		c.Error(apperr.Internal("Failed to remove from cart", err))
+		return
+	}
+
+	respondCart(c, http.StatusOK, "Removed from cart")
+}
+
+// Checkout places one order for everything in the cart, less the discount
+// of the voucher given, if any, and plus the delivery chosen for each
+// seller. If any item changed since the buyer last
+// saw it nothing is bought, and the details list each item that needs
+// attention by its position in the cart.
+func Checkout(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var req CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	shipments, ok := checkoutShipping(c, userID.(int), req)
+	if !ok {
+		return
+	}
+
+	order, err := store.Default.Checkout(userID.(int), store.CheckoutOptions{VoucherCode: req.VoucherCode, Shipping: shipments})
+	var changed *store.CartError
+	switch {
+	case errors.As(err, &changed):
+		e := *apperr.ErrCartChanged
+		for i, item := range changed.Cart.Items {
+			if item.Problem != "" {
+				e.Details = append(e.Details, apperr.FieldError{Field: fmt.Sprintf("items[%d]", i), Message: cartProblem(item)})
+			}
+		}
+		c.Error(&e)
+		return
+	case errors.Is(err, store.ErrEmptyCart):
+		c.Error(apperr.ErrCartEmpty)
+		return
+	case errors.Is(err, store.ErrNoShipping):
+		c.Error(apperr.Invalid("shipping", "must choose a service for every seller in the cart"))
+		return
+	case err != nil:
+		voucherError(c, "Failed to check out", err)
+		return
+	}
+	signOrder(order)
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Order created", order))
+}
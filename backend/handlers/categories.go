@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CategoryRequest creates or replaces a category. Type defaults to the
+// parent's, which a subcategory must share. Active defaults to true when
+// creating and to the current state when replacing; DELETE deactivates.
+type CategoryRequest struct {
+	ParentID   *int               `json:"parent_id" binding:"omitempty,min=1"`
+	Name       string             `json:"name" binding:"required,max=100"`
+	Names      map[string]string  `json:"names" binding:"max=20,dive,required,max=100"`
+	Icon       string             `json:"icon" binding:"max=50"`
+	Type       string             `json:"type" binding:"omitempty,oneof=animal food"`
+	SortOrder  int                `json:"sort_order"`
+	Active     *bool              `json:"active"`
+	Attributes []AttributeRequest `json:"attributes" binding:"max=30,dive"`
+}
+
+// AttributeRequest declares one detail listings in a category give. Options
+// are required for, and only allowed on, enum attributes.
+type AttributeRequest struct {
+	Key      string   `json:"key" binding:"required,max=50"`
+	Label    string   `json:"label" binding:"required,max=100"`
+	Type     string   `json:"type" binding:"required,oneof=text number boolean enum"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options" binding:"max=100,dive,required,max=100"`
+}
+
+var (
+	languageCode = regexp.MustCompile(`^[a-z]{2}(-[a-z]{2})?$`)
+	attributeKey = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+// maxAttributeText is the longest text attribute value a listing can give.
+const maxAttributeText = 100
+
+// categoryError reports a failed category operation.
+func categoryError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(apperr.ErrCategoryNotFound)
+	case errors.Is(err, store.ErrConflict):
+		c.Error(apperr.ErrCategoryExists)
+	case errors.Is(err, store.ErrHasSubcategories):
+		c.Error(apperr.ErrCategoryInUse)
+	default:
+		c.Error(apperr.Internal(message, err))
+	}
+}
+
+func categoriesByID(categories []models.Category) map[int]models.Category {
+	byID := make(map[int]models.Category, len(categories))
+	for _, cat := range categories {
+		byID[cat.ID] = cat
+	}
+	return byID
+}
+
+// categorySchema returns the attributes listings in a category give: its
+// ancestors' and its own, where a category's own attribute replaces an
+// ancestor's with the same key.
+func categorySchema(byID map[int]models.Category, id int) []models.CategoryAttribute {
+	var chain []models.Category // the category, then its ancestors
+	seen := map[int]bool{}
+	for next := &id; next != nil && !seen[*next]; {
+		cat, ok := byID[*next]
+		if !ok {
+			break
+		}
+		seen[cat.ID] = true
+		chain = append(chain, cat)
+		next = cat.ParentID
+	}
+
+	var schema []models.CategoryAttribute
+	index := map[string]int{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, attr := range chain[i].Attributes {
+			if j, ok := index[attr.Key]; ok {
+				schema[j] = attr
+				continue
+			}
+			index[attr.Key] = len(schema)
+			schema = append(schema, attr)
+		}
+	}
+	return schema
+}
+
+// describeCategories fills in the label, in lang when the category has a
+// name in it, and the full attribute schema of each category.
+func describeCategories(categories []models.Category, lang string) {
+	byID := categoriesByID(categories)
+	for i := range categories {
+		cat := &categories[i]
+		cat.Label = cat.Name
+		if name, ok := cat.Names[lang]; ok {
+			cat.Label = name
+		}
+		cat.Schema = categorySchema(byID, cat.ID)
+	}
+}
+
+// categoryTree nests categories under their parents, keeping their order.
+// Categories whose parent is not among them are put at the top.
+func categoryTree(categories []models.Category) []models.Category {
+	listed := map[int]bool{}
+	for _, cat := range categories {
+		listed[cat.ID] = true
+	}
+	children := map[int][]models.Category{} // by parent id; 0 for the top
+	for _, cat := range categories {
+		parent := 0
+		if cat.ParentID != nil && listed[*cat.ParentID] {
+			parent = *cat.ParentID
+		}
+		children[parent] = append(children[parent], cat)
+	}
+
+	var build func(parent int) []models.Category
+	build = func(parent int) []models.Category {
+		nodes := children[parent]
+		for i := range nodes {
+			nodes[i].Children = build(nodes[i].ID)
+		}
+		return nodes
+	}
+	return build(0)
+}
+
+// respondCategories lists categories as GetCategories and GetAllCategories
+// do.
+func respondCategories(c *gin.Context, categories []models.Category) {
+	params := &queryParams{c: c}
+	tree := params.boolean("tree")
+	if !params.done() {
+		return
+	}
+
+	describeCategories(categories, strings.ToLower(c.Query("lang")))
+	if tree {
+		categories = categoryTree(categories)
+	}
+	if categories == nil {
+		categories = []models.Category{}
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Categories retrieved", categories))
+}
+
+// GetCategories lists the active categories in sort order, or nested when
+// tree is set. Each is labelled in lang when it has a name in it.
+func GetCategories(c *gin.Context) {
+	categories, err := store.Default.ListCategories()
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch categories", err))
+		return
+	}
+	respondCategories(c, categories)
+}
+
+// GetAllCategories lists every category for moderators, inactive ones
+// included.
+func GetAllCategories(c *gin.Context) {
+	categories, err := store.Default.AllCategories()
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch categories", err))
+		return
+	}
+	respondCategories(c, categories)
+}
+
+// bindCategory reads a CategoryRequest and checks it against the other
+// categories. existing is the category it replaces, or nil for a new one.
+func bindCategory(c *gin.Context, categories []models.Category, existing *models.Category) (*models.Category, bool) {
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return nil, false
+	}
+	byID := categoriesByID(categories)
+
+	cat := &models.Category{
+		ParentID:  req.ParentID,
+		Name:      strings.TrimSpace(req.Name),
+		Names:     map[string]string{},
+		Icon:      req.Icon,
+		Type:      req.Type,
+		SortOrder: req.SortOrder,
+		Active:    true,
+	}
+	if existing != nil {
+		cat.ID, cat.Active = existing.ID, existing.Active
+	}
+	if req.Active != nil {
+		cat.Active = *req.Active
+	}
+
+	var problems []apperr.FieldError
+	invalid := func(field, message string) {
+		problems = append(problems, apperr.FieldError{Field: field, Message: message})
+	}
+	if cat.Name == "" {
+		invalid("name", "is required")
+	}
+	langs := make([]string, 0, len(req.Names))
+	for lang := range req.Names {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		if !languageCode.MatchString(lang) {
+			invalid("names", fmt.Sprintf("%q is not a language code such as en or pt-br", lang))
+			continue
+		}
+		cat.Names[lang] = strings.TrimSpace(req.Names[lang])
+	}
+
+	if req.ParentID != nil {
+		parent, ok := byID[*req.ParentID]
+		// Walk up from the new parent to make sure the category is not
+		// moved under itself.
+		cycle := false
+		for next := req.ParentID; existing != nil && next != nil; next = byID[*next].ParentID {
+			if *next == existing.ID {
+				cycle = true
+				break
+			}
+		}
+		switch {
+		case !ok:
+			invalid("parent_id", "does not exist")
+		case cycle:
+			invalid("parent_id", "cannot be the category itself or one under it")
+		case cat.Active && !parent.Active:
+			invalid("parent_id", "is inactive")
+		case cat.Type == "":
+			cat.Type = parent.Type
+		case cat.Type != parent.Type:
+			invalid("type", fmt.Sprintf("must be the parent category's, %s", parent.Type))
+		}
+	}
+	if cat.Type == "" {
+		cat.Type = "animal"
+	}
+	if existing != nil {
+		for _, child := range categories {
+			if child.ParentID == nil || *child.ParentID != existing.ID {
+				continue
+			}
+			if child.Type != cat.Type {
+				invalid("type", fmt.Sprintf("must be the subcategories', %s", child.Type))
+				break
+			}
+			if child.Active && !cat.Active {
+				c.Error(apperr.ErrCategoryInUse)
+				return nil, false
+			}
+		}
+	}
+
+	keys := map[string]bool{}
+	cat.Attributes = []models.CategoryAttribute{}
+	for i, a := range req.Attributes {
+		field := fmt.Sprintf("attributes[%d]", i)
+		switch {
+		case !attributeKey.MatchString(a.Key):
+			invalid(field+".key", "must be lowercase letters, digits and underscores, starting with a letter")
+		case keys[a.Key]:
+			invalid(field+".key", "is used by another attribute")
+		}
+		keys[a.Key] = true
+		if a.Type == "enum" && len(a.Options) == 0 {
+			invalid(field+".options", "are required for an enum")
+		} else if a.Type != "enum" && len(a.Options) > 0 {
+			invalid(field+".options", "are only allowed for an enum")
+		}
+		cat.Attributes = append(cat.Attributes, models.CategoryAttribute{
+			Key: a.Key, Label: strings.TrimSpace(a.Label), Type: a.Type, Required: a.Required, Options: a.Options,
+		})
+	}
+
+	if len(problems) > 0 {
+		c.Error(apperr.Fields(problems...))
+		return nil, false
+	}
+	return cat, true
+}
+
+// CreateCategory adds a category, at the top or under a parent.
+func CreateCategory(c *gin.Context) {
+	categories, err := store.Default.AllCategories()
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch categories", err))
+		return
+	}
+	cat, ok := bindCategory(c, categories, nil)
+	if !ok {
+		return
+	}
+
+	if err := store.Default.CreateCategory(cat); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.Error(apperr.Invalid("parent_id", "does not exist"))
+			return
+		}
+		categoryError(c, "Failed to create category", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Category created", IDResponse{ID: cat.ID}))
+}
+
+// UpdateCategory replaces a category's details. Renaming it does not move
+// listings already filed under it, but new listings find it by the new
+// name.
+func UpdateCategory(c *gin.Context) {
+	categoryID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	categories, err := store.Default.AllCategories()
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch categories", err))
+		return
+	}
+	byID := categoriesByID(categories)
+	existing, found := byID[categoryID]
+	if !found {
+		c.Error(apperr.ErrCategoryNotFound)
+		return
+	}
+
+	cat, ok := bindCategory(c, categories, &existing)
+	if !ok {
+		return
+	}
+	if err := store.Default.UpdateCategory(cat); err != nil {
+		categoryError(c, "Failed to update category", err)
+		return
+	}
+	byID[cat.ID] = *cat
+	cat.Label, cat.Schema = cat.Name, categorySchema(byID, cat.ID)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Category updated", cat))
+}
+
+// DeleteCategory deactivates a category, hiding it from the marketplace.
+// Listings and products in it keep it, and it can be restored through
+// UpdateCategory.
+func DeleteCategory(c *gin.Context) {
+	categoryID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := store.Default.DeactivateCategory(categoryID); err != nil {
+		categoryError(c, "Failed to deactivate category", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Category deactivated", nil))
+}
+
+// checkCategory checks that a listing can be filed under the category
+// id: an active category with no active subcategories.
+func checkCategory(c *gin.Context, id int) bool {
+	categories, err := store.Default.AllCategories()
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch categories", err))
+		return false
+	}
+	if cat, found := categoriesByID(categories)[id]; !found || !cat.Active {
+		c.Error(apperr.Invalid("category_id", "does not exist"))
+		return false
+	}
+	for _, other := range categories {
+		if other.Active && other.ParentID != nil && *other.ParentID == id {
+			c.Error(apperr.Invalid("category_id", "has subcategories; file the listing under one of them"))
+			return false
+		}
+	}
+	return true
+}
+
+// checkAttributes checks a listing's attribute values against the schema
+// of the category categoryID, reporting every problem. A listing without a
+// category has no attributes. Values given as null are dropped.
+func checkAttributes(c *gin.Context, categoryID *int, values models.AttributeMap) bool {
+	var schema []models.CategoryAttribute
+	if categoryID != nil {
+		categories, err := store.Default.AllCategories()
+		if err != nil {
+			c.Error(apperr.Internal("Failed to fetch categories", err))
+			return false
+		}
+		schema = categorySchema(categoriesByID(categories), *categoryID)
+	}
+
+	var problems []apperr.FieldError
+	known := map[string]bool{}
+	for _, attr := range schema {
+		known[attr.Key] = true
+		field := "attributes." + attr.Key
+		v, ok := values[attr.Key]
+		if !ok || v == nil {
+			if attr.Required {
+				problems = append(problems, apperr.FieldError{Field: field, Message: "is required"})
+			}
+			continue
+		}
+		if message := attributeProblem(attr, v); message != "" {
+			problems = append(problems, apperr.FieldError{Field: field, Message: message})
+		}
+	}
+
+	var unknown []string
+	for key, v := range values {
+		if v == nil {
+			delete(values, key)
+		} else if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		problems = append(problems, apperr.FieldError{Field: "attributes." + key, Message: "is not an attribute of the category"})
+	}
+
+	if len(problems) > 0 {
+		c.Error(apperr.Fields(problems...))
+		return false
+	}
+	return true
+}
+
+// attributeProblem says what is wrong with v as a value of attr, or returns
+// "" when nothing is.
+func attributeProblem(attr models.CategoryAttribute, v interface{}) string {
+	switch attr.Type {
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return "must be a number"
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return "must be true or false"
+		}
+	case "enum":
+		s, _ := v.(string)
+		for _, option := range attr.Options {
+			if s == option {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(attr.Options, ", ")
+	default:
+		s, ok := v.(string)
+		switch {
+		case !ok || strings.TrimSpace(s) == "":
+			return "must be text"
+		case len(s) > maxAttributeText:
+			return fmt.Sprintf("must be at most %d characters", maxAttributeText)
+		}
+	}
+	return ""
+}
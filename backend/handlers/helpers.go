@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// paramID parses a numeric path parameter. On failure it records a
+// VALIDATION_FAILED error and returns false.
+func paramID(c *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		c.Error(apperr.Invalid(name, "must be an integer"))
+		return 0, false
+	}
+	return id, true
+}
+
+// queryParams parses optional query parameters, collecting a FieldError for
+// every invalid one so a request reports all its problems at once.
+type queryParams struct {
+	c    *gin.Context
+	errs []apperr.FieldError
+}
+
+func (q *queryParams) invalid(name, message string) {
+	q.errs = append(q.errs, apperr.FieldError{Field: name, Message: message})
+}
+
+// number parses a float in [min, max]; it returns nil when the parameter is
+// absent or invalid.
+func (q *queryParams) number(name string, min, max float64) *float64 {
+	raw := q.c.Query(name)
+	if raw == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	switch {
+	case err != nil:
+		q.invalid(name, "must be a number")
+	case v < min:
+		q.invalid(name, "must be at least "+strconv.FormatFloat(min, 'f', -1, 64))
+	case v > max:
+		q.invalid(name, "must be at most "+strconv.FormatFloat(max, 'f', -1, 64))
+	default:
+		return &v
+	}
+	return nil
+}
+
+// integer parses an int no smaller than min.
+func (q *queryParams) integer(name string, min int) *int {
+	raw := q.c.Query(name)
+	if raw == "" {
+		return nil
+	}
+	v, err := strconv.Atoi(raw)
+	switch {
+	case err != nil:
+		q.invalid(name, "must be an integer")
+	case v < min:
+		q.invalid(name, "must be at least "+strconv.Itoa(min))
+	default:
+		return &v
+	}
+	return nil
+}
+
+func (q *queryParams) boolean(name string) bool {
+	raw := q.c.Query(name)
+	if raw == "" {
+		return false
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		q.invalid(name, "must be a boolean")
+	}
+	return v
+}
+
+// date parses a YYYY-MM-DD date; it returns nil when the parameter is
+// absent or invalid.
+func (q *queryParams) date(name string) *time.Time {
+	raw := q.c.Query(name)
+	if raw == "" {
+		return nil
+	}
+	v, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		q.invalid(name, "must be a date (YYYY-MM-DD)")
+		return nil
+	}
+	return &v
+}
+
+// point parses a "latitude,longitude" pair in degrees; it returns nil when
+// the parameter is absent or invalid.
+func (q *queryParams) point(name string) *models.GeoPoint {
+	raw := q.c.Query(name)
+	if raw == "" {
+		return nil
+	}
+	rawLat, rawLng, _ := strings.Cut(raw, ",")
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(rawLat), 64)
+	lng, errLng := strconv.ParseFloat(strings.TrimSpace(rawLng), 64)
+	if errLat != nil || errLng != nil || !(lat >= -90 && lat <= 90) || !(lng >= -180 && lng <= 180) {
+		q.invalid(name, "must be latitude,longitude in degrees")
+		return nil
+	}
+	return &models.GeoPoint{Latitude: lat, Longitude: lng}
+}
+
+// oneOf returns the parameter if it is one of allowed, or "" otherwise.
+func (q *queryParams) oneOf(name string, allowed []string) string {
+	raw := q.c.Query(name)
+	if raw == "" {
+		return ""
+	}
+	for _, a := range allowed {
+		if raw == a {
+			return raw
+		}
+	}
+	q.invalid(name, "must be one of "+strings.Join(allowed, ", "))
+	return ""
+}
+
+// ordered checks that an optional lower bound does not exceed its upper bound.
+func ordered[T int | float64](q *queryParams, name string, lo, hi *T) {
+	if lo != nil && hi != nil && *lo > *hi {
+		q.invalid(name, "must not exceed the maximum")
+	}
+}
+
+// done records the collected errors, if any, and reports whether parsing
+// succeeded.
+func (q *queryParams) done() bool {
+	if len(q.errs) > 0 {
+		q.c.Error(apperr.Fields(q.errs...))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateAnimalRequest replaces every detail of a listing. Stock changes
+// through restock and orders; status through PATCH.
+type UpdateAnimalRequest struct {
+	AnimalType  string  `json:"animal_type" binding:"required"`
+	Breed       string  `json:"breed"`
+	Name        string  `json:"name" binding:"required"`
+	Age         int     `json:"age" binding:"min=0"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price" binding:"required,min=0"`
+	ImageURL    string  `json:"image_url"`
+	Location    string  `json:"location"`
+	Color       string  `json:"color"`
+	Gender      string  `json:"gender"`
+	// CategoryID files the listing under a leaf category; without it the
+	// listing has none.
+	CategoryID *int `json:"category_id" binding:"omitempty,min=1"`
+	// Latitude and Longitude pin the listing; without them it is placed
+	// by geocoding Location.
+	Latitude  *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	// Attributes replace the listing's, as the schema of its category asks.
+	Attributes models.AttributeMap `json:"attributes" binding:"max=30"`
+}
+
+// PatchAnimalRequest changes only the fields present in the body.
+type PatchAnimalRequest struct {
+	AnimalType  *string  `json:"animal_type" binding:"omitempty,min=1"`
+	Breed       *string  `json:"breed"`
+	Name        *string  `json:"name" binding:"omitempty,min=1"`
+	Age         *int     `json:"age" binding:"omitempty,min=0"`
+	Description *string  `json:"description"`
+	Price       *float64 `json:"price" binding:"omitempty,min=0"`
+	ImageURL    *string  `json:"image_url"`
+	Location    *string  `json:"location"`
+	Color       *string  `json:"color"`
+	Gender      *string  `json:"gender"`
+	Status      *string  `json:"status" binding:"omitempty,oneof=available reserved archived"`
+	// CategoryID refiles the listing under a leaf category.
+	CategoryID *int `json:"category_id" binding:"omitempty,min=1"`
+	// Latitude and Longitude pin the listing; a new Location without them
+	// places it by geocoding.
+	Latitude  *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	// Attributes replace the listing's. They are checked against the schema
+	// of its category whenever they or the category change.
+	Attributes models.AttributeMap `json:"attributes" binding:"max=30"`
+}
+
+type RestockRequest struct {
+	Quantity int `json:"quantity" binding:"required,min=1,max=10000"`
+}
+
+// listingError reports a failed seller operation on a listing.
+func listingError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(apperr.ErrAnimalNotFound)
+	case errors.Is(err, store.ErrNotOwner):
+		c.Error(apperr.ErrNotListingOwner)
+	case errors.Is(err, store.ErrInsufficientStock):
+		c.Error(apperr.Invalid("status", "cannot be available without stock; restock first"))
+	default:
+		c.Error(apperr.Internal(message, err))
+	}
+}
+
+// updateAnimal applies u to the listing in the :id path parameter on
+// behalf of the signed-in seller.
+func updateAnimal(c *gin.Context, u store.AnimalUpdate) {
+	userID, _ := c.Get("user_id")
+	animalID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	animal, err := store.Default.UpdateAnimal(animalID, userID.(int), u)
+	if err != nil {
+		listingError(c, "Failed to update animal listing", err)
+		return
+	}
+	signAnimal(animal)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Animal listing updated", animal))
+}
+
+func ReplaceAnimal(c *gin.Context) {
+	var req UpdateAnimalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	at, ok := givenPosition(c, req.Latitude, req.Longitude)
+	if !ok {
+		return
+	}
+	if req.Attributes == nil {
+		req.Attributes = models.AttributeMap{}
+	}
+	if req.CategoryID != nil && !checkCategory(c, *req.CategoryID) {
+		return
+	}
+	if !checkAttributes(c, req.CategoryID, req.Attributes) {
+		return
+	}
+	if at == nil {
+		at = locateListing(c, req.Location)
+	}
+
+	req.ImageURL = storedURL(req.ImageURL)
+	updateAnimal(c, store.AnimalUpdate{
+		AnimalType:  &req.AnimalType,
+		Breed:       &req.Breed,
+		Name:        &req.Name,
+		Age:         &req.Age,
+		Description: &req.Description,
+		Price:       &req.Price,
+		ImageURL:    &req.ImageURL,
+		Location:    &req.Location,
+		Color:       &req.Color,
+		Gender:      &req.Gender,
+		Attributes:  req.Attributes,
+		Refiled:     true,
+		CategoryID:  req.CategoryID,
+		Relocated:   true,
+		Position:    at,
+	})
+}
+
+func PatchAnimal(c *gin.Context) {
+	var req PatchAnimalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	at, ok := givenPosition(c, req.Latitude, req.Longitude)
+	if !ok {
+		return
+	}
+	if req.CategoryID != nil && !checkCategory(c, *req.CategoryID) {
+		return
+	}
+	if req.CategoryID != nil || req.Attributes != nil {
+		if req.Attributes, ok = patchedAttributes(c, req); !ok {
+			return
+		}
+	}
+	relocated := at != nil || req.Location != nil
+	if at == nil && req.Location != nil {
+		at = locateListing(c, *req.Location)
+	}
+
+	if req.ImageURL != nil {
+		*req.ImageURL = storedURL(*req.ImageURL)
+	}
+	updateAnimal(c, store.AnimalUpdate{
+		AnimalType:  req.AnimalType,
+		Breed:       req.Breed,
+		Name:        req.Name,
+		Age:         req.Age,
+		Description: req.Description,
+		Price:       req.Price,
+		ImageURL:    req.ImageURL,
+		Location:    req.Location,
+		Color:       req.Color,
+		Gender:      req.Gender,
+		Status:      req.Status,
+		Attributes:  req.Attributes,
+		Refiled:     req.CategoryID != nil,
+		CategoryID:  req.CategoryID,
+		Relocated:   relocated,
+		Position:    at,
+	})
+}
+
+// patchedAttributes checks the attributes a listing has after req against
+// the schema of the category it is filed under after req, and returns them.
+func patchedAttributes(c *gin.Context, req PatchAnimalRequest) (models.AttributeMap, bool) {
+	userID, _ := c.Get("user_id")
+	animalID, ok := paramID(c, "id")
+	if !ok {
+		return nil, false
+	}
+	animal, err := store.Default.GetAnimal(animalID)
+	if err == nil && animal.SellerID != userID.(int) {
+		err = store.ErrNotOwner
+	}
+	if err != nil {
+		listingError(c, "Failed to fetch animal listing", err)
+		return nil, false
+	}
+
+	categoryID, values := animal.CategoryID, animal.Attributes
+	if req.CategoryID != nil {
+		categoryID = req.CategoryID
+	}
+	if req.Attributes != nil {
+		values = req.Attributes
+	}
+	if values == nil {
+		values = models.AttributeMap{}
+	}
+	return values, checkAttributes(c, categoryID, values)
+}
+
+func DeleteAnimal(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	animalID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := store.Default.DeleteAnimal(animalID, userID.(int)); err != nil {
+		listingError(c, "Failed to delete animal listing", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Animal listing deleted", nil))
+}
+
+func RestockAnimal(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	animalID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req RestockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	animal, err := store.Default.RestockAnimal(animalID, userID.(int), req.Quantity)
+	if err != nil {
+		listingError(c, "Failed to restock animal listing", err)
+		return
+	}
+	signAnimal(animal)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Animal listing restocked", animal))
+}
+
+// sellerListingsPage is the page GetMyListings returns.
+type sellerListingsPage struct {
+	utils.PaginatedResponse
+	Counts *models.ListingCounts `json:"counts"`
+}
+
+// GetMyListings lists the signed-in seller's listings with a count per
+// status, so the tabs of the seller screen can show them without another
+// request.
+func GetMyListings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	q, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+	params := &queryParams{c: c}
+	status := params.oneOf("status", store.ListingStatuses)
+	if !params.done() {
+		return
+	}
+
+	listings, err := store.Default.ListSellerAnimals(userID.(int), status, q.PageRequest)
+	if err != nil {
+		listError(c, "Failed to fetch listings", err)
+		return
+	}
+	counts, err := store.Default.CountSellerAnimals(userID.(int))
+	if err != nil {
+		c.Error(apperr.Internal("Failed to count listings", err))
+		return
+	}
+
+	signEach(listings.Items, signAnimal)
+	c.JSON(http.StatusOK, sellerListingsPage{
+		PaginatedResponse: pageResponse("Listings retrieved", q, listings),
+		Counts:            counts,
+	})
+}
+
+func GetPriceHistory(c *gin.Context) {
+	animalID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	history, err := store.Default.ListPriceHistory(animalID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrAnimalNotFound)
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch price history", err))
+		return
+	}
+	if history == nil {
+		history = []models.PriceChange{}
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Price history retrieved", history))
+}
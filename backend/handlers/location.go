@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/geo"
+	"github.com/TerraPaw/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// givenPosition returns the point a request sets with its latitude and
+// longitude fields, or nil when it sets neither. The two go together: ok
+// is false, with the error recorded, when only one is set.
+func givenPosition(c *gin.Context, lat, lng *float64) (p *models.GeoPoint, ok bool) {
+	switch {
+	case lat == nil && lng == nil:
+		return nil, true
+	case lat == nil:
+		c.Error(apperr.Invalid("latitude", "is required with longitude"))
+		return nil, false
+	case lng == nil:
+		c.Error(apperr.Invalid("longitude", "is required with latitude"))
+		return nil, false
+	}
+	return &models.GeoPoint{Latitude: *lat, Longitude: *lng}, true
+}
+
+// locateListing geocodes a listing's location, or returns nil when the
+// geocoder cannot place it. A failing geocoder only leaves the listing off
+// distance searches until jobs.LocateListings retries it, so it is logged
+// rather than reported.
+func locateListing(c *gin.Context, location string) *models.GeoPoint {
+	if strings.TrimSpace(location) == "" {
+		return nil
+	}
+	p, err := geo.Default.Geocode(c.Request.Context(), location)
+	if err != nil {
+		if !errors.Is(err, geo.ErrUnknownPlace) {
+			log.Printf("Failed to geocode %q: %v", location, err)
+		}
+		return nil
+	}
+	return p
+}
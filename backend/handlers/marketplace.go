@@ -1,13 +1,15 @@
 package handlers
 
 import (
-	"database/sql"
-	"fmt"
+	"errors"
+	"log"
+	"math"
 	"net/http"
-	"strconv"
+	"strings"
 
-	"github.com/TerraPaw/backend/db"
+	"github.com/TerraPaw/backend/apperr"
 	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/store"
 	"github.com/TerraPaw/backend/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -24,198 +26,164 @@ type CreateAnimalRequest struct {
 	Color       string  `json:"color"`
 	Gender      string  `json:"gender"`
 	Stock       int     `json:"stock"`
+	// CategoryID files the listing under a leaf category.
+	CategoryID *int `json:"category_id" binding:"omitempty,min=1"`
+	// Latitude and Longitude pin the listing; without them it is placed
+	// by geocoding Location.
+	Latitude  *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	// Attributes are the values the schema of the category asks for, such
+	// as a reptile's morph.
+	Attributes models.AttributeMap `json:"attributes" binding:"max=30"`
 }
 
+// CreateOrderRequest buys a listing or a product variant outright.
 type CreateOrderRequest struct {
-	AnimalID int `json:"animal_id" binding:"required"`
-	Quantity int `json:"quantity"`
-}
-
-type CreateReviewRequest struct {
-	OrderID  *int   `json:"order_id"`
-	AnimalID int    `json:"animal_id" binding:"required"`
-	Rating   int    `json:"rating" binding:"required,min=1,max=5"`
-	Comment  string `json:"comment"`
-	ImageURL string `json:"image_url"`
+	AnimalID  int `json:"animal_id" binding:"omitempty,min=1"`
+	VariantID int `json:"variant_id" binding:"omitempty,min=1"`
+	Quantity  int `json:"quantity"`
 }
 
 type AddWishlistRequest struct {
 	AnimalID int `json:"animal_id" binding:"required"`
 }
 
-type Category struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
-	Icon string `json:"icon"`
-	Type string `json:"type"`
+// Distance search radius, in km, when near is given without radius_km, and
+// the largest one accepted.
+const (
+	defaultRadiusKm = 50
+	maxRadiusKm     = 2000
+)
+
+// animalsPage is the page GetAnimals returns, with the facet counts when
+// they are asked for.
+type animalsPage struct {
+	utils.PaginatedResponse
+	Facets *models.AnimalFacets `json:"facets,omitempty"`
 }
 
-func GetCategories(c *gin.Context) {
-	rows, err := db.DB.Query("SELECT id, name, icon, type FROM categories WHERE is_active = TRUE ORDER BY id ASC")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch categories", err.Error()))
+func GetAnimals(c *gin.Context) {
+	q, ok := pageQuery(c)
+	if !ok {
 		return
 	}
-	defer rows.Close()
 
-	var categories []Category
-	for rows.Next() {
-		var cat Category
-		if err := rows.Scan(&cat.ID, &cat.Name, &cat.Icon, &cat.Type); err == nil {
-			categories = append(categories, cat)
+	params := &queryParams{c: c}
+	filter := store.AnimalFilter{
+		AnimalType: c.Query("animal_type"),
+		Search:     c.Query("search"),
+		Breed:      c.Query("breed"),
+		Gender:     c.Query("gender"),
+		Color:      c.Query("color"),
+		Location:   c.Query("location"),
+		MinPrice:   params.number("min_price", 0, math.MaxFloat64),
+		MaxPrice:   params.number("max_price", 0, math.MaxFloat64),
+		MinAge:     params.integer("min_age", 0),
+		MaxAge:     params.integer("max_age", 0),
+		MinRating:  params.number("min_rating", 0, 5),
+		InStock:    params.boolean("in_stock"),
+		Near:       params.point("near"),
+		RadiusKm:   defaultRadiusKm,
+		Sort:       params.oneOf("sort", store.AnimalSorts),
+	}
+	radius := params.number("radius_km", 0.1, maxRadiusKm)
+	withFacets := params.boolean("facets")
+	if id := params.integer("seller_id", 1); id != nil {
+		filter.SellerID = *id
+	}
+	if id := params.integer("category_id", 1); id != nil {
+		filter.CategoryID = *id
+	}
+	switch {
+	case filter.Sort == "" && filter.Search != "":
+		filter.Sort = "relevance"
+	case filter.Sort == "" && filter.Near != nil:
+		filter.Sort = "distance"
+	case filter.Sort == "relevance" && filter.Search == "":
+		params.invalid("sort", "relevance requires search")
+	case filter.Sort == "distance" && filter.Near == nil:
+		params.invalid("sort", "distance requires near")
+	}
+	if radius != nil {
+		filter.RadiusKm = *radius
+		if filter.Near == nil {
+			params.invalid("radius_km", "requires near")
 		}
 	}
-
-	// Fallback if empty (should not happen after seed)
-	if len(categories) == 0 {
-		categories = []Category{}
-	}
-
-	c.JSON(http.StatusOK, utils.SuccessResponse("Categories retrieved", categories))
-}
-
-func GetAnimals(c *gin.Context) {
-	animalType := c.Query("animal_type")
-	searchQuery := c.Query("search")
-	breed := c.Query("breed")
-	minPriceStr := c.Query("min_price")
-	maxPriceStr := c.Query("max_price")
-	sortBy := c.Query("sort") // price_asc, price_desc, newest, oldest
-
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset := (page - 1) * limit
-
-	var rows *sql.Rows
-	var err error
-
-	query := `SELECT a.id, a.seller_id, a.animal_type, COALESCE(a.breed, ''), a.name, a.age, COALESCE(a.description, ''), 
-	                 a.price, COALESCE(a.image_url, ''), COALESCE(a.location, ''), a.rating, a.status, 
-                     COALESCE(a.color, ''), COALESCE(a.gender, ''), COALESCE(a.stock, 0),
-                     a.created_at, a.updated_at,
-	                 u.id, u.username, u.email, u.fullname, COALESCE(u.avatar_url, ''), COALESCE(u.bio, '')
-	          FROM animals a
-	          LEFT JOIN users u ON a.seller_id = u.id
-	          WHERE a.status = 'available'`
-
-	if animalType != "" {
-		query += fmt.Sprintf(" AND a.animal_type ILIKE '%%%s%%'", animalType)
-	}
-
-	if searchQuery != "" {
-		query += fmt.Sprintf(" AND (a.name ILIKE '%%%s%%' OR a.breed ILIKE '%%%s%%')", searchQuery, searchQuery)
+	ordered(params, "min_price", filter.MinPrice, filter.MaxPrice)
+	ordered(params, "min_age", filter.MinAge, filter.MaxAge)
+	if !params.done() {
+		return
 	}
 
-	if breed != "" {
-		query += fmt.Sprintf(" AND a.breed ILIKE '%%%s%%'", breed)
+	animals, err := store.Default.ListAnimals(filter, q.PageRequest)
+	if err != nil {
+		listError(c, "Failed to fetch animals", err)
+		return
 	}
 
-	if minPriceStr != "" {
-		fmt.Println("Received min_price:", minPriceStr)
-		if minPrice, err := strconv.ParseFloat(minPriceStr, 64); err == nil {
-			query += fmt.Sprintf(" AND a.price >= %f", minPrice)
-		} else {
-			fmt.Println("Error parsing min_price:", err)
+	signEach(animals.Items, signAnimal)
+	resp := animalsPage{PaginatedResponse: pageResponse("Animals retrieved", q, animals)}
+	if withFacets {
+		facets, err := store.Default.AnimalFacets(filter)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to count facets", err))
+			return
 		}
+		resp.Facets = facets
 	}
+	c.JSON(http.StatusOK, resp)
+}
 
-	if maxPriceStr != "" {
-		fmt.Println("Received max_price:", maxPriceStr)
-		if maxPrice, err := strconv.ParseFloat(maxPriceStr, 64); err == nil {
-			query += fmt.Sprintf(" AND a.price <= %f", maxPrice)
-		} else {
-			fmt.Println("Error parsing max_price:", err)
-		}
+// SuggestSearch returns autocomplete entries for a partially typed search.
+func SuggestSearch(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	params := &queryParams{c: c}
+	switch {
+	case q == "":
+		params.invalid("q", "is required")
+	case len(q) > 100:
+		params.invalid("q", "must be at most 100 characters")
+	}
+	limit := params.integer("limit", 1)
+	if !params.done() {
+		return
 	}
-
-	fmt.Println("Executing Query:", query)
-
-	orderBy := "a.created_at DESC"
-	switch sortBy {
-	case "price_asc":
-		orderBy = "a.price ASC"
-	case "price_desc":
-		orderBy = "a.price DESC"
-	case "oldest":
-		orderBy = "a.created_at ASC"
-	case "newest":
-		orderBy = "a.created_at DESC"
+	n := 10
+	if limit != nil {
+		n = min(*limit, 20)
 	}
 
-	query += fmt.Sprintf(" ORDER BY %s LIMIT %d OFFSET %d", orderBy, limit, offset)
-
-	rows, err = db.DB.Query(query)
+	suggestions, err := store.Default.SuggestAnimals(q, n)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch animals", err.Error()))
+		c.Error(apperr.Internal("Failed to fetch suggestions", err))
 		return
 	}
-	defer rows.Close()
-
-	var animals []models.Animal
-	for rows.Next() {
-		var animal models.Animal
-		var seller models.User
-		err := rows.Scan(
-			&animal.ID, &animal.SellerID, &animal.AnimalType, &animal.Breed, &animal.Name, &animal.Age,
-			&animal.Description, &animal.Price, &animal.ImageURL, &animal.Location, &animal.Rating, &animal.Status,
-			&animal.Color, &animal.Gender, &animal.Stock,
-			&animal.CreatedAt, &animal.UpdatedAt,
-			&seller.ID, &seller.Username, &seller.Email, &seller.FullName, &seller.AvatarURL, &seller.Bio,
-		)
-		if err == nil {
-			animal.Seller = &seller
-			animals = append(animals, animal)
-		}
-	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse("Animals retrieved", animals))
+	c.JSON(http.StatusOK, utils.SuccessResponse("Suggestions retrieved", suggestions))
 }
 
 func GetAnimal(c *gin.Context) {
-	id := c.Param("id")
-	animalID, _ := strconv.Atoi(id)
-
-	var animal models.Animal
-	var seller models.User
-
-	err := db.DB.QueryRow(
-		`SELECT a.id, a.seller_id, a.animal_type, COALESCE(a.breed, ''), a.name, a.age, COALESCE(a.description, ''), 
-		        a.price, COALESCE(a.image_url, ''), COALESCE(a.location, ''), a.rating, a.status, 
-                COALESCE(a.color, ''), COALESCE(a.gender, ''), COALESCE(a.stock, 0),
-                a.created_at, a.updated_at,
-		        u.id, u.username, u.email, u.fullname, COALESCE(u.avatar_url, ''), COALESCE(u.bio, '')
-		FROM animals a
-		LEFT JOIN users u ON a.seller_id = u.id
-		WHERE a.id = $1`,
-		animalID,
-	).Scan(
-		&animal.ID, &animal.SellerID, &animal.AnimalType, &animal.Breed, &animal.Name, &animal.Age,
-		&animal.Description, &animal.Price, &animal.ImageURL, &animal.Location, &animal.Rating, &animal.Status,
-		&animal.Color, &animal.Gender, &animal.Stock,
-		&animal.CreatedAt, &animal.UpdatedAt,
-		&seller.ID, &seller.Username, &seller.Email, &seller.FullName, &seller.AvatarURL, &seller.Bio,
-	)
+	animalID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
 
+	animal, err := store.Default.GetAnimal(animalID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrAnimalNotFound)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, utils.ErrorResponse("Animal not found", ""))
+		c.Error(apperr.Internal("Failed to fetch animal", err))
 		return
 	}
-
-	// Fetch Media
-	var mediaList []models.AnimalMedia
-	mediaRows, err := db.DB.Query("SELECT id, animal_id, media_url, media_type, COALESCE(thumbnail_url, ''), sort_order FROM animal_media WHERE animal_id = $1 ORDER BY sort_order ASC", animal.ID)
-	if err == nil {
-		defer mediaRows.Close()
-		for mediaRows.Next() {
-			var m models.AnimalMedia
-			if err := mediaRows.Scan(&m.ID, &m.AnimalID, &m.MediaURL, &m.MediaType, &m.ThumbnailURL, &m.SortOrder); err == nil {
-				mediaList = append(mediaList, m)
-			}
-		}
+	// A lost view only skews the seller's analytics, so it is not an error.
+	if err := store.Default.RecordAnimalView(animalID); err != nil {
+		log.Printf("Failed to record view of animal %d: %v", animalID, err)
 	}
-	animal.Media = mediaList
+	signAnimal(animal)
 
-	animal.Seller = &seller
 	c.JSON(http.StatusOK, utils.SuccessResponse("Animal details", animal))
 }
 
@@ -223,7 +191,7 @@ func CreateAnimal(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	var req CreateAnimalRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request", err.Error()))
+		c.Error(apperr.Validation(err))
 		return
 	}
 
@@ -231,107 +199,105 @@ func CreateAnimal(c *gin.Context) {
 	if req.Stock <= 0 {
 		req.Stock = 1
 	}
-
-	var animalID int
-	err := db.DB.QueryRow(
-		`INSERT INTO animals (seller_id, animal_type, breed, name, age, description, price, image_url, location, status, color, gender, stock)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'available', $10, $11, $12) RETURNING id`,
-		userID, req.AnimalType, req.Breed, req.Name, req.Age, req.Description, req.Price, req.ImageURL, req.Location, req.Color, req.Gender, req.Stock,
-	).Scan(&animalID)
-
+	at, ok := givenPosition(c, req.Latitude, req.Longitude)
+	if !ok {
+		return
+	}
+	if req.CategoryID != nil && !checkCategory(c, *req.CategoryID) {
+		return
+	}
+	if !checkAttributes(c, req.CategoryID, req.Attributes) {
+		return
+	}
+	if at == nil {
+		at = locateListing(c, req.Location)
+	}
+
+	animal := &models.Animal{
+		SellerID:    userID.(int),
+		AnimalType:  req.AnimalType,
+		CategoryID:  req.CategoryID,
+		Breed:       req.Breed,
+		Name:        req.Name,
+		Age:         req.Age,
+		Description: req.Description,
+		Price:       req.Price,
+		ImageURL:    storedURL(req.ImageURL),
+		Location:    req.Location,
+		Color:       req.Color,
+		Gender:      req.Gender,
+		Stock:       req.Stock,
+		Attributes:  req.Attributes,
+	}
+	if at != nil {
+		animal.Latitude, animal.Longitude = &at.Latitude, &at.Longitude
+	}
+	animalID, err := store.Default.CreateAnimal(animal)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to create animal listing", err.Error()))
+		c.Error(apperr.Internal("Failed to create animal listing", err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, utils.SuccessResponse("Animal listing created", gin.H{"id": animalID}))
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Animal listing created", IDResponse{ID: animalID}))
 }
 
 func CreateOrder(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	var req CreateOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request", err.Error()))
+		c.Error(apperr.Validation(err))
 		return
 	}
 
-	if req.Quantity <= 0 {
-		req.Quantity = 1
-	}
-
-	// Get animal price and stock
-	var price float64
-	var stock int
-	err := db.DB.QueryRow("SELECT price, stock FROM animals WHERE id = $1", req.AnimalID).Scan(&price, &stock)
-	if err != nil {
-		c.JSON(http.StatusNotFound, utils.ErrorResponse("Animal not found", ""))
+	if !itemChosen(c, req.AnimalID, req.VariantID) {
 		return
 	}
-
-	// Validate stock
-	if stock < req.Quantity {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("Insufficient stock", ""))
-		return
+	if req.Quantity <= 0 {
+		req.Quantity = 1
 	}
 
-	// Create order
+	missing, unavailable := apperr.ErrAnimalNotFound, apperr.ErrAnimalUnavailable
 	var orderID int
-	err = db.DB.QueryRow(
-		"INSERT INTO orders (buyer_id, animal_id, total_price, status, quantity) VALUES ($1, $2, $3, 'pending', $4) RETURNING id",
-		userID, req.AnimalID, price*float64(req.Quantity), req.Quantity,
-	).Scan(&orderID)
-
+	var err error
+	if req.VariantID != 0 {
+		missing, unavailable = apperr.ErrVariantNotFound, apperr.ErrProductUnavailable
+		orderID, err = store.Default.CreateVariantOrder(userID.(int), req.VariantID, req.Quantity)
+	} else {
+		orderID, err = store.Default.CreateOrder(userID.(int), req.AnimalID, req.Quantity)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to create order", err.Error()))
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.Error(missing)
+		case errors.Is(err, store.ErrInsufficientStock):
+			c.Error(apperr.ErrInsufficientStock)
+		case errors.Is(err, store.ErrUnavailable):
+			c.Error(unavailable)
+		default:
+			c.Error(apperr.Internal("Failed to create order", err))
+		}
 		return
 	}
 
-	// Update animal status and stock
-	if stock-req.Quantity == 0 {
-		db.DB.Exec("UPDATE animals SET status = 'sold', stock = stock - $1 WHERE id = $2", req.Quantity, req.AnimalID)
-	} else {
-		db.DB.Exec("UPDATE animals SET stock = stock - $1 WHERE id = $2", req.Quantity, req.AnimalID)
-	}
-
-	c.JSON(http.StatusCreated, utils.SuccessResponse("Order created", gin.H{"id": orderID}))
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Order created", IDResponse{ID: orderID}))
 }
 
 func GetOrders(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
-	rows, err := db.DB.Query(
-		`SELECT o.id, o.buyer_id, o.animal_id, o.total_price, o.status, o.quantity, o.created_at,
-		        a.id, a.seller_id, a.animal_type, COALESCE(a.breed, ''), a.name, a.price, COALESCE(a.image_url, '')
-		FROM orders o
-		LEFT JOIN animals a ON o.animal_id = a.id
-		WHERE o.buyer_id = $1
-		ORDER BY o.created_at DESC`,
-		userID,
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch orders", err.Error()))
+	q, ok := pageQuery(c)
+	if !ok {
 		return
 	}
-	defer rows.Close()
-
-	var orders []models.Order
-	for rows.Next() {
-		var order models.Order
-		var animal models.Animal
-		// Handle quantity scan if it exists in DB, otherwise use default
-		var quantity int
-		err := rows.Scan(
-			&order.ID, &order.BuyerID, &order.AnimalID, &order.TotalPrice, &order.Status, &quantity, &order.CreatedAt,
-			&animal.ID, &animal.SellerID, &animal.AnimalType, &animal.Breed, &animal.Name, &animal.Price, &animal.ImageURL,
-		)
-		if err == nil {
-			order.Quantity = quantity
-			order.Animal = &animal
-			orders = append(orders, order)
-		}
+
+	orders, err := store.Default.ListOrders(userID.(int), q.PageRequest)
+	if err != nil {
+		listError(c, "Failed to fetch orders", err)
+		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse("Orders retrieved", orders))
+	signEach(orders.Items, signOrder)
+	respondPage(c, "Orders retrieved", q, orders)
 }
 
 // Wishlist Handlers
@@ -340,13 +306,17 @@ func AddToWishlist(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	var req AddWishlistRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request", err.Error()))
+		c.Error(apperr.Validation(err))
 		return
 	}
 
-	_, err := db.DB.Exec("INSERT INTO wishlists (user_id, animal_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", userID, req.AnimalID)
+	err := store.Default.AddToWishlist(userID.(int), req.AnimalID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrAnimalNotFound)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to add to wishlist", err.Error()))
+		c.Error(apperr.Internal("Failed to add to wishlist", err))
 		return
 	}
 
@@ -355,111 +325,34 @@ func AddToWishlist(c *gin.Context) {
 
 func RemoveFromWishlist(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	animalID := c.Param("id")
-
-	_, err := db.DB.Exec("DELETE FROM wishlists WHERE user_id = $1 AND animal_id = $2", userID, animalID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to remove from wishlist", err.Error()))
+	animalID, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse("Removed from wishlist", nil))
-}
-
-func GetWishlist(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-
-	rows, err := db.DB.Query(`
-		SELECT w.id, w.animal_id, w.created_at,
-		       a.animal_type, a.name, a.price, COALESCE(a.image_url, ''), a.status, a.color, a.gender, a.stock
-		FROM wishlists w
-		JOIN animals a ON w.animal_id = a.id
-		WHERE w.user_id = $1
-		ORDER BY w.created_at DESC
-	`, userID)
+	err := store.Default.RemoveFromWishlist(userID.(int), animalID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch wishlist", err.Error()))
+		c.Error(apperr.Internal("Failed to remove from wishlist", err))
 		return
 	}
-	defer rows.Close()
-
-	var wishlist []models.Wishlist
-	for rows.Next() {
-		var w models.Wishlist
-		var a models.Animal
-		err := rows.Scan(&w.ID, &w.AnimalID, &w.CreatedAt,
-			&a.AnimalType, &a.Name, &a.Price, &a.ImageURL, &a.Status, &a.Color, &a.Gender, &a.Stock)
-		if err == nil {
-			w.Animal = &a
-			w.UserID = userID.(int)
-			wishlist = append(wishlist, w)
-		}
-	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse("Wishlist retrieved", wishlist))
+	c.JSON(http.StatusOK, utils.SuccessResponse("Removed from wishlist", nil))
 }
 
-// Review Handlers
-
-func CreateReview(c *gin.Context) {
+func GetWishlist(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	var req CreateReviewRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request", err.Error()))
-		return
-	}
-
-	// Check if order exists (optional validation if order_id provided)
-	if req.OrderID != nil {
-		var status string
-		err := db.DB.QueryRow("SELECT status FROM orders WHERE id = $1 AND buyer_id = $2", req.OrderID, userID).Scan(&status)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse("Order not found or not owned by user", ""))
-			return
-		}
-	}
 
-	_, err := db.DB.Exec(`
-        INSERT INTO reviews (user_id, order_id, animal_id, rating, comment, image_url)
-        VALUES ($1, $2, $3, $4, $5, $6)
-    `, userID, req.OrderID, req.AnimalID, req.Rating, req.Comment, req.ImageURL)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to submit review", err.Error()))
+	q, ok := pageQuery(c)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusCreated, utils.SuccessResponse("Review submitted", nil))
-}
-
-func GetReviews(c *gin.Context) {
-	animalID := c.Param("id")
-
-	rows, err := db.DB.Query(`
-        SELECT r.id, r.user_id, r.rating, r.comment, COALESCE(r.image_url, ''), r.created_at,
-               u.username, u.fullname, COALESCE(u.avatar_url, '')
-        FROM reviews r
-        JOIN users u ON r.user_id = u.id
-        WHERE r.animal_id = $1
-        ORDER BY r.created_at DESC
-    `, animalID)
+	wishlist, err := store.Default.ListWishlist(userID.(int), q.PageRequest)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch reviews", err.Error()))
+		listError(c, "Failed to fetch wishlist", err)
 		return
 	}
-	defer rows.Close()
-
-	var reviews []models.Review
-	for rows.Next() {
-		var r models.Review
-		var u models.User
-		err := rows.Scan(&r.ID, &r.UserID, &r.Rating, &r.Comment, &r.ImageURL, &r.CreatedAt,
-			&u.Username, &u.FullName, &u.AvatarURL)
-		if err == nil {
-			r.User = &u
-			reviews = append(reviews, r)
-		}
-	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse("Reviews retrieved", reviews))
+	signEach(wishlist.Items, signWishlist)
+	respondPage(c, "Wishlist retrieved", q, wishlist)
 }
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/config"
+	"github.com/TerraPaw/backend/media"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/storage"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// UploadKinds are the accepted values of UploadMedia's kind parameter. The
+// kind is the first segment of the stored keys. Listing photos go through
+// AddAnimalMedia instead.
+var UploadKinds = []string{"post", "pet", "review"}
+
+// signURL turns a stored media reference into a link clients can load.
+// External URLs are returned unchanged.
+func signURL(ref string) string {
+	if storage.DefaultSigner == nil {
+		return ref
+	}
+	return storage.DefaultSigner.Sign(ref)
+}
+
+// storedURL is the inverse of signURL for URLs clients send back: a signed
+// link is saved as its stable reference so it does not expire in the
+// database.
+func storedURL(url string) string {
+	if key, ok := storage.KeyOf(url); ok {
+		return storage.Ref(key)
+	}
+	return url
+}
+
+func signAnimal(a *models.Animal) {
+	a.ImageURL = signURL(a.ImageURL)
+	for i := range a.Media {
+		a.Media[i].MediaURL = signURL(a.Media[i].MediaURL)
+		a.Media[i].ThumbnailURL = signURL(a.Media[i].ThumbnailURL)
+	}
+}
+
+func signPost(p *models.Post) {
+	p.ImageURL = signURL(p.ImageURL)
+	for i := range p.Media {
+		p.Media[i].MediaURL = signURL(p.Media[i].MediaURL)
+	}
+}
+
+func signPet(p *models.UserPet)   { p.ImageURL = signURL(p.ImageURL) }
+func signReview(r *models.Review) { r.ImageURL = signURL(r.ImageURL) }
+
+func signOrder(o *models.Order) {
+	if o.Animal != nil {
+		signAnimal(o.Animal)
+	}
+	for i := range o.Items {
+		if o.Items[i].Animal != nil {
+			signAnimal(o.Items[i].Animal)
+		}
+		if o.Items[i].Variant != nil {
+			o.Items[i].Variant.ImageURL = signURL(o.Items[i].Variant.ImageURL)
+		}
+	}
+	for i := range o.SubOrders {
+		signOrder(&o.SubOrders[i])
+	}
+}
+
+func signCart(c *models.Cart) {
+	for i := range c.Items {
+		if c.Items[i].Animal != nil {
+			signAnimal(c.Items[i].Animal)
+		}
+		if c.Items[i].Variant != nil {
+			c.Items[i].Variant.ImageURL = signURL(c.Items[i].Variant.ImageURL)
+		}
+	}
+}
+
+func signWishlist(w *models.Wishlist) {
+	if w.Animal != nil {
+		signAnimal(w.Animal)
+	}
+}
+
+func signRecommendation(r *models.Recommendation) {
+	signAnimal(r.Animal)
+}
+
+// signEach applies sign to every item of a page before it is written.
+func signEach[T any](items []T, sign func(*T)) {
+	for i := range items {
+		sign(&items[i])
+	}
+}
+
+// readImage reads the "file" field of a multipart upload and processes it.
+// On failure it records the error and returns false.
+func readImage(c *gin.Context) (*media.Image, bool) {
+	limit := config.LoadConfig().MaxUploadBytes
+	// Leave room for the multipart framing around the file.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1<<20)
+
+	header, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.Error(apperr.ErrFileTooLarge)
+		return nil, false
+	case err != nil:
+		c.Error(apperr.Invalid("file", "is required"))
+		return nil, false
+	case header.Size > limit:
+		c.Error(apperr.ErrFileTooLarge)
+		return nil, false
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		c.Error(apperr.Internal("Failed to read upload", err))
+		return nil, false
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, limit))
+	if err != nil {
+		c.Error(apperr.Internal("Failed to read upload", err))
+		return nil, false
+	}
+
+	img, err := media.Process(data)
+	switch {
+	case errors.Is(err, media.ErrUnsupportedType):
+		c.Error(apperr.ErrUnsupportedMedia)
+	case errors.Is(err, media.ErrInvalidImage):
+		c.Error(apperr.Invalid("file", "is not a valid image"))
+	case errors.Is(err, media.ErrTooManyPixels):
+		c.Error(apperr.Invalid("file", fmt.Sprintf("must be at most %d megapixels", media.MaxPixels/1_000_000)))
+	case err != nil:
+		c.Error(apperr.Internal("Failed to process upload", err))
+	default:
+		return img, true
+	}
+	return nil, false
+}
+
+// storeImage saves img and its thumbnail under kind/YYYY/MM/ with a random
+// name. On failure it records the error and returns false.
+func storeImage(c *gin.Context, kind string, img *media.Image) (UploadResponse, bool) {
+	var name [16]byte
+	if _, err := rand.Read(name[:]); err != nil {
+		c.Error(apperr.Internal("Failed to store upload", err))
+		return UploadResponse{}, false
+	}
+	base := kind + "/" + time.Now().UTC().Format("2006/01") + "/" + hex.EncodeToString(name[:])
+	key, thumbKey := base+img.Ext, base+"_thumb.jpg"
+
+	ctx := c.Request.Context()
+	if err := storage.Default.Put(ctx, key, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType); err != nil {
+		c.Error(apperr.Internal("Failed to store upload", err))
+		return UploadResponse{}, false
+	}
+	if err := storage.Default.Put(ctx, thumbKey, bytes.NewReader(img.Thumbnail), int64(len(img.Thumbnail)), "image/jpeg"); err != nil {
+		storage.Default.Delete(ctx, key)
+		c.Error(apperr.Internal("Failed to store upload", err))
+		return UploadResponse{}, false
+	}
+
+	return UploadResponse{
+		Ref:          storage.Ref(key),
+		URL:          signURL(storage.Ref(key)),
+		ThumbnailRef: storage.Ref(thumbKey),
+		ThumbnailURL: signURL(storage.Ref(thumbKey)),
+		ContentType:  img.ContentType,
+		Size:         len(img.Data),
+		Width:        img.Width,
+		Height:       img.Height,
+	}, true
+}
+
+// discard removes the files of an upload that could not be attached.
+func discard(ctx context.Context, up UploadResponse) {
+	for _, ref := range []string{up.Ref, up.ThumbnailRef} {
+		if key, ok := storage.KeyOf(ref); ok {
+			storage.Default.Delete(ctx, key)
+		}
+	}
+}
+
+// UploadMedia stores an image for a post, pet or review. The returned ref
+// goes into the resource's media_url or image_url when it is created.
+func UploadMedia(c *gin.Context) {
+	params := &queryParams{c: c}
+	kind := params.oneOf("kind", UploadKinds)
+	if kind == "" && c.Query("kind") == "" {
+		params.invalid("kind", "is required")
+	}
+	if !params.done() {
+		return
+	}
+
+	img, ok := readImage(c)
+	if !ok {
+		return
+	}
+	up, ok := storeImage(c, kind, img)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse("File uploaded", up))
+}
+
+// AddAnimalMedia uploads a photo to a listing's gallery, with a thumbnail.
+// The first photo also becomes the listing's main image.
+func AddAnimalMedia(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	animalID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	// Check ownership before spending time on the image.
+	animal, err := store.Default.GetAnimal(animalID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(apperr.ErrAnimalNotFound)
+		return
+	case err != nil:
+		c.Error(apperr.Internal("Failed to fetch animal", err))
+		return
+	case animal.SellerID != userID.(int):
+		c.Error(apperr.ErrNotListingOwner)
+		return
+	}
+
+	img, ok := readImage(c)
+	if !ok {
+		return
+	}
+	up, ok := storeImage(c, "animal", img)
+	if !ok {
+		return
+	}
+
+	_, err = store.Default.AddAnimalMedia(animalID, userID.(int), &models.AnimalMedia{
+		MediaURL:     up.Ref,
+		MediaType:    "image",
+		ThumbnailURL: up.ThumbnailRef,
+	})
+	if err != nil {
+		discard(c.Request.Context(), up)
+		listingError(c, "Failed to add listing photo", err)
+		return
+	}
+
+	animal, err = store.Default.GetAnimal(animalID)
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch animal", err))
+		return
+	}
+	signAnimal(animal)
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Photo added", animal))
+}
+
+// ServeMedia streams an uploaded file to holders of a valid signed link.
+func ServeMedia(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if storage.DefaultSigner == nil || !storage.DefaultSigner.Verify(key, c.Query("expires"), c.Query("signature")) {
+		c.Error(apperr.ErrMediaLinkInvalid)
+		return
+	}
+
+	body, obj, err := storage.Default.Get(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.Error(apperr.ErrMediaNotFound)
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Internal("Failed to read media", err))
+		return
+	}
+	defer body.Close()
+
+	// The link is only valid until it expires, so caches may keep the file
+	// that long and no longer.
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+	maxAge := max(0, expires-time.Now().Unix())
+	c.DataFromReader(http.StatusOK, obj.Size, obj.ContentType, body, map[string]string{
+		"Cache-Control":          "private, max-age=" + strconv.FormatInt(maxAge, 10),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
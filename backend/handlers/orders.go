@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateOrderStatusRequest moves an order along its lifecycle. Whether the
+// change is allowed depends on the order's status and on whether the
+// caller is its buyer or seller.
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=paid processing shipped delivered completed cancelled refunded"`
+	Note   string `json:"note" binding:"max=500"`
+}
+
+// GetOrder returns an order the user bought or sells, with its lines,
+// sub-orders and status history.
+func GetOrder(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	orderID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	order, err := store.Default.GetOrderDetails(orderID, userID.(int))
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrOrderNotFound)
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch order", err))
+		return
+	}
+	signOrder(order)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Order details", order))
+}
+
+// UpdateOrderStatus moves an order to a new status on behalf of its buyer
+// or seller. Changing a checkout changes each of its sellers' parts.
+func UpdateOrderStatus(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	orderID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	order, err := store.Default.UpdateOrderStatus(orderID, userID.(int), req.Status, req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.Error(apperr.ErrOrderNotFound)
+		case errors.Is(err, store.ErrNotOwner):
+			c.Error(apperr.ErrOrderForbidden)
+		case errors.Is(err, store.ErrInvalidTransition):
+			c.Error(apperr.ErrInvalidTransition)
+		default:
+			c.Error(apperr.Internal("Failed to update order", err))
+		}
+		return
+	}
+	signOrder(order)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Order "+req.Status, order))
+}
+
+// GetSales lists the orders placed for the seller's listings.
+func GetSales(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	q, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+	params := &queryParams{c: c}
+	status := params.oneOf("status", store.OrderStatuses)
+	if !params.done() {
+		return
+	}
+
+	orders, err := store.Default.ListSellerOrders(userID.(int), status, q.PageRequest)
+	if err != nil {
+		listError(c, "Failed to fetch sales", err)
+		return
+	}
+
+	signEach(orders.Items, signOrder)
+	respondPage(c, "Sales retrieved", q, orders)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// listQuery is a parsed page request plus the legacy page number, which is
+// echoed back to clients that still paginate with page=.
+type listQuery struct {
+	store.PageRequest
+	page int
+}
+
+// pageQuery reads cursor, limit, page and include_total from the query
+// string. limit defaults to defaultPageSize and is capped at maxPageSize.
+// On invalid input it records a VALIDATION_FAILED error and returns false.
+func pageQuery(c *gin.Context) (listQuery, bool) {
+	q := listQuery{PageRequest: store.PageRequest{Limit: defaultPageSize}}
+
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.Error(apperr.Invalid("limit", "must be a positive integer"))
+			return q, false
+		}
+		q.Limit = min(n, maxPageSize)
+	}
+
+	if raw := c.Query("include_total"); raw != "" {
+		withTotal, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.Error(apperr.Invalid("include_total", "must be a boolean"))
+			return q, false
+		}
+		q.WithTotal = withTotal
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := store.DecodeCursor(raw)
+		if err != nil {
+			c.Error(apperr.Invalid("cursor", "is invalid"))
+			return q, false
+		}
+		q.Cursor = cursor
+		return q, true
+	}
+
+	if raw := c.Query("page"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.Error(apperr.Invalid("page", "must be a positive integer"))
+			return q, false
+		}
+		q.page = n
+		q.Offset = (n - 1) * q.Limit
+	}
+	return q, true
+}
+
+// listError reports a failed list query. A cursor the store rejects is the
+// client's fault; anything else is internal.
+func listError(c *gin.Context, message string, err error) {
+	if errors.Is(err, store.ErrInvalidCursor) {
+		c.Error(apperr.Invalid("cursor", "is invalid"))
+		return
+	}
+	c.Error(apperr.Internal(message, err))
+}
+
+// respondPage writes page as a utils.PaginatedResponse.
+func respondPage[T any](c *gin.Context, message string, q listQuery, page store.Page[T]) {
+	c.JSON(http.StatusOK, pageResponse(message, q, page))
+}
+
+// pageResponse builds the envelope respondPage writes, for handlers that
+// add fields of their own.
+func pageResponse[T any](message string, q listQuery, page store.Page[T]) utils.PaginatedResponse {
+	resp := utils.PaginatedResponse{
+		Success:        true,
+		Message:        message,
+		Data:           page.Items,
+		HasMore:        page.Next != nil,
+		Limit:          q.Limit,
+		Page:           q.page,
+		Total:          page.Total,
+		TotalEstimated: page.TotalEstimated,
+	}
+	if page.Next != nil {
+		resp.NextCursor = page.Next.Encode()
+	}
+	return resp
+}
+
+// mapPage converts the items of a page, keeping its cursor and total.
+func mapPage[T, U any](page store.Page[T], f func(T) U) store.Page[U] {
+	items := make([]U, 0, len(page.Items))
+	for _, item := range page.Items {
+		items = append(items, f(item))
+	}
+	return store.Page[U]{Items: items, Next: page.Next, Total: page.Total, TotalEstimated: page.TotalEstimated}
+}
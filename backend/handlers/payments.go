@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/config"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/payments"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBytes caps the size of a webhook body.
+const maxWebhookBytes = 64 << 10
+
+// CreatePaymentRequest picks how the buyer pays. Bank is required for
+// virtual accounts.
+type CreatePaymentRequest struct {
+	Method string `json:"method" binding:"required,oneof=virtual_account qris"`
+	Bank   string `json:"bank" binding:"omitempty,oneof=bca bni bri mandiri permata"`
+}
+
+// CreatePayment starts paying for a pending order: it opens a charge at the
+// payment gateway and returns the account number or QR code to pay to.
+// The order moves to paid when the gateway confirms the payment.
+func CreatePayment(c *gin.Context) {
+	if payments.Default == nil {
+		c.Error(apperr.ErrPaymentsDisabled)
+		return
+	}
+	userID, _ := c.Get("user_id")
+	orderID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req CreatePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+	switch {
+	case req.Method == payments.MethodVirtualAccount && req.Bank == "":
+		c.Error(apperr.Invalid("bank", "is required for virtual accounts"))
+		return
+	case req.Method != payments.MethodVirtualAccount:
+		req.Bank = ""
+	}
+
+	order, err := store.Default.GetOrderDetails(orderID, userID.(int))
+	if err == nil && order.BuyerID != userID.(int) {
+		err = store.ErrNotFound
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrOrderNotFound)
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch order", err))
+		return
+	}
+	// The charge lapses when the order would be cancelled as unpaid.
+	expires := order.CreatedAt.Add(config.LoadConfig().PaymentWindow)
+	if !time.Now().Before(expires) {
+		c.Error(apperr.ErrOrderNotPayable)
+		return
+	}
+
+	payment := &models.Payment{
+		OrderID: orderID, Provider: payments.Default.Name(), Method: req.Method, Bank: req.Bank, ExpiresAt: &expires,
+	}
+	if err := store.Default.CreatePayment(payment, userID.(int)); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.Error(apperr.ErrOrderNotFound)
+		case errors.Is(err, store.ErrInvalidTransition):
+			c.Error(apperr.ErrOrderNotPayable)
+		case errors.Is(err, store.ErrConflict):
+			c.Error(apperr.ErrPaymentPending)
+		default:
+			c.Error(apperr.Internal("Failed to create payment", err))
+		}
+		return
+	}
+
+	charge, err := payments.Default.CreateCharge(c.Request.Context(), payments.ChargeRequest{
+		Reference: payment.Reference,
+		Amount:    int64(math.Round(payment.Amount)),
+		Method:    payment.Method,
+		Bank:      payment.Bank,
+		ExpiresAt: expires,
+	})
+	if err != nil {
+		payment.Status = store.PaymentFailed
+		if saveErr := store.Default.SavePaymentCharge(payment); saveErr != nil {
+			c.Error(apperr.Internal("Failed to save payment", saveErr))
+			return
+		}
+		c.Error(apperr.ErrPaymentGateway.Wrap(err))
+		return
+	}
+	payment.ChargeID, payment.VANumber, payment.QRString = charge.ID, charge.VANumber, charge.QRString
+	payment.ExpiresAt = &charge.ExpiresAt
+	if err := store.Default.SavePaymentCharge(payment); err != nil {
+		c.Error(apperr.Internal("Failed to save payment", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Payment created", payment))
+}
+
+// GetPayments lists the payments made for one of the buyer's orders.
+func GetPayments(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	orderID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	list, err := store.Default.ListOrderPayments(orderID, userID.(int))
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrOrderNotFound)
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch payments", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Payments retrieved", list))
+}
+
+// PaymentWebhook receives charge updates from the payment gateway. Events
+// are applied once; redeliveries are acknowledged without effect.
+func PaymentWebhook(c *gin.Context) {
+	provider := payments.Default
+	if provider == nil {
+		c.Error(apperr.ErrPaymentsDisabled)
+		return
+	}
+	if c.Param("provider") != provider.Name() {
+		c.Error(apperr.ErrRouteNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBytes))
+	if err != nil {
+		c.Error(apperr.Invalid("body", "could not be read"))
+		return
+	}
+	event, err := provider.ParseWebhook(c.Request.Header, body)
+	if errors.Is(err, payments.ErrInvalidSignature) {
+		c.Error(apperr.ErrInvalidSignature)
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Invalid("body", "is not a valid payment event"))
+		return
+	}
+
+	err = store.Default.ApplyPaymentEvent(&models.PaymentEvent{
+		Provider: provider.Name(), EventID: event.ID, ChargeID: event.ChargeID, Status: event.Status,
+		Amount: float64(event.Amount), Currency: event.Currency,
+	})
+	switch {
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusOK, utils.SuccessResponse("Event already processed", nil))
+	case errors.Is(err, store.ErrPaymentMismatch):
+		// Recorded; acknowledge it so the gateway stops redelivering.
+		log.Printf("payments: %s charge %s paid %d %s, not the amount charged", provider.Name(), event.ChargeID, event.Amount, event.Currency)
+		c.JSON(http.StatusOK, utils.SuccessResponse("Event recorded; amount does not match the payment", nil))
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(apperr.ErrPaymentNotFound)
+	case err != nil:
+		c.Error(apperr.Internal("Failed to process payment event", err))
+	default:
+		c.JSON(http.StatusOK, utils.SuccessResponse("Event processed", nil))
+	}
+}
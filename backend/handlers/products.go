@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateProductRequest adds a pet supply to the catalog. SKUs are the
+// seller's own codes: a product's is unique among the seller's products and
+// a variant's among the product's variants.
+type CreateProductRequest struct {
+	CategoryID  int              `json:"category_id" binding:"required,min=1"`
+	SKU         string           `json:"sku" binding:"required,max=64"`
+	Name        string           `json:"name" binding:"required,max=255"`
+	Brand       string           `json:"brand" binding:"max=100"`
+	Description string           `json:"description" binding:"max=5000"`
+	ImageURL    string           `json:"image_url" binding:"max=500"`
+	Location    string           `json:"location" binding:"max=255"`
+	Variants    []VariantRequest `json:"variants" binding:"required,min=1,max=50,dive"`
+}
+
+// VariantRequest is one size or flavour of a new product.
+type VariantRequest struct {
+	SKU         string  `json:"sku" binding:"required,max=64"`
+	Size        string  `json:"size" binding:"max=50"`
+	Flavour     string  `json:"flavour" binding:"max=50"`
+	Price       float64 `json:"price" binding:"required,min=0"`
+	WeightGrams int     `json:"weight_grams" binding:"required,min=1"`
+	Stock       int     `json:"stock" binding:"min=0,max=1000000"`
+}
+
+// UpdateVariantRequest changes only the fields present in the body.
+type UpdateVariantRequest struct {
+	Price *float64 `json:"price" binding:"omitempty,min=0"`
+	Stock *int     `json:"stock" binding:"omitempty,min=0,max=1000000"`
+}
+
+func signProduct(p *models.Product) {
+	p.ImageURL = signURL(p.ImageURL)
+}
+
+// productError reports a failed product operation.
+func productError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(apperr.ErrProductNotFound)
+	case errors.Is(err, store.ErrNotOwner):
+		c.Error(apperr.ErrNotListingOwner)
+	case errors.Is(err, store.ErrConflict):
+		c.Error(apperr.ErrProductSKUTaken)
+	default:
+		c.Error(apperr.Internal(message, err))
+	}
+}
+
+// GetProducts lists the supplies catalog.
+func GetProducts(c *gin.Context) {
+	q, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	params := &queryParams{c: c}
+	filter := store.ProductFilter{
+		Search:  strings.TrimSpace(c.Query("search")),
+		Brand:   strings.TrimSpace(c.Query("brand")),
+		InStock: params.boolean("in_stock"),
+		Sort:    params.oneOf("sort", store.ProductSorts),
+	}
+	if id := params.integer("category_id", 1); id != nil {
+		filter.CategoryID = *id
+	}
+	if id := params.integer("seller_id", 1); id != nil {
+		filter.SellerID = *id
+	}
+	if !params.done() {
+		return
+	}
+
+	products, err := store.Default.ListProducts(filter, q.PageRequest)
+	if err != nil {
+		listError(c, "Failed to fetch products", err)
+		return
+	}
+
+	signEach(products.Items, signProduct)
+	respondPage(c, "Products retrieved", q, products)
+}
+
+func GetProduct(c *gin.Context) {
+	productID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	product, err := store.Default.GetProduct(productID)
+	if err != nil {
+		productError(c, "Failed to fetch product", err)
+		return
+	}
+	signProduct(product)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Product details", product))
+}
+
+// CreateProduct adds a product to the signed-in seller's catalog. Products
+// go in supplies categories; live animals are listed as animals.
+func CreateProduct(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var req CreateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	categories, err := store.Default.ListCategories()
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch categories", err))
+		return
+	}
+	var category *models.Category
+	for i := range categories {
+		if categories[i].ID == req.CategoryID {
+			category = &categories[i]
+		}
+	}
+	switch {
+	case category == nil:
+		c.Error(apperr.Invalid("category_id", "does not exist"))
+		return
+	case category.Type == "animal":
+		c.Error(apperr.Invalid("category_id", "is for live animals; list them with POST /api/marketplace/animals"))
+		return
+	}
+
+	product := &models.Product{
+		SellerID:    userID.(int),
+		CategoryID:  req.CategoryID,
+		SKU:         strings.TrimSpace(req.SKU),
+		Name:        strings.TrimSpace(req.Name),
+		Brand:       strings.TrimSpace(req.Brand),
+		Description: req.Description,
+		ImageURL:    storedURL(req.ImageURL),
+		Location:    req.Location,
+	}
+	skus := map[string]bool{}
+	for i, v := range req.Variants {
+		sku := strings.TrimSpace(v.SKU)
+		if skus[sku] {
+			c.Error(apperr.Invalid(fmt.Sprintf("variants[%d].sku", i), "is used by another variant"))
+			return
+		}
+		skus[sku] = true
+		product.Variants = append(product.Variants, models.ProductVariant{
+			SKU: sku, Size: v.Size, Flavour: v.Flavour, Price: v.Price, WeightGrams: v.WeightGrams, Stock: v.Stock,
+		})
+	}
+
+	if err := store.Default.CreateProduct(product); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.Error(apperr.Invalid("category_id", "does not exist"))
+			return
+		}
+		productError(c, "Failed to create product", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Product created", IDResponse{ID: product.ID}))
+}
+
+// UpdateProductVariant changes the price or stock of a variant of the
+// signed-in seller's product.
+func UpdateProductVariant(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	productID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	variantID, ok := paramID(c, "variant_id")
+	if !ok {
+		return
+	}
+	var req UpdateVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	product, err := store.Default.UpdateVariant(productID, variantID, userID.(int), store.VariantUpdate{Price: req.Price, Stock: req.Stock})
+	if errors.Is(err, store.ErrNotFound) {
+		// Tell a missing variant of an existing product apart.
+		if _, getErr := store.Default.GetProduct(productID); getErr == nil {
+			c.Error(apperr.ErrVariantNotFound)
+			return
+		}
+	}
+	if err != nil {
+		productError(c, "Failed to update variant", err)
+		return
+	}
+	signProduct(product)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Variant updated", product))
+}
@@ -1,206 +1,276 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
-	"github.com/TerraPaw/backend/db"
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/geo"
 	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
 	"github.com/gin-gonic/gin"
 )
 
+type CreateUserPetRequest struct {
+	Name       string `json:"name" binding:"required"`
+	AnimalType string `json:"animal_type" binding:"required"`
+	Breed      string `json:"breed"`
+	Age        int    `json:"age"`
+	ImageURL   string `json:"image_url"`
+	Story      string `json:"story"`
+}
+
+// AlertPreferencesRequest changes the user's wishlist alert preferences.
+// Fields left out keep their value.
+type AlertPreferencesRequest struct {
+	PriceDrop      *bool `json:"price_drop"`
+	BackInStock    *bool `json:"back_in_stock"`
+	MinDropPercent *int  `json:"min_drop_percent" binding:"omitempty,min=0,max=90"`
+	Email          *bool `json:"email"`
+	Push           *bool `json:"push"`
+}
+
+// LocationRequest sets where the user is. Latitude and Longitude pin it;
+// without them Location is geocoded.
+type LocationRequest struct {
+	Location  string   `json:"location" binding:"required,max=255"`
+	Latitude  *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"`
+}
+
 // GetMyPets returns all pets owned by the current user
 func GetMyPets(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperr.ErrUnauthorized)
 		return
 	}
 
-	rows, err := db.DB.Query("SELECT id, owner_id, name, animal_type, COALESCE(breed, ''), age, COALESCE(image_url, ''), COALESCE(story, ''), created_at FROM user_pets WHERE owner_id = $1 ORDER BY created_at DESC", userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pets"})
+	q, ok := pageQuery(c)
+	if !ok {
 		return
 	}
-	defer rows.Close()
 
-	var pets []models.UserPet
-	for rows.Next() {
-		var p models.UserPet
-		if err := rows.Scan(&p.ID, &p.OwnerID, &p.Name, &p.AnimalType, &p.Breed, &p.Age, &p.ImageURL, &p.Story, &p.CreatedAt); err != nil {
-			continue
-		}
-		pets = append(pets, p)
-	}
-
-	if pets == nil {
-		pets = []models.UserPet{}
+	pets, err := store.Default.ListUserPets(userID.(int), q.PageRequest)
+	if err != nil {
+		listError(c, "Failed to fetch pets", err)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": pets})
+	signEach(pets.Items, signPet)
+	respondPage(c, "Pets retrieved", q, pets)
 }
 
 // GetMedicalRecords returns all medical records for the current user's pets
 func GetMedicalRecords(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperr.ErrUnauthorized)
 		return
 	}
 
-	query := `
-		SELECT mr.id, mr.pet_id, mr.veterinarian_id, mr.record_type, COALESCE(mr.description, ''), COALESCE(mr.treatment, ''), mr.date, COALESCE(mr.notes, ''), mr.created_at,
-		       p.name, p.animal_type,
-		       COALESCE(v.clinic_name, ''), u.fullname
-		FROM medical_records mr
-		JOIN user_pets p ON mr.pet_id = p.id
-		LEFT JOIN veterinarians v ON mr.veterinarian_id = v.id
-		LEFT JOIN users u ON v.user_id = u.id
-		WHERE p.owner_id = $1
-		ORDER BY mr.date DESC
-	`
+	q, ok := pageQuery(c)
+	if !ok {
+		return
+	}
 
-	rows, err := db.DB.Query(query, userID)
+	rows, err := store.Default.ListMedicalRecords(userID.(int), q.PageRequest)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch medical records"})
+		listError(c, "Failed to fetch medical records", err)
 		return
 	}
-	defer rows.Close()
-
-	var records []gin.H
-	for rows.Next() {
-		var mr models.MedicalRecord
-		var petName, petType, clinicName, vetName string
-		var vetID *int // Handle null vet
-
-		if err := rows.Scan(
-			&mr.ID, &mr.PetID, &vetID, &mr.RecordType, &mr.Description, &mr.Treatment, &mr.Date, &mr.Notes, &mr.CreatedAt,
-			&petName, &petType,
-			&clinicName, &vetName,
-		); err != nil {
-			continue
-		}
 
-		// Construct response object
-		record := gin.H{
-			"id":          mr.ID,
-			"pet_id":      mr.PetID,
-			"pet_name":    petName,
-			"pet_type":    petType,
-			"record_type": mr.RecordType,
-			"description": mr.Description,
-			"treatment":   mr.Treatment,
-			"date":        mr.Date,
-			"notes":       mr.Notes,
-			"doctor_name": vetName,
-			"clinic_name": clinicName,
+	records := mapPage(rows, func(mr models.MedicalRecord) MedicalRecordResponse {
+		return MedicalRecordResponse{
+			ID:          mr.ID,
+			PetID:       mr.PetID,
+			PetName:     mr.Pet.Name,
+			PetType:     mr.Pet.AnimalType,
+			RecordType:  mr.RecordType,
+			Description: mr.Description,
+			Treatment:   mr.Treatment,
+			Date:        mr.Date,
+			Notes:       mr.Notes,
+			DoctorName:  mr.Veterinarian.User.FullName,
+			ClinicName:  mr.Veterinarian.ClinicName,
 		}
-		records = append(records, record)
-	}
-
-	if records == nil {
-		records = []gin.H{}
-	}
+	})
 
-	c.JSON(http.StatusOK, gin.H{"data": records})
+	respondPage(c, "Medical records retrieved", q, records)
 }
 
 // GetNotifications returns notifications for the current user
 func GetNotifications(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperr.ErrUnauthorized)
 		return
 	}
 
-	rows, err := db.DB.Query("SELECT id, user_id, title, message, type, is_read, created_at FROM notifications WHERE user_id = $1 ORDER BY created_at DESC", userID)
+	q, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	notifications, err := store.Default.ListNotifications(userID.(int), q.PageRequest)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		listError(c, "Failed to fetch notifications", err)
 		return
 	}
-	defer rows.Close()
 
-	var notifications []models.Notification
-	for rows.Next() {
-		var n models.Notification
-		if err := rows.Scan(&n.ID, &n.UserID, &n.Title, &n.Message, &n.Type, &n.IsRead, &n.CreatedAt); err != nil {
-			continue
-		}
-		notifications = append(notifications, n)
+	respondPage(c, "Notifications retrieved", q, notifications)
+}
+
+// GetAlertPreferences returns which wishlist alerts the user gets and
+// where.
+func GetAlertPreferences(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	prefs, err := store.Default.GetAlertPreferences(userID.(int))
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch alert preferences", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Alert preferences retrieved", prefs))
+}
+
+func UpdateAlertPreferences(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var req AlertPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
 	}
 
-	if notifications == nil {
-		notifications = []models.Notification{}
+	prefs, err := store.Default.GetAlertPreferences(userID.(int))
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch alert preferences", err))
+		return
+	}
+	if req.PriceDrop != nil {
+		prefs.PriceDrop = *req.PriceDrop
+	}
+	if req.BackInStock != nil {
+		prefs.BackInStock = *req.BackInStock
+	}
+	if req.MinDropPercent != nil {
+		prefs.MinDropPercent = *req.MinDropPercent
+	}
+	if req.Email != nil {
+		prefs.Email = *req.Email
+	}
+	if req.Push != nil {
+		prefs.Push = *req.Push
+	}
+	if err := store.Default.SetAlertPreferences(userID.(int), prefs); err != nil {
+		c.Error(apperr.Internal("Failed to save alert preferences", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Alert preferences saved", prefs))
+}
+
+// UpdateLocation sets where the user is, for clients to search listings
+// near them. It returns the updated profile.
+func UpdateLocation(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var req LocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	at, ok := givenPosition(c, req.Latitude, req.Longitude)
+	if !ok {
+		return
+	}
+	if at == nil {
+		var err error
+		at, err = geo.Default.Geocode(c.Request.Context(), req.Location)
+		if errors.Is(err, geo.ErrUnknownPlace) {
+			c.Error(apperr.Invalid("location", "is not a place we know; send latitude and longitude with it"))
+			return
+		}
+		if err != nil {
+			c.Error(apperr.Internal("Failed to geocode location", err))
+			return
+		}
+	}
+	if err := store.Default.SetUserLocation(userID.(int), req.Location, *at); err != nil {
+		c.Error(apperr.Internal("Failed to save location", err))
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": notifications})
+	user, err := store.Default.GetUser(userID.(int))
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch user", err))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse("Location updated", user))
 }
 
 // CreateUserPet adds a new pet for the user
 func CreateUserPet(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperr.ErrUnauthorized)
 		return
 	}
 
-	var input struct {
-		Name       string `json:"name" binding:"required"`
-		AnimalType string `json:"animal_type" binding:"required"`
-		Breed      string `json:"breed"`
-		Age        int    `json:"age"`
-		ImageURL   string `json:"image_url"`
-		Story      string `json:"story"`
-	}
-
+	var input CreateUserPetRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Validation(err))
 		return
 	}
 
-	var petID int
-	err := db.DB.QueryRow(`
-		INSERT INTO user_pets (owner_id, name, animal_type, breed, age, image_url, story)
-		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
-		userID, input.Name, input.AnimalType, input.Breed, input.Age, input.ImageURL, input.Story,
-	).Scan(&petID)
+	petID, err := store.Default.CreateUserPet(&models.UserPet{
+		OwnerID:    userID.(int),
+		Name:       input.Name,
+		AnimalType: input.AnimalType,
+		Breed:      input.Breed,
+		Age:        input.Age,
+		ImageURL:   storedURL(input.ImageURL),
+		Story:      input.Story,
+	})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pet"})
+		c.Error(apperr.Internal("Failed to create pet", err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Pet created successfully", "id": petID})
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Pet created successfully", IDResponse{ID: petID}))
 }
 
 // GetUserStats returns statistics for the user profile (Pets, Orders, Vouchers)
 func GetUserStats(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperr.ErrUnauthorized)
 		return
 	}
 
-	var petCount, orderCount int
-
 	// Count Pets
-	if err := db.DB.QueryRow("SELECT COUNT(*) FROM user_pets WHERE owner_id = $1", userID).Scan(&petCount); err != nil {
+	petCount, err := store.Default.CountUserPets(userID.(int))
+	if err != nil {
 		petCount = 0
 	}
 
 	// Count Orders
-	if err := db.DB.QueryRow("SELECT COUNT(*) FROM orders WHERE buyer_id = $1", userID).Scan(&orderCount); err != nil {
+	orderCount, err := store.Default.CountUserOrders(userID.(int))
+	if err != nil {
 		orderCount = 0
 	}
 
-	// Voucher Count (Mock for now, as voucher system is not fully implemented)
-	// In a real app, this would be SELECT COUNT(*) FROM user_vouchers WHERE user_id = ...
-	voucherCount := 5
+	// Count claimed vouchers that can still be used
+	voucherCount, err := store.Default.CountUsableVouchers(userID.(int))
+	if err != nil {
+		voucherCount = 0
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": gin.H{
-			"pets":     petCount,
-			"orders":   orderCount,
-			"vouchers": voucherCount,
-		},
-	})
+	c.JSON(http.StatusOK, utils.SuccessResponse("Stats retrieved", UserStats{
+		Pets:     petCount,
+		Orders:   orderCount,
+		Vouchers: voucherCount,
+	}))
 }
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/recommend"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxRecommendations caps the limit of GetRecommendations.
+const maxRecommendations = 50
+
+// similarCandidates is how many listings of the same type are scored when a
+// listing has no stored matches yet.
+const similarCandidates = 200
+
+// recommendationLimit reads the limit query parameter, def by default and
+// at most max.
+func recommendationLimit(c *gin.Context, def, max int) (int, bool) {
+	params := &queryParams{c: c}
+	limit := params.integer("limit", 1)
+	if !params.done() {
+		return 0, false
+	}
+	if limit == nil {
+		return def, true
+	}
+	return min(*limit, max), true
+}
+
+// GetSimilarAnimals returns listings for sale like this one, best first.
+// Listings created since the last batch are matched on their details.
+func GetSimilarAnimals(c *gin.Context) {
+	animalID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	limit, ok := recommendationLimit(c, 10, recommend.PerListing)
+	if !ok {
+		return
+	}
+
+	animal, err := store.Default.GetAnimal(animalID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrAnimalNotFound)
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch animal", err))
+		return
+	}
+
+	recs, err := store.Default.SimilarAnimals(animalID, limit)
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch similar listings", err))
+		return
+	}
+	if len(recs) == 0 {
+		candidates, err := store.Default.ListAnimals(
+			store.AnimalFilter{AnimalType: animal.AnimalType, InStock: true}, store.PageRequest{Limit: similarCandidates},
+		)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to fetch similar listings", err))
+			return
+		}
+		byID := map[int]models.Animal{}
+		for _, a := range candidates.Items {
+			byID[a.ID] = a
+		}
+		for _, sim := range recommend.ByContent(*animal, candidates.Items, limit) {
+			a := byID[sim.SimilarID]
+			recs = append(recs, models.Recommendation{Animal: &a, Score: sim.Score, Reasons: sim.Reasons})
+		}
+	}
+
+	signEach(recs, signRecommendation)
+	c.JSON(http.StatusOK, utils.SuccessResponse("Similar listings retrieved", recs))
+}
+
+// GetRecommendations suggests listings to the caller from what they ordered
+// and saved, topped up with popular listings.
+func GetRecommendations(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	limit, ok := recommendationLimit(c, 20, maxRecommendations)
+	if !ok {
+		return
+	}
+
+	recs, err := store.Default.RecommendAnimals(userID.(int), limit)
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch recommendations", err))
+		return
+	}
+
+	signEach(recs, signRecommendation)
+	c.JSON(http.StatusOK, utils.SuccessResponse("Recommendations retrieved", recs))
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import "time"
+
+// Payload types returned under utils.Response.Data. They are named so the
+// OpenAPI generator can describe them; the JSON shape matches the gin.H
+// literals they replaced.
+
+// IDResponse is returned by endpoints that create a single resource.
+type IDResponse struct {
+	ID int `json:"id"`
+}
+
+// AuthResponse is returned by register and login.
+type AuthResponse struct {
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	FullName  string `json:"fullname"`
+	AvatarURL string `json:"avatar_url"`
+	Bio       string `json:"bio"`
+	UserType  string `json:"user_type"`
+	Token     string `json:"token"`
+}
+
+// ForgotPasswordResponse carries the mock reset code until email delivery exists.
+type ForgotPasswordResponse struct {
+	MockCode string `json:"mock_code"`
+}
+
+// UserStats are the counters shown on the profile screen.
+type UserStats struct {
+	Pets     int `json:"pets"`
+	Orders   int `json:"orders"`
+	Vouchers int `json:"vouchers"`
+}
+
+// MedicalRecordResponse flattens a medical record with its pet and vet.
+type MedicalRecordResponse struct {
+	ID          int       `json:"id"`
+	PetID       int       `json:"pet_id"`
+	PetName     string    `json:"pet_name"`
+	PetType     string    `json:"pet_type"`
+	RecordType  string    `json:"record_type"`
+	Description string    `json:"description"`
+	Treatment   string    `json:"treatment"`
+	Date        time.Time `json:"date"`
+	Notes       string    `json:"notes"`
+	DoctorName  string    `json:"doctor_name"`
+	ClinicName  string    `json:"clinic_name"`
+}
+
+// UploadResponse describes a stored image. Save Ref in image_url or
+// media_url fields; URL and ThumbnailURL are signed links for showing the
+// upload straight away and expire.
+type UploadResponse struct {
+	Ref          string `json:"ref"`
+	URL          string `json:"url"`
+	ThumbnailRef string `json:"thumbnail_ref"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	Size         int    `json:"size"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateReviewRequest reviews a listing the caller bought. OrderID is the
+// completed order it was bought on, or the checkout holding that order.
+type CreateReviewRequest struct {
+	OrderID  int    `json:"order_id" binding:"required"`
+	AnimalID int    `json:"animal_id" binding:"required"`
+	Rating   int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment  string `json:"comment"`
+	ImageURL string `json:"image_url"`
+}
+
+// ReviewReplyRequest is the seller's public answer to a review.
+type ReviewReplyRequest struct {
+	Body string `json:"body" binding:"required,max=2000"`
+}
+
+// ReportReviewRequest reports a review to the moderators.
+type ReportReviewRequest struct {
+	Reason  string `json:"reason" binding:"required,oneof=spam abusive offensive_image fake other"`
+	Details string `json:"details" binding:"max=1000"`
+}
+
+// ResolveReportRequest closes a report: dismissed keeps the review up,
+// removed takes it down.
+type ResolveReportRequest struct {
+	Status string `json:"status" binding:"required,oneof=dismissed removed"`
+}
+
+// reviewError reports a failed change to a review.
+func reviewError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(apperr.ErrReviewNotFound)
+	case errors.Is(err, store.ErrNotOwner):
+		c.Error(apperr.ErrNotReviewSeller)
+	case errors.Is(err, store.ErrOwnReview):
+		c.Error(apperr.ErrOwnReview)
+	case errors.Is(err, store.ErrConflict):
+		c.Error(apperr.ErrReviewReported)
+	default:
+		c.Error(apperr.Internal(message, err))
+	}
+}
+
+func signReport(rr *models.ReviewReport) {
+	if rr.Review != nil {
+		signReview(rr.Review)
+	}
+}
+
+// CreateReview records a verified-purchase review. Each line of a completed
+// order can be reviewed once.
+func CreateReview(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var req CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	review := &models.Review{
+		UserID:   userID.(int),
+		OrderID:  &req.OrderID,
+		AnimalID: req.AnimalID,
+		Rating:   req.Rating,
+		Comment:  req.Comment,
+		ImageURL: storedURL(req.ImageURL),
+	}
+	_, err := store.Default.CreateReview(review)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(apperr.ErrOrderNotFound.WithMessage("Order not found, or the animal is not on it"))
+		return
+	case errors.Is(err, store.ErrNotReviewable):
+		c.Error(apperr.ErrNotReviewable)
+		return
+	case errors.Is(err, store.ErrConflict):
+		c.Error(apperr.ErrReviewExists)
+		return
+	case err != nil:
+		c.Error(apperr.Internal("Failed to submit review", err))
+		return
+	}
+
+	signReview(review)
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Review submitted", review))
+}
+
+// reviewsPage is the page GetReviews returns.
+type reviewsPage struct {
+	utils.PaginatedResponse
+	Summary *models.RatingSummary `json:"summary"`
+}
+
+// GetReviews lists a listing's reviews, newest first unless sorted
+// otherwise, with the breakdown of all of them by rating in summary.
+func GetReviews(c *gin.Context) {
+	animalID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	q, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+	params := &queryParams{c: c}
+	filter := store.ReviewFilter{
+		WithPhotos: params.boolean("with_photos"),
+		Sort:       params.oneOf("sort", store.ReviewSorts),
+	}
+	if !params.done() {
+		return
+	}
+
+	reviews, err := store.Default.ListReviews(animalID, filter, q.PageRequest)
+	if err != nil {
+		listError(c, "Failed to fetch reviews", err)
+		return
+	}
+	summary, err := store.Default.ReviewSummary(animalID)
+	if err != nil {
+		c.Error(apperr.Internal("Failed to summarize reviews", err))
+		return
+	}
+
+	signEach(reviews.Items, signReview)
+	c.JSON(http.StatusOK, reviewsPage{
+		PaginatedResponse: pageResponse("Reviews retrieved", q, reviews),
+		Summary:           summary,
+	})
+}
+
+// ReplyToReview sets the caller's reply to a review of their listing,
+// replacing the one they wrote before.
+func ReplyToReview(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	reviewID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req ReviewReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		c.Error(apperr.Invalid("body", "is required"))
+		return
+	}
+
+	reply, err := store.Default.ReplyToReview(reviewID, userID.(int), body)
+	if err != nil {
+		reviewError(c, "Failed to reply to review", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Reply saved", reply))
+}
+
+func DeleteReviewReply(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	reviewID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := store.Default.DeleteReviewReply(reviewID, userID.(int)); err != nil {
+		reviewError(c, "Failed to delete reply", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Reply deleted", nil))
+}
+
+func VoteReviewHelpful(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	reviewID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := store.Default.VoteReviewHelpful(reviewID, userID.(int)); err != nil {
+		reviewError(c, "Failed to vote", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Review marked helpful", nil))
+}
+
+func UnvoteReviewHelpful(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	reviewID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := store.Default.UnvoteReviewHelpful(reviewID, userID.(int)); err != nil {
+		reviewError(c, "Failed to remove vote", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Helpful vote removed", nil))
+}
+
+// ReportReview puts a review in the moderation queue. Each user reports a
+// review once.
+func ReportReview(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	reviewID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req ReportReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	report := &models.ReviewReport{ReviewID: reviewID, ReporterID: userID.(int), Reason: req.Reason, Details: req.Details}
+	if err := store.Default.ReportReview(report); err != nil {
+		reviewError(c, "Failed to report review", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Review reported", report))
+}
+
+// GetReviewReports is the moderation queue: reports in status (open by
+// default), oldest first.
+func GetReviewReports(c *gin.Context) {
+	q, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+	params := &queryParams{c: c}
+	status := params.oneOf("status", store.ReportStatuses)
+	if !params.done() {
+		return
+	}
+	if status == "" {
+		status = store.ReportOpen
+	}
+
+	reports, err := store.Default.ListReviewReports(status, q.PageRequest)
+	if err != nil {
+		listError(c, "Failed to fetch reports", err)
+		return
+	}
+
+	signEach(reports.Items, signReport)
+	respondPage(c, "Reports retrieved", q, reports)
+}
+
+// ResolveReviewReport dismisses a report or removes the review it is
+// about, which settles the other open reports on that review too.
+func ResolveReviewReport(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	reportID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req ResolveReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	report, err := store.Default.ResolveReviewReport(reportID, userID.(int), req.Status)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(apperr.ErrReportNotFound)
+		return
+	case errors.Is(err, store.ErrConflict):
+		c.Error(apperr.ErrReportResolved)
+		return
+	case err != nil:
+		c.Error(apperr.Internal("Failed to resolve report", err))
+		return
+	}
+
+	signReport(report)
+	c.JSON(http.StatusOK, utils.SuccessResponse("Report resolved", report))
+}
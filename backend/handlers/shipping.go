@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/shipping"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AddressRequest is an entry for the address book. City must be one we
+// deliver to; it is stored under its usual spelling.
+type AddressRequest struct {
+	Label         string `json:"label" binding:"required,max=50"`
+	RecipientName string `json:"recipient_name" binding:"required,max=100"`
+	Phone         string `json:"phone" binding:"required,min=8,max=20"`
+	Street        string `json:"street" binding:"required,max=500"`
+	City          string `json:"city" binding:"required,max=100"`
+	Province      string `json:"province" binding:"max=100"`
+	PostalCode    string `json:"postal_code" binding:"required,numeric,len=5"`
+	IsDefault     bool   `json:"is_default"`
+}
+
+type ShippingQuoteRequest struct {
+	AddressID int `json:"address_id" binding:"required"`
+}
+
+// ShippingChoice picks the delivery service for one seller's part of the
+// cart.
+type ShippingChoice struct {
+	SellerID int    `json:"seller_id" binding:"required"`
+	Service  string `json:"service" binding:"required"`
+}
+
+// SellerShipping is what delivery from one seller of the cart costs.
+// Parcels holding a live animal are only offered live-animal services.
+type SellerShipping struct {
+	SellerID    int             `json:"seller_id"`
+	Origin      string          `json:"origin"`
+	Destination string          `json:"destination"`
+	Items       int             `json:"items"`
+	LiveAnimals bool            `json:"live_animals"`
+	Rates       []shipping.Rate `json:"rates"`
+}
+
+// liveAnimalTypes returns a function reporting whether a listing is a live
+// animal. Anything not in a food category counts as one, so an animal is
+// never offered an ordinary parcel service.
+func liveAnimalTypes() (func(*models.Animal) bool, error) {
+	categories, err := store.Default.ListCategories()
+	if err != nil {
+		return nil, err
+	}
+	food := map[int]bool{}
+	for _, cat := range categories {
+		if cat.Type == "food" {
+			food[cat.ID] = true
+		}
+	}
+	return func(a *models.Animal) bool { return a.CategoryID == nil || !food[*a.CategoryID] }, nil
+}
+
+// shippingError reports a failed quote or booking at the carrier.
+func shippingError(c *gin.Context, err error) {
+	if errors.Is(err, shipping.ErrUnserviceable) {
+		c.Error(apperr.ErrNoShipping)
+		return
+	}
+	c.Error(apperr.ErrCarrier.Wrap(err))
+}
+
+// cartShipping quotes delivery of each seller's part of the buyer's cart to
+// an address, in the order checkout creates the sub-orders.
+func cartShipping(c *gin.Context, buyerID int, address *models.Address) ([]SellerShipping, bool) {
+	cart, err := store.Default.GetCart(buyerID)
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch cart", err))
+		return nil, false
+	}
+	if len(cart.Items) == 0 {
+		c.Error(apperr.ErrCartEmpty)
+		return nil, false
+	}
+	isLive, err := liveAnimalTypes()
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch categories", err))
+		return nil, false
+	}
+
+	var quotes []SellerShipping
+	index := map[int]int{}
+	for _, item := range cart.Items {
+		if item.Problem == models.CartUnavailable {
+			continue
+		}
+		// Products are supplies, never live animals.
+		sellerID, location, live := 0, "", false
+		if item.Variant != nil {
+			sellerID, location = item.Variant.SellerID, item.Variant.Location
+		} else {
+			sellerID, location, live = item.Animal.SellerID, item.Animal.Location, isLive(item.Animal)
+		}
+		i, ok := index[sellerID]
+		if !ok {
+			i = len(quotes)
+			index[sellerID] = i
+			quotes = append(quotes, SellerShipping{SellerID: sellerID, Destination: address.City})
+		}
+		q := &quotes[i]
+		q.Items += item.Quantity
+		q.LiveAnimals = q.LiveAnimals || live
+		if q.Origin == "" {
+			q.Origin = shipping.City(location)
+		}
+	}
+
+	for i := range quotes {
+		q := &quotes[i]
+		if q.Origin == "" {
+			c.Error(apperr.ErrNoShipping.WithMessage(fmt.Sprintf("Seller %d has not said which city they ship from", q.SellerID)))
+			return nil, false
+		}
+		q.Rates, err = shipping.Default.Quote(c.Request.Context(), shipping.QuoteRequest{
+			Origin: q.Origin, Destination: q.Destination, Items: q.Items, LiveAnimals: q.LiveAnimals,
+		})
+		if err == nil && len(q.Rates) == 0 {
+			err = shipping.ErrUnserviceable
+		}
+		if err != nil {
+			shippingError(c, err)
+			return nil, false
+		}
+	}
+	return quotes, true
+}
+
+// checkoutShipping books the services the buyer chose for each seller of
+// the cart at the rates quoted now. It returns nil when no address was
+// given.
+func checkoutShipping(c *gin.Context, buyerID int, req CheckoutRequest) (map[int]*models.Shipment, bool) {
+	if req.AddressID == 0 {
+		if len(req.Shipping) > 0 {
+			c.Error(apperr.Invalid("address_id", "is required to choose shipping"))
+			return nil, false
+		}
+		return nil, true
+	}
+	address, ok := buyerAddress(c, buyerID, req.AddressID)
+	if !ok {
+		return nil, false
+	}
+	quotes, ok := cartShipping(c, buyerID, address)
+	if !ok {
+		return nil, false
+	}
+
+	chosen := map[int]string{}
+	for _, choice := range req.Shipping {
+		chosen[choice.SellerID] = choice.Service
+	}
+	booked := map[int]*models.Shipment{}
+	for _, q := range quotes {
+		service, ok := chosen[q.SellerID]
+		if !ok {
+			c.Error(apperr.Invalid("shipping", fmt.Sprintf("must choose a service for seller %d", q.SellerID)))
+			return nil, false
+		}
+		var rate *shipping.Rate
+		for i := range q.Rates {
+			if q.Rates[i].Service == service {
+				rate = &q.Rates[i]
+			}
+		}
+		if rate == nil {
+			message := fmt.Sprintf("%s cannot deliver from seller %d to %s", service, q.SellerID, q.Destination)
+			if q.LiveAnimals && !shipping.Allowed(service, true) {
+				message = fmt.Sprintf("%s cannot carry the live animals from seller %d", service, q.SellerID)
+			}
+			c.Error(apperr.Invalid("shipping", message))
+			return nil, false
+		}
+		booked[q.SellerID] = &models.Shipment{
+			Carrier: shipping.Default.Name(), Service: rate.Service, LiveAnimals: q.LiveAnimals,
+			Cost: float64(rate.Price), MinDays: rate.MinDays, MaxDays: rate.MaxDays, Origin: q.Origin,
+			RecipientName: address.RecipientName, Phone: address.Phone,
+			Address: address.Street + ", " + address.City + " " + address.PostalCode,
+			City:    address.City, PostalCode: address.PostalCode,
+		}
+	}
+	return booked, true
+}
+
+// buyerAddress loads one of the user's addresses, reporting a missing one.
+func buyerAddress(c *gin.Context, userID, id int) (*models.Address, bool) {
+	address, err := store.Default.GetAddress(userID, id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrAddressNotFound)
+		return nil, false
+	}
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch address", err))
+		return nil, false
+	}
+	return address, true
+}
+
+// bindAddress reads an AddressRequest into an address of the user.
+func bindAddress(c *gin.Context, userID int) (*models.Address, bool) {
+	var req AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return nil, false
+	}
+	city := shipping.City(req.City)
+	if city == "" {
+		c.Error(apperr.Invalid("city", "is not a city we deliver to yet"))
+		return nil, false
+	}
+	return &models.Address{
+		UserID: userID, Label: req.Label, RecipientName: req.RecipientName, Phone: req.Phone, Street: req.Street,
+		City: city, Province: req.Province, PostalCode: req.PostalCode, IsDefault: req.IsDefault,
+	}, true
+}
+
+// GetAddresses lists the user's address book, the default first.
+func GetAddresses(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	addresses, err := store.Default.ListAddresses(userID.(int))
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch addresses", err))
+		return
+	}
+	if addresses == nil {
+		addresses = []models.Address{}
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Addresses retrieved", addresses))
+}
+
+// CreateAddress adds to the user's address book. The first address becomes
+// the default.
+func CreateAddress(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	address, ok := bindAddress(c, userID.(int))
+	if !ok {
+		return
+	}
+
+	if err := store.Default.CreateAddress(address); err != nil {
+		c.Error(apperr.Internal("Failed to save address", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Address saved", address))
+}
+
+// UpdateAddress replaces one of the user's addresses. Shipments already
+// booked keep the address they were booked with.
+func UpdateAddress(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	address, ok := bindAddress(c, userID.(int))
+	if !ok {
+		return
+	}
+	address.ID = id
+
+	err := store.Default.UpdateAddress(address)
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrAddressNotFound)
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Internal("Failed to update address", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Address updated", address))
+}
+
+func DeleteAddress(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	err := store.Default.DeleteAddress(userID.(int), id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrAddressNotFound)
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Internal("Failed to delete address", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Address deleted", nil))
+}
+
+// GetAnimalShipping quotes delivering one of a listing to a city. Live
+// animals are only quoted live-animal services.
+func GetAnimalShipping(c *gin.Context) {
+	animalID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	destination := shipping.City(c.Query("city"))
+	if destination == "" {
+		c.Error(apperr.Invalid("city", "is not a city we deliver to yet"))
+		return
+	}
+
+	animal, err := store.Default.GetAnimal(animalID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrAnimalNotFound)
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch animal", err))
+		return
+	}
+	isLive, err := liveAnimalTypes()
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch categories", err))
+		return
+	}
+	origin := shipping.City(animal.Location)
+	if origin == "" {
+		c.Error(apperr.ErrNoShipping.WithMessage("The seller has not said which city they ship from"))
+		return
+	}
+
+	quote := SellerShipping{SellerID: animal.SellerID, Origin: origin, Destination: destination, Items: 1, LiveAnimals: isLive(animal)}
+	quote.Rates, err = shipping.Default.Quote(c.Request.Context(), shipping.QuoteRequest{
+		Origin: origin, Destination: destination, Items: 1, LiveAnimals: quote.LiveAnimals,
+	})
+	if err == nil && len(quote.Rates) == 0 {
+		err = shipping.ErrUnserviceable
+	}
+	if err != nil {
+		shippingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Shipping rates", quote))
+}
+
+// QuoteCartShipping quotes delivering each seller's part of the cart to one
+// of the buyer's addresses. Checkout takes one of the quoted services per
+// seller.
+func QuoteCartShipping(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var req ShippingQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+	address, ok := buyerAddress(c, userID.(int), req.AddressID)
+	if !ok {
+		return
+	}
+
+	quotes, ok := cartShipping(c, userID.(int), address)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Shipping rates", quotes))
+}
+
+// GetShipment returns the delivery of an order the user bought or sells,
+// with its tracking events.
+func GetShipment(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	orderID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	shipment, err := store.Default.GetShipment(orderID, userID.(int))
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrShipmentNotFound)
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch shipment", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Shipment details", shipment))
+}
+
+// ShipOrder books the carrier's pickup of a seller's processing order and
+// marks it shipped with the tracking number the carrier issued.
+func ShipOrder(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	orderID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	order, err := store.Default.GetOrderDetails(orderID, userID.(int))
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperr.ErrOrderNotFound)
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch order", err))
+		return
+	}
+	switch {
+	case order.SellerID == nil || *order.SellerID != userID.(int):
+		c.Error(apperr.ErrOrderForbidden)
+		return
+	case order.Shipment == nil:
+		c.Error(apperr.ErrShipmentNotFound)
+		return
+	case order.Status != store.OrderProcessing:
+		c.Error(apperr.ErrInvalidTransition)
+		return
+	}
+
+	s := order.Shipment
+	label, err := shipping.Default.CreateLabel(c.Request.Context(), shipping.LabelRequest{
+		Reference: fmt.Sprintf("TP-SHIP-%d", s.ID), Service: s.Service, Origin: s.Origin, Destination: s.City,
+		Items: order.Quantity, LiveAnimals: s.LiveAnimals, Recipient: s.RecipientName, Phone: s.Phone, Address: s.Address,
+	})
+	if err != nil {
+		shippingError(c, err)
+		return
+	}
+
+	order, err = store.Default.ShipOrder(orderID, userID.(int), label.TrackingNumber)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.Error(apperr.ErrShipmentNotFound)
+		case errors.Is(err, store.ErrInvalidTransition):
+			c.Error(apperr.ErrInvalidTransition)
+		default:
+			c.Error(apperr.Internal("Failed to ship order", err))
+		}
+		return
+	}
+	signOrder(order)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Order shipped", order))
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// shopPageListings is how many of its newest listings a shop page shows;
+// the rest are paged with GET /api/marketplace/animals?seller_id=.
+const shopPageListings = 12
+
+// shopSlug is the form of a shop's address: lowercase words joined by
+// hyphens.
+var shopSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ShopRequest describes a seller's shop. Slug is the shop's address in
+// /api/shops/{slug}; when it is left out a new shop gets one made from its
+// name and an existing shop keeps its own.
+type ShopRequest struct {
+	Slug         string                `json:"slug" binding:"omitempty,min=3,max=50"`
+	Name         string                `json:"name" binding:"required,max=100"`
+	Description  string                `json:"description" binding:"max=2000"`
+	LogoURL      string                `json:"logo_url" binding:"max=500"`
+	BannerURL    string                `json:"banner_url" binding:"max=500"`
+	Address      string                `json:"address" binding:"max=500"`
+	City         string                `json:"city" binding:"max=100"`
+	Phone        string                `json:"phone" binding:"omitempty,min=8,max=20"`
+	OpeningHours []OpeningHoursRequest `json:"opening_hours" binding:"max=7,dive"`
+}
+
+// OpeningHoursRequest gives a day's opening hours as HH:MM times.
+type OpeningHoursRequest struct {
+	Day    string `json:"day" binding:"required,oneof=mon tue wed thu fri sat sun"`
+	Opens  string `json:"opens" binding:"required"`
+	Closes string `json:"closes" binding:"required"`
+}
+
+// slugify makes a slug from a shop name, or returns "" when the name has
+// no letters or digits.
+func slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return strings.Trim(b.String()[:min(b.Len(), 50)], "-")
+}
+
+// bindShop reads a ShopRequest into a shop for the seller.
+func bindShop(c *gin.Context, sellerID int) (*models.Shop, bool) {
+	var req ShopRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return nil, false
+	}
+	if req.Slug != "" && !shopSlug.MatchString(req.Slug) {
+		c.Error(apperr.Invalid("slug", "must be lowercase letters and digits joined by hyphens"))
+		return nil, false
+	}
+
+	shop := &models.Shop{
+		SellerID: sellerID, Slug: req.Slug, Name: strings.TrimSpace(req.Name), Description: req.Description,
+		LogoURL: storedURL(req.LogoURL), BannerURL: storedURL(req.BannerURL), Address: req.Address, City: req.City,
+		Phone: req.Phone, OpeningHours: []models.OpeningHours{},
+	}
+	days := map[string]bool{}
+	for i, h := range req.OpeningHours {
+		field := fmt.Sprintf("opening_hours[%d]", i)
+		opens, err := time.Parse("15:04", h.Opens)
+		if err != nil {
+			c.Error(apperr.Invalid(field+".opens", "must be a time (HH:MM)"))
+			return nil, false
+		}
+		closes, err := time.Parse("15:04", h.Closes)
+		switch {
+		case err != nil:
+			c.Error(apperr.Invalid(field+".closes", "must be a time (HH:MM)"))
+			return nil, false
+		case !closes.After(opens):
+			c.Error(apperr.Invalid(field+".closes", "must be after opens"))
+			return nil, false
+		case days[h.Day]:
+			c.Error(apperr.Invalid(field+".day", "is listed more than once"))
+			return nil, false
+		}
+		days[h.Day] = true
+		shop.OpeningHours = append(shop.OpeningHours, models.OpeningHours{Day: h.Day, Opens: h.Opens, Closes: h.Closes})
+	}
+	return shop, true
+}
+
+// shopError reports a failed shop write.
+func shopError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, store.ErrHasShop):
+		c.Error(apperr.ErrShopExists)
+	case errors.Is(err, store.ErrConflict):
+		c.Error(apperr.ErrShopSlugTaken)
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(apperr.ErrShopNotFound)
+	default:
+		c.Error(apperr.Internal(message, err))
+	}
+}
+
+func signShop(s *models.Shop) {
+	s.LogoURL = signURL(s.LogoURL)
+	s.BannerURL = signURL(s.BannerURL)
+}
+
+// CreateShop opens the caller's shop. Without a slug the shop gets one
+// from its name, with the seller's id added if another shop has it.
+func CreateShop(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	shop, ok := bindShop(c, userID.(int))
+	if !ok {
+		return
+	}
+
+	var err error
+	if shop.Slug != "" {
+		err = store.Default.CreateShop(shop)
+	} else {
+		base := slugify(shop.Name)
+		if base == "" {
+			base = "shop"
+		}
+		for _, slug := range []string{base, fmt.Sprintf("%s-%d", base, shop.SellerID)} {
+			if len(slug) < 3 {
+				continue
+			}
+			shop.Slug = slug
+			if err = store.Default.CreateShop(shop); !errors.Is(err, store.ErrConflict) {
+				break
+			}
+		}
+	}
+	if err != nil {
+		shopError(c, "Failed to create shop", err)
+		return
+	}
+
+	signShop(shop)
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Shop created", shop))
+}
+
+// GetMyShop returns the caller's own shop.
+func GetMyShop(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	shop, err := store.Default.GetSellerShop(userID.(int))
+	if err != nil {
+		shopError(c, "Failed to fetch shop", err)
+		return
+	}
+
+	signShop(shop)
+	c.JSON(http.StatusOK, utils.SuccessResponse("Shop retrieved", shop))
+}
+
+// UpdateShop replaces the caller's shop. Changing the slug moves the shop
+// page; the old address stops working.
+func UpdateShop(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	shop, ok := bindShop(c, userID.(int))
+	if !ok {
+		return
+	}
+	if shop.Slug == "" {
+		current, err := store.Default.GetSellerShop(shop.SellerID)
+		if err != nil {
+			shopError(c, "Failed to fetch shop", err)
+			return
+		}
+		shop.Slug = current.Slug
+	}
+
+	if err := store.Default.UpdateShop(shop); err != nil {
+		shopError(c, "Failed to update shop", err)
+		return
+	}
+
+	signShop(shop)
+	c.JSON(http.StatusOK, utils.SuccessResponse("Shop updated", shop))
+}
+
+// GetShop is the public shop page: the shop, its ratings and response
+// figures, and its newest listings.
+func GetShop(c *gin.Context) {
+	shop, err := store.Default.GetShop(c.Param("slug"))
+	if err != nil {
+		shopError(c, "Failed to fetch shop", err)
+		return
+	}
+	stats, err := store.Default.ShopStats(shop.SellerID)
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch shop figures", err))
+		return
+	}
+	listings, err := store.Default.ListAnimals(store.AnimalFilter{SellerID: shop.SellerID}, store.PageRequest{Limit: shopPageListings})
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch shop listings", err))
+		return
+	}
+
+	signShop(shop)
+	page := models.ShopPage{Shop: *shop, Stats: *stats, Listings: listings.Items}
+	if page.Listings == nil {
+		page.Listings = []models.Animal{}
+	}
+	signEach(page.Listings, signAnimal)
+	c.JSON(http.StatusOK, utils.SuccessResponse("Shop retrieved", page))
+}
+
+// followedShop looks up the shop a follow request names by its slug.
+func followedShop(c *gin.Context) (*models.Shop, bool) {
+	shop, err := store.Default.GetShop(c.Param("slug"))
+	if err != nil {
+		shopError(c, "Failed to fetch shop", err)
+		return nil, false
+	}
+	return shop, true
+}
+
+func FollowShop(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	shop, ok := followedShop(c)
+	if !ok {
+		return
+	}
+
+	if err := store.Default.FollowShop(shop.ID, userID.(int)); err != nil {
+		shopError(c, "Failed to follow shop", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Shop followed", nil))
+}
+
+func UnfollowShop(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	shop, ok := followedShop(c)
+	if !ok {
+		return
+	}
+
+	if err := store.Default.UnfollowShop(shop.ID, userID.(int)); err != nil {
+		c.Error(apperr.Internal("Failed to unfollow shop", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Shop unfollowed", nil))
+}
+
+// GetFollowedShops lists the shops the caller follows, latest follow
+// first.
+func GetFollowedShops(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	q, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	shops, err := store.Default.ListFollowedShops(userID.(int), q.PageRequest)
+	if err != nil {
+		listError(c, "Failed to fetch followed shops", err)
+		return
+	}
+
+	signEach(shops.Items, signShop)
+	respondPage(c, "Followed shops retrieved", q, shops)
+}
+
+// GetShopFeed lists the available listings of the shops the caller
+// follows, newest first.
+func GetShopFeed(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	q, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	animals, err := store.Default.ListAnimals(store.AnimalFilter{FollowedBy: userID.(int)}, q.PageRequest)
+	if err != nil {
+		listError(c, "Failed to fetch feed", err)
+		return
+	}
+
+	signEach(animals.Items, signAnimal)
+	respondPage(c, "Feed retrieved", q, animals)
+}
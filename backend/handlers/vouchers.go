@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/store"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateVoucherRequest describes a seller voucher. Value is a percentage
+// for percentage discounts and an amount in rupiah for fixed ones.
+type CreateVoucherRequest struct {
+	Code         string     `json:"code" binding:"required,alphanum,min=4,max=20"`
+	Description  string     `json:"description" binding:"max=500"`
+	DiscountType string     `json:"discount_type" binding:"required,oneof=percentage fixed"`
+	Value        float64    `json:"value" binding:"required,gt=0"`
+	MaxDiscount  *float64   `json:"max_discount" binding:"omitempty,gt=0"`
+	MinSpend     float64    `json:"min_spend" binding:"min=0"`
+	UsageLimit   *int       `json:"usage_limit" binding:"omitempty,min=1"`
+	PerUserLimit int        `json:"per_user_limit" binding:"omitempty,min=1"`
+	CategoryID   *int       `json:"category_id"`
+	Public       bool       `json:"public"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+}
+
+type VoucherCodeRequest struct {
+	Code string `json:"code" binding:"required,max=50"`
+}
+
+// voucherError reports a voucher code that cannot be used, giving the
+// store's reason.
+func voucherError(c *gin.Context, message string, err error) {
+	var unusable *store.VoucherError
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(apperr.ErrVoucherNotFound)
+	case errors.As(err, &unusable):
+		c.Error(apperr.ErrVoucherInvalid.WithMessage("This voucher " + unusable.Reason))
+	default:
+		c.Error(apperr.Internal(message, err))
+	}
+}
+
+// CreateVoucher lets a seller issue a voucher for their own listings.
+func CreateVoucher(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var req CreateVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+	switch {
+	case req.DiscountType == models.VoucherPercentage && req.Value > 100:
+		c.Error(apperr.Invalid("value", "must be at most 100 for percentage discounts"))
+		return
+	case req.DiscountType == models.VoucherFixed && req.MaxDiscount != nil:
+		c.Error(apperr.Invalid("max_discount", "only applies to percentage discounts"))
+		return
+	case req.EndsAt != nil && req.StartsAt != nil && !req.EndsAt.After(*req.StartsAt):
+		c.Error(apperr.Invalid("ends_at", "must be after starts_at"))
+		return
+	case req.EndsAt != nil && !req.EndsAt.After(time.Now()):
+		c.Error(apperr.Invalid("ends_at", "must be in the future"))
+		return
+	}
+
+	sellerID := userID.(int)
+	v := &models.Voucher{
+		Code: req.Code, Description: req.Description, DiscountType: req.DiscountType, Value: req.Value,
+		MaxDiscount: req.MaxDiscount, MinSpend: req.MinSpend, UsageLimit: req.UsageLimit, PerUserLimit: req.PerUserLimit,
+		SellerID: &sellerID, CategoryID: req.CategoryID, Public: req.Public, EndsAt: req.EndsAt,
+	}
+	if v.PerUserLimit == 0 {
+		v.PerUserLimit = 1
+	}
+	if req.StartsAt != nil {
+		v.StartsAt = *req.StartsAt
+	}
+
+	err := store.Default.CreateVoucher(v)
+	switch {
+	case errors.Is(err, store.ErrConflict):
+		c.Error(apperr.ErrVoucherExists)
+		return
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(apperr.Invalid("category_id", "does not exist"))
+		return
+	case err != nil:
+		c.Error(apperr.Internal("Failed to create voucher", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Voucher created", v))
+}
+
+// GetVouchers lists the public vouchers that can be used now.
+func GetVouchers(c *gin.Context) {
+	q, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	vouchers, err := store.Default.ListVouchers(q.PageRequest)
+	if err != nil {
+		listError(c, "Failed to fetch vouchers", err)
+		return
+	}
+
+	respondPage(c, "Vouchers retrieved", q, vouchers)
+}
+
+// ClaimVoucher saves a voucher to the user's wallet by its code.
+func ClaimVoucher(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var req VoucherCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	voucher, err := store.Default.ClaimVoucher(userID.(int), req.Code)
+	if errors.Is(err, store.ErrConflict) {
+		c.Error(apperr.ErrVoucherClaimed)
+		return
+	}
+	if err != nil {
+		voucherError(c, "Failed to claim voucher", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse("Voucher claimed", voucher))
+}
+
+// GetMyVouchers lists the vouchers the user has claimed, including ones
+// they can no longer use.
+func GetMyVouchers(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	q, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	vouchers, err := store.Default.ListUserVouchers(userID.(int), q.PageRequest)
+	if err != nil {
+		listError(c, "Failed to fetch vouchers", err)
+		return
+	}
+
+	respondPage(c, "Vouchers retrieved", q, vouchers)
+}
+
+// QuoteVoucher checks a voucher code against the cart and returns what it
+// would take off at checkout.
+func QuoteVoucher(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var req VoucherCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	quote, err := store.Default.QuoteVoucher(userID.(int), req.Code)
+	if err != nil {
+		voucherError(c, "Failed to check voucher", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse("Voucher applies", quote))
+}
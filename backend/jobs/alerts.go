@@ -0,0 +1,47 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/TerraPaw/backend/notify"
+	"github.com/TerraPaw/backend/store"
+)
+
+// alertBatch caps the wishlist entries matched per run.
+const alertBatch = 500
+
+// SendWishlistAlerts tells users when a listing on their wishlist gets
+// cheaper or comes back in stock. The in-app notification is written by
+// the store; copies by email or push are best effort and not retried.
+func SendWishlistAlerts(ctx context.Context) error {
+	alerts, err := store.Default.MatchWishlistAlerts(alertBatch)
+	if err != nil {
+		return err
+	}
+	var messages []notify.Message
+	for _, a := range alerts {
+		msg := notify.Message{UserID: a.Notification.UserID, Title: a.Notification.Title, Body: a.Notification.Message}
+		if a.Email != "" {
+			email := msg
+			email.Channel, email.To = notify.ChannelEmail, a.Email
+			messages = append(messages, email)
+		}
+		if a.Push {
+			msg.Channel = notify.ChannelPush
+			messages = append(messages, msg)
+		}
+	}
+	failed := 0
+	for _, msg := range messages {
+		if err := notify.Default.Send(ctx, msg); err != nil {
+			log.Printf("Sending %s alert to user %d failed: %v", msg.Channel, msg.UserID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d wishlist alert messages failed to send", failed, len(messages))
+	}
+	return nil
+}
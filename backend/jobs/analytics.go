@@ -0,0 +1,12 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/TerraPaw/backend/store"
+)
+
+// RefreshAnalytics rebuilds the rollups behind the seller analytics.
+func RefreshAnalytics(ctx context.Context) error {
+	return store.Default.RefreshAnalytics()
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/TerraPaw/backend/geo"
+	"github.com/TerraPaw/backend/store"
+)
+
+// geocodeBatch caps the listings read at a time.
+const geocodeBatch = 500
+
+// LocateListings geocodes the listings that have a location but no
+// coordinates, such as those created before distance search or while the
+// geocoder was down. Places the geocoder does not know are left as they
+// are and tried again on the next run.
+func LocateListings(ctx context.Context) error {
+	after := 0
+	for {
+		listings, err := store.Default.ListUnlocatedAnimals(after, geocodeBatch)
+		if err != nil || len(listings) == 0 {
+			return err
+		}
+		for _, a := range listings {
+			after = a.ID
+			p, err := geo.Default.Geocode(ctx, a.Location)
+			if errors.Is(err, geo.ErrUnknownPlace) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := store.Default.LocateAnimal(a.ID, a.Location, *p); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/TerraPaw/backend/payments"
+	"github.com/TerraPaw/backend/store"
+)
+
+// refundBatch caps the refunds sent to the gateway per run.
+const refundBatch = 50
+
+// ExpireUnpaidOrders returns a job that cancels orders still unpaid window
+// after they were placed. Cancelling puts their stock back.
+func ExpireUnpaidOrders(window time.Duration) func(context.Context) error {
+	return func(ctx context.Context) error {
+		n, err := store.Default.ExpireUnpaidOrders(time.Now().Add(-window))
+		if n > 0 {
+			log.Printf("Cancelled %d unpaid orders", n)
+		}
+		return err
+	}
+}
+
+// SendRefunds sends queued refunds to the payment gateway. A refund that
+// fails stays queued and is retried on the next run, and while payments are
+// disabled every refund stays queued.
+func SendRefunds(ctx context.Context) error {
+	if payments.Default == nil {
+		return nil
+	}
+	refunds, err := store.Default.ListPendingRefunds(refundBatch)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, r := range refunds {
+		if r.Provider != payments.Default.Name() {
+			continue
+		}
+		amount := int64(math.Round(r.Amount))
+		err := payments.Default.Refund(ctx, r.ChargeID, fmt.Sprintf("TP-REFUND-%d", r.ID), amount)
+		if err == nil {
+			err = store.Default.CompleteRefund(r.ID)
+		}
+		if err != nil {
+			log.Printf("Refund %d failed: %v", r.ID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d refunds failed", failed, len(refunds))
+	}
+	return nil
+}
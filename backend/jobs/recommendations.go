@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/TerraPaw/backend/recommend"
+	"github.com/TerraPaw/backend/store"
+)
+
+// interestsPerUser caps the listings each user ordered or saved that count
+// toward co-interest, latest first.
+const interestsPerUser = 50
+
+// RecomputeRecommendations works out the similar listings of every listing
+// from their details and from what users ordered and saved together, and
+// replaces the stored ones.
+func RecomputeRecommendations(ctx context.Context) error {
+	listings, err := store.Default.ListRecommendable()
+	if err != nil {
+		return err
+	}
+	baskets, err := store.Default.ListInterests(interestsPerUser)
+	if err != nil {
+		return err
+	}
+	return store.Default.ReplaceSimilarities(recommend.Similar(listings, baskets))
+}
@@ -0,0 +1,47 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/shipping"
+	"github.com/TerraPaw/backend/store"
+)
+
+// trackingBatch caps the shipments polled at the carrier per run.
+const trackingBatch = 100
+
+// TrackShipments fetches the scans of shipments on their way and records
+// the new ones. A delivered scan marks the order delivered.
+func TrackShipments(ctx context.Context) error {
+	shipments, err := store.Default.ListActiveShipments(trackingBatch)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, s := range shipments {
+		if s.Carrier != shipping.Default.Name() {
+			continue
+		}
+		scans, err := shipping.Default.Track(ctx, s.TrackingNumber)
+		if err == nil {
+			events := make([]models.ShipmentEvent, len(scans))
+			for i, scan := range scans {
+				events[i] = models.ShipmentEvent{
+					Status: scan.Status, Description: scan.Description, Location: scan.Location, OccurredAt: scan.OccurredAt,
+				}
+			}
+			err = store.Default.AddShipmentEvents(s.ID, events)
+		}
+		if err != nil {
+			log.Printf("Tracking shipment %d failed: %v", s.ID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d shipments failed to track", failed, len(shipments))
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Package media validates uploaded images and prepares them for storage:
+// the type is sniffed from the content, the image is re-encoded so EXIF,
+// GPS and other metadata are dropped, and a JPEG thumbnail is generated.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+var (
+	// ErrUnsupportedType is returned for content that is not a JPEG, PNG or
+	// GIF image, whatever its file name or declared type says.
+	ErrUnsupportedType = errors.New("unsupported file type")
+	// ErrInvalidImage is returned when the content sniffs as an image but
+	// cannot be decoded.
+	ErrInvalidImage = errors.New("invalid image")
+	// ErrTooManyPixels guards against decompression bombs: small files
+	// that decode to huge bitmaps.
+	ErrTooManyPixels = errors.New("image dimensions too large")
+)
+
+// Types maps the accepted content types to the extension stored files get.
+var Types = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+const (
+	// MaxPixels bounds width × height of an accepted image.
+	MaxPixels = 40_000_000
+	// ThumbnailSize is the longest side of generated thumbnails.
+	ThumbnailSize = 320
+)
+
+// Image is a processed upload.
+type Image struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+	Thumbnail   []byte // JPEG
+}
+
+// Process checks that data is an accepted image and returns it stripped of
+// metadata, with a thumbnail. JPEG orientation is applied to the pixels
+// before the EXIF block carrying it is dropped.
+func Process(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := Types[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	var out bytes.Buffer
+	var img image.Image
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		img = orient(img, exifOrientation(data))
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: 90})
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		err = png.Encode(&out, img)
+	case "image/gif":
+		var g *gif.GIF
+		g, err = gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(g.Image) == 0 {
+			return nil, ErrInvalidImage
+		}
+		img = g.Image[0]
+		// Re-encoding keeps the frames and loop count and drops comment
+		// and application extensions.
+		err = gif.EncodeAll(&out, g)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, thumbnail(img, ThumbnailSize), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	if contentType == "image/gif" {
+		// The first frame may cover only part of the canvas.
+		b = image.Rect(0, 0, cfg.Width, cfg.Height)
+	}
+	return &Image{
+		Data:        out.Bytes(),
+		ContentType: contentType,
+		Ext:         ext,
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+		Thumbnail:   thumb.Bytes(),
+	}, nil
+}
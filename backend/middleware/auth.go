@@ -1,9 +1,10 @@
 package middleware
 
 import (
-	"net/http"
 	"strings"
 
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/store"
 	"github.com/TerraPaw/backend/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -12,24 +13,21 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized", "Missing authorization header"))
-			c.Abort()
+			apperr.Abort(c, apperr.ErrUnauthorized.WithMessage("Missing authorization header"))
 			return
 		}
 
 		// Extract token from "Bearer <token>"
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized", "Invalid authorization header format"))
-			c.Abort()
+			apperr.Abort(c, apperr.ErrUnauthorized.WithMessage("Invalid authorization header format"))
 			return
 		}
 
 		token := parts[1]
 		claims, err := utils.ValidateToken(token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized", "Invalid or expired token"))
-			c.Abort()
+			apperr.Abort(c, apperr.ErrUnauthorized.WithMessage("Invalid or expired token"))
 			return
 		}
 
@@ -39,3 +37,21 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// ModeratorMiddleware lets only moderators through. It runs after
+// AuthMiddleware.
+func ModeratorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		moderator, err := store.Default.IsModerator(userID.(int))
+		if err != nil {
+			apperr.Abort(c, apperr.Internal("Failed to check moderator", err))
+			return
+		}
+		if !moderator {
+			apperr.Abort(c, apperr.ErrNotModerator)
+			return
+		}
+		c.Next()
+	}
+}
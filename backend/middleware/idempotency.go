@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/store"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyHeader is the request header clients send to make a POST safe to retry.
+	IdempotencyHeader = "Idempotency-Key"
+	// IdempotencyReplayedHeader is set on responses replayed from a stored key.
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	idempotencyTTL       = 24 * time.Hour
+	maxIdempotencyKeyLen = 255
+
+	// idempotencyLease is how long a key stays in flight before a retry
+	// may take it over, in case the process died mid-request.
+	idempotencyLease = time.Minute
+)
+
+// Idempotency makes the route safe to retry when the client sends an
+// Idempotency-Key header. The first request with a key runs normally and its
+// response is stored; retries with the same key and payload get the stored
+// response without running the handler again. Reusing a key with a
+// different payload is rejected. Keys are scoped to the authenticated user,
+// so the middleware must run after AuthMiddleware. Requests without the
+// header are unaffected.
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			apperr.Abort(c, apperr.Invalid(IdempotencyHeader, "must be at most 255 characters"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			apperr.Abort(c, apperr.Invalid("body", "could not be read"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := c.GetInt("user_id")
+		record := &models.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: fingerprint(c.Request.Method, c.Request.URL.Path, body),
+		}
+
+		existing, err := store.Default.ClaimIdempotencyKey(record, idempotencyTTL, idempotencyLease)
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			replay(c, record, existing)
+			return
+		case err != nil:
+			apperr.Abort(c, apperr.Internal("Failed to process idempotency key", err))
+			return
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				// Don't leave the key stuck in flight until it expires.
+				_ = store.Default.ReleaseIdempotencyKey(userID, key)
+				panic(r)
+			}
+		}()
+
+		rec := &recorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+
+		// Errors are normally rendered by apperr.Middleware further out;
+		// render them here so the stored response matches what was sent.
+		if len(c.Errors) > 0 && !c.Writer.Written() {
+			apperr.Render(c, c.Errors.Last().Err)
+		}
+
+		status := c.Writer.Status()
+		if status >= http.StatusInternalServerError {
+			// Server errors are not final; let the client retry with the same key.
+			err = store.Default.ReleaseIdempotencyKey(userID, key)
+		} else {
+			err = store.Default.SaveIdempotentResponse(userID, key, status, rec.body.Bytes())
+		}
+		if err != nil {
+			log.Printf("idempotency: failed to record response for key %q: %v", key, err)
+		}
+	}
+}
+
+func replay(c *gin.Context, record, existing *models.IdempotencyKey) {
+	switch {
+	case existing.Fingerprint != record.Fingerprint:
+		apperr.Abort(c, apperr.ErrIdempotencyKeyReused)
+	case existing.Status == 0:
+		apperr.Abort(c, apperr.ErrIdempotencyInProgress)
+	default:
+		c.Header(IdempotencyReplayedHeader, "true")
+		c.Data(existing.Status, "application/json; charset=utf-8", existing.Body)
+		c.Abort()
+	}
+}
+
+// fingerprint identifies a request by its method, path and body. The path
+// is the one requested, not the route, so the same key sent to two orders'
+// payments is a reuse rather than a retry.
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder tees the response body so it can be stored for replay.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -11,6 +11,9 @@ type User struct {
 	AvatarURL string    `json:"avatar_url"`
 	Bio       string    `json:"bio"`
 	UserType  string    `json:"user_type"`
+	Location  string    `json:"location,omitempty"`  // only on the user's own profile
+	Latitude  *float64  `json:"latitude,omitempty"`  // of Location
+	Longitude *float64  `json:"longitude,omitempty"` // of Location
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -68,6 +71,8 @@ type Animal struct {
 	ID          int           `json:"id"`
 	SellerID    int           `json:"seller_id"`
 	AnimalType  string        `json:"animal_type"`
+	CategoryID  *int          `json:"category_id"`          // the category named AnimalType; nil when none is
+	Attributes  AttributeMap  `json:"attributes,omitempty"` // as its category's schema asks
 	Breed       string        `json:"breed"`
 	Name        string        `json:"name"`
 	Age         int           `json:"age"`
@@ -75,28 +80,398 @@ type Animal struct {
 	Price       float64       `json:"price"`
 	ImageURL    string        `json:"image_url"` // Main thumbnail
 	Location    string        `json:"location"`
-	Rating      float64       `json:"rating"`
+	Latitude    *float64      `json:"latitude"`              // of Location; nil when unknown
+	Longitude   *float64      `json:"longitude"`             // of Location; nil when unknown
+	DistanceKm  *float64      `json:"distance_km,omitempty"` // from the point searched near
+	Rating      float64       `json:"rating"`                // mean of its reviews
+	ReviewCount int           `json:"review_count"`
 	Status      string        `json:"status"`
 	Color       string        `json:"color"`
 	Gender      string        `json:"gender"`
 	Stock       int           `json:"stock"`
-	Media       []AnimalMedia `json:"media,omitempty"` // Added
+	Popularity  int           `json:"popularity"`          // orders plus wishlist saves
+	Relevance   float64       `json:"relevance,omitempty"` // search rank, set when searching
+	Media       []AnimalMedia `json:"media,omitempty"`     // Added
 	Seller      *User         `json:"seller,omitempty"`
+	Shop        *ShopRef      `json:"shop,omitempty"` // nil when the seller has no shop
 	CreatedAt   time.Time     `json:"created_at"`
 	UpdatedAt   time.Time     `json:"updated_at"`
 }
 
+// PriceChange records one edit of a listing's price.
+type PriceChange struct {
+	ID        int       `json:"id"`
+	AnimalID  int       `json:"animal_id"`
+	OldPrice  float64   `json:"old_price"`
+	NewPrice  float64   `json:"new_price"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// ListingCounts is the number of a seller's listings in each status.
+type ListingCounts struct {
+	Available int `json:"available"`
+	Reserved  int `json:"reserved"`
+	Sold      int `json:"sold"`
+	Archived  int `json:"archived"`
+}
+
+// Order is either a single-listing order or a checkout. A checkout is a
+// header order holding the buyer's total, with one sub-order per seller
+// that carries that seller's lines. Items on a header list every line of
+// the checkout.
 type Order struct {
+	ID         int                 `json:"id"`
+	BuyerID    int                 `json:"buyer_id"`
+	AnimalID   int                 `json:"animal_id,omitempty"`     // single-listing orders only
+	ParentID   *int                `json:"parent_id,omitempty"`     // set on sub-orders
+	SellerID   *int                `json:"seller_id,omitempty"`     // nil on checkout headers
+	TotalPrice float64             `json:"total_price"`             // after any discount
+	Discount   float64             `json:"discount,omitempty"`      // taken off by a voucher
+	VoucherID  *int                `json:"voucher_id,omitempty"`    // set on the order the voucher was applied to
+	Shipping   float64             `json:"shipping_cost,omitempty"` // included in TotalPrice
+	Status     string              `json:"status"`
+	Quantity   int                 `json:"quantity"`
+	Animal     *Animal             `json:"animal,omitempty"`
+	Buyer      *User               `json:"buyer,omitempty"`
+	Items      []OrderItem         `json:"items,omitempty"`
+	SubOrders  []Order             `json:"sub_orders,omitempty"`
+	Shipment   *Shipment           `json:"shipment,omitempty"` // orders with a seller, when delivery was booked
+	History    []OrderStatusChange `json:"history,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+// OrderStatusChange records one step of an order's lifecycle. ChangedBy is
+// nil for changes made by the system, such as payment confirmations.
+type OrderStatusChange struct {
 	ID         int       `json:"id"`
-	BuyerID    int       `json:"buyer_id"`
-	AnimalID   int       `json:"animal_id"`
-	TotalPrice float64   `json:"total_price"`
-	Status     string    `json:"status"`
-	Quantity   int       `json:"quantity"`
-	Animal     *Animal   `json:"animal,omitempty"`
-	Buyer      *User     `json:"buyer,omitempty"`
+	OrderID    int       `json:"order_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  *int      `json:"changed_by"`
+	Role       string    `json:"role"`
+	Note       string    `json:"note,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Address is an entry in a buyer's address book. A buyer with addresses
+// has exactly one default.
+type Address struct {
+	ID            int       `json:"id"`
+	UserID        int       `json:"user_id"`
+	Label         string    `json:"label"` // e.g. Rumah, Kantor
+	RecipientName string    `json:"recipient_name"`
+	Phone         string    `json:"phone"`
+	Street        string    `json:"street"`
+	City          string    `json:"city"`
+	Province      string    `json:"province"`
+	PostalCode    string    `json:"postal_code"`
+	IsDefault     bool      `json:"is_default"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Shipment is the delivery of an order with a seller. It is booked at
+// checkout with a copy of the buyer's address and gets a tracking number
+// when the seller hands the parcel to the carrier.
+type Shipment struct {
+	ID             int             `json:"id"`
+	OrderID        int             `json:"order_id"`
+	Carrier        string          `json:"carrier"`
+	Service        string          `json:"service"`
+	LiveAnimals    bool            `json:"live_animals"`
+	Cost           float64         `json:"cost"`
+	MinDays        int             `json:"min_days"`
+	MaxDays        int             `json:"max_days"`
+	Origin         string          `json:"origin"` // city
+	RecipientName  string          `json:"recipient_name"`
+	Phone          string          `json:"phone"`
+	Address        string          `json:"address"`
+	City           string          `json:"city"`
+	PostalCode     string          `json:"postal_code"`
+	TrackingNumber string          `json:"tracking_number,omitempty"`
+	Status         string          `json:"status"` // pending until the label is created, then the latest scan
+	ShippedAt      *time.Time      `json:"shipped_at,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	Events         []ShipmentEvent `json:"events,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// ShipmentEvent is one tracking scan of a shipment.
+type ShipmentEvent struct {
+	ID          int       `json:"id"`
+	ShipmentID  int       `json:"shipment_id"`
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
+	Location    string    `json:"location"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// SellerAnalytics summarises a seller's sales from the analytics rollups,
+// which lag live orders until the next refresh at RefreshedAt. Sales cover
+// paid orders from From up to To; the funnel and rating cover all time.
+type SellerAnalytics struct {
+	Period        string               `json:"period"`
+	From          time.Time            `json:"from"`
+	To            time.Time            `json:"to"`      // exclusive
+	Revenue       float64              `json:"revenue"` // after discounts, without shipping
+	Orders        int                  `json:"orders"`
+	Units         int                  `json:"units"`
+	Sales         []SalesBucket        `json:"sales"`
+	TopListings   []ListingPerformance `json:"top_listings"`
+	Funnel        ConversionFunnel     `json:"funnel"`
+	StockAlerts   []StockAlert         `json:"stock_alerts"`
+	AverageRating float64              `json:"average_rating"`
+	RatingCount   int                  `json:"rating_count"`
+	RefreshedAt   *time.Time           `json:"refreshed_at"` // nil until the rollups are first refreshed
+}
+
+// SalesBucket is a seller's paid orders in the day, week (from Monday) or
+// month starting at Start.
+type SalesBucket struct {
+	Start   time.Time `json:"start"`
+	Orders  int       `json:"orders"`
+	Units   int       `json:"units"`
+	Revenue float64   `json:"revenue"`
+}
+
+// ListingPerformance is how one listing sold over the requested range.
+// Revenue is at list price, before vouchers.
+type ListingPerformance struct {
+	AnimalID    int     `json:"animal_id"`
+	Name        string  `json:"name"`
+	Orders      int     `json:"orders"`
+	Units       int     `json:"units"`
+	Revenue     float64 `json:"revenue"`
+	Views       int     `json:"views"`
+	Wishlists   int     `json:"wishlists"`
+	Rating      float64 `json:"rating"`
+	ReviewCount int     `json:"review_count"`
+}
+
+// ConversionFunnel follows a seller's listings from views to wishlist
+// saves to paid orders. Rates are fractions, 0 when there is nothing to
+// convert from.
+type ConversionFunnel struct {
+	Views           int     `json:"views"`
+	Wishlists       int     `json:"wishlists"`
+	Orders          int     `json:"orders"`
+	ViewToWishlist  float64 `json:"view_to_wishlist"`
+	WishlistToOrder float64 `json:"wishlist_to_order"`
+	ViewToOrder     float64 `json:"view_to_order"`
+}
+
+// StockAlert flags a listing that has run out or is about to. DaysLeft
+// projects its stock at the last 30 days' sales, and is nil without sales.
+type StockAlert struct {
+	AnimalID        int      `json:"animal_id"`
+	Name            string   `json:"name"`
+	Status          string   `json:"status"`
+	Stock           int      `json:"stock"`
+	UnitsLast30Days int      `json:"units_last_30_days"`
+	DaysLeft        *float64 `json:"days_left,omitempty"`
+}
+
+// Shop is a seller's storefront. A seller has at most one, found by its
+// slug.
+type Shop struct {
+	ID            int            `json:"id"`
+	SellerID      int            `json:"seller_id"`
+	Slug          string         `json:"slug"`
+	Name          string         `json:"name"`
+	Description   string         `json:"description"`
+	LogoURL       string         `json:"logo_url"`
+	BannerURL     string         `json:"banner_url"`
+	Address       string         `json:"address"`
+	City          string         `json:"city"`
+	Phone         string         `json:"phone"`
+	OpeningHours  []OpeningHours `json:"opening_hours"`
+	FollowerCount int            `json:"follower_count"`
+	FollowedAt    *time.Time     `json:"followed_at,omitempty"` // set in the viewer's list of followed shops
+	Seller        *User          `json:"seller,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// OpeningHours is when a shop is open on one day of the week. Times are
+// HH:MM, local to the shop.
+type OpeningHours struct {
+	Day    string `json:"day"` // mon to sun
+	Opens  string `json:"opens"`
+	Closes string `json:"closes"`
+}
+
+// ShopRef names the shop a listing belongs to.
+type ShopRef struct {
+	ID   int    `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// ShopStats are the public figures on a shop page. Response figures cover
+// chats buyers started with the seller in the last 90 days and are nil
+// when there were none.
+type ShopStats struct {
+	Listings      int      `json:"listings"` // available
+	Sold          int      `json:"sold"`     // units on paid orders
+	Rating        float64  `json:"rating"`   // over all of the seller's listings
+	ReviewCount   int      `json:"review_count"`
+	ResponseRate  *float64 `json:"response_rate"`  // share of chats the seller answered
+	ResponseHours *float64 `json:"response_hours"` // mean time to the first answer
+}
+
+// ShopPage is a shop with its figures and newest listings.
+type ShopPage struct {
+	Shop     Shop      `json:"shop"`
+	Stats    ShopStats `json:"stats"`
+	Listings []Animal  `json:"listings"`
+}
+
+// Payment is one attempt to pay for an order through the payment gateway.
+// Payments belong to single-listing orders and checkout headers; an order
+// has at most one pending payment at a time.
+type Payment struct {
+	ID             int        `json:"id"`
+	OrderID        int        `json:"order_id"`
+	Provider       string     `json:"provider"`
+	Reference      string     `json:"reference"`
+	ChargeID       string     `json:"charge_id,omitempty"`
+	Method         string     `json:"method"`
+	Bank           string     `json:"bank,omitempty"`
+	VANumber       string     `json:"va_number,omitempty"`
+	QRString       string     `json:"qr_string,omitempty"`
+	Amount         float64    `json:"amount"`
+	RefundedAmount float64    `json:"refunded_amount"`
+	Status         string     `json:"status"` // pending, paid, expired or failed
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	PaidAt         *time.Time `json:"paid_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// PaymentEvent is a webhook event from the payment gateway. Events are
+// kept so that redelivered ones are only applied once.
+type PaymentEvent struct {
+	ID        int       `json:"id"`
+	Provider  string    `json:"provider"`
+	EventID   string    `json:"event_id"`
+	ChargeID  string    `json:"charge_id"`
+	Status    string    `json:"status"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PaymentRefund is money owed back on a paid payment. Refunds are queued
+// with the order change that causes them and sent to the gateway later.
+type PaymentRefund struct {
+	ID         int        `json:"id"`
+	PaymentID  int        `json:"payment_id"`
+	OrderID    int        `json:"order_id"`
+	Amount     float64    `json:"amount"`
+	Status     string     `json:"status"` // pending or done
+	Provider   string     `json:"provider"`
+	ChargeID   string     `json:"charge_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RefundedAt *time.Time `json:"refunded_at,omitempty"`
+}
+
+// OrderItem is one listing bought in an order, at the price paid.
+type OrderItem struct {
+	ID        int         `json:"id"`
+	OrderID   int         `json:"order_id"`
+	AnimalID  int         `json:"animal_id,omitempty"`  // a listing,
+	VariantID int         `json:"variant_id,omitempty"` // or a product variant
+	SellerID  int         `json:"seller_id"`
+	Quantity  int         `json:"quantity"`
+	UnitPrice float64     `json:"unit_price"`
+	Subtotal  float64     `json:"subtotal"`
+	Animal    *Animal     `json:"animal,omitempty"`
+	Variant   *VariantRef `json:"variant,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Cart problems, reported on CartItem.Problem when the listing changed
+// after it was added.
+const (
+	CartUnavailable       = "unavailable"
+	CartInsufficientStock = "insufficient_stock"
+	CartPriceChanged      = "price_changed"
+)
+
+// CartItem is a listing or a product variant in a buyer's cart. AddedPrice
+// is the price the buyer last saw; Price and Subtotal use the current
+// price.
+type CartItem struct {
+	ID         int         `json:"id"`
+	AnimalID   int         `json:"animal_id,omitempty"`  // a listing,
+	VariantID  int         `json:"variant_id,omitempty"` // or a product variant
+	Quantity   int         `json:"quantity"`
+	AddedPrice float64     `json:"added_price"`
+	Price      float64     `json:"price"`
+	Subtotal   float64     `json:"subtotal"`
+	Problem    string      `json:"problem,omitempty"`
+	Animal     *Animal     `json:"animal,omitempty"`
+	Variant    *VariantRef `json:"variant,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// Cart is a buyer's cart, revalidated against the current listings.
+// Subtotal covers the items that can still be bought.
+type Cart struct {
+	Items       []CartItem `json:"items"`
+	ItemCount   int        `json:"item_count"`
+	Subtotal    float64    `json:"subtotal"`
+	CanCheckout bool       `json:"can_checkout"`
+}
+
+// Voucher discount types.
+const (
+	VoucherPercentage = "percentage"
+	VoucherFixed      = "fixed"
+)
+
+// Voucher is a discount code. Platform vouchers have no SellerID; seller
+// vouchers only discount that seller's listings. CategoryID further limits
+// a voucher to listings of one category. Public vouchers are listed and can
+// be claimed; any active voucher can be applied by code at checkout.
+type Voucher struct {
+	ID           int        `json:"id"`
+	Code         string     `json:"code"`
+	Description  string     `json:"description"`
+	DiscountType string     `json:"discount_type"`          // percentage or fixed
+	Value        float64    `json:"value"`                  // percent off, or rupiah off
+	MaxDiscount  *float64   `json:"max_discount,omitempty"` // caps percentage discounts
+	MinSpend     float64    `json:"min_spend"`              // on eligible items
+	UsageLimit   *int       `json:"usage_limit,omitempty"`  // redemptions across all users
+	PerUserLimit int        `json:"per_user_limit"`
+	UsedCount    int        `json:"used_count"`
+	SellerID     *int       `json:"seller_id,omitempty"`
+	CategoryID   *int       `json:"category_id,omitempty"`
+	Public       bool       `json:"public"`
+	StartsAt     time.Time  `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// UserVoucher is a voucher in a user's wallet. Usable is false once it has
+// expired, run out, or been used as often as the user may.
+type UserVoucher struct {
+	Voucher
+	TimesUsed int       `json:"times_used"`
+	Usable    bool      `json:"usable"`
+	ClaimedAt time.Time `json:"claimed_at"`
+}
+
+// VoucherQuote is what a voucher takes off the buyer's current cart.
+type VoucherQuote struct {
+	Voucher  Voucher `json:"voucher"`
+	Eligible float64 `json:"eligible_subtotal"` // cart lines the voucher applies to
+	Discount float64 `json:"discount"`
+	Subtotal float64 `json:"subtotal"`
+	Total    float64 `json:"total"`
 }
 
 type Veterinarian struct {
@@ -166,6 +541,8 @@ type MedicalRecord struct {
 	CreatedAt      time.Time     `json:"created_at"`
 }
 
+// Notification is an in-app message. Notifications with a DedupeKey are
+// written at most once per user and key.
 type Notification struct {
 	ID        int       `json:"id"`
 	UserID    int       `json:"user_id"`
@@ -173,6 +550,7 @@ type Notification struct {
 	Message   string    `json:"message"`
 	Type      string    `json:"type"`
 	IsRead    bool      `json:"is_read"`
+	DedupeKey string    `json:"-"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -186,22 +564,234 @@ type SplashEvent struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Wishlist is a listing a user saved. AlertPrice and AlertInStock are the
+// price and availability the user was last told about, or saw when saving
+// it; the alert job compares them with the listing.
 type Wishlist struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	AnimalID  int       `json:"animal_id"`
-	Animal    *Animal   `json:"animal,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	AnimalID     int       `json:"animal_id"`
+	AlertPrice   float64   `json:"-"`
+	AlertInStock bool      `json:"-"`
+	Animal       *Animal   `json:"animal,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Similarity says how alike listing SimilarID is to AnimalID, and why.
+type Similarity struct {
+	AnimalID  int      `json:"-"`
+	SimilarID int      `json:"-"`
+	Score     float64  `json:"score"`
+	Reasons   []string `json:"reasons"`
+}
+
+// Recommendation is a listing suggested to a user, with why. BecauseOf is
+// the listing the user ordered or saved that it is most like.
+type Recommendation struct {
+	Animal    *Animal  `json:"animal"`
+	Score     float64  `json:"score"`
+	Reasons   []string `json:"reasons"`
+	BecauseOf *int     `json:"because_of,omitempty"`
+}
+
+// GeoPoint is a position on the map, in degrees.
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// AlertPreferences says which wishlist alerts a user gets and where, on top
+// of the in-app notification. A price drop is only reported once it is at
+// least MinDropPercent of the price the user last saw.
+type AlertPreferences struct {
+	PriceDrop      bool `json:"price_drop"`
+	BackInStock    bool `json:"back_in_stock"`
+	MinDropPercent int  `json:"min_drop_percent"`
+	Email          bool `json:"email"`
+	Push           bool `json:"push"`
 }
 
+// Review is a buyer's rating of a listing. Reviews written against a
+// completed order line are verified purchases; older reviews have no line.
+// RemovedAt is set when a moderator took the review down.
 type Review struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	OrderID   *int      `json:"order_id,omitempty"`
-	AnimalID  int       `json:"animal_id"`
-	Rating    int       `json:"rating"`
-	Comment   string    `json:"comment"`
-	ImageURL  string    `json:"image_url"`
-	User      *User     `json:"user,omitempty"`
+	ID           int          `json:"id"`
+	UserID       int          `json:"user_id"`
+	OrderID      *int         `json:"order_id,omitempty"`
+	OrderItemID  *int         `json:"order_item_id,omitempty"`
+	AnimalID     int          `json:"animal_id"`
+	Rating       int          `json:"rating"`
+	Comment      string       `json:"comment"`
+	ImageURL     string       `json:"image_url"`
+	Verified     bool         `json:"verified_purchase"`
+	HelpfulCount int          `json:"helpful_count"`
+	Reply        *ReviewReply `json:"reply,omitempty"`
+	User         *User        `json:"user,omitempty"`
+	RemovedAt    *time.Time   `json:"removed_at,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// ReviewReply is the seller's public answer to a review.
+type ReviewReply struct {
+	SellerID  int       `json:"seller_id"`
+	Body      string    `json:"body"`
 	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ReviewReport is a user's complaint about a review, waiting in the
+// moderation queue while open.
+type ReviewReport struct {
+	ID         int        `json:"id"`
+	ReviewID   int        `json:"review_id"`
+	ReporterID int        `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"` // open, dismissed or removed
+	ResolvedBy *int       `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	Review     *Review    `json:"review,omitempty"`
+	Reporter   *User      `json:"reporter,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// RatingSummary breaks a listing's reviews down by rating. Distribution
+// maps each rating from 1 to 5 to its number of reviews.
+type RatingSummary struct {
+	Average      float64     `json:"average"`
+	Count        int         `json:"count"`
+	Verified     int         `json:"verified"`
+	Distribution map[int]int `json:"distribution"`
+}
+
+// SearchSuggestion is an autocomplete entry. Kind is the listing field the
+// text came from: name, breed or animal_type.
+type SearchSuggestion struct {
+	Text string `json:"text"`
+	Kind string `json:"kind"`
+}
+
+// FacetCount is the number of listings sharing one value of a field.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// PriceBucket counts listings priced in [Min, Max). Max is nil for the
+// open-ended top bucket.
+type PriceBucket struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int      `json:"count"`
+}
+
+// AnimalFacets summarises the listings matching a marketplace search for
+// the filter sheet. Value lists are ordered by count, largest first.
+type AnimalFacets struct {
+	AnimalType []FacetCount  `json:"animal_type"`
+	Breed      []FacetCount  `json:"breed"`
+	Color      []FacetCount  `json:"color"`
+	Gender     []FacetCount  `json:"gender"`
+	Location   []FacetCount  `json:"location"`
+	Price      []PriceBucket `json:"price"`
+}
+
+// Category groups listings and products. Categories nest under a parent
+// (Reptil → Gecko → Leopard Gecko) and are ordered by SortOrder among their
+// siblings. Name is the key listings are filed by, through their
+// animal_type; Names holds its translations by language code. Attributes
+// are the details listings in the category give, on top of those of its
+// ancestors.
+type Category struct {
+	ID         int                 `json:"id"`
+	ParentID   *int                `json:"parent_id"`
+	Name       string              `json:"name"`
+	Label      string              `json:"label,omitempty"` // Name in the language asked for
+	Names      map[string]string   `json:"names"`
+	Icon       string              `json:"icon"`
+	Type       string              `json:"type"` // animal or food, as its parent's
+	SortOrder  int                 `json:"sort_order"`
+	Active     bool                `json:"active"`
+	Attributes []CategoryAttribute `json:"attributes"`
+	Schema     []CategoryAttribute `json:"schema,omitempty"`   // Attributes with those inherited
+	Children   []Category          `json:"children,omitempty"` // when listed as a tree
+}
+
+// AttributeMap holds a listing's attribute values by key: strings for text
+// and enum attributes, numbers and booleans for the others.
+type AttributeMap map[string]interface{}
+
+// CategoryAttribute is one detail a listing gives about its animal, such
+// as a reptile's morph.
+type CategoryAttribute struct {
+	Key      string   `json:"key"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"` // text, number, boolean or enum
+	Required bool     `json:"required"`
+	Options  []string `json:"options,omitempty"` // the values an enum allows
+}
+
+// Product is a pet supply, such as food or an accessory, sold by the unit.
+// Unlike a live animal listing it comes in Variants, each with its own SKU,
+// price, shipping weight and stock.
+type Product struct {
+	ID          int              `json:"id"`
+	SellerID    int              `json:"seller_id"`
+	CategoryID  int              `json:"category_id"`
+	SKU         string           `json:"sku"`
+	Name        string           `json:"name"`
+	Brand       string           `json:"brand"`
+	Description string           `json:"description"`
+	ImageURL    string           `json:"image_url"`
+	Location    string           `json:"location"`
+	Status      string           `json:"status"`    // active or archived
+	MinPrice    float64          `json:"min_price"` // of its variants
+	Stock       int              `json:"stock"`     // across its variants
+	Variants    []ProductVariant `json:"variants"`
+	Seller      *User            `json:"seller,omitempty"`
+	Shop        *ShopRef         `json:"shop,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// ProductVariant is one size or flavour of a product.
+type ProductVariant struct {
+	ID          int     `json:"id"`
+	ProductID   int     `json:"product_id"`
+	SKU         string  `json:"sku"`
+	Size        string  `json:"size,omitempty"`    // such as "1 kg"
+	Flavour     string  `json:"flavour,omitempty"` // such as "Tuna"
+	Price       float64 `json:"price"`
+	WeightGrams int     `json:"weight_grams"`
+	Stock       int     `json:"stock"`
+}
+
+// VariantRef is the product variant summary embedded in carts and orders,
+// with what it needs from its product.
+type VariantRef struct {
+	ID         int     `json:"id"`
+	ProductID  int     `json:"product_id"`
+	SellerID   int     `json:"seller_id"`
+	CategoryID int     `json:"category_id"`
+	SKU        string  `json:"sku"`
+	Name       string  `json:"name"`
+	Brand      string  `json:"brand,omitempty"`
+	Size       string  `json:"size,omitempty"`
+	Flavour    string  `json:"flavour,omitempty"`
+	ImageURL   string  `json:"image_url"`
+	Location   string  `json:"location"`
+	Status     string  `json:"status"` // the product's
+	Price      float64 `json:"price"`
+	Stock      int     `json:"stock"`
+}
+
+// IdempotencyKey is a client-supplied Idempotency-Key together with the
+// response recorded for the first request that used it.
+type IdempotencyKey struct {
+	UserID      int       `json:"user_id"`
+	Key         string    `json:"key"`
+	Fingerprint string    `json:"fingerprint"`
+	Status      int       `json:"status"` // 0 while the first request is still in flight
+	Body        []byte    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
 }
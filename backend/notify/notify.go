@@ -0,0 +1,76 @@
+// Package notify delivers notifications outside the app, by email or push.
+// Sender is implemented by a logger for development and a recorder for
+// tests; mail services and push gateways such as FCM plug in behind the
+// same interface.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/TerraPaw/backend/config"
+)
+
+// Channels a message can go out on.
+const (
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+)
+
+// Message is one notification for one user on one channel. Email goes to
+// To; push goes to the devices the push service knows for UserID.
+type Message struct {
+	Channel string
+	UserID  int
+	To      string
+	Title   string
+	Body    string
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Default is the sender used by the jobs. It is set in main from the
+// configuration.
+var Default Sender
+
+// Open returns the sender described by cfg.
+func Open(cfg *config.Config) (Sender, error) {
+	switch cfg.NotifyDriver {
+	case "log":
+		return Log{}, nil
+	}
+	return nil, fmt.Errorf("notify: unknown driver %q", cfg.NotifyDriver)
+}
+
+// Log writes messages to the server log instead of sending them.
+type Log struct{}
+
+func (Log) Send(ctx context.Context, msg Message) error {
+	log.Printf("notify: %s to user %d %s: %s", msg.Channel, msg.UserID, msg.To, msg.Title)
+	return nil
+}
+
+// Recorder keeps the messages it is given, for tests.
+type Recorder struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (r *Recorder) Send(ctx context.Context, msg Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+// Sent returns the messages sent so far.
+func (r *Recorder) Sent() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Message(nil), r.sent...)
+}
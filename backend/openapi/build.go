@@ -0,0 +1,252 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Envelope describes how a handler wraps its payload.
+type Envelope int
+
+const (
+	// Enveloped responses are utils.Response with the payload under "data".
+	Enveloped Envelope = iota
+	// Paginated responses are utils.PaginatedResponse.
+	Paginated
+	// Raw responses are written as-is without a wrapper.
+	Raw
+)
+
+// Param documents a query or header parameter.
+type Param struct {
+	Name        string
+	Type        string // string, integer, number or boolean; defaults to string
+	Description string
+	Required    bool
+	Enum        []string
+}
+
+// Route documents a single gin route. Request and Response are zero values
+// of the Go types that are bound and returned; they are reflected into
+// JSON Schema.
+type Route struct {
+	Summary  string
+	Tag      string
+	Auth     bool
+	Query    []Param
+	Headers  []Param
+	Request  interface{}
+	Response interface{}
+	Envelope Envelope
+	Status   int // success status, defaults to 200
+	// Extra documents route-specific envelope fields next to data, keyed
+	// by JSON name.
+	Extra map[string]interface{}
+	// Files names the file fields of a multipart/form-data upload. Such
+	// bodies are documented but not checked by the validator.
+	Files []string
+	// ContentType is the media type of a Raw response that is not JSON.
+	ContentType string
+}
+
+// Build generates a document for the registered routes. docs is keyed by
+// "METHOD /path" using gin's path syntax; routes without an entry are still
+// listed, just without schemas.
+func Build(routes gin.RoutesInfo, docs map[string]Route, info Info) *Document {
+	d := &Document{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	errorRef := d.schemaFor(reflect.TypeOf(utils.Response{}))
+	d.schemaFor(reflect.TypeOf(utils.PaginatedResponse{}))
+	d.Components.Schemas["Response"].Required = []string{"success", "message"}
+	d.Components.Schemas["Response"].Properties["code"].Description = "Stable error code, set when success is false"
+	d.Components.Schemas["Response"].Properties["details"] = d.schemaFor(reflect.TypeOf([]apperr.FieldError{}))
+	d.Components.Schemas["PaginatedResponse"].Required = []string{"success", "message", "data", "has_more", "limit"}
+
+	tags := map[string]bool{}
+	for _, r := range routes {
+		doc := docs[r.Method+" "+r.Path]
+		path, pathParams := convertPath(r.Path)
+
+		op := &Operation{
+			OperationID: operationID(r.Method, r.Path),
+			Summary:     doc.Summary,
+			Responses:   map[string]*Response{},
+		}
+		if doc.Tag != "" {
+			op.Tags = []string{doc.Tag}
+			tags[doc.Tag] = true
+		}
+		for _, name := range pathParams {
+			// Ids are integers; catch-all paths, slugs and provider
+			// names are strings.
+			schema := &Schema{Type: "string"}
+			if name == "id" {
+				schema = &Schema{Type: "integer"}
+			}
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name: name, In: "path", Required: true, Schema: schema,
+			})
+		}
+		for _, q := range doc.Query {
+			op.Parameters = append(op.Parameters, q.parameter("query"))
+		}
+		for _, hp := range doc.Headers {
+			op.Parameters = append(op.Parameters, hp.parameter("header"))
+		}
+		if doc.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  jsonContent(d.schemaFor(reflect.TypeOf(doc.Request))),
+			}
+			op.Responses["400"] = &Response{Description: "Invalid request", Content: jsonContent(errorRef)}
+		}
+		if len(doc.Files) > 0 {
+			form := &Schema{Type: "object", Properties: map[string]*Schema{}, Required: doc.Files}
+			for _, name := range doc.Files {
+				form.Properties[name] = &Schema{Type: "string", Format: "binary"}
+			}
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]*MediaType{"multipart/form-data": {Schema: form}},
+			}
+			op.Responses["400"] = &Response{Description: "Invalid request", Content: jsonContent(errorRef)}
+		}
+		if doc.Auth {
+			op.Security = []map[string][]string{{"bearerAuth": {}}}
+			op.Responses["401"] = &Response{Description: "Missing or invalid token", Content: jsonContent(errorRef)}
+		}
+
+		status := doc.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		content := jsonContent(d.responseSchema(doc))
+		if doc.ContentType != "" {
+			content = map[string]*MediaType{doc.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+		}
+		op.Responses[strconv.Itoa(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     content,
+		}
+
+		item := d.Paths[path]
+		if item == nil {
+			item = &PathItem{}
+			d.Paths[path] = item
+		}
+		(*item)[strings.ToLower(r.Method)] = op
+	}
+
+	for name := range tags {
+		d.Tags = append(d.Tags, Tag{Name: name})
+	}
+	sort.Slice(d.Tags, func(i, j int) bool { return d.Tags[i].Name < d.Tags[j].Name })
+	return d
+}
+
+func (p Param) parameter(in string) *Parameter {
+	s := &Schema{Type: p.Type}
+	if s.Type == "" {
+		s.Type = "string"
+	}
+	for _, e := range p.Enum {
+		s.Enum = append(s.Enum, e)
+	}
+	return &Parameter{Name: p.Name, In: in, Description: p.Description, Required: p.Required, Schema: s}
+}
+
+func (d *Document) responseSchema(doc Route) *Schema {
+	var payload *Schema
+	if doc.Response != nil {
+		payload = d.schemaFor(reflect.TypeOf(doc.Response))
+	}
+
+	var env *Schema
+	switch doc.Envelope {
+	case Raw:
+		if payload == nil {
+			return &Schema{}
+		}
+		return payload
+	case Paginated:
+		env = &Schema{Ref: "#/components/schemas/PaginatedResponse"}
+	default:
+		env = &Schema{Ref: "#/components/schemas/Response"}
+	}
+	props := map[string]*Schema{}
+	if payload != nil {
+		props["data"] = payload
+	}
+	for name, v := range doc.Extra {
+		props[name] = d.schemaFor(reflect.TypeOf(v))
+	}
+	if len(props) == 0 {
+		return env
+	}
+	return &Schema{AllOf: []*Schema{env, {
+		Type:       "object",
+		Properties: props,
+	}}}
+}
+
+func jsonContent(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
+
+// convertPath rewrites gin's ":id" segments to OpenAPI's "{id}" form and
+// returns the parameter names in order.
+func convertPath(p string) (string, []string) {
+	var params []string
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			params = append(params, part[1:])
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.Split(path, "/") {
+		part = strings.TrimLeft(part, ":*")
+		if part == "" || part == "api" {
+			continue
+		}
+		for _, word := range strings.FieldsFunc(part, func(r rune) bool { return r == '-' || r == '_' }) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// Lazy memoises build so the document is generated on first use, after all
+// routes have been registered.
+func Lazy(build func() *Document) func() *Document {
+	var once sync.Once
+	var doc *Document
+	return func() *Document {
+		once.Do(func() { doc = build() })
+		return doc
+	}
+}
@@ -0,0 +1,27 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed ui.html
+var uiPage string
+
+// ServeSpec writes the document as JSON.
+func ServeSpec(doc func() *Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, doc())
+	}
+}
+
+// ServeUI renders a Redoc page that loads the spec from specURL.
+func ServeUI(specURL string) gin.HandlerFunc {
+	page := strings.ReplaceAll(uiPage, "{{SPEC_URL}}", specURL)
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	}
+}
@@ -0,0 +1,155 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor returns the schema for t, registering named struct types under
+// components/schemas and referencing them by $ref.
+func (d *Document) schemaFor(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var s *Schema
+	switch {
+	case t == timeType:
+		s = &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		name := t.Name()
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Reserve the name first so self-referencing types terminate.
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.structSchema(t)
+		}
+		ref := &Schema{Ref: "#/components/schemas/" + name}
+		if nullable {
+			return &Schema{AllOf: []*Schema{ref}, Type: "object", Nullable: true}
+		}
+		return ref
+	case t.Kind() == reflect.Struct:
+		s = d.structSchema(t)
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s = &Schema{Type: "array", Items: d.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Map:
+		s = &Schema{Type: "object", AdditionalProperties: d.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Interface:
+		return &Schema{}
+	case t.Kind() == reflect.Bool:
+		s = &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		s = &Schema{Type: "integer"}
+		if t.Kind() == reflect.Int64 || t.Kind() == reflect.Uint64 {
+			s.Format = "int64"
+		}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s = &Schema{Type: "number"}
+	case t.Kind() == reflect.String:
+		s = &Schema{Type: "string"}
+	default:
+		s = &Schema{}
+	}
+	s.Nullable = s.Nullable || nullable
+	return s
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name := jsonName(f)
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			// Flatten embedded structs the way encoding/json does.
+			embedded := d.Resolve(d.schemaFor(f.Type))
+			if embedded != nil {
+				for k, v := range embedded.Properties {
+					s.Properties[k] = v
+				}
+				s.Required = append(s.Required, embedded.Required...)
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := d.schemaFor(f.Type)
+		if bindingRules(f)["required"] {
+			s.Required = append(s.Required, name)
+		}
+		if prop.Ref == "" {
+			applyBindingBounds(prop, f)
+		}
+		if desc := f.Tag.Get("doc"); desc != "" {
+			prop.Description = desc
+		}
+		s.Properties[name] = prop
+	}
+	return s
+}
+
+func jsonName(f reflect.StructField) string {
+	return strings.Split(f.Tag.Get("json"), ",")[0]
+}
+
+func bindingRules(f reflect.StructField) map[string]bool {
+	rules := map[string]bool{}
+	for _, r := range strings.Split(f.Tag.Get("binding"), ",") {
+		if r != "" {
+			rules[strings.SplitN(r, "=", 2)[0]] = true
+		}
+	}
+	return rules
+}
+
+// applyBindingBounds copies gin/validator min, max and oneof rules onto the
+// schema so the spec documents the same constraints the handlers enforce.
+func applyBindingBounds(s *Schema, f reflect.StructField) {
+	for _, r := range strings.Split(f.Tag.Get("binding"), ",") {
+		kv := strings.SplitN(r, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "min", "gte", "max", "lte":
+			n, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil {
+				continue
+			}
+			isMin := kv[0] == "min" || kv[0] == "gte"
+			switch s.Type {
+			case "string":
+				length := int(n)
+				if isMin {
+					s.MinLength = &length
+				} else {
+					s.MaxLength = &length
+				}
+			case "integer", "number":
+				if isMin {
+					s.Minimum = &n
+				} else {
+					s.Maximum = &n
+				}
+			}
+		case "oneof":
+			for _, v := range strings.Fields(kv[1]) {
+				s.Enum = append(s.Enum, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/gin-gonic/gin"
+)
+
+// Validator returns middleware that checks path parameters, query
+// parameters and JSON bodies against the operation documented for the
+// matched route. Requests to undocumented routes pass through untouched.
+// Failures are reported as VALIDATION_FAILED with one detail per field. It
+// must be installed after apperr.Middleware and before the routes are
+// registered.
+func Validator(doc func() *Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.FullPath() == "" {
+			c.Next()
+			return
+		}
+
+		path, _ := convertPath(c.FullPath())
+		item := doc().Paths[path]
+		if item == nil {
+			c.Next()
+			return
+		}
+		op := (*item)[strings.ToLower(c.Request.Method)]
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		if errs := doc().validateRequest(c, op); len(errs) > 0 {
+			apperr.Abort(c, apperr.Fields(errs...))
+			return
+		}
+		c.Next()
+	}
+}
+
+func (d *Document) validateRequest(c *gin.Context, op *Operation) []apperr.FieldError {
+	var errs []apperr.FieldError
+	for _, p := range op.Parameters {
+		var raw string
+		var present bool
+		switch p.In {
+		case "path":
+			raw = c.Param(p.Name)
+			present = raw != ""
+		case "query":
+			raw, present = c.GetQuery(p.Name)
+		case "header":
+			raw = c.GetHeader(p.Name)
+			present = raw != ""
+		default:
+			continue
+		}
+		if !present {
+			if p.Required {
+				errs = append(errs, apperr.FieldError{Field: p.Name, Message: "is required"})
+			}
+			continue
+		}
+		if err := checkParam(raw, p.Schema); err != "" {
+			errs = append(errs, apperr.FieldError{Field: p.Name, Message: err})
+		}
+	}
+
+	if op.RequestBody == nil {
+		return errs
+	}
+	media := op.RequestBody.Content["application/json"]
+	if media == nil {
+		return errs
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return append(errs, apperr.FieldError{Field: "body", Message: "could not be read"})
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var value interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return append(errs, apperr.FieldError{Field: "body", Message: "must be valid JSON"})
+	}
+	return append(errs, d.validateValue("", value, media.Schema)...)
+}
+
+func checkParam(raw string, s *Schema) string {
+	if s == nil {
+		return ""
+	}
+	switch s.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(raw, 10, 64); err != nil {
+			return "must be an integer"
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return "must be a number"
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(raw); err != nil {
+			return "must be a boolean"
+		}
+	}
+	if len(s.Enum) > 0 && !inEnum(raw, s.Enum) {
+		return "must be one of " + enumList(s.Enum)
+	}
+	return ""
+}
+
+// validateValue checks a decoded JSON value against s. It covers the subset
+// of JSON Schema the generator emits. at is the dotted path of v within the
+// body, empty for the body itself.
+func (d *Document) validateValue(at string, v interface{}, s *Schema) []apperr.FieldError {
+	s = d.Resolve(s)
+	if s == nil {
+		return nil
+	}
+
+	if v == nil {
+		if s.Type != "" && !s.Nullable {
+			return []apperr.FieldError{fieldErr(at, "must not be null")}
+		}
+		return nil
+	}
+
+	var errs []apperr.FieldError
+	for _, sub := range s.AllOf {
+		errs = append(errs, d.validateValue(at, v, sub)...)
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return append(errs, fieldErr(at, "must be an object"))
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				errs = append(errs, fieldErr(join(at, name), "is required"))
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := s.Properties[k]; ok {
+				errs = append(errs, d.validateValue(join(at, k), obj[k], prop)...)
+			} else if s.AdditionalProperties != nil {
+				errs = append(errs, d.validateValue(join(at, k), obj[k], s.AdditionalProperties)...)
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return append(errs, fieldErr(at, "must be an array"))
+		}
+		for i, item := range arr {
+			errs = append(errs, d.validateValue(fmt.Sprintf("%s[%d]", at, i), item, s.Items)...)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return append(errs, fieldErr(at, "must be a string"))
+		}
+		if s.MinLength != nil && len([]rune(str)) < *s.MinLength {
+			errs = append(errs, fieldErr(at, fmt.Sprintf("must be at least %d characters", *s.MinLength)))
+		}
+		if s.MaxLength != nil && len([]rune(str)) > *s.MaxLength {
+			errs = append(errs, fieldErr(at, fmt.Sprintf("must be at most %d characters", *s.MaxLength)))
+		}
+		if len(s.Enum) > 0 && !inEnum(str, s.Enum) {
+			errs = append(errs, fieldErr(at, "must be one of "+enumList(s.Enum)))
+		}
+	case "integer", "number":
+		num, ok := v.(json.Number)
+		if !ok {
+			if s.Type == "integer" {
+				return append(errs, fieldErr(at, "must be an integer"))
+			}
+			return append(errs, fieldErr(at, "must be a number"))
+		}
+		if s.Type == "integer" {
+			if _, err := num.Int64(); err != nil {
+				return append(errs, fieldErr(at, "must be an integer"))
+			}
+		}
+		f, _ := num.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			errs = append(errs, fieldErr(at, fmt.Sprintf("must be at least %g", *s.Minimum)))
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			errs = append(errs, fieldErr(at, fmt.Sprintf("must be at most %g", *s.Maximum)))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			errs = append(errs, fieldErr(at, "must be a boolean"))
+		}
+	}
+	return errs
+}
+
+func fieldErr(at, message string) apperr.FieldError {
+	if at == "" {
+		at = "body"
+	}
+	return apperr.FieldError{Field: at, Message: message}
+}
+
+func join(at, name string) string {
+	if at == "" {
+		return name
+	}
+	return at + "." + name
+}
+
+func inEnum(v string, enum []interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == v {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []interface{}) string {
+	parts := make([]string, len(enum))
+	for i, e := range enum {
+		parts[i] = fmt.Sprint(e)
+	}
+	return strings.Join(parts, ", ")
+}
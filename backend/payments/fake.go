@@ -0,0 +1,296 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SignatureHeader carries the HMAC of a fake gateway webhook body.
+const SignatureHeader = "X-Signature"
+
+// Fake is the client for a FakeGateway. Webhooks are signed with Secret.
+type Fake struct {
+	BaseURL string
+	Secret  []byte
+	Client  *http.Client // http.DefaultClient when nil
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+func (f *Fake) CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error) {
+	var charge Charge
+	err := f.call(ctx, "/charges", Charge{
+		Reference: req.Reference, Amount: req.Amount, Method: req.Method, Bank: req.Bank, ExpiresAt: req.ExpiresAt,
+	}, &charge)
+	if err != nil {
+		return nil, err
+	}
+	return &charge, nil
+}
+
+func (f *Fake) Refund(ctx context.Context, chargeID, reference string, amount int64) error {
+	return f.call(ctx, "/charges/"+chargeID+"/refunds", fakeRefund{Reference: reference, Amount: amount}, nil)
+}
+
+func (f *Fake) ParseWebhook(header http.Header, body []byte) (*Event, error) {
+	sig := header.Get(SignatureHeader)
+	if sig == "" || !hmac.Equal([]byte(sig), []byte(Sign(f.Secret, body))) {
+		return nil, ErrInvalidSignature
+	}
+	var e Event
+	if err := json.Unmarshal(body, &e); err != nil || e.ID == "" || e.ChargeID == "" {
+		return nil, fmt.Errorf("payments: malformed event: %s", body)
+	}
+	return &e, nil
+}
+
+// call posts in as JSON and decodes the response into out.
+func (f *Fake) call(ctx context.Context, path string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(f.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var failure fakeError
+		json.NewDecoder(resp.Body).Decode(&failure)
+		return fmt.Errorf("payments: fake gateway: %s %s", resp.Status, failure.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type fakeRefund struct {
+	Reference string `json:"reference"`
+	Amount    int64  `json:"amount"`
+}
+
+type fakeError struct {
+	Error string `json:"error"`
+}
+
+// FakeGateway is a local stand-in for a payment gateway. It issues charges
+// with made-up account numbers and QR codes and, when a charge is settled
+// through POST /charges/{id}/pay or /expire, sends a signed webhook to
+// WebhookURL the way a real gateway would.
+type FakeGateway struct {
+	Secret     []byte
+	WebhookURL string
+	Client     *http.Client // http.DefaultClient when nil
+
+	mu      sync.Mutex
+	seq     int
+	charges map[string]*Charge
+	refs    map[string]bool
+	refunds map[string]fakeRefund // by reference
+	mux     *http.ServeMux
+}
+
+func NewFakeGateway(secret []byte, webhookURL string) *FakeGateway {
+	g := &FakeGateway{
+		Secret:     secret,
+		WebhookURL: webhookURL,
+		charges:    map[string]*Charge{},
+		refs:       map[string]bool{},
+		refunds:    map[string]fakeRefund{},
+		mux:        http.NewServeMux(),
+	}
+	g.mux.HandleFunc("POST /charges", g.createCharge)
+	g.mux.HandleFunc("GET /charges/{id}", g.getCharge)
+	g.mux.HandleFunc("POST /charges/{id}/refunds", g.refund)
+	g.mux.HandleFunc("POST /charges/{id}/pay", g.settle(StatusPaid))
+	g.mux.HandleFunc("POST /charges/{id}/expire", g.settle(StatusExpired))
+	return g
+}
+
+func (g *FakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+// Settle moves a pending charge to status and delivers the webhook for
+// it, as if the buyer had paid or let the charge lapse.
+func (g *FakeGateway) Settle(ctx context.Context, id, status string) (*Event, error) {
+	g.mu.Lock()
+	charge, ok := g.charges[id]
+	if !ok {
+		g.mu.Unlock()
+		return nil, errors.New("no such charge")
+	}
+	if charge.Status != StatusPending {
+		g.mu.Unlock()
+		return nil, fmt.Errorf("charge is %s", charge.Status)
+	}
+	charge.Status = status
+	g.seq++
+	e := &Event{ID: fmt.Sprintf("evt_%d", g.seq), ChargeID: id, Status: status, Amount: charge.Amount, Currency: Currency}
+	g.mu.Unlock()
+
+	return e, g.Deliver(ctx, e)
+}
+
+// Deliver sends e to WebhookURL. Gateways redeliver events they are not
+// sure arrived; calling Deliver again does the same.
+func (g *FakeGateway) Deliver(ctx context.Context, e *Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(g.Secret, body))
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Refunded returns how much of a charge has been refunded.
+func (g *FakeGateway) Refunded(id string) int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.refunded(id)
+}
+
+// refunded totals the refunds on a charge. Callers must hold mu.
+func (g *FakeGateway) refunded(id string) int64 {
+	var total int64
+	for ref, r := range g.refunds {
+		if strings.HasPrefix(ref, id+"/") {
+			total += r.Amount
+		}
+	}
+	return total
+}
+
+func (g *FakeGateway) createCharge(w http.ResponseWriter, r *http.Request) {
+	var charge Charge
+	if err := json.NewDecoder(r.Body).Decode(&charge); err != nil || charge.Reference == "" || charge.Amount <= 0 {
+		fakeReply(w, http.StatusBadRequest, fakeError{"reference and a positive amount are required"})
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.refs[charge.Reference] {
+		fakeReply(w, http.StatusConflict, fakeError{"duplicate reference"})
+		return
+	}
+	g.seq++
+	charge.ID = fmt.Sprintf("ch_%d", g.seq)
+	charge.Status = StatusPending
+	switch charge.Method {
+	case MethodVirtualAccount:
+		if charge.Bank == "" {
+			fakeReply(w, http.StatusBadRequest, fakeError{"bank is required for virtual accounts"})
+			return
+		}
+		charge.VANumber = fmt.Sprintf("8808%012d", g.seq)
+	case MethodQRIS:
+		charge.QRString = fmt.Sprintf("00020101021226610016ID.CO.FAKE.WWW0118%s5204599953033605802ID6304", charge.ID)
+	default:
+		fakeReply(w, http.StatusBadRequest, fakeError{"unsupported method"})
+		return
+	}
+	if charge.ExpiresAt.IsZero() {
+		charge.ExpiresAt = time.Now().Add(24 * time.Hour)
+	}
+	g.refs[charge.Reference] = true
+	g.charges[charge.ID] = &charge
+	fakeReply(w, http.StatusCreated, charge)
+}
+
+func (g *FakeGateway) getCharge(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	charge, ok := g.charges[r.PathValue("id")]
+	if !ok {
+		fakeReply(w, http.StatusNotFound, fakeError{"no such charge"})
+		return
+	}
+	fakeReply(w, http.StatusOK, charge)
+}
+
+func (g *FakeGateway) refund(w http.ResponseWriter, r *http.Request) {
+	var req fakeRefund
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Reference == "" || req.Amount <= 0 {
+		fakeReply(w, http.StatusBadRequest, fakeError{"reference and a positive amount are required"})
+		return
+	}
+	id := r.PathValue("id")
+	key := id + "/" + req.Reference
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	charge, ok := g.charges[id]
+	switch {
+	case !ok:
+		fakeReply(w, http.StatusNotFound, fakeError{"no such charge"})
+		return
+	case charge.Status != StatusPaid:
+		fakeReply(w, http.StatusConflict, fakeError{"charge is not paid"})
+		return
+	}
+	if _, done := g.refunds[key]; done {
+		fakeReply(w, http.StatusOK, req)
+		return
+	}
+	if g.refunded(id)+req.Amount > charge.Amount {
+		fakeReply(w, http.StatusConflict, fakeError{"refund exceeds the amount paid"})
+		return
+	}
+	g.refunds[key] = req
+	fakeReply(w, http.StatusOK, req)
+}
+
+func (g *FakeGateway) settle(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e, err := g.Settle(r.Context(), r.PathValue("id"), status)
+		if err != nil {
+			fakeReply(w, http.StatusConflict, fakeError{err.Error()})
+			return
+		}
+		fakeReply(w, http.StatusOK, e)
+	}
+}
+
+func fakeReply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,129 @@
+// Package payments collects order payments through a payment gateway.
+// Provider is implemented by a fake gateway that runs as a local HTTP
+// stand-in; Indonesian gateways such as Midtrans or Xendit plug in behind
+// the same interface.
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TerraPaw/backend/config"
+)
+
+// Payment methods. Virtual accounts are paid by bank transfer to a
+// per-charge account number; QRIS by scanning a code in any e-wallet.
+const (
+	MethodVirtualAccount = "virtual_account"
+	MethodQRIS           = "qris"
+)
+
+// Methods lists the accepted payment methods.
+var Methods = []string{MethodVirtualAccount, MethodQRIS}
+
+// Banks lists the banks that issue virtual accounts.
+var Banks = []string{"bca", "bni", "bri", "mandiri", "permata"}
+
+// Charge statuses, as reported by the gateway.
+const (
+	StatusPending = "pending"
+	StatusPaid    = "paid"
+	StatusExpired = "expired"
+	StatusFailed  = "failed"
+)
+
+// ErrInvalidSignature is returned by ParseWebhook for requests that were
+// not signed by the gateway.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ErrDisabled is returned by Open when no gateway that may take real
+// payments is configured. The server then runs without payments.
+var ErrDisabled = errors.New("payments are disabled")
+
+// ChargeRequest asks the gateway to collect Amount rupiah.
+type ChargeRequest struct {
+	// Reference identifies the payment on our side. Gateways reject a
+	// reference they have already seen, so it must be unique per charge.
+	Reference string
+	Amount    int64
+	Method    string
+	Bank      string // virtual accounts only
+	ExpiresAt time.Time
+}
+
+// Charge is a request for payment the buyer completes outside the app.
+type Charge struct {
+	ID        string    `json:"id"`
+	Reference string    `json:"reference"`
+	Amount    int64     `json:"amount"`
+	Method    string    `json:"method"`
+	Bank      string    `json:"bank,omitempty"`
+	VANumber  string    `json:"va_number,omitempty"`
+	QRString  string    `json:"qr_string,omitempty"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Currency is the ISO 4217 code of every amount charged: whole rupiah.
+const Currency = "IDR"
+
+// Event is a charge status change pushed by the gateway. Gateways retry
+// deliveries, so the same event can arrive more than once. Amount and
+// Currency are what the gateway collected.
+type Event struct {
+	ID       string `json:"id"`
+	ChargeID string `json:"charge_id"`
+	Status   string `json:"status"`
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// Provider is a payment gateway.
+type Provider interface {
+	// Name identifies the gateway in webhook URLs and stored payments.
+	Name() string
+	CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error)
+	// Refund returns amount rupiah of a paid charge. reference is unique
+	// per refund, so retrying a refund that may have gone through is safe.
+	Refund(ctx context.Context, chargeID, reference string, amount int64) error
+	// ParseWebhook checks the signature of a webhook request and decodes
+	// its event.
+	ParseWebhook(header http.Header, body []byte) (*Event, error)
+}
+
+// Default is the gateway used by the payment handlers and jobs. It is set
+// in main from the configuration, and is nil while payments are disabled.
+var Default Provider
+
+// Open returns the gateway described by cfg. Outside development it
+// refuses the fake gateway and the default webhook secret, which would let
+// anyone mark orders paid, with an error matching ErrDisabled.
+func Open(cfg *config.Config) (Provider, error) {
+	if !cfg.Dev {
+		switch {
+		case cfg.PaymentProvider == "fake":
+			return nil, fmt.Errorf("%w: the fake provider only runs with APP_ENV=development", ErrDisabled)
+		case cfg.PaymentSecret == config.DefaultPaymentSecret:
+			return nil, fmt.Errorf("%w: PAYMENT_SECRET must be set outside development", ErrDisabled)
+		}
+	}
+	switch cfg.PaymentProvider {
+	case "fake":
+		return &Fake{BaseURL: cfg.PaymentFakeURL, Secret: []byte(cfg.PaymentSecret)}, nil
+	}
+	return nil, fmt.Errorf("payments: unknown provider %q", cfg.PaymentProvider)
+}
+
+// Sign returns the hex HMAC-SHA256 of body, the signature the fake gateway
+// sends with each webhook.
+func Sign(secret, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
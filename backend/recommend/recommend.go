@@ -0,0 +1,209 @@
+// Package recommend works out which listings are alike. Two listings are
+// alike when they share a type, breed, price band or location, and when the
+// same users ordered or saved both of them. The scores are computed in a
+// batch by a job and stored; see store.RecommendationStore.
+package recommend
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// Reasons a listing is similar to another.
+const (
+	ReasonSameType     = "same_type"
+	ReasonSameBreed    = "same_breed"
+	ReasonSimilarPrice = "similar_price"
+	ReasonSameLocation = "same_location"
+	ReasonAlsoLiked    = "also_liked" // users who ordered or saved one did the other too
+)
+
+// Weights of each signal in a score. A listing exactly like another in
+// every way scores 1 plus the co-interest weight.
+const (
+	weightType      = 0.3
+	weightBreed     = 0.3
+	weightPrice     = 0.3
+	weightLocation  = 0.1
+	weightAlsoLiked = 0.5
+
+	// priceBand is how far apart two prices may be, as a share of the
+	// higher one, and still count as similar.
+	priceBand = 0.3
+)
+
+// PerListing is how many similar listings are kept for each listing.
+const PerListing = 20
+
+// Content scores how alike b is to a by what they are: type, breed, price
+// and location. Listings of different types score nothing.
+func Content(a, b models.Animal) (float64, []string) {
+	if !same(a.AnimalType, b.AnimalType) {
+		return 0, nil
+	}
+	score, reasons := weightType, []string{ReasonSameType}
+	if same(a.Breed, b.Breed) {
+		score += weightBreed
+		reasons = append(reasons, ReasonSameBreed)
+	}
+	if hi := math.Max(a.Price, b.Price); hi > 0 {
+		if diff := math.Abs(a.Price-b.Price) / hi; diff < priceBand {
+			score += weightPrice * (1 - diff/priceBand)
+			reasons = append(reasons, ReasonSimilarPrice)
+		}
+	}
+	if same(a.Location, b.Location) {
+		score += weightLocation
+		reasons = append(reasons, ReasonSameLocation)
+	}
+	return score, reasons
+}
+
+// same compares two free-text fields, ignoring case and blanks. Empty
+// fields are never the same.
+func same(a, b string) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	return a != "" && strings.EqualFold(a, b)
+}
+
+// Similar scores every pair of listings that share a type or a user, and
+// keeps the PerListing best matches of each listing. listings are the
+// listings to find matches for; only those in stock are offered as
+// matches. baskets are the listings each user ordered or saved.
+func Similar(listings []models.Animal, baskets [][]int) []models.Similarity {
+	byID := make(map[int]models.Animal, len(listings))
+	byType := map[string][]int{}
+	for _, a := range listings {
+		byID[a.ID] = a
+		t := strings.ToLower(strings.TrimSpace(a.AnimalType))
+		byType[t] = append(byType[t], a.ID)
+	}
+
+	// Users interested in each listing and in each pair of them, for the
+	// cosine similarity below.
+	interest := map[int]int{}
+	together := map[[2]int]int{}
+	for _, basket := range baskets {
+		basket = unique(basket)
+		for i, a := range basket {
+			interest[a]++
+			for _, b := range basket[i+1:] {
+				together[key(a, b)]++
+			}
+		}
+	}
+
+	candidates := map[[2]int]bool{}
+	for t, ids := range byType {
+		if t == "" {
+			continue
+		}
+		for i, a := range ids {
+			for _, b := range ids[i+1:] {
+				candidates[key(a, b)] = true
+			}
+		}
+	}
+	for pair := range together {
+		candidates[pair] = true
+	}
+
+	matches := map[int][]models.Similarity{}
+	add := func(from, to models.Animal, score float64, reasons []string) {
+		if forSale(to) {
+			matches[from.ID] = append(matches[from.ID], models.Similarity{
+				AnimalID: from.ID, SimilarID: to.ID, Score: round(score), Reasons: reasons,
+			})
+		}
+	}
+	for pair := range candidates {
+		a, okA := byID[pair[0]]
+		b, okB := byID[pair[1]]
+		if !okA || !okB {
+			continue
+		}
+		score, reasons := Content(a, b)
+		if n := together[pair]; n > 0 {
+			score += weightAlsoLiked * float64(n) / math.Sqrt(float64(interest[a.ID]*interest[b.ID]))
+			reasons = append(reasons, ReasonAlsoLiked)
+		}
+		if score > 0 {
+			add(a, b, score, reasons)
+			add(b, a, score, reasons)
+		}
+	}
+
+	var out []models.Similarity
+	ids := make([]int, 0, len(matches))
+	for id := range matches {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		out = append(out, Best(matches[id], PerListing)...)
+	}
+	return out
+}
+
+// ByContent scores candidates against a by Content alone and returns the
+// n best, for listings the batch has not seen yet.
+func ByContent(a models.Animal, candidates []models.Animal, n int) []models.Similarity {
+	var sims []models.Similarity
+	for _, b := range candidates {
+		if b.ID == a.ID || !forSale(b) {
+			continue
+		}
+		if score, reasons := Content(a, b); score > 0 {
+			sims = append(sims, models.Similarity{AnimalID: a.ID, SimilarID: b.ID, Score: round(score), Reasons: reasons})
+		}
+	}
+	return Best(sims, n)
+}
+
+// forSale reports whether a listing can be offered as a match: available
+// (store.StatusAvailable) with stock left.
+func forSale(a models.Animal) bool {
+	return a.Status == "available" && a.Stock > 0
+}
+
+// Best returns the n highest-scoring similarities, ties going to the
+// lower listing ID.
+func Best(sims []models.Similarity, n int) []models.Similarity {
+	sort.Slice(sims, func(i, j int) bool {
+		if sims[i].Score != sims[j].Score {
+			return sims[i].Score > sims[j].Score
+		}
+		return sims[i].SimilarID < sims[j].SimilarID
+	})
+	if len(sims) > n {
+		sims = sims[:n]
+	}
+	return sims
+}
+
+func key(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+func unique(ids []int) []int {
+	seen := map[int]bool{}
+	out := ids[:0:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// round keeps scores to four decimals, as they are stored.
+func round(score float64) float64 {
+	return math.Round(score*1e4) / 1e4
+}
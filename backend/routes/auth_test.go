@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func TestAuthRoutes(t *testing.T) {
+	s := newTestServer(t)
+	userID, token := s.register("anna")
+
+	// Duplicate username/email
+	s.expect("POST", "/api/auth/register", "", map[string]string{
+		"username": "anna", "email": "anna@example.com", "password": "x",
+	}, http.StatusBadRequest)
+	s.expect("POST", "/api/auth/register", "", map[string]string{"username": "x"}, http.StatusBadRequest)
+
+	login := s.expect("POST", "/api/auth/login", "", map[string]string{
+		"email": " ANNA@example.com ", "password": "secret",
+	}, http.StatusOK)
+	if dataMap(t, login)["token"] == "" {
+		t.Fatal("login returned no token")
+	}
+	s.expect("POST", "/api/auth/login", "", map[string]string{
+		"email": "anna@example.com", "password": "wrong",
+	}, http.StatusUnauthorized)
+
+	profile := s.expect("GET", "/api/auth/profile", token, nil, http.StatusOK)
+	if got := int(dataMap(t, profile)["id"].(float64)); got != userID {
+		t.Fatalf("profile id = %d, want %d", got, userID)
+	}
+	s.expect("GET", "/api/auth/profile", "", nil, http.StatusUnauthorized)
+	s.expect("GET", "/api/auth/profile", "not-a-jwt", nil, http.StatusUnauthorized)
+
+	// Unknown emails get the same answer as known ones
+	s.expect("POST", "/api/auth/forgot-password", "", map[string]string{"email": "nobody@example.com"}, http.StatusOK)
+	forgot := s.expect("POST", "/api/auth/forgot-password", "", map[string]string{"email": "anna@example.com"}, http.StatusOK)
+	code := dataMap(t, forgot)["mock_code"].(string)
+
+	s.expect("POST", "/api/auth/reset-password", "", map[string]string{
+		"email": "anna@example.com", "token": "000000", "new_password": "changed",
+	}, http.StatusBadRequest)
+	s.expect("POST", "/api/auth/reset-password", "", map[string]string{
+		"email": "anna@example.com", "token": code, "new_password": "changed",
+	}, http.StatusOK)
+	s.expect("POST", "/api/auth/login", "", map[string]string{
+		"email": "anna@example.com", "password": "changed",
+	}, http.StatusOK)
+}
+
+func TestProfileRoutes(t *testing.T) {
+	s := newTestServer(t)
+	userID, token := s.register("budi")
+
+	s.expect("GET", "/api/profile/pets", "", nil, http.StatusUnauthorized)
+	petID := idOf(t, s.expect("POST", "/api/profile/pets", token, map[string]interface{}{
+		"name": "Mochi", "animal_type": "Kucing", "breed": "Persia", "age": 2,
+	}, http.StatusCreated))
+	s.expect("POST", "/api/profile/pets", token, map[string]interface{}{"name": "NoType"}, http.StatusBadRequest)
+
+	if pets := s.list("/api/profile/pets", token); len(pets) != 1 {
+		t.Fatalf("pets = %v", pets)
+	}
+
+	s.mem.AddMedicalRecord(models.MedicalRecord{PetID: petID, RecordType: "vaccine", Date: time.Now()})
+	records := s.list("/api/profile/medical-records", token)
+	if len(records) != 1 || records[0].(map[string]interface{})["pet_name"] != "Mochi" {
+		t.Fatalf("medical records = %v", records)
+	}
+
+	s.mem.AddNotification(models.Notification{UserID: userID, Title: "Hi", Type: "info"})
+	if notifications := s.list("/api/profile/notifications", token); len(notifications) != 1 {
+		t.Fatalf("notifications = %v", notifications)
+	}
+
+	if pets := s.data("GET", "/api/profile/stats", token, nil, http.StatusOK)["pets"]; pets != float64(1) {
+		t.Fatalf("stats pets = %v, want 1", pets)
+	}
+}
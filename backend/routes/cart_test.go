@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCartCheckout(t *testing.T) {
+	s := newTestServer(t)
+	_, catSeller := s.register("catseller")
+	_, foodSeller := s.register("foodseller")
+	_, buyer := s.register("buyer")
+
+	kitten := s.catListing(catSeller, "Mochi", 1500000, 1)
+	food := s.catListing(foodSeller, "Makanan Kucing 2kg", 85000, 10)
+	treats := s.catListing(foodSeller, "Snack Kucing", 20000, 5)
+
+	cart := s.data("GET", "/api/marketplace/cart", buyer, nil, http.StatusOK)
+	if cart["item_count"] != 0.0 || cart["can_checkout"] != false || len(cart["items"].([]interface{})) != 0 {
+		t.Fatalf("empty cart = %v", cart)
+	}
+	assertCode(t, s.expect("POST", "/api/marketplace/checkout", buyer, nil, http.StatusBadRequest), "CART_EMPTY")
+
+	// Adding the same listing again adds to its quantity, within stock.
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": kitten}, http.StatusOK)
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": food, "quantity": 2}, http.StatusOK)
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": food, "quantity": 1}, http.StatusOK)
+	assertCode(t, s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": kitten}, http.StatusBadRequest), "INSUFFICIENT_STOCK")
+	assertCode(t, s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": 999}, http.StatusNotFound), "ANIMAL_NOT_FOUND")
+	assertDetail(t, s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": food, "quantity": 101}, http.StatusBadRequest),
+		"quantity", "must be at most 100")
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": treats}, http.StatusOK)
+	cart = s.data("DELETE", fmt.Sprintf("/api/marketplace/cart/items/%d", treats), buyer, nil, http.StatusOK)
+	if cart["item_count"] != 4.0 || cart["subtotal"] != 1755000.0 || cart["can_checkout"] != true {
+		t.Fatalf("cart = %v", cart)
+	}
+
+	foodItem := fmt.Sprintf("/api/marketplace/cart/items/%d", food)
+	assertCode(t, s.expect("PUT", fmt.Sprintf("/api/marketplace/cart/items/%d", treats), buyer, map[string]int{"quantity": 1}, http.StatusNotFound),
+		"CART_ITEM_NOT_FOUND")
+	assertCode(t, s.expect("PUT", foodItem, buyer, map[string]int{"quantity": 11}, http.StatusBadRequest), "INSUFFICIENT_STOCK")
+	s.expect("PUT", foodItem, buyer, map[string]int{"quantity": 2}, http.StatusOK)
+
+	// A price change stops checkout once; the retry buys at the new price.
+	s.expect("PATCH", fmt.Sprintf("/api/marketplace/animals/%d", food), foodSeller, map[string]interface{}{"price": 90000}, http.StatusOK)
+	cart = s.data("GET", "/api/marketplace/cart", buyer, nil, http.StatusOK)
+	item := cart["items"].([]interface{})[1].(map[string]interface{})
+	if item["problem"] != "price_changed" || item["added_price"] != 85000.0 || item["price"] != 90000.0 || cart["can_checkout"] != false {
+		t.Fatalf("repriced cart = %v", cart)
+	}
+	out := s.expect("POST", "/api/marketplace/checkout", buyer, nil, http.StatusConflict)
+	assertCode(t, out, "CART_CHANGED")
+	assertDetail(t, out, "items[1]", "price changed from 85000 to 90000")
+	if cart := s.data("GET", "/api/marketplace/cart", buyer, nil, http.StatusOK); cart["can_checkout"] != true {
+		t.Fatalf("cart after review = %v", cart)
+	}
+
+	order := s.data("POST", "/api/marketplace/checkout", buyer, nil, http.StatusCreated)
+	if order["total_price"] != 1680000.0 || order["quantity"] != 3.0 || order["status"] != "pending" || len(order["items"].([]interface{})) != 2 {
+		t.Fatalf("order = %v", order)
+	}
+	subs := order["sub_orders"].([]interface{})
+	if len(subs) != 2 {
+		t.Fatalf("sub-orders = %v", subs)
+	}
+	for _, sub := range subs {
+		sub := sub.(map[string]interface{})
+		items := sub["items"].([]interface{})
+		line := items[0].(map[string]interface{})
+		if sub["parent_id"] != order["id"] || len(items) != 1 || sub["seller_id"] != line["seller_id"] || sub["total_price"] != line["subtotal"] {
+			t.Errorf("sub-order = %v", sub)
+		}
+	}
+	if cart := s.data("GET", "/api/marketplace/cart", buyer, nil, http.StatusOK); len(cart["items"].([]interface{})) != 0 {
+		t.Errorf("cart not emptied: %v", cart)
+	}
+
+	// Orders list the checkout once, with every line; single-listing
+	// orders carry their line too.
+	s.expect("POST", "/api/marketplace/orders", buyer, map[string]int{"animal_id": treats}, http.StatusCreated)
+	orders := s.list("/api/marketplace/orders", buyer)
+	if len(orders) != 2 {
+		t.Fatalf("orders = %v", orders)
+	}
+	single, checkout := orders[0].(map[string]interface{}), orders[1].(map[string]interface{})
+	if single["animal_id"] != float64(treats) || len(single["items"].([]interface{})) != 1 {
+		t.Errorf("single order = %v", single)
+	}
+	if checkout["id"] != order["id"] || len(checkout["items"].([]interface{})) != 2 {
+		t.Errorf("checkout order = %v", checkout)
+	}
+
+	// Unavailable items block checkout until removed.
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": treats}, http.StatusOK)
+	s.expect("PATCH", fmt.Sprintf("/api/marketplace/animals/%d", treats), foodSeller, map[string]interface{}{"status": "archived"}, http.StatusOK)
+	assertCode(t, s.expect("PUT", fmt.Sprintf("/api/marketplace/cart/items/%d", treats), buyer, map[string]int{"quantity": 2}, http.StatusConflict),
+		"ANIMAL_UNAVAILABLE")
+	assertDetail(t, s.expect("POST", "/api/marketplace/checkout", buyer, nil, http.StatusConflict), "items[0]", "is no longer available")
+	assertCode(t, s.expect("GET", "/api/marketplace/cart", "", nil, http.StatusUnauthorized), "UNAUTHORIZED")
+}
@@ -0,0 +1,150 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCategories(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+	_, other := s.register("other")
+	modID, mod := s.register("moderator")
+	s.mem.SetModerator(modID)
+
+	category := func(method, path string, body map[string]interface{}, status int) map[string]interface{} {
+		t.Helper()
+		return s.expect(method, "/api/moderation/categories"+path, mod, body, status)
+	}
+	s.expect("POST", "/api/moderation/categories", seller, map[string]interface{}{"name": "Ikan"}, http.StatusForbidden)
+	s.expect("GET", "/api/moderation/categories", "", nil, http.StatusUnauthorized)
+
+	// Reptil → Gecko → Leopard Gecko, where leopard geckos narrow the
+	// reptile morph to a list.
+	reptiles := idOf(t, category("POST", "", map[string]interface{}{
+		"name": "Reptil", "icon": "🦎", "sort_order": 20, "names": map[string]string{"en": "Reptiles"},
+		"attributes": []map[string]interface{}{{"key": "morph", "label": "Morph", "type": "text", "required": true}},
+	}, http.StatusCreated))
+	geckos := idOf(t, category("POST", "", map[string]interface{}{
+		"name": "Gecko", "parent_id": reptiles, "names": map[string]string{"en": "Geckos"},
+		"attributes": []map[string]interface{}{{"key": "length_cm", "label": "Length (cm)", "type": "number"}},
+	}, http.StatusCreated))
+	leopards := idOf(t, category("POST", "", map[string]interface{}{
+		"name": "Leopard Gecko", "parent_id": geckos,
+		"attributes": []map[string]interface{}{{"key": "morph", "label": "Morph", "type": "enum", "required": true, "options": []string{"Normal", "Tangerine"}}},
+	}, http.StatusCreated))
+	cats := idOf(t, category("POST", "", map[string]interface{}{"name": "Kucing", "sort_order": 10}, http.StatusCreated))
+
+	list := s.list("/api/marketplace/categories?lang=en", "")
+	if len(list) != 4 {
+		t.Fatalf("categories = %v", list)
+	}
+	for _, c := range list {
+		gecko := c.(map[string]interface{})
+		if gecko["id"] == float64(geckos) && (gecko["label"] != "Geckos" || gecko["type"] != "animal" || len(gecko["schema"].([]interface{})) != 2) {
+			t.Fatalf("gecko = %v", gecko)
+		}
+	}
+	tree := s.list("/api/marketplace/categories?tree=true", "")
+	if len(tree) != 2 || tree[0].(map[string]interface{})["id"] != float64(cats) {
+		t.Fatalf("category tree in sort order = %v", tree)
+	}
+	reptile := tree[1].(map[string]interface{})
+	leopard := reptile["children"].([]interface{})[0].(map[string]interface{})["children"].([]interface{})[0].(map[string]interface{})
+	schema := leopard["schema"].([]interface{})
+	if reptile["label"] != "Reptil" || leopard["id"] != float64(leopards) || len(schema) != 2 || schema[0].(map[string]interface{})["type"] != "enum" {
+		t.Fatalf("reptile branch = %v", reptile)
+	}
+	s.expect("GET", "/api/marketplace/categories?tree=maybe", "", nil, http.StatusBadRequest)
+
+	assertCode(t, category("POST", "", map[string]interface{}{"name": "Gecko"}, http.StatusConflict), "CATEGORY_NAME_TAKEN")
+	assertDetail(t, category("POST", "", map[string]interface{}{"name": "Ikan", "parent_id": 999}, http.StatusBadRequest), "parent_id", "does not exist")
+	assertDetail(t, category("POST", "", map[string]interface{}{"name": "Pakan Gecko", "parent_id": geckos, "type": "food"}, http.StatusBadRequest),
+		"type", "must be the parent category's, animal")
+	out := category("POST", "", map[string]interface{}{
+		"name": "Ikan", "names": map[string]string{"English": "Fish"},
+		"attributes": []map[string]interface{}{
+			{"key": "Water Type", "label": "Water", "type": "text"},
+			{"key": "fins", "label": "Fins", "type": "enum"},
+			{"key": "salt", "label": "Salt", "type": "boolean", "options": []string{"yes"}},
+		},
+	}, http.StatusBadRequest)
+	assertDetail(t, out, "names", `"English" is not a language code such as en or pt-br`)
+	assertDetail(t, out, "attributes[0].key", "must be lowercase letters, digits and underscores, starting with a letter")
+	assertDetail(t, out, "attributes[1].options", "are required for an enum")
+	assertDetail(t, out, "attributes[2].options", "are only allowed for an enum")
+	assertDetail(t, category("PUT", fmt.Sprintf("/%d", reptiles), map[string]interface{}{"name": "Reptil", "parent_id": leopards}, http.StatusBadRequest),
+		"parent_id", "cannot be the category itself or one under it")
+	assertCode(t, category("PUT", "/999", map[string]interface{}{"name": "Ikan"}, http.StatusNotFound), "CATEGORY_NOT_FOUND")
+
+	// Listings go in a leaf category and give the attributes its schema
+	// asks for.
+	listing := func(token string, body map[string]interface{}, status int) map[string]interface{} {
+		t.Helper()
+		body["animal_type"], body["name"], body["price"] = "Gecko", "Sunny", 750000
+		return s.expect("POST", "/api/marketplace/animals", token, body, status)
+	}
+	out = listing(seller, map[string]interface{}{"category_id": leopards, "attributes": map[string]interface{}{"morph": "Purple", "length_cm": "long", "spots": 3}}, http.StatusBadRequest)
+	assertDetail(t, out, "attributes.morph", "must be one of Normal, Tangerine")
+	assertDetail(t, out, "attributes.length_cm", "must be a number")
+	assertDetail(t, out, "attributes.spots", "is not an attribute of the category")
+	assertDetail(t, listing(seller, map[string]interface{}{"category_id": leopards}, http.StatusBadRequest), "attributes.morph", "is required")
+	assertDetail(t, listing(seller, map[string]interface{}{"category_id": geckos, "attributes": map[string]interface{}{"morph": "Normal"}}, http.StatusBadRequest),
+		"category_id", "has subcategories; file the listing under one of them")
+	assertDetail(t, listing(seller, map[string]interface{}{"category_id": 999}, http.StatusBadRequest), "category_id", "does not exist")
+	assertDetail(t, listing(seller, map[string]interface{}{"attributes": map[string]interface{}{"morph": "Normal"}}, http.StatusBadRequest),
+		"attributes.morph", "is not an attribute of the category")
+	sunny := idOf(t, listing(seller, map[string]interface{}{"category_id": leopards, "attributes": map[string]interface{}{"morph": "Tangerine", "length_cm": 20}}, http.StatusCreated))
+	listing(seller, map[string]interface{}{"category_id": cats}, http.StatusCreated)
+
+	sunnyPath := fmt.Sprintf("/api/marketplace/animals/%d", sunny)
+	if a := s.data("GET", sunnyPath, "", nil, http.StatusOK); a["attributes"].(map[string]interface{})["morph"] != "Tangerine" {
+		t.Fatalf("listing attributes = %v", a["attributes"])
+	}
+	// Filtering by a category takes in the categories under it.
+	if found := s.list(fmt.Sprintf("/api/marketplace/animals?category_id=%d", reptiles), ""); len(found) != 1 {
+		t.Fatalf("reptile listings = %v", found)
+	}
+
+	// A new category is checked against the listing's attributes, and new
+	// attributes against its category.
+	assertDetail(t, s.expect("PATCH", sunnyPath, seller, map[string]interface{}{"attributes": map[string]interface{}{"morph": 5}}, http.StatusBadRequest),
+		"attributes.morph", "must be one of Normal, Tangerine")
+	assertDetail(t, s.expect("PATCH", sunnyPath, seller, map[string]interface{}{"category_id": cats}, http.StatusBadRequest),
+		"attributes.morph", "is not an attribute of the category")
+	assertDetail(t, s.expect("PATCH", sunnyPath, seller, map[string]interface{}{"category_id": reptiles}, http.StatusBadRequest),
+		"category_id", "has subcategories; file the listing under one of them")
+	s.expect("PATCH", sunnyPath, other, map[string]interface{}{"attributes": map[string]interface{}{}}, http.StatusForbidden)
+	if a := s.data("PATCH", sunnyPath, seller, map[string]interface{}{"category_id": cats, "attributes": map[string]interface{}{}}, http.StatusOK); a["category_id"] != float64(cats) {
+		t.Fatalf("refiled listing = %v", a)
+	}
+	s.expect("PATCH", sunnyPath, seller, map[string]interface{}{"price": 700000}, http.StatusOK)
+	assertDetail(t, s.expect("PUT", sunnyPath, seller, map[string]interface{}{"animal_type": "Gecko", "category_id": leopards, "name": "Sunny", "price": 700000}, http.StatusBadRequest),
+		"attributes.morph", "is required")
+	if a := s.data("PUT", sunnyPath, seller, map[string]interface{}{"animal_type": "Gecko", "name": "Sunny", "price": 700000}, http.StatusOK); a["category_id"] != nil {
+		t.Fatalf("replaced listing without a category = %v", a)
+	}
+
+	// Categories are deactivated from the bottom up, and can come back.
+	assertCode(t, category("DELETE", fmt.Sprintf("/%d", geckos), nil, http.StatusConflict), "CATEGORY_HAS_SUBCATEGORIES")
+	category("DELETE", fmt.Sprintf("/%d", leopards), nil, http.StatusOK)
+	assertDetail(t, listing(seller, map[string]interface{}{"category_id": leopards, "attributes": map[string]interface{}{"morph": "Normal"}}, http.StatusBadRequest),
+		"category_id", "does not exist")
+	assertCode(t, category("DELETE", "/999", nil, http.StatusNotFound), "CATEGORY_NOT_FOUND")
+	if n := len(s.list("/api/marketplace/categories", "")); n != 3 {
+		t.Fatalf("active categories = %d, want 3", n)
+	}
+	all := dataList(t, category("GET", "", nil, http.StatusOK))
+	if len(all) != 4 || all[1].(map[string]interface{})["active"] != false {
+		t.Fatalf("all categories = %v", all)
+	}
+	assertDetail(t, category("PUT", fmt.Sprintf("/%d", leopards), map[string]interface{}{"name": "Leopard Gecko", "parent_id": geckos, "active": false, "type": "food"}, http.StatusBadRequest),
+		"type", "must be the parent category's, animal")
+	restored := dataMap(t, category("PUT", fmt.Sprintf("/%d", leopards), map[string]interface{}{
+		"name": "Leopard Gecko", "parent_id": geckos, "active": true, "names": map[string]string{"en": "Leopard Geckos"},
+	}, http.StatusOK))
+	if restored["active"] != true || len(restored["schema"].([]interface{})) != 2 || len(restored["attributes"].([]interface{})) != 0 {
+		t.Fatalf("restored category = %v", restored)
+	}
+}
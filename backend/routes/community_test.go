@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCommunityRoutes(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.register("siti")
+
+	s.expect("GET", "/api/community/posts", "", nil, http.StatusUnauthorized)
+	s.expect("POST", "/api/community/posts", token, map[string]interface{}{}, http.StatusBadRequest)
+
+	created := s.expect("POST", "/api/community/posts", token, map[string]interface{}{
+		"content": "Hello TerraPaw",
+		"media":   []map[string]string{{"media_url": "https://example.com/a.jpg", "media_type": "image"}},
+	}, http.StatusCreated)
+	postID := idOf(t, created)
+	postPath := fmt.Sprintf("/api/community/posts/%d", postID)
+
+	s.expect("POST", postPath+"/like", token, nil, http.StatusOK)
+	s.expect("POST", postPath+"/bookmark", token, nil, http.StatusOK)
+	s.expect("POST", postPath+"/share", token, nil, http.StatusOK)
+	comment := s.expect("POST", postPath+"/comments", token, map[string]string{"content": "Lucu!"}, http.StatusCreated)
+	commentPath := fmt.Sprintf("/api/community/comments/%d", idOf(t, comment))
+	s.expect("POST", commentPath+"/like", token, nil, http.StatusOK)
+
+	list := s.list("/api/community/posts?page=1&limit=10", token)
+	if len(list) != 1 {
+		t.Fatalf("posts = %d, want 1", len(list))
+	}
+	post := list[0].(map[string]interface{})
+	if post["likes"].(float64) != 1 || post["is_liked"] != true || post["is_bookmarked"] != true ||
+		post["comments_count"].(float64) != 1 || post["shares_count"].(float64) != 1 {
+		t.Fatalf("post counters wrong: %v", post)
+	}
+	if len(post["media"].([]interface{})) != 1 {
+		t.Fatalf("post media = %v", post["media"])
+	}
+
+	detail := s.data("GET", postPath, token, nil, http.StatusOK)
+	comments := detail["comments"].([]interface{})
+	if len(comments) != 1 || comments[0].(map[string]interface{})["is_liked"] != true {
+		t.Fatalf("post comments = %v", comments)
+	}
+
+	s.expect("DELETE", commentPath+"/like", token, nil, http.StatusOK)
+	s.expect("DELETE", postPath+"/like", token, nil, http.StatusOK)
+	s.expect("DELETE", postPath+"/bookmark", token, nil, http.StatusOK)
+	detail = s.data("GET", postPath, token, nil, http.StatusOK)
+	if detail["likes"].(float64) != 0 || detail["is_bookmarked"] != false {
+		t.Fatalf("post after unlike/unbookmark = %v", detail)
+	}
+
+	s.expect("GET", "/api/community/posts/999", token, nil, http.StatusNotFound)
+	s.expect("GET", "/api/community/posts/abc", token, nil, http.StatusBadRequest)
+	s.expect("POST", "/api/community/posts/999/like", token, nil, http.StatusNotFound)
+	s.expect("POST", "/api/community/posts/999/comments", token, map[string]string{"content": "x"}, http.StatusNotFound)
+	s.expect("POST", "/api/community/comments/999/like", token, nil, http.StatusNotFound)
+}
+
+func TestChatRoutes(t *testing.T) {
+	s := newTestServer(t)
+	aliceID, alice := s.register("alice")
+	bobID, bob := s.register("bob")
+
+	s.expect("POST", "/api/chat/messages", alice, map[string]interface{}{"receiver_id": bobID}, http.StatusBadRequest)
+	s.expect("POST", "/api/chat/messages", alice, map[string]interface{}{"receiver_id": 999, "content": "hi"}, http.StatusNotFound)
+	s.expect("POST", "/api/chat/messages", alice, map[string]interface{}{"receiver_id": bobID, "content": "Halo"}, http.StatusCreated)
+	s.expect("POST", "/api/chat/messages", bob, map[string]interface{}{"receiver_id": aliceID, "content": "Hai juga"}, http.StatusCreated)
+
+	s.expect("GET", "/api/chat/messages", alice, nil, http.StatusBadRequest)
+	messages := s.list(fmt.Sprintf("/api/chat/messages?partner_id=%d", bobID), alice)
+	if len(messages) != 2 || messages[0].(map[string]interface{})["content"] != "Halo" {
+		t.Fatalf("messages = %v", messages)
+	}
+	if name := messages[1].(map[string]interface{})["sender"].(map[string]interface{})["name"]; name != "Bob" {
+		t.Fatalf("sender name = %v", name)
+	}
+}
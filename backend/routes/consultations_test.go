@@ -0,0 +1,70 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestConsultationRoutes(t *testing.T) {
+	s := newTestServer(t)
+	_, vetToken := s.register("drsarah")
+	_, owner := s.register("owner")
+
+	s.expect("POST", "/api/consultation/veterinarians/register", vetToken, map[string]string{"clinic_name": "x"}, http.StatusBadRequest)
+	vetID := idOf(t, s.expect("POST", "/api/consultation/veterinarians/register", vetToken, map[string]string{
+		"clinic_name": "Klinik Sehat", "license_number": "LIC-1", "specialization": "Kucing",
+	}, http.StatusCreated))
+
+	vets := s.list("/api/consultation/veterinarians?page=1&limit=10", "")
+	if len(vets) != 1 {
+		t.Fatalf("veterinarians = %v", vets)
+	}
+	vet := s.data("GET", fmt.Sprintf("/api/consultation/veterinarians/%d", vetID), "", nil, http.StatusOK)
+	if vet["user"].(map[string]interface{})["user_type"] != "veterinarian" {
+		t.Fatalf("vet user not promoted: %v", vet["user"])
+	}
+	s.expect("GET", "/api/consultation/veterinarians/999", "", nil, http.StatusNotFound)
+
+	s.expect("POST", "/api/consultation/consultations", owner, map[string]interface{}{"pet_name": "Mochi"}, http.StatusBadRequest)
+	s.expect("POST", "/api/consultation/consultations", owner, map[string]interface{}{
+		"veterinarian_id": 999, "pet_name": "Mochi", "symptoms": "Bersin",
+	}, http.StatusNotFound)
+	consultationID := idOf(t, s.expect("POST", "/api/consultation/consultations", owner, map[string]interface{}{
+		"veterinarian_id": vetID, "pet_name": "Mochi", "symptoms": "Bersin",
+	}, http.StatusCreated))
+
+	list := s.list("/api/consultation/consultations", owner)
+	if len(list) != 1 || list[0].(map[string]interface{})["consultation_type"] != "online" {
+		t.Fatalf("consultations = %v", list)
+	}
+
+	path := fmt.Sprintf("/api/consultation/consultations/%d", consultationID)
+	s.expect("PUT", path+"/status", owner, map[string]string{}, http.StatusBadRequest)
+	s.expect("PUT", path+"/status", owner, map[string]string{"status": "scheduled"}, http.StatusOK)
+	if got := s.data("GET", path, owner, nil, http.StatusOK)["status"]; got != "scheduled" {
+		t.Fatalf("consultation status = %v", got)
+	}
+	s.expect("GET", "/api/consultation/consultations/999", owner, nil, http.StatusNotFound)
+}
+
+func TestIdempotentConsultations(t *testing.T) {
+	s := newTestServer(t)
+	_, vet := s.register("drhewan")
+	_, owner := s.register("owner")
+
+	vetID := idOf(t, s.expect("POST", "/api/consultation/veterinarians/register", vet, map[string]interface{}{
+		"clinic_name": "Klinik", "license_number": "L-1",
+	}, http.StatusCreated))
+	booking := map[string]interface{}{"veterinarian_id": vetID, "pet_name": "Mochi", "symptoms": "Bersin"}
+	key := map[string]string{"Idempotency-Key": "consult-1"}
+
+	for i := 0; i < 2; i++ {
+		if w := s.send("POST", "/api/consultation/consultations", owner, key, booking); w.Code != http.StatusCreated {
+			t.Fatalf("attempt %d = %d %s", i, w.Code, w.Body.String())
+		}
+	}
+	if list := s.list("/api/consultation/consultations", owner); len(list) != 1 {
+		t.Fatalf("consultations after retry = %d, want 1", len(list))
+	}
+}
@@ -0,0 +1,295 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/TerraPaw/backend/jobs"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/notify"
+)
+
+func TestMarketplaceRoutes(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+	_, buyer := s.register("buyer")
+
+	s.mem.AddCategory(models.Category{Name: "Kucing", Icon: "🐱", Type: "animal"})
+	categories := s.list("/api/marketplace/categories", "")
+	if len(categories) != 1 {
+		t.Fatalf("categories = %v", categories)
+	}
+
+	s.expect("POST", "/api/marketplace/animals", "", map[string]interface{}{}, http.StatusUnauthorized)
+	s.expect("POST", "/api/marketplace/animals", seller, map[string]interface{}{"name": "x"}, http.StatusBadRequest)
+
+	persian := s.listing(seller, map[string]interface{}{
+		"animal_type": "Kucing", "breed": "Persia", "name": "Mochi", "price": 1500000, "stock": 2, "location": "Jakarta",
+	})
+	s.expect("POST", "/api/marketplace/animals", seller, map[string]interface{}{
+		"animal_type": "Anjing", "breed": "Poodle", "name": "Bobby", "price": 3000000,
+	}, http.StatusCreated)
+
+	all := s.list("/api/marketplace/animals?sort=price_asc", "")
+	if len(all) != 2 || int(all[0].(map[string]interface{})["id"].(float64)) != persian {
+		t.Fatalf("animals sorted by price = %v", all)
+	}
+	cats := s.list("/api/marketplace/animals?animal_type=kucing&search=moc&min_price=1000000&max_price=2000000", "")
+	if len(cats) != 1 {
+		t.Fatalf("filtered animals = %v", cats)
+	}
+
+	animalPath := fmt.Sprintf("/api/marketplace/animals/%d", persian)
+	animal := s.data("GET", animalPath, "", nil, http.StatusOK)
+	if animal["seller"].(map[string]interface{})["username"] != "seller" {
+		t.Fatalf("animal seller = %v", animal["seller"])
+	}
+	s.expect("GET", "/api/marketplace/animals/999", "", nil, http.StatusNotFound)
+
+	// Orders
+	s.expect("POST", "/api/marketplace/orders", buyer, map[string]int{"animal_id": persian, "quantity": 3}, http.StatusBadRequest)
+	s.expect("POST", "/api/marketplace/orders", buyer, map[string]int{"animal_id": 999}, http.StatusNotFound)
+	orderID := s.order(buyer, persian, 2)
+	orders := s.list("/api/marketplace/orders", buyer)
+	if len(orders) != 1 || orders[0].(map[string]interface{})["total_price"].(float64) != 3000000 {
+		t.Fatalf("orders = %v", orders)
+	}
+	if s.data("GET", animalPath, "", nil, http.StatusOK)["status"] != "sold" {
+		t.Fatal("animal should be sold once stock reaches zero")
+	}
+
+	// Wishlist
+	s.expect("POST", "/api/marketplace/wishlist", buyer, map[string]int{"animal_id": persian}, http.StatusOK)
+	s.expect("POST", "/api/marketplace/wishlist", buyer, map[string]int{"animal_id": persian}, http.StatusOK)
+	s.expect("POST", "/api/marketplace/wishlist", buyer, map[string]int{"animal_id": 999}, http.StatusNotFound)
+	if n := len(s.list("/api/marketplace/wishlist", buyer)); n != 1 {
+		t.Fatalf("wishlist = %d, want 1", n)
+	}
+	s.expect("DELETE", fmt.Sprintf("/api/marketplace/wishlist/%d", persian), buyer, nil, http.StatusOK)
+	if n := len(s.list("/api/marketplace/wishlist", buyer)); n != 0 {
+		t.Fatalf("wishlist after delete = %d, want 0", n)
+	}
+
+	// Reviews: one per line of a completed order.
+	review := func(token string, animalID, rating int, status int) map[string]interface{} {
+		return s.expect("POST", "/api/marketplace/reviews", token, map[string]interface{}{
+			"animal_id": animalID, "rating": rating, "comment": "Sehat!", "order_id": orderID,
+		}, status)
+	}
+	review(buyer, persian, 6, http.StatusBadRequest)
+	s.expect("POST", "/api/marketplace/reviews", buyer, map[string]interface{}{"animal_id": persian, "rating": 5}, http.StatusBadRequest)
+	assertCode(t, review(seller, persian, 5, http.StatusNotFound), "ORDER_NOT_FOUND")
+	assertCode(t, review(buyer, persian, 5, http.StatusConflict), "ORDER_NOT_REVIEWABLE")
+	s.complete(orderID, seller, buyer)
+	review(buyer, 999, 5, http.StatusNotFound)
+	if created := dataMap(t, review(buyer, persian, 4, http.StatusCreated)); created["verified_purchase"] != true || created["order_item_id"] == nil {
+		t.Fatalf("review = %v", created)
+	}
+	assertCode(t, review(buyer, persian, 5, http.StatusConflict), "REVIEW_ALREADY_EXISTS")
+
+	out := s.expect("GET", animalPath+"/reviews", "", nil, http.StatusOK)
+	reviews := dataList(t, out)
+	if len(reviews) != 1 || reviews[0].(map[string]interface{})["user"].(map[string]interface{})["username"] != "buyer" {
+		t.Fatalf("reviews = %v", reviews)
+	}
+	summary := out["summary"].(map[string]interface{})
+	histogram := summary["distribution"].(map[string]interface{})
+	if summary["average"] != 4.0 || summary["count"] != 1.0 || summary["verified"] != 1.0 || len(histogram) != 5 || histogram["4"] != 1.0 || histogram["5"] != 0.0 {
+		t.Fatalf("summary = %v", summary)
+	}
+	if rated := s.data("GET", animalPath, "", nil, http.StatusOK); rated["rating"] != 4.0 || rated["review_count"] != 1.0 {
+		t.Fatalf("rated animal = %v", rated)
+	}
+}
+
+func TestListingManagement(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+	_, other := s.register("other")
+	_, buyer := s.register("buyer")
+
+	id := s.listing(seller, map[string]interface{}{
+		"animal_type": "Kucing", "name": "Mochii", "price": 100, "stock": 1,
+	})
+	path := fmt.Sprintf("/api/marketplace/animals/%d", id)
+
+	// Only the seller may edit, and a partial edit keeps the other fields.
+	assertCode(t, s.expect("PATCH", path, other, map[string]interface{}{"name": "Mine"}, http.StatusForbidden), "FORBIDDEN")
+	a := s.data("PATCH", path, seller, map[string]interface{}{"name": "Mochi", "price": 150}, http.StatusOK)
+	if a["name"] != "Mochi" || a["price"] != 150.0 || a["animal_type"] != "Kucing" {
+		t.Fatalf("patched = %v", a)
+	}
+	a = s.data("PUT", path, seller, map[string]interface{}{
+		"animal_type": "Kucing", "name": "Mochi", "breed": "Persian", "price": 120,
+	}, http.StatusOK)
+	if a["breed"] != "Persian" || a["price"] != 120.0 {
+		t.Fatalf("replaced = %v", a)
+	}
+	assertDetail(t, s.expect("PUT", path, seller, map[string]interface{}{"name": "Mochi"}, http.StatusBadRequest), "animal_type", "is required")
+	assertDetail(t, s.expect("PATCH", path, seller, map[string]interface{}{"status": "sold"}, http.StatusBadRequest),
+		"status", "must be one of available, reserved, archived")
+
+	history := s.list(path+"/price-history", "")
+	if len(history) != 2 {
+		t.Fatalf("price history = %v", history)
+	}
+	if h := history[0].(map[string]interface{}); h["old_price"] != 150.0 || h["new_price"] != 120.0 {
+		t.Errorf("latest price change = %v", h)
+	}
+
+	// A reserved listing leaves the marketplace and cannot be ordered.
+	s.expect("PATCH", path, seller, map[string]interface{}{"status": "reserved"}, http.StatusOK)
+	if items := s.list("/api/marketplace/animals", ""); len(items) != 0 {
+		t.Fatalf("reserved listing still browsable: %v", items)
+	}
+	assertCode(t, s.expect("POST", "/api/marketplace/orders", buyer, map[string]int{"animal_id": id}, http.StatusConflict), "ANIMAL_UNAVAILABLE")
+
+	// Selling out marks it sold; restocking puts it back on sale.
+	s.expect("PATCH", path, seller, map[string]interface{}{"status": "available"}, http.StatusOK)
+	s.expect("POST", "/api/marketplace/orders", buyer, map[string]int{"animal_id": id}, http.StatusCreated)
+	assertDetail(t, s.expect("PATCH", path, seller, map[string]interface{}{"status": "available"}, http.StatusBadRequest),
+		"status", "cannot be available without stock; restock first")
+	assertCode(t, s.expect("POST", path+"/restock", other, map[string]int{"quantity": 2}, http.StatusForbidden), "FORBIDDEN")
+	assertDetail(t, s.expect("POST", path+"/restock", seller, map[string]int{"quantity": 0}, http.StatusBadRequest), "quantity", "is required")
+	a = s.data("POST", path+"/restock", seller, map[string]int{"quantity": 2}, http.StatusOK)
+	if a["status"] != "available" || a["stock"] != 2.0 {
+		t.Fatalf("restocked = %v", a)
+	}
+
+	archived := s.listing(seller, map[string]interface{}{
+		"animal_type": "Anjing", "name": "Bobby", "price": 300,
+	})
+	s.expect("PATCH", fmt.Sprintf("/api/marketplace/animals/%d", archived), seller, map[string]interface{}{"status": "archived"}, http.StatusOK)
+	deleted := s.listing(seller, map[string]interface{}{
+		"animal_type": "Anjing", "name": "Rex", "price": 300,
+	})
+	assertCode(t, s.expect("DELETE", fmt.Sprintf("/api/marketplace/animals/%d", deleted), other, nil, http.StatusForbidden), "FORBIDDEN")
+	s.expect("DELETE", fmt.Sprintf("/api/marketplace/animals/%d", deleted), seller, nil, http.StatusOK)
+	assertCode(t, s.expect("GET", fmt.Sprintf("/api/marketplace/animals/%d", deleted), "", nil, http.StatusNotFound), "ANIMAL_NOT_FOUND")
+	assertCode(t, s.expect("PATCH", fmt.Sprintf("/api/marketplace/animals/%d", deleted), seller, map[string]interface{}{"name": "Rex"}, http.StatusNotFound), "ANIMAL_NOT_FOUND")
+
+	out := s.expect("GET", "/api/marketplace/my-listings", seller, nil, http.StatusOK)
+	if items := dataList(t, out); len(items) != 2 {
+		t.Fatalf("my listings = %v", items)
+	}
+	counts := out["counts"].(map[string]interface{})
+	if counts["available"] != 1.0 || counts["archived"] != 1.0 || counts["sold"] != 0.0 || counts["reserved"] != 0.0 {
+		t.Errorf("counts = %v", counts)
+	}
+	if items := s.list("/api/marketplace/my-listings?status=archived", seller); len(items) != 1 ||
+		items[0].(map[string]interface{})["name"] != "Bobby" {
+		t.Errorf("archived listings = %v", items)
+	}
+	if items := s.list("/api/marketplace/my-listings", other); len(items) != 0 {
+		t.Errorf("other seller's listings = %v", items)
+	}
+	assertDetail(t, s.expect("GET", "/api/marketplace/my-listings?status=deleted", seller, nil, http.StatusBadRequest),
+		"status", "must be one of available, reserved, sold, archived")
+}
+
+func TestWishlistAlerts(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+	_, keen := s.register("keen")
+	_, picky := s.register("picky")
+	_, other := s.register("other")
+
+	kitten := s.listing(seller, map[string]interface{}{
+		"animal_type": "Kucing", "name": "Mochi", "price": 1000000, "stock": 1,
+	})
+	kittenPath := fmt.Sprintf("/api/marketplace/animals/%d", kitten)
+	for _, buyer := range []string{keen, picky} {
+		s.expect("POST", "/api/marketplace/wishlist", buyer, map[string]int{"animal_id": kitten}, http.StatusOK)
+	}
+
+	// Everyone starts with every alert, in the app only; a partial change
+	// keeps the other preferences.
+	if prefs := s.data("GET", "/api/profile/alert-preferences", keen, nil, http.StatusOK); prefs["price_drop"] != true ||
+		prefs["back_in_stock"] != true || prefs["min_drop_percent"] != 0.0 || prefs["email"] != false || prefs["push"] != false {
+		t.Fatalf("default preferences = %v", prefs)
+	}
+	assertDetail(t, s.expect("PUT", "/api/profile/alert-preferences", picky, map[string]int{"min_drop_percent": 95}, http.StatusBadRequest),
+		"min_drop_percent", "must be at most 90")
+	s.expect("PUT", "/api/profile/alert-preferences", picky, map[string]interface{}{"min_drop_percent": 20, "email": true}, http.StatusOK)
+	prefs := s.data("PUT", "/api/profile/alert-preferences", picky, map[string]bool{"push": true, "back_in_stock": false}, http.StatusOK)
+	if prefs["min_drop_percent"] != 20.0 || prefs["email"] != true || prefs["push"] != true || prefs["price_drop"] != true || prefs["back_in_stock"] != false {
+		t.Fatalf("saved preferences = %v", prefs)
+	}
+	s.expect("PUT", "/api/profile/alert-preferences", "", map[string]bool{"push": true}, http.StatusUnauthorized)
+
+	run := func() {
+		t.Helper()
+		if err := jobs.SendWishlistAlerts(context.Background()); err != nil {
+			t.Fatalf("alerts: %v", err)
+		}
+	}
+	alerts := func(token string) []string {
+		t.Helper()
+		var titles []string
+		for _, n := range s.list("/api/profile/notifications", token) {
+			if n := n.(map[string]interface{}); n["type"] == "wishlist" {
+				titles = append(titles, n["title"].(string)+": "+n["message"].(string))
+			}
+		}
+		return titles
+	}
+	expectAlerts := func(label, token string, want ...string) {
+		t.Helper()
+		if got := alerts(token); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("%s: alerts = %q, want %q", label, got, want)
+		}
+	}
+
+	run()
+	expectAlerts("unchanged", keen)
+
+	// A 10% drop reaches keen but not picky, who waits for 20%.
+	s.expect("PATCH", kittenPath, seller, map[string]int{"price": 900000}, http.StatusOK)
+	run()
+	run()
+	expectAlerts("small drop", keen, "Price drop: Mochi: Mochi from your wishlist dropped from Rp1.000.000 to Rp900.000.")
+	expectAlerts("small drop", picky)
+
+	// Picky measures from the price they last saw, and gets copies by
+	// email and push.
+	s.expect("PATCH", kittenPath, seller, map[string]int{"price": 700000}, http.StatusOK)
+	run()
+	expectAlerts("big drop", picky, "Price drop: Mochi: Mochi from your wishlist dropped from Rp900.000 to Rp700.000.")
+	if n := len(alerts(keen)); n != 2 {
+		t.Fatalf("keen alerts after big drop = %d, want 2", n)
+	}
+	sent := s.sender.Sent()
+	if len(sent) != 2 || sent[0].Channel != notify.ChannelEmail || sent[0].To != "picky@example.com" ||
+		sent[1].Channel != notify.ChannelPush || sent[1].To != "" || sent[1].Title != "Price drop: Mochi" {
+		t.Fatalf("sent = %+v", sent)
+	}
+
+	// Going back to a price already announced is not announced again.
+	s.expect("PATCH", kittenPath, seller, map[string]int{"price": 800000}, http.StatusOK)
+	run()
+	s.expect("PATCH", kittenPath, seller, map[string]int{"price": 700000}, http.StatusOK)
+	run()
+	if n := len(alerts(keen)); n != 2 {
+		t.Fatalf("keen alerts after repeated price = %d, want 2", n)
+	}
+
+	// Selling out is silent; the restock is announced to those who want it.
+	s.expect("POST", "/api/marketplace/orders", other, map[string]int{"animal_id": kitten, "quantity": 1}, http.StatusCreated)
+	run()
+	if n := len(alerts(keen)); n != 2 {
+		t.Fatalf("keen alerts after selling out = %d, want 2", n)
+	}
+	s.expect("POST", kittenPath+"/restock", seller, map[string]int{"quantity": 2}, http.StatusOK)
+	run()
+	if got := alerts(keen); len(got) != 3 || got[0] != "Back in stock: Mochi: Mochi from your wishlist is available again at Rp700.000." {
+		t.Fatalf("keen alerts after restock = %q", got)
+	}
+	if n := len(alerts(picky)); n != 1 {
+		t.Fatalf("picky alerts after restock = %d, want 1", n)
+	}
+	if n := len(s.sender.Sent()); n != 2 {
+		t.Fatalf("sent after restock = %d, want 2", n)
+	}
+}
@@ -0,0 +1,126 @@
+package routes
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/TerraPaw/backend/storage"
+)
+
+// testJPEG encodes a w×h JPEG and splices in an EXIF block that asks for
+// a 90° turn and carries a marker standing in for GPS coordinates.
+func testJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 90, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// TIFF header, one IFD entry (orientation = 6), then the marker.
+	tiff := []byte{'I', 'I', 42, 0, 8, 0, 0, 0, 1, 0, 0x12, 0x01, 3, 0, 1, 0, 0, 0, 6, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	payload := append(append([]byte("Exif\x00\x00"), tiff...), "GPS 6.2088S 106.8456E"...)
+	segment := append([]byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}, payload...)
+	raw := buf.Bytes()
+	return append(append(append([]byte{}, raw[:2]...), segment...), raw[2:]...)
+}
+
+func TestMediaUploads(t *testing.T) {
+	s := newTestServer(t)
+	sellerID, seller := s.register("seller")
+	_, other := s.register("other")
+	_ = sellerID
+
+	photo := testJPEG(t, 640, 320)
+	s.upload("/api/media/uploads?kind=post", "", "cat.jpg", photo, http.StatusUnauthorized)
+	up := dataMap(t, s.upload("/api/media/uploads?kind=post", seller, "cat.jpg", photo, http.StatusCreated))
+	ref := up["ref"].(string)
+	if !strings.HasPrefix(ref, "/api/media/post/") || strings.Contains(ref, "?") || up["content_type"] != "image/jpeg" {
+		t.Fatalf("upload = %v", up)
+	}
+	// The EXIF orientation is applied to the pixels before it is dropped.
+	if up["width"] != 320.0 || up["height"] != 640.0 {
+		t.Errorf("dimensions = %vx%v, want 320x640", up["width"], up["height"])
+	}
+
+	fetch := func(url string) []byte {
+		t.Helper()
+		w := s.send("GET", url, "", nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %s", url, w.Code, w.Body.String())
+		}
+		if w.Header().Get("Content-Type") != "image/jpeg" {
+			t.Errorf("GET %s: content type %q", url, w.Header().Get("Content-Type"))
+		}
+		return w.Body.Bytes()
+	}
+	stored := fetch(up["url"].(string))
+	if bytes.Contains(stored, []byte("Exif")) || bytes.Contains(stored, []byte("106.8456E")) {
+		t.Error("stored image still carries EXIF metadata")
+	}
+	thumb, err := jpeg.DecodeConfig(bytes.NewReader(fetch(up["thumbnail_url"].(string))))
+	if err != nil || thumb.Width != 160 || thumb.Height != 320 {
+		t.Errorf("thumbnail = %+v, %v", thumb, err)
+	}
+
+	// Files are only served through valid, unexpired signatures.
+	assertCode(t, s.expect("GET", ref, "", nil, http.StatusForbidden), "FORBIDDEN")
+	assertCode(t, s.expect("GET", up["url"].(string)+"0", "", nil, http.StatusForbidden), "FORBIDDEN")
+	assertCode(t, s.expect("GET", ref+"?expires=1&signature=00", "", nil, http.StatusForbidden), "FORBIDDEN")
+	assertCode(t, s.expect("GET", storage.DefaultSigner.Sign("/api/media/post/missing.jpg"), "", nil, http.StatusNotFound), "MEDIA_NOT_FOUND")
+
+	// Content is sniffed, whatever the file is called.
+	assertCode(t, s.upload("/api/media/uploads?kind=pet", seller, "cat.jpg", []byte("#!/bin/sh\necho not an image\n"), http.StatusUnsupportedMediaType),
+		"UNSUPPORTED_MEDIA_TYPE")
+	assertDetail(t, s.upload("/api/media/uploads?kind=pet", seller, "cat.jpg", append([]byte{0xFF, 0xD8, 0xFF}, make([]byte, 64)...), http.StatusBadRequest),
+		"file", "is not a valid image")
+	assertDetail(t, s.upload("/api/media/uploads", seller, "cat.jpg", photo, http.StatusBadRequest), "kind", "is required")
+	t.Setenv("MAX_UPLOAD_BYTES", "1000")
+	assertCode(t, s.upload("/api/media/uploads?kind=pet", seller, "cat.jpg", photo, http.StatusRequestEntityTooLarge), "FILE_TOO_LARGE")
+
+	// Listing photos fill the gallery, with thumbnails, and the first one
+	// becomes the main image.
+	animalID := s.listing(seller, map[string]interface{}{
+		"animal_type": "Kucing", "name": "Mochi", "price": 100,
+	})
+	path := fmt.Sprintf("/api/marketplace/animals/%d/media", animalID)
+	var logo bytes.Buffer
+	png.Encode(&logo, image.NewNRGBA(image.Rect(0, 0, 32, 32)))
+	assertCode(t, s.upload(path, other, "logo.png", logo.Bytes(), http.StatusForbidden), "FORBIDDEN")
+	s.upload(path, seller, "logo.png", logo.Bytes(), http.StatusCreated)
+
+	animal := s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d", animalID), "", nil, http.StatusOK)
+	gallery := animal["media"].([]interface{})
+	if len(gallery) != 1 {
+		t.Fatalf("media = %v", animal["media"])
+	}
+	item := gallery[0].(map[string]interface{})
+	if !strings.HasPrefix(animal["image_url"].(string), "/api/media/animal/") || !strings.Contains(animal["image_url"].(string), "signature=") {
+		t.Errorf("image_url = %v", animal["image_url"])
+	}
+	if !strings.Contains(item["thumbnail_url"].(string), "_thumb.jpg?") {
+		t.Errorf("thumbnail_url = %v", item["thumbnail_url"])
+	}
+	fetch(item["thumbnail_url"].(string))
+
+	// A signed link sent back is stored as its reference and re-signed on read.
+	postID := idOf(t, s.expect("POST", "/api/community/posts", seller, map[string]interface{}{
+		"content": "Meet Mochi", "media": []map[string]string{{"media_url": up["url"].(string), "media_type": "image"}},
+	}, http.StatusCreated))
+	post := s.data("GET", fmt.Sprintf("/api/community/posts/%d", postID), seller, nil, http.StatusOK)
+	if u := post["media"].([]interface{})[0].(map[string]interface{})["media_url"].(string); strings.Count(u, "?") != 1 || !strings.HasPrefix(u, ref+"?") {
+		t.Errorf("post media_url = %v", u)
+	}
+}
@@ -0,0 +1,336 @@
+package routes
+
+import (
+	"net/http"
+
+	h "github.com/TerraPaw/backend/handlers"
+	"github.com/TerraPaw/backend/middleware"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/openapi"
+	"github.com/TerraPaw/backend/payments"
+	"github.com/TerraPaw/backend/store"
+	"github.com/gin-gonic/gin"
+)
+
+var pageParams = []openapi.Param{
+	{Name: "cursor", Description: "Opaque next_cursor from the previous page"},
+	{Name: "limit", Type: "integer", Description: "Page size, 20 by default and at most 100"},
+	{Name: "page", Type: "integer", Description: "Deprecated 1-based page number; ignored when cursor is set"},
+	{Name: "include_total", Type: "boolean", Description: "Include the total match count, estimated for public lists"},
+}
+
+var categoryParams = []openapi.Param{
+	{Name: "tree", Type: "boolean", Description: "Nest subcategories under their parents"},
+	{Name: "lang", Description: "Language code to label categories in, such as en; their name when they have none in it"},
+}
+
+var idempotencyHeader = []openapi.Param{{
+	Name:        middleware.IdempotencyHeader,
+	Description: "Unique key that makes the request safe to retry; retries with the same key and body replay the first response",
+}}
+
+// apiDocs describes every route registered in SetupRoutes. Keys use gin's
+// path syntax. The contract suite fails if a route is missing from here.
+var apiDocs = map[string]openapi.Route{
+	"GET /health": {Summary: "Health check", Tag: "system", Envelope: openapi.Raw,
+		Response: struct {
+			Status string `json:"status"`
+		}{}},
+	"GET /api/openapi.json": {Summary: "OpenAPI document", Tag: "system", Envelope: openapi.Raw},
+	"GET /api/docs":         {Summary: "API reference UI", Tag: "system", Envelope: openapi.Raw},
+
+	// Auth
+	"POST /api/auth/register": {Summary: "Register a customer account", Tag: "auth",
+		Request: h.RegisterRequest{}, Response: h.AuthResponse{}, Status: http.StatusCreated},
+	"POST /api/auth/login": {Summary: "Log in with email and password", Tag: "auth",
+		Request: h.LoginRequest{}, Response: h.AuthResponse{}},
+	"POST /api/auth/forgot-password": {Summary: "Request a password reset code", Tag: "auth",
+		Request: h.ForgotPasswordRequest{}, Response: h.ForgotPasswordResponse{}},
+	"POST /api/auth/reset-password": {Summary: "Reset password with a reset code", Tag: "auth",
+		Request: h.ResetPasswordRequest{}},
+	"GET /api/auth/profile": {Summary: "Current user", Tag: "auth", Auth: true,
+		Response: models.User{}},
+
+	// Profile
+	"GET /api/profile/pets": {Summary: "List my pets", Tag: "profile", Auth: true,
+		Query: pageParams, Response: []models.UserPet{}, Envelope: openapi.Paginated},
+	"POST /api/profile/pets": {Summary: "Add a pet", Tag: "profile", Auth: true,
+		Request: h.CreateUserPetRequest{}, Response: h.IDResponse{}, Status: http.StatusCreated},
+	"GET /api/profile/medical-records": {Summary: "Medical records for my pets", Tag: "profile", Auth: true,
+		Query: pageParams, Response: []h.MedicalRecordResponse{}, Envelope: openapi.Paginated},
+	"GET /api/profile/notifications": {Summary: "My notifications", Tag: "profile", Auth: true,
+		Query: pageParams, Response: []models.Notification{}, Envelope: openapi.Paginated},
+	"GET /api/profile/alert-preferences": {Summary: "My wishlist alert preferences", Tag: "profile", Auth: true,
+		Response: models.AlertPreferences{}},
+	"PUT /api/profile/alert-preferences": {Summary: "Change wishlist alert preferences", Tag: "profile", Auth: true,
+		Request: h.AlertPreferencesRequest{}, Response: models.AlertPreferences{}},
+	"PUT /api/profile/location": {Summary: "Set where I am, geocoded unless coordinates are given", Tag: "profile", Auth: true,
+		Request: h.LocationRequest{}, Response: models.User{}},
+	"GET /api/profile/stats": {Summary: "Profile counters", Tag: "profile", Auth: true, Response: h.UserStats{}},
+	"POST /api/profile/vouchers": {Summary: "Claim a voucher by code", Tag: "profile", Auth: true,
+		Request: h.VoucherCodeRequest{}, Response: models.UserVoucher{}, Status: http.StatusCreated},
+	"GET /api/profile/vouchers": {Summary: "My claimed vouchers", Tag: "profile", Auth: true,
+		Query: pageParams, Response: []models.UserVoucher{}, Envelope: openapi.Paginated},
+	"GET /api/profile/addresses": {Summary: "My address book, default first", Tag: "profile", Auth: true,
+		Response: []models.Address{}},
+	"POST /api/profile/addresses": {Summary: "Add an address", Tag: "profile", Auth: true,
+		Request: h.AddressRequest{}, Response: models.Address{}, Status: http.StatusCreated},
+	"PUT /api/profile/addresses/:id": {Summary: "Replace an address", Tag: "profile", Auth: true,
+		Request: h.AddressRequest{}, Response: models.Address{}},
+	"DELETE /api/profile/addresses/:id": {Summary: "Delete an address", Tag: "profile", Auth: true},
+	"GET /api/profile/following": {Summary: "Shops I follow, latest follow first", Tag: "profile", Auth: true,
+		Query: pageParams, Response: []models.Shop{}, Envelope: openapi.Paginated},
+	"GET /api/profile/feed": {Summary: "Newest listings from shops I follow", Tag: "profile", Auth: true,
+		Query: pageParams, Response: []models.Animal{}, Envelope: openapi.Paginated},
+
+	// Community
+	"POST /api/community/posts": {Summary: "Create a post", Tag: "community", Auth: true,
+		Request: h.CreatePostRequest{}, Response: h.IDResponse{}, Status: http.StatusCreated},
+	"GET /api/community/posts": {Summary: "Community feed", Tag: "community", Auth: true,
+		Query: pageParams, Response: []models.Post{}, Envelope: openapi.Paginated},
+	"GET /api/community/posts/:id":             {Summary: "Get a post", Tag: "community", Auth: true, Response: models.Post{}},
+	"POST /api/community/posts/:id/like":       {Summary: "Like a post", Tag: "community", Auth: true},
+	"DELETE /api/community/posts/:id/like":     {Summary: "Unlike a post", Tag: "community", Auth: true},
+	"POST /api/community/posts/:id/bookmark":   {Summary: "Bookmark a post", Tag: "community", Auth: true},
+	"DELETE /api/community/posts/:id/bookmark": {Summary: "Remove a bookmark", Tag: "community", Auth: true},
+	"POST /api/community/posts/:id/share":      {Summary: "Record a share", Tag: "community", Auth: true},
+	"POST /api/community/posts/:id/comments": {Summary: "Comment on a post", Tag: "community", Auth: true,
+		Request: h.CreateCommentRequest{}, Response: h.IDResponse{}, Status: http.StatusCreated},
+	"POST /api/community/comments/:id/like":   {Summary: "Like a comment", Tag: "community", Auth: true},
+	"DELETE /api/community/comments/:id/like": {Summary: "Unlike a comment", Tag: "community", Auth: true},
+
+	// Marketplace
+	"GET /api/marketplace/animals": {Summary: "Browse listings", Tag: "marketplace",
+		Query: append([]openapi.Param{
+			{Name: "animal_type", Description: "Substring of the animal type, case-insensitive"},
+			{Name: "search", Description: "Full-text search over name, breed, type and description; tolerates typos"},
+			{Name: "breed", Description: "Substring of the breed, case-insensitive"},
+			{Name: "gender", Description: "Exact gender, case-insensitive"},
+			{Name: "color", Description: "Exact color, case-insensitive"},
+			{Name: "location", Description: "Substring of the location, case-insensitive"},
+			{Name: "min_price", Type: "number"},
+			{Name: "max_price", Type: "number"},
+			{Name: "min_age", Type: "integer"},
+			{Name: "max_age", Type: "integer"},
+			{Name: "min_rating", Type: "number", Description: "0 to 5"},
+			{Name: "seller_id", Type: "integer"},
+			{Name: "category_id", Type: "integer", Description: "Category the listing is filed under, or one above it"},
+			{Name: "in_stock", Type: "boolean", Description: "Only listings with stock left"},
+			{Name: "near", Description: "latitude,longitude in degrees; only listings within radius_km, each with its distance_km"},
+			{Name: "radius_km", Type: "number", Description: "With near: 50 by default, from 0.1 to 2000"},
+			{Name: "sort", Enum: store.AnimalSorts, Description: "relevance when searching, distance near a point, newest otherwise; popularity counts orders and wishlist saves"},
+			{Name: "facets", Type: "boolean", Description: "Also return counts by type, breed, color, gender, location and price for the filtered listings"},
+		}, pageParams...),
+		Response: []models.Animal{}, Envelope: openapi.Paginated,
+		Extra: map[string]interface{}{"facets": models.AnimalFacets{}}},
+	"GET /api/marketplace/animals/:id": {Summary: "Listing details", Tag: "marketplace", Response: models.Animal{}},
+	"GET /api/marketplace/animals/:id/reviews": {Summary: "Listing reviews with a rating breakdown", Tag: "reviews",
+		Query: append([]openapi.Param{
+			{Name: "sort", Enum: store.ReviewSorts, Description: "newest by default; helpful ranks by helpful votes"},
+			{Name: "with_photos", Type: "boolean", Description: "Only reviews with a photo"},
+		}, pageParams...),
+		Response: []models.Review{}, Envelope: openapi.Paginated,
+		Extra: map[string]interface{}{"summary": models.RatingSummary{}}},
+	"GET /api/marketplace/search/suggest": {Summary: "Search autocomplete", Tag: "marketplace",
+		Query: []openapi.Param{
+			{Name: "q", Required: true, Description: "Partial search text, at most 100 characters"},
+			{Name: "limit", Type: "integer", Description: "Number of suggestions, 10 by default and at most 20"},
+		},
+		Response: []models.SearchSuggestion{}},
+	"GET /api/marketplace/categories": {Summary: "Marketplace categories in sort order, each with its attribute schema", Tag: "marketplace",
+		Query: categoryParams, Response: []models.Category{}},
+	"GET /api/marketplace/products": {Summary: "Browse pet supplies", Tag: "marketplace",
+		Query: append([]openapi.Param{
+			{Name: "search", Description: "Words in the name or brand"},
+			{Name: "brand", Description: "Exact brand, ignoring case"},
+			{Name: "category_id", Type: "integer", Description: "Supplies category"},
+			{Name: "seller_id", Type: "integer", Description: "Seller"},
+			{Name: "in_stock", Type: "boolean", Description: "Only products with a variant in stock"},
+			{Name: "sort", Enum: store.ProductSorts, Description: "newest by default; prices are of the cheapest variant"},
+		}, pageParams...),
+		Response: []models.Product{}, Envelope: openapi.Paginated},
+	"GET /api/marketplace/products/:id": {Summary: "Product details with its variants", Tag: "marketplace",
+		Response: models.Product{}},
+	"POST /api/marketplace/products": {Summary: "Add a product in a supplies category", Tag: "marketplace", Auth: true,
+		Request: h.CreateProductRequest{}, Response: h.IDResponse{}, Status: http.StatusCreated},
+	"PATCH /api/marketplace/products/:id/variants/:variant_id": {Summary: "Change a variant's price or stock", Tag: "marketplace", Auth: true,
+		Request: h.UpdateVariantRequest{}, Response: models.Product{}},
+	"POST /api/marketplace/animals": {Summary: "Create a listing", Tag: "marketplace", Auth: true,
+		Request: h.CreateAnimalRequest{}, Response: h.IDResponse{}, Status: http.StatusCreated},
+	"PUT /api/marketplace/animals/:id": {Summary: "Replace a listing's details", Tag: "marketplace", Auth: true,
+		Request: h.UpdateAnimalRequest{}, Response: models.Animal{}},
+	"PATCH /api/marketplace/animals/:id": {Summary: "Edit a listing", Tag: "marketplace", Auth: true,
+		Request: h.PatchAnimalRequest{}, Response: models.Animal{}},
+	"DELETE /api/marketplace/animals/:id": {Summary: "Delete a listing", Tag: "marketplace", Auth: true},
+	"POST /api/marketplace/animals/:id/restock": {Summary: "Restock a listing", Tag: "marketplace", Auth: true,
+		Request: h.RestockRequest{}, Response: models.Animal{}},
+	"POST /api/marketplace/animals/:id/media": {Summary: "Add a listing photo", Tag: "marketplace", Auth: true,
+		Files: []string{"file"}, Response: models.Animal{}, Status: http.StatusCreated},
+	"GET /api/marketplace/animals/:id/price-history": {Summary: "Listing price history", Tag: "marketplace",
+		Response: []models.PriceChange{}},
+	"GET /api/marketplace/animals/:id/shipping": {Summary: "Delivery rates for one of a listing; live animals only get live-animal services", Tag: "marketplace",
+		Query: []openapi.Param{
+			{Name: "city", Required: true, Description: "Destination city"},
+		},
+		Response: h.SellerShipping{}},
+	"GET /api/marketplace/animals/:id/similar": {Summary: "Listings for sale like this one, best first", Tag: "marketplace",
+		Query: []openapi.Param{
+			{Name: "limit", Type: "integer", Description: "Number of listings, 10 by default and at most 20"},
+		},
+		Response: []models.Recommendation{}},
+	"GET /api/marketplace/recommendations": {Summary: "Listings suggested from what I ordered and saved", Tag: "marketplace", Auth: true,
+		Query: []openapi.Param{
+			{Name: "limit", Type: "integer", Description: "Number of listings, 20 by default and at most 50"},
+		},
+		Response: []models.Recommendation{}},
+	"GET /api/marketplace/my-listings": {Summary: "My listings", Tag: "marketplace", Auth: true,
+		Query: append([]openapi.Param{
+			{Name: "status", Enum: store.ListingStatuses, Description: "All statuses when omitted"},
+		}, pageParams...),
+		Response: []models.Animal{}, Envelope: openapi.Paginated,
+		Extra: map[string]interface{}{"counts": models.ListingCounts{}}},
+	"POST /api/marketplace/orders": {Summary: "Buy a listing or a product variant outright", Tag: "marketplace", Auth: true,
+		Headers: idempotencyHeader, Request: h.CreateOrderRequest{}, Response: h.IDResponse{}, Status: http.StatusCreated},
+	"GET /api/marketplace/orders": {Summary: "My orders", Tag: "marketplace", Auth: true,
+		Query: pageParams, Response: []models.Order{}, Envelope: openapi.Paginated},
+	"GET /api/marketplace/orders/:id": {Summary: "Order details with lines, sub-orders and history (buyer or seller)", Tag: "marketplace", Auth: true,
+		Response: models.Order{}},
+	"PUT /api/marketplace/orders/:id/status": {Summary: "Move an order to its next status", Tag: "marketplace", Auth: true,
+		Request: h.UpdateOrderStatusRequest{}, Response: models.Order{}},
+	"POST /api/marketplace/orders/:id/payments": {Summary: "Pay for an order by virtual account or QRIS", Tag: "marketplace", Auth: true,
+		Headers: idempotencyHeader, Request: h.CreatePaymentRequest{}, Response: models.Payment{}, Status: http.StatusCreated},
+	"GET /api/marketplace/orders/:id/payments": {Summary: "Payments for my order", Tag: "marketplace", Auth: true,
+		Response: []models.Payment{}},
+	"GET /api/marketplace/orders/:id/shipment": {Summary: "Delivery and tracking of an order (buyer or seller)", Tag: "marketplace", Auth: true,
+		Response: models.Shipment{}},
+	"POST /api/marketplace/orders/:id/shipment": {Summary: "Book the courier pickup and mark the order shipped", Tag: "marketplace", Auth: true,
+		Response: models.Order{}},
+	"GET /api/marketplace/sales": {Summary: "Orders for my listings", Tag: "marketplace", Auth: true,
+		Query: append([]openapi.Param{
+			{Name: "status", Enum: store.OrderStatuses, Description: "All statuses when omitted"},
+		}, pageParams...),
+		Response: []models.Order{}, Envelope: openapi.Paginated},
+	"GET /api/marketplace/cart": {Summary: "My cart, checked against current listings", Tag: "marketplace", Auth: true,
+		Response: models.Cart{}},
+	"POST /api/marketplace/cart/items": {Summary: "Add a listing or a product variant to the cart", Tag: "marketplace", Auth: true,
+		Request: h.AddCartItemRequest{}, Response: models.Cart{}},
+	"PUT /api/marketplace/cart/items/:id": {Summary: "Change the quantity of a cart item (id is the animal id)", Tag: "marketplace", Auth: true,
+		Request: h.UpdateCartItemRequest{}, Response: models.Cart{}},
+	"DELETE /api/marketplace/cart/items/:id": {Summary: "Remove from cart (id is the animal id)", Tag: "marketplace", Auth: true,
+		Response: models.Cart{}},
+	"PUT /api/marketplace/cart/variants/:id": {Summary: "Change the quantity of a product variant in the cart", Tag: "marketplace", Auth: true,
+		Request: h.UpdateCartItemRequest{}, Response: models.Cart{}},
+	"DELETE /api/marketplace/cart/variants/:id": {Summary: "Remove a product variant from the cart", Tag: "marketplace", Auth: true,
+		Response: models.Cart{}},
+	"POST /api/marketplace/cart/voucher": {Summary: "Check what a voucher takes off the cart", Tag: "marketplace", Auth: true,
+		Request: h.VoucherCodeRequest{}, Response: models.VoucherQuote{}},
+	"POST /api/marketplace/cart/shipping": {Summary: "Delivery rates for each seller in the cart to one of my addresses", Tag: "marketplace", Auth: true,
+		Request: h.ShippingQuoteRequest{}, Response: []h.SellerShipping{}},
+	"POST /api/marketplace/checkout": {Summary: "Check out the cart, optionally with a voucher and delivery", Tag: "marketplace", Auth: true,
+		Headers: idempotencyHeader, Request: h.CheckoutRequest{}, Response: models.Order{}, Status: http.StatusCreated},
+	"GET /api/marketplace/vouchers": {Summary: "Public vouchers that can be used now", Tag: "marketplace",
+		Query: pageParams, Response: []models.Voucher{}, Envelope: openapi.Paginated},
+	"POST /api/marketplace/vouchers": {Summary: "Issue a voucher for my listings", Tag: "marketplace", Auth: true,
+		Request: h.CreateVoucherRequest{}, Response: models.Voucher{}, Status: http.StatusCreated},
+	"POST /api/marketplace/wishlist": {Summary: "Add to wishlist", Tag: "marketplace", Auth: true,
+		Request: h.AddWishlistRequest{}},
+	"DELETE /api/marketplace/wishlist/:id": {Summary: "Remove from wishlist", Tag: "marketplace", Auth: true},
+	"GET /api/marketplace/wishlist": {Summary: "My wishlist", Tag: "marketplace", Auth: true,
+		Query: pageParams, Response: []models.Wishlist{}, Envelope: openapi.Paginated},
+	"POST /api/marketplace/reviews": {Summary: "Review a listing bought on a completed order", Tag: "reviews", Auth: true,
+		Request: h.CreateReviewRequest{}, Response: models.Review{}, Status: http.StatusCreated},
+	"PUT /api/marketplace/reviews/:id/reply": {Summary: "Reply to a review of my listing", Tag: "reviews", Auth: true,
+		Request: h.ReviewReplyRequest{}, Response: models.ReviewReply{}},
+	"DELETE /api/marketplace/reviews/:id/reply":   {Summary: "Delete my reply to a review", Tag: "reviews", Auth: true},
+	"POST /api/marketplace/reviews/:id/helpful":   {Summary: "Mark a review helpful", Tag: "reviews", Auth: true},
+	"DELETE /api/marketplace/reviews/:id/helpful": {Summary: "Take back a helpful vote", Tag: "reviews", Auth: true},
+	"POST /api/marketplace/reviews/:id/report": {Summary: "Report a review to the moderators", Tag: "reviews", Auth: true,
+		Request: h.ReportReviewRequest{}, Response: models.ReviewReport{}, Status: http.StatusCreated},
+
+	// Moderation
+	"GET /api/moderation/reports": {Summary: "Review reports queue, oldest first", Tag: "moderation", Auth: true,
+		Query: append([]openapi.Param{
+			{Name: "status", Enum: store.ReportStatuses, Description: "open by default"},
+		}, pageParams...),
+		Response: []models.ReviewReport{}, Envelope: openapi.Paginated},
+	"PUT /api/moderation/reports/:id": {Summary: "Dismiss a report or remove the review", Tag: "moderation", Auth: true,
+		Request: h.ResolveReportRequest{}, Response: models.ReviewReport{}},
+	"GET /api/moderation/categories": {Summary: "Every category, inactive ones included", Tag: "moderation", Auth: true,
+		Query: categoryParams, Response: []models.Category{}},
+	"POST /api/moderation/categories": {Summary: "Add a category", Tag: "moderation", Auth: true,
+		Request: h.CategoryRequest{}, Response: h.IDResponse{}, Status: http.StatusCreated},
+	"PUT /api/moderation/categories/:id": {Summary: "Replace a category's details", Tag: "moderation", Auth: true,
+		Request: h.CategoryRequest{}, Response: models.Category{}},
+	"DELETE /api/moderation/categories/:id": {Summary: "Deactivate a category", Tag: "moderation", Auth: true},
+
+	// Seller
+	"GET /api/seller/analytics": {Summary: "Sales, top listings, conversion, stock alerts and rating for my listings", Tag: "seller", Auth: true,
+		Query: []openapi.Param{
+			{Name: "period", Enum: store.AnalyticsPeriods, Description: "Sales bucket size, day by default"},
+			{Name: "from", Description: "First date (YYYY-MM-DD); 30 days, 12 weeks or 12 months before to by default"},
+			{Name: "to", Description: "Last date (YYYY-MM-DD), today by default"},
+			{Name: "top", Type: "integer", Description: "Number of top listings, 10 by default and at most 50"},
+		},
+		Response: models.SellerAnalytics{}},
+	"GET /api/seller/shop": {Summary: "My shop", Tag: "seller", Auth: true, Response: models.Shop{}},
+	"POST /api/seller/shop": {Summary: "Open my shop", Tag: "seller", Auth: true,
+		Request: h.ShopRequest{}, Response: models.Shop{}, Status: http.StatusCreated},
+	"PUT /api/seller/shop": {Summary: "Replace my shop's details", Tag: "seller", Auth: true,
+		Request: h.ShopRequest{}, Response: models.Shop{}},
+
+	// Shops
+	"GET /api/shops/:slug": {Summary: "Shop page with ratings, response figures and newest listings", Tag: "shops",
+		Response: models.ShopPage{}},
+	"POST /api/shops/:slug/follow":   {Summary: "Follow a shop", Tag: "shops", Auth: true},
+	"DELETE /api/shops/:slug/follow": {Summary: "Unfollow a shop", Tag: "shops", Auth: true},
+
+	// Payments
+	"POST /api/payments/webhooks/:provider": {Summary: "Payment gateway webhook", Tag: "payments",
+		Headers: []openapi.Param{{Name: payments.SignatureHeader, Required: true, Description: "Hex HMAC-SHA256 of the body with the gateway secret"}},
+		Request: payments.Event{}},
+
+	// Media
+	"POST /api/media/uploads": {Summary: "Upload an image", Tag: "media", Auth: true,
+		Query: []openapi.Param{
+			{Name: "kind", Required: true, Enum: h.UploadKinds, Description: "What the image is for"},
+		},
+		Files: []string{"file"}, Response: h.UploadResponse{}, Status: http.StatusCreated},
+	"GET /api/media/*key": {Summary: "Download an upload through a signed link", Tag: "media",
+		Query: []openapi.Param{
+			{Name: "expires", Required: true, Type: "integer", Description: "Unix time the link expires"},
+			{Name: "signature", Required: true},
+		},
+		Envelope: openapi.Raw, ContentType: "image/*"},
+
+	// Consultation
+	"GET /api/consultation/veterinarians": {Summary: "List veterinarians", Tag: "consultation",
+		Query: pageParams, Response: []models.Veterinarian{}, Envelope: openapi.Paginated},
+	"GET /api/consultation/veterinarians/:id": {Summary: "Veterinarian details", Tag: "consultation", Response: models.Veterinarian{}},
+	"POST /api/consultation/veterinarians/register": {Summary: "Register as a veterinarian", Tag: "consultation", Auth: true,
+		Request: h.VeterinarianRegistrationRequest{}, Response: h.IDResponse{}, Status: http.StatusCreated},
+	"POST /api/consultation/consultations": {Summary: "Book a consultation", Tag: "consultation", Auth: true,
+		Headers: idempotencyHeader, Request: h.CreateConsultationRequest{}, Response: h.IDResponse{}, Status: http.StatusCreated},
+	"GET /api/consultation/consultations": {Summary: "My consultations", Tag: "consultation", Auth: true,
+		Query: pageParams, Response: []models.Consultation{}, Envelope: openapi.Paginated},
+	"GET /api/consultation/consultations/:id": {Summary: "Consultation details", Tag: "consultation", Auth: true, Response: models.Consultation{}},
+	"PUT /api/consultation/consultations/:id/status": {Summary: "Update consultation status", Tag: "consultation", Auth: true,
+		Request: h.UpdateConsultationStatusRequest{}},
+
+	// Chat
+	"POST /api/chat/messages": {Summary: "Send a message", Tag: "chat", Auth: true,
+		Request: h.SendMessageRequest{}, Response: h.IDResponse{}, Status: http.StatusCreated},
+	"GET /api/chat/messages": {Summary: "Conversation with a user", Tag: "chat", Auth: true,
+		Query:    append([]openapi.Param{{Name: "partner_id", Type: "integer", Required: true}}, pageParams...),
+		Response: []models.Message{}, Envelope: openapi.Paginated},
+
+	// Config
+	"GET /api/config/splash": {Summary: "Active splash event", Tag: "config", Response: models.SplashEvent{}},
+}
+
+// OpenAPIDocument builds the spec for the routes registered on router.
+func OpenAPIDocument(router *gin.Engine) *openapi.Document {
+	return openapi.Build(router.Routes(), apiDocs, openapi.Info{
+		Title:   "TerraPaw API",
+		Version: "1.0.0",
+	})
+}
@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TerraPaw/backend/openapi"
+)
+
+func TestOpenAPIRoutes(t *testing.T) {
+	s := newTestServer(t)
+
+	code, raw := s.do("GET", "/api/openapi.json", "", nil)
+	if code != http.StatusOK || raw["openapi"] != "3.1.0" {
+		t.Fatalf("openapi.json = %d %v", code, raw["openapi"])
+	}
+
+	var doc openapi.Document
+	encoded, _ := json.Marshal(raw)
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		t.Fatalf("decode spec: %v", err)
+	}
+	for _, r := range s.router.Routes() {
+		key := r.Method + " " + r.Path
+		if _, ok := apiDocs[key]; !ok {
+			t.Errorf("%s has no entry in apiDocs", key)
+		}
+		path := strings.NewReplacer(":id", "{id}", ":variant_id", "{variant_id}", ":provider", "{provider}", ":slug", "{slug}", "*key", "{key}").Replace(r.Path)
+		item := doc.Paths[path]
+		if item == nil || (*item)[strings.ToLower(r.Method)] == nil {
+			t.Errorf("%s missing from spec", key)
+		}
+	}
+	for key := range apiDocs {
+		found := false
+		for _, r := range s.router.Routes() {
+			found = found || r.Method+" "+r.Path == key
+		}
+		if !found {
+			t.Errorf("apiDocs entry %s has no route", key)
+		}
+	}
+
+	op := (*doc.Paths["/api/marketplace/animals"])["post"]
+	if len(op.Security) == 0 || op.Responses["401"] == nil {
+		t.Fatalf("create animal should require bearer auth: %+v", op)
+	}
+	body := doc.Resolve(op.RequestBody.Content["application/json"].Schema)
+	if body == nil || body.Properties["price"].Type != "number" || !contains(body.Required, "animal_type") {
+		t.Fatalf("CreateAnimalRequest schema = %+v", body)
+	}
+	review := doc.Resolve(doc.Components.Schemas["CreateReviewRequest"])
+	if r := review.Properties["rating"]; r.Minimum == nil || *r.Minimum != 1 || *r.Maximum != 5 {
+		t.Fatalf("rating bounds = %+v", r)
+	}
+	if doc.Components.Schemas["Response"] == nil || doc.Components.Schemas["PaginatedResponse"] == nil {
+		t.Fatal("envelope schemas missing from components")
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/docs", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/api/openapi.json") {
+		t.Fatalf("docs page = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOpenAPIValidator(t *testing.T) {
+	t.Setenv("OPENAPI_VALIDATE", "true")
+	s := newTestServer(t)
+	_, token := s.register("seller")
+
+	out := s.expect("POST", "/api/marketplace/animals", token, map[string]interface{}{
+		"animal_type": "Kucing", "name": "Mochi", "price": "murah",
+	}, http.StatusBadRequest)
+	assertDetail(t, out, "price", "must be a number")
+	out = s.expect("POST", "/api/marketplace/reviews", token, map[string]interface{}{
+		"animal_id": 1, "rating": 9,
+	}, http.StatusBadRequest)
+	assertDetail(t, out, "rating", "must be at most 5")
+	s.expect("GET", "/api/marketplace/animals?min_price=abc", "", nil, http.StatusBadRequest)
+	s.expect("GET", "/api/marketplace/animals?sort=random", "", nil, http.StatusBadRequest)
+
+	// Valid requests reach the handler with the body intact.
+	s.expect("POST", "/api/marketplace/animals", token, map[string]interface{}{
+		"animal_type": "Kucing", "name": "Mochi", "price": 1500000,
+	}, http.StatusCreated)
+	s.expect("GET", "/api/marketplace/animals/abc", "", nil, http.StatusBadRequest)
+	// Slugs are strings, so an unknown one reaches the handler.
+	assertCode(t, s.expect("GET", "/api/shops/no-such-shop", "", nil, http.StatusNotFound), "SHOP_NOT_FOUND")
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
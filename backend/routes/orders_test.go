@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOrderLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+	_, otherSeller := s.register("otherseller")
+	_, buyer := s.register("buyer")
+	_, stranger := s.register("stranger")
+
+	kitten := s.catListing(seller, "Mochi", 100000, 2)
+	food := s.catListing(otherSeller, "Makanan Kucing", 100000, 10)
+
+	// Only the parties see an order, and each moves it only as their role
+	// allows. Cancelling tells the seller.
+	orderID := s.order(buyer, kitten, 2)
+	assertCode(t, s.expect("GET", fmt.Sprintf("/api/marketplace/orders/%d", orderID), stranger, nil, http.StatusNotFound), "ORDER_NOT_FOUND")
+	assertCode(t, s.orderStatus(orderID, stranger, "cancelled", http.StatusNotFound), "ORDER_NOT_FOUND")
+	assertCode(t, s.orderStatus(orderID, buyer, "paid", http.StatusForbidden), "FORBIDDEN")
+	assertCode(t, s.orderStatus(orderID, seller, "shipped", http.StatusConflict), "INVALID_ORDER_TRANSITION")
+	assertDetail(t, s.orderStatus(orderID, buyer, "lost", http.StatusBadRequest), "status",
+		"must be one of paid, processing, shipped, delivered, completed, cancelled, refunded")
+
+	order := dataMap(t, s.orderStatus(orderID, buyer, "cancelled", http.StatusOK))
+	history := order["history"].([]interface{})
+	if order["status"] != "cancelled" || len(history) != 1 || history[0].(map[string]interface{})["role"] != "buyer" {
+		t.Fatalf("cancelled order = %v", order)
+	}
+	notices := s.list("/api/profile/notifications", seller)
+	if len(notices) == 0 || notices[0].(map[string]interface{})["type"] != "order" {
+		t.Errorf("seller notifications = %v", notices)
+	}
+	assertCode(t, s.orderStatus(orderID, seller, "paid", http.StatusConflict), "INVALID_ORDER_TRANSITION")
+
+	// The seller takes an order through to shipping, the buyer closes it.
+	orderID = s.order(buyer, kitten, 1)
+	for _, step := range []struct{ token, status string }{
+		{seller, "paid"}, {seller, "processing"}, {seller, "shipped"}, {buyer, "delivered"}, {buyer, "completed"},
+	} {
+		s.orderStatus(orderID, step.token, step.status, http.StatusOK)
+	}
+	order = s.data("GET", fmt.Sprintf("/api/marketplace/orders/%d", orderID), seller, nil, http.StatusOK)
+	if order["status"] != "completed" || len(order["history"].([]interface{})) != 5 || len(order["items"].([]interface{})) != 1 {
+		t.Fatalf("completed order = %v", order)
+	}
+
+	// Each seller moves their own part of a checkout, which the buyer sees
+	// on the checkout; sellers never see the checkout itself.
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": kitten}, http.StatusOK)
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": food, "quantity": 3}, http.StatusOK)
+	checkout := s.data("POST", "/api/marketplace/checkout", buyer, nil, http.StatusCreated)
+	headerID := int(checkout["id"].(float64))
+	subID := func(sub interface{}) int { return int(sub.(map[string]interface{})["id"].(float64)) }
+	kittenSub, foodSub := subID(checkout["sub_orders"].([]interface{})[0]), subID(checkout["sub_orders"].([]interface{})[1])
+	assertCode(t, s.expect("GET", fmt.Sprintf("/api/marketplace/orders/%d", headerID), seller, nil, http.StatusNotFound), "ORDER_NOT_FOUND")
+	assertCode(t, s.orderStatus(kittenSub, otherSeller, "paid", http.StatusNotFound), "ORDER_NOT_FOUND")
+	// An unpaid checkout is cancelled as a whole, not one part at a time.
+	assertCode(t, s.orderStatus(foodSub, buyer, "cancelled", http.StatusConflict), "INVALID_ORDER_TRANSITION")
+	s.orderStatus(kittenSub, seller, "paid", http.StatusOK)
+	order = s.data("GET", fmt.Sprintf("/api/marketplace/orders/%d", headerID), buyer, nil, http.StatusOK)
+	subs := order["sub_orders"].([]interface{})
+	if order["status"] != "pending" || subs[0].(map[string]interface{})["status"] != "paid" {
+		t.Errorf("checkout with one part paid = %v", order)
+	}
+
+	// Sellers see their own orders, newest first, with the buyer.
+	sales := s.list("/api/marketplace/sales", seller)
+	if len(sales) != 3 || int(sales[0].(map[string]interface{})["id"].(float64)) != kittenSub {
+		t.Fatalf("sales = %v", sales)
+	}
+	if b := sales[0].(map[string]interface{})["buyer"].(map[string]interface{}); b["username"] != "buyer" {
+		t.Errorf("sale buyer = %v", b)
+	}
+	if sales := s.list("/api/marketplace/sales?status=completed", seller); len(sales) != 1 {
+		t.Errorf("completed sales = %v", sales)
+	}
+	if sales := s.list("/api/marketplace/sales", otherSeller); len(sales) != 1 {
+		t.Errorf("other seller's sales = %v", sales)
+	}
+	assertDetail(t, s.expect("GET", "/api/marketplace/sales?status=lost", seller, nil, http.StatusBadRequest), "status",
+		"must be one of pending, paid, processing, shipped, delivered, completed, cancelled, refunded")
+	assertCode(t, s.expect("GET", "/api/marketplace/sales", "", nil, http.StatusUnauthorized), "UNAUTHORIZED")
+}
+
+func TestIdempotentOrders(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+	_, buyer := s.register("buyer")
+	_, other := s.register("other")
+
+	animalID := s.listing(seller, map[string]interface{}{
+		"animal_type": "Kucing", "name": "Mochi", "price": 100, "stock": 3,
+	})
+	order := map[string]interface{}{"animal_id": animalID, "quantity": 1}
+	key := map[string]string{"Idempotency-Key": "order-1"}
+
+	first := s.send("POST", "/api/marketplace/orders", buyer, key, order)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first order = %d %s", first.Code, first.Body.String())
+	}
+	retry := s.send("POST", "/api/marketplace/orders", buyer, key, order)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Fatalf("retry = %d %s, want replay of %s", retry.Code, retry.Body.String(), first.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatal("retry missing Idempotent-Replayed header")
+	}
+	if stock := s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d", animalID), "", nil, http.StatusOK)["stock"]; stock != float64(2) {
+		t.Fatalf("stock after retried order = %v, want 2", stock)
+	}
+
+	reused := s.send("POST", "/api/marketplace/orders", buyer, key, map[string]interface{}{"animal_id": animalID, "quantity": 2})
+	if reused.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("reused key = %d %s", reused.Code, reused.Body.String())
+	}
+	assertCode(t, s.decode("POST", "reused", reused), "IDEMPOTENCY_KEY_REUSED")
+
+	// Keys are scoped per user.
+	if w := s.send("POST", "/api/marketplace/orders", other, key, order); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("other user's order = %d %s", w.Code, w.Body.String())
+	}
+
+	// Client errors are recorded and replayed too.
+	tooMany := map[string]interface{}{"animal_id": animalID, "quantity": 10}
+	failKey := map[string]string{"Idempotency-Key": "order-2"}
+	failed := s.send("POST", "/api/marketplace/orders", buyer, failKey, tooMany)
+	if failed.Code != http.StatusBadRequest {
+		t.Fatalf("oversized order = %d", failed.Code)
+	}
+	again := s.send("POST", "/api/marketplace/orders", buyer, failKey, tooMany)
+	if again.Code != http.StatusBadRequest || again.Body.String() != failed.Body.String() || again.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("replayed error = %d %s", again.Code, again.Body.String())
+	}
+
+	long := map[string]string{"Idempotency-Key": strings.Repeat("k", 256)}
+	assertCode(t, s.decode("POST", "long", s.send("POST", "/api/marketplace/orders", buyer, long, order)), "VALIDATION_FAILED")
+
+	// The same key and body sent to another order's payments is a reuse,
+	// not a retry of the first payment.
+	payKey := map[string]string{"Idempotency-Key": "pay-1"}
+	qris := map[string]string{"method": "qris"}
+	firstOrder := idOf(t, s.decode("POST", "order", first))
+	secondOrder := idOf(t, s.expect("POST", "/api/marketplace/orders", buyer, order, http.StatusCreated))
+	if w := s.send("POST", fmt.Sprintf("/api/marketplace/orders/%d/payments", firstOrder), buyer, payKey, qris); w.Code != http.StatusCreated {
+		t.Fatalf("first payment = %d %s", w.Code, w.Body.String())
+	}
+	assertCode(t, s.decode("POST", "payment", s.send("POST", fmt.Sprintf("/api/marketplace/orders/%d/payments", secondOrder), buyer, payKey, qris)),
+		"IDEMPOTENCY_KEY_REUSED")
+}
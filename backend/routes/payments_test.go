@@ -0,0 +1,157 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TerraPaw/backend/jobs"
+	"github.com/TerraPaw/backend/payments"
+)
+
+func TestPayments(t *testing.T) {
+	s := newTestServer(t)
+	api := httptest.NewServer(s.router)
+	defer api.Close()
+	s.gateway.WebhookURL = api.URL + "/api/payments/webhooks/fake"
+	ctx := context.Background()
+
+	_, seller := s.register("seller")
+	_, otherSeller := s.register("otherseller")
+	_, buyer := s.register("buyer")
+	kitten := s.catListing(seller, "Mochi", 150000, 3)
+	food := s.catListing(otherSeller, "Makanan Kucing", 50000, 3)
+
+	orderID := s.order(buyer, kitten, 1)
+	paymentsPath := fmt.Sprintf("/api/marketplace/orders/%d/payments", orderID)
+	assertDetail(t, s.expect("POST", paymentsPath, buyer, map[string]string{"method": "virtual_account"}, http.StatusBadRequest),
+		"bank", "is required for virtual accounts")
+	assertDetail(t, s.expect("POST", paymentsPath, buyer, map[string]string{"method": "cash"}, http.StatusBadRequest),
+		"method", "must be one of virtual_account, qris")
+	assertCode(t, s.expect("POST", paymentsPath, seller, map[string]string{"method": "qris"}, http.StatusNotFound), "ORDER_NOT_FOUND")
+
+	payment := s.data("POST", paymentsPath, buyer, map[string]string{"method": "virtual_account", "bank": "bca"}, http.StatusCreated)
+	if payment["status"] != "pending" || payment["amount"] != 150000.0 || payment["va_number"] == nil || payment["charge_id"] == nil {
+		t.Fatalf("payment = %v", payment)
+	}
+	assertCode(t, s.expect("POST", paymentsPath, buyer, map[string]string{"method": "qris"}, http.StatusConflict), "PAYMENT_PENDING")
+
+	// Webhooks must be signed by the gateway.
+	forged := map[string]interface{}{"id": "evt_forged", "charge_id": payment["charge_id"], "status": "paid", "amount": 150000}
+	assertCode(t, s.decode("POST", "webhook", s.send("POST", "/api/payments/webhooks/fake", "", map[string]string{payments.SignatureHeader: "00"}, forged)),
+		"INVALID_SIGNATURE")
+	assertCode(t, s.decode("POST", "webhook", s.send("POST", "/api/payments/webhooks/midtrans", "", nil, forged)), "ROUTE_NOT_FOUND")
+
+	// A capture for another amount or currency than charged is recorded
+	// but does not pay the order.
+	for _, short := range []*payments.Event{
+		{ID: "evt_partial", ChargeID: payment["charge_id"].(string), Status: payments.StatusPaid, Amount: 100000, Currency: payments.Currency},
+		{ID: "evt_usd", ChargeID: payment["charge_id"].(string), Status: payments.StatusPaid, Amount: 150000, Currency: "USD"},
+	} {
+		if err := s.gateway.Deliver(ctx, short); err != nil {
+			t.Fatalf("%s: %v", short.ID, err)
+		}
+		if order := s.data("GET", fmt.Sprintf("/api/marketplace/orders/%d", orderID), buyer, nil, http.StatusOK); order["status"] != "pending" {
+			t.Fatalf("order after %s = %v", short.ID, order["status"])
+		}
+		if p := s.list(paymentsPath, buyer)[0].(map[string]interface{}); p["status"] != "mismatch" {
+			t.Fatalf("payment after %s = %v", short.ID, p["status"])
+		}
+	}
+
+	// Paying at the gateway marks the order paid, once however often the
+	// event is delivered.
+	event, err := s.gateway.Settle(ctx, payment["charge_id"].(string), payments.StatusPaid)
+	if err != nil {
+		t.Fatalf("settle: %v", err)
+	}
+	if err := s.gateway.Deliver(ctx, event); err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+	order := s.data("GET", fmt.Sprintf("/api/marketplace/orders/%d", orderID), buyer, nil, http.StatusOK)
+	history := order["history"].([]interface{})
+	if order["status"] != "paid" || len(history) != 1 || history[0].(map[string]interface{})["role"] != "system" {
+		t.Fatalf("paid order = %v", order)
+	}
+	list := s.list(paymentsPath, buyer)
+	if len(list) != 1 || list[0].(map[string]interface{})["status"] != "paid" || list[0].(map[string]interface{})["paid_at"] == nil {
+		t.Fatalf("payments = %v", list)
+	}
+	assertCode(t, s.expect("GET", paymentsPath, seller, nil, http.StatusNotFound), "ORDER_NOT_FOUND")
+	assertCode(t, s.expect("POST", paymentsPath, buyer, map[string]string{"method": "qris"}, http.StatusConflict), "ORDER_NOT_PAYABLE")
+
+	// Refunding the order sends the money back through the gateway.
+	s.orderStatus(orderID, seller, "refunded", http.StatusOK)
+	if err := jobs.SendRefunds(ctx); err != nil {
+		t.Fatalf("refunds: %v", err)
+	}
+	if got := s.gateway.Refunded(payment["charge_id"].(string)); got != 150000 {
+		t.Errorf("refunded at gateway = %d", got)
+	}
+	if p := s.list(paymentsPath, buyer)[0].(map[string]interface{}); p["refunded_amount"] != 150000.0 {
+		t.Errorf("refunded payment = %v", p)
+	}
+
+	// A checkout is paid as a whole. Left unpaid, it expires and gets its
+	// stock back; money arriving afterwards is refunded.
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": kitten}, http.StatusOK)
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": food, "quantity": 2}, http.StatusOK)
+	checkout := s.data("POST", "/api/marketplace/checkout", buyer, nil, http.StatusCreated)
+	subID := int(checkout["sub_orders"].([]interface{})[0].(map[string]interface{})["id"].(float64))
+	assertCode(t, s.expect("POST", fmt.Sprintf("/api/marketplace/orders/%d/payments", subID), buyer, map[string]string{"method": "qris"}, http.StatusConflict),
+		"ORDER_NOT_PAYABLE")
+	checkoutPayments := fmt.Sprintf("/api/marketplace/orders/%v/payments", checkout["id"])
+	qr := s.data("POST", checkoutPayments, buyer, map[string]string{"method": "qris"}, http.StatusCreated)
+	if qr["amount"] != 250000.0 || qr["qr_string"] == nil || qr["bank"] != nil {
+		t.Fatalf("qris payment = %v", qr)
+	}
+
+	if err := jobs.ExpireUnpaidOrders(0)(ctx); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	order = s.data("GET", fmt.Sprintf("/api/marketplace/orders/%v", checkout["id"]), buyer, nil, http.StatusOK)
+	if order["status"] != "cancelled" {
+		t.Fatalf("unpaid checkout = %v", order["status"])
+	}
+	if a := s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d", food), "", nil, http.StatusOK); a["stock"] != 3.0 {
+		t.Errorf("food stock after expiry = %v", a["stock"])
+	}
+	if p := s.list(checkoutPayments, buyer)[0].(map[string]interface{}); p["status"] != "expired" {
+		t.Errorf("payment of expired checkout = %v", p["status"])
+	}
+
+	if _, err := s.gateway.Settle(ctx, qr["charge_id"].(string), payments.StatusPaid); err != nil {
+		t.Fatalf("late settle: %v", err)
+	}
+	if err := jobs.SendRefunds(ctx); err != nil {
+		t.Fatalf("refunds: %v", err)
+	}
+	if got := s.gateway.Refunded(qr["charge_id"].(string)); got != 250000 {
+		t.Errorf("late payment refunded = %d", got)
+	}
+	if order := s.data("GET", fmt.Sprintf("/api/marketplace/orders/%v", checkout["id"]), buyer, nil, http.StatusOK); order["status"] != "cancelled" {
+		t.Errorf("checkout after late payment = %v", order["status"])
+	}
+}
+
+// Without a gateway that may take real payments the server still runs, and
+// only paying is unavailable.
+func TestPaymentsDisabled(t *testing.T) {
+	s := newTestServer(t)
+	payments.Default = nil
+	_, seller := s.register("seller")
+	_, buyer := s.register("buyer")
+	kitten := s.listing(seller, map[string]interface{}{
+		"animal_type": "Kucing", "name": "Mochi", "price": 150000,
+	})
+	orderID := s.order(buyer, kitten, 1)
+
+	paymentsPath := fmt.Sprintf("/api/marketplace/orders/%d/payments", orderID)
+	assertCode(t, s.expect("POST", paymentsPath, buyer, map[string]string{"method": "qris"}, http.StatusServiceUnavailable), "PAYMENTS_DISABLED")
+	if list := s.list(paymentsPath, buyer); len(list) != 0 {
+		t.Fatalf("payments = %v", list)
+	}
+	assertCode(t, s.expect("POST", "/api/payments/webhooks/fake", "", map[string]string{}, http.StatusServiceUnavailable), "PAYMENTS_DISABLED")
+}
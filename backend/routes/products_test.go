@@ -0,0 +1,172 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func TestProducts(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+	otherID, other := s.register("other")
+	cats := s.mem.AddCategory(models.Category{Name: "Kucing", Type: "animal"})
+	catFood := s.mem.AddCategory(models.Category{Name: "Makanan Kucing", Type: "food"})
+	dogFood := s.mem.AddCategory(models.Category{Name: "Makanan Anjing", Type: "food"})
+
+	// Listings are filed under the category they give, whatever their
+	// type says.
+	kitten := s.listing(seller, map[string]interface{}{"animal_type": "Kucing", "category_id": cats, "name": "Mochi", "price": 1500000})
+	alien := s.listing(seller, map[string]interface{}{"animal_type": "Kucing", "name": "Zorg", "price": 1})
+	s.expect("PATCH", fmt.Sprintf("/api/marketplace/animals/%d", kitten), seller, map[string]string{"animal_type": "Kucing Anggora"}, http.StatusOK)
+	if a := s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d", kitten), "", nil, http.StatusOK); a["category_id"] != float64(cats) {
+		t.Fatalf("retyped kitten category = %v", a["category_id"])
+	}
+	if a := s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d", alien), "", nil, http.StatusOK); a["category_id"] != nil {
+		t.Fatalf("uncategorised listing category = %v", a["category_id"])
+	}
+	if list := s.list(fmt.Sprintf("/api/marketplace/animals?category_id=%d", cats), ""); len(list) != 1 {
+		t.Fatalf("listings in category = %v", list)
+	}
+
+	product := func(token string, body map[string]interface{}, status int) map[string]interface{} {
+		t.Helper()
+		return s.expect("POST", "/api/marketplace/products", token, body, status)
+	}
+	whiskas := idOf(t, product(seller, map[string]interface{}{
+		"category_id": catFood, "sku": "WHK-TUNA", "name": "Whiskas Tuna", "brand": "Whiskas",
+		"variants": []map[string]interface{}{
+			{"sku": "WHK-TUNA-1KG", "size": "1 kg", "flavour": "Tuna", "price": 55000, "weight_grams": 1000, "stock": 10},
+			{"sku": "WHK-TUNA-480G", "size": "480 g", "flavour": "Tuna", "price": 28000, "weight_grams": 480, "stock": 4},
+		},
+	}, http.StatusCreated))
+	pedigree := idOf(t, product(seller, map[string]interface{}{
+		"category_id": dogFood, "sku": "PDG-CHK", "name": "Pedigree Chicken", "brand": "Pedigree",
+		"variants": []map[string]interface{}{{"sku": "PDG-CHK-3KG", "size": "3 kg", "price": 120000, "weight_grams": 3000, "stock": 2}},
+	}, http.StatusCreated))
+	meo := idOf(t, product(other, map[string]interface{}{
+		"category_id": catFood, "sku": "WHK-TUNA", "name": "Me-O Salmon", "brand": "Me-O",
+		"variants": []map[string]interface{}{{"sku": "MEO-1KG", "price": 45000, "weight_grams": 1000}},
+	}, http.StatusCreated))
+
+	assertCode(t, product(seller, map[string]interface{}{
+		"category_id": catFood, "sku": "WHK-TUNA", "name": "Again",
+		"variants": []map[string]interface{}{{"sku": "X", "price": 1, "weight_grams": 1}},
+	}, http.StatusConflict), "PRODUCT_SKU_TAKEN")
+	assertDetail(t, product(seller, map[string]interface{}{
+		"category_id": cats, "sku": "CAT", "name": "A cat",
+		"variants": []map[string]interface{}{{"sku": "X", "price": 1, "weight_grams": 1}},
+	}, http.StatusBadRequest), "category_id", "is for live animals; list them with POST /api/marketplace/animals")
+	assertDetail(t, product(seller, map[string]interface{}{
+		"category_id": 999, "sku": "NONE", "name": "Nothing",
+		"variants": []map[string]interface{}{{"sku": "X", "price": 1, "weight_grams": 1}},
+	}, http.StatusBadRequest), "category_id", "does not exist")
+	assertDetail(t, product(seller, map[string]interface{}{
+		"category_id": catFood, "sku": "DUP", "name": "Twins",
+		"variants": []map[string]interface{}{{"sku": "X", "price": 1, "weight_grams": 1}, {"sku": "X", "price": 2, "weight_grams": 2}},
+	}, http.StatusBadRequest), "variants[1].sku", "is used by another variant")
+	assertCode(t, product(seller, map[string]interface{}{"category_id": catFood, "sku": "EMPTY", "name": "Empty"}, http.StatusBadRequest), "VALIDATION_FAILED")
+	product("", map[string]interface{}{"category_id": catFood}, http.StatusUnauthorized)
+
+	// Variants come cheapest first, summed up on the product.
+	details := s.data("GET", fmt.Sprintf("/api/marketplace/products/%d", whiskas), "", nil, http.StatusOK)
+	variants := details["variants"].([]interface{})
+	if details["min_price"] != 28000.0 || details["stock"] != 14.0 || details["brand"] != "Whiskas" || len(variants) != 2 ||
+		variants[0].(map[string]interface{})["sku"] != "WHK-TUNA-480G" || details["status"] != "active" {
+		t.Fatalf("product = %v", details)
+	}
+	assertCode(t, s.expect("GET", "/api/marketplace/products/999", "", nil, http.StatusNotFound), "PRODUCT_NOT_FOUND")
+
+	ids := func(path string) []int {
+		t.Helper()
+		var got []int
+		for _, p := range s.list(path, "") {
+			got = append(got, int(p.(map[string]interface{})["id"].(float64)))
+		}
+		return got
+	}
+	expectIDs := func(label, path string, want ...int) {
+		t.Helper()
+		if got := ids(path); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("%s = %v, want %v", label, got, want)
+		}
+	}
+	expectIDs("newest", "/api/marketplace/products", meo, pedigree, whiskas)
+	expectIDs("cheapest", "/api/marketplace/products?sort=price_asc", whiskas, meo, pedigree)
+	expectIDs("category", fmt.Sprintf("/api/marketplace/products?category_id=%d", catFood), meo, whiskas)
+	expectIDs("seller", fmt.Sprintf("/api/marketplace/products?seller_id=%d", otherID), meo)
+	expectIDs("search", "/api/marketplace/products?search=tuna", whiskas)
+	expectIDs("brand", "/api/marketplace/products?brand=me-o", meo)
+	expectIDs("in stock", "/api/marketplace/products?in_stock=true", pedigree, whiskas)
+	first := s.expect("GET", "/api/marketplace/products?sort=price_desc&limit=2", "", nil, http.StatusOK)
+	expectIDs("next page", "/api/marketplace/products?sort=price_desc&limit=2&cursor="+first["next_cursor"].(string), whiskas)
+	assertDetail(t, s.expect("GET", "/api/marketplace/products?sort=rating", "", nil, http.StatusBadRequest),
+		"sort", "must be one of newest, oldest, price_asc, price_desc")
+
+	// Supplies are not animal listings.
+	for _, a := range s.list("/api/marketplace/animals", "") {
+		if a.(map[string]interface{})["name"] == "Whiskas Tuna" {
+			t.Fatalf("product listed as an animal")
+		}
+	}
+
+	// Sellers keep each variant's price and stock.
+	pedigreeVariant := int(s.data("GET", fmt.Sprintf("/api/marketplace/products/%d", pedigree), "", nil, http.StatusOK)["variants"].([]interface{})[0].(map[string]interface{})["id"].(float64))
+	variantPath := fmt.Sprintf("/api/marketplace/products/%d/variants/%d", pedigree, pedigreeVariant)
+	updated := s.data("PATCH", variantPath, seller, map[string]interface{}{"stock": 0, "price": 110000}, http.StatusOK)
+	if updated["stock"] != 0.0 || updated["min_price"] != 110000.0 {
+		t.Fatalf("updated product = %v", updated)
+	}
+	expectIDs("sold out", "/api/marketplace/products?in_stock=true", whiskas)
+	s.expect("PATCH", variantPath, other, map[string]int{"stock": 5}, http.StatusForbidden)
+	assertCode(t, s.expect("PATCH", fmt.Sprintf("/api/marketplace/products/%d/variants/%d", whiskas, pedigreeVariant), seller, map[string]int{"stock": 5}, http.StatusNotFound), "VARIANT_NOT_FOUND")
+	assertCode(t, s.expect("PATCH", fmt.Sprintf("/api/marketplace/products/999/variants/%d", pedigreeVariant), seller, map[string]int{"stock": 5}, http.StatusNotFound), "PRODUCT_NOT_FOUND")
+	assertCode(t, s.expect("PATCH", variantPath, seller, map[string]int{"stock": -1}, http.StatusBadRequest), "VALIDATION_FAILED")
+
+	// Variants go through the cart and checkout next to listings.
+	_, buyer := s.register("buyer")
+	variantID := func(v interface{}) int { return int(v.(map[string]interface{})["id"].(float64)) }
+	tunaSmall, tunaKilo := variantID(variants[0]), variantID(variants[1])
+	addItem := func(body map[string]int, status int) map[string]interface{} {
+		t.Helper()
+		return s.expect("POST", "/api/marketplace/cart/items", buyer, body, status)
+	}
+	assertDetail(t, addItem(map[string]int{"quantity": 1}, http.StatusBadRequest), "animal_id", "is required unless variant_id is given")
+	assertDetail(t, addItem(map[string]int{"animal_id": kitten, "variant_id": tunaKilo}, http.StatusBadRequest), "variant_id", "cannot be given with animal_id")
+	assertCode(t, addItem(map[string]int{"variant_id": 999}, http.StatusNotFound), "VARIANT_NOT_FOUND")
+	assertCode(t, addItem(map[string]int{"variant_id": pedigreeVariant}, http.StatusBadRequest), "INSUFFICIENT_STOCK")
+	addItem(map[string]int{"animal_id": kitten}, http.StatusOK)
+	addItem(map[string]int{"variant_id": tunaKilo, "quantity": 2}, http.StatusOK)
+	addItem(map[string]int{"variant_id": tunaSmall}, http.StatusOK)
+	s.expect("DELETE", fmt.Sprintf("/api/marketplace/cart/variants/%d", tunaSmall), buyer, nil, http.StatusOK)
+	assertCode(t, s.expect("PUT", fmt.Sprintf("/api/marketplace/cart/variants/%d", tunaSmall), buyer, map[string]int{"quantity": 1}, http.StatusNotFound),
+		"CART_ITEM_NOT_FOUND")
+	kiloItem := fmt.Sprintf("/api/marketplace/cart/variants/%d", tunaKilo)
+	assertCode(t, s.expect("PUT", kiloItem, buyer, map[string]int{"quantity": 11}, http.StatusBadRequest), "INSUFFICIENT_STOCK")
+	cart := s.data("PUT", kiloItem, buyer, map[string]int{"quantity": 3}, http.StatusOK)
+	items := cart["items"].([]interface{})
+	line := items[1].(map[string]interface{})
+	if len(items) != 2 || cart["subtotal"] != 1665000.0 || cart["can_checkout"] != true ||
+		line["variant_id"] != float64(tunaKilo) || line["variant"].(map[string]interface{})["sku"] != "WHK-TUNA-1KG" {
+		t.Fatalf("cart = %v", cart)
+	}
+
+	order := s.data("POST", "/api/marketplace/checkout", buyer, nil, http.StatusCreated)
+	if order["total_price"] != 1665000.0 || len(order["items"].([]interface{})) != 2 || len(order["sub_orders"].([]interface{})) != 1 {
+		t.Fatalf("order = %v", order)
+	}
+
+	// Buying a variant outright works the same way.
+	assertDetail(t, s.expect("POST", "/api/marketplace/orders", buyer, map[string]int{"quantity": 1}, http.StatusBadRequest),
+		"animal_id", "is required unless variant_id is given")
+	assertCode(t, s.expect("POST", "/api/marketplace/orders", buyer, map[string]int{"variant_id": tunaSmall, "quantity": 5}, http.StatusBadRequest),
+		"INSUFFICIENT_STOCK")
+	single := s.expect("POST", "/api/marketplace/orders", buyer, map[string]int{"variant_id": tunaSmall, "quantity": 2}, http.StatusCreated)
+	bought := s.data("GET", fmt.Sprintf("/api/marketplace/orders/%d", idOf(t, single)), buyer, nil, http.StatusOK)
+	boughtLine := bought["items"].([]interface{})[0].(map[string]interface{})
+	if bought["total_price"] != 56000.0 || boughtLine["variant_id"] != float64(tunaSmall) {
+		t.Fatalf("variant order = %v", bought)
+	}
+}
@@ -0,0 +1,140 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestReviewInteractions(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+	_, rival := s.register("rival")
+	modID, mod := s.register("moderator")
+	s.mem.SetModerator(modID)
+
+	kitten := s.listing(seller, map[string]interface{}{
+		"animal_type": "Kucing", "name": "Mochi", "price": 1500000, "stock": 3,
+	})
+	reviewsPath := fmt.Sprintf("/api/marketplace/animals/%d/reviews", kitten)
+
+	// Three buyers review it: 5 stars with a photo, then 2 and 4 stars.
+	var ids []int
+	var buyers []string
+	for i, r := range []struct {
+		rating int
+		photo  string
+	}{{5, "https://example.com/mochi.jpg"}, {2, ""}, {4, ""}} {
+		_, buyer := s.register(fmt.Sprintf("buyer%d", i))
+		order := s.order(buyer, kitten, 1)
+		s.complete(order, seller, buyer)
+		ids = append(ids, idOf(t, s.expect("POST", "/api/marketplace/reviews", buyer, map[string]interface{}{
+			"animal_id": kitten, "order_id": order, "rating": r.rating, "image_url": r.photo,
+		}, http.StatusCreated)))
+		buyers = append(buyers, buyer)
+	}
+	reviewPath := func(i int, action string) string {
+		return fmt.Sprintf("/api/marketplace/reviews/%d/%s", ids[i], action)
+	}
+
+	// Helpful votes: repeats count once, nobody votes for their own review.
+	s.expect("POST", reviewPath(2, "helpful"), buyers[0], nil, http.StatusOK)
+	s.expect("POST", reviewPath(2, "helpful"), buyers[1], nil, http.StatusOK)
+	s.expect("POST", reviewPath(2, "helpful"), buyers[1], nil, http.StatusOK)
+	s.expect("POST", reviewPath(1, "helpful"), rival, nil, http.StatusOK)
+	s.expect("POST", reviewPath(0, "helpful"), rival, nil, http.StatusOK)
+	s.expect("DELETE", reviewPath(0, "helpful"), rival, nil, http.StatusOK)
+	s.expect("DELETE", reviewPath(0, "helpful"), rival, nil, http.StatusOK)
+	s.expect("POST", reviewPath(2, "helpful"), buyers[2], nil, http.StatusForbidden)
+	assertCode(t, s.expect("POST", "/api/marketplace/reviews/999/helpful", rival, nil, http.StatusNotFound), "REVIEW_NOT_FOUND")
+
+	order := func(query string) []int {
+		var got []int
+		for _, r := range s.list(reviewsPath+query, "") {
+			got = append(got, int(r.(map[string]interface{})["id"].(float64)))
+		}
+		return got
+	}
+	for query, want := range map[string][]int{
+		"":                  {ids[2], ids[1], ids[0]},
+		"?sort=helpful":     {ids[2], ids[1], ids[0]},
+		"?sort=rating_desc": {ids[0], ids[2], ids[1]},
+		"?sort=rating_asc":  {ids[1], ids[2], ids[0]},
+		"?with_photos=true": {ids[0]},
+	} {
+		if got := order(query); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("reviews%s = %v, want %v", query, got, want)
+		}
+	}
+	first := s.expect("GET", reviewsPath+"?sort=helpful&limit=1", "", nil, http.StatusOK)
+	if got := order("?sort=helpful&limit=1&cursor=" + first["next_cursor"].(string)); len(got) != 1 || got[0] != ids[1] {
+		t.Fatalf("second helpful page = %v", got)
+	}
+	assertDetail(t, s.expect("GET", reviewsPath+"?sort=random", "", nil, http.StatusBadRequest), "sort", "must be one of newest, helpful, rating_desc, rating_asc")
+
+	// Only the listing's seller replies; replying again replaces the reply.
+	s.expect("PUT", reviewPath(1, "reply"), rival, map[string]string{"body": "Beli di toko kami saja"}, http.StatusForbidden)
+	s.expect("PUT", reviewPath(1, "reply"), seller, map[string]string{"body": "  "}, http.StatusBadRequest)
+	s.expect("PUT", reviewPath(1, "reply"), seller, map[string]string{"body": "Maaf, kak"}, http.StatusOK)
+	reply := s.data("PUT", reviewPath(1, "reply"), seller, map[string]string{"body": "Maaf, kami kirim ganti"}, http.StatusOK)
+	shown := s.list(reviewsPath+"?sort=rating_asc", "")[0].(map[string]interface{})
+	if got := shown["reply"].(map[string]interface{}); got["body"] != "Maaf, kami kirim ganti" || got["created_at"] == reply["updated_at"] && reply["created_at"] != reply["updated_at"] {
+		t.Fatalf("reply = %v", got)
+	}
+	if shown["helpful_count"] != 1.0 {
+		t.Fatalf("helpful count = %v", shown["helpful_count"])
+	}
+	s.expect("DELETE", reviewPath(1, "reply"), seller, nil, http.StatusOK)
+	if shown := s.list(reviewsPath+"?sort=rating_asc", "")[0].(map[string]interface{}); shown["reply"] != nil {
+		t.Fatalf("reply after delete = %v", shown["reply"])
+	}
+
+	// Reports wait in the moderation queue, one per user and review.
+	s.expect("POST", reviewPath(1, "report"), seller, map[string]string{"reason": "rude"}, http.StatusBadRequest)
+	s.expect("POST", reviewPath(1, "report"), seller, map[string]string{"reason": "fake", "details": "Never bought"}, http.StatusCreated)
+	assertCode(t, s.expect("POST", reviewPath(1, "report"), seller, map[string]string{"reason": "spam"}, http.StatusConflict), "REVIEW_ALREADY_REPORTED")
+	s.expect("POST", reviewPath(1, "report"), rival, map[string]string{"reason": "abusive"}, http.StatusCreated)
+	s.expect("POST", reviewPath(0, "report"), rival, map[string]string{"reason": "offensive_image"}, http.StatusCreated)
+
+	s.expect("GET", "/api/moderation/reports", seller, nil, http.StatusForbidden)
+	s.expect("GET", "/api/moderation/reports", "", nil, http.StatusUnauthorized)
+	queue := s.list("/api/moderation/reports", mod)
+	if len(queue) != 3 || queue[0].(map[string]interface{})["reason"] != "fake" || queue[0].(map[string]interface{})["review"].(map[string]interface{})["rating"] != 2.0 {
+		t.Fatalf("queue = %v", queue)
+	}
+	reportPath := func(i int) string {
+		return fmt.Sprintf("/api/moderation/reports/%v", queue[i].(map[string]interface{})["id"])
+	}
+
+	// Dismissing keeps the review; removing hides it, settles its other
+	// reports and drops it from the ratings.
+	s.expect("PUT", reportPath(2), mod, map[string]string{"status": "open"}, http.StatusBadRequest)
+	if dismissed := s.data("PUT", reportPath(2), mod, map[string]string{"status": "dismissed"}, http.StatusOK); dismissed["resolved_by"] != float64(modID) {
+		t.Fatalf("dismissed = %v", dismissed)
+	}
+	removed := s.data("PUT", reportPath(0), mod, map[string]string{"status": "removed"}, http.StatusOK)
+	if removed["status"] != "removed" || removed["review"].(map[string]interface{})["removed_at"] == nil {
+		t.Fatalf("removed = %v", removed)
+	}
+	assertCode(t, s.expect("PUT", reportPath(1), mod, map[string]string{"status": "dismissed"}, http.StatusConflict), "REPORT_ALREADY_RESOLVED")
+	s.expect("PUT", "/api/moderation/reports/999", mod, map[string]string{"status": "dismissed"}, http.StatusNotFound)
+	if open := s.list("/api/moderation/reports", mod); len(open) != 0 {
+		t.Fatalf("open reports = %v", open)
+	}
+	if settled := s.list("/api/moderation/reports?status=removed", mod); len(settled) != 2 {
+		t.Fatalf("removed reports = %v", settled)
+	}
+
+	out := s.expect("GET", reviewsPath, "", nil, http.StatusOK)
+	if reviews := dataList(t, out); len(reviews) != 2 {
+		t.Fatalf("reviews after removal = %v", reviews)
+	}
+	if summary := out["summary"].(map[string]interface{}); summary["count"] != 2.0 || summary["average"] != 4.5 {
+		t.Fatalf("summary after removal = %v", summary)
+	}
+	if animal := s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d", kitten), "", nil, http.StatusOK); animal["rating"] != 4.5 || animal["review_count"] != 2.0 {
+		t.Fatalf("animal after removal = %v", animal)
+	}
+	s.expect("POST", reviewPath(1, "helpful"), rival, nil, http.StatusNotFound)
+	s.expect("PUT", reviewPath(1, "reply"), seller, map[string]string{"body": "Halo"}, http.StatusNotFound)
+}
@@ -1,12 +1,24 @@
 package routes
 
 import (
+	"github.com/TerraPaw/backend/apperr"
+	"github.com/TerraPaw/backend/config"
 	h "github.com/TerraPaw/backend/handlers"
 	"github.com/TerraPaw/backend/middleware"
+	"github.com/TerraPaw/backend/openapi"
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRoutes(router *gin.Engine) {
+	// Error rendering must wrap every route, so it is installed first.
+	router.Use(apperr.Middleware())
+	router.NoRoute(apperr.NoRoute)
+
+	spec := openapi.Lazy(func() *openapi.Document { return OpenAPIDocument(router) })
+	if config.LoadConfig().ValidateRequests {
+		router.Use(openapi.Validator(spec))
+	}
+
 	// Auth routes (public)
 	auth := router.Group("/api/auth")
 	{
@@ -25,7 +37,18 @@ func SetupRoutes(router *gin.Engine) {
 		profile.POST("/pets", h.CreateUserPet)
 		profile.GET("/medical-records", h.GetMedicalRecords)
 		profile.GET("/notifications", h.GetNotifications)
+		profile.GET("/alert-preferences", h.GetAlertPreferences)
+		profile.PUT("/alert-preferences", h.UpdateAlertPreferences)
+		profile.PUT("/location", h.UpdateLocation)
 		profile.GET("/stats", h.GetUserStats) // New endpoint for profile stats
+		profile.POST("/vouchers", h.ClaimVoucher)
+		profile.GET("/vouchers", h.GetMyVouchers)
+		profile.GET("/addresses", h.GetAddresses)
+		profile.POST("/addresses", h.CreateAddress)
+		profile.PUT("/addresses/:id", h.UpdateAddress)
+		profile.DELETE("/addresses/:id", h.DeleteAddress)
+		profile.GET("/following", h.GetFollowedShops)
+		profile.GET("/feed", h.GetShopFeed)
 	}
 
 	// Community routes
@@ -51,15 +74,52 @@ func SetupRoutes(router *gin.Engine) {
 		marketplace.GET("/animals", h.GetAnimals)
 		marketplace.GET("/animals/:id", h.GetAnimal)
 		marketplace.GET("/animals/:id/reviews", h.GetReviews)
+		marketplace.GET("/animals/:id/price-history", h.GetPriceHistory)
+		marketplace.GET("/animals/:id/shipping", h.GetAnimalShipping)
+		marketplace.GET("/animals/:id/similar", h.GetSimilarAnimals)
 		marketplace.GET("/categories", h.GetCategories)
+		marketplace.GET("/products", h.GetProducts)
+		marketplace.GET("/products/:id", h.GetProduct)
+		marketplace.GET("/search/suggest", h.SuggestSearch)
+		marketplace.GET("/vouchers", h.GetVouchers)
 	}
 
 	marketplaceProtected := router.Group("/api/marketplace")
 	marketplaceProtected.Use(middleware.AuthMiddleware())
 	{
 		marketplaceProtected.POST("/animals", h.CreateAnimal)
-		marketplaceProtected.POST("/orders", h.CreateOrder)
+		marketplaceProtected.PUT("/animals/:id", h.ReplaceAnimal)
+		marketplaceProtected.PATCH("/animals/:id", h.PatchAnimal)
+		marketplaceProtected.DELETE("/animals/:id", h.DeleteAnimal)
+		marketplaceProtected.POST("/animals/:id/restock", h.RestockAnimal)
+		marketplaceProtected.POST("/animals/:id/media", h.AddAnimalMedia)
+		marketplaceProtected.GET("/my-listings", h.GetMyListings)
+		marketplaceProtected.POST("/products", h.CreateProduct)
+		marketplaceProtected.PATCH("/products/:id/variants/:variant_id", h.UpdateProductVariant)
+		marketplaceProtected.GET("/recommendations", h.GetRecommendations)
+		marketplaceProtected.POST("/orders", middleware.Idempotency(), h.CreateOrder)
 		marketplaceProtected.GET("/orders", h.GetOrders)
+		marketplaceProtected.GET("/orders/:id", h.GetOrder)
+		marketplaceProtected.PUT("/orders/:id/status", h.UpdateOrderStatus)
+		marketplaceProtected.POST("/orders/:id/payments", middleware.Idempotency(), h.CreatePayment)
+		marketplaceProtected.GET("/orders/:id/payments", h.GetPayments)
+		marketplaceProtected.GET("/orders/:id/shipment", h.GetShipment)
+		marketplaceProtected.POST("/orders/:id/shipment", h.ShipOrder)
+		marketplaceProtected.GET("/sales", h.GetSales)
+
+		// Cart
+		marketplaceProtected.GET("/cart", h.GetCart)
+		marketplaceProtected.POST("/cart/items", h.AddCartItem)
+		marketplaceProtected.PUT("/cart/items/:id", h.UpdateCartItem)
+		marketplaceProtected.DELETE("/cart/items/:id", h.RemoveCartItem)
+		marketplaceProtected.PUT("/cart/variants/:id", h.UpdateCartVariant)
+		marketplaceProtected.DELETE("/cart/variants/:id", h.RemoveCartVariant)
+		marketplaceProtected.POST("/cart/voucher", h.QuoteVoucher)
+		marketplaceProtected.POST("/cart/shipping", h.QuoteCartShipping)
+		marketplaceProtected.POST("/checkout", middleware.Idempotency(), h.Checkout)
+
+		// Vouchers
+		marketplaceProtected.POST("/vouchers", h.CreateVoucher)
 
 		// Wishlist
 		marketplaceProtected.POST("/wishlist", h.AddToWishlist)
@@ -68,6 +128,51 @@ func SetupRoutes(router *gin.Engine) {
 
 		// Reviews
 		marketplaceProtected.POST("/reviews", h.CreateReview)
+		marketplaceProtected.PUT("/reviews/:id/reply", h.ReplyToReview)
+		marketplaceProtected.DELETE("/reviews/:id/reply", h.DeleteReviewReply)
+		marketplaceProtected.POST("/reviews/:id/helpful", h.VoteReviewHelpful)
+		marketplaceProtected.DELETE("/reviews/:id/helpful", h.UnvoteReviewHelpful)
+		marketplaceProtected.POST("/reviews/:id/report", h.ReportReview)
+	}
+
+	// Moderation queue and category management
+	moderation := router.Group("/api/moderation")
+	moderation.Use(middleware.AuthMiddleware(), middleware.ModeratorMiddleware())
+	{
+		moderation.GET("/reports", h.GetReviewReports)
+		moderation.PUT("/reports/:id", h.ResolveReviewReport)
+		moderation.GET("/categories", h.GetAllCategories)
+		moderation.POST("/categories", h.CreateCategory)
+		moderation.PUT("/categories/:id", h.UpdateCategory)
+		moderation.DELETE("/categories/:id", h.DeleteCategory)
+	}
+
+	// Seller dashboard
+	seller := router.Group("/api/seller")
+	seller.Use(middleware.AuthMiddleware())
+	{
+		seller.GET("/analytics", h.GetSellerAnalytics)
+		seller.GET("/shop", h.GetMyShop)
+		seller.POST("/shop", h.CreateShop)
+		seller.PUT("/shop", h.UpdateShop)
+	}
+
+	// Shop pages (public); following needs a token
+	shops := router.Group("/api/shops")
+	{
+		shops.GET("/:slug", h.GetShop)
+		shops.POST("/:slug/follow", middleware.AuthMiddleware(), h.FollowShop)
+		shops.DELETE("/:slug/follow", middleware.AuthMiddleware(), h.UnfollowShop)
+	}
+
+	// Payment gateway webhooks (public, verified by signature)
+	router.POST("/api/payments/webhooks/:provider", h.PaymentWebhook)
+
+	// Media routes: uploads need a token, files are served to signed links
+	media := router.Group("/api/media")
+	{
+		media.POST("/uploads", middleware.AuthMiddleware(), h.UploadMedia)
+		media.GET("/*key", h.ServeMedia)
 	}
 
 	// Consultation routes
@@ -81,7 +186,7 @@ func SetupRoutes(router *gin.Engine) {
 	consultationProtected.Use(middleware.AuthMiddleware())
 	{
 		consultationProtected.POST("/veterinarians/register", h.RegisterVeterinarian)
-		consultationProtected.POST("/consultations", h.CreateConsultation)
+		consultationProtected.POST("/consultations", middleware.Idempotency(), h.CreateConsultation)
 		consultationProtected.GET("/consultations", h.GetConsultations)
 		consultationProtected.GET("/consultations/:id", h.GetConsultation)
 		consultationProtected.PUT("/consultations/:id/status", h.UpdateConsultationStatus)
@@ -108,4 +213,8 @@ func SetupRoutes(router *gin.Engine) {
 		config.GET("/splash", h.GetCurrentSplash)
 		// config.POST("/splash", h.CreateSplashEvent) // Admin only, uncomment if needed
 	}
+
+	// API documentation (Public)
+	router.GET("/api/openapi.json", openapi.ServeSpec(spec))
+	router.GET("/api/docs", openapi.ServeUI("/api/openapi.json"))
 }
@@ -0,0 +1,445 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TerraPaw/backend/geo"
+	"github.com/TerraPaw/backend/models"
+	"github.com/TerraPaw/backend/notify"
+	"github.com/TerraPaw/backend/payments"
+	"github.com/TerraPaw/backend/shipping"
+	"github.com/TerraPaw/backend/storage"
+	"github.com/TerraPaw/backend/store"
+	"github.com/gin-gonic/gin"
+)
+
+// covered records every "METHOD /route/:pattern" hit by the suite so that
+// TestMain can fail when a route registered in SetupRoutes has no test.
+var covered sync.Map
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	code := m.Run()
+
+	// Only enforce coverage when the whole suite ran.
+	if code == 0 && flag.Lookup("test.run").Value.String() == "" {
+		var missing []string
+		for _, r := range newEngine().Routes() {
+			key := r.Method + " " + r.Path
+			if _, ok := covered.Load(key); !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			fmt.Fprintf(os.Stderr, "routes without contract tests:\n  %s\n", strings.Join(missing, "\n  "))
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+func newEngine() *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Next()
+		if c.FullPath() != "" {
+			covered.Store(c.Request.Method+" "+c.FullPath(), true)
+		}
+	})
+	SetupRoutes(r)
+	return r
+}
+
+type testServer struct {
+	t       *testing.T
+	router  *gin.Engine
+	mem     *store.MemoryStore
+	gateway *payments.FakeGateway
+	carrier *shipping.Fake
+	sender  *notify.Recorder
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	mem := store.NewMemory()
+	store.Default = mem
+	blob, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("storage: %v", err)
+	}
+	storage.Default = blob
+	storage.DefaultSigner = &storage.Signer{Secret: []byte("test-media-secret"), TTL: time.Hour}
+
+	// Webhooks only reach the router in tests that point WebhookURL at it.
+	secret := []byte("test-payment-secret")
+	gateway := payments.NewFakeGateway(secret, "")
+	gatewayServer := httptest.NewServer(gateway)
+	t.Cleanup(gatewayServer.Close)
+	payments.Default = &payments.Fake{BaseURL: gatewayServer.URL, Secret: secret}
+	carrier := shipping.NewFake()
+	shipping.Default = carrier
+	sender := &notify.Recorder{}
+	notify.Default = sender
+	geo.Default = geo.NewOffline()
+	return &testServer{t: t, router: newEngine(), mem: mem, gateway: gateway, carrier: carrier, sender: sender}
+}
+
+// do performs a request and decodes the JSON body into a map.
+func (s *testServer) do(method, path, token string, body interface{}) (int, map[string]interface{}) {
+	s.t.Helper()
+	w := s.send(method, path, token, nil, body)
+	return w.Code, s.decode(method, path, w)
+}
+
+// send performs a request with extra headers and returns the raw recorder.
+func (s *testServer) send(method, path, token string, headers map[string]string, body interface{}) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("marshal body: %v", err)
+		}
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// upload posts data as the "file" field of a multipart form.
+func (s *testServer) upload(path, token, filename string, data []byte, status int) map[string]interface{} {
+	s.t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", filename)
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	out := s.decode("POST", path, w)
+	if w.Code != status {
+		s.t.Fatalf("POST %s: status = %d, want %d (body %v)", path, w.Code, status, out)
+	}
+	assertEnvelope(s.t, "POST "+path, out, status < 400)
+	return out
+}
+
+func (s *testServer) decode(method, path string, w *httptest.ResponseRecorder) map[string]interface{} {
+	s.t.Helper()
+	var out map[string]interface{}
+	if w.Body.Len() > 0 {
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			s.t.Fatalf("%s %s: response is not JSON: %v\n%s", method, path, err, w.Body.String())
+		}
+	}
+	return out
+}
+
+// expect performs a request and asserts its status code and that the body
+// follows the utils.Response envelope.
+func (s *testServer) expect(method, path, token string, body interface{}, status int) map[string]interface{} {
+	s.t.Helper()
+	code, out := s.do(method, path, token, body)
+	if code != status {
+		s.t.Fatalf("%s %s: status = %d, want %d (body %v)", method, path, code, status, out)
+	}
+	assertEnvelope(s.t, method+" "+path, out, status < 400)
+	return out
+}
+
+func assertEnvelope(t *testing.T, label string, body map[string]interface{}, success bool) {
+	t.Helper()
+	got, ok := body["success"].(bool)
+	if !ok {
+		t.Fatalf("%s: missing boolean success field in %v", label, body)
+	}
+	if got != success {
+		t.Fatalf("%s: success = %v, want %v (body %v)", label, got, success, body)
+	}
+	if _, ok := body["message"].(string); !ok {
+		t.Fatalf("%s: missing message field in %v", label, body)
+	}
+	if code, _ := body["code"].(string); !success && code == "" {
+		t.Fatalf("%s: error response without code: %v", label, body)
+	}
+}
+
+// register creates a user through the API and returns its id and token.
+func (s *testServer) register(username string) (int, string) {
+	s.t.Helper()
+	out := s.expect("POST", "/api/auth/register", "", map[string]string{
+		"username": username,
+		"email":    username + "@example.com",
+		"password": "secret",
+		"fullname": strings.ToUpper(username[:1]) + username[1:],
+	}, http.StatusCreated)
+	data := out["data"].(map[string]interface{})
+	return int(data["user_id"].(float64)), data["token"].(string)
+}
+
+// complete takes an order from pending to completed: the seller confirms
+// and ships it, the buyer accepts it.
+func (s *testServer) complete(orderID int, seller, buyer string) {
+	s.t.Helper()
+	for _, step := range []struct{ token, status string }{
+		{seller, "paid"}, {seller, "processing"}, {seller, "shipped"}, {buyer, "delivered"}, {buyer, "completed"},
+	} {
+		s.orderStatus(orderID, step.token, step.status, http.StatusOK)
+	}
+}
+
+func dataMap(t *testing.T, body map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	data, ok := body["data"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("data is not an object: %v", body)
+	}
+	return data
+}
+
+func dataList(t *testing.T, body map[string]interface{}) []interface{} {
+	t.Helper()
+	if body["data"] == nil {
+		return nil
+	}
+	data, ok := body["data"].([]interface{})
+	if !ok {
+		t.Fatalf("data is not a list: %v", body)
+	}
+	return data
+}
+
+func idOf(t *testing.T, body map[string]interface{}) int {
+	t.Helper()
+	return int(dataMap(t, body)["id"].(float64))
+}
+
+// data performs a request like expect and returns its data object.
+func (s *testServer) data(method, path, token string, body interface{}, status int) map[string]interface{} {
+	s.t.Helper()
+	return dataMap(s.t, s.expect(method, path, token, body, status))
+}
+
+// list fetches path as token and returns its data list.
+func (s *testServer) list(path, token string) []interface{} {
+	s.t.Helper()
+	return dataList(s.t, s.expect("GET", path, token, nil, http.StatusOK))
+}
+
+// listing creates a listing as token and returns its id.
+func (s *testServer) listing(token string, body map[string]interface{}) int {
+	s.t.Helper()
+	return idOf(s.t, s.expect("POST", "/api/marketplace/animals", token, body, http.StatusCreated))
+}
+
+// catListing creates a Kucing listing as token and returns its id.
+func (s *testServer) catListing(token, name string, price float64, stock int) int {
+	s.t.Helper()
+	return s.listing(token, map[string]interface{}{"animal_type": "Kucing", "name": name, "price": price, "stock": stock})
+}
+
+// order buys quantity of a listing outright as token and returns the
+// order id.
+func (s *testServer) order(token string, animalID, quantity int) int {
+	s.t.Helper()
+	return idOf(s.t, s.expect("POST", "/api/marketplace/orders", token, map[string]int{"animal_id": animalID, "quantity": quantity}, http.StatusCreated))
+}
+
+// orderStatus moves an order to status as token.
+func (s *testServer) orderStatus(orderID int, token, status string, code int) map[string]interface{} {
+	s.t.Helper()
+	return s.expect("PUT", fmt.Sprintf("/api/marketplace/orders/%d/status", orderID), token, map[string]string{"status": status}, code)
+}
+
+func assertCode(t *testing.T, body map[string]interface{}, code string) {
+	t.Helper()
+	if body["code"] != code {
+		t.Fatalf("code = %v, want %s (body %v)", body["code"], code, body)
+	}
+}
+
+func assertDetail(t *testing.T, body map[string]interface{}, field, message string) {
+	t.Helper()
+	details, _ := body["details"].([]interface{})
+	for _, d := range details {
+		d := d.(map[string]interface{})
+		if d["field"] == field && d["message"] == message {
+			return
+		}
+	}
+	t.Fatalf("no detail {%s: %s} in %v", field, message, body)
+}
+
+func TestHealth(t *testing.T) {
+	s := newTestServer(t)
+	code, out := s.do("GET", "/health", "", nil)
+	if code != http.StatusOK || out["status"] != "ok" {
+		t.Fatalf("health = %d %v", code, out)
+	}
+}
+
+func TestConfigRoutes(t *testing.T) {
+	s := newTestServer(t)
+
+	out := s.expect("GET", "/api/config/splash", "", nil, http.StatusOK)
+	if out["data"] != nil {
+		t.Fatalf("splash without events = %v", out["data"])
+	}
+
+	now := time.Now()
+	s.mem.CreateSplashEvent(&models.SplashEvent{
+		EventName: "Lebaran", ImageURL: "https://example.com/splash.png",
+		StartDate: now.Add(-time.Hour), EndDate: now.Add(time.Hour),
+	})
+	splash := s.data("GET", "/api/config/splash", "", nil, http.StatusOK)
+	if splash["event_name"] != "Lebaran" {
+		t.Fatalf("splash = %v", splash)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+
+	for i, price := range []int{500, 300, 300, 100, 400} {
+		s.expect("POST", "/api/marketplace/animals", seller, map[string]interface{}{
+			"animal_type": "Kucing", "name": fmt.Sprintf("Cat %d", i), "price": price,
+		}, http.StatusCreated)
+	}
+
+	// Walk every page by cursor; ties on price must neither repeat nor skip.
+	var prices []float64
+	seen := map[int]bool{}
+	path := "/api/marketplace/animals?sort=price_asc&limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("cursor walk did not terminate")
+		}
+		out := s.expect("GET", path, "", nil, http.StatusOK)
+		if out["limit"].(float64) != 2 {
+			t.Fatalf("limit = %v", out["limit"])
+		}
+		for _, item := range dataList(t, out) {
+			a := item.(map[string]interface{})
+			id := int(a["id"].(float64))
+			if seen[id] {
+				t.Fatalf("animal %d returned twice", id)
+			}
+			seen[id] = true
+			prices = append(prices, a["price"].(float64))
+		}
+		if out["has_more"] != true {
+			if _, ok := out["next_cursor"]; ok {
+				t.Fatalf("last page has next_cursor: %v", out)
+			}
+			break
+		}
+		path = "/api/marketplace/animals?sort=price_asc&limit=2&cursor=" + out["next_cursor"].(string)
+	}
+	if fmt.Sprint(prices) != "[100 300 300 400 500]" {
+		t.Fatalf("prices = %v", prices)
+	}
+
+	// Legacy page= still works and is echoed back.
+	out := s.expect("GET", "/api/marketplace/animals?sort=price_asc&limit=2&page=3", "", nil, http.StatusOK)
+	if items := dataList(t, out); len(items) != 1 || out["page"].(float64) != 3 || out["has_more"] != false {
+		t.Fatalf("page 3 = %v", out)
+	}
+
+	// Oversized limits are capped; totals are only sent on request.
+	out = s.expect("GET", "/api/marketplace/animals?limit=100000", "", nil, http.StatusOK)
+	if out["limit"].(float64) != 100 || out["total"] != nil {
+		t.Fatalf("capped page = %v", out)
+	}
+	out = s.expect("GET", "/api/marketplace/animals?limit=1&include_total=true", "", nil, http.StatusOK)
+	if out["total"].(float64) != 5 || out["has_more"] != true {
+		t.Fatalf("page with total = %v", out)
+	}
+
+	assertDetail(t, s.expect("GET", "/api/marketplace/animals?limit=-1", "", nil, http.StatusBadRequest), "limit", "must be a positive integer")
+	assertDetail(t, s.expect("GET", "/api/marketplace/animals?page=0", "", nil, http.StatusBadRequest), "page", "must be a positive integer")
+	assertDetail(t, s.expect("GET", "/api/marketplace/animals?cursor=nope", "", nil, http.StatusBadRequest), "cursor", "is invalid")
+
+	// A cursor only resumes the sort it was issued for.
+	next := s.expect("GET", "/api/marketplace/animals?sort=price_asc&limit=1", "", nil, http.StatusOK)["next_cursor"].(string)
+	assertDetail(t, s.expect("GET", "/api/marketplace/animals?sort=newest&cursor="+next, "", nil, http.StatusBadRequest), "cursor", "is invalid")
+}
+
+// failingStore returns an internal error from ListCategories.
+type failingStore struct {
+	*store.MemoryStore
+}
+
+func (failingStore) ListCategories() ([]models.Category, error) {
+	return nil, errors.New(`pq: relation "categories" does not exist`)
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.register("rina")
+
+	out := s.expect("GET", "/api/does-not-exist", "", nil, http.StatusNotFound)
+	assertCode(t, out, "ROUTE_NOT_FOUND")
+
+	out = s.expect("POST", "/api/marketplace/animals", token, map[string]interface{}{"price": "murah"}, http.StatusBadRequest)
+	assertCode(t, out, "VALIDATION_FAILED")
+	assertDetail(t, out, "price", "must be of type number")
+
+	out = s.expect("POST", "/api/marketplace/animals", token, map[string]interface{}{"price": 10}, http.StatusBadRequest)
+	assertDetail(t, out, "animal_type", "is required")
+	assertDetail(t, out, "name", "is required")
+
+	out = s.expect("GET", "/api/marketplace/animals/abc", "", nil, http.StatusBadRequest)
+	assertDetail(t, out, "id", "must be an integer")
+
+	assertCode(t, s.expect("GET", "/api/marketplace/animals/999", "", nil, http.StatusNotFound), "ANIMAL_NOT_FOUND")
+	assertCode(t, s.expect("GET", "/api/profile/pets", "", nil, http.StatusUnauthorized), "UNAUTHORIZED")
+
+	animalID := s.listing(token, map[string]interface{}{
+		"animal_type": "Kucing", "name": "Mochi", "price": 100, "stock": 1,
+	})
+	out = s.expect("POST", "/api/marketplace/orders", token, map[string]interface{}{
+		"animal_id": animalID, "quantity": 5,
+	}, http.StatusBadRequest)
+	assertCode(t, out, "INSUFFICIENT_STOCK")
+
+	store.Default = failingStore{s.mem}
+	out = s.expect("GET", "/api/marketplace/categories", "", nil, http.StatusInternalServerError)
+	assertCode(t, out, "INTERNAL_ERROR")
+	if raw, _ := json.Marshal(out); strings.Contains(string(raw), "pq:") {
+		t.Fatalf("internal error leaked to client: %s", raw)
+	}
+}
@@ -0,0 +1,370 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/TerraPaw/backend/jobs"
+	"github.com/TerraPaw/backend/models"
+)
+
+func TestAnimalFilters(t *testing.T) {
+	s := newTestServer(t)
+	sellerID, seller := s.register("seller")
+	_, other := s.register("other")
+	_, buyer := s.register("buyer")
+	catID := s.mem.AddCategory(models.Category{Name: "Kucing", Icon: "🐱", Type: "animal"})
+
+	mochi := s.listing(seller, map[string]interface{}{
+		"animal_type": "Kucing", "category_id": catID, "name": "Mochi", "price": 100, "age": 1, "gender": "Betina", "color": "Putih", "location": "Jakarta Selatan", "stock": 1,
+	})
+	s.expect("POST", "/api/marketplace/animals", seller, map[string]interface{}{
+		"animal_type": "Kucing", "category_id": catID, "name": "Oyen", "price": 200, "age": 4, "gender": "Jantan", "color": "Oranye", "location": "Bandung",
+	}, http.StatusCreated)
+	bobby := s.listing(other, map[string]interface{}{
+		"animal_type": "Anjing", "name": "Bobby", "price": 300, "age": 2, "gender": "Jantan", "location": "Jakarta Barat", "stock": 3,
+	})
+	s.expect("POST", "/api/marketplace/wishlist", buyer, map[string]int{"animal_id": bobby}, http.StatusOK)
+
+	names := func(query string) string {
+		var out []string
+		for _, item := range s.list("/api/marketplace/animals?"+query, "") {
+			out = append(out, item.(map[string]interface{})["name"].(string))
+		}
+		return strings.Join(out, ",")
+	}
+	cases := map[string]string{
+		"gender=betina":                                    "Mochi",
+		"color=ORANYE":                                     "Oyen",
+		"location=jakarta&sort=oldest":                     "Mochi,Bobby",
+		"min_age=2&max_age=4&sort=oldest":                  "Oyen,Bobby",
+		fmt.Sprintf("seller_id=%d&sort=oldest", sellerID):  "Mochi,Oyen",
+		fmt.Sprintf("category_id=%d&in_stock=true", catID): "Oyen,Mochi",
+		"min_rating=1":                                     "",
+		"sort=popularity&limit=1":                          "Bobby",
+		"search=" + url.QueryEscape("x' OR '1'='1"):        "",
+	}
+	for query, want := range cases {
+		if got := names(query); got != want {
+			t.Errorf("%s: got %q, want %q", query, got, want)
+		}
+	}
+	if p := s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d", mochi), "", nil, http.StatusOK)["popularity"]; p != 0.0 {
+		t.Errorf("popularity = %v", p)
+	}
+
+	out := s.expect("GET", "/api/marketplace/animals?sort=cheapest&min_price=abc&min_age=-1&min_rating=6&in_stock=maybe", "", nil, http.StatusBadRequest)
+	assertDetail(t, out, "sort", "must be one of newest, oldest, price_asc, price_desc, rating, popularity, relevance, distance")
+	assertDetail(t, out, "min_price", "must be a number")
+	assertDetail(t, out, "min_age", "must be at least 0")
+	assertDetail(t, out, "min_rating", "must be at most 5")
+	assertDetail(t, out, "in_stock", "must be a boolean")
+	assertDetail(t, s.expect("GET", "/api/marketplace/animals?min_price=10&max_price=5", "", nil, http.StatusBadRequest), "min_price", "must not exceed the maximum")
+	assertDetail(t, s.expect("GET", "/api/marketplace/animals?seller_id=0", "", nil, http.StatusBadRequest), "seller_id", "must be at least 1")
+}
+
+func TestSearch(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+
+	for _, a := range []map[string]interface{}{
+		{"animal_type": "Kucing", "breed": "Persian", "name": "Mochi", "price": 100},
+		{"animal_type": "Kucing", "breed": "Domestik", "name": "Oyen", "price": 100, "description": "Mirip persian, bulu oranye"},
+		{"animal_type": "Anjing", "breed": "Poodle", "name": "Bobby", "price": 100},
+	} {
+		s.expect("POST", "/api/marketplace/animals", seller, a, http.StatusCreated)
+	}
+
+	names := func(query string) string {
+		var out []string
+		for _, item := range s.list("/api/marketplace/animals?"+query, "") {
+			out = append(out, item.(map[string]interface{})["name"].(string))
+		}
+		return strings.Join(out, ",")
+	}
+	// A prefix finds the breed, and a name or breed hit outranks a description hit.
+	if got := names("search=persia"); got != "Mochi,Oyen" {
+		t.Errorf("search=persia: %q", got)
+	}
+	// Misspellings fall back to trigram similarity.
+	if got := names("search=persain"); got != "Mochi" {
+		t.Errorf("search=persain: %q", got)
+	}
+	if got := names("search=kucing%20oranye"); got != "Oyen" {
+		t.Errorf("search=kucing oranye: %q", got)
+	}
+	if got := names("search=persia&sort=oldest"); got != "Mochi,Oyen" {
+		t.Errorf("search sorted oldest: %q", got)
+	}
+	assertDetail(t, s.expect("GET", "/api/marketplace/animals?sort=relevance", "", nil, http.StatusBadRequest), "sort", "relevance requires search")
+
+	// Relevance pages resume from the cursor like any other sort.
+	first := s.expect("GET", "/api/marketplace/animals?search=persia&limit=1", "", nil, http.StatusOK)
+	next := s.expect("GET", "/api/marketplace/animals?search=persia&limit=1&cursor="+first["next_cursor"].(string), "", nil, http.StatusOK)
+	if items := dataList(t, next); len(items) != 1 || items[0].(map[string]interface{})["name"] != "Oyen" || next["has_more"] != false {
+		t.Fatalf("second relevance page = %v", next)
+	}
+
+	suggest := s.list("/api/marketplace/search/suggest?q=Per", "")
+	if len(suggest) != 1 || suggest[0].(map[string]interface{})["text"] != "Persian" || suggest[0].(map[string]interface{})["kind"] != "breed" {
+		t.Fatalf("suggest = %v", suggest)
+	}
+	suggest = s.list("/api/marketplace/search/suggest?q=kuc", "")
+	if len(suggest) != 1 || suggest[0].(map[string]interface{})["text"] != "Kucing" {
+		t.Fatalf("suggest = %v", suggest)
+	}
+	assertDetail(t, s.expect("GET", "/api/marketplace/search/suggest", "", nil, http.StatusBadRequest), "q", "is required")
+}
+
+func TestAnimalFacets(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+
+	for _, a := range []map[string]interface{}{
+		{"animal_type": "Kucing", "breed": "Persian", "name": "Mochi", "price": 50000, "gender": "Betina", "location": "Jakarta"},
+		{"animal_type": "Kucing", "breed": "Persian", "name": "Oyen", "price": 750000, "gender": "Jantan", "location": "Bandung"},
+		{"animal_type": "Anjing", "breed": "Poodle", "name": "Bobby", "price": 6000000, "gender": "Jantan", "location": "Jakarta"},
+	} {
+		s.expect("POST", "/api/marketplace/animals", seller, a, http.StatusCreated)
+	}
+
+	counts := func(facets map[string]interface{}, name string) string {
+		var out []string
+		for _, f := range facets[name].([]interface{}) {
+			f := f.(map[string]interface{})
+			out = append(out, fmt.Sprintf("%s=%v", f["value"], f["count"]))
+		}
+		return strings.Join(out, ",")
+	}
+
+	out := s.expect("GET", "/api/marketplace/animals?facets=true&limit=1", "", nil, http.StatusOK)
+	if len(dataList(t, out)) != 1 || out["has_more"] != true {
+		t.Fatalf("page = %v", out)
+	}
+	facets := out["facets"].(map[string]interface{})
+	for name, want := range map[string]string{
+		"animal_type": "Kucing=2,Anjing=1",
+		"breed":       "Persian=2,Poodle=1",
+		"gender":      "Jantan=2,Betina=1",
+		"location":    "Jakarta=2,Bandung=1",
+		"color":       "",
+	} {
+		if got := counts(facets, name); got != want {
+			t.Errorf("%s facet = %q, want %q", name, got, want)
+		}
+	}
+	var buckets []string
+	for _, b := range facets["price"].([]interface{}) {
+		b := b.(map[string]interface{})
+		buckets = append(buckets, fmt.Sprintf("%v-%v:%v", b["min"], b["max"], b["count"]))
+	}
+	if got := strings.Join(buckets, " "); got != "0-100000:1 100000-500000:0 500000-1e+06:1 1e+06-5e+06:0 5e+06-<nil>:1" {
+		t.Errorf("price facet = %s", got)
+	}
+
+	// Facets follow the filters but ignore paging.
+	out = s.expect("GET", "/api/marketplace/animals?facets=true&animal_type=kucing&limit=1", "", nil, http.StatusOK)
+	if got := counts(out["facets"].(map[string]interface{}), "gender"); got != "Betina=1,Jantan=1" {
+		t.Errorf("filtered gender facet = %q", got)
+	}
+	if _, ok := s.expect("GET", "/api/marketplace/animals", "", nil, http.StatusOK)["facets"]; ok {
+		t.Error("facets returned without facets=true")
+	}
+	assertDetail(t, s.expect("GET", "/api/marketplace/animals?facets=yes", "", nil, http.StatusBadRequest), "facets", "must be a boolean")
+}
+
+func TestRecommendations(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+	_, breeder := s.register("breeder")
+	listing := func(token, kind, breed string, price, stock int, location string) int {
+		return s.listing(token, map[string]interface{}{
+			"animal_type": kind, "breed": breed, "name": breed, "price": price, "stock": stock, "location": location,
+		})
+	}
+	persian := listing(seller, "Kucing", "Persian", 1000000, 2, "Jakarta")
+	twin := listing(seller, "Kucing", "Persian", 1100000, 2, "jakarta")
+	angora := listing(seller, "Kucing", "Anggora", 3000000, 2, "Bandung")
+	golden := listing(seller, "Anjing", "Golden", 2000000, 2, "Jakarta")
+	lastOne := listing(seller, "Kucing", "Persian", 1000000, 1, "Jakarta")
+	parrot := listing(breeder, "Burung", "Parrot", 500000, 2, "Bogor")
+
+	ids := func(path, token string) []int {
+		t.Helper()
+		var got []int
+		for _, r := range s.list(path, token) {
+			got = append(got, int(r.(map[string]interface{})["animal"].(map[string]interface{})["id"].(float64)))
+		}
+		return got
+	}
+	expectIDs := func(label, path, token string, want ...int) {
+		t.Helper()
+		if got := ids(path, token); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("%s = %v, want %v", label, got, want)
+		}
+	}
+	similarPath := func(id int) string { return fmt.Sprintf("/api/marketplace/animals/%d/similar", id) }
+
+	// Before the first batch, listings are matched on their details alone.
+	expectIDs("similar before batch", similarPath(persian), "", lastOne, twin, angora)
+	first := s.list(similarPath(persian), "")[0].(map[string]interface{})
+	if fmt.Sprint(first["reasons"]) != "[same_type same_breed similar_price same_location]" || first["score"] != 1.0 {
+		t.Fatalf("best match = %v", first)
+	}
+	s.expect("GET", similarPath(999), "", nil, http.StatusNotFound)
+	assertDetail(t, s.expect("GET", similarPath(persian)+"?limit=0", "", nil, http.StatusBadRequest), "limit", "must be at least 1")
+
+	// Users who save the Persian save the Golden too, and the last Persian
+	// sells out.
+	for _, name := range []string{"ani", "budi"} {
+		_, buyer := s.register(name)
+		for _, id := range []int{persian, golden} {
+			s.expect("POST", "/api/marketplace/wishlist", buyer, map[string]int{"animal_id": id}, http.StatusOK)
+		}
+	}
+	_, citra := s.register("citra")
+	s.expect("POST", "/api/marketplace/orders", citra, map[string]int{"animal_id": lastOne, "quantity": 1}, http.StatusCreated)
+	if err := jobs.RecomputeRecommendations(context.Background()); err != nil {
+		t.Fatalf("recompute: %v", err)
+	}
+
+	expectIDs("similar", similarPath(persian), "", twin, golden, angora)
+	expectIDs("similar with limit", similarPath(persian)+"?limit=1", "", twin)
+	also := s.list(similarPath(persian), "")[1].(map[string]interface{})
+	if fmt.Sprint(also["reasons"]) != "[also_liked]" || also["score"] != 0.5 {
+		t.Fatalf("co-interest match = %v", also)
+	}
+
+	// Citra bought the last Persian: its matches come first, then the most
+	// popular listings fill up the rest.
+	recs := s.list("/api/marketplace/recommendations?limit=4", citra)
+	var got []int
+	for _, r := range recs {
+		got = append(got, int(r.(map[string]interface{})["animal"].(map[string]interface{})["id"].(float64)))
+	}
+	if fmt.Sprint(got) != fmt.Sprint([]int{persian, twin, angora, golden}) {
+		t.Fatalf("recommendations = %v", got)
+	}
+	top, filler := recs[0].(map[string]interface{}), recs[3].(map[string]interface{})
+	if top["because_of"] != float64(lastOne) || fmt.Sprint(filler["reasons"]) != "[popular]" || filler["because_of"] != nil {
+		t.Fatalf("recommendations = %v", recs)
+	}
+
+	// Nobody is recommended their own listings, nor what they already saved.
+	expectIDs("seller recommendations", "/api/marketplace/recommendations", seller, parrot)
+	_, ani := s.do("POST", "/api/auth/login", "", map[string]string{"email": "ani@example.com", "password": "secret"})
+	expectIDs("saver recommendations", "/api/marketplace/recommendations?limit=3", dataMap(t, ani)["token"].(string), twin, angora, parrot)
+	s.expect("GET", "/api/marketplace/recommendations", "", nil, http.StatusUnauthorized)
+}
+
+func TestGeoSearch(t *testing.T) {
+	s := newTestServer(t)
+	sellerID, seller := s.register("seller")
+	listing := func(location string, extra map[string]interface{}) int {
+		body := map[string]interface{}{"animal_type": "Kucing", "name": location, "price": 1000000, "location": location}
+		for k, v := range extra {
+			body[k] = v
+		}
+		return s.listing(seller, body)
+	}
+	jaksel := listing("Kebayoran Baru, Jakarta Selatan", nil)
+	pinned := listing("Rumah kami", map[string]interface{}{"latitude": -6.40, "longitude": 106.79})
+	bogor := listing("Bogor", nil)
+	bandung := listing("Kota Bandung, Jawa Barat", nil)
+	atlantis := listing("Atlantis", nil)
+
+	// Listings are placed by their location unless pinned.
+	details := s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d", jaksel), "", nil, http.StatusOK)
+	if details["latitude"] != -6.2615 || details["longitude"] != 106.8106 {
+		t.Fatalf("geocoded listing = %v", details)
+	}
+	details = s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d", atlantis), "", nil, http.StatusOK)
+	if details["latitude"] != nil || details["longitude"] != nil {
+		t.Fatalf("unknown place = %v", details)
+	}
+
+	near := "/api/marketplace/animals?near=-6.2088,106.8456"
+	search := func(path string) ([]int, []float64) {
+		t.Helper()
+		var ids []int
+		var distances []float64
+		for _, a := range s.list(path, "") {
+			animal := a.(map[string]interface{})
+			ids = append(ids, int(animal["id"].(float64)))
+			distances = append(distances, animal["distance_km"].(float64))
+		}
+		return ids, distances
+	}
+	expectIDs := func(label, path string, want ...int) {
+		t.Helper()
+		if got, _ := search(path); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("%s = %v, want %v", label, got, want)
+		}
+	}
+
+	// Nearest first, within 50 km unless asked otherwise.
+	ids, distances := search(near)
+	if fmt.Sprint(ids) != fmt.Sprint([]int{jaksel, pinned, bogor}) || distances[0] < 6 || distances[0] > 8 ||
+		distances[1] < 21 || distances[1] > 23 || distances[2] < 42 || distances[2] > 44 {
+		t.Fatalf("near = %v %v", ids, distances)
+	}
+	expectIDs("wider", near+"&radius_km=300", jaksel, pinned, bogor, bandung)
+	expectIDs("narrower", near+"&radius_km=10", jaksel)
+	expectIDs("newest", near+"&sort=newest", bogor, pinned, jaksel)
+	first := s.expect("GET", near+"&radius_km=300&limit=2", "", nil, http.StatusOK)
+	expectIDs("next page", near+"&radius_km=300&limit=2&cursor="+first["next_cursor"].(string), bogor, bandung)
+	if a := s.list("/api/marketplace/animals", "")[0].(map[string]interface{}); a["distance_km"] != nil {
+		t.Fatalf("distance without near = %v", a)
+	}
+
+	out := s.expect("GET", "/api/marketplace/animals?near=jakarta&radius_km=0&sort=distance", "", nil, http.StatusBadRequest)
+	assertDetail(t, out, "near", "must be latitude,longitude in degrees")
+	assertDetail(t, out, "radius_km", "must be at least 0.1")
+	assertDetail(t, out, "sort", "distance requires near")
+	assertDetail(t, s.expect("GET", "/api/marketplace/animals?near=91,106", "", nil, http.StatusBadRequest),
+		"near", "must be latitude,longitude in degrees")
+	assertDetail(t, s.expect("GET", "/api/marketplace/animals?radius_km=10", "", nil, http.StatusBadRequest),
+		"radius_km", "requires near")
+
+	// Moving a listing places it again; coordinates go in pairs.
+	bandungPath := fmt.Sprintf("/api/marketplace/animals/%d", bandung)
+	s.expect("PATCH", bandungPath, seller, map[string]string{"location": "Depok"}, http.StatusOK)
+	expectIDs("moved", near, jaksel, pinned, bandung, bogor)
+	assertDetail(t, s.expect("PATCH", bandungPath, seller, map[string]float64{"latitude": -6.9}, http.StatusBadRequest),
+		"longitude", "is required with latitude")
+	s.expect("PATCH", bandungPath, seller, map[string]string{"location": "Atlantis"}, http.StatusOK)
+	expectIDs("moved away", near, jaksel, pinned, bogor)
+
+	// Listings added without coordinates are placed by the job.
+	tangerang, err := s.mem.CreateAnimal(&models.Animal{SellerID: sellerID, AnimalType: "Kucing", Name: "Old", Price: 1, Stock: 1, Location: "Tangerang"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	expectIDs("before job", near, jaksel, pinned, bogor)
+	if err := jobs.LocateListings(context.Background()); err != nil {
+		t.Fatalf("locate: %v", err)
+	}
+	expectIDs("after job", near, jaksel, pinned, tangerang, bogor)
+
+	// Users set where they are; only they see it.
+	user := s.data("PUT", "/api/profile/location", seller, map[string]string{"location": "Jogja"}, http.StatusOK)
+	if user["location"] != "Jogja" || user["latitude"] != -7.7956 || user["longitude"] != 110.3695 {
+		t.Fatalf("user location = %v", user)
+	}
+	assertDetail(t, s.expect("PUT", "/api/profile/location", seller, map[string]string{"location": "Atlantis"}, http.StatusBadRequest),
+		"location", "is not a place we know; send latitude and longitude with it")
+	s.expect("PUT", "/api/profile/location", seller, map[string]interface{}{"location": "Atlantis", "latitude": 1.5, "longitude": 2.5}, http.StatusOK)
+	profile := s.data("GET", "/api/auth/profile", seller, nil, http.StatusOK)
+	if profile["location"] != "Atlantis" || profile["latitude"] != 1.5 || profile["longitude"] != 2.5 {
+		t.Fatalf("profile = %v", profile)
+	}
+	details = s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d", jaksel), "", nil, http.StatusOK)
+	if sellerRef := details["seller"].(map[string]interface{}); sellerRef["latitude"] != nil || sellerRef["location"] != nil {
+		t.Fatalf("seller = %v", sellerRef)
+	}
+	s.expect("PUT", "/api/profile/location", "", map[string]string{"location": "Jogja"}, http.StatusUnauthorized)
+}
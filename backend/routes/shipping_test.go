@@ -0,0 +1,159 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TerraPaw/backend/jobs"
+	"github.com/TerraPaw/backend/models"
+)
+
+func TestShipping(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now()
+	s.carrier.Now = func() time.Time { return now }
+	_, catSeller := s.register("catseller")
+	_, foodSeller := s.register("foodseller")
+	buyerID, buyer := s.register("buyer")
+	catID := s.mem.AddCategory(models.Category{Name: "Kucing", Type: "animal"})
+	foodID := s.mem.AddCategory(models.Category{Name: "Makanan Kucing", Type: "food"})
+
+	listing := func(token, animalType string, categoryID int, location string, price float64) int {
+		return s.listing(token, map[string]interface{}{
+			"animal_type": animalType, "category_id": categoryID, "name": animalType, "price": price, "stock": 5, "location": location,
+		})
+	}
+	kitten := listing(catSeller, "Kucing", catID, "Jakarta Selatan", 1500000)
+	food := listing(foodSeller, "Makanan Kucing", foodID, "Bandung, Jawa Barat", 85000)
+
+	// The address book keeps one default, and cities we do not deliver to
+	// are refused.
+	address := func(label, city string, isDefault bool) map[string]interface{} {
+		return map[string]interface{}{
+			"label": label, "recipient_name": "Budi", "phone": "081234567890", "street": "Jl. Darmo 12",
+			"city": city, "postal_code": "60241", "is_default": isDefault,
+		}
+	}
+	home := s.data("POST", "/api/profile/addresses", buyer, address("Rumah", "kota surabaya", false), http.StatusCreated)
+	if home["city"] != "Surabaya" || home["is_default"] != true || home["user_id"] != float64(buyerID) {
+		t.Fatalf("first address = %v", home)
+	}
+	assertDetail(t, s.expect("POST", "/api/profile/addresses", buyer, address("Kapal", "Atlantis", false), http.StatusBadRequest),
+		"city", "is not a city we deliver to yet")
+	office := s.data("POST", "/api/profile/addresses", buyer, address("Kantor", "Jakarta", true), http.StatusCreated)
+	book := s.list("/api/profile/addresses", buyer)
+	if len(book) != 2 || book[0].(map[string]interface{})["label"] != "Kantor" || book[1].(map[string]interface{})["is_default"] != false {
+		t.Fatalf("address book = %v", book)
+	}
+	officePath := fmt.Sprintf("/api/profile/addresses/%v", office["id"])
+	if moved := s.data("PUT", officePath, buyer, address("Kantor", "Bandung", false), http.StatusOK); moved["city"] != "Bandung" || moved["is_default"] != true {
+		t.Fatalf("updated address = %v", moved)
+	}
+	_, other := s.register("other")
+	assertCode(t, s.expect("PUT", officePath, other, address("Kantor", "Bandung", false), http.StatusNotFound), "ADDRESS_NOT_FOUND")
+	s.expect("DELETE", officePath, buyer, nil, http.StatusOK)
+	if book := s.list("/api/profile/addresses", buyer); len(book) != 1 || book[0].(map[string]interface{})["is_default"] != true {
+		t.Fatalf("address book after delete = %v", book)
+	}
+
+	// Live animals are only offered live-animal services.
+	services := func(quote map[string]interface{}) []string {
+		var codes []string
+		for _, r := range quote["rates"].([]interface{}) {
+			codes = append(codes, r.(map[string]interface{})["service"].(string))
+		}
+		return codes
+	}
+	kittenRates := s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d/shipping?city=Bandung", kitten), "", nil, http.StatusOK)
+	if got := services(kittenRates); kittenRates["live_animals"] != true || strings.Join(got, ",") != "pet_ground,pet_cargo" {
+		t.Fatalf("kitten rates = %v", kittenRates)
+	}
+	foodRates := s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d/shipping?city=Bandung", food), "", nil, http.StatusOK)
+	if got := services(foodRates); foodRates["live_animals"] != false || strings.Join(got, ",") != "regular,express,same_day" {
+		t.Fatalf("food rates = %v", foodRates)
+	}
+	assertDetail(t, s.expect("GET", fmt.Sprintf("/api/marketplace/animals/%d/shipping?city=Gotham", kitten), "", nil, http.StatusBadRequest),
+		"city", "is not a city we deliver to yet")
+
+	// The cart is quoted per seller; checkout books one quoted service
+	// each and adds it to the totals.
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": kitten}, http.StatusOK)
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": food, "quantity": 2}, http.StatusOK)
+	quotes := dataList(t, s.expect("POST", "/api/marketplace/cart/shipping", buyer, map[string]interface{}{"address_id": home["id"]}, http.StatusOK))
+	catQuote, foodQuote := quotes[0].(map[string]interface{}), quotes[1].(map[string]interface{})
+	if strings.Join(services(catQuote), ",") != "pet_ground,pet_cargo" || strings.Join(services(foodQuote), ",") != "regular,express" || foodQuote["items"] != 2.0 {
+		t.Fatalf("cart quotes = %v", quotes)
+	}
+	assertCode(t, s.expect("POST", "/api/marketplace/cart/shipping", buyer, map[string]interface{}{"address_id": office["id"]}, http.StatusNotFound), "ADDRESS_NOT_FOUND")
+
+	catSellerID, foodSellerID := catQuote["seller_id"], foodQuote["seller_id"]
+	checkout := func(choices []map[string]interface{}, status int) map[string]interface{} {
+		return s.expect("POST", "/api/marketplace/checkout", buyer, map[string]interface{}{"address_id": home["id"], "shipping": choices}, status)
+	}
+	assertDetail(t, checkout([]map[string]interface{}{
+		{"seller_id": catSellerID, "service": "regular"}, {"seller_id": foodSellerID, "service": "regular"},
+	}, http.StatusBadRequest), "shipping", fmt.Sprintf("regular cannot carry the live animals from seller %v", catSellerID))
+	assertDetail(t, checkout([]map[string]interface{}{{"seller_id": catSellerID, "service": "pet_ground"}}, http.StatusBadRequest),
+		"shipping", fmt.Sprintf("must choose a service for seller %v", foodSellerID))
+	order := dataMap(t, checkout([]map[string]interface{}{
+		{"seller_id": catSellerID, "service": "pet_ground"}, {"seller_id": foodSellerID, "service": "regular"},
+	}, http.StatusCreated))
+	if order["shipping_cost"] != 243000.0 || order["total_price"] != 1913000.0 {
+		t.Fatalf("order with shipping = %v", order)
+	}
+	sub := order["sub_orders"].([]interface{})[0].(map[string]interface{})
+	shipment := sub["shipment"].(map[string]interface{})
+	if sub["total_price"] != 1725000.0 || shipment["service"] != "pet_ground" || shipment["status"] != "pending" || shipment["city"] != "Surabaya" {
+		t.Fatalf("kitten sub-order = %v", sub)
+	}
+
+	// The seller books the pickup once the order is processing; the
+	// tracking job follows the parcel and marks the order delivered.
+	shipmentPath := fmt.Sprintf("/api/marketplace/orders/%v/shipment", sub["id"])
+	assertCode(t, s.expect("POST", shipmentPath, catSeller, nil, http.StatusConflict), "INVALID_ORDER_TRANSITION")
+	for _, status := range []string{"paid", "processing"} {
+		s.expect("PUT", fmt.Sprintf("/api/marketplace/orders/%v/status", sub["id"]), catSeller, map[string]string{"status": status}, http.StatusOK)
+	}
+	assertCode(t, s.expect("POST", shipmentPath, buyer, nil, http.StatusForbidden), "FORBIDDEN")
+	shipped := s.data("POST", shipmentPath, catSeller, nil, http.StatusOK)
+	label := shipped["shipment"].(map[string]interface{})
+	if shipped["status"] != "shipped" || label["tracking_number"] == nil || label["status"] != "label_created" {
+		t.Fatalf("shipped order = %v", shipped)
+	}
+	assertCode(t, s.expect("GET", shipmentPath, other, nil, http.StatusNotFound), "SHIPMENT_NOT_FOUND")
+
+	if err := jobs.TrackShipments(context.Background()); err != nil {
+		t.Fatalf("tracking: %v", err)
+	}
+	if tracked := s.data("GET", shipmentPath, buyer, nil, http.StatusOK); tracked["status"] != "label_created" {
+		t.Fatalf("shipment before pickup = %v", tracked)
+	}
+	now = now.Add(12 * time.Hour)
+	if err := jobs.TrackShipments(context.Background()); err != nil {
+		t.Fatalf("tracking: %v", err)
+	}
+	if tracked := s.data("GET", shipmentPath, buyer, nil, http.StatusOK); tracked["status"] != "in_transit" || len(tracked["events"].([]interface{})) != 3 {
+		t.Fatalf("shipment in transit = %v", tracked)
+	}
+	now = now.Add(48 * time.Hour)
+	if err := jobs.TrackShipments(context.Background()); err != nil {
+		t.Fatalf("tracking: %v", err)
+	}
+	tracked := s.data("GET", shipmentPath, buyer, nil, http.StatusOK)
+	if tracked["status"] != "delivered" || tracked["delivered_at"] == nil || len(tracked["events"].([]interface{})) != 5 {
+		t.Fatalf("delivered shipment = %v", tracked)
+	}
+	delivered := s.data("GET", fmt.Sprintf("/api/marketplace/orders/%v", sub["id"]), buyer, nil, http.StatusOK)
+	history := delivered["history"].([]interface{})
+	if delivered["status"] != "delivered" || history[len(history)-1].(map[string]interface{})["role"] != "system" {
+		t.Fatalf("order after delivery = %v", delivered)
+	}
+
+	// Orders placed without an address have no shipment.
+	direct := s.order(buyer, food, 1)
+	assertCode(t, s.expect("GET", fmt.Sprintf("/api/marketplace/orders/%d/shipment", direct), buyer, nil, http.StatusNotFound), "SHIPMENT_NOT_FOUND")
+}
@@ -0,0 +1,178 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/TerraPaw/backend/jobs"
+)
+
+func TestSellerAnalytics(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+	_, buyer := s.register("buyer")
+	kitten := s.catListing(seller, "Mochi", 1500000, 2)
+	food := s.catListing(seller, "Makanan Kucing", 50000, 10)
+
+	for i := 0; i < 4; i++ {
+		s.expect("GET", fmt.Sprintf("/api/marketplace/animals/%d", kitten), "", nil, http.StatusOK)
+	}
+	s.expect("GET", fmt.Sprintf("/api/marketplace/animals/%d", food), "", nil, http.StatusOK)
+	s.expect("POST", "/api/marketplace/wishlist", buyer, map[string]int{"animal_id": kitten}, http.StatusOK)
+	order := func(animalID, quantity int, paid bool) int {
+		id := s.order(buyer, animalID, quantity)
+		if paid {
+			s.orderStatus(id, seller, "paid", http.StatusOK)
+		}
+		return id
+	}
+	kittenOrder := order(kitten, 1, false)
+	s.complete(kittenOrder, seller, buyer)
+	order(food, 3, true)
+	order(food, 1, false)
+	s.expect("POST", "/api/marketplace/reviews", buyer, map[string]interface{}{"animal_id": kitten, "rating": 4, "order_id": kittenOrder}, http.StatusCreated)
+
+	analytics := func(query string, status int) map[string]interface{} {
+		return s.expect("GET", "/api/seller/analytics"+query, seller, nil, status)
+	}
+
+	// Nothing shows until the rollups are refreshed.
+	stale := dataMap(t, analytics("", http.StatusOK))
+	if stale["refreshed_at"] != nil || stale["revenue"] != 0.0 || len(stale["sales"].([]interface{})) != 30 {
+		t.Fatalf("before refresh = %v", stale)
+	}
+
+	if err := jobs.RefreshAnalytics(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	report := dataMap(t, analytics("", http.StatusOK))
+	sales := report["sales"].([]interface{})
+	today := sales[len(sales)-1].(map[string]interface{})
+	if report["revenue"] != 1650000.0 || report["orders"] != 2.0 || report["units"] != 4.0 || today["revenue"] != 1650000.0 || report["refreshed_at"] == nil {
+		t.Fatalf("sales = %v", report)
+	}
+	top := report["top_listings"].([]interface{})
+	if len(top) != 2 || top[0].(map[string]interface{})["name"] != "Mochi" || top[0].(map[string]interface{})["views"] != 4.0 || top[1].(map[string]interface{})["units"] != 3.0 {
+		t.Fatalf("top listings = %v", top)
+	}
+	funnel := report["funnel"].(map[string]interface{})
+	if funnel["views"] != 5.0 || funnel["wishlists"] != 1.0 || funnel["orders"] != 2.0 || funnel["view_to_wishlist"] != 0.2 || funnel["view_to_order"] != 0.4 {
+		t.Fatalf("funnel = %v", funnel)
+	}
+	alerts := report["stock_alerts"].([]interface{})
+	if len(alerts) != 1 || alerts[0].(map[string]interface{})["stock"] != 1.0 || alerts[0].(map[string]interface{})["days_left"] != 30.0 {
+		t.Fatalf("stock alerts = %v", alerts)
+	}
+	if report["average_rating"] != 4.0 || report["rating_count"] != 1.0 {
+		t.Fatalf("rating = %v (%v reviews)", report["average_rating"], report["rating_count"])
+	}
+
+	// Other buckets and ranges.
+	if weeks := dataMap(t, analytics("?period=week&top=1", http.StatusOK)); len(weeks["sales"].([]interface{})) != 12 || len(weeks["top_listings"].([]interface{})) != 1 {
+		t.Fatalf("weekly = %v", weeks)
+	}
+	if months := dataMap(t, analytics("?period=month", http.StatusOK)); len(months["sales"].([]interface{})) != 12 || months["revenue"] != 1650000.0 {
+		t.Fatalf("monthly = %v", months)
+	}
+	if past := dataMap(t, analytics("?from=2020-01-01&to=2020-01-31", http.StatusOK)); past["revenue"] != 0.0 || len(past["sales"].([]interface{})) != 31 {
+		t.Fatalf("past range = %v", past)
+	}
+	assertDetail(t, analytics("?period=year", http.StatusBadRequest), "period", "must be one of day, week, month")
+	assertDetail(t, analytics("?from=2024-02-01&to=2024-01-01", http.StatusBadRequest), "from", "must not be after to")
+	assertDetail(t, analytics("?to=yesterday", http.StatusBadRequest), "to", "must be a date (YYYY-MM-DD)")
+
+	// Buyers without listings get an empty report.
+	if empty := s.data("GET", "/api/seller/analytics", buyer, nil, http.StatusOK); empty["orders"] != 0.0 || len(empty["top_listings"].([]interface{})) != 0 {
+		t.Fatalf("empty report = %v", empty)
+	}
+}
+
+func TestShops(t *testing.T) {
+	s := newTestServer(t)
+	sellerID, seller := s.register("seller")
+	_, rival := s.register("rival")
+	buyerID, buyer := s.register("buyer")
+
+	s.expect("GET", "/api/seller/shop", seller, nil, http.StatusNotFound)
+	shop := s.data("POST", "/api/seller/shop", seller, map[string]interface{}{
+		"name": "Kucing Ras Bandung!", "city": "Bandung",
+		"opening_hours": []map[string]string{{"day": "mon", "opens": "09:00", "closes": "17:00"}},
+	}, http.StatusCreated)
+	if shop["slug"] != "kucing-ras-bandung" || len(shop["opening_hours"].([]interface{})) != 1 {
+		t.Fatalf("shop = %v", shop)
+	}
+	assertCode(t, s.expect("POST", "/api/seller/shop", seller, map[string]string{"name": "Again"}, http.StatusConflict), "SHOP_ALREADY_EXISTS")
+
+	// A second shop with the same name gets the seller's id in its slug;
+	// an explicit slug that is taken is refused.
+	assertCode(t, s.expect("POST", "/api/seller/shop", rival, map[string]string{"name": "Rival", "slug": "kucing-ras-bandung"}, http.StatusConflict), "SHOP_SLUG_TAKEN")
+	if other := s.data("POST", "/api/seller/shop", rival, map[string]string{"name": "Kucing Ras Bandung"}, http.StatusCreated); !strings.HasPrefix(other["slug"].(string), "kucing-ras-bandung-") {
+		t.Fatalf("rival slug = %v", other["slug"])
+	}
+	assertDetail(t, s.expect("PUT", "/api/seller/shop", seller, map[string]interface{}{"name": "X", "slug": "Bad Slug"}, http.StatusBadRequest), "slug", "must be lowercase letters and digits joined by hyphens")
+	assertDetail(t, s.expect("PUT", "/api/seller/shop", seller, map[string]interface{}{
+		"name": "X", "opening_hours": []map[string]string{{"day": "tue", "opens": "18:00", "closes": "09:00"}},
+	}, http.StatusBadRequest), "opening_hours[0].closes", "must be after opens")
+	assertCode(t, s.expect("PUT", "/api/seller/shop", buyer, map[string]string{"name": "None"}, http.StatusNotFound), "SHOP_NOT_FOUND")
+
+	// Updating without a slug keeps it; a new slug moves the page.
+	updated := s.data("PUT", "/api/seller/shop", seller, map[string]string{"name": "Kucing Ras", "description": "Breeder since 2015"}, http.StatusOK)
+	if updated["slug"] != "kucing-ras-bandung" || updated["name"] != "Kucing Ras" || len(updated["opening_hours"].([]interface{})) != 0 {
+		t.Fatalf("updated = %v", updated)
+	}
+	s.expect("PUT", "/api/seller/shop", seller, map[string]string{"name": "Kucing Ras", "slug": "kucing-ras"}, http.StatusOK)
+	s.expect("GET", "/api/shops/kucing-ras-bandung", "", nil, http.StatusNotFound)
+	if mine := s.data("GET", "/api/seller/shop", seller, nil, http.StatusOK); mine["slug"] != "kucing-ras" {
+		t.Fatalf("my shop = %v", mine)
+	}
+
+	// Listings carry their shop, and the page shows them with the figures.
+	kitten := s.listing(seller, map[string]interface{}{
+		"animal_type": "Kucing", "name": "Mochi", "price": 1500000, "stock": 2,
+	})
+	if ref := s.data("GET", fmt.Sprintf("/api/marketplace/animals/%d", kitten), "", nil, http.StatusOK)["shop"].(map[string]interface{}); ref["slug"] != "kucing-ras" {
+		t.Fatalf("listing shop = %v", ref)
+	}
+	order := s.order(buyer, kitten, 1)
+	s.complete(order, seller, buyer)
+	s.expect("POST", "/api/marketplace/reviews", buyer, map[string]interface{}{"animal_id": kitten, "rating": 5, "order_id": order}, http.StatusCreated)
+	s.expect("POST", "/api/chat/messages", buyer, map[string]interface{}{"receiver_id": sellerID, "content": "Masih ada?"}, http.StatusCreated)
+	s.expect("POST", "/api/chat/messages", seller, map[string]interface{}{"receiver_id": buyerID, "content": "Masih, kak"}, http.StatusCreated)
+
+	page := s.data("GET", "/api/shops/kucing-ras", "", nil, http.StatusOK)
+	stats := page["stats"].(map[string]interface{})
+	if stats["listings"] != 1.0 || stats["sold"] != 1.0 || stats["rating"] != 5.0 || stats["review_count"] != 1.0 || stats["response_rate"] != 1.0 || stats["response_hours"] != 0.0 {
+		t.Fatalf("stats = %v", stats)
+	}
+	if len(page["listings"].([]interface{})) != 1 || page["shop"].(map[string]interface{})["seller"].(map[string]interface{})["username"] != "seller" {
+		t.Fatalf("page = %v", page)
+	}
+	assertCode(t, s.expect("GET", "/api/shops/nope", "", nil, http.StatusNotFound), "SHOP_NOT_FOUND")
+
+	// Following, twice, counts once and fills the feed.
+	s.expect("POST", "/api/shops/kucing-ras/follow", buyer, nil, http.StatusOK)
+	s.expect("POST", "/api/shops/kucing-ras/follow", buyer, nil, http.StatusOK)
+	s.expect("POST", "/api/shops/nope/follow", buyer, nil, http.StatusNotFound)
+	if shop := s.data("GET", "/api/shops/kucing-ras", "", nil, http.StatusOK)["shop"].(map[string]interface{}); shop["follower_count"] != 1.0 {
+		t.Fatalf("followers = %v", shop["follower_count"])
+	}
+	following := s.list("/api/profile/following", buyer)
+	if len(following) != 1 || following[0].(map[string]interface{})["slug"] != "kucing-ras" || following[0].(map[string]interface{})["followed_at"] == nil {
+		t.Fatalf("following = %v", following)
+	}
+	s.expect("POST", "/api/marketplace/animals", rival, map[string]interface{}{"animal_type": "Kucing", "name": "Rival cat", "price": 100}, http.StatusCreated)
+	newer := s.listing(seller, map[string]interface{}{"animal_type": "Kucing", "name": "Kopi", "price": 900000})
+	feed := s.list("/api/profile/feed", buyer)
+	if len(feed) != 2 || feed[0].(map[string]interface{})["id"] != float64(newer) {
+		t.Fatalf("feed = %v", feed)
+	}
+
+	s.expect("DELETE", "/api/shops/kucing-ras/follow", buyer, nil, http.StatusOK)
+	if feed := s.list("/api/profile/feed", buyer); len(feed) != 0 {
+		t.Fatalf("feed after unfollow = %v", feed)
+	}
+	s.expect("DELETE", "/api/shops/kucing-ras/follow", "", nil, http.StatusUnauthorized)
+}
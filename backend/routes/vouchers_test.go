@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func TestVouchers(t *testing.T) {
+	s := newTestServer(t)
+	_, catSeller := s.register("catseller")
+	_, foodSeller := s.register("foodseller")
+	_, buyer := s.register("buyer")
+	catID := s.mem.AddCategory(models.Category{Name: "Kucing", Icon: "🐱", Type: "animal"})
+	foodID := s.mem.AddCategory(models.Category{Name: "Makanan Kucing", Type: "food"})
+
+	listing := func(token, animalType string, categoryID int, price float64) int {
+		return s.listing(token, map[string]interface{}{
+			"animal_type": animalType, "category_id": categoryID, "name": animalType, "price": price, "stock": 5,
+		})
+	}
+	kitten := listing(catSeller, "Kucing", catID, 1500000)
+	food := listing(foodSeller, "Makanan Kucing", foodID, 85000)
+
+	// Platform vouchers are seeded; only live public ones are listed.
+	maxDiscount, once := 100000.0, 1
+	past := time.Now().Add(-time.Hour)
+	for _, v := range []*models.Voucher{
+		{Code: "hemat10", DiscountType: "percentage", Value: 10, MaxDiscount: &maxDiscount, MinSpend: 500000, UsageLimit: &once, PerUserLimit: 1, Public: true},
+		{Code: "LAMA", DiscountType: "fixed", Value: 5000, PerUserLimit: 1, Public: true, StartsAt: past.Add(-time.Hour), EndsAt: &past},
+	} {
+		if err := s.mem.CreateVoucher(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Sellers issue vouchers for their own listings.
+	create := func(token string, body map[string]interface{}, code int) map[string]interface{} {
+		return s.expect("POST", "/api/marketplace/vouchers", token, body, code)
+	}
+	mochi := dataMap(t, create(catSeller, map[string]interface{}{
+		"code": "mochi50k", "discount_type": "fixed", "value": 50000, "category_id": catID, "public": true,
+	}, http.StatusCreated))
+	if mochi["code"] != "MOCHI50K" || mochi["per_user_limit"] != 1.0 || mochi["seller_id"] == nil {
+		t.Fatalf("seller voucher = %v", mochi)
+	}
+	create(foodSeller, map[string]interface{}{"code": "FOOD20", "discount_type": "percentage", "value": 20, "min_spend": 200000}, http.StatusCreated)
+	assertCode(t, create(foodSeller, map[string]interface{}{"code": "Mochi50K", "discount_type": "fixed", "value": 1000}, http.StatusConflict), "VOUCHER_CODE_TAKEN")
+	assertDetail(t, create(foodSeller, map[string]interface{}{"code": "HALF", "discount_type": "percentage", "value": 150}, http.StatusBadRequest),
+		"value", "must be at most 100 for percentage discounts")
+	assertDetail(t, create(foodSeller, map[string]interface{}{"code": "NOCAT", "discount_type": "fixed", "value": 1000, "category_id": 999}, http.StatusBadRequest),
+		"category_id", "does not exist")
+	assertCode(t, create("", map[string]interface{}{"code": "ANON", "discount_type": "fixed", "value": 1000}, http.StatusUnauthorized), "UNAUTHORIZED")
+	if public := s.list("/api/marketplace/vouchers", ""); len(public) != 2 {
+		t.Fatalf("public vouchers = %v", public)
+	}
+
+	// Claiming saves a voucher to the wallet, once.
+	claim := func(code string, status int) map[string]interface{} {
+		return s.expect("POST", "/api/profile/vouchers", buyer, map[string]string{"code": code}, status)
+	}
+	if claimed := dataMap(t, claim(" Hemat10 ", http.StatusCreated)); claimed["code"] != "HEMAT10" || claimed["usable"] != true {
+		t.Fatalf("claimed = %v", claimed)
+	}
+	assertCode(t, claim("HEMAT10", http.StatusConflict), "VOUCHER_ALREADY_CLAIMED")
+	assertCode(t, claim("NOPE", http.StatusNotFound), "VOUCHER_NOT_FOUND")
+	if out := claim("LAMA", http.StatusBadRequest); out["code"] != "VOUCHER_NOT_APPLICABLE" || out["message"] != "This voucher has expired" {
+		t.Fatalf("expired claim = %v", out)
+	}
+	stats := func() float64 {
+		return s.data("GET", "/api/profile/stats", buyer, nil, http.StatusOK)["vouchers"].(float64)
+	}
+	if n := stats(); n != 1 {
+		t.Fatalf("stats vouchers = %v, want 1", n)
+	}
+
+	// Quotes check restrictions and minimum spend against the cart.
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": kitten}, http.StatusOK)
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": food, "quantity": 2}, http.StatusOK)
+	quote := func(code string, status int) map[string]interface{} {
+		return s.expect("POST", "/api/marketplace/cart/voucher", buyer, map[string]string{"code": code}, status)
+	}
+	if q := dataMap(t, quote("MOCHI50K", http.StatusOK)); q["eligible_subtotal"] != 1500000.0 || q["discount"] != 50000.0 || q["total"] != 1620000.0 {
+		t.Fatalf("seller quote = %v", q)
+	}
+	if q := dataMap(t, quote("HEMAT10", http.StatusOK)); q["eligible_subtotal"] != 1670000.0 || q["discount"] != 100000.0 {
+		t.Fatalf("capped quote = %v", q)
+	}
+	if out := quote("FOOD20", http.StatusBadRequest); out["message"] != "This voucher needs a minimum spend of 200000" {
+		t.Fatalf("min spend quote = %v", out)
+	}
+	assertCode(t, s.expect("POST", "/api/marketplace/checkout", buyer, map[string]string{"voucher_code": "NOPE"}, http.StatusNotFound), "VOUCHER_NOT_FOUND")
+
+	// Checkout records the discount on the checkout and its sub-orders.
+	order := s.data("POST", "/api/marketplace/checkout", buyer, map[string]string{"voucher_code": "hemat10"}, http.StatusCreated)
+	if order["total_price"] != 1570000.0 || order["discount"] != 100000.0 || order["voucher_id"] == nil {
+		t.Fatalf("discounted order = %v", order)
+	}
+	subs := order["sub_orders"].([]interface{})
+	if cat := subs[0].(map[string]interface{}); cat["discount"] != 89820.0 || cat["total_price"] != 1410180.0 {
+		t.Fatalf("sub-orders = %v", subs)
+	}
+	wallet := func() map[string]interface{} {
+		return s.list("/api/profile/vouchers", buyer)[0].(map[string]interface{})
+	}
+	if v := wallet(); v["times_used"] != 1.0 || v["usable"] != false || v["used_count"] != 1.0 {
+		t.Fatalf("wallet after checkout = %v", v)
+	}
+	if n := stats(); n != 0 {
+		t.Fatalf("stats vouchers after use = %v, want 0", n)
+	}
+
+	// The global cap holds for other buyers until the checkout is
+	// cancelled, which gives the voucher back.
+	_, other := s.register("other")
+	s.expect("POST", "/api/marketplace/cart/items", other, map[string]int{"animal_id": kitten}, http.StatusOK)
+	out := s.expect("POST", "/api/marketplace/checkout", other, map[string]string{"voucher_code": "HEMAT10"}, http.StatusBadRequest)
+	if out["message"] != "This voucher has been fully redeemed" {
+		t.Fatalf("capped checkout = %v", out)
+	}
+	s.expect("PUT", fmt.Sprintf("/api/marketplace/orders/%v/status", order["id"]), buyer, map[string]string{"status": "cancelled"}, http.StatusOK)
+	if v := wallet(); v["times_used"] != 0.0 || v["usable"] != true || v["used_count"] != 0.0 {
+		t.Fatalf("wallet after cancel = %v", v)
+	}
+	if order := s.data("POST", "/api/marketplace/checkout", other, map[string]string{"voucher_code": "HEMAT10"}, http.StatusCreated); order["discount"] != 100000.0 {
+		t.Fatalf("checkout after release = %v", order)
+	}
+}
+
+func TestVoucherSubcategories(t *testing.T) {
+	s := newTestServer(t)
+	_, seller := s.register("seller")
+	_, buyer := s.register("buyer")
+	cats := s.mem.AddCategory(models.Category{Name: "Kucing", Type: "animal"})
+	persians := s.mem.AddCategory(models.Category{Name: "Kucing Persia", ParentID: &cats, Type: "animal"})
+
+	// A voucher on a parent category covers listings in the categories
+	// under it.
+	persian := s.listing(seller, map[string]interface{}{
+		"animal_type": "Kucing Persia", "category_id": persians, "name": "Snowy", "price": 2000000, "stock": 1,
+	})
+	s.expect("POST", "/api/marketplace/vouchers", seller, map[string]interface{}{
+		"code": "KUCING10", "discount_type": "percentage", "value": 10, "category_id": cats,
+	}, http.StatusCreated)
+	s.expect("POST", "/api/marketplace/cart/items", buyer, map[string]int{"animal_id": persian}, http.StatusOK)
+	if q := s.data("POST", "/api/marketplace/cart/voucher", buyer, map[string]string{"code": "KUCING10"}, http.StatusOK); q["eligible_subtotal"] != 2000000.0 || q["discount"] != 200000.0 {
+		t.Fatalf("parent category quote = %v", q)
+	}
+	if order := s.data("POST", "/api/marketplace/checkout", buyer, map[string]string{"voucher_code": "KUCING10"}, http.StatusCreated); order["discount"] != 200000.0 {
+		t.Fatalf("parent category checkout = %v", order)
+	}
+}
@@ -0,0 +1,149 @@
+package shipping
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeService is how the fake carrier prices a service: a base price, a
+// price per extra item and the delivery days by zone (same city, same
+// island, across islands). Zero days means the zone is not served.
+type fakeService struct {
+	base, perItem int64
+	days          [3][2]int
+}
+
+var fakeServices = map[string]fakeService{
+	ServiceRegular:   {base: 10000, perItem: 2000, days: [3][2]int{{1, 2}, {2, 4}, {4, 7}}},
+	ServiceExpress:   {base: 20000, perItem: 4000, days: [3][2]int{{1, 1}, {1, 2}, {2, 3}}},
+	ServiceSameDay:   {base: 35000, perItem: 5000, days: [3][2]int{{1, 1}}},
+	ServicePetGround: {base: 150000, perItem: 50000, days: [3][2]int{{1, 1}, {1, 2}}},
+	ServicePetCargo:  {base: 400000, perItem: 75000, days: [3][2]int{{}, {1, 1}, {1, 2}}},
+}
+
+// zoneFactor scales prices by zone, in halves.
+var zoneFactor = [3]int64{2, 3, 5}
+
+// Fake is a carrier that needs no network. Prices, tracking numbers and
+// scans follow from the request alone, and scans appear as Now passes the
+// times the parcel would reach them, so tests can move Now forward.
+type Fake struct {
+	Now func() time.Time // time.Now when nil
+
+	mu     sync.Mutex
+	labels map[string]fakeLabel // by tracking number
+}
+
+type fakeLabel struct {
+	req     LabelRequest
+	created time.Time
+	days    int
+}
+
+func NewFake() *Fake {
+	return &Fake{labels: map[string]fakeLabel{}}
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+func (f *Fake) now() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+	return time.Now()
+}
+
+// zone is 0 within a city, 1 within an island and 2 across islands.
+func zone(origin, destination string) (int, error) {
+	from, to := island(origin), island(destination)
+	switch {
+	case from == "" || to == "":
+		return 0, ErrUnserviceable
+	case origin == destination:
+		return 0, nil
+	case from == to:
+		return 1, nil
+	}
+	return 2, nil
+}
+
+func (f *Fake) Quote(ctx context.Context, req QuoteRequest) ([]Rate, error) {
+	z, err := zone(req.Origin, req.Destination)
+	if err != nil {
+		return nil, err
+	}
+	items := int64(req.Items)
+	if items < 1 {
+		items = 1
+	}
+	var rates []Rate
+	for _, s := range Services {
+		fs := fakeServices[s.Code]
+		if s.LiveAnimals != req.LiveAnimals || fs.days[z][1] == 0 {
+			continue
+		}
+		rates = append(rates, Rate{
+			Service: s.Code, Name: s.Name, Price: (fs.base + fs.perItem*(items-1)) * zoneFactor[z] / 2,
+			MinDays: fs.days[z][0], MaxDays: fs.days[z][1], LiveAnimals: s.LiveAnimals,
+		})
+	}
+	sort.SliceStable(rates, func(i, j int) bool { return rates[i].Price < rates[j].Price })
+	return rates, nil
+}
+
+func (f *Fake) CreateLabel(ctx context.Context, req LabelRequest) (*Label, error) {
+	rates, err := f.Quote(ctx, QuoteRequest{Origin: req.Origin, Destination: req.Destination, Items: req.Items, LiveAnimals: req.LiveAnimals})
+	if err != nil {
+		return nil, err
+	}
+	days := 0
+	for _, r := range rates {
+		if r.Service == req.Service {
+			days = r.MaxDays
+		}
+	}
+	if days == 0 {
+		return nil, fmt.Errorf("shipping: %s cannot carry this parcel from %s to %s", req.Service, req.Origin, req.Destination)
+	}
+
+	sum := sha256.Sum256([]byte(req.Reference))
+	number := strings.ToUpper(fmt.Sprintf("FK%x", sum[:5]))
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l, ok := f.labels[number]
+	if !ok {
+		l = fakeLabel{req: req, created: f.now(), days: days}
+		f.labels[number] = l
+	}
+	return &Label{TrackingNumber: number, CreatedAt: l.created}, nil
+}
+
+func (f *Fake) Track(ctx context.Context, trackingNumber string) ([]TrackingEvent, error) {
+	f.mu.Lock()
+	l, ok := f.labels[trackingNumber]
+	f.mu.Unlock()
+	if !ok {
+		return nil, ErrUnknownTracking
+	}
+
+	arrive := l.created.Add(time.Duration(l.days) * 24 * time.Hour)
+	scans := []TrackingEvent{
+		{StatusPickedUp, "Picked up from the seller", l.req.Origin, l.created.Add(3 * time.Hour)},
+		{StatusInTransit, "Departed the " + l.req.Origin + " hub", l.req.Origin, l.created.Add(8 * time.Hour)},
+		{StatusOutForDelivery, "Out for delivery", l.req.Destination, arrive.Add(-6 * time.Hour)},
+		{StatusDelivered, "Delivered to " + l.req.Recipient, l.req.Destination, arrive},
+	}
+	now := f.now()
+	var events []TrackingEvent
+	for _, e := range scans {
+		if !e.OccurredAt.After(now) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,189 @@
+// Package shipping quotes, books and tracks deliveries through a courier.
+// Carrier is implemented by a deterministic fake; couriers such as JNE,
+// SiCepat or a pet transport partner plug in behind the same interface.
+package shipping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TerraPaw/backend/config"
+)
+
+// Services. Parcels holding live animals only travel by the pet services:
+// pet_ground is an escorted, climate-controlled van on the same island,
+// pet_cargo a live-animal crate on a passenger flight.
+const (
+	ServiceRegular   = "regular"
+	ServiceExpress   = "express"
+	ServiceSameDay   = "same_day"
+	ServicePetGround = "pet_ground"
+	ServicePetCargo  = "pet_cargo"
+)
+
+// Service describes a delivery service.
+type Service struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	LiveAnimals bool   `json:"live_animals"` // may carry live animals, and nothing else may
+}
+
+// Services lists every delivery service.
+var Services = []Service{
+	{Code: ServiceRegular, Name: "Regular"},
+	{Code: ServiceExpress, Name: "Express"},
+	{Code: ServiceSameDay, Name: "Same day"},
+	{Code: ServicePetGround, Name: "Pet transport (ground)", LiveAnimals: true},
+	{Code: ServicePetCargo, Name: "Pet cargo (air)", LiveAnimals: true},
+}
+
+// Allowed reports whether service may carry a parcel. Live animals only go
+// by live-animal services, which carry nothing else.
+func Allowed(service string, liveAnimals bool) bool {
+	for _, s := range Services {
+		if s.Code == service {
+			return s.LiveAnimals == liveAnimals
+		}
+	}
+	return false
+}
+
+// Tracking statuses, in the order a parcel reaches them.
+const (
+	StatusLabelCreated   = "label_created"
+	StatusPickedUp       = "picked_up"
+	StatusInTransit      = "in_transit"
+	StatusOutForDelivery = "out_for_delivery"
+	StatusDelivered      = "delivered"
+)
+
+var (
+	// ErrUnserviceable is returned for cities the carrier does not serve.
+	ErrUnserviceable = errors.New("shipping: route not serviced")
+	// ErrUnknownTracking is returned by Track for tracking numbers the
+	// carrier never issued.
+	ErrUnknownTracking = errors.New("shipping: unknown tracking number")
+)
+
+// cities maps the spellings we recognise in listing locations and
+// addresses to a city and its island.
+var cities = map[string][2]string{
+	"jakarta":    {"Jakarta", "java"},
+	"bogor":      {"Bogor", "java"},
+	"depok":      {"Depok", "java"},
+	"tangerang":  {"Tangerang", "java"},
+	"bekasi":     {"Bekasi", "java"},
+	"bandung":    {"Bandung", "java"},
+	"semarang":   {"Semarang", "java"},
+	"yogyakarta": {"Yogyakarta", "java"},
+	"jogja":      {"Yogyakarta", "java"},
+	"solo":       {"Surakarta", "java"},
+	"surakarta":  {"Surakarta", "java"},
+	"surabaya":   {"Surabaya", "java"},
+	"malang":     {"Malang", "java"},
+	"denpasar":   {"Denpasar", "bali"},
+	"bali":       {"Denpasar", "bali"},
+	"medan":      {"Medan", "sumatra"},
+	"palembang":  {"Palembang", "sumatra"},
+	"makassar":   {"Makassar", "sulawesi"},
+	"balikpapan": {"Balikpapan", "kalimantan"},
+}
+
+// City finds the city a free-text location or address refers to, or ""
+// if it names none we serve.
+func City(location string) string {
+	for _, word := range strings.FieldsFunc(strings.ToLower(location), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z')
+	}) {
+		if c, ok := cities[word]; ok {
+			return c[0]
+		}
+	}
+	return ""
+}
+
+// island returns the island of a city returned by City.
+func island(city string) string {
+	for _, c := range cities {
+		if c[0] == city {
+			return c[1]
+		}
+	}
+	return ""
+}
+
+// QuoteRequest describes a parcel to price. Origin and Destination are
+// city names as returned by City.
+type QuoteRequest struct {
+	Origin      string
+	Destination string
+	Items       int
+	LiveAnimals bool // only live-animal services are quoted
+}
+
+// Rate is the price of sending a parcel by one service.
+type Rate struct {
+	Service     string `json:"service"`
+	Name        string `json:"name"`
+	Price       int64  `json:"price"`
+	MinDays     int    `json:"min_days"`
+	MaxDays     int    `json:"max_days"`
+	LiveAnimals bool   `json:"live_animals"`
+}
+
+// LabelRequest books the pickup of a parcel.
+type LabelRequest struct {
+	// Reference identifies the shipment on our side. Booking the same
+	// reference again returns the label already issued.
+	Reference   string
+	Service     string
+	Origin      string
+	Destination string
+	Items       int
+	LiveAnimals bool
+	Recipient   string
+	Phone       string
+	Address     string
+}
+
+// Label is a booked pickup.
+type Label struct {
+	TrackingNumber string    `json:"tracking_number"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TrackingEvent is one scan of a parcel.
+type TrackingEvent struct {
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
+	Location    string    `json:"location"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// Carrier is a courier.
+type Carrier interface {
+	// Name identifies the carrier on stored shipments.
+	Name() string
+	// Quote returns the services that can carry the parcel, cheapest
+	// first, or ErrUnserviceable.
+	Quote(ctx context.Context, req QuoteRequest) ([]Rate, error)
+	CreateLabel(ctx context.Context, req LabelRequest) (*Label, error)
+	// Track returns every scan of a parcel so far, oldest first.
+	Track(ctx context.Context, trackingNumber string) ([]TrackingEvent, error)
+}
+
+// Default is the carrier used by the shipping handlers and jobs. It is set
+// in main from the configuration.
+var Default Carrier
+
+// Open returns the carrier described by cfg.
+func Open(cfg *config.Config) (Carrier, error) {
+	switch cfg.ShippingCarrier {
+	case "fake":
+		return NewFake(), nil
+	}
+	return nil, fmt.Errorf("shipping: unknown carrier %q", cfg.ShippingCarrier)
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MediaPath is the route uploaded objects are served from. The database
+// stores MediaPath+key, without a signature, wherever a URL is expected.
+const MediaPath = "/api/media/"
+
+// Ref returns the stable reference stored for key.
+func Ref(key string) string {
+	return MediaPath + key
+}
+
+// KeyOf returns the key a reference or signed URL points at. ok is false
+// for URLs that are not uploads, such as external image links.
+func KeyOf(ref string) (key string, ok bool) {
+	if i := strings.IndexByte(ref, '?'); i >= 0 {
+		ref = ref[:i]
+	}
+	if !strings.HasPrefix(ref, MediaPath) {
+		return "", false
+	}
+	key = strings.TrimPrefix(ref, MediaPath)
+	return key, validKey(key)
+}
+
+// Signer issues and checks expiring links to stored objects.
+type Signer struct {
+	Secret []byte
+	TTL    time.Duration
+}
+
+// DefaultSigner signs the media links handlers return. It is set in main
+// from the configuration.
+var DefaultSigner *Signer
+
+// Sign turns a reference into a link valid for at least TTL. Expiry is
+// rounded up to the next TTL boundary so a link stays the same for a while
+// and clients can cache it. Other URLs are returned unchanged.
+func (s *Signer) Sign(ref string) string {
+	key, ok := KeyOf(ref)
+	if !ok {
+		return ref
+	}
+	expires := time.Now().Add(s.TTL).Truncate(s.TTL).Add(s.TTL).Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("signature", s.signature(key, expires))
+	return Ref(key) + "?" + q.Encode()
+}
+
+// Verify reports whether signature is valid for key and has not expired.
+func (s *Signer) Verify(key, expires, signature string) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.signature(key, exp)))
+}
+
+func (s *Signer) signature(key string, expires int64) string {
+	h := hmac.New(sha256.New, s.Secret)
+	h.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(h.Sum(nil))
+}
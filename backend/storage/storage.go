@@ -0,0 +1,70 @@
+// Package storage keeps uploaded files. Blob is implemented on the local
+// filesystem and on S3-compatible object stores; files are served to
+// clients through URLs signed by a Signer.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/TerraPaw/backend/config"
+)
+
+// ErrNotFound is returned by Get and Delete when no object has the key.
+var ErrNotFound = errors.New("object not found")
+
+// Blob stores objects under slash-separated keys.
+type Blob interface {
+	// Put stores size bytes read from r under key, replacing any object
+	// already there.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object under key. The caller closes the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Object describes a stored object.
+type Object struct {
+	Size        int64
+	ContentType string
+}
+
+// Default is the blob store used by the upload handlers. It is set in main
+// from the configuration and replaced with a temporary Local store in tests.
+var Default Blob
+
+// validKey rejects keys that could escape the store root or confuse an
+// object store: empty, absolute, or containing empty, "." or ".." segments.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// Open returns the blob store and link signer described by cfg.
+func Open(cfg *config.Config) (Blob, *Signer, error) {
+	signer := &Signer{Secret: []byte(cfg.MediaSecret), TTL: cfg.MediaURLTTL}
+	switch cfg.StorageDriver {
+	case "local":
+		blob, err := NewLocal(cfg.StorageDir)
+		return blob, signer, err
+	case "s3":
+		return &S3{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		}, signer, nil
+	}
+	return nil, nil, fmt.Errorf("storage: unknown driver %q", cfg.StorageDriver)
+}
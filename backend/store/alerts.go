@@ -0,0 +1,78 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// WishlistAlert is a notification the alert job wrote, with where else
+// the user wants it sent.
+type WishlistAlert struct {
+	Notification models.Notification
+	Email        string // the user's address when they want alerts by email
+	Push         bool
+}
+
+// DefaultAlertPreferences apply to users who never set theirs: every
+// price drop and return to stock, in the app only.
+func DefaultAlertPreferences() models.AlertPreferences {
+	return models.AlertPreferences{PriceDrop: true, BackInStock: true}
+}
+
+// inStock reports whether a listing in status with stock can be bought.
+func inStock(status string, stock int) bool {
+	return status == StatusAvailable && stock > 0
+}
+
+// wishlistAlert decides what to tell the user behind w about listing a,
+// which they last saw at w.AlertPrice and w.AlertInStock. A listing back in
+// stock is reported as such, whatever its price; a price drop only while
+// the listing can be bought. The dedupe key tells a user once about each
+// price of a listing, and at most once a day that it is back in stock.
+func wishlistAlert(prefs models.AlertPreferences, w models.Wishlist, a *models.Animal, now time.Time) (models.Notification, bool) {
+	available := inStock(a.Status, a.Stock)
+	switch {
+	case !available:
+		return models.Notification{}, false
+	case !w.AlertInStock:
+		if !prefs.BackInStock {
+			return models.Notification{}, false
+		}
+		return models.Notification{
+			UserID:    w.UserID,
+			Title:     "Back in stock: " + a.Name,
+			Message:   fmt.Sprintf("%s from your wishlist is available again at %s.", a.Name, rupiah(a.Price)),
+			Type:      "wishlist",
+			DedupeKey: fmt.Sprintf("wishlist:%d:stock:%s", a.ID, now.Format("2006-01-02")),
+		}, true
+	case a.Price < w.AlertPrice:
+		drop := (w.AlertPrice - a.Price) / w.AlertPrice * 100
+		if !prefs.PriceDrop || drop < float64(prefs.MinDropPercent) {
+			return models.Notification{}, false
+		}
+		return models.Notification{
+			UserID:    w.UserID,
+			Title:     "Price drop: " + a.Name,
+			Message:   fmt.Sprintf("%s from your wishlist dropped from %s to %s.", a.Name, rupiah(w.AlertPrice), rupiah(a.Price)),
+			Type:      "wishlist",
+			DedupeKey: fmt.Sprintf("wishlist:%d:price:%.0f", a.ID, a.Price),
+		}, true
+	}
+	return models.Notification{}, false
+}
+
+// rupiah formats a whole-rupiah amount the Indonesian way, as Rp1.500.000.
+func rupiah(amount float64) string {
+	digits := strconv.FormatInt(int64(amount), 10)
+	out := make([]byte, 0, len(digits)+len(digits)/3)
+	for i := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out = append(out, '.')
+		}
+		out = append(out, digits[i])
+	}
+	return "Rp" + string(out)
+}
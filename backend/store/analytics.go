@@ -0,0 +1,131 @@
+package store
+
+import (
+	"math"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// AnalyticsPeriods lists the accepted AnalyticsQuery.Period values.
+var AnalyticsPeriods = []string{"day", "week", "month"}
+
+// LowStock is the stock at or below which a listing gets a stock alert.
+const LowStock = 3
+
+// salesStatuses are the statuses of orders counted as sales: paid and not
+// called off.
+var salesStatuses = []string{OrderPaid, OrderProcessing, OrderShipped, OrderDelivered, OrderCompleted}
+
+// AnalyticsQuery selects the sales shown by SellerAnalytics. From and To
+// are dates; To is exclusive.
+type AnalyticsQuery struct {
+	Period string // one of AnalyticsPeriods
+	From   time.Time
+	To     time.Time
+	Top    int // number of top listings
+}
+
+// isSale reports whether an order in status counts as a sale.
+func isSale(status string) bool {
+	for _, s := range salesStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Day returns the date of t as midnight UTC, the form dates come back from
+// Postgres in.
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// BucketStart returns the start of the period holding day: the day itself,
+// the Monday of its week or the first of its month.
+func BucketStart(period string, day time.Time) time.Time {
+	day = Day(day)
+	switch period {
+	case "week":
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+// nextBucket returns the start of the period after the one starting at
+// start.
+func nextBucket(period string, start time.Time) time.Time {
+	switch period {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// salesDay is one day of a rollup: paid orders, units and revenue.
+type salesDay struct {
+	day     time.Time
+	orders  int
+	units   int
+	revenue float64
+}
+
+// fillSales sums days into one bucket per period between q.From and q.To,
+// including empty ones, and totals them on a.
+func fillSales(a *models.SellerAnalytics, q AnalyticsQuery, days []salesDay) {
+	index := map[time.Time]int{}
+	a.Sales = []models.SalesBucket{}
+	for start := BucketStart(q.Period, q.From); start.Before(q.To); start = nextBucket(q.Period, start) {
+		index[start] = len(a.Sales)
+		a.Sales = append(a.Sales, models.SalesBucket{Start: start})
+	}
+	for _, d := range days {
+		if d.day.Before(q.From) || !d.day.Before(q.To) {
+			continue
+		}
+		b := &a.Sales[index[BucketStart(q.Period, d.day)]]
+		b.Orders += d.orders
+		b.Units += d.units
+		b.Revenue += d.revenue
+		a.Orders += d.orders
+		a.Units += d.units
+		a.Revenue += d.revenue
+	}
+}
+
+// newFunnel works out the conversion rates between the funnel's steps.
+func newFunnel(views, wishlists, orders int) models.ConversionFunnel {
+	rate := func(n, of int) float64 {
+		if of == 0 {
+			return 0
+		}
+		return math.Round(float64(n)/float64(of)*10000) / 10000
+	}
+	return models.ConversionFunnel{
+		Views: views, Wishlists: wishlists, Orders: orders,
+		ViewToWishlist: rate(wishlists, views), WishlistToOrder: rate(orders, wishlists), ViewToOrder: rate(orders, views),
+	}
+}
+
+// averageRating is the mean of count ratings adding up to sum, to two
+// decimals.
+func averageRating(sum, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return math.Round(float64(sum)/float64(count)*100) / 100
+}
+
+// daysLeft projects how long stock lasts at the last 30 days' sales.
+func daysLeft(stock, unitsLast30Days int) *float64 {
+	if unitsLast30Days == 0 {
+		return nil
+	}
+	days := math.Round(float64(stock)/(float64(unitsLast30Days)/30)*10) / 10
+	return &days
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// cartKey names what a cart line sells: a listing or, when variantID is
+// set, a product variant.
+type cartKey struct {
+	animalID, variantID int
+}
+
+// column returns the cart_items and order_items column holding the key and
+// its value.
+func (k cartKey) column() (string, int) {
+	if k.variantID != 0 {
+		return "variant_id", k.variantID
+	}
+	return "animal_id", k.animalID
+}
+
+// nullID is id as a query argument, NULL when it is 0.
+func nullID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// checkCartItem fills in the current price and subtotal of item from its
+// listing or variant and records what, if anything, stops it from being
+// bought.
+func checkCartItem(item *models.CartItem, available bool, stock int, price float64) {
+	item.Price = price
+	item.Subtotal = price * float64(item.Quantity)
+	item.Problem = ""
+	switch {
+	case !available:
+		item.Problem = models.CartUnavailable
+	case stock < item.Quantity:
+		item.Problem = models.CartInsufficientStock
+	case price != item.AddedPrice:
+		item.Problem = models.CartPriceChanged
+	}
+}
+
+// newCart totals checked items. Unavailable items are listed but left out
+// of the subtotal.
+func newCart(items []models.CartItem) *models.Cart {
+	cart := &models.Cart{Items: items, CanCheckout: len(items) > 0}
+	if cart.Items == nil {
+		cart.Items = []models.CartItem{}
+	}
+	for _, item := range items {
+		if item.Problem != "" {
+			cart.CanCheckout = false
+		}
+		if item.Problem == models.CartUnavailable {
+			continue
+		}
+		cart.ItemCount += item.Quantity
+		cart.Subtotal += item.Subtotal
+	}
+	return cart
+}
+
+// itemSeller returns the seller and category of what a cart item sells.
+func itemSeller(item models.CartItem) (int, *int) {
+	if item.Variant != nil {
+		return item.Variant.SellerID, &item.Variant.CategoryID
+	}
+	return item.Animal.SellerID, item.Animal.CategoryID
+}
+
+// inCartOrder sorts items the way the cart lists them, oldest first.
+func inCartOrder(items []models.CartItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+}
+
+// checkoutLine is a cart item being bought, with its seller.
+type checkoutLine struct {
+	models.CartItem
+	sellerID int
+}
+
+// bySeller groups checkout lines into sub-orders, ordered by seller id so
+// both stores build the same order.
+func bySeller(lines []checkoutLine) [][]checkoutLine {
+	sorted := append([]checkoutLine(nil), lines...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].sellerID < sorted[j].sellerID })
+
+	var groups [][]checkoutLine
+	for i, line := range sorted {
+		if i == 0 || line.sellerID != sorted[i-1].sellerID {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], line)
+	}
+	return groups
+}
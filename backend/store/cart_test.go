@@ -0,0 +1,93 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func TestCheckCartItem(t *testing.T) {
+	for _, c := range []struct {
+		label     string
+		available bool
+		stock     int
+		price     float64
+		want      string
+	}{
+		{"as added", true, 5, 100, ""},
+		{"off sale", false, 5, 100, models.CartUnavailable},
+		{"short", true, 1, 100, models.CartInsufficientStock},
+		{"repriced", true, 5, 120, models.CartPriceChanged},
+	} {
+		item := models.CartItem{Quantity: 2, AddedPrice: 100}
+		checkCartItem(&item, c.available, c.stock, c.price)
+		if item.Problem != c.want || item.Price != c.price || item.Subtotal != 2*c.price {
+			t.Errorf("%s: %+v", c.label, item)
+		}
+	}
+}
+
+// Checkout takes each line's stock, from listings or variants, marks
+// sold-out listings sold and empties the cart; cancelling puts it back.
+func TestMemoryCheckoutStock(t *testing.T) {
+	m := NewMemory()
+	sellerID := memoryUser(t, m, "seller")
+	buyerID := memoryUser(t, m, "buyer")
+	kitten := memoryListing(t, m, sellerID, "Mochi", 1500000, 1)
+	food := memoryListing(t, m, sellerID, "Makanan Kucing", 85000, 10)
+	foodCat := m.AddCategory(models.Category{Name: "Makanan Kucing", Type: "food"})
+	p := &models.Product{SellerID: sellerID, CategoryID: foodCat, SKU: "WHK", Name: "Whiskas",
+		Variants: []models.ProductVariant{{SKU: "WHK-1KG", Price: 55000, WeightGrams: 1000, Stock: 4}}}
+	if err := m.CreateProduct(p); err != nil {
+		t.Fatal(err)
+	}
+	variantID := p.Variants[0].ID
+
+	if err := m.AddCartItem(buyerID, kitten, 2); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("adding past stock = %v", err)
+	}
+	if err := m.AddCartItem(buyerID, kitten, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddCartItem(buyerID, food, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddCartVariant(buyerID, variantID, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UpdateCartVariant(buyerID, variantID, 5); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("raising past variant stock = %v", err)
+	}
+
+	order, err := m.Checkout(buyerID, CheckoutOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.TotalPrice != 1920000 || order.Quantity != 7 || len(order.SubOrders) != 1 || len(order.Items) != 3 {
+		t.Fatalf("order = %+v", order)
+	}
+	expectStock(t, m, kitten, 0, StatusSold)
+	expectStock(t, m, food, 7, StatusAvailable)
+	if got, _ := m.GetProduct(p.ID); got.Variants[0].Stock != 1 {
+		t.Errorf("variant stock = %d, want 1", got.Variants[0].Stock)
+	}
+	if cart, _ := m.GetCart(buyerID); len(cart.Items) != 0 {
+		t.Errorf("cart not emptied: %+v", cart.Items)
+	}
+	if _, err := m.Checkout(buyerID, CheckoutOptions{}); err != ErrEmptyCart {
+		t.Errorf("checking out an empty cart = %v", err)
+	}
+	if _, err := m.CreateOrder(buyerID, kitten, 1); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("ordering a sold listing = %v", err)
+	}
+
+	// Cancelling gives variants their stock back too.
+	if _, err := m.UpdateOrderStatus(order.ID, buyerID, OrderCancelled, ""); err != nil {
+		t.Fatal(err)
+	}
+	expectStock(t, m, kitten, 1, StatusAvailable)
+	if got, _ := m.GetProduct(p.ID); got.Variants[0].Stock != 4 {
+		t.Errorf("variant stock after cancelling = %d, want 4", got.Variants[0].Stock)
+	}
+}
@@ -0,0 +1,9 @@
+package store
+
+// categorySubtree selects the id bound to ? and those of every category
+// under it, so filtering by a category takes in its subcategories.
+const categorySubtree = `WITH RECURSIVE subtree AS (
+		SELECT id FROM categories WHERE id = ?
+		UNION
+		SELECT c.id FROM categories c JOIN subtree ON c.parent_id = subtree.id
+	) SELECT id FROM subtree`
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// priceBounds are the upper bounds, in rupiah, of the price facet buckets.
+// A final bucket holds everything from the last bound up.
+var priceBounds = []float64{100000, 500000, 1000000, 5000000}
+
+// maxFacetValues caps each value list so a free-text field such as
+// location cannot blow up the response.
+const maxFacetValues = 20
+
+// priceBucket returns the index of the bucket price falls in, matching
+// Postgres' width_bucket(price, priceBounds).
+func priceBucket(price float64) int {
+	return sort.Search(len(priceBounds), func(i int) bool { return priceBounds[i] > price })
+}
+
+// facetCounts accumulates counts per facet and value before they are
+// shaped into models.AnimalFacets.
+type facetCounts struct {
+	values map[string]map[string]int
+	prices []int
+}
+
+func newFacetCounts() *facetCounts {
+	return &facetCounts{values: map[string]map[string]int{}, prices: make([]int, len(priceBounds)+1)}
+}
+
+// add records n listings with value for facet. Empty values are skipped.
+func (fc *facetCounts) add(facet, value string, n int) {
+	if value == "" {
+		return
+	}
+	if fc.values[facet] == nil {
+		fc.values[facet] = map[string]int{}
+	}
+	fc.values[facet][value] += n
+}
+
+func (fc *facetCounts) facets() *models.AnimalFacets {
+	list := func(facet string) []models.FacetCount {
+		out := []models.FacetCount{}
+		for v, n := range fc.values[facet] {
+			out = append(out, models.FacetCount{Value: v, Count: n})
+		}
+		sort.Slice(out, func(i, j int) bool {
+			if out[i].Count != out[j].Count {
+				return out[i].Count > out[j].Count
+			}
+			return out[i].Value < out[j].Value
+		})
+		if len(out) > maxFacetValues {
+			out = out[:maxFacetValues]
+		}
+		return out
+	}
+
+	f := &models.AnimalFacets{
+		AnimalType: list("animal_type"),
+		Breed:      list("breed"),
+		Color:      list("color"),
+		Gender:     list("gender"),
+		Location:   list("location"),
+	}
+	for i, n := range fc.prices {
+		b := models.PriceBucket{Count: n}
+		if i > 0 {
+			b.Min = priceBounds[i-1]
+		}
+		if i < len(priceBounds) {
+			upper := priceBounds[i]
+			b.Max = &upper
+		}
+		f.Price = append(f.Price, b)
+	}
+	return f
+}
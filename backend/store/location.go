@@ -0,0 +1,39 @@
+package store
+
+import (
+	"math"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// earthRadiusKm is the radius of the sphere earthdistance measures on
+// (its earth() function), so both stores agree on distances.
+const earthRadiusKm = 6378.168
+
+// distanceKm is the great-circle distance between two points, kept to ten
+// metres like the distances ListAnimals reports.
+func distanceKm(a, b models.GeoPoint) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	d := 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+	return math.Round(d*100) / 100
+}
+
+// coordinates splits p into the nullable latitude and longitude stored on
+// listings and users.
+func coordinates(p *models.GeoPoint) (lat, lng *float64) {
+	if p == nil {
+		return nil, nil
+	}
+	return &p.Latitude, &p.Longitude
+}
+
+// position is the inverse of coordinates.
+func position(lat, lng *float64) *models.GeoPoint {
+	if lat == nil || lng == nil {
+		return nil
+	}
+	return &models.GeoPoint{Latitude: *lat, Longitude: *lng}
+}
@@ -0,0 +1,183 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// pair is the composite key used for the (entity, user) join tables such as
+// likes, bookmarks and wishlists.
+type pair [2]int
+
+type memUser struct {
+	models.User
+	resetToken  string
+	resetExpiry time.Time
+	moderator   bool
+}
+
+// MemoryStore is an in-process implementation of Store used by the test
+// suite. It mirrors the Postgres behaviour closely enough for the HTTP
+// contract tests and needs no database.
+type MemoryStore struct {
+	mu  sync.Mutex
+	seq map[string]int
+
+	users          map[int]*memUser
+	pets           []models.UserPet
+	medicalRecords []models.MedicalRecord
+	notifications  []models.Notification
+
+	posts        map[int]*models.Post
+	postMedia    []models.PostMedia
+	comments     []models.Comment
+	likes        map[pair]bool
+	bookmarks    map[pair]bool
+	shares       map[pair]bool
+	commentLikes map[pair]bool
+
+	categories  []models.Category
+	animals     map[int]*models.Animal
+	animalMedia []models.AnimalMedia
+	prices      []models.PriceChange
+	orders      map[int]*models.Order
+	orderItems  []models.OrderItem
+	orderLog    []models.OrderStatusChange
+	carts       map[int][]models.CartItem // by buyer
+	payments    map[int]*models.Payment
+	paymentLog  map[[2]string]bool // applied events by provider and event id
+	refunds     []*models.PaymentRefund
+	vouchers    map[int]*models.Voucher
+	claims      map[pair]time.Time // claimed vouchers by voucher and user
+	redemptions map[int]int        // voucher used by order
+	addresses   map[int]*models.Address
+	shipments   map[int]*models.Shipment
+	shipmentLog []models.ShipmentEvent
+	wishlists   []models.Wishlist
+	alertPrefs  map[int]models.AlertPreferences // by user
+	similar     map[int][]models.Similarity     // by animal, best first
+	reviews     []models.Review
+	reviewVotes map[pair]bool // helpful votes by review and user
+	reports     map[int]*models.ReviewReport
+	views       map[int]int // listing detail views by animal
+	analytics   *memAnalytics
+	shops       map[int]*models.Shop
+	follows     map[pair]time.Time // followed shops by shop and user
+	products    map[int]*models.Product
+
+	vets          map[int]*models.Veterinarian
+	consultations map[int]*models.Consultation
+	messages      []models.Message
+	splashEvents  []models.SplashEvent
+
+	idempotencyKeys map[idemKey]*models.IdempotencyKey
+}
+
+func NewMemory() *MemoryStore {
+	return &MemoryStore{
+		seq:           map[string]int{},
+		users:         map[int]*memUser{},
+		posts:         map[int]*models.Post{},
+		likes:         map[pair]bool{},
+		bookmarks:     map[pair]bool{},
+		shares:        map[pair]bool{},
+		commentLikes:  map[pair]bool{},
+		animals:       map[int]*models.Animal{},
+		orders:        map[int]*models.Order{},
+		carts:         map[int][]models.CartItem{},
+		payments:      map[int]*models.Payment{},
+		paymentLog:    map[[2]string]bool{},
+		vouchers:      map[int]*models.Voucher{},
+		claims:        map[pair]time.Time{},
+		redemptions:   map[int]int{},
+		addresses:     map[int]*models.Address{},
+		shipments:     map[int]*models.Shipment{},
+		alertPrefs:    map[int]models.AlertPreferences{},
+		similar:       map[int][]models.Similarity{},
+		reviewVotes:   map[pair]bool{},
+		reports:       map[int]*models.ReviewReport{},
+		views:         map[int]int{},
+		shops:         map[int]*models.Shop{},
+		follows:       map[pair]time.Time{},
+		products:      map[int]*models.Product{},
+		vets:          map[int]*models.Veterinarian{},
+		consultations: map[int]*models.Consultation{},
+
+		idempotencyKeys: map[idemKey]*models.IdempotencyKey{},
+	}
+}
+
+// nextID emulates a SERIAL column for the named table. Callers must hold mu.
+func (m *MemoryStore) nextID(table string) int {
+	m.seq[table]++
+	return m.seq[table]
+}
+
+// userRef returns a copy of the user suitable for embedding in responses.
+// It leaves out where the user is, which only their own profile shows.
+// Callers must hold mu.
+func (m *MemoryStore) userRef(id int) *models.User {
+	u, ok := m.users[id]
+	if !ok {
+		return &models.User{}
+	}
+	ref := u.User
+	ref.Location, ref.Latitude, ref.Longitude = "", nil, nil
+	return &ref
+}
+
+func countPairs(set map[pair]bool, id int) int {
+	n := 0
+	for k := range set {
+		if k[0] == id {
+			n++
+		}
+	}
+	return n
+}
+
+// AddCategory seeds a category, standing in for db.SeedCategories.
+func (m *MemoryStore) AddCategory(c models.Category) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c.ID = m.nextID("categories")
+	c.Active = true
+	m.categories = append(m.categories, c)
+	return c.ID
+}
+
+// SetModerator lets a user work the moderation queue, standing in for
+// setting users.is_moderator by hand.
+func (m *MemoryStore) SetModerator(userID int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[userID]; ok {
+		u.moderator = true
+	}
+}
+
+// AddMedicalRecord seeds a medical record; there is no API to create one.
+func (m *MemoryStore) AddMedicalRecord(r models.MedicalRecord) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r.ID = m.nextID("medical_records")
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	m.medicalRecords = append(m.medicalRecords, r)
+	return r.ID
+}
+
+// AddNotification seeds a notification for a user.
+func (m *MemoryStore) AddNotification(n models.Notification) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n.ID = m.nextID("notifications")
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	m.notifications = append(m.notifications, n)
+	return n.ID
+}
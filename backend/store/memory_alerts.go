@@ -0,0 +1,77 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (m *MemoryStore) GetAlertPreferences(userID int) (*models.AlertPreferences, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefs, ok := m.alertPrefs[userID]
+	if !ok {
+		prefs = DefaultAlertPreferences()
+	}
+	return &prefs, nil
+}
+
+func (m *MemoryStore) SetAlertPreferences(userID int, p *models.AlertPreferences) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.alertPrefs[userID] = *p
+	return nil
+}
+
+func (m *MemoryStore) MatchWishlistAlerts(limit int) ([]WishlistAlert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var changed []*models.Wishlist
+	for i := range m.wishlists {
+		w := &m.wishlists[i]
+		a, ok := m.animals[w.AnimalID]
+		if ok && a.Status != StatusDeleted && (a.Price != w.AlertPrice || inStock(a.Status, a.Stock) != w.AlertInStock) {
+			changed = append(changed, w)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].ID < changed[j].ID })
+	if len(changed) > limit {
+		changed = changed[:limit]
+	}
+
+	now := time.Now()
+	alerts := []WishlistAlert{}
+	for _, w := range changed {
+		a := m.animals[w.AnimalID]
+		prefs, ok := m.alertPrefs[w.UserID]
+		if !ok {
+			prefs = DefaultAlertPreferences()
+		}
+		if n, ok := wishlistAlert(prefs, *w, a, now); ok && !m.hasNotification(n.UserID, n.DedupeKey) {
+			n.ID, n.CreatedAt = m.nextID("notifications"), now
+			m.notifications = append(m.notifications, n)
+			alert := WishlistAlert{Notification: n, Push: prefs.Push}
+			if prefs.Email {
+				alert.Email = m.userRef(w.UserID).Email
+			}
+			alerts = append(alerts, alert)
+		}
+		w.AlertPrice, w.AlertInStock = a.Price, inStock(a.Status, a.Stock)
+	}
+	return alerts, nil
+}
+
+// hasNotification reports whether the user already has a notification
+// with the dedupe key. Callers must hold mu.
+func (m *MemoryStore) hasNotification(userID int, key string) bool {
+	for _, n := range m.notifications {
+		if n.UserID == userID && n.DedupeKey == key {
+			return true
+		}
+	}
+	return false
+}
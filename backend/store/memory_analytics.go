@@ -0,0 +1,192 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// memAnalytics is the snapshot RefreshAnalytics takes, standing in for the
+// Postgres materialized views.
+type memAnalytics struct {
+	refreshedAt time.Time
+	sellerDays  map[int][]salesDay // by seller
+	listingDays map[int][]salesDay // by listing
+	listings    map[int]memListingStats
+}
+
+// memListingStats mirrors a row of the listing_stats view.
+type memListingStats struct {
+	sellerID, views, wishlists, orders, units30d, reviews, ratingSum int
+}
+
+// addDay adds a sale to the day it falls on in days.
+func addDay(days map[time.Time]*salesDay, at time.Time, orders, units int, revenue float64) {
+	d, ok := days[Day(at)]
+	if !ok {
+		d = &salesDay{day: Day(at)}
+		days[d.day] = d
+	}
+	d.orders += orders
+	d.units += units
+	d.revenue += revenue
+}
+
+// flattenDays lists days oldest first.
+func flattenDays(days map[time.Time]*salesDay) []salesDay {
+	var out []salesDay
+	for _, d := range days {
+		out = append(out, *d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].day.Before(out[j].day) })
+	return out
+}
+
+func (m *MemoryStore) RecordAnimalView(animalID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.views[animalID]++
+	return nil
+}
+
+func (m *MemoryStore) RefreshAnalytics() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bySeller := map[int]map[time.Time]*salesDay{}
+	byListing := map[int]map[time.Time]*salesDay{}
+	for _, o := range m.orders {
+		if o.SellerID == nil || !isSale(o.Status) {
+			continue
+		}
+		if bySeller[*o.SellerID] == nil {
+			bySeller[*o.SellerID] = map[time.Time]*salesDay{}
+		}
+		addDay(bySeller[*o.SellerID], o.CreatedAt, 1, o.Quantity, o.TotalPrice-o.Shipping)
+		for _, item := range m.orderLines(o.ID) {
+			if item.AnimalID == 0 {
+				continue // a product, not a listing
+			}
+			if byListing[item.AnimalID] == nil {
+				byListing[item.AnimalID] = map[time.Time]*salesDay{}
+			}
+			addDay(byListing[item.AnimalID], o.CreatedAt, 1, item.Quantity, float64(item.Quantity)*item.UnitPrice)
+		}
+	}
+
+	snapshot := &memAnalytics{
+		refreshedAt: time.Now(),
+		sellerDays:  map[int][]salesDay{},
+		listingDays: map[int][]salesDay{},
+		listings:    map[int]memListingStats{},
+	}
+	for id, days := range bySeller {
+		snapshot.sellerDays[id] = flattenDays(days)
+	}
+	for id, days := range byListing {
+		snapshot.listingDays[id] = flattenDays(days)
+	}
+	monthAgo := Day(time.Now()).AddDate(0, 0, -30)
+	for id, a := range m.animals {
+		if a.Status == StatusDeleted {
+			continue
+		}
+		stats := memListingStats{sellerID: a.SellerID, views: m.views[id]}
+		for _, w := range m.wishlists {
+			if w.AnimalID == id {
+				stats.wishlists++
+			}
+		}
+		for _, d := range snapshot.listingDays[id] {
+			stats.orders += d.orders
+			if d.day.After(monthAgo) {
+				stats.units30d += d.units
+			}
+		}
+		for _, r := range m.reviews {
+			if r.AnimalID == id && r.RemovedAt == nil {
+				stats.reviews++
+				stats.ratingSum += r.Rating
+			}
+		}
+		snapshot.listings[id] = stats
+	}
+	m.analytics = snapshot
+	return nil
+}
+
+func (m *MemoryStore) SellerAnalytics(sellerID int, q AnalyticsQuery) (*models.SellerAnalytics, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := &models.SellerAnalytics{Period: q.Period, From: q.From, To: q.To, TopListings: []models.ListingPerformance{}, StockAlerts: []models.StockAlert{}}
+	snapshot := m.analytics
+	if snapshot == nil {
+		snapshot = &memAnalytics{}
+	} else {
+		out.RefreshedAt = &snapshot.refreshedAt
+	}
+	fillSales(out, q, snapshot.sellerDays[sellerID])
+
+	var views, wishlists, orders, reviews, ratingSum int
+	for id, stats := range snapshot.listings {
+		if stats.sellerID != sellerID {
+			continue
+		}
+		views += stats.views
+		wishlists += stats.wishlists
+		orders += stats.orders
+		reviews += stats.reviews
+		ratingSum += stats.ratingSum
+
+		top := models.ListingPerformance{
+			AnimalID: id, Name: m.animals[id].Name, Views: stats.views, Wishlists: stats.wishlists,
+			Rating: averageRating(stats.ratingSum, stats.reviews), ReviewCount: stats.reviews,
+		}
+		for _, d := range snapshot.listingDays[id] {
+			if !d.day.Before(q.From) && d.day.Before(q.To) {
+				top.Orders += d.orders
+				top.Units += d.units
+				top.Revenue += d.revenue
+			}
+		}
+		if top.Orders > 0 {
+			out.TopListings = append(out.TopListings, top)
+		}
+	}
+	sort.Slice(out.TopListings, func(i, j int) bool {
+		a, b := out.TopListings[i], out.TopListings[j]
+		if a.Revenue != b.Revenue {
+			return a.Revenue > b.Revenue
+		}
+		if a.Units != b.Units {
+			return a.Units > b.Units
+		}
+		return a.AnimalID < b.AnimalID
+	})
+	if len(out.TopListings) > q.Top {
+		out.TopListings = out.TopListings[:q.Top]
+	}
+	out.Funnel = newFunnel(views, wishlists, orders)
+	out.AverageRating, out.RatingCount = averageRating(ratingSum, reviews), reviews
+
+	for id, a := range m.animals {
+		if a.SellerID != sellerID || (a.Status != StatusAvailable && a.Status != StatusSold) || a.Stock > LowStock {
+			continue
+		}
+		units := snapshot.listings[id].units30d
+		out.StockAlerts = append(out.StockAlerts, models.StockAlert{
+			AnimalID: id, Name: a.Name, Status: a.Status, Stock: a.Stock, UnitsLast30Days: units, DaysLeft: daysLeft(a.Stock, units),
+		})
+	}
+	sort.Slice(out.StockAlerts, func(i, j int) bool {
+		a, b := out.StockAlerts[i], out.StockAlerts[j]
+		if a.Stock != b.Stock {
+			return a.Stock < b.Stock
+		}
+		return a.AnimalID < b.AnimalID
+	})
+	return out, nil
+}
@@ -0,0 +1,290 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// orderAnimal is the short listing summary embedded in orders. Callers
+// must hold mu.
+func (m *MemoryStore) orderAnimal(a *models.Animal) *models.Animal {
+	return &models.Animal{
+		ID: a.ID, SellerID: a.SellerID, AnimalType: a.AnimalType, Breed: a.Breed,
+		Name: a.Name, Price: a.Price, ImageURL: a.ImageURL,
+	}
+}
+
+// orderLines returns the lines of an order, or of all its sub-orders when
+// it is a checkout header. Callers must hold mu.
+func (m *MemoryStore) orderLines(orderID int) []models.OrderItem {
+	var items []models.OrderItem
+	for _, item := range m.orderItems {
+		o := m.orders[item.OrderID]
+		if item.OrderID != orderID && (o.ParentID == nil || *o.ParentID != orderID) {
+			continue
+		}
+		item.Subtotal = item.UnitPrice * float64(item.Quantity)
+		if a, ok := m.animals[item.AnimalID]; ok {
+			item.Animal = m.orderAnimal(a)
+		}
+		if p, v := m.variant(item.VariantID); v != nil {
+			item.Variant = variantRef(p, v)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// cart returns the buyer's items checked against their listings and
+// variants. Callers must hold mu.
+func (m *MemoryStore) cart(userID int) []models.CartItem {
+	var items []models.CartItem
+	for _, item := range m.carts[userID] {
+		if item.VariantID != 0 {
+			p, v := m.variant(item.VariantID)
+			item.Variant = variantRef(p, v)
+			checkCartItem(&item, p.Status == ProductActive, v.Stock, v.Price)
+		} else {
+			a := m.animals[item.AnimalID]
+			item.Animal = m.listing(a)
+			checkCartItem(&item, a.Status == StatusAvailable, a.Stock, a.Price)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// cartSupply returns the price and stock of what key sells: ErrNotFound
+// for a missing or deleted listing or variant, ErrUnavailable for one not
+// on sale. Callers must hold mu.
+func (m *MemoryStore) cartSupply(key cartKey) (float64, int, error) {
+	if key.variantID != 0 {
+		p, v := m.variant(key.variantID)
+		switch {
+		case v == nil:
+			return 0, 0, ErrNotFound
+		case p.Status != ProductActive:
+			return 0, 0, ErrUnavailable
+		}
+		return v.Price, v.Stock, nil
+	}
+	a, ok := m.animals[key.animalID]
+	switch {
+	case !ok || a.Status == StatusDeleted:
+		return 0, 0, ErrNotFound
+	case a.Status != StatusAvailable:
+		return 0, 0, ErrUnavailable
+	}
+	return a.Price, a.Stock, nil
+}
+
+func (m *MemoryStore) GetCart(userID int) (*models.Cart, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return newCart(m.cart(userID)), nil
+}
+
+func (m *MemoryStore) AddCartItem(userID, animalID, quantity int) error {
+	return m.setCartItem(userID, cartKey{animalID: animalID}, quantity, true)
+}
+
+func (m *MemoryStore) UpdateCartItem(userID, animalID, quantity int) error {
+	return m.setCartItem(userID, cartKey{animalID: animalID}, quantity, false)
+}
+
+func (m *MemoryStore) AddCartVariant(userID, variantID, quantity int) error {
+	return m.setCartItem(userID, cartKey{variantID: variantID}, quantity, true)
+}
+
+func (m *MemoryStore) UpdateCartVariant(userID, variantID, quantity int) error {
+	return m.setCartItem(userID, cartKey{variantID: variantID}, quantity, false)
+}
+
+func (m *MemoryStore) setCartItem(userID int, key cartKey, quantity int, add bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	items := m.carts[userID]
+	i := -1
+	for j, item := range items {
+		if (cartKey{item.AnimalID, item.VariantID}) == key {
+			i = j
+		}
+	}
+	if i < 0 && !add {
+		return ErrNotFound
+	}
+	if i >= 0 && add {
+		quantity += items[i].Quantity
+	}
+
+	price, stock, err := m.cartSupply(key)
+	switch {
+	case err == ErrNotFound && !add:
+		return ErrUnavailable
+	case err != nil:
+		return err
+	case stock < quantity:
+		return ErrInsufficientStock
+	}
+
+	now := time.Now()
+	if i < 0 {
+		m.carts[userID] = append(items, models.CartItem{
+			ID: m.nextID("cart_items"), AnimalID: key.animalID, VariantID: key.variantID, Quantity: quantity,
+			AddedPrice: price, CreatedAt: now, UpdatedAt: now,
+		})
+		return nil
+	}
+	items[i].Quantity = quantity
+	items[i].AddedPrice = price
+	items[i].UpdatedAt = now
+	return nil
+}
+
+func (m *MemoryStore) RemoveCartItem(userID, animalID int) error {
+	return m.removeCartItem(userID, cartKey{animalID: animalID})
+}
+
+func (m *MemoryStore) RemoveCartVariant(userID, variantID int) error {
+	return m.removeCartItem(userID, cartKey{variantID: variantID})
+}
+
+func (m *MemoryStore) removeCartItem(userID int, key cartKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var kept []models.CartItem
+	for _, item := range m.carts[userID] {
+		if (cartKey{item.AnimalID, item.VariantID}) != key {
+			kept = append(kept, item)
+		}
+	}
+	m.carts[userID] = kept
+	return nil
+}
+
+func (m *MemoryStore) Checkout(buyerID int, opts CheckoutOptions) (*models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	items := m.cart(buyerID)
+	if len(items) == 0 {
+		return nil, ErrEmptyCart
+	}
+	if cart := newCart(items); !cart.CanCheckout {
+		for i, item := range items {
+			if item.Problem == models.CartPriceChanged {
+				m.carts[buyerID][i].AddedPrice = item.Price
+			}
+		}
+		return nil, &CartError{Cart: cart}
+	}
+
+	var quote *models.VoucherQuote
+	if opts.VoucherCode != "" {
+		var err error
+		if quote, err = m.quoteVoucher(buyerID, opts.VoucherCode, items); err != nil {
+			return nil, err
+		}
+	}
+
+	lines := make([]checkoutLine, len(items))
+	for i, item := range items {
+		sellerID, _ := itemSeller(item)
+		lines[i] = checkoutLine{CartItem: item, sellerID: sellerID}
+	}
+	groups := bySeller(lines)
+	discounts := make([]float64, len(groups))
+	shipments := make([]*models.Shipment, len(groups))
+	for i, group := range groups {
+		var err error
+		if shipments[i], err = bookedShipment(opts, group[0].sellerID); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	header := &models.Order{ID: m.nextID("orders"), BuyerID: buyerID, Status: "pending", CreatedAt: now, UpdatedAt: now}
+	for _, line := range lines {
+		header.TotalPrice += line.Subtotal
+		header.Quantity += line.Quantity
+	}
+	if quote != nil {
+		v := m.vouchers[quote.Voucher.ID]
+		discounts = checkoutDiscounts(quote, m.voucherCategories(quote.Voucher), groups)
+		voucherID := v.ID
+		header.Discount, header.VoucherID = quote.Discount, &voucherID
+		header.TotalPrice -= quote.Discount
+		v.UsedCount++
+		m.redemptions[header.ID] = v.ID
+	}
+	m.orders[header.ID] = header
+
+	out := *header
+	for i, group := range groups {
+		sellerID := group[0].sellerID
+		sub := &models.Order{
+			ID: m.nextID("orders"), BuyerID: buyerID, ParentID: &header.ID, SellerID: &sellerID,
+			Discount: discounts[i], Status: "pending", CreatedAt: now, UpdatedAt: now,
+		}
+		for _, line := range group {
+			sub.TotalPrice += line.Subtotal
+			sub.Quantity += line.Quantity
+		}
+		sub.TotalPrice -= sub.Discount
+		if shipment := shipments[i]; shipment != nil {
+			booked := *shipment
+			booked.ID, booked.OrderID, booked.Status = m.nextID("shipments"), sub.ID, ShipmentPending
+			booked.CreatedAt, booked.UpdatedAt = now, now
+			m.shipments[booked.ID] = &booked
+			sub.Shipping = booked.Cost
+			sub.TotalPrice += booked.Cost
+			header.Shipping += booked.Cost
+			header.TotalPrice += booked.Cost
+		}
+		m.orders[sub.ID] = sub
+
+		view := *sub
+		view.Shipment = m.shipmentOf(sub.ID)
+		for _, line := range group {
+			item := models.OrderItem{
+				ID: m.nextID("order_items"), OrderID: sub.ID, AnimalID: line.AnimalID, VariantID: line.VariantID,
+				SellerID: sellerID, Quantity: line.Quantity, UnitPrice: line.Price, CreatedAt: now,
+			}
+			m.orderItems = append(m.orderItems, item)
+			m.takeStock(item, now)
+
+			item.Subtotal = line.Subtotal
+			if p, v := m.variant(line.VariantID); v != nil {
+				item.Variant = variantRef(p, v)
+			} else {
+				item.Animal = m.orderAnimal(m.animals[line.AnimalID])
+			}
+			view.Items = append(view.Items, item)
+			out.Items = append(out.Items, item)
+		}
+		out.SubOrders = append(out.SubOrders, view)
+	}
+	out.TotalPrice, out.Shipping = header.TotalPrice, header.Shipping
+
+	delete(m.carts, buyerID)
+	return &out, nil
+}
+
+// takeStock removes what an order line sells from its listing, marking it
+// sold when none is left, or from its variant. Callers must hold mu.
+func (m *MemoryStore) takeStock(item models.OrderItem, now time.Time) {
+	if _, v := m.variant(item.VariantID); v != nil {
+		v.Stock -= item.Quantity
+		return
+	}
+	a := m.animals[item.AnimalID]
+	a.Stock -= item.Quantity
+	if a.Stock == 0 {
+		a.Status = StatusSold
+	}
+	a.UpdatedAt = now
+}
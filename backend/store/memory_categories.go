@@ -0,0 +1,117 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// cloneCategory copies c so callers cannot change the stored parent, names
+// or attributes.
+func cloneCategory(c models.Category) models.Category {
+	names := make(map[string]string, len(c.Names))
+	for lang, name := range c.Names {
+		names[lang] = name
+	}
+	c.Names = names
+	if c.ParentID != nil {
+		parent := *c.ParentID
+		c.ParentID = &parent
+	}
+	c.Attributes = append([]models.CategoryAttribute{}, c.Attributes...)
+	return c
+}
+
+// sortedCategories returns copies of every category in sort order. Callers
+// must hold mu.
+func (m *MemoryStore) sortedCategories() []models.Category {
+	categories := make([]models.Category, 0, len(m.categories))
+	for _, c := range m.categories {
+		categories = append(categories, cloneCategory(c))
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i].SortOrder < categories[j].SortOrder
+	})
+	return categories
+}
+
+// subcategories returns the id of a category and those of every category
+// under it. Callers must hold mu.
+func (m *MemoryStore) subcategories(id int) map[int]bool {
+	ids := map[int]bool{id: true}
+	for grew := true; grew; {
+		grew = false
+		for _, c := range m.categories {
+			if c.ParentID != nil && ids[*c.ParentID] && !ids[c.ID] {
+				ids[c.ID] = true
+				grew = true
+			}
+		}
+	}
+	return ids
+}
+
+// checkCategory returns ErrNotFound when c's parent does not exist and
+// ErrConflict when another category has its name. Callers must hold mu.
+func (m *MemoryStore) checkCategory(c *models.Category) error {
+	if c.ParentID != nil && m.category(*c.ParentID) == nil {
+		return ErrNotFound
+	}
+	for _, other := range m.categories {
+		if other.ID != c.ID && other.Name == c.Name {
+			return ErrConflict
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) AllCategories() ([]models.Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sortedCategories(), nil
+}
+
+func (m *MemoryStore) CreateCategory(c *models.Category) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkCategory(c); err != nil {
+		return err
+	}
+	c.ID = m.nextID("categories")
+	m.categories = append(m.categories, cloneCategory(*c))
+	return nil
+}
+
+func (m *MemoryStore) UpdateCategory(c *models.Category) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := m.category(c.ID)
+	if stored == nil {
+		return ErrNotFound
+	}
+	if err := m.checkCategory(c); err != nil {
+		return err
+	}
+	*stored = cloneCategory(*c)
+	return nil
+}
+
+func (m *MemoryStore) DeactivateCategory(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := m.category(id)
+	if stored == nil {
+		return ErrNotFound
+	}
+	for _, c := range m.categories {
+		if c.ParentID != nil && *c.ParentID == id && c.Active {
+			return ErrHasSubcategories
+		}
+	}
+	stored.Active = false
+	return nil
+}
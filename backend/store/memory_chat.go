@@ -0,0 +1,39 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (m *MemoryStore) CreateMessage(msg *models.Message) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[msg.ReceiverID]; !ok {
+		return 0, ErrNotFound
+	}
+	row := models.Message{SenderID: msg.SenderID, ReceiverID: msg.ReceiverID, Content: msg.Content}
+	row.ID = m.nextID("messages")
+	row.CreatedAt = time.Now()
+	m.messages = append(m.messages, row)
+	return row.ID, nil
+}
+
+func (m *MemoryStore) ListMessages(userID, partnerID int, p PageRequest) (Page[models.Message], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var messages []models.Message
+	for _, msg := range m.messages {
+		if (msg.SenderID == userID && msg.ReceiverID == partnerID) ||
+			(msg.SenderID == partnerID && msg.ReceiverID == userID) {
+			sender := m.userRef(msg.SenderID)
+			receiver := m.userRef(msg.ReceiverID)
+			msg.Sender = &models.User{ID: sender.ID, FullName: sender.FullName, AvatarURL: sender.AvatarURL}
+			msg.Receiver = &models.User{ID: receiver.ID, FullName: receiver.FullName, AvatarURL: receiver.AvatarURL}
+			messages = append(messages, msg)
+		}
+	}
+	return paginate(messages, p, oldestFirst("m.created_at"), func(msg models.Message) (interface{}, int) { return msg.CreatedAt, msg.ID })
+}
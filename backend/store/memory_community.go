@@ -0,0 +1,163 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (m *MemoryStore) CreatePost(p *models.Post) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row := models.Post{UserID: p.UserID, Content: p.Content}
+	row.ID = m.nextID("posts")
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	m.posts[row.ID] = &row
+
+	for i, media := range p.Media {
+		media.ID = m.nextID("post_media")
+		media.PostID = row.ID
+		media.SortOrder = i
+		media.CreatedAt = row.CreatedAt
+		m.postMedia = append(m.postMedia, media)
+	}
+	return row.ID, nil
+}
+
+// hydratePost fills in the author, counters and media for a stored post.
+// Callers must hold mu.
+func (m *MemoryStore) hydratePost(p models.Post, viewerID int) models.Post {
+	p.User = m.userRef(p.UserID)
+	p.Likes = countPairs(m.likes, p.ID)
+	p.BookmarksCount = countPairs(m.bookmarks, p.ID)
+	p.SharesCount = countPairs(m.shares, p.ID)
+	p.IsLiked = m.likes[pair{p.ID, viewerID}]
+	p.IsBookmarked = m.bookmarks[pair{p.ID, viewerID}]
+	p.CommentsCount = 0
+	for _, c := range m.comments {
+		if c.PostID == p.ID {
+			p.CommentsCount++
+		}
+	}
+	p.Media = []models.PostMedia{}
+	for _, media := range m.postMedia {
+		if media.PostID == p.ID {
+			p.Media = append(p.Media, media)
+		}
+	}
+	return p
+}
+
+func (m *MemoryStore) ListPosts(viewerID int, p PageRequest) (Page[models.Post], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	posts := make([]models.Post, 0, len(m.posts))
+	for _, post := range m.posts {
+		posts = append(posts, m.hydratePost(*post, viewerID))
+	}
+	return paginate(posts, p, newestFirst("p.created_at"), func(post models.Post) (interface{}, int) { return post.CreatedAt, post.ID })
+}
+
+func (m *MemoryStore) GetPost(id, viewerID int) (*models.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	post := m.hydratePost(*stored, viewerID)
+
+	for i := len(m.comments) - 1; i >= 0; i-- {
+		c := m.comments[i]
+		if c.PostID != id {
+			continue
+		}
+		c.User = m.userRef(c.UserID)
+		c.LikesCount = countPairs(m.commentLikes, c.ID)
+		c.IsLiked = m.commentLikes[pair{c.ID, viewerID}]
+		post.Comments = append(post.Comments, c)
+	}
+	return &post, nil
+}
+
+// togglePair inserts or deletes a row in a join table, enforcing the
+// foreign key on the parent row the way Postgres would.
+func (m *MemoryStore) togglePair(set map[pair]bool, parentExists bool, id, userID int, on bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !on {
+		delete(set, pair{id, userID})
+		return nil
+	}
+	if !parentExists {
+		return ErrNotFound
+	}
+	set[pair{id, userID}] = true
+	return nil
+}
+
+func (m *MemoryStore) postExists(id int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.posts[id]
+	return ok
+}
+
+func (m *MemoryStore) commentExists(id int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.comments {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryStore) LikePost(postID, userID int) error {
+	return m.togglePair(m.likes, m.postExists(postID), postID, userID, true)
+}
+
+func (m *MemoryStore) UnlikePost(postID, userID int) error {
+	return m.togglePair(m.likes, true, postID, userID, false)
+}
+
+func (m *MemoryStore) BookmarkPost(postID, userID int) error {
+	return m.togglePair(m.bookmarks, m.postExists(postID), postID, userID, true)
+}
+
+func (m *MemoryStore) UnbookmarkPost(postID, userID int) error {
+	return m.togglePair(m.bookmarks, true, postID, userID, false)
+}
+
+func (m *MemoryStore) SharePost(postID, userID int) error {
+	return m.togglePair(m.shares, m.postExists(postID), postID, userID, true)
+}
+
+func (m *MemoryStore) CreateComment(cm *models.Comment) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.posts[cm.PostID]; !ok {
+		return 0, ErrNotFound
+	}
+	row := models.Comment{PostID: cm.PostID, UserID: cm.UserID, Content: cm.Content}
+	row.ID = m.nextID("comments")
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	m.comments = append(m.comments, row)
+	return row.ID, nil
+}
+
+func (m *MemoryStore) LikeComment(commentID, userID int) error {
+	return m.togglePair(m.commentLikes, m.commentExists(commentID), commentID, userID, true)
+}
+
+func (m *MemoryStore) UnlikeComment(commentID, userID int) error {
+	return m.togglePair(m.commentLikes, true, commentID, userID, false)
+}
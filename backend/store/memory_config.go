@@ -0,0 +1,39 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (m *MemoryStore) GetActiveSplash(now time.Time) (*models.SplashEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var active *models.SplashEvent
+	for i := range m.splashEvents {
+		e := m.splashEvents[i]
+		if !e.IsActive || e.StartDate.After(now) || e.EndDate.Before(now) {
+			continue
+		}
+		if active == nil || e.StartDate.After(active.StartDate) {
+			active = &e
+		}
+	}
+	if active == nil {
+		return nil, ErrNotFound
+	}
+	return active, nil
+}
+
+func (m *MemoryStore) CreateSplashEvent(e *models.SplashEvent) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row := *e
+	row.ID = m.nextID("splash_events")
+	row.IsActive = true
+	row.CreatedAt = time.Now()
+	m.splashEvents = append(m.splashEvents, row)
+	return row.ID, nil
+}
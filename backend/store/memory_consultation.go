@@ -0,0 +1,125 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (m *MemoryStore) CreateVeterinarian(v *models.Veterinarian) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[v.UserID]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	row := *v
+	row.ID = m.nextID("veterinarians")
+	row.User = nil
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	m.vets[row.ID] = &row
+
+	u.UserType = "veterinarian"
+	return row.ID, nil
+}
+
+func (m *MemoryStore) ListVeterinarians(p PageRequest) (Page[models.Veterinarian], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var vets []models.Veterinarian
+	for _, v := range m.vets {
+		vet := *v
+		vet.User = m.userRef(v.UserID)
+		vets = append(vets, vet)
+	}
+	return paginate(vets, p, vetOrder, func(v models.Veterinarian) (interface{}, int) { return v.Rating, v.ID })
+}
+
+func (m *MemoryStore) GetVeterinarian(id int) (*models.Veterinarian, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, ok := m.vets[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	vet := *v
+	vet.User = m.userRef(v.UserID)
+	return &vet, nil
+}
+
+func (m *MemoryStore) CreateConsultation(co *models.Consultation) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.vets[co.VeterinarianID]; !ok {
+		return 0, ErrNotFound
+	}
+	row := *co
+	row.ID = m.nextID("consultations")
+	row.Status = "pending"
+	row.User = nil
+	row.Veterinarian = nil
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	m.consultations[row.ID] = &row
+	return row.ID, nil
+}
+
+// vetSummary returns the subset of veterinarian columns joined onto
+// consultation rows. Callers must hold mu.
+func (m *MemoryStore) vetSummary(id int) *models.Veterinarian {
+	vet := &models.Veterinarian{}
+	if v, ok := m.vets[id]; ok {
+		vet.ID = v.ID
+		vet.ClinicName = v.ClinicName
+		vet.Specialization = v.Specialization
+		vet.Phone = v.Phone
+	}
+	return vet
+}
+
+func (m *MemoryStore) ListConsultations(userID int, p PageRequest) (Page[models.Consultation], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var consultations []models.Consultation
+	for _, co := range m.consultations {
+		if co.UserID != userID {
+			continue
+		}
+		c := *co
+		c.Veterinarian = m.vetSummary(co.VeterinarianID)
+		consultations = append(consultations, c)
+	}
+	return paginate(consultations, p, newestFirst("co.created_at"), func(co models.Consultation) (interface{}, int) { return co.CreatedAt, co.ID })
+}
+
+func (m *MemoryStore) GetConsultation(id int) (*models.Consultation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	co, ok := m.consultations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *co
+	c.Veterinarian = m.vetSummary(co.VeterinarianID)
+	u := m.userRef(co.UserID)
+	c.User = &models.User{ID: u.ID, Username: u.Username, Email: u.Email, FullName: u.FullName}
+	return &c, nil
+}
+
+func (m *MemoryStore) UpdateConsultationStatus(id int, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if co, ok := m.consultations[id]; ok {
+		co.Status = status
+		co.UpdatedAt = time.Now()
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+type idemKey struct {
+	userID int
+	key    string
+}
+
+func (m *MemoryStore) ClaimIdempotencyKey(k *models.IdempotencyKey, ttl, lease time.Duration) (*models.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := idemKey{k.UserID, k.Key}
+	if existing, ok := m.idempotencyKeys[id]; ok && time.Since(existing.CreatedAt) < heldFor(existing, ttl, lease) {
+		rec := *existing
+		return &rec, ErrConflict
+	}
+
+	rec := *k
+	rec.Status = 0
+	rec.Body = nil
+	rec.CreatedAt = time.Now()
+	m.idempotencyKeys[id] = &rec
+	return nil, nil
+}
+
+func (m *MemoryStore) SaveIdempotentResponse(userID int, key string, status int, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rec, ok := m.idempotencyKeys[idemKey{userID, key}]; ok {
+		rec.Status = status
+		rec.Body = append([]byte(nil), body...)
+	}
+	return nil
+}
+
+func (m *MemoryStore) ReleaseIdempotencyKey(userID int, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.idempotencyKeys, idemKey{userID, key})
+	return nil
+}
+
+// heldFor is how long rec holds its key: ttl once a response is
+// stored, lease while the request is still in flight.
+func heldFor(rec *models.IdempotencyKey, ttl, lease time.Duration) time.Duration {
+	if rec.Status == 0 {
+		return lease
+	}
+	return ttl
+}
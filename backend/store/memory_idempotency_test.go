@@ -0,0 +1,34 @@
+package store
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// A key left in flight by a request that never finished is freed once its
+// lease runs out; a stored response is kept for the full TTL.
+func TestIdempotencyLease(t *testing.T) {
+	m := NewMemory()
+	k := &models.IdempotencyKey{UserID: 1, Key: "stuck", Fingerprint: "f"}
+
+	if _, err := m.ClaimIdempotencyKey(k, time.Hour, time.Hour); err != nil {
+		t.Fatalf("claim = %v", err)
+	}
+	if existing, err := m.ClaimIdempotencyKey(k, time.Hour, time.Hour); !errors.Is(err, ErrConflict) || existing.Status != 0 {
+		t.Fatalf("claim in flight = %+v, %v", existing, err)
+	}
+	if _, err := m.ClaimIdempotencyKey(k, time.Hour, 0); err != nil {
+		t.Fatalf("claim after lease = %v, want key reclaimed", err)
+	}
+
+	if err := m.SaveIdempotentResponse(1, "stuck", http.StatusCreated, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if existing, err := m.ClaimIdempotencyKey(k, time.Hour, 0); !errors.Is(err, ErrConflict) || existing.Status != http.StatusCreated {
+		t.Fatalf("claim of finished key = %+v, %v", existing, err)
+	}
+}
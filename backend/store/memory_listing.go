@@ -0,0 +1,177 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// ownListing returns a seller's listing. Callers must hold mu.
+func (m *MemoryStore) ownListing(id, sellerID int) (*models.Animal, error) {
+	a, ok := m.animals[id]
+	if !ok || a.Status == StatusDeleted {
+		return nil, ErrNotFound
+	}
+	if a.SellerID != sellerID {
+		return nil, ErrNotOwner
+	}
+	return a, nil
+}
+
+// listing returns a copy of a stored listing with its seller and
+// popularity filled in. Callers must hold mu.
+func (m *MemoryStore) listing(a *models.Animal) *models.Animal {
+	animal := *a
+	animal.Media = nil
+	animal.Seller = m.userRef(a.SellerID)
+	animal.Shop = m.shopRef(a.SellerID)
+	animal.Popularity = m.popularity(a.ID)
+	return &animal
+}
+
+func (m *MemoryStore) UpdateAnimal(id, sellerID int, u AnimalUpdate) (*models.Animal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, err := m.ownListing(id, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Status != nil && *u.Status == StatusAvailable && a.Stock <= 0 {
+		return nil, ErrInsufficientStock
+	}
+
+	now := time.Now()
+	if u.Price != nil && *u.Price != a.Price {
+		m.prices = append(m.prices, models.PriceChange{
+			ID: m.nextID("animal_price_history"), AnimalID: id, OldPrice: a.Price, NewPrice: *u.Price, ChangedAt: now,
+		})
+	}
+	set(&a.AnimalType, u.AnimalType)
+	set(&a.Breed, u.Breed)
+	set(&a.Name, u.Name)
+	set(&a.Age, u.Age)
+	set(&a.Description, u.Description)
+	set(&a.Price, u.Price)
+	set(&a.ImageURL, u.ImageURL)
+	set(&a.Location, u.Location)
+	set(&a.Color, u.Color)
+	set(&a.Gender, u.Gender)
+	set(&a.Status, u.Status)
+	if u.Attributes != nil {
+		a.Attributes = u.Attributes
+	}
+	if u.Refiled {
+		a.CategoryID = u.CategoryID
+	}
+	if u.Relocated {
+		a.Latitude, a.Longitude = coordinates(u.Position)
+	}
+	a.UpdatedAt = now
+	return m.listing(a), nil
+}
+
+// set copies *v into *dst unless v is nil.
+func set[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func (m *MemoryStore) DeleteAnimal(id, sellerID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, err := m.ownListing(id, sellerID)
+	if err != nil {
+		return err
+	}
+	a.Status = StatusDeleted
+	a.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *MemoryStore) RestockAnimal(id, sellerID, quantity int) (*models.Animal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, err := m.ownListing(id, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	a.Stock += quantity
+	if a.Status == StatusSold {
+		a.Status = StatusAvailable
+	}
+	a.UpdatedAt = time.Now()
+	return m.listing(a), nil
+}
+
+func (m *MemoryStore) ListSellerAnimals(sellerID int, status string, p PageRequest) (Page[models.Animal], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []models.Animal
+	for _, a := range m.animals {
+		if a.SellerID != sellerID || a.Status == StatusDeleted || (status != "" && a.Status != status) {
+			continue
+		}
+		rows = append(rows, *m.listing(a))
+	}
+	o := newestFirst("a.created_at")
+	return paginate(rows, p, o, animalKey(o))
+}
+
+func (m *MemoryStore) CountSellerAnimals(sellerID int) (*models.ListingCounts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var counts models.ListingCounts
+	for _, a := range m.animals {
+		if a.SellerID == sellerID {
+			addListingCount(&counts, a.Status, 1)
+		}
+	}
+	return &counts, nil
+}
+
+func (m *MemoryStore) AddAnimalMedia(animalID, sellerID int, media *models.AnimalMedia) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, err := m.ownListing(animalID, sellerID)
+	if err != nil {
+		return 0, err
+	}
+	row := *media
+	row.ID = m.nextID("animal_media")
+	row.AnimalID = animalID
+	row.CreatedAt = time.Now()
+	for _, existing := range m.animalMedia {
+		if existing.AnimalID == animalID {
+			row.SortOrder = max(row.SortOrder, existing.SortOrder+1)
+		}
+	}
+	m.animalMedia = append(m.animalMedia, row)
+	if a.ImageURL == "" {
+		a.ImageURL = row.MediaURL
+		a.UpdatedAt = time.Now()
+	}
+	return row.ID, nil
+}
+
+func (m *MemoryStore) ListPriceHistory(animalID int) ([]models.PriceChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if a, ok := m.animals[animalID]; !ok || a.Status == StatusDeleted {
+		return nil, ErrNotFound
+	}
+	history := []models.PriceChange{}
+	for i := len(m.prices) - 1; i >= 0; i-- {
+		if m.prices[i].AnimalID == animalID {
+			history = append(history, m.prices[i])
+		}
+	}
+	return history, nil
+}
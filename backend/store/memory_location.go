@@ -0,0 +1,49 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (m *MemoryStore) ListUnlocatedAnimals(afterID, limit int) ([]models.Animal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var listings []models.Animal
+	for _, a := range m.animals {
+		if a.ID > afterID && a.Status != StatusDeleted && a.Location != "" && a.Latitude == nil {
+			listings = append(listings, models.Animal{ID: a.ID, Location: a.Location})
+		}
+	}
+	sort.Slice(listings, func(i, j int) bool { return listings[i].ID < listings[j].ID })
+	if len(listings) > limit {
+		listings = listings[:limit]
+	}
+	return listings, nil
+}
+
+func (m *MemoryStore) LocateAnimal(id int, location string, p models.GeoPoint) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.animals[id]
+	if !ok || a.Location != location || a.Latitude != nil {
+		return false, nil
+	}
+	a.Latitude, a.Longitude = coordinates(&p)
+	return true, nil
+}
+
+func (m *MemoryStore) SetUserLocation(userID int, location string, p models.GeoPoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	u.Location = location
+	u.Latitude, u.Longitude = coordinates(&p)
+	return nil
+}
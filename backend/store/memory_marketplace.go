@@ -0,0 +1,383 @@
+package store
+
+import (
+	"strings"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// category returns category id, or nil if there is none. Callers must
+// hold mu.
+func (m *MemoryStore) category(id int) *models.Category {
+	for i := range m.categories {
+		if m.categories[i].ID == id {
+			return &m.categories[i]
+		}
+	}
+	return nil
+}
+
+// popularity counts orders and wishlist saves for an animal. Callers must
+// hold mu.
+func (m *MemoryStore) popularity(animalID int) int {
+	n := 0
+	for _, item := range m.orderItems {
+		if item.AnimalID == animalID {
+			n++
+		}
+	}
+	for _, w := range m.wishlists {
+		if w.AnimalID == animalID {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *MemoryStore) ListCategories() ([]models.Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var active []models.Category
+	for _, c := range m.sortedCategories() {
+		if c.Active {
+			active = append(active, c)
+		}
+	}
+	return active, nil
+}
+
+func (m *MemoryStore) ListAnimals(f AnimalFilter, p PageRequest) (Page[models.Animal], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o := animalOrder(f.Sort)
+	return paginate(m.matchAnimals(f), p, o, animalKey(o))
+}
+
+func (m *MemoryStore) AnimalFacets(f AnimalFilter) (*models.AnimalFacets, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := newFacetCounts()
+	for _, a := range m.matchAnimals(f) {
+		counts.add("animal_type", a.AnimalType, 1)
+		counts.add("breed", a.Breed, 1)
+		counts.add("color", a.Color, 1)
+		counts.add("gender", a.Gender, 1)
+		counts.add("location", a.Location, 1)
+		counts.prices[priceBucket(a.Price)]++
+	}
+	return counts.facets(), nil
+}
+
+// matchAnimals returns the available listings matching f, unsorted.
+// Callers must hold mu.
+func (m *MemoryStore) matchAnimals(f AnimalFilter) []models.Animal {
+	terms := searchTerms(f.Search)
+	categories := m.subcategories(f.CategoryID)
+	var matched []models.Animal
+	for _, a := range m.animals {
+		if a.Status != "available" {
+			continue
+		}
+		if f.AnimalType != "" && !containsFold(a.AnimalType, f.AnimalType) {
+			continue
+		}
+		relevance := 0.0
+		if len(terms) > 0 {
+			if relevance = searchRelevance(a, terms, strings.Join(terms, " ")); relevance == 0 {
+				continue
+			}
+		}
+		if f.Breed != "" && !containsFold(a.Breed, f.Breed) {
+			continue
+		}
+		if f.MinPrice != nil && a.Price < *f.MinPrice {
+			continue
+		}
+		if f.MaxPrice != nil && a.Price > *f.MaxPrice {
+			continue
+		}
+		if f.Gender != "" && !strings.EqualFold(a.Gender, f.Gender) {
+			continue
+		}
+		if f.Color != "" && !strings.EqualFold(a.Color, f.Color) {
+			continue
+		}
+		if f.Location != "" && !containsFold(a.Location, f.Location) {
+			continue
+		}
+		if f.MinAge != nil && a.Age < *f.MinAge {
+			continue
+		}
+		if f.MaxAge != nil && a.Age > *f.MaxAge {
+			continue
+		}
+		if f.MinRating != nil && a.Rating < *f.MinRating {
+			continue
+		}
+		if f.SellerID != 0 && a.SellerID != f.SellerID {
+			continue
+		}
+		if f.CategoryID != 0 && (a.CategoryID == nil || !categories[*a.CategoryID]) {
+			continue
+		}
+		if f.FollowedBy != 0 && !m.followsSeller(f.FollowedBy, a.SellerID) {
+			continue
+		}
+		if f.InStock && a.Stock <= 0 {
+			continue
+		}
+		var distance *float64
+		if f.Near != nil {
+			at := position(a.Latitude, a.Longitude)
+			if at == nil {
+				continue
+			}
+			d := distanceKm(*f.Near, *at)
+			if d > f.RadiusKm {
+				continue
+			}
+			distance = &d
+		}
+		animal := *a
+		animal.Media = nil
+		animal.Seller = m.userRef(a.SellerID)
+		animal.Shop = m.shopRef(a.SellerID)
+		animal.Popularity = m.popularity(a.ID)
+		animal.Relevance = relevance
+		animal.DistanceKm = distance
+		matched = append(matched, animal)
+	}
+	return matched
+}
+
+func (m *MemoryStore) SuggestAnimals(q string, limit int) ([]models.SearchSuggestion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lq := strings.ToLower(q)
+	var found []models.SearchSuggestion
+	for _, a := range m.animals {
+		if a.Status != "available" {
+			continue
+		}
+		for _, sg := range []models.SearchSuggestion{
+			{Text: a.Name, Kind: "name"}, {Text: a.Breed, Kind: "breed"}, {Text: a.AnimalType, Kind: "animal_type"},
+		} {
+			if sg.Text == "" {
+				continue
+			}
+			if strings.HasPrefix(strings.ToLower(sg.Text), lq) || wordSimilarity(q, sg.Text) >= similarityThreshold {
+				found = append(found, sg)
+			}
+		}
+	}
+	return rankSuggestions(found, q, limit), nil
+}
+
+func (m *MemoryStore) GetAnimal(id int) (*models.Animal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.animals[id]
+	if !ok || stored.Status == StatusDeleted {
+		return nil, ErrNotFound
+	}
+	animal := *stored
+	animal.Seller = m.userRef(animal.SellerID)
+	animal.Shop = m.shopRef(animal.SellerID)
+	animal.Popularity = m.popularity(id)
+	animal.Media = nil
+	for _, media := range m.animalMedia {
+		if media.AnimalID == id {
+			animal.Media = append(animal.Media, media)
+		}
+	}
+	return &animal, nil
+}
+
+func (m *MemoryStore) CreateAnimal(a *models.Animal) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row := *a
+	row.ID = m.nextID("animals")
+	row.Status = "available"
+	row.Media = nil
+	row.Seller = nil
+	row.Shop = nil
+	row.Latitude, row.Longitude = coordinates(position(a.Latitude, a.Longitude))
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	m.animals[row.ID] = &row
+	return row.ID, nil
+}
+
+func (m *MemoryStore) CreateOrder(buyerID, animalID, quantity int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	animal, ok := m.animals[animalID]
+	if !ok || animal.Status == StatusDeleted {
+		return 0, ErrNotFound
+	}
+	if animal.Stock < quantity {
+		return 0, ErrInsufficientStock
+	}
+	if animal.Status != StatusAvailable {
+		return 0, ErrUnavailable
+	}
+
+	sellerID := animal.SellerID
+	order := &models.Order{
+		BuyerID:    buyerID,
+		AnimalID:   animalID,
+		SellerID:   &sellerID,
+		TotalPrice: animal.Price * float64(quantity),
+		Status:     "pending",
+		Quantity:   quantity,
+	}
+	order.ID = m.nextID("orders")
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	m.orders[order.ID] = order
+	m.orderItems = append(m.orderItems, models.OrderItem{
+		ID: m.nextID("order_items"), OrderID: order.ID, AnimalID: animalID, SellerID: sellerID,
+		Quantity: quantity, UnitPrice: animal.Price, CreatedAt: order.CreatedAt,
+	})
+
+	animal.Stock -= quantity
+	if animal.Stock == 0 {
+		animal.Status = "sold"
+	}
+	return order.ID, nil
+}
+
+func (m *MemoryStore) CreateVariantOrder(buyerID, variantID, quantity int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	product, variant := m.variant(variantID)
+	if variant == nil {
+		return 0, ErrNotFound
+	}
+	if variant.Stock < quantity {
+		return 0, ErrInsufficientStock
+	}
+	if product.Status != ProductActive {
+		return 0, ErrUnavailable
+	}
+
+	sellerID := product.SellerID
+	order := &models.Order{
+		BuyerID:    buyerID,
+		SellerID:   &sellerID,
+		TotalPrice: variant.Price * float64(quantity),
+		Status:     "pending",
+		Quantity:   quantity,
+	}
+	order.ID = m.nextID("orders")
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	m.orders[order.ID] = order
+	item := models.OrderItem{
+		ID: m.nextID("order_items"), OrderID: order.ID, VariantID: variantID, SellerID: sellerID,
+		Quantity: quantity, UnitPrice: variant.Price, CreatedAt: order.CreatedAt,
+	}
+	m.orderItems = append(m.orderItems, item)
+	m.takeStock(item, order.CreatedAt)
+	return order.ID, nil
+}
+
+func (m *MemoryStore) GetOrder(id, buyerID int) (*models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[id]
+	if !ok || order.BuyerID != buyerID {
+		return nil, ErrNotFound
+	}
+	o := *order
+	return &o, nil
+}
+
+func (m *MemoryStore) ListOrders(buyerID int, p PageRequest) (Page[models.Order], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var orders []models.Order
+	for _, o := range m.orders {
+		if o.BuyerID != buyerID || o.ParentID != nil {
+			continue
+		}
+		order := *o
+		if a, ok := m.animals[o.AnimalID]; ok {
+			order.Animal = m.orderAnimal(a)
+		}
+		order.Items = m.orderLines(o.ID)
+		orders = append(orders, order)
+	}
+	return paginate(orders, p, newestFirst("o.created_at"), func(o models.Order) (interface{}, int) { return o.CreatedAt, o.ID })
+}
+
+func (m *MemoryStore) AddToWishlist(userID, animalID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.animals[animalID]
+	if !ok {
+		return ErrNotFound
+	}
+	for _, w := range m.wishlists {
+		if w.UserID == userID && w.AnimalID == animalID {
+			return nil
+		}
+	}
+	w := models.Wishlist{
+		UserID: userID, AnimalID: animalID, AlertPrice: a.Price, AlertInStock: inStock(a.Status, a.Stock), CreatedAt: time.Now(),
+	}
+	w.ID = m.nextID("wishlists")
+	m.wishlists = append(m.wishlists, w)
+	return nil
+}
+
+func (m *MemoryStore) RemoveFromWishlist(userID, animalID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.wishlists[:0]
+	for _, w := range m.wishlists {
+		if w.UserID != userID || w.AnimalID != animalID {
+			kept = append(kept, w)
+		}
+	}
+	m.wishlists = kept
+	return nil
+}
+
+func (m *MemoryStore) ListWishlist(userID int, p PageRequest) (Page[models.Wishlist], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var wishlist []models.Wishlist
+	for i := len(m.wishlists) - 1; i >= 0; i-- {
+		w := m.wishlists[i]
+		a, ok := m.animals[w.AnimalID]
+		if w.UserID != userID || !ok {
+			continue
+		}
+		w.Animal = &models.Animal{
+			ID: a.ID, AnimalType: a.AnimalType, Name: a.Name, Price: a.Price, ImageURL: a.ImageURL,
+			Status: a.Status, Color: a.Color, Gender: a.Gender, Stock: a.Stock,
+		}
+		wishlist = append(wishlist, w)
+	}
+	return paginate(wishlist, p, newestFirst("w.created_at"), func(w models.Wishlist) (interface{}, int) { return w.CreatedAt, w.ID })
+}
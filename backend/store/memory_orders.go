@@ -0,0 +1,199 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// subOrders returns the sub-orders of a checkout header in id order.
+// Callers must hold mu.
+func (m *MemoryStore) subOrders(id int) []*models.Order {
+	var subs []*models.Order
+	for _, o := range m.orders {
+		if o.ParentID != nil && *o.ParentID == id {
+			subs = append(subs, o)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+	return subs
+}
+
+// orderDetails returns a copy of an order with its lines, shipment,
+// sub-orders and history. Callers must hold mu.
+func (m *MemoryStore) orderDetails(id int) *models.Order {
+	order := *m.orders[id]
+	order.Items = m.orderLines(id)
+	order.Shipment = m.shipmentOf(id)
+	for _, o := range m.subOrders(id) {
+		sub := *o
+		sub.Items = m.orderLines(o.ID)
+		sub.Shipment = m.shipmentOf(o.ID)
+		order.SubOrders = append(order.SubOrders, sub)
+	}
+	for _, h := range m.orderLog {
+		if h.OrderID == id {
+			order.History = append(order.History, h)
+		}
+	}
+	return &order
+}
+
+func (m *MemoryStore) GetOrderDetails(id, userID int) (*models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.orders[id]
+	if !ok || userID == 0 || orderRole(userID, o.BuyerID, o.SellerID) == "" {
+		return nil, ErrNotFound
+	}
+	return m.orderDetails(id), nil
+}
+
+func (m *MemoryStore) UpdateOrderStatus(id, userID int, status, note string) (*models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.updateOrderStatus(id, userID, status, note); err != nil {
+		return nil, err
+	}
+	return m.orderDetails(id), nil
+}
+
+// updateOrderStatus is UpdateOrderStatus without the result. Callers must
+// hold mu.
+func (m *MemoryStore) updateOrderStatus(id, userID int, status, note string) error {
+	order, ok := m.orders[id]
+	if !ok {
+		return ErrNotFound
+	}
+	role := orderRole(userID, order.BuyerID, order.SellerID)
+	if role == "" {
+		return ErrNotFound
+	}
+	var actor *int
+	if userID != 0 {
+		actor = &userID
+	}
+
+	if order.SellerID == nil {
+		var moving []*models.Order
+		for _, sub := range m.subOrders(id) {
+			if sub.Status == status || closed(sub.Status) {
+				continue
+			}
+			if err := checkTransition(sub.Status, status, role); err != nil {
+				return err
+			}
+			moving = append(moving, sub)
+		}
+		if len(moving) == 0 {
+			return ErrInvalidTransition
+		}
+		for _, sub := range moving {
+			m.moveOrder(sub, status, role, actor, note)
+		}
+		m.rollupOrder(order, role, actor)
+	} else {
+		if err := checkTransition(order.Status, status, role); err != nil {
+			return err
+		}
+		if order.ParentID != nil {
+			if err := checkSubOrder(m.orders[*order.ParentID].Status, status); err != nil {
+				return err
+			}
+		}
+		m.moveOrder(order, status, role, actor, note)
+		if order.ParentID != nil {
+			m.rollupOrder(m.orders[*order.ParentID], role, actor)
+		}
+	}
+	return nil
+}
+
+// rollupOrder mirrors the Postgres rollupOrder. Callers must hold mu.
+func (m *MemoryStore) rollupOrder(header *models.Order, role string, actor *int) {
+	var statuses []string
+	for _, o := range m.subOrders(header.ID) {
+		statuses = append(statuses, o.Status)
+	}
+	if status := rollupStatus(statuses); status != header.Status {
+		m.moveOrder(header, status, role, actor, "")
+	}
+}
+
+// moveOrder mirrors the Postgres moveOrder. Callers must hold mu.
+func (m *MemoryStore) moveOrder(o *models.Order, status, role string, actor *int, note string) {
+	now := time.Now()
+	m.orderLog = append(m.orderLog, models.OrderStatusChange{
+		ID: m.nextID("order_status_history"), OrderID: o.ID, FromStatus: o.Status, ToStatus: status,
+		ChangedBy: actor, Role: role, Note: note, CreatedAt: now,
+	})
+	o.Status = status
+	o.UpdatedAt = now
+	if status == OrderCancelled && o.ParentID == nil {
+		for _, p := range m.payments {
+			if p.OrderID == o.ID && p.Status == PaymentPending {
+				p.Status, p.UpdatedAt = PaymentExpired, now
+			}
+		}
+		m.releaseVoucher(o.ID)
+	}
+	if o.SellerID == nil {
+		return
+	}
+
+	if status == OrderRefunded {
+		for _, p := range m.payments {
+			if p.OrderID == paymentOrderID(*o) && p.Status == PaymentPaid {
+				m.queueRefund(p, o.ID, o.TotalPrice)
+			}
+		}
+	}
+
+	if restocks(status) {
+		for _, item := range m.orderItems {
+			if item.OrderID != o.ID {
+				continue
+			}
+			if _, v := m.variant(item.VariantID); v != nil {
+				v.Stock += item.Quantity
+				continue
+			}
+			a := m.animals[item.AnimalID]
+			a.Stock += item.Quantity
+			if a.Status == StatusSold {
+				a.Status = StatusAvailable
+			}
+			a.UpdatedAt = now
+		}
+	}
+
+	notice := orderNotice(o.ID, status)
+	for _, userID := range noticeRecipients(role, o.BuyerID, *o.SellerID) {
+		n := notice
+		n.ID = m.nextID("notifications")
+		n.UserID = userID
+		n.CreatedAt = now
+		m.notifications = append(m.notifications, n)
+	}
+}
+
+func (m *MemoryStore) ListSellerOrders(sellerID int, status string, p PageRequest) (Page[models.Order], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var orders []models.Order
+	for _, o := range m.orders {
+		if o.SellerID == nil || *o.SellerID != sellerID || (status != "" && o.Status != status) {
+			continue
+		}
+		order := *o
+		buyer := m.userRef(o.BuyerID)
+		order.Buyer = &models.User{ID: buyer.ID, Username: buyer.Username, FullName: buyer.FullName, AvatarURL: buyer.AvatarURL}
+		order.Items = m.orderLines(o.ID)
+		orders = append(orders, order)
+	}
+	return paginate(orders, p, newestFirst("o.created_at"), func(o models.Order) (interface{}, int) { return o.CreatedAt, o.ID })
+}
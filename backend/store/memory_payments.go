@@ -0,0 +1,169 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (m *MemoryStore) CreatePayment(p *models.Payment, buyerID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[p.OrderID]
+	if !ok || order.BuyerID != buyerID {
+		return ErrNotFound
+	}
+	if order.ParentID != nil || order.Status != OrderPending {
+		return ErrInvalidTransition
+	}
+	for _, other := range m.payments {
+		if other.OrderID == order.ID && other.Status == PaymentPending {
+			return ErrConflict
+		}
+	}
+
+	now := time.Now()
+	p.ID = m.nextID("payments")
+	p.Reference = paymentReference(p.ID)
+	p.Amount, p.Status = order.TotalPrice, PaymentPending
+	p.CreatedAt, p.UpdatedAt = now, now
+	stored := *p
+	m.payments[p.ID] = &stored
+	return nil
+}
+
+func (m *MemoryStore) SavePaymentCharge(p *models.Payment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.payments[p.ID]
+	if !ok || stored.Status != PaymentPending {
+		return ErrNotFound
+	}
+	stored.ChargeID, stored.VANumber, stored.QRString, stored.Status = p.ChargeID, p.VANumber, p.QRString, p.Status
+	if p.ExpiresAt != nil {
+		stored.ExpiresAt = p.ExpiresAt
+	}
+	stored.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *MemoryStore) ListOrderPayments(orderID, buyerID int) ([]models.Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[orderID]
+	if !ok || order.BuyerID != buyerID {
+		return nil, ErrNotFound
+	}
+	payments := []models.Payment{}
+	for _, p := range m.payments {
+		if p.OrderID == orderID {
+			payments = append(payments, *p)
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool { return payments[i].ID > payments[j].ID })
+	return payments, nil
+}
+
+func (m *MemoryStore) ApplyPaymentEvent(e *models.PaymentEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := [2]string{e.Provider, e.EventID}
+	if m.paymentLog[key] {
+		return ErrConflict
+	}
+	var p *models.Payment
+	for _, candidate := range m.payments {
+		if candidate.Provider == e.Provider && candidate.ChargeID == e.ChargeID {
+			p = candidate
+		}
+	}
+	if p == nil {
+		return ErrNotFound
+	}
+	m.paymentLog[key] = true
+
+	now := time.Now()
+	switch {
+	case e.Status == PaymentPaid && p.Status != PaymentPaid && !paymentMatches(*p, e):
+		p.Status, p.UpdatedAt = PaymentMismatch, now
+		return ErrPaymentMismatch
+	case e.Status == PaymentPaid && p.Status != PaymentPaid:
+		p.Status, p.PaidAt, p.UpdatedAt = PaymentPaid, &now, now
+		err := m.updateOrderStatus(p.OrderID, 0, OrderPaid, "Paid by "+p.Method)
+		if err == ErrInvalidTransition {
+			// The order was cancelled before the money arrived.
+			m.queueRefund(p, p.OrderID, p.Amount)
+			err = nil
+		}
+		return err
+	case (e.Status == PaymentExpired || e.Status == PaymentFailed) && p.Status == PaymentPending:
+		p.Status, p.UpdatedAt = e.Status, now
+	}
+	return nil
+}
+
+// queueRefund records that amount is owed back on p for orderID. Callers
+// must hold mu.
+func (m *MemoryStore) queueRefund(p *models.Payment, orderID int, amount float64) {
+	m.refunds = append(m.refunds, &models.PaymentRefund{
+		ID: m.nextID("payment_refunds"), PaymentID: p.ID, OrderID: orderID, Amount: amount,
+		Status: "pending", CreatedAt: time.Now(),
+	})
+}
+
+func (m *MemoryStore) ExpireUnpaidOrders(cutoff time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []int
+	for _, o := range m.orders {
+		if o.ParentID == nil && o.Status == OrderPending && o.CreatedAt.Before(cutoff) {
+			ids = append(ids, o.ID)
+		}
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if err := m.updateOrderStatus(id, 0, OrderCancelled, "Not paid in time"); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+func (m *MemoryStore) ListPendingRefunds(limit int) ([]models.PaymentRefund, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var refunds []models.PaymentRefund
+	for _, r := range m.refunds {
+		if r.Status != "pending" || len(refunds) == limit {
+			continue
+		}
+		refund := *r
+		refund.Provider, refund.ChargeID = m.payments[r.PaymentID].Provider, m.payments[r.PaymentID].ChargeID
+		refunds = append(refunds, refund)
+	}
+	return refunds, nil
+}
+
+func (m *MemoryStore) CompleteRefund(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.refunds {
+		if r.ID == id && r.Status == "pending" {
+			now := time.Now()
+			r.Status, r.RefundedAt = "done", &now
+			p := m.payments[r.PaymentID]
+			p.RefundedAmount += r.Amount
+			p.UpdatedAt = now
+			return nil
+		}
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// product returns a copy of a stored product with its seller and summary
+// filled in, and its variants cheapest first. Callers must hold mu.
+func (m *MemoryStore) product(p *models.Product) models.Product {
+	out := *p
+	out.Variants = append([]models.ProductVariant{}, p.Variants...)
+	sort.SliceStable(out.Variants, func(i, j int) bool { return out.Variants[i].Price < out.Variants[j].Price })
+	out.Seller = m.userRef(p.SellerID)
+	out.Shop = m.shopRef(p.SellerID)
+	summarize(&out)
+	return out
+}
+
+func (m *MemoryStore) ListProducts(f ProductFilter, p PageRequest) (Page[models.Product], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	categories := m.subcategories(f.CategoryID)
+	var matched []models.Product
+	for _, stored := range m.products {
+		if stored.Status != ProductActive {
+			continue
+		}
+		if f.Search != "" && !containsFold(stored.Name, f.Search) && !containsFold(stored.Brand, f.Search) {
+			continue
+		}
+		if f.Brand != "" && !strings.EqualFold(stored.Brand, f.Brand) {
+			continue
+		}
+		if f.CategoryID != 0 && !categories[stored.CategoryID] {
+			continue
+		}
+		if f.SellerID != 0 && stored.SellerID != f.SellerID {
+			continue
+		}
+		product := m.product(stored)
+		if f.InStock && product.Stock <= 0 {
+			continue
+		}
+		matched = append(matched, product)
+	}
+	o := productOrder(f.Sort)
+	return paginate(matched, p, o, productKey(o))
+}
+
+func (m *MemoryStore) GetProduct(id int) (*models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.products[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	product := m.product(stored)
+	return &product, nil
+}
+
+func (m *MemoryStore) CreateProduct(p *models.Product) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.category(p.CategoryID) == nil {
+		return ErrNotFound
+	}
+	for _, other := range m.products {
+		if other.SellerID == p.SellerID && other.SKU == p.SKU {
+			return ErrConflict
+		}
+	}
+	p.ID = m.nextID("products")
+	if p.Status == "" {
+		p.Status = ProductActive
+	}
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+	for i := range p.Variants {
+		p.Variants[i].ID = m.nextID("product_variants")
+		p.Variants[i].ProductID = p.ID
+	}
+	stored := *p
+	stored.Variants = append([]models.ProductVariant(nil), p.Variants...)
+	stored.Seller, stored.Shop = nil, nil
+	m.products[p.ID] = &stored
+	return nil
+}
+
+func (m *MemoryStore) UpdateVariant(productID, variantID, sellerID int, u VariantUpdate) (*models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.products[productID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if stored.SellerID != sellerID {
+		return nil, ErrNotOwner
+	}
+	for i := range stored.Variants {
+		if v := &stored.Variants[i]; v.ID == variantID {
+			set(&v.Price, u.Price)
+			set(&v.Stock, u.Stock)
+			stored.UpdatedAt = time.Now()
+			product := m.product(stored)
+			return &product, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// variant returns a stored variant and its product, or nils. Callers must
+// hold mu.
+func (m *MemoryStore) variant(id int) (*models.Product, *models.ProductVariant) {
+	for _, p := range m.products {
+		for i := range p.Variants {
+			if p.Variants[i].ID == id {
+				return p, &p.Variants[i]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// variantRef is the summary of variant v of product p.
+func variantRef(p *models.Product, v *models.ProductVariant) *models.VariantRef {
+	return &models.VariantRef{
+		ID: v.ID, ProductID: p.ID, SellerID: p.SellerID, CategoryID: p.CategoryID, SKU: v.SKU,
+		Name: p.Name, Brand: p.Brand, Size: v.Size, Flavour: v.Flavour, ImageURL: p.ImageURL,
+		Location: p.Location, Status: p.Status, Price: v.Price, Stock: v.Stock,
+	}
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (m *MemoryStore) ListUserPets(ownerID int, p PageRequest) (Page[models.UserPet], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pets []models.UserPet
+	for _, pet := range m.pets {
+		if pet.OwnerID == ownerID {
+			pets = append(pets, pet)
+		}
+	}
+	return paginate(pets, p, newestFirst("created_at"), func(pet models.UserPet) (interface{}, int) { return pet.CreatedAt, pet.ID })
+}
+
+func (m *MemoryStore) CreateUserPet(p *models.UserPet) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row := *p
+	row.ID = m.nextID("user_pets")
+	row.CreatedAt = time.Now()
+	m.pets = append(m.pets, row)
+	return row.ID, nil
+}
+
+func (m *MemoryStore) ListMedicalRecords(ownerID int, p PageRequest) (Page[models.MedicalRecord], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	petsByID := map[int]models.UserPet{}
+	for _, pet := range m.pets {
+		if pet.OwnerID == ownerID {
+			petsByID[pet.ID] = pet
+		}
+	}
+
+	var records []models.MedicalRecord
+	for _, r := range m.medicalRecords {
+		pet, ok := petsByID[r.PetID]
+		if !ok {
+			continue
+		}
+		vet := models.Veterinarian{User: &models.User{}}
+		if v, ok := m.vets[r.VeterinarianID]; ok {
+			vet.ClinicName = v.ClinicName
+			vet.User = m.userRef(v.UserID)
+		}
+		r.Pet = &models.UserPet{ID: pet.ID, Name: pet.Name, AnimalType: pet.AnimalType}
+		r.Veterinarian = &vet
+		records = append(records, r)
+	}
+	return paginate(records, p, medicalRecordOrder, func(r models.MedicalRecord) (interface{}, int) { return r.Date, r.ID })
+}
+
+func (m *MemoryStore) ListNotifications(userID int, p PageRequest) (Page[models.Notification], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var notifications []models.Notification
+	for _, n := range m.notifications {
+		if n.UserID == userID {
+			notifications = append(notifications, n)
+		}
+	}
+	return paginate(notifications, p, newestFirst("created_at"), func(n models.Notification) (interface{}, int) { return n.CreatedAt, n.ID })
+}
+
+func (m *MemoryStore) CountUserPets(ownerID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, p := range m.pets {
+		if p.OwnerID == ownerID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryStore) CountUserOrders(buyerID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, o := range m.orders {
+		if o.BuyerID == buyerID && o.ParentID == nil {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (m *MemoryStore) ListRecommendable() ([]models.Animal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var listings []models.Animal
+	for _, a := range m.animals {
+		if a.Status != StatusDeleted {
+			listings = append(listings, models.Animal{
+				ID: a.ID, SellerID: a.SellerID, AnimalType: a.AnimalType, Breed: a.Breed,
+				Location: a.Location, Price: a.Price, Status: a.Status, Stock: a.Stock,
+			})
+		}
+	}
+	sort.Slice(listings, func(i, j int) bool { return listings[i].ID < listings[j].ID })
+	return listings, nil
+}
+
+// interest is a listing a user ordered or saved, and when.
+type interest struct {
+	animalID int
+	at       time.Time
+}
+
+// interests returns the listings each user ordered or saved, latest first.
+// Callers must hold mu.
+func (m *MemoryStore) interests() map[int][]interest {
+	byUser := map[int][]interest{}
+	for _, item := range m.orderItems {
+		if item.AnimalID == 0 {
+			continue
+		}
+		o := m.orders[item.OrderID]
+		byUser[o.BuyerID] = append(byUser[o.BuyerID], interest{item.AnimalID, o.CreatedAt})
+	}
+	for _, w := range m.wishlists {
+		byUser[w.UserID] = append(byUser[w.UserID], interest{w.AnimalID, w.CreatedAt})
+	}
+	for _, list := range byUser {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].at.After(list[j].at) })
+	}
+	return byUser
+}
+
+func (m *MemoryStore) ListInterests(perUser int) ([][]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := m.interests()
+	ids := make([]int, 0, len(users))
+	for id := range users {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	var baskets [][]int
+	for _, id := range ids {
+		var basket []int
+		for _, in := range users[id] {
+			if len(basket) == perUser {
+				break
+			}
+			basket = append(basket, in.animalID)
+		}
+		baskets = append(baskets, basket)
+	}
+	return baskets, nil
+}
+
+func (m *MemoryStore) ReplaceSimilarities(sims []models.Similarity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.similar = map[int][]models.Similarity{}
+	for _, s := range sims {
+		m.similar[s.AnimalID] = append(m.similar[s.AnimalID], s)
+	}
+	for _, list := range m.similar {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Score > list[j].Score })
+	}
+	return nil
+}
+
+// forSale returns a listing that can be recommended. Callers must hold mu.
+func (m *MemoryStore) forSale(id int) (*models.Animal, bool) {
+	a, ok := m.animals[id]
+	if !ok || !inStock(a.Status, a.Stock) {
+		return nil, false
+	}
+	return m.listing(a), true
+}
+
+func (m *MemoryStore) SimilarAnimals(animalID, limit int) ([]models.Recommendation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	recs := []models.Recommendation{}
+	for _, s := range m.similar[animalID] {
+		if len(recs) == limit {
+			break
+		}
+		if a, ok := m.forSale(s.SimilarID); ok {
+			recs = append(recs, models.Recommendation{Animal: a, Score: s.Score, Reasons: s.Reasons})
+		}
+	}
+	return recs, nil
+}
+
+func (m *MemoryStore) RecommendAnimals(userID, limit int) ([]models.Recommendation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seeds := map[int]bool{}
+	for _, in := range m.interests()[userID] {
+		if len(seeds) == maxRecommendationSeeds {
+			break
+		}
+		seeds[in.animalID] = true
+	}
+
+	// Sum the matches of every seed; the strongest one explains it.
+	byID := map[int]*models.Recommendation{}
+	best := map[int]float64{}
+	for seed := range seeds {
+		for _, s := range m.similar[seed] {
+			if seeds[s.SimilarID] {
+				continue
+			}
+			rec, ok := byID[s.SimilarID]
+			if !ok {
+				rec = &models.Recommendation{}
+				byID[s.SimilarID] = rec
+			}
+			rec.Score += s.Score
+			if s.Score > best[s.SimilarID] || (s.Score == best[s.SimilarID] && seed < *rec.BecauseOf) {
+				best[s.SimilarID] = s.Score
+				from := seed
+				rec.BecauseOf, rec.Reasons = &from, s.Reasons
+			}
+		}
+	}
+	var recs []models.Recommendation
+	for id, rec := range byID {
+		if a, ok := m.forSale(id); ok && a.SellerID != userID {
+			rec.Animal, rec.Score = a, roundScore(rec.Score)
+			recs = append(recs, *rec)
+		}
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		return recs[i].Animal.ID < recs[j].Animal.ID
+	})
+	if len(recs) > limit {
+		recs = recs[:limit]
+	}
+
+	if len(recs) < limit {
+		taken := map[int]bool{}
+		for _, r := range recs {
+			taken[r.Animal.ID] = true
+		}
+		var popular []models.Animal
+		for id := range m.animals {
+			if a, ok := m.forSale(id); ok && !seeds[id] && !taken[id] && a.SellerID != userID {
+				popular = append(popular, *a)
+			}
+		}
+		sort.Slice(popular, func(i, j int) bool {
+			if popular[i].Popularity != popular[j].Popularity {
+				return popular[i].Popularity > popular[j].Popularity
+			}
+			return popular[i].ID > popular[j].ID
+		})
+		for i := 0; i < len(popular) && len(recs) < limit; i++ {
+			recs = append(recs, popularRecommendation(popular[i]))
+		}
+	}
+	if recs == nil {
+		recs = []models.Recommendation{}
+	}
+	return recs, nil
+}
@@ -0,0 +1,260 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// liveReview returns the stored review unless it is missing or removed.
+// Callers must hold mu.
+func (m *MemoryStore) liveReview(id int) *models.Review {
+	for i := range m.reviews {
+		if m.reviews[i].ID == id && m.reviews[i].RemovedAt == nil {
+			return &m.reviews[i]
+		}
+	}
+	return nil
+}
+
+// review returns a copy of a stored review with its author and reply.
+// Callers must hold mu.
+func (m *MemoryStore) review(r models.Review) models.Review {
+	u := m.userRef(r.UserID)
+	r.User = &models.User{Username: u.Username, FullName: u.FullName, AvatarURL: u.AvatarURL}
+	if r.Reply != nil {
+		reply := *r.Reply
+		r.Reply = &reply
+	}
+	return r
+}
+
+// rateAnimal recomputes a listing's rating and review count. Callers must
+// hold mu.
+func (m *MemoryStore) rateAnimal(animalID int) {
+	if a, ok := m.animals[animalID]; ok {
+		sum := m.ratingSummary(animalID)
+		a.Rating, a.ReviewCount = sum.Average, sum.Count
+	}
+}
+
+func (m *MemoryStore) CreateReview(r *models.Review) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[*r.OrderID]
+	if !ok || order.BuyerID != r.UserID {
+		return 0, ErrNotFound
+	}
+	var line *models.OrderItem
+	for i, item := range m.orderItems {
+		holder := m.orders[item.OrderID]
+		if item.AnimalID == r.AnimalID && (holder.ID == order.ID || (holder.ParentID != nil && *holder.ParentID == order.ID)) {
+			line = &m.orderItems[i]
+			break
+		}
+	}
+	if line == nil {
+		return 0, ErrNotFound
+	}
+	if m.orders[line.OrderID].Status != OrderCompleted {
+		return 0, ErrNotReviewable
+	}
+	for _, existing := range m.reviews {
+		if existing.OrderItemID != nil && *existing.OrderItemID == line.ID {
+			return 0, ErrConflict
+		}
+	}
+
+	itemID := line.ID
+	r.ID, r.OrderItemID, r.Verified, r.CreatedAt = m.nextID("reviews"), &itemID, true, time.Now()
+	row := *r
+	row.User = nil
+	m.reviews = append(m.reviews, row)
+	m.rateAnimal(r.AnimalID)
+	return r.ID, nil
+}
+
+// ratingSummary breaks a listing's reviews down by rating. Callers must
+// hold mu.
+func (m *MemoryStore) ratingSummary(animalID int) *models.RatingSummary {
+	sum := newRatingSummary()
+	for _, r := range m.reviews {
+		if r.AnimalID == animalID && r.RemovedAt == nil {
+			verified := 0
+			if r.Verified {
+				verified = 1
+			}
+			addRatings(sum, r.Rating, 1, verified)
+		}
+	}
+	return sum
+}
+
+func (m *MemoryStore) ListReviews(animalID int, f ReviewFilter, p PageRequest) (Page[models.Review], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var reviews []models.Review
+	for _, r := range m.reviews {
+		if r.AnimalID != animalID || r.RemovedAt != nil || (f.WithPhotos && r.ImageURL == "") {
+			continue
+		}
+		reviews = append(reviews, m.review(r))
+	}
+	o := reviewOrder(f.Sort)
+	return paginate(reviews, p, o, reviewKey(o))
+}
+
+func (m *MemoryStore) ReviewSummary(animalID int) (*models.RatingSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.ratingSummary(animalID), nil
+}
+
+func (m *MemoryStore) ReplyToReview(reviewID, sellerID int, body string) (*models.ReviewReply, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.liveReview(reviewID)
+	if r == nil {
+		return nil, ErrNotFound
+	}
+	if m.animals[r.AnimalID].SellerID != sellerID {
+		return nil, ErrNotOwner
+	}
+	now := time.Now()
+	reply := models.ReviewReply{SellerID: sellerID, Body: body, CreatedAt: now, UpdatedAt: now}
+	if r.Reply != nil {
+		reply.CreatedAt = r.Reply.CreatedAt
+	}
+	r.Reply = &reply
+	out := reply
+	return &out, nil
+}
+
+func (m *MemoryStore) DeleteReviewReply(reviewID, sellerID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.liveReview(reviewID)
+	if r == nil {
+		return ErrNotFound
+	}
+	if m.animals[r.AnimalID].SellerID != sellerID {
+		return ErrNotOwner
+	}
+	r.Reply = nil
+	return nil
+}
+
+func (m *MemoryStore) VoteReviewHelpful(reviewID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.liveReview(reviewID)
+	if r == nil {
+		return ErrNotFound
+	}
+	if r.UserID == userID {
+		return ErrOwnReview
+	}
+	if !m.reviewVotes[pair{reviewID, userID}] {
+		m.reviewVotes[pair{reviewID, userID}] = true
+		r.HelpfulCount++
+	}
+	return nil
+}
+
+func (m *MemoryStore) UnvoteReviewHelpful(reviewID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reviewVotes[pair{reviewID, userID}] {
+		delete(m.reviewVotes, pair{reviewID, userID})
+		for i := range m.reviews {
+			if m.reviews[i].ID == reviewID {
+				m.reviews[i].HelpfulCount--
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) ReportReview(r *models.ReviewReport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.liveReview(r.ReviewID) == nil {
+		return ErrNotFound
+	}
+	for _, existing := range m.reports {
+		if existing.ReviewID == r.ReviewID && existing.ReporterID == r.ReporterID {
+			return ErrConflict
+		}
+	}
+	r.ID, r.Status, r.CreatedAt = m.nextID("review_reports"), ReportOpen, time.Now()
+	stored := *r
+	m.reports[r.ID] = &stored
+	return nil
+}
+
+// reviewReport returns a copy of a stored report with its review and
+// reporter. Callers must hold mu.
+func (m *MemoryStore) reviewReport(rr *models.ReviewReport) models.ReviewReport {
+	out := *rr
+	for _, r := range m.reviews {
+		if r.ID == rr.ReviewID {
+			review := m.review(r)
+			out.Review = &review
+		}
+	}
+	u := m.userRef(rr.ReporterID)
+	out.Reporter = &models.User{ID: u.ID, Username: u.Username, FullName: u.FullName, AvatarURL: u.AvatarURL}
+	return out
+}
+
+func (m *MemoryStore) ListReviewReports(status string, p PageRequest) (Page[models.ReviewReport], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var reports []models.ReviewReport
+	for _, rr := range m.reports {
+		if rr.Status == status {
+			reports = append(reports, m.reviewReport(rr))
+		}
+	}
+	return paginate(reports, p, oldestFirst("rr.created_at"), func(rr models.ReviewReport) (interface{}, int) { return rr.CreatedAt, rr.ID })
+}
+
+func (m *MemoryStore) ResolveReviewReport(reportID, moderatorID int, status string) (*models.ReviewReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rr, ok := m.reports[reportID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if rr.Status != ReportOpen {
+		return nil, ErrConflict
+	}
+	now := time.Now()
+	resolve := func(rr *models.ReviewReport) {
+		rr.Status, rr.ResolvedBy, rr.ResolvedAt = status, &moderatorID, &now
+	}
+	resolve(rr)
+	if status == ReportRemoved {
+		if r := m.liveReview(rr.ReviewID); r != nil {
+			r.RemovedAt = &now
+			m.rateAnimal(r.AnimalID)
+		}
+		for _, other := range m.reports {
+			if other.ReviewID == rr.ReviewID && other.Status == ReportOpen {
+				resolve(other)
+			}
+		}
+	}
+	out := m.reviewReport(rr)
+	return &out, nil
+}
@@ -0,0 +1,245 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// shipmentOf returns a copy of an order's shipment with its events, or nil.
+// Callers must hold mu.
+func (m *MemoryStore) shipmentOf(orderID int) *models.Shipment {
+	for _, s := range m.shipments {
+		if s.OrderID == orderID {
+			out := *s
+			out.Events = m.shipmentEvents(s.ID)
+			return &out
+		}
+	}
+	return nil
+}
+
+// shipmentEvents returns a shipment's events, oldest first. Callers must
+// hold mu.
+func (m *MemoryStore) shipmentEvents(shipmentID int) []models.ShipmentEvent {
+	var events []models.ShipmentEvent
+	for _, e := range m.shipmentLog {
+		if e.ShipmentID == shipmentID {
+			events = append(events, e)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].OccurredAt.Before(events[j].OccurredAt) })
+	return events
+}
+
+// resetDefaultAddress makes the user's newest address the default if none
+// is. Callers must hold mu.
+func (m *MemoryStore) resetDefaultAddress(userID int) {
+	var newest *models.Address
+	for _, a := range m.addresses {
+		if a.UserID != userID {
+			continue
+		}
+		if a.IsDefault {
+			return
+		}
+		if newest == nil || a.ID > newest.ID {
+			newest = a
+		}
+	}
+	if newest != nil {
+		newest.IsDefault = true
+	}
+}
+
+// clearDefaultAddress unsets the user's default address. Callers must hold
+// mu.
+func (m *MemoryStore) clearDefaultAddress(userID int) {
+	for _, a := range m.addresses {
+		if a.UserID == userID {
+			a.IsDefault = false
+		}
+	}
+}
+
+func (m *MemoryStore) ListAddresses(userID int) ([]models.Address, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var addresses []models.Address
+	for _, a := range m.addresses {
+		if a.UserID == userID {
+			addresses = append(addresses, *a)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		if addresses[i].IsDefault != addresses[j].IsDefault {
+			return addresses[i].IsDefault
+		}
+		return addresses[i].ID > addresses[j].ID
+	})
+	return addresses, nil
+}
+
+func (m *MemoryStore) GetAddress(userID, id int) (*models.Address, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.addresses[id]
+	if !ok || a.UserID != userID {
+		return nil, ErrNotFound
+	}
+	out := *a
+	return &out, nil
+}
+
+func (m *MemoryStore) CreateAddress(a *models.Address) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if a.IsDefault {
+		m.clearDefaultAddress(a.UserID)
+	}
+	now := time.Now()
+	a.ID, a.CreatedAt, a.UpdatedAt = m.nextID("addresses"), now, now
+	stored := *a
+	m.addresses[a.ID] = &stored
+	m.resetDefaultAddress(a.UserID)
+	a.IsDefault = stored.IsDefault
+	return nil
+}
+
+func (m *MemoryStore) UpdateAddress(a *models.Address) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.addresses[a.ID]
+	if !ok || old.UserID != a.UserID {
+		return ErrNotFound
+	}
+	if a.IsDefault {
+		m.clearDefaultAddress(a.UserID)
+	} else {
+		a.IsDefault = old.IsDefault
+	}
+	a.CreatedAt, a.UpdatedAt = old.CreatedAt, time.Now()
+	*old = *a
+	return nil
+}
+
+func (m *MemoryStore) DeleteAddress(userID, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.addresses[id]
+	if !ok || a.UserID != userID {
+		return ErrNotFound
+	}
+	delete(m.addresses, id)
+	m.resetDefaultAddress(userID)
+	return nil
+}
+
+func (m *MemoryStore) GetShipment(orderID, userID int) (*models.Shipment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.orders[orderID]
+	if !ok || userID == 0 || orderRole(userID, o.BuyerID, o.SellerID) == "" {
+		return nil, ErrNotFound
+	}
+	s := m.shipmentOf(orderID)
+	if s == nil {
+		return nil, ErrNotFound
+	}
+	return s, nil
+}
+
+func (m *MemoryStore) ShipOrder(orderID, sellerID int, trackingNumber string) (*models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.orders[orderID]
+	if !ok || o.SellerID == nil || *o.SellerID != sellerID {
+		return nil, ErrNotFound
+	}
+	var shipment *models.Shipment
+	for _, s := range m.shipments {
+		if s.OrderID == orderID {
+			shipment = s
+		}
+	}
+	if shipment == nil {
+		return nil, ErrNotFound
+	}
+	if err := m.updateOrderStatus(orderID, sellerID, OrderShipped, "Tracking number "+trackingNumber); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	shipment.TrackingNumber, shipment.Status = trackingNumber, ShipmentLabelled
+	shipment.ShippedAt, shipment.UpdatedAt = &now, now
+	m.shipmentLog = append(m.shipmentLog, models.ShipmentEvent{
+		ID: m.nextID("shipment_events"), ShipmentID: shipment.ID, Status: ShipmentLabelled,
+		Description: "Shipping label created", Location: shipment.Origin, OccurredAt: now,
+	})
+	return m.orderDetails(orderID), nil
+}
+
+func (m *MemoryStore) ListActiveShipments(limit int) ([]models.Shipment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var shipments []models.Shipment
+	for _, s := range m.shipments {
+		if s.TrackingNumber != "" && s.Status != ShipmentDelivered {
+			shipments = append(shipments, *s)
+		}
+	}
+	sort.Slice(shipments, func(i, j int) bool { return shipments[i].ID < shipments[j].ID })
+	if len(shipments) > limit {
+		shipments = shipments[:limit]
+	}
+	return shipments, nil
+}
+
+func (m *MemoryStore) AddShipmentEvents(shipmentID int, events []models.ShipmentEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.shipments[shipmentID]
+	if !ok {
+		return ErrNotFound
+	}
+	seen := map[string]bool{}
+	for _, e := range m.shipmentEvents(shipmentID) {
+		seen[e.Status] = true
+	}
+	added := false
+	for _, e := range events {
+		if seen[e.Status] {
+			continue
+		}
+		seen[e.Status] = true
+		e.ID, e.ShipmentID = m.nextID("shipment_events"), shipmentID
+		m.shipmentLog = append(m.shipmentLog, e)
+		added = true
+	}
+	if !added {
+		return nil
+	}
+
+	latest := latestEvent(m.shipmentEvents(shipmentID))
+	s.Status, s.UpdatedAt = latest.Status, time.Now()
+	if latest.Status != ShipmentDelivered {
+		return nil
+	}
+	s.DeliveredAt = &latest.OccurredAt
+	err := m.updateOrderStatus(s.OrderID, 0, OrderDelivered, "Delivered by "+s.Carrier)
+	if errors.Is(err, ErrInvalidTransition) {
+		return nil
+	}
+	return err
+}
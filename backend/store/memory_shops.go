@@ -0,0 +1,223 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// sellerShop returns the seller's shop, or nil. Callers must hold mu.
+func (m *MemoryStore) sellerShop(sellerID int) *models.Shop {
+	for _, s := range m.shops {
+		if s.SellerID == sellerID {
+			return s
+		}
+	}
+	return nil
+}
+
+// slugTaken reports whether a shop other than exceptID uses slug. Callers
+// must hold mu.
+func (m *MemoryStore) slugTaken(slug string, exceptID int) bool {
+	for _, s := range m.shops {
+		if s.Slug == slug && s.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// shopRef names the seller's shop for a listing, or returns nil. Callers
+// must hold mu.
+func (m *MemoryStore) shopRef(sellerID int) *models.ShopRef {
+	s := m.sellerShop(sellerID)
+	if s == nil {
+		return nil
+	}
+	return &models.ShopRef{ID: s.ID, Slug: s.Slug, Name: s.Name}
+}
+
+// followsSeller reports whether the user follows the seller's shop.
+// Callers must hold mu.
+func (m *MemoryStore) followsSeller(userID, sellerID int) bool {
+	s := m.sellerShop(sellerID)
+	if s == nil {
+		return false
+	}
+	_, ok := m.follows[pair{s.ID, userID}]
+	return ok
+}
+
+// shop returns a copy of a stored shop with its follower count and seller.
+// Callers must hold mu.
+func (m *MemoryStore) shop(s *models.Shop) *models.Shop {
+	out := *s
+	out.OpeningHours = append([]models.OpeningHours{}, s.OpeningHours...)
+	out.FollowerCount = 0
+	for k := range m.follows {
+		if k[0] == s.ID {
+			out.FollowerCount++
+		}
+	}
+	seller := m.userRef(s.SellerID)
+	out.Seller = &models.User{ID: seller.ID, Username: seller.Username, FullName: seller.FullName, AvatarURL: seller.AvatarURL, Bio: seller.Bio, CreatedAt: seller.CreatedAt}
+	return &out
+}
+
+// sellerRating is the mean rating and number of reviews across all of the
+// seller's listings, deleted ones included. Callers must hold mu.
+func (m *MemoryStore) sellerRating(sellerID int) (float64, int) {
+	sum, count := 0, 0
+	for _, r := range m.reviews {
+		if a, ok := m.animals[r.AnimalID]; ok && a.SellerID == sellerID && r.RemovedAt == nil {
+			sum += r.Rating
+			count++
+		}
+	}
+	return averageRating(sum, count), count
+}
+
+func (m *MemoryStore) CreateShop(s *models.Shop) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sellerShop(s.SellerID) != nil {
+		return ErrHasShop
+	}
+	if m.slugTaken(s.Slug, 0) {
+		return ErrConflict
+	}
+	now := time.Now()
+	s.ID, s.CreatedAt, s.UpdatedAt = m.nextID("shops"), now, now
+	stored := *s
+	stored.Seller = nil
+	m.shops[s.ID] = &stored
+	return nil
+}
+
+func (m *MemoryStore) UpdateShop(s *models.Shop) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.sellerShop(s.SellerID)
+	if old == nil {
+		return ErrNotFound
+	}
+	if m.slugTaken(s.Slug, old.ID) {
+		return ErrConflict
+	}
+	s.ID, s.CreatedAt, s.UpdatedAt = old.ID, old.CreatedAt, time.Now()
+	stored := *s
+	stored.Seller = nil
+	m.shops[s.ID] = &stored
+	s.FollowerCount = m.shop(&stored).FollowerCount
+	return nil
+}
+
+func (m *MemoryStore) GetSellerShop(sellerID int) (*models.Shop, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.sellerShop(sellerID)
+	if s == nil {
+		return nil, ErrNotFound
+	}
+	return m.shop(s), nil
+}
+
+func (m *MemoryStore) GetShop(slug string) (*models.Shop, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.shops {
+		if s.Slug == slug {
+			return m.shop(s), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryStore) ShopStats(sellerID int) (*models.ShopStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := &models.ShopStats{}
+	for _, a := range m.animals {
+		if a.SellerID == sellerID && a.Status == StatusAvailable {
+			st.Listings++
+		}
+	}
+	st.Rating, st.ReviewCount = m.sellerRating(sellerID)
+	for _, item := range m.orderItems {
+		if item.SellerID == sellerID && isSale(m.orders[item.OrderID].Status) {
+			st.Sold += item.Quantity
+		}
+	}
+
+	// The first message each buyer sent in the window opens a chat; the
+	// seller's first message back to them after it answers it.
+	since := time.Now().Add(-ResponseWindow)
+	opened := map[int]time.Time{}
+	for _, msg := range m.messages {
+		if msg.ReceiverID != sellerID || !msg.CreatedAt.After(since) {
+			continue
+		}
+		if at, ok := opened[msg.SenderID]; !ok || msg.CreatedAt.Before(at) {
+			opened[msg.SenderID] = msg.CreatedAt
+		}
+	}
+	answered, wait := 0, time.Duration(0)
+	for buyerID, at := range opened {
+		var first *time.Time
+		for _, msg := range m.messages {
+			if msg.SenderID == sellerID && msg.ReceiverID == buyerID && !msg.CreatedAt.Before(at) && (first == nil || msg.CreatedAt.Before(*first)) {
+				t := msg.CreatedAt
+				first = &t
+			}
+		}
+		if first != nil {
+			answered++
+			wait += first.Sub(at)
+		}
+	}
+	shopResponse(st, len(opened), answered, wait)
+	return st, nil
+}
+
+func (m *MemoryStore) FollowShop(shopID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.shops[shopID]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := m.follows[pair{shopID, userID}]; !ok {
+		m.follows[pair{shopID, userID}] = time.Now()
+	}
+	return nil
+}
+
+func (m *MemoryStore) UnfollowShop(shopID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.follows, pair{shopID, userID})
+	return nil
+}
+
+func (m *MemoryStore) ListFollowedShops(userID int, p PageRequest) (Page[models.Shop], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var shops []models.Shop
+	for k, at := range m.follows {
+		if k[1] != userID {
+			continue
+		}
+		s := m.shop(m.shops[k[0]])
+		followedAt := at
+		s.FollowedAt = &followedAt
+		shops = append(shops, *s)
+	}
+	return paginate(shops, p, newestFirst("fw.created_at"), func(s models.Shop) (interface{}, int) { return *s.FollowedAt, s.ID })
+}
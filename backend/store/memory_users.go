@@ -0,0 +1,97 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (m *MemoryStore) CreateUser(u *models.User) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.users {
+		if existing.Username == u.Username || existing.Email == u.Email {
+			return 0, ErrConflict
+		}
+	}
+
+	row := &memUser{User: *u}
+	row.ID = m.nextID("users")
+	if row.UserType == "" {
+		row.UserType = "customer"
+	}
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	m.users[row.ID] = row
+	return row.ID, nil
+}
+
+func (m *MemoryStore) GetUser(id int) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user := u.User
+	return &user, nil
+}
+
+func (m *MemoryStore) AuthenticateUser(email, passwordHash string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]int, 0, len(m.users))
+	for id := range m.users {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		u := m.users[id]
+		if strings.EqualFold(u.Email, email) && u.Password == passwordHash {
+			return m.userRef(id), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryStore) SetResetToken(email, token string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Email == email {
+			u.resetToken = token
+			u.resetExpiry = time.Now().Add(ttl)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *MemoryStore) ResetPassword(email, token, passwordHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Email == email && u.resetToken != "" && u.resetToken == token && u.resetExpiry.After(time.Now()) {
+			u.Password = passwordHash
+			u.resetToken = ""
+			u.resetExpiry = time.Time{}
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *MemoryStore) IsModerator(userID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[userID]
+	return ok && u.moderator, nil
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// voucherByCode returns the voucher with code, or nil. Callers must hold
+// mu.
+func (m *MemoryStore) voucherByCode(code string) *models.Voucher {
+	code = voucherCode(code)
+	for _, v := range m.vouchers {
+		if v.Code == code {
+			return v
+		}
+	}
+	return nil
+}
+
+// timesUsed counts the user's redemptions of a voucher. Callers must hold
+// mu.
+func (m *MemoryStore) timesUsed(voucherID, userID int) int {
+	n := 0
+	for orderID, id := range m.redemptions {
+		if id == voucherID && m.orders[orderID].BuyerID == userID {
+			n++
+		}
+	}
+	return n
+}
+
+// quoteVoucher prices the voucher with code against items. Callers must
+// hold mu.
+func (m *MemoryStore) quoteVoucher(userID int, code string, items []models.CartItem) (*models.VoucherQuote, error) {
+	v := m.voucherByCode(code)
+	if v == nil {
+		return nil, ErrNotFound
+	}
+	return quoteVoucher(*v, m.voucherCategories(*v), m.timesUsed(v.ID, userID), items, time.Now())
+}
+
+// voucherCategories returns the categories a voucher limited to one takes
+// in, or nil when it is not limited. Callers must hold mu.
+func (m *MemoryStore) voucherCategories(v models.Voucher) map[int]bool {
+	if v.CategoryID == nil {
+		return nil
+	}
+	return m.subcategories(*v.CategoryID)
+}
+
+// releaseVoucher gives back the voucher redeemed on a cancelled order.
+// Callers must hold mu.
+func (m *MemoryStore) releaseVoucher(orderID int) {
+	if id, ok := m.redemptions[orderID]; ok {
+		delete(m.redemptions, orderID)
+		m.vouchers[id].UsedCount--
+	}
+}
+
+func (m *MemoryStore) CreateVoucher(v *models.Voucher) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v.Code = voucherCode(v.Code)
+	if m.voucherByCode(v.Code) != nil {
+		return ErrConflict
+	}
+	if v.CategoryID != nil && m.category(*v.CategoryID) == nil {
+		return ErrNotFound
+	}
+	v.ID = m.nextID("vouchers")
+	v.CreatedAt = time.Now()
+	if v.StartsAt.IsZero() {
+		v.StartsAt = v.CreatedAt
+	}
+	stored := *v
+	m.vouchers[v.ID] = &stored
+	return nil
+}
+
+func (m *MemoryStore) ListVouchers(p PageRequest) (Page[models.Voucher], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var vouchers []models.Voucher
+	for _, v := range m.vouchers {
+		if v.Public && voucherUsable(*v, 0, now) == "" {
+			vouchers = append(vouchers, *v)
+		}
+	}
+	return paginate(vouchers, p, newestFirst("created_at"), func(v models.Voucher) (interface{}, int) { return v.CreatedAt, v.ID })
+}
+
+// userVoucher is the wallet entry for a claimed voucher. Callers must hold
+// mu.
+func (m *MemoryStore) userVoucher(v *models.Voucher, userID int, now time.Time) models.UserVoucher {
+	uv := models.UserVoucher{Voucher: *v, TimesUsed: m.timesUsed(v.ID, userID), ClaimedAt: m.claims[pair{v.ID, userID}]}
+	uv.Usable = voucherUsable(*v, uv.TimesUsed, now) == ""
+	return uv
+}
+
+func (m *MemoryStore) ClaimVoucher(userID int, code string) (*models.UserVoucher, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v := m.voucherByCode(code)
+	if v == nil {
+		return nil, ErrNotFound
+	}
+	now := time.Now()
+	if reason := voucherUsable(*v, m.timesUsed(v.ID, userID), now); reason != "" {
+		return nil, &VoucherError{Reason: reason}
+	}
+	if _, ok := m.claims[pair{v.ID, userID}]; ok {
+		return nil, ErrConflict
+	}
+	m.claims[pair{v.ID, userID}] = now
+	uv := m.userVoucher(v, userID, now)
+	return &uv, nil
+}
+
+func (m *MemoryStore) ListUserVouchers(userID int, p PageRequest) (Page[models.UserVoucher], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var vouchers []models.UserVoucher
+	for key := range m.claims {
+		if key[1] == userID {
+			vouchers = append(vouchers, m.userVoucher(m.vouchers[key[0]], userID, now))
+		}
+	}
+	return paginate(vouchers, p, newestFirst("claimed_at"), func(v models.UserVoucher) (interface{}, int) { return v.ClaimedAt, v.ID })
+}
+
+func (m *MemoryStore) CountUsableVouchers(userID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	n := 0
+	for key := range m.claims {
+		if key[1] == userID && m.userVoucher(m.vouchers[key[0]], userID, now).Usable {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryStore) QuoteVoucher(userID int, code string) (*models.VoucherQuote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.quoteVoucher(userID, code, m.cart(userID))
+}
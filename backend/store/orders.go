@@ -0,0 +1,182 @@
+package store
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// Order statuses. Orders move forward from pending to completed; pending
+// orders can be cancelled and paid ones not yet shipped refunded, which
+// puts their stock back.
+const (
+	OrderPending    = "pending"
+	OrderPaid       = "paid"
+	OrderProcessing = "processing"
+	OrderShipped    = "shipped"
+	OrderDelivered  = "delivered"
+	OrderCompleted  = "completed"
+	OrderCancelled  = "cancelled"
+	OrderRefunded   = "refunded"
+)
+
+// OrderStatuses lists every order status in lifecycle order.
+var OrderStatuses = []string{
+	OrderPending, OrderPaid, OrderProcessing, OrderShipped, OrderDelivered, OrderCompleted, OrderCancelled, OrderRefunded,
+}
+
+// Payment statuses. Pending payments are waiting for the buyer; cancelling
+// the order expires them. Mismatched payments were reported paid for another
+// amount or currency than charged and are left for someone to look into.
+const (
+	PaymentPending  = "pending"
+	PaymentPaid     = "paid"
+	PaymentExpired  = "expired"
+	PaymentFailed   = "failed"
+	PaymentMismatch = "mismatch"
+)
+
+// PaymentCurrency is the currency payments are charged in.
+const PaymentCurrency = "IDR"
+
+// paymentMatches reports whether a paid event collected exactly what p
+// charged. Charges are for whole rupiah.
+func paymentMatches(p models.Payment, e *models.PaymentEvent) bool {
+	return e.Currency == PaymentCurrency && e.Amount == math.Round(p.Amount)
+}
+
+// paymentReference is the reference a payment is known by at the gateway.
+func paymentReference(id int) string {
+	return fmt.Sprintf("TP-PAY-%d", id)
+}
+
+// paymentOrderID is the order that o is paid through: its checkout header
+// for sub-orders, otherwise o itself.
+func paymentOrderID(o models.Order) int {
+	if o.ParentID != nil {
+		return *o.ParentID
+	}
+	return o.ID
+}
+
+// Who changed an order's status.
+const (
+	RoleBuyer  = "buyer"
+	RoleSeller = "seller"
+	RoleSystem = "system" // payments and scheduled jobs
+)
+
+// orderTransitions lists the roles allowed to move an order from one
+// status to another. Sellers may confirm a manual transfer as paid; the
+// system marks orders delivered when the carrier reports it.
+var orderTransitions = map[[2]string][]string{
+	{OrderPending, OrderPaid}:        {RoleSeller, RoleSystem},
+	{OrderPaid, OrderProcessing}:     {RoleSeller},
+	{OrderProcessing, OrderShipped}:  {RoleSeller},
+	{OrderShipped, OrderDelivered}:   {RoleSeller, RoleBuyer, RoleSystem},
+	{OrderDelivered, OrderCompleted}: {RoleBuyer, RoleSystem},
+	{OrderPending, OrderCancelled}:   {RoleBuyer, RoleSeller, RoleSystem},
+	{OrderPaid, OrderRefunded}:       {RoleSeller, RoleSystem},
+	{OrderProcessing, OrderRefunded}: {RoleSeller, RoleSystem},
+}
+
+// checkTransition reports whether role may move an order from one status
+// to another: ErrInvalidTransition if no one may, ErrNotOwner if only
+// other roles may.
+func checkTransition(from, to, role string) error {
+	roles, ok := orderTransitions[[2]string{from, to}]
+	if !ok {
+		return ErrInvalidTransition
+	}
+	for _, r := range roles {
+		if r == role {
+			return nil
+		}
+	}
+	return ErrNotOwner
+}
+
+// checkSubOrder reports whether a sub-order of a checkout whose header is
+// in headerStatus may move to status on its own. Until the checkout is
+// paid, its total and voucher cover every sub-order, so a sub-order is only
+// cancelled along with the rest, by cancelling the header.
+func checkSubOrder(headerStatus, status string) error {
+	if headerStatus == OrderPending && status == OrderCancelled {
+		return ErrInvalidTransition
+	}
+	return nil
+}
+
+// orderRole is the part userID plays in an order, or "" if none. userID 0
+// is the system.
+func orderRole(userID, buyerID int, sellerID *int) string {
+	switch {
+	case userID == 0:
+		return RoleSystem
+	case sellerID != nil && *sellerID == userID:
+		return RoleSeller
+	case buyerID == userID:
+		return RoleBuyer
+	}
+	return ""
+}
+
+// restocks reports whether moving into status puts the stock back.
+func restocks(status string) bool {
+	return status == OrderCancelled || status == OrderRefunded
+}
+
+// closed reports whether an order in status was called off. Changes to a
+// checkout header leave its closed sub-orders alone.
+func closed(status string) bool {
+	return status == OrderCancelled || status == OrderRefunded
+}
+
+// rollupStatus is the status of a checkout header given its sub-orders:
+// the least advanced of the live ones, or cancelled/refunded once none
+// is live.
+func rollupStatus(statuses []string) string {
+	rank := map[string]int{}
+	for i, s := range OrderStatuses {
+		rank[s] = i
+	}
+	live, refunded := "", false
+	for _, s := range statuses {
+		switch {
+		case s == OrderRefunded:
+			refunded = true
+		case s == OrderCancelled:
+		case live == "" || rank[s] < rank[live]:
+			live = s
+		}
+	}
+	switch {
+	case live != "":
+		return live
+	case refunded:
+		return OrderRefunded
+	}
+	return OrderCancelled
+}
+
+// orderNotice is the notification sent to the other party when an order
+// changes status.
+func orderNotice(orderID int, status string) models.Notification {
+	return models.Notification{
+		Title:   fmt.Sprintf("Order #%d %s", orderID, status),
+		Message: fmt.Sprintf("Order #%d is now %s.", orderID, status),
+		Type:    "order",
+	}
+}
+
+// noticeRecipients are the parties told about a change made by role.
+func noticeRecipients(role string, buyerID, sellerID int) []int {
+	switch role {
+	case RoleBuyer:
+		return []int{sellerID}
+	case RoleSeller:
+		return []int{buyerID}
+	}
+	return []int{buyerID, sellerID}
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func TestCheckTransition(t *testing.T) {
+	for _, c := range []struct {
+		from, to, role string
+		want           error
+	}{
+		{OrderPending, OrderPaid, RoleSeller, nil},
+		{OrderPending, OrderPaid, RoleSystem, nil},
+		{OrderPending, OrderPaid, RoleBuyer, ErrNotOwner},
+		{OrderPending, OrderCancelled, RoleBuyer, nil},
+		{OrderPending, OrderShipped, RoleSeller, ErrInvalidTransition},
+		{OrderPaid, OrderProcessing, RoleSeller, nil},
+		{OrderPaid, OrderCancelled, RoleBuyer, ErrInvalidTransition},
+		{OrderProcessing, OrderRefunded, RoleSeller, nil},
+		{OrderProcessing, OrderRefunded, RoleBuyer, ErrNotOwner},
+		{OrderShipped, OrderDelivered, RoleBuyer, nil},
+		{OrderShipped, OrderRefunded, RoleSeller, ErrInvalidTransition},
+		{OrderDelivered, OrderCompleted, RoleSeller, ErrNotOwner},
+		{OrderCancelled, OrderPaid, RoleSeller, ErrInvalidTransition},
+		{OrderCompleted, OrderRefunded, RoleSystem, ErrInvalidTransition},
+	} {
+		if err := checkTransition(c.from, c.to, c.role); err != c.want {
+			t.Errorf("%s -> %s by %s = %v, want %v", c.from, c.to, c.role, err, c.want)
+		}
+	}
+}
+
+func TestCheckSubOrder(t *testing.T) {
+	if err := checkSubOrder(OrderPending, OrderCancelled); err != ErrInvalidTransition {
+		t.Errorf("cancelling part of an unpaid checkout = %v", err)
+	}
+	for _, c := range [][2]string{{OrderPending, OrderPaid}, {OrderPaid, OrderRefunded}, {OrderPaid, OrderProcessing}} {
+		if err := checkSubOrder(c[0], c[1]); err != nil {
+			t.Errorf("header %s, sub-order to %s = %v", c[0], c[1], err)
+		}
+	}
+}
+
+func TestRollupStatus(t *testing.T) {
+	for _, c := range []struct {
+		statuses []string
+		want     string
+	}{
+		{[]string{OrderPaid, OrderPending}, OrderPending},
+		{[]string{OrderShipped, OrderPaid, OrderCancelled}, OrderPaid},
+		{[]string{OrderCompleted, OrderRefunded}, OrderCompleted},
+		{[]string{OrderCancelled, OrderRefunded}, OrderRefunded},
+		{[]string{OrderCancelled, OrderCancelled}, OrderCancelled},
+	} {
+		if got := rollupStatus(c.statuses); got != c.want {
+			t.Errorf("rollupStatus(%v) = %s, want %s", c.statuses, got, c.want)
+		}
+	}
+}
+
+// memoryUser adds a user to m and returns its id.
+func memoryUser(t *testing.T, m *MemoryStore, name string) int {
+	t.Helper()
+	id, err := m.CreateUser(&models.User{Username: name, Email: name + "@example.com", Password: "x", FullName: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// memoryListing adds a listing to m and returns its id.
+func memoryListing(t *testing.T, m *MemoryStore, sellerID int, name string, price float64, stock int) int {
+	t.Helper()
+	id, err := m.CreateAnimal(&models.Animal{SellerID: sellerID, AnimalType: "Kucing", Name: name, Price: price, Stock: stock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// expectStock fails unless the listing has stock left and is in status.
+func expectStock(t *testing.T, m *MemoryStore, animalID, stock int, status string) {
+	t.Helper()
+	a, err := m.GetAnimal(animalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Stock != stock || a.Status != status {
+		t.Errorf("listing %d: stock %d, status %s; want %d, %s", animalID, a.Stock, a.Status, stock, status)
+	}
+}
+
+// Cancelling or refunding puts stock back and relists sold-out listings;
+// cancelling a checkout header cancels its live sub-orders.
+func TestMemoryOrderRestock(t *testing.T) {
+	m := NewMemory()
+	sellerID := memoryUser(t, m, "seller")
+	otherID := memoryUser(t, m, "otherseller")
+	buyerID := memoryUser(t, m, "buyer")
+	kitten := memoryListing(t, m, sellerID, "Mochi", 100000, 2)
+	food := memoryListing(t, m, otherID, "Makanan Kucing", 100000, 10)
+
+	orderID, err := m.CreateOrder(buyerID, kitten, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStock(t, m, kitten, 0, StatusSold)
+	if _, err := m.UpdateOrderStatus(orderID, buyerID, OrderCancelled, ""); err != nil {
+		t.Fatal(err)
+	}
+	expectStock(t, m, kitten, 2, StatusAvailable)
+	if _, err := m.UpdateOrderStatus(orderID, sellerID, OrderPaid, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("paying a cancelled order = %v", err)
+	}
+
+	if err := m.AddCartItem(buyerID, kitten, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddCartItem(buyerID, food, 3); err != nil {
+		t.Fatal(err)
+	}
+	checkout, err := m.Checkout(buyerID, CheckoutOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kittenSub, foodSub := checkout.SubOrders[0].ID, checkout.SubOrders[1].ID
+	if _, err := m.UpdateOrderStatus(foodSub, buyerID, OrderCancelled, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("cancelling part of an unpaid checkout = %v", err)
+	}
+	if _, err := m.UpdateOrderStatus(kittenSub, sellerID, OrderPaid, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.UpdateOrderStatus(checkout.ID, buyerID, OrderCancelled, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("cancelling a part-paid checkout = %v", err)
+	}
+	if _, err := m.UpdateOrderStatus(kittenSub, sellerID, OrderRefunded, ""); err != nil {
+		t.Fatal(err)
+	}
+	order, err := m.UpdateOrderStatus(checkout.ID, buyerID, OrderCancelled, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != OrderRefunded || order.SubOrders[0].Status != OrderRefunded || order.SubOrders[1].Status != OrderCancelled {
+		t.Errorf("cancelled checkout %s, sub-orders %s and %s", order.Status, order.SubOrders[0].Status, order.SubOrders[1].Status)
+	}
+	expectStock(t, m, kitten, 2, StatusAvailable)
+	expectStock(t, m, food, 10, StatusAvailable)
+}
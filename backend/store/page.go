@@ -0,0 +1,260 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidCursor is returned by list methods when the cursor cannot be
+// decoded or was issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageRequest selects one page of a list. Lists are ordered by a sort key
+// with the row id as tie-breaker, and Cursor resumes right after the last
+// row of the previous page. Offset serves clients still sending page= and
+// is ignored when a cursor is given.
+type PageRequest struct {
+	Limit     int
+	Cursor    *Cursor
+	Offset    int
+	WithTotal bool
+}
+
+// Cursor is the position of the last row returned: its sort key and id.
+// Clients treat the encoded form as opaque.
+type Cursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   int    `json:"i"`
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor produced by Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort == "" || c.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Page is one page of a list. Next is nil on the last page. Total is only
+// set when requested; for large public lists it is the planner's estimate
+// and TotalEstimated is true.
+type Page[T any] struct {
+	Items          []T
+	Next           *Cursor
+	Total          *int
+	TotalEstimated bool
+}
+
+// order is a keyset sort: by column, then by id in the same direction.
+// cast is the SQL type cursor keys are converted to; keys are time.Time or
+// float64 values on the Go side.
+type order struct {
+	name   string
+	column string
+	cast   string
+	desc   bool
+}
+
+func newestFirst(column string) order {
+	return order{name: "newest", column: column, cast: "timestamp", desc: true}
+}
+
+func oldestFirst(column string) order {
+	return order{name: "oldest", column: column, cast: "timestamp"}
+}
+
+// check rejects cursors issued for another order or carrying a key of the
+// wrong type, before they reach a query.
+func (o order) check(p PageRequest) error {
+	if p.Cursor == nil {
+		return nil
+	}
+	if p.Cursor.Sort != o.name {
+		return ErrInvalidCursor
+	}
+	_, err := o.parseKey(p.Cursor.Key)
+	return err
+}
+
+func (o order) parseKey(s string) (interface{}, error) {
+	if o.cast == "timestamp" {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return t, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return f, nil
+}
+
+// after returns the predicate selecting rows past the cursor, or "" when
+// there is none. idColumn is the tie-breaking id column.
+func (o order) after(p PageRequest, idColumn string, args *[]interface{}) string {
+	if p.Cursor == nil {
+		return ""
+	}
+	op := ">"
+	if o.desc {
+		op = "<"
+	}
+	*args = append(*args, p.Cursor.Key, p.Cursor.ID)
+	n := len(*args)
+	return fmt.Sprintf("(%s, %s) %s ($%d::%s, $%d)", o.column, idColumn, op, n-1, o.cast, n)
+}
+
+func (o order) orderBy(idColumn string) string {
+	dir := "ASC"
+	if o.desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("ORDER BY %s %s, %s %s", o.column, dir, idColumn, dir)
+}
+
+// limit fetches one row more than requested so the page knows whether
+// another one follows.
+func limit(p PageRequest, args *[]interface{}) string {
+	*args = append(*args, p.Limit+1)
+	clause := fmt.Sprintf("LIMIT $%d", len(*args))
+	if p.Cursor == nil && p.Offset > 0 {
+		*args = append(*args, p.Offset)
+		clause += fmt.Sprintf(" OFFSET $%d", len(*args))
+	}
+	return clause
+}
+
+// where joins predicates into a WHERE clause, skipping empty ones.
+func where(preds ...string) string {
+	clause := ""
+	for _, p := range preds {
+		if p == "" {
+			continue
+		}
+		if clause == "" {
+			clause = "WHERE " + p
+		} else {
+			clause += " AND " + p
+		}
+	}
+	return clause
+}
+
+// newPage trims rows fetched with limit+1 to the requested size and
+// records the cursor of the last row kept.
+func newPage[T any](rows []T, p PageRequest, o order, key func(T) (interface{}, int)) Page[T] {
+	page := Page[T]{Items: rows}
+	if len(rows) > p.Limit {
+		page.Items = rows[:p.Limit]
+		k, id := key(page.Items[p.Limit-1])
+		page.Next = &Cursor{Sort: o.name, Key: formatKey(k), ID: id}
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	return page
+}
+
+// paginate applies p to an in-memory list, sorting it by o first.
+func paginate[T any](rows []T, p PageRequest, o order, key func(T) (interface{}, int)) (Page[T], error) {
+	if err := o.check(p); err != nil {
+		return Page[T]{}, err
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		ki, idi := key(rows[i])
+		kj, idj := key(rows[j])
+		return compareKeys(ki, idi, kj, idj, o.desc) < 0
+	})
+	total := len(rows)
+
+	start := 0
+	if p.Cursor != nil {
+		ck, _ := o.parseKey(p.Cursor.Key)
+		for start < len(rows) {
+			k, id := key(rows[start])
+			if compareKeys(k, id, ck, p.Cursor.ID, o.desc) > 0 {
+				break
+			}
+			start++
+		}
+	} else if p.Offset > 0 {
+		start = p.Offset
+	}
+	if start > len(rows) {
+		start = len(rows)
+	}
+	end := start + p.Limit + 1
+	if end > len(rows) {
+		end = len(rows)
+	}
+
+	page := newPage(append([]T(nil), rows[start:end]...), p, o, key)
+	if p.WithTotal {
+		page.Total = &total
+	}
+	return page, nil
+}
+
+// compareKeys orders (key, id) pairs in list order: negative when a comes
+// first.
+func compareKeys(a interface{}, aID int, b interface{}, bID int, desc bool) int {
+	c := 0
+	switch av := a.(type) {
+	case time.Time:
+		bv := b.(time.Time)
+		if av.Before(bv) {
+			c = -1
+		} else if av.After(bv) {
+			c = 1
+		}
+	case float64:
+		bv := b.(float64)
+		if av < bv {
+			c = -1
+		} else if av > bv {
+			c = 1
+		}
+	}
+	if c == 0 {
+		c = aID - bID
+	}
+	if desc {
+		c = -c
+	}
+	return c
+}
+
+func formatKey(k interface{}) string {
+	switch v := k.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(k)
+}
+
+var (
+	// vetOrder lists the best-rated veterinarians first.
+	vetOrder = order{name: "rating", column: "v.rating", cast: "numeric", desc: true}
+	// medicalRecordOrder lists the most recent visits first.
+	medicalRecordOrder = order{name: "date", column: "mr.date", cast: "timestamp", desc: true}
+)
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// PostgresStore implements Store on top of the shared *sql.DB opened by db.InitDB.
+type PostgresStore struct {
+	DB *sql.DB
+}
+
+func NewPostgres(db *sql.DB) *PostgresStore {
+	return &PostgresStore{DB: db}
+}
+
+// notFound translates sql.ErrNoRows into ErrNotFound and passes other errors through.
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+// writeErr maps constraint violations on INSERT to the store's sentinel
+// errors: a missing parent row is ErrNotFound, a duplicate is ErrConflict.
+func writeErr(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23503":
+			return ErrNotFound
+		case "23505":
+			return ErrConflict
+		}
+	}
+	return err
+}
+
+// fillTotal sets page.Total when the request asks for it, querying db, the
+// database or the transaction the list ran in. from is the list query's
+// FROM and WHERE clauses without keyset or limit; args are its
+// parameters. Large public lists pass estimate to read the planner's row
+// estimate instead of counting every match.
+func fillTotal[T any](db queryer, page *Page[T], p PageRequest, from string, args []interface{}, estimate bool) error {
+	if !p.WithTotal {
+		return nil
+	}
+	var total int
+	if estimate {
+		var plan string
+		if err := db.QueryRow("EXPLAIN (FORMAT JSON) SELECT 1 "+from, args...).Scan(&plan); err != nil {
+			return err
+		}
+		var explain []struct {
+			Plan struct {
+				Rows float64 `json:"Plan Rows"`
+			} `json:"Plan"`
+		}
+		if err := json.Unmarshal([]byte(plan), &explain); err != nil || len(explain) == 0 {
+			return fmt.Errorf("parse query plan: %v", err)
+		}
+		total = int(explain[0].Plan.Rows)
+		page.TotalEstimated = true
+	} else if err := db.QueryRow("SELECT COUNT(*) "+from, args...).Scan(&total); err != nil {
+		return err
+	}
+	page.Total = &total
+	return nil
+}
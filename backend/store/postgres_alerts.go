@@ -0,0 +1,127 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (s *PostgresStore) GetAlertPreferences(userID int) (*models.AlertPreferences, error) {
+	var p models.AlertPreferences
+	err := s.DB.QueryRow(
+		"SELECT price_drop, back_in_stock, min_drop_percent, email, push FROM alert_preferences WHERE user_id = $1", userID,
+	).Scan(&p.PriceDrop, &p.BackInStock, &p.MinDropPercent, &p.Email, &p.Push)
+	if errors.Is(err, sql.ErrNoRows) {
+		p, err = DefaultAlertPreferences(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *PostgresStore) SetAlertPreferences(userID int, p *models.AlertPreferences) error {
+	_, err := s.DB.Exec(
+		`INSERT INTO alert_preferences (user_id, price_drop, back_in_stock, min_drop_percent, email, push)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET
+			price_drop = EXCLUDED.price_drop, back_in_stock = EXCLUDED.back_in_stock,
+			min_drop_percent = EXCLUDED.min_drop_percent, email = EXCLUDED.email, push = EXCLUDED.push,
+			updated_at = CURRENT_TIMESTAMP`,
+		userID, p.PriceDrop, p.BackInStock, p.MinDropPercent, p.Email, p.Push,
+	)
+	return writeErr(err)
+}
+
+// wishlistChange is a wishlist entry whose listing moved on since its
+// user last heard of it.
+type wishlistChange struct {
+	wishlist models.Wishlist
+	animal   models.Animal
+	email    string
+	prefs    models.AlertPreferences
+}
+
+func (s *PostgresStore) MatchWishlistAlerts(limit int) ([]WishlistAlert, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Entries another run has locked are left to it.
+	rows, err := tx.Query(
+		`SELECT w.id, w.user_id, w.animal_id,
+		        COALESCE(w.alert_price, a.price, 0), COALESCE(w.alert_in_stock, a.status = 'available' AND COALESCE(a.stock, 0) > 0),
+		        a.name, COALESCE(a.price, 0), a.status, COALESCE(a.stock, 0), u.email,
+		        COALESCE(ap.price_drop, TRUE), COALESCE(ap.back_in_stock, TRUE), COALESCE(ap.min_drop_percent, 0),
+		        COALESCE(ap.email, FALSE), COALESCE(ap.push, FALSE)
+		FROM wishlists w
+		JOIN animals a ON a.id = w.animal_id
+		JOIN users u ON u.id = w.user_id
+		LEFT JOIN alert_preferences ap ON ap.user_id = w.user_id
+		WHERE a.status <> 'deleted'
+		  AND (w.alert_price IS DISTINCT FROM COALESCE(a.price, 0)
+		       OR w.alert_in_stock IS DISTINCT FROM (a.status = 'available' AND COALESCE(a.stock, 0) > 0))
+		ORDER BY w.id
+		LIMIT $1
+		FOR UPDATE OF w SKIP LOCKED`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var changes []wishlistChange
+	for rows.Next() {
+		var c wishlistChange
+		w, a, p := &c.wishlist, &c.animal, &c.prefs
+		err := rows.Scan(
+			&w.ID, &w.UserID, &w.AnimalID, &w.AlertPrice, &w.AlertInStock,
+			&a.Name, &a.Price, &a.Status, &a.Stock, &c.email,
+			&p.PriceDrop, &p.BackInStock, &p.MinDropPercent, &p.Email, &p.Push,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.ID = w.AnimalID
+		changes = append(changes, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	alerts := []WishlistAlert{}
+	for _, c := range changes {
+		if n, ok := wishlistAlert(c.prefs, c.wishlist, &c.animal, now); ok {
+			err := tx.QueryRow(
+				`INSERT INTO notifications (user_id, title, message, type, dedupe_key) VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (user_id, dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING
+				RETURNING id, created_at`,
+				n.UserID, n.Title, n.Message, n.Type, n.DedupeKey,
+			).Scan(&n.ID, &n.CreatedAt)
+			switch {
+			case err == nil:
+				alert := WishlistAlert{Notification: n, Push: c.prefs.Push}
+				if c.prefs.Email {
+					alert.Email = c.email
+				}
+				alerts = append(alerts, alert)
+			case !errors.Is(err, sql.ErrNoRows):
+				return nil, err
+			}
+		}
+		_, err := tx.Exec(
+			"UPDATE wishlists SET alert_price = $2, alert_in_stock = $3 WHERE id = $1",
+			c.wishlist.ID, c.animal.Price, inStock(c.animal.Status, c.animal.Stock),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return alerts, tx.Commit()
+}
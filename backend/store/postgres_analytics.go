@@ -0,0 +1,143 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// analyticsViews are the rollups behind SellerAnalytics, in the order they
+// are refreshed; listing_stats reads listing_sales_daily. See
+// db.createAnalyticsRollups.
+var analyticsViews = []string{"seller_sales_daily", "listing_sales_daily", "listing_stats"}
+
+func (s *PostgresStore) RecordAnimalView(animalID int) error {
+	_, err := s.DB.Exec(
+		`INSERT INTO animal_views (animal_id, day, views) VALUES ($1, CURRENT_DATE, 1)
+		ON CONFLICT (animal_id, day) DO UPDATE SET views = animal_views.views + 1`,
+		animalID,
+	)
+	return err
+}
+
+// RefreshAnalytics refreshes the rollups concurrently, so SellerAnalytics
+// keeps reading the previous figures while it runs.
+func (s *PostgresStore) RefreshAnalytics() error {
+	for _, view := range analyticsViews {
+		if _, err := s.DB.Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY " + view); err != nil {
+			return err
+		}
+	}
+	_, err := s.DB.Exec(
+		`INSERT INTO analytics_refreshes (id, refreshed_at) VALUES (TRUE, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE SET refreshed_at = EXCLUDED.refreshed_at`,
+	)
+	return err
+}
+
+// salesDays reads (day, orders, units, revenue) rows.
+func salesDays(rows *sql.Rows) ([]salesDay, error) {
+	defer rows.Close()
+	var days []salesDay
+	for rows.Next() {
+		var d salesDay
+		if err := rows.Scan(&d.day, &d.orders, &d.units, &d.revenue); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+func (s *PostgresStore) SellerAnalytics(sellerID int, q AnalyticsQuery) (*models.SellerAnalytics, error) {
+	out := &models.SellerAnalytics{Period: q.Period, From: q.From, To: q.To, TopListings: []models.ListingPerformance{}, StockAlerts: []models.StockAlert{}}
+
+	var refreshedAt time.Time
+	err := s.DB.QueryRow("SELECT refreshed_at FROM analytics_refreshes").Scan(&refreshedAt)
+	switch {
+	case err == nil:
+		out.RefreshedAt = &refreshedAt
+	case err != sql.ErrNoRows:
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(
+		`SELECT day, orders, units, revenue FROM seller_sales_daily
+		WHERE seller_id = $1 AND day >= $2 AND day < $3 ORDER BY day`,
+		sellerID, q.From, q.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	days, err := salesDays(rows)
+	if err != nil {
+		return nil, err
+	}
+	fillSales(out, q, days)
+
+	rows, err = s.DB.Query(
+		`SELECT d.animal_id, a.name, SUM(d.orders), SUM(d.units), SUM(d.revenue),
+		        COALESCE(MAX(ls.views), 0), COALESCE(MAX(ls.wishlists), 0), COALESCE(MAX(ls.reviews), 0), COALESCE(MAX(ls.rating_sum), 0)
+		FROM listing_sales_daily d
+		JOIN animals a ON a.id = d.animal_id AND a.status <> 'deleted'
+		LEFT JOIN listing_stats ls ON ls.animal_id = d.animal_id
+		WHERE d.seller_id = $1 AND d.day >= $2 AND d.day < $3
+		GROUP BY d.animal_id, a.name
+		ORDER BY SUM(d.revenue) DESC, SUM(d.units) DESC, d.animal_id
+		LIMIT $4`,
+		sellerID, q.From, q.To, q.Top,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l models.ListingPerformance
+		var ratingSum int
+		err := rows.Scan(&l.AnimalID, &l.Name, &l.Orders, &l.Units, &l.Revenue, &l.Views, &l.Wishlists, &l.ReviewCount, &ratingSum)
+		if err != nil {
+			return nil, err
+		}
+		l.Rating = averageRating(ratingSum, l.ReviewCount)
+		out.TopListings = append(out.TopListings, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var views, wishlists, orders, reviews, ratingSum int
+	err = s.DB.QueryRow(
+		`SELECT COALESCE(SUM(views), 0), COALESCE(SUM(wishlists), 0), COALESCE(SUM(orders), 0),
+		        COALESCE(SUM(reviews), 0), COALESCE(SUM(rating_sum), 0)
+		FROM listing_stats WHERE seller_id = $1`,
+		sellerID,
+	).Scan(&views, &wishlists, &orders, &reviews, &ratingSum)
+	if err != nil {
+		return nil, err
+	}
+	out.Funnel = newFunnel(views, wishlists, orders)
+	out.AverageRating, out.RatingCount = averageRating(ratingSum, reviews), reviews
+
+	rows, err = s.DB.Query(
+		`SELECT a.id, a.name, a.status, COALESCE(a.stock, 0), COALESCE(ls.units_30d, 0)
+		FROM animals a
+		LEFT JOIN listing_stats ls ON ls.animal_id = a.id
+		WHERE a.seller_id = $1 AND a.status IN ($2, $3) AND COALESCE(a.stock, 0) <= $4
+		ORDER BY COALESCE(a.stock, 0), a.id`,
+		sellerID, StatusAvailable, StatusSold, LowStock,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var alert models.StockAlert
+		if err := rows.Scan(&alert.AnimalID, &alert.Name, &alert.Status, &alert.Stock, &alert.UnitsLast30Days); err != nil {
+			return nil, err
+		}
+		alert.DaysLeft = daysLeft(alert.Stock, alert.UnitsLast30Days)
+		out.StockAlerts = append(out.StockAlerts, alert)
+	}
+	return out, rows.Err()
+}
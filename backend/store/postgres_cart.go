@@ -0,0 +1,411 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/TerraPaw/backend/models"
+	"github.com/lib/pq"
+)
+
+// cartLines returns the buyer's cart items checked against their listings
+// and variants, in cart order. With lock, tx locks the listings and then
+// the variants in id order, so concurrent checkouts sharing one queue up
+// instead of deadlocking, and the items come in that order.
+func cartLines(q queryer, userID int, lock bool) ([]models.CartItem, error) {
+	animalOrder, variantOrder := "ORDER BY ci.created_at, ci.id", "ORDER BY ci.created_at, ci.id"
+	if lock {
+		animalOrder, variantOrder = "ORDER BY a.id FOR UPDATE OF a", "ORDER BY v.id FOR UPDATE OF v"
+	}
+
+	rows, err := q.Query(
+		`SELECT `+animalColumns+`, ci.id, ci.quantity, ci.price, ci.created_at, ci.updated_at
+		FROM cart_items ci
+		JOIN carts c ON c.id = ci.cart_id
+		JOIN animals a ON a.id = ci.animal_id
+		LEFT JOIN users u ON a.seller_id = u.id
+		LEFT JOIN shops sh ON sh.seller_id = a.seller_id
+		WHERE c.user_id = $1
+		`+animalOrder,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var items []models.CartItem
+	for rows.Next() {
+		var item models.CartItem
+		animal, err := scanAnimal(rows, &item.ID, &item.Quantity, &item.AddedPrice, &item.CreatedAt, &item.UpdatedAt)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		item.AnimalID = animal.ID
+		item.Animal = &animal
+		checkCartItem(&item, animal.Status == StatusAvailable, animal.Stock, animal.Price)
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(
+		`SELECT `+variantRefColumns+`, ci.id, ci.quantity, ci.price, ci.created_at, ci.updated_at
+		FROM cart_items ci
+		JOIN carts c ON c.id = ci.cart_id
+		JOIN product_variants v ON v.id = ci.variant_id
+		JOIN products p ON p.id = v.product_id
+		WHERE c.user_id = $1
+		`+variantOrder,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item models.CartItem
+		variant, err := scanVariantRef(rows, &item.ID, &item.Quantity, &item.AddedPrice, &item.CreatedAt, &item.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		item.VariantID = variant.ID
+		item.Variant = variant
+		checkCartItem(&item, variant.Status == ProductActive, variant.Stock, variant.Price)
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !lock {
+		inCartOrder(items)
+	}
+	return items, nil
+}
+
+func (s *PostgresStore) GetCart(userID int) (*models.Cart, error) {
+	items, err := cartLines(s.DB, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	return newCart(items), nil
+}
+
+func (s *PostgresStore) AddCartItem(userID, animalID, quantity int) error {
+	return s.setCartItem(userID, cartKey{animalID: animalID}, quantity, true)
+}
+
+func (s *PostgresStore) UpdateCartItem(userID, animalID, quantity int) error {
+	return s.setCartItem(userID, cartKey{animalID: animalID}, quantity, false)
+}
+
+func (s *PostgresStore) AddCartVariant(userID, variantID, quantity int) error {
+	return s.setCartItem(userID, cartKey{variantID: variantID}, quantity, true)
+}
+
+func (s *PostgresStore) UpdateCartVariant(userID, variantID, quantity int) error {
+	return s.setCartItem(userID, cartKey{variantID: variantID}, quantity, false)
+}
+
+// setCartItem adds quantity to the cart line for key, or replaces it when
+// add is false, after checking the listing or variant can supply the
+// result.
+func (s *PostgresStore) setCartItem(userID int, key cartKey, quantity int, add bool) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var cartID int
+	err = tx.QueryRow(
+		`INSERT INTO carts (user_id) VALUES ($1)
+		ON CONFLICT (user_id) DO UPDATE SET updated_at = CURRENT_TIMESTAMP
+		RETURNING id`,
+		userID,
+	).Scan(&cartID)
+	if err != nil {
+		return writeErr(err)
+	}
+
+	column, id := key.column()
+	var current int
+	err = tx.QueryRow(
+		"SELECT quantity FROM cart_items WHERE cart_id = $1 AND "+column+" = $2",
+		cartID, id,
+	).Scan(&current)
+	if err == sql.ErrNoRows && !add {
+		return ErrNotFound
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if add {
+		quantity += current
+	}
+
+	var price float64
+	var stock int
+	var available bool
+	if key.variantID != 0 {
+		err = tx.QueryRow(
+			`SELECT v.price, v.stock, p.status = $2
+			FROM product_variants v JOIN products p ON p.id = v.product_id
+			WHERE v.id = $1`,
+			id, ProductActive,
+		).Scan(&price, &stock, &available)
+	} else {
+		err = tx.QueryRow(
+			"SELECT price, COALESCE(stock, 0), status = $2 FROM animals WHERE id = $1 AND status <> 'deleted'",
+			id, StatusAvailable,
+		).Scan(&price, &stock, &available)
+	}
+	switch {
+	case err == sql.ErrNoRows && add:
+		return ErrNotFound
+	case err == sql.ErrNoRows:
+		return ErrUnavailable
+	case err != nil:
+		return err
+	case !available:
+		return ErrUnavailable
+	case stock < quantity:
+		return ErrInsufficientStock
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO cart_items (cart_id, `+column+`, quantity, price) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (cart_id, `+column+`) DO UPDATE
+		SET quantity = EXCLUDED.quantity, price = EXCLUDED.price, updated_at = CURRENT_TIMESTAMP`,
+		cartID, id, quantity, price,
+	)
+	if err != nil {
+		return writeErr(err)
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) RemoveCartItem(userID, animalID int) error {
+	return s.removeCartItem(userID, cartKey{animalID: animalID})
+}
+
+func (s *PostgresStore) RemoveCartVariant(userID, variantID int) error {
+	return s.removeCartItem(userID, cartKey{variantID: variantID})
+}
+
+func (s *PostgresStore) removeCartItem(userID int, key cartKey) error {
+	column, id := key.column()
+	_, err := s.DB.Exec(
+		"DELETE FROM cart_items WHERE "+column+" = $2 AND cart_id IN (SELECT id FROM carts WHERE user_id = $1)",
+		userID, id,
+	)
+	return err
+}
+
+func (s *PostgresStore) Checkout(buyerID int, opts CheckoutOptions) (*models.Order, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	items, err := cartLines(tx, buyerID, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrEmptyCart
+	}
+	lines := make([]checkoutLine, len(items))
+	changed := false
+	for i, item := range items {
+		sellerID, _ := itemSeller(item)
+		lines[i] = checkoutLine{CartItem: item, sellerID: sellerID}
+		changed = changed || item.Problem != ""
+	}
+
+	if changed {
+		// Report the cart as read under lock, not as it may be by now.
+		for _, item := range items {
+			if item.Problem != models.CartPriceChanged {
+				continue
+			}
+			if _, err := tx.Exec("UPDATE cart_items SET price = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", item.Price, item.ID); err != nil {
+				return nil, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		// Lines were locked in listing and variant order; show them in
+		// cart order.
+		inCartOrder(items)
+		return nil, &CartError{Cart: newCart(items)}
+	}
+
+	groups := bySeller(lines)
+	discounts := make([]float64, len(groups))
+	shipments := make([]*models.Shipment, len(groups))
+	header := &models.Order{BuyerID: buyerID, Status: "pending"}
+	for i, group := range groups {
+		if shipments[i], err = bookedShipment(opts, group[0].sellerID); err != nil {
+			return nil, err
+		}
+		if shipments[i] != nil {
+			header.Shipping += shipments[i].Cost
+		}
+	}
+	for _, line := range lines {
+		header.TotalPrice += line.Subtotal
+		header.Quantity += line.Quantity
+	}
+	if opts.VoucherCode != "" {
+		// Lock the voucher after the listings, the same order cancelling
+		// takes, so its usage caps hold under concurrent checkouts.
+		quote, err := quoteVoucherRow(tx, buyerID, opts.VoucherCode, items, true)
+		if err != nil {
+			return nil, err
+		}
+		categories, err := voucherCategories(tx, quote.Voucher)
+		if err != nil {
+			return nil, err
+		}
+		discounts = checkoutDiscounts(quote, categories, groups)
+		header.Discount, header.VoucherID = quote.Discount, &quote.Voucher.ID
+		header.TotalPrice -= quote.Discount
+	}
+	header.TotalPrice += header.Shipping
+	err = tx.QueryRow(
+		`INSERT INTO orders (buyer_id, total_price, discount, voucher_id, shipping_cost, status, quantity)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`,
+		buyerID, header.TotalPrice, header.Discount, header.VoucherID, header.Shipping, header.Status, header.Quantity,
+	).Scan(&header.ID, &header.CreatedAt, &header.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if header.VoucherID != nil {
+		_, err = tx.Exec("INSERT INTO voucher_redemptions (voucher_id, user_id, order_id) VALUES ($1, $2, $3)", *header.VoucherID, buyerID, header.ID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec("UPDATE vouchers SET used_count = used_count + 1 WHERE id = $1", *header.VoucherID); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, group := range groups {
+		sellerID := group[0].sellerID
+		sub := models.Order{BuyerID: buyerID, ParentID: &header.ID, SellerID: &sellerID, Discount: discounts[i], Status: "pending"}
+		for _, line := range group {
+			sub.TotalPrice += line.Subtotal
+			sub.Quantity += line.Quantity
+		}
+		sub.TotalPrice -= sub.Discount
+		if shipments[i] != nil {
+			sub.Shipping = shipments[i].Cost
+			sub.TotalPrice += sub.Shipping
+		}
+		err = tx.QueryRow(
+			`INSERT INTO orders (buyer_id, parent_id, seller_id, total_price, discount, shipping_cost, status, quantity)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at, updated_at`,
+			buyerID, header.ID, sellerID, sub.TotalPrice, sub.Discount, sub.Shipping, sub.Status, sub.Quantity,
+		).Scan(&sub.ID, &sub.CreatedAt, &sub.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if shipments[i] != nil {
+			if sub.Shipment, err = insertShipment(tx, sub.ID, *shipments[i]); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, line := range group {
+			item := models.OrderItem{
+				OrderID: sub.ID, AnimalID: line.AnimalID, VariantID: line.VariantID, SellerID: sellerID,
+				Quantity: line.Quantity, UnitPrice: line.Price, Subtotal: line.Subtotal,
+				Animal: line.Animal, Variant: line.Variant,
+			}
+			if err := insertOrderItem(tx, &item); err != nil {
+				return nil, err
+			}
+			sub.Items = append(sub.Items, item)
+			header.Items = append(header.Items, item)
+		}
+		header.SubOrders = append(header.SubOrders, sub)
+	}
+
+	_, err = tx.Exec("DELETE FROM cart_items WHERE cart_id IN (SELECT id FROM carts WHERE user_id = $1)", buyerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// attachOrderItems loads the lines of each order. A checkout header gets
+// the lines of all its sub-orders.
+func (s *PostgresStore) attachOrderItems(orders []models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(orders))
+	index := map[int]int{}
+	for i, o := range orders {
+		ids[i] = int64(o.ID)
+		index[o.ID] = i
+	}
+
+	rows, err := s.DB.Query(
+		`SELECT o.parent_id, oi.id, oi.order_id, COALESCE(oi.animal_id, 0), COALESCE(oi.variant_id, 0), oi.seller_id,
+		        oi.quantity, oi.unit_price, oi.created_at,
+		        COALESCE(a.animal_type, ''), COALESCE(a.breed, ''), COALESCE(a.name, ''), COALESCE(a.price, 0), COALESCE(a.image_url, ''),
+		        COALESCE(v.product_id, 0), COALESCE(p.category_id, 0), COALESCE(v.sku, ''), COALESCE(p.name, ''), COALESCE(p.brand, ''),
+		        COALESCE(v.size, ''), COALESCE(v.flavour, ''), COALESCE(p.image_url, ''), COALESCE(v.price, 0)
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		LEFT JOIN animals a ON a.id = oi.animal_id
+		LEFT JOIN product_variants v ON v.id = oi.variant_id
+		LEFT JOIN products p ON p.id = v.product_id
+		WHERE oi.order_id = ANY($1) OR o.parent_id = ANY($1)
+		ORDER BY oi.id`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var parentID sql.NullInt64
+		var item models.OrderItem
+		var animal models.Animal
+		var variant models.VariantRef
+		err := rows.Scan(
+			&parentID, &item.ID, &item.OrderID, &item.AnimalID, &item.VariantID, &item.SellerID,
+			&item.Quantity, &item.UnitPrice, &item.CreatedAt,
+			&animal.AnimalType, &animal.Breed, &animal.Name, &animal.Price, &animal.ImageURL,
+			&variant.ProductID, &variant.CategoryID, &variant.SKU, &variant.Name, &variant.Brand,
+			&variant.Size, &variant.Flavour, &variant.ImageURL, &variant.Price,
+		)
+		if err != nil {
+			return err
+		}
+		if item.VariantID != 0 {
+			variant.ID, variant.SellerID = item.VariantID, item.SellerID
+			item.Variant = &variant
+		} else {
+			animal.ID, animal.SellerID = item.AnimalID, item.SellerID
+			item.Animal = &animal
+		}
+		item.Subtotal = item.UnitPrice * float64(item.Quantity)
+		if i, ok := index[item.OrderID]; ok {
+			orders[i].Items = append(orders[i].Items, item)
+		}
+		if i, ok := index[int(parentID.Int64)]; ok && parentID.Valid {
+			orders[i].Items = append(orders[i].Items, item)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+const categoryColumns = `id, parent_id, name, COALESCE(icon, ''), COALESCE(type, 'animal'), sort_order, names, attributes,
+	COALESCE(is_active, TRUE)`
+
+func scanCategory(row rowScanner) (models.Category, error) {
+	var c models.Category
+	var names, attributes []byte
+	err := row.Scan(&c.ID, &c.ParentID, &c.Name, &c.Icon, &c.Type, &c.SortOrder, &names, &attributes, &c.Active)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(names, &c.Names); err != nil {
+		return c, err
+	}
+	return c, json.Unmarshal(attributes, &c.Attributes)
+}
+
+// categoryJSON encodes the names and attributes of c for their JSONB
+// columns.
+func categoryJSON(c *models.Category) (names, attributes []byte, err error) {
+	if c.Names == nil {
+		c.Names = map[string]string{}
+	}
+	if c.Attributes == nil {
+		c.Attributes = []models.CategoryAttribute{}
+	}
+	if names, err = json.Marshal(c.Names); err != nil {
+		return nil, nil, err
+	}
+	attributes, err = json.Marshal(c.Attributes)
+	return names, attributes, err
+}
+
+// listCategories returns the categories matching where in sort order.
+func (s *PostgresStore) listCategories(where string) ([]models.Category, error) {
+	rows, err := s.DB.Query("SELECT " + categoryColumns + " FROM categories " + where + " ORDER BY sort_order, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func (s *PostgresStore) ListCategories() ([]models.Category, error) {
+	return s.listCategories("WHERE COALESCE(is_active, TRUE)")
+}
+
+func (s *PostgresStore) AllCategories() ([]models.Category, error) {
+	return s.listCategories("")
+}
+
+func (s *PostgresStore) CreateCategory(c *models.Category) error {
+	names, attributes, err := categoryJSON(c)
+	if err != nil {
+		return err
+	}
+	err = s.DB.QueryRow(
+		`INSERT INTO categories (parent_id, name, icon, type, sort_order, names, attributes, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		c.ParentID, c.Name, c.Icon, c.Type, c.SortOrder, names, attributes, c.Active,
+	).Scan(&c.ID)
+	return writeErr(err)
+}
+
+func (s *PostgresStore) UpdateCategory(c *models.Category) error {
+	names, attributes, err := categoryJSON(c)
+	if err != nil {
+		return err
+	}
+	res, err := s.DB.Exec(
+		`UPDATE categories SET parent_id = $2, name = $3, icon = $4, type = $5, sort_order = $6, names = $7,
+			attributes = $8, is_active = $9
+		WHERE id = $1`,
+		c.ID, c.ParentID, c.Name, c.Icon, c.Type, c.SortOrder, names, attributes, c.Active,
+	)
+	if err != nil {
+		return writeErr(err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) DeactivateCategory(id int) error {
+	var children int
+	err := s.DB.QueryRow(
+		"SELECT (SELECT COUNT(*) FROM categories sc WHERE sc.parent_id = c.id AND COALESCE(sc.is_active, TRUE)) FROM categories c WHERE c.id = $1",
+		id,
+	).Scan(&children)
+	if err != nil {
+		return notFound(err)
+	}
+	if children > 0 {
+		return ErrHasSubcategories
+	}
+	_, err = s.DB.Exec("UPDATE categories SET is_active = FALSE WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"github.com/TerraPaw/backend/models"
+)
+
+// conversation selects the messages exchanged between users $1 and $2.
+const conversation = "((m.sender_id = $1 AND m.receiver_id = $2) OR (m.sender_id = $2 AND m.receiver_id = $1))"
+
+func (s *PostgresStore) CreateMessage(m *models.Message) (int, error) {
+	var messageID int
+	err := s.DB.QueryRow(
+		`INSERT INTO messages (sender_id, receiver_id, content) VALUES ($1, $2, $3) RETURNING id`,
+		m.SenderID, m.ReceiverID, m.Content,
+	).Scan(&messageID)
+	return messageID, writeErr(err)
+}
+
+func (s *PostgresStore) ListMessages(userID, partnerID int, p PageRequest) (Page[models.Message], error) {
+	o := oldestFirst("m.created_at")
+	if err := o.check(p); err != nil {
+		return Page[models.Message]{}, err
+	}
+
+	args := []interface{}{userID, partnerID}
+	rows, err := s.DB.Query(
+		`SELECT m.id, m.sender_id, m.receiver_id, m.content, m.is_read, m.created_at,
+		        COALESCE(s.fullname, '') as sender_name, COALESCE(s.avatar_url, '') as sender_avatar,
+		        COALESCE(r.fullname, '') as receiver_name, COALESCE(r.avatar_url, '') as receiver_avatar
+		 FROM messages m
+		 JOIN users s ON m.sender_id = s.id
+		 JOIN users r ON m.receiver_id = r.id
+		 `+where(conversation, o.after(p, "m.id", &args))+" "+o.orderBy("m.id")+" "+limit(p, &args),
+		args...,
+	)
+	if err != nil {
+		return Page[models.Message]{}, err
+	}
+	defer rows.Close()
+
+	var messages []models.Message
+	for rows.Next() {
+		var m models.Message
+		var sender, receiver models.User
+
+		err := rows.Scan(
+			&m.ID, &m.SenderID, &m.ReceiverID, &m.Content, &m.IsRead, &m.CreatedAt,
+			&sender.FullName, &sender.AvatarURL, &receiver.FullName, &receiver.AvatarURL,
+		)
+		if err == nil {
+			sender.ID = m.SenderID
+			receiver.ID = m.ReceiverID
+			m.Sender = &sender
+			m.Receiver = &receiver
+			messages = append(messages, m)
+		}
+	}
+
+	page := newPage(messages, p, o, func(m models.Message) (interface{}, int) { return m.CreatedAt, m.ID })
+	err = fillTotal(s.DB, &page, p, "FROM messages m "+where(conversation), []interface{}{userID, partnerID}, false)
+	return page, err
+}
@@ -0,0 +1,233 @@
+package store
+
+import (
+	"log"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (s *PostgresStore) CreatePost(p *models.Post) (int, error) {
+	var postID int
+	// Start transaction
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.QueryRow(
+		"INSERT INTO posts (user_id, content) VALUES ($1, $2) RETURNING id",
+		p.UserID, p.Content,
+	).Scan(&postID)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	// Insert Media
+	for i, m := range p.Media {
+		_, err = tx.Exec(
+			"INSERT INTO post_media (post_id, media_url, media_type, sort_order) VALUES ($1, $2, $3, $4)",
+			postID, m.MediaURL, m.MediaType, i,
+		)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return postID, nil
+}
+
+func (s *PostgresStore) ListPosts(viewerID int, p PageRequest) (Page[models.Post], error) {
+	o := newestFirst("p.created_at")
+	if err := o.check(p); err != nil {
+		return Page[models.Post]{}, err
+	}
+
+	args := []interface{}{viewerID}
+	rows, err := s.DB.Query(
+		`SELECT p.id, p.user_id, p.content, COALESCE(p.image_url, ''), p.created_at, p.updated_at,
+		        COALESCE(u.id, 0), COALESCE(u.username, 'Unknown'), COALESCE(u.email, ''), COALESCE(u.fullname, ''), COALESCE(u.avatar_url, ''), COALESCE(u.bio, ''),
+		        COUNT(DISTINCT l.user_id) as like_count,
+                (SELECT COUNT(*) FROM comments WHERE post_id = p.id) as comment_count,
+                COUNT(DISTINCT b.user_id) as bookmark_count,
+                COUNT(DISTINCT s.user_id) as shares_count,
+                EXISTS(SELECT 1 FROM likes WHERE post_id = p.id AND user_id = $1) as is_liked,
+                EXISTS(SELECT 1 FROM bookmarks WHERE post_id = p.id AND user_id = $1) as is_bookmarked
+		FROM posts p
+		LEFT JOIN users u ON p.user_id = u.id
+		LEFT JOIN likes l ON p.id = l.post_id
+        LEFT JOIN bookmarks b ON p.id = b.post_id
+        LEFT JOIN post_shares s ON p.id = s.post_id
+		`+where(o.after(p, "p.id", &args))+`
+		GROUP BY p.id, u.id
+		`+o.orderBy("p.id")+" "+limit(p, &args),
+		args...,
+	)
+	if err != nil {
+		return Page[models.Post]{}, err
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		var post models.Post
+		var user models.User
+
+		err := rows.Scan(
+			&post.ID, &post.UserID, &post.Content, &post.ImageURL, &post.CreatedAt, &post.UpdatedAt,
+			&user.ID, &user.Username, &user.Email, &user.FullName, &user.AvatarURL, &user.Bio,
+			&post.Likes, &post.CommentsCount, &post.BookmarksCount, &post.SharesCount, &post.IsLiked, &post.IsBookmarked,
+		)
+		if err != nil {
+			log.Printf("Error scanning post row: %v", err)
+			continue
+		}
+
+		post.User = &user
+		post.Media = []models.PostMedia{} // Initialize empty slice
+		posts = append(posts, post)
+	}
+
+	page := newPage(posts, p, o, func(post models.Post) (interface{}, int) { return post.CreatedAt, post.ID })
+	// Pages are capped, so one media query per post is fast enough for now.
+	for i := range page.Items {
+		page.Items[i].Media = append(page.Items[i].Media, s.listPostMedia(page.Items[i].ID)...)
+	}
+	err = fillTotal(s.DB, &page, p, "FROM posts p", nil, true)
+	return page, err
+}
+
+func (s *PostgresStore) listPostMedia(postID int) []models.PostMedia {
+	var media []models.PostMedia
+	mediaRows, err := s.DB.Query(
+		"SELECT id, post_id, media_url, media_type, sort_order FROM post_media WHERE post_id = $1 ORDER BY sort_order ASC",
+		postID,
+	)
+	if err != nil {
+		return media
+	}
+	defer mediaRows.Close()
+	for mediaRows.Next() {
+		var pm models.PostMedia
+		if err := mediaRows.Scan(&pm.ID, &pm.PostID, &pm.MediaURL, &pm.MediaType, &pm.SortOrder); err == nil {
+			media = append(media, pm)
+		}
+	}
+	return media
+}
+
+func (s *PostgresStore) GetPost(id, viewerID int) (*models.Post, error) {
+	var post models.Post
+	var user models.User
+
+	err := s.DB.QueryRow(
+		`SELECT p.id, p.user_id, p.content, COALESCE(p.image_url, ''), p.created_at, p.updated_at,
+		        COALESCE(u.id, 0), COALESCE(u.username, 'Unknown'), COALESCE(u.email, ''), COALESCE(u.fullname, ''), COALESCE(u.avatar_url, ''), COALESCE(u.bio, ''),
+		        COUNT(DISTINCT l.user_id) as like_count,
+                (SELECT COUNT(*) FROM comments WHERE post_id = p.id) as comment_count,
+                COUNT(DISTINCT b.user_id) as bookmark_count,
+                COUNT(DISTINCT s.user_id) as shares_count,
+                EXISTS(SELECT 1 FROM likes WHERE post_id = p.id AND user_id = $2) as is_liked,
+                EXISTS(SELECT 1 FROM bookmarks WHERE post_id = p.id AND user_id = $2) as is_bookmarked
+		FROM posts p
+		LEFT JOIN users u ON p.user_id = u.id
+		LEFT JOIN likes l ON p.id = l.post_id
+        LEFT JOIN bookmarks b ON p.id = b.post_id
+        LEFT JOIN post_shares s ON p.id = s.post_id
+		WHERE p.id = $1
+		GROUP BY p.id, u.id`,
+		id, viewerID,
+	).Scan(
+		&post.ID, &post.UserID, &post.Content, &post.ImageURL, &post.CreatedAt, &post.UpdatedAt,
+		&user.ID, &user.Username, &user.Email, &user.FullName, &user.AvatarURL, &user.Bio,
+		&post.Likes, &post.CommentsCount, &post.BookmarksCount, &post.SharesCount, &post.IsLiked, &post.IsBookmarked,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	post.User = &user
+	post.Media = []models.PostMedia{}
+	post.Media = append(post.Media, s.listPostMedia(post.ID)...)
+
+	// Get comments (detailed list)
+	commentRows, err := s.DB.Query(
+		`SELECT c.id, c.post_id, c.user_id, c.content, c.created_at,
+		        u.id, u.username, u.email, COALESCE(u.fullname, ''), COALESCE(u.avatar_url, ''), COALESCE(u.bio, ''),
+                (SELECT COUNT(*) FROM comment_likes WHERE comment_id = c.id) as likes_count,
+                EXISTS(SELECT 1 FROM comment_likes WHERE comment_id = c.id AND user_id = $2) as is_liked
+		FROM comments c
+		INNER JOIN users u ON c.user_id = u.id
+		WHERE c.post_id = $1
+		ORDER BY c.created_at DESC`,
+		post.ID, viewerID,
+	)
+	if err == nil {
+		defer commentRows.Close()
+		for commentRows.Next() {
+			var comment models.Comment
+			var commentUser models.User
+
+			err := commentRows.Scan(
+				&comment.ID, &comment.PostID, &comment.UserID, &comment.Content, &comment.CreatedAt,
+				&commentUser.ID, &commentUser.Username, &commentUser.Email, &commentUser.FullName,
+				&commentUser.AvatarURL, &commentUser.Bio,
+				&comment.LikesCount, &comment.IsLiked,
+			)
+			if err == nil {
+				comment.User = &commentUser
+				post.Comments = append(post.Comments, comment)
+			}
+		}
+	}
+
+	return &post, nil
+}
+
+func (s *PostgresStore) LikePost(postID, userID int) error {
+	_, err := s.DB.Exec("INSERT INTO likes (post_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", postID, userID)
+	return writeErr(err)
+}
+
+func (s *PostgresStore) UnlikePost(postID, userID int) error {
+	_, err := s.DB.Exec("DELETE FROM likes WHERE post_id = $1 AND user_id = $2", postID, userID)
+	return err
+}
+
+func (s *PostgresStore) BookmarkPost(postID, userID int) error {
+	_, err := s.DB.Exec("INSERT INTO bookmarks (post_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", postID, userID)
+	return writeErr(err)
+}
+
+func (s *PostgresStore) UnbookmarkPost(postID, userID int) error {
+	_, err := s.DB.Exec("DELETE FROM bookmarks WHERE post_id = $1 AND user_id = $2", postID, userID)
+	return err
+}
+
+func (s *PostgresStore) SharePost(postID, userID int) error {
+	_, err := s.DB.Exec("INSERT INTO post_shares (post_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", postID, userID)
+	return writeErr(err)
+}
+
+func (s *PostgresStore) CreateComment(cm *models.Comment) (int, error) {
+	var commentID int
+	err := s.DB.QueryRow(
+		"INSERT INTO comments (post_id, user_id, content) VALUES ($1, $2, $3) RETURNING id",
+		cm.PostID, cm.UserID, cm.Content,
+	).Scan(&commentID)
+	return commentID, writeErr(err)
+}
+
+func (s *PostgresStore) LikeComment(commentID, userID int) error {
+	_, err := s.DB.Exec("INSERT INTO comment_likes (comment_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", commentID, userID)
+	return writeErr(err)
+}
+
+func (s *PostgresStore) UnlikeComment(commentID, userID int) error {
+	_, err := s.DB.Exec("DELETE FROM comment_likes WHERE comment_id = $1 AND user_id = $2", commentID, userID)
+	return err
+}
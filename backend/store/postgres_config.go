@@ -0,0 +1,45 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (s *PostgresStore) GetActiveSplash(now time.Time) (*models.SplashEvent, error) {
+	var splash models.SplashEvent
+
+	// Find active splash event where the given time is between start and end date
+	query := `
+		SELECT id, event_name, image_url, start_date, end_date, is_active
+		FROM splash_events
+		WHERE is_active = TRUE
+		AND start_date <= $1
+		AND end_date >= $1
+		ORDER BY start_date DESC
+		LIMIT 1`
+
+	err := s.DB.QueryRow(query, now).Scan(
+		&splash.ID,
+		&splash.EventName,
+		&splash.ImageURL,
+		&splash.StartDate,
+		&splash.EndDate,
+		&splash.IsActive,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &splash, nil
+}
+
+func (s *PostgresStore) CreateSplashEvent(e *models.SplashEvent) (int, error) {
+	query := `
+		INSERT INTO splash_events (event_name, image_url, start_date, end_date)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`
+
+	var id int
+	err := s.DB.QueryRow(query, e.EventName, e.ImageURL, e.StartDate, e.EndDate).Scan(&id)
+	return id, err
+}
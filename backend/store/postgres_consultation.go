@@ -0,0 +1,181 @@
+package store
+
+import (
+	"github.com/TerraPaw/backend/models"
+)
+
+func (s *PostgresStore) CreateVeterinarian(v *models.Veterinarian) (int, error) {
+	var vetID int
+	err := s.DB.QueryRow(
+		`INSERT INTO veterinarians (user_id, clinic_name, license_number, specialization, phone, address, bio)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		v.UserID, v.ClinicName, v.LicenseNumber, v.Specialization, v.Phone, v.Address, v.Bio,
+	).Scan(&vetID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Update user type
+	s.DB.Exec("UPDATE users SET user_type = 'veterinarian' WHERE id = $1", v.UserID)
+
+	return vetID, nil
+}
+
+func (s *PostgresStore) ListVeterinarians(p PageRequest) (Page[models.Veterinarian], error) {
+	o := vetOrder
+	if err := o.check(p); err != nil {
+		return Page[models.Veterinarian]{}, err
+	}
+
+	var args []interface{}
+	rows, err := s.DB.Query(
+		`SELECT v.id, v.user_id, v.clinic_name, v.license_number, v.specialization, v.phone,
+		        v.address, v.bio, v.rating, v.created_at, v.updated_at,
+		        u.id, u.username, u.email, u.fullname, u.avatar_url, u.bio
+		FROM veterinarians v
+		LEFT JOIN users u ON v.user_id = u.id
+		`+where(o.after(p, "v.id", &args))+" "+o.orderBy("v.id")+" "+limit(p, &args),
+		args...,
+	)
+	if err != nil {
+		return Page[models.Veterinarian]{}, err
+	}
+	defer rows.Close()
+
+	var vets []models.Veterinarian
+	for rows.Next() {
+		var vet models.Veterinarian
+		var user models.User
+
+		err := rows.Scan(
+			&vet.ID, &vet.UserID, &vet.ClinicName, &vet.LicenseNumber, &vet.Specialization, &vet.Phone,
+			&vet.Address, &vet.Bio, &vet.Rating, &vet.CreatedAt, &vet.UpdatedAt,
+			&user.ID, &user.Username, &user.Email, &user.FullName, &user.AvatarURL, &user.Bio,
+		)
+		if err == nil {
+			vet.User = &user
+			vets = append(vets, vet)
+		}
+	}
+
+	page := newPage(vets, p, o, func(v models.Veterinarian) (interface{}, int) { return v.Rating, v.ID })
+	err = fillTotal(s.DB, &page, p, "FROM veterinarians v", nil, true)
+	return page, err
+}
+
+func (s *PostgresStore) GetVeterinarian(id int) (*models.Veterinarian, error) {
+	var vet models.Veterinarian
+	var user models.User
+
+	err := s.DB.QueryRow(
+		`SELECT v.id, v.user_id, v.clinic_name, v.license_number, v.specialization, v.phone,
+		        v.address, v.bio, v.rating, v.created_at, v.updated_at,
+		        u.id, u.username, u.email, u.fullname, u.avatar_url, u.bio
+		FROM veterinarians v
+		LEFT JOIN users u ON v.user_id = u.id
+		WHERE v.id = $1`,
+		id,
+	).Scan(
+		&vet.ID, &vet.UserID, &vet.ClinicName, &vet.LicenseNumber, &vet.Specialization, &vet.Phone,
+		&vet.Address, &vet.Bio, &vet.Rating, &vet.CreatedAt, &vet.UpdatedAt,
+		&user.ID, &user.Username, &user.Email, &user.FullName, &user.AvatarURL, &user.Bio,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	vet.User = &user
+	return &vet, nil
+}
+
+func (s *PostgresStore) CreateConsultation(co *models.Consultation) (int, error) {
+	var consultationID int
+	err := s.DB.QueryRow(
+		`INSERT INTO consultations (user_id, veterinarian_id, pet_name, symptoms, consultation_type, status, scheduled_at)
+		VALUES ($1, $2, $3, $4, $5, 'pending', $6) RETURNING id`,
+		co.UserID, co.VeterinarianID, co.PetName, co.Symptoms, co.ConsultationType, co.ScheduledAt,
+	).Scan(&consultationID)
+	return consultationID, writeErr(err)
+}
+
+func (s *PostgresStore) ListConsultations(userID int, p PageRequest) (Page[models.Consultation], error) {
+	o := newestFirst("co.created_at")
+	if err := o.check(p); err != nil {
+		return Page[models.Consultation]{}, err
+	}
+
+	args := []interface{}{userID}
+	rows, err := s.DB.Query(
+		`SELECT co.id, co.user_id, co.veterinarian_id, co.pet_name, co.symptoms, co.consultation_type,
+		        co.status, co.scheduled_at, co.created_at, co.updated_at,
+		        v.id, v.clinic_name, v.specialization, v.phone
+		FROM consultations co
+		LEFT JOIN veterinarians v ON co.veterinarian_id = v.id
+		`+where("co.user_id = $1", o.after(p, "co.id", &args))+" "+o.orderBy("co.id")+" "+limit(p, &args),
+		args...,
+	)
+	if err != nil {
+		return Page[models.Consultation]{}, err
+	}
+	defer rows.Close()
+
+	var consultations []models.Consultation
+	for rows.Next() {
+		var consultation models.Consultation
+		var vet models.Veterinarian
+
+		err := rows.Scan(
+			&consultation.ID, &consultation.UserID, &consultation.VeterinarianID, &consultation.PetName,
+			&consultation.Symptoms, &consultation.ConsultationType, &consultation.Status, &consultation.ScheduledAt,
+			&consultation.CreatedAt, &consultation.UpdatedAt,
+			&vet.ID, &vet.ClinicName, &vet.Specialization, &vet.Phone,
+		)
+		if err == nil {
+			consultation.Veterinarian = &vet
+			consultations = append(consultations, consultation)
+		}
+	}
+
+	page := newPage(consultations, p, o, func(co models.Consultation) (interface{}, int) { return co.CreatedAt, co.ID })
+	err = fillTotal(s.DB, &page, p, "FROM consultations co WHERE co.user_id = $1", []interface{}{userID}, false)
+	return page, err
+}
+
+func (s *PostgresStore) GetConsultation(id int) (*models.Consultation, error) {
+	var consultation models.Consultation
+	var vet models.Veterinarian
+	var user models.User
+
+	err := s.DB.QueryRow(
+		`SELECT co.id, co.user_id, co.veterinarian_id, co.pet_name, co.symptoms, co.consultation_type,
+		        co.status, co.scheduled_at, co.created_at, co.updated_at,
+		        v.id, v.clinic_name, v.specialization, v.phone,
+		        u.id, u.username, u.email, u.fullname
+		FROM consultations co
+		LEFT JOIN veterinarians v ON co.veterinarian_id = v.id
+		LEFT JOIN users u ON co.user_id = u.id
+		WHERE co.id = $1`,
+		id,
+	).Scan(
+		&consultation.ID, &consultation.UserID, &consultation.VeterinarianID, &consultation.PetName,
+		&consultation.Symptoms, &consultation.ConsultationType, &consultation.Status, &consultation.ScheduledAt,
+		&consultation.CreatedAt, &consultation.UpdatedAt,
+		&vet.ID, &vet.ClinicName, &vet.Specialization, &vet.Phone,
+		&user.ID, &user.Username, &user.Email, &user.FullName,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	consultation.Veterinarian = &vet
+	consultation.User = &user
+	return &consultation, nil
+}
+
+func (s *PostgresStore) UpdateConsultationStatus(id int, status string) error {
+	_, err := s.DB.Exec(
+		"UPDATE consultations SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
+		status, id,
+	)
+	return err
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (s *PostgresStore) ClaimIdempotencyKey(k *models.IdempotencyKey, ttl, lease time.Duration) (*models.IdempotencyKey, error) {
+	// Expired keys, and keys whose request died in flight, may be reused.
+	now := time.Now()
+	_, err := s.DB.Exec(
+		`DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND idem_key = $2
+		  AND (created_at < $3 OR (response_status = 0 AND created_at < $4))`,
+		k.UserID, k.Key, now.Add(-ttl), now.Add(-lease),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.DB.Exec(`
+		INSERT INTO idempotency_keys (user_id, idem_key, fingerprint)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, idem_key) DO NOTHING`,
+		k.UserID, k.Key, k.Fingerprint,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return nil, nil
+	}
+
+	existing := models.IdempotencyKey{UserID: k.UserID, Key: k.Key}
+	err = s.DB.QueryRow(`
+		SELECT fingerprint, response_status, COALESCE(response_body, ''::bytea), created_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idem_key = $2`,
+		k.UserID, k.Key,
+	).Scan(&existing.Fingerprint, &existing.Status, &existing.Body, &existing.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &existing, ErrConflict
+}
+
+func (s *PostgresStore) SaveIdempotentResponse(userID int, key string, status int, body []byte) error {
+	_, err := s.DB.Exec(
+		"UPDATE idempotency_keys SET response_status = $1, response_body = $2 WHERE user_id = $3 AND idem_key = $4",
+		status, body, userID, key,
+	)
+	return err
+}
+
+func (s *PostgresStore) ReleaseIdempotencyKey(userID int, key string) error {
+	_, err := s.DB.Exec("DELETE FROM idempotency_keys WHERE user_id = $1 AND idem_key = $2", userID, key)
+	return err
+}
@@ -0,0 +1,262 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// lockListing locks a seller's listing for the rest of tx and returns its
+// price and stock.
+func lockListing(tx *sql.Tx, id, sellerID int) (price float64, stock int, err error) {
+	var owner int
+	err = tx.QueryRow(
+		"SELECT seller_id, COALESCE(price, 0), COALESCE(stock, 0) FROM animals WHERE id = $1 AND status <> 'deleted' FOR UPDATE",
+		id,
+	).Scan(&owner, &price, &stock)
+	if err != nil {
+		return 0, 0, notFound(err)
+	}
+	if owner != sellerID {
+		return 0, 0, ErrNotOwner
+	}
+	return price, stock, nil
+}
+
+// addListingCount adds n listings in status to c. Deleted listings are not
+// counted.
+func addListingCount(c *models.ListingCounts, status string, n int) {
+	switch status {
+	case StatusAvailable:
+		c.Available += n
+	case StatusReserved:
+		c.Reserved += n
+	case StatusSold:
+		c.Sold += n
+	case StatusArchived:
+		c.Archived += n
+	}
+}
+
+func (s *PostgresStore) UpdateAnimal(id, sellerID int, u AnimalUpdate) (*models.Animal, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	price, stock, err := lockListing(tx, id, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Status != nil && *u.Status == StatusAvailable && stock <= 0 {
+		return nil, ErrInsufficientStock
+	}
+
+	var attributes interface{} // NULL keeps them
+	if u.Attributes != nil {
+		if attributes, err = attributesJSON(u.Attributes); err != nil {
+			return nil, err
+		}
+	}
+	lat, lng := coordinates(u.Position)
+	_, err = tx.Exec(`
+		UPDATE animals SET
+			animal_type = COALESCE($2, animal_type),
+			breed = COALESCE($3, breed), name = COALESCE($4, name),
+			age = COALESCE($5, age), description = COALESCE($6, description), price = COALESCE($7, price),
+			image_url = COALESCE($8, image_url), location = COALESCE($9, location), color = COALESCE($10, color),
+			gender = COALESCE($11, gender), status = COALESCE($12, status),
+			latitude = CASE WHEN $13 THEN $14::float8 ELSE latitude END,
+			longitude = CASE WHEN $13 THEN $15::float8 ELSE longitude END,
+			attributes = COALESCE($16::jsonb, attributes),
+			category_id = CASE WHEN $17 THEN $18::int ELSE category_id END, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		id, u.AnimalType, u.Breed, u.Name, u.Age, u.Description, u.Price, u.ImageURL, u.Location, u.Color, u.Gender, u.Status,
+		u.Relocated, lat, lng, attributes, u.Refiled, u.CategoryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Price != nil && *u.Price != price {
+		_, err = tx.Exec(
+			"INSERT INTO animal_price_history (animal_id, old_price, new_price) VALUES ($1, $2, $3)",
+			id, price, *u.Price,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetAnimal(id)
+}
+
+func (s *PostgresStore) DeleteAnimal(id, sellerID int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockListing(tx, id, sellerID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		"UPDATE animals SET status = 'deleted', deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) RestockAnimal(id, sellerID, quantity int) (*models.Animal, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockListing(tx, id, sellerID); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`
+		UPDATE animals SET
+			stock = COALESCE(stock, 0) + $2,
+			status = CASE WHEN status = 'sold' THEN 'available' ELSE status END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		id, quantity,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetAnimal(id)
+}
+
+func (s *PostgresStore) ListSellerAnimals(sellerID int, status string, p PageRequest) (Page[models.Animal], error) {
+	o := newestFirst("a.created_at")
+	if err := o.check(p); err != nil {
+		return Page[models.Animal]{}, err
+	}
+
+	q := &query{}
+	q.add("a.seller_id = ?", sellerID)
+	if status != "" {
+		q.add("a.status = ?", status)
+	} else {
+		q.add("a.status <> 'deleted'")
+	}
+	filterArgs := append([]interface{}(nil), q.args...)
+
+	rows, err := s.DB.Query(
+		animalSelect+"\n"+q.where(o.after(p, "a.id", &q.args))+" "+o.orderBy("a.id")+" "+limit(p, &q.args),
+		q.args...,
+	)
+	if err != nil {
+		return Page[models.Animal]{}, err
+	}
+	defer rows.Close()
+
+	var animals []models.Animal
+	for rows.Next() {
+		if animal, err := scanAnimal(rows); err == nil {
+			animals = append(animals, animal)
+		}
+	}
+
+	page := newPage(animals, p, o, animalKey(o))
+	err = fillTotal(s.DB, &page, p, "FROM animals a "+where(q.preds...), filterArgs, false)
+	return page, err
+}
+
+func (s *PostgresStore) CountSellerAnimals(sellerID int) (*models.ListingCounts, error) {
+	rows, err := s.DB.Query("SELECT status, COUNT(*) FROM animals WHERE seller_id = $1 GROUP BY status", sellerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts models.ListingCounts
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		addListingCount(&counts, status, n)
+	}
+	return &counts, rows.Err()
+}
+
+func (s *PostgresStore) AddAnimalMedia(animalID, sellerID int, m *models.AnimalMedia) (int, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockListing(tx, animalID, sellerID); err != nil {
+		return 0, err
+	}
+	var mediaID int
+	err = tx.QueryRow(`
+		INSERT INTO animal_media (animal_id, media_url, media_type, thumbnail_url, sort_order)
+		VALUES ($1, $2, $3, $4, (SELECT COALESCE(MAX(sort_order) + 1, 0) FROM animal_media WHERE animal_id = $1))
+		RETURNING id`,
+		animalID, m.MediaURL, m.MediaType, m.ThumbnailURL,
+	).Scan(&mediaID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(
+		"UPDATE animals SET image_url = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND COALESCE(image_url, '') = ''",
+		animalID, m.MediaURL,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return mediaID, tx.Commit()
+}
+
+func (s *PostgresStore) ListPriceHistory(animalID int) ([]models.PriceChange, error) {
+	var exists bool
+	err := s.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM animals WHERE id = $1 AND status <> 'deleted')", animalID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT id, animal_id, COALESCE(old_price, 0), COALESCE(new_price, 0), changed_at
+		FROM animal_price_history
+		WHERE animal_id = $1
+		ORDER BY changed_at DESC, id DESC`,
+		animalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.PriceChange{}
+	for rows.Next() {
+		var pc models.PriceChange
+		if err := rows.Scan(&pc.ID, &pc.AnimalID, &pc.OldPrice, &pc.NewPrice, &pc.ChangedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, pc)
+	}
+	return history, rows.Err()
+}
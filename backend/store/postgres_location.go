@@ -0,0 +1,57 @@
+package store
+
+import (
+	"github.com/TerraPaw/backend/models"
+)
+
+func (s *PostgresStore) ListUnlocatedAnimals(afterID, limit int) ([]models.Animal, error) {
+	rows, err := s.DB.Query(
+		`SELECT id, location FROM animals
+		WHERE id > $1 AND status <> 'deleted' AND COALESCE(location, '') <> '' AND latitude IS NULL
+		ORDER BY id
+		LIMIT $2`,
+		afterID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listings []models.Animal
+	for rows.Next() {
+		var a models.Animal
+		if err := rows.Scan(&a.ID, &a.Location); err != nil {
+			return nil, err
+		}
+		listings = append(listings, a)
+	}
+	return listings, rows.Err()
+}
+
+func (s *PostgresStore) LocateAnimal(id int, location string, p models.GeoPoint) (bool, error) {
+	res, err := s.DB.Exec(
+		"UPDATE animals SET latitude = $3, longitude = $4 WHERE id = $1 AND location = $2 AND latitude IS NULL",
+		id, location, p.Latitude, p.Longitude,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *PostgresStore) SetUserLocation(userID int, location string, p models.GeoPoint) error {
+	res, err := s.DB.Exec(
+		"UPDATE users SET location = $2, latitude = $3, longitude = $4, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		userID, location, p.Latitude, p.Longitude,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
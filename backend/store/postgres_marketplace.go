@@ -0,0 +1,616 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TerraPaw/backend/models"
+	"github.com/lib/pq"
+)
+
+const animalSelect = `SELECT ` + animalColumns + animalFrom
+
+const animalColumns = `a.id, a.seller_id, a.animal_type, a.category_id, a.attributes, COALESCE(a.breed, ''), a.name, a.age, COALESCE(a.description, ''),
+	                 a.price, COALESCE(a.image_url, ''), COALESCE(a.location, ''), a.rating, a.review_count, a.status,
+                     COALESCE(a.color, ''), COALESCE(a.gender, ''), COALESCE(a.stock, 0), a.latitude, a.longitude,
+                     a.created_at, a.updated_at, ` + animalPopularity + `,
+	                 u.id, u.username, u.email, u.fullname, COALESCE(u.avatar_url, ''), COALESCE(u.bio, ''),
+	                 sh.id, sh.slug, sh.name`
+
+// animalFrom joins what animalColumns reads besides the listing.
+const animalFrom = `
+	          FROM animals a
+	          LEFT JOIN users u ON a.seller_id = u.id
+	          LEFT JOIN shops sh ON sh.seller_id = a.seller_id`
+
+// animalPopularity ranks listings by orders placed plus wishlist saves.
+const animalPopularity = `((SELECT COUNT(*) FROM order_items po WHERE po.animal_id = a.id) +
+	(SELECT COUNT(*) FROM wishlists pw WHERE pw.animal_id = a.id))`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAnimal reads a row selected with animalColumns. extra receives any
+// columns selected after them.
+func scanAnimal(row rowScanner, extra ...interface{}) (models.Animal, error) {
+	var animal models.Animal
+	var seller models.User
+	var shopID sql.NullInt64
+	var shopSlug, shopName sql.NullString
+	var attributes []byte
+	err := row.Scan(append([]interface{}{
+		&animal.ID, &animal.SellerID, &animal.AnimalType, &animal.CategoryID, &attributes, &animal.Breed, &animal.Name, &animal.Age,
+		&animal.Description, &animal.Price, &animal.ImageURL, &animal.Location, &animal.Rating, &animal.ReviewCount, &animal.Status,
+		&animal.Color, &animal.Gender, &animal.Stock, &animal.Latitude, &animal.Longitude,
+		&animal.CreatedAt, &animal.UpdatedAt, &animal.Popularity,
+		&seller.ID, &seller.Username, &seller.Email, &seller.FullName, &seller.AvatarURL, &seller.Bio,
+		&shopID, &shopSlug, &shopName,
+	}, extra...)...)
+	animal.Seller = &seller
+	if shopID.Valid {
+		animal.Shop = &models.ShopRef{ID: int(shopID.Int64), Slug: shopSlug.String, Name: shopName.String}
+	}
+	if err != nil {
+		return animal, err
+	}
+	return animal, json.Unmarshal(attributes, &animal.Attributes)
+}
+
+func (s *PostgresStore) ListAnimals(f AnimalFilter, p PageRequest) (Page[models.Animal], error) {
+	o := animalOrder(f.Sort)
+	if err := o.check(p); err != nil {
+		return Page[models.Animal]{}, err
+	}
+
+	q, rank, distance := animalQuery(f)
+	switch o.name {
+	case "relevance":
+		o.column = rank
+	case "distance":
+		o.column = distance
+	}
+	filterArgs := append([]interface{}(nil), q.args...)
+	sql := "SELECT " + animalColumns + ", " + rank + ", " + distance + animalFrom + "\n" +
+		q.where(o.after(p, "a.id", &q.args)) + " " + o.orderBy("a.id") + " " + limit(p, &q.args)
+
+	var page Page[models.Animal]
+	err := s.withSearch(f.Search, func(db queryer) error {
+		rows, err := db.Query(sql, q.args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var animals []models.Animal
+		for rows.Next() {
+			var relevance float64
+			var distance *float64
+			if animal, err := scanAnimal(rows, &relevance, &distance); err == nil {
+				animal.Relevance, animal.DistanceKm = relevance, distance
+				animals = append(animals, animal)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		page = newPage(animals, p, o, animalKey(o))
+		return fillTotal(db, &page, p, "FROM animals a "+where(q.preds...), filterArgs, true)
+	})
+	return page, err
+}
+
+// withSearch runs fn against the database. When search has terms it runs
+// in a read-only transaction in which the pg_trgm <% and %> operators
+// match at similarityThreshold; the setting is local to the transaction,
+// so pooled connections keep the server's default.
+func (s *PostgresStore) withSearch(search string, fn func(db queryer) error) error {
+	if len(searchTerms(search)) == 0 {
+		return fn(s.DB)
+	}
+	tx, err := s.DB.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)",
+		strconv.FormatFloat(similarityThreshold, 'f', -1, 64))
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// animalQuery translates f into bound predicates over animals a. rank is
+// the search relevance expression, or 0 when f has no search terms;
+// distance is the distance in km from f.Near, or NULL without one.
+func animalQuery(f AnimalFilter) (q *query, rank, distance string) {
+	q = &query{}
+	rank = "0::float8"
+	distance = "NULL::float8"
+	q.add("a.status = 'available'")
+	if f.AnimalType != "" {
+		q.add("a.animal_type ILIKE ?", likePattern(f.AnimalType))
+	}
+	if terms := searchTerms(f.Search); len(terms) > 0 {
+		// Word prefixes through the full-text index, or a close enough
+		// trigram match for misspellings through the trigram index. <%
+		// needs the threshold withSearch sets; word_similarity only ranks.
+		q.add("(a.search_vector @@ to_tsquery('"+searchConfig+"', ?) OR ? <% "+animalSearchText+")",
+			prefixQuery(terms), strings.Join(terms, " "))
+		tsq, raw := len(q.args)-1, len(q.args)
+		rank = fmt.Sprintf("(ts_rank(a.search_vector, to_tsquery('%s', $%d)) + word_similarity($%d, %s))::float8",
+			searchConfig, tsq, raw, animalSearchText)
+	}
+	if f.Breed != "" {
+		q.add("a.breed ILIKE ?", likePattern(f.Breed))
+	}
+	if f.Gender != "" {
+		q.add("LOWER(a.gender) = LOWER(?)", f.Gender)
+	}
+	if f.Color != "" {
+		q.add("LOWER(a.color) = LOWER(?)", f.Color)
+	}
+	if f.Location != "" {
+		q.add("a.location ILIKE ?", likePattern(f.Location))
+	}
+	if f.MinPrice != nil {
+		q.add("a.price >= ?", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		q.add("a.price <= ?", *f.MaxPrice)
+	}
+	if f.MinAge != nil {
+		q.add("a.age >= ?", *f.MinAge)
+	}
+	if f.MaxAge != nil {
+		q.add("a.age <= ?", *f.MaxAge)
+	}
+	if f.MinRating != nil {
+		q.add("a.rating >= ?", *f.MinRating)
+	}
+	if f.SellerID != 0 {
+		q.add("a.seller_id = ?", f.SellerID)
+	}
+	if f.CategoryID != 0 {
+		q.add("a.category_id IN ("+categorySubtree+")", f.CategoryID)
+	}
+	if f.FollowedBy != 0 {
+		q.add("a.seller_id IN (SELECT fs.seller_id FROM shop_follows ff JOIN shops fs ON fs.id = ff.shop_id WHERE ff.user_id = ?)", f.FollowedBy)
+	}
+	if f.InStock {
+		q.add("a.stock > 0")
+	}
+	if f.Near != nil {
+		// The cube around the point goes through the GiST index on
+		// ll_to_earth; the distance then trims its corners.
+		q.add("earth_box(ll_to_earth(?, ?), ?) @> ll_to_earth(a.latitude, a.longitude)",
+			f.Near.Latitude, f.Near.Longitude, f.RadiusKm*1000)
+		n := len(q.args)
+		distance = fmt.Sprintf("ROUND((earth_distance(ll_to_earth($%d, $%d), ll_to_earth(a.latitude, a.longitude)) / 1000)::numeric, 2)::float8",
+			n-2, n-1)
+		q.add(distance+" <= ?", f.RadiusKm)
+	}
+	return q, rank, distance
+}
+
+func (s *PostgresStore) AnimalFacets(f AnimalFilter) (*models.AnimalFacets, error) {
+	q, _, _ := animalQuery(f)
+	q.args = append(q.args, pq.Array(priceBounds))
+	bucket := fmt.Sprintf("width_bucket(a.price, $%d::numeric[])", len(q.args))
+
+	counts := newFacetCounts()
+	err := s.withSearch(f.Search, func(db queryer) error {
+		// One pass over the matching rows, grouped once per facet.
+		rows, err := db.Query(`
+			SELECT CASE
+			           WHEN GROUPING(a.animal_type) = 0 THEN 'animal_type'
+			           WHEN GROUPING(a.breed) = 0 THEN 'breed'
+			           WHEN GROUPING(a.color) = 0 THEN 'color'
+			           WHEN GROUPING(a.gender) = 0 THEN 'gender'
+			           WHEN GROUPING(a.location) = 0 THEN 'location'
+			           ELSE 'price'
+			       END,
+			       COALESCE(CASE
+			           WHEN GROUPING(a.animal_type) = 0 THEN a.animal_type
+			           WHEN GROUPING(a.breed) = 0 THEN a.breed
+			           WHEN GROUPING(a.color) = 0 THEN a.color
+			           WHEN GROUPING(a.gender) = 0 THEN a.gender
+			           WHEN GROUPING(a.location) = 0 THEN a.location
+			           ELSE `+bucket+`::text
+			       END, ''),
+			       COUNT(*)
+			FROM animals a
+			`+q.where()+`
+			GROUP BY GROUPING SETS ((a.animal_type), (a.breed), (a.color), (a.gender), (a.location), (`+bucket+`))`,
+			q.args...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var facet, value string
+			var n int
+			if err := rows.Scan(&facet, &value, &n); err != nil {
+				return err
+			}
+			if facet != "price" {
+				counts.add(facet, value, n)
+				continue
+			}
+			if i, err := strconv.Atoi(value); err == nil && i >= 0 && i < len(counts.prices) {
+				counts.prices[i] += n
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts.facets(), nil
+}
+
+// SuggestAnimals matches q as a prefix, or through the trigram indexes on
+// each column as a close enough word, in every branch of the union so each
+// can use its index.
+func (s *PostgresStore) SuggestAnimals(q string, limit int) ([]models.SearchSuggestion, error) {
+	suggestions := []models.SearchSuggestion{}
+	err := s.withSearch(q, func(db queryer) error {
+		rows, err := db.Query(`
+			SELECT text, kind FROM (
+				SELECT DISTINCT ON (LOWER(text), kind) text, kind,
+				       text ILIKE $2 AS is_prefix, word_similarity($1, text) AS sim
+				FROM (
+					SELECT name AS text, 'name' AS kind FROM animals
+					WHERE status = 'available' AND (name ILIKE $2 OR $1 <% name)
+					UNION ALL
+					SELECT breed, 'breed' FROM animals
+					WHERE status = 'available' AND breed <> '' AND (breed ILIKE $2 OR $1 <% breed)
+					UNION ALL
+					SELECT animal_type, 'animal_type' FROM animals
+					WHERE status = 'available' AND (animal_type ILIKE $2 OR $1 <% animal_type)
+				) terms
+				ORDER BY LOWER(text), kind
+			) matches
+			ORDER BY is_prefix DESC, sim DESC, text
+			LIMIT $3`,
+			q, likePrefix(q), limit,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var sg models.SearchSuggestion
+			if err := rows.Scan(&sg.Text, &sg.Kind); err != nil {
+				return err
+			}
+			suggestions = append(suggestions, sg)
+		}
+		return rows.Err()
+	})
+	return suggestions, err
+}
+
+func (s *PostgresStore) GetAnimal(id int) (*models.Animal, error) {
+	animal, err := scanAnimal(s.DB.QueryRow(animalSelect+`
+		WHERE a.id = $1 AND a.status <> 'deleted'`, id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	// Fetch Media
+	mediaRows, err := s.DB.Query("SELECT id, animal_id, media_url, media_type, COALESCE(thumbnail_url, ''), sort_order FROM animal_media WHERE animal_id = $1 ORDER BY sort_order ASC", animal.ID)
+	if err == nil {
+		defer mediaRows.Close()
+		for mediaRows.Next() {
+			var m models.AnimalMedia
+			if err := mediaRows.Scan(&m.ID, &m.AnimalID, &m.MediaURL, &m.MediaType, &m.ThumbnailURL, &m.SortOrder); err == nil {
+				animal.Media = append(animal.Media, m)
+			}
+		}
+	}
+
+	return &animal, nil
+}
+
+// attributesJSON encodes a listing's attribute values for
+// animals.attributes.
+func attributesJSON(values models.AttributeMap) ([]byte, error) {
+	if values == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(values)
+}
+
+func (s *PostgresStore) CreateAnimal(a *models.Animal) (int, error) {
+	attributes, err := attributesJSON(a.Attributes)
+	if err != nil {
+		return 0, err
+	}
+	var animalID int
+	err = s.DB.QueryRow(
+		`INSERT INTO animals (seller_id, animal_type, breed, name, age, description, price, image_url, location, status, color, gender, stock, latitude, longitude, category_id, attributes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'available', $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id`,
+		a.SellerID, a.AnimalType, a.Breed, a.Name, a.Age, a.Description, a.Price, a.ImageURL, a.Location, a.Color, a.Gender, a.Stock,
+		a.Latitude, a.Longitude, a.CategoryID, attributes,
+	).Scan(&animalID)
+	return animalID, err
+}
+
+func (s *PostgresStore) CreateOrder(buyerID, animalID, quantity int) (int, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Lock the listing so concurrent orders see each other's stock.
+	var price float64
+	var stock, sellerID int
+	var status string
+	err = tx.QueryRow(
+		"SELECT price, COALESCE(stock, 0), status, seller_id FROM animals WHERE id = $1 AND status <> 'deleted' FOR UPDATE", animalID,
+	).Scan(&price, &stock, &status, &sellerID)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	if stock < quantity {
+		return 0, ErrInsufficientStock
+	}
+	if status != StatusAvailable {
+		return 0, ErrUnavailable
+	}
+
+	var orderID int
+	err = tx.QueryRow(
+		"INSERT INTO orders (buyer_id, animal_id, seller_id, total_price, status, quantity) VALUES ($1, $2, $3, $4, 'pending', $5) RETURNING id",
+		buyerID, animalID, sellerID, price*float64(quantity), quantity,
+	).Scan(&orderID)
+	if err != nil {
+		return 0, err
+	}
+	item := models.OrderItem{OrderID: orderID, AnimalID: animalID, SellerID: sellerID, Quantity: quantity, UnitPrice: price}
+	if err := insertOrderItem(tx, &item); err != nil {
+		return 0, err
+	}
+
+	return orderID, tx.Commit()
+}
+
+func (s *PostgresStore) CreateVariantOrder(buyerID, variantID, quantity int) (int, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Lock the variant so concurrent orders see each other's stock.
+	var price float64
+	var stock, sellerID int
+	var status string
+	err = tx.QueryRow(
+		`SELECT v.price, v.stock, p.status, p.seller_id
+		FROM product_variants v JOIN products p ON p.id = v.product_id
+		WHERE v.id = $1 FOR UPDATE OF v`, variantID,
+	).Scan(&price, &stock, &status, &sellerID)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	if stock < quantity {
+		return 0, ErrInsufficientStock
+	}
+	if status != ProductActive {
+		return 0, ErrUnavailable
+	}
+
+	var orderID int
+	err = tx.QueryRow(
+		"INSERT INTO orders (buyer_id, seller_id, total_price, status, quantity) VALUES ($1, $2, $3, 'pending', $4) RETURNING id",
+		buyerID, sellerID, price*float64(quantity), quantity,
+	).Scan(&orderID)
+	if err != nil {
+		return 0, err
+	}
+	item := models.OrderItem{OrderID: orderID, VariantID: variantID, SellerID: sellerID, Quantity: quantity, UnitPrice: price}
+	if err := insertOrderItem(tx, &item); err != nil {
+		return 0, err
+	}
+
+	return orderID, tx.Commit()
+}
+
+// insertOrderItem stores an order line and takes its stock from the
+// listing or variant, which tx must hold locked.
+func insertOrderItem(tx *sql.Tx, item *models.OrderItem) error {
+	err := tx.QueryRow(
+		`INSERT INTO order_items (order_id, animal_id, variant_id, seller_id, quantity, unit_price)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		item.OrderID, nullID(item.AnimalID), nullID(item.VariantID), item.SellerID, item.Quantity, item.UnitPrice,
+	).Scan(&item.ID, &item.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if item.VariantID != 0 {
+		return takeVariantStock(tx, item.VariantID, item.Quantity)
+	}
+	return takeStock(tx, item.AnimalID, item.Quantity)
+}
+
+// takeStock removes quantity from a listing locked by tx, marking it sold
+// when none is left. The stock condition is a second guard behind the lock
+// and the animals_stock_check constraint.
+func takeStock(tx *sql.Tx, animalID, quantity int) error {
+	res, err := tx.Exec(
+		`UPDATE animals SET stock = stock - $1,
+		        status = CASE WHEN stock - $1 = 0 THEN 'sold' ELSE status END,
+		        updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND stock >= $1`,
+		quantity, animalID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetOrder(id, buyerID int) (*models.Order, error) {
+	var order models.Order
+	err := s.DB.QueryRow(
+		"SELECT id, buyer_id, animal_id, total_price, status, quantity, created_at FROM orders WHERE id = $1 AND buyer_id = $2",
+		id, buyerID,
+	).Scan(&order.ID, &order.BuyerID, &order.AnimalID, &order.TotalPrice, &order.Status, &order.Quantity, &order.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &order, nil
+}
+
+func (s *PostgresStore) ListOrders(buyerID int, p PageRequest) (Page[models.Order], error) {
+	o := newestFirst("o.created_at")
+	if err := o.check(p); err != nil {
+		return Page[models.Order]{}, err
+	}
+
+	// Sub-orders are listed through their checkout header.
+	args := []interface{}{buyerID}
+	rows, err := s.DB.Query(
+		`SELECT o.id, o.buyer_id, COALESCE(o.animal_id, 0), o.seller_id, o.total_price, COALESCE(o.discount, 0), COALESCE(o.shipping_cost, 0), o.status, o.quantity, o.created_at,
+		        a.id, a.seller_id, a.animal_type, COALESCE(a.breed, ''), a.name, a.price, COALESCE(a.image_url, '')
+		FROM orders o
+		LEFT JOIN animals a ON o.animal_id = a.id
+		`+where("o.buyer_id = $1 AND o.parent_id IS NULL", o.after(p, "o.id", &args))+" "+o.orderBy("o.id")+" "+limit(p, &args),
+		args...,
+	)
+	if err != nil {
+		return Page[models.Order]{}, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		var order models.Order
+		var sellerID sql.NullInt64
+		var animalID, animalSeller sql.NullInt64
+		var animalType, breed, name, imageURL sql.NullString
+		var price sql.NullFloat64
+		err := rows.Scan(
+			&order.ID, &order.BuyerID, &order.AnimalID, &sellerID, &order.TotalPrice, &order.Discount, &order.Shipping, &order.Status, &order.Quantity, &order.CreatedAt,
+			&animalID, &animalSeller, &animalType, &breed, &name, &price, &imageURL,
+		)
+		if err != nil {
+			return Page[models.Order]{}, err
+		}
+		if sellerID.Valid {
+			id := int(sellerID.Int64)
+			order.SellerID = &id
+		}
+		if animalID.Valid {
+			order.Animal = &models.Animal{
+				ID: int(animalID.Int64), SellerID: int(animalSeller.Int64), AnimalType: animalType.String,
+				Breed: breed.String, Name: name.String, Price: price.Float64, ImageURL: imageURL.String,
+			}
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[models.Order]{}, err
+	}
+
+	page := newPage(orders, p, o, func(row models.Order) (interface{}, int) { return row.CreatedAt, row.ID })
+	if err := s.attachOrderItems(page.Items); err != nil {
+		return page, err
+	}
+	err = fillTotal(s.DB, &page, p, "FROM orders o WHERE o.buyer_id = $1 AND o.parent_id IS NULL", []interface{}{buyerID}, false)
+	return page, err
+}
+
+func (s *PostgresStore) AddToWishlist(userID, animalID int) error {
+	// A missing listing leaves alert_price NULL and fails the foreign key.
+	_, err := s.DB.Exec(
+		`INSERT INTO wishlists (user_id, animal_id, alert_price, alert_in_stock)
+		VALUES ($1, $2,
+			(SELECT COALESCE(price, 0) FROM animals WHERE id = $2),
+			(SELECT status = 'available' AND COALESCE(stock, 0) > 0 FROM animals WHERE id = $2))
+		ON CONFLICT DO NOTHING`,
+		userID, animalID,
+	)
+	return writeErr(err)
+}
+
+func (s *PostgresStore) RemoveFromWishlist(userID, animalID int) error {
+	_, err := s.DB.Exec("DELETE FROM wishlists WHERE user_id = $1 AND animal_id = $2", userID, animalID)
+	return err
+}
+
+func (s *PostgresStore) ListWishlist(userID int, p PageRequest) (Page[models.Wishlist], error) {
+	o := newestFirst("w.created_at")
+	if err := o.check(p); err != nil {
+		return Page[models.Wishlist]{}, err
+	}
+
+	args := []interface{}{userID}
+	rows, err := s.DB.Query(`
+		SELECT w.id, w.animal_id, w.created_at,
+		       a.animal_type, a.name, a.price, COALESCE(a.image_url, ''), a.status, COALESCE(a.color, ''), COALESCE(a.gender, ''), COALESCE(a.stock, 0)
+		FROM wishlists w
+		JOIN animals a ON w.animal_id = a.id
+		`+where("w.user_id = $1", o.after(p, "w.id", &args))+" "+o.orderBy("w.id")+" "+limit(p, &args),
+		args...,
+	)
+	if err != nil {
+		return Page[models.Wishlist]{}, err
+	}
+	defer rows.Close()
+
+	var wishlist []models.Wishlist
+	for rows.Next() {
+		var w models.Wishlist
+		var a models.Animal
+		err := rows.Scan(&w.ID, &w.AnimalID, &w.CreatedAt,
+			&a.AnimalType, &a.Name, &a.Price, &a.ImageURL, &a.Status, &a.Color, &a.Gender, &a.Stock)
+		if err == nil {
+			a.ID = w.AnimalID
+			w.Animal = &a
+			w.UserID = userID
+			wishlist = append(wishlist, w)
+		}
+	}
+
+	page := newPage(wishlist, p, o, func(w models.Wishlist) (interface{}, int) { return w.CreatedAt, w.ID })
+	err = fillTotal(s.DB, &page, p, "FROM wishlists w JOIN animals a ON w.animal_id = a.id WHERE w.user_id = $1", []interface{}{userID}, false)
+	return page, err
+}
+
+// takeVariantStock removes quantity from a variant locked by tx, guarded
+// like takeStock by the condition and product_variants' stock check.
+func takeVariantStock(tx *sql.Tx, variantID, quantity int) error {
+	res, err := tx.Exec("UPDATE product_variants SET stock = stock - $1 WHERE id = $2 AND stock >= $1", quantity, variantID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
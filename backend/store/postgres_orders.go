@@ -0,0 +1,395 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+const orderColumns = `o.id, o.buyer_id, COALESCE(o.animal_id, 0), o.parent_id, o.seller_id,
+	COALESCE(o.total_price, 0), COALESCE(o.discount, 0), o.voucher_id, COALESCE(o.shipping_cost, 0), o.status, COALESCE(o.quantity, 1),
+	o.created_at, o.updated_at`
+
+// scanOrder reads a row selected with orderColumns. extra receives any
+// columns selected after them.
+func scanOrder(row rowScanner, extra ...interface{}) (models.Order, error) {
+	var o models.Order
+	var parentID, sellerID, voucherID sql.NullInt64
+	err := row.Scan(append([]interface{}{
+		&o.ID, &o.BuyerID, &o.AnimalID, &parentID, &sellerID,
+		&o.TotalPrice, &o.Discount, &voucherID, &o.Shipping, &o.Status, &o.Quantity,
+		&o.CreatedAt, &o.UpdatedAt,
+	}, extra...)...)
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		o.ParentID = &id
+	}
+	if sellerID.Valid {
+		id := int(sellerID.Int64)
+		o.SellerID = &id
+	}
+	if voucherID.Valid {
+		id := int(voucherID.Int64)
+		o.VoucherID = &id
+	}
+	return o, err
+}
+
+// orderDetails loads an order with its lines, shipment, sub-orders and history,
+// without checking who is asking.
+func (s *PostgresStore) orderDetails(id int) (*models.Order, error) {
+	order, err := scanOrder(s.DB.QueryRow("SELECT "+orderColumns+" FROM orders o WHERE o.id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	rows, err := s.DB.Query("SELECT "+orderColumns+" FROM orders o WHERE o.parent_id = $1 ORDER BY o.id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		sub, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		order.SubOrders = append(order.SubOrders, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	all := append([]models.Order{order}, order.SubOrders...)
+	if err := s.attachOrderItems(all); err != nil {
+		return nil, err
+	}
+	if err := s.attachShipments(all); err != nil {
+		return nil, err
+	}
+	order, order.SubOrders = all[0], all[1:]
+	if len(order.SubOrders) == 0 {
+		order.SubOrders = nil
+	}
+
+	order.History, err = s.orderHistory(id)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (s *PostgresStore) orderHistory(orderID int) ([]models.OrderStatusChange, error) {
+	rows, err := s.DB.Query(
+		`SELECT id, order_id, from_status, to_status, changed_by, role, COALESCE(note, ''), created_at
+		FROM order_status_history WHERE order_id = $1 ORDER BY id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.OrderStatusChange
+	for rows.Next() {
+		var h models.OrderStatusChange
+		var changedBy sql.NullInt64
+		if err := rows.Scan(&h.ID, &h.OrderID, &h.FromStatus, &h.ToStatus, &changedBy, &h.Role, &h.Note, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		if changedBy.Valid {
+			id := int(changedBy.Int64)
+			h.ChangedBy = &id
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+func (s *PostgresStore) GetOrderDetails(id, userID int) (*models.Order, error) {
+	order, err := s.orderDetails(id)
+	if err != nil {
+		return nil, err
+	}
+	if userID == 0 || orderRole(userID, order.BuyerID, order.SellerID) == "" {
+		return nil, ErrNotFound
+	}
+	return order, nil
+}
+
+// lockOrders locks the orders matched by cond for the rest of tx.
+func lockOrders(tx *sql.Tx, cond string, arg int) ([]models.Order, error) {
+	rows, err := tx.Query("SELECT "+orderColumns+" FROM orders o WHERE "+cond+" ORDER BY o.id FOR UPDATE", arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+func (s *PostgresStore) UpdateOrderStatus(id, userID int, status, note string) (*models.Order, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := updateOrderStatus(tx, id, userID, status, note); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.orderDetails(id)
+}
+
+// updateOrderStatus is UpdateOrderStatus within tx.
+func updateOrderStatus(tx *sql.Tx, id, userID int, status, note string) error {
+	// A sub-order's header is locked before the sub-order, the same order
+	// a change to the header takes, so the two cannot deadlock.
+	var parentID sql.NullInt64
+	if err := tx.QueryRow("SELECT parent_id FROM orders WHERE id = $1", id).Scan(&parentID); err != nil {
+		return notFound(err)
+	}
+	var header []models.Order
+	var err error
+	if parentID.Valid {
+		if header, err = lockOrders(tx, "o.id = $1", int(parentID.Int64)); err != nil {
+			return err
+		}
+	}
+	locked, err := lockOrders(tx, "o.id = $1", id)
+	if err != nil {
+		return err
+	}
+	if len(locked) == 0 {
+		return ErrNotFound
+	}
+	order := locked[0]
+	role := orderRole(userID, order.BuyerID, order.SellerID)
+	if role == "" {
+		return ErrNotFound
+	}
+	var actor *int
+	if userID != 0 {
+		actor = &userID
+	}
+
+	if order.SellerID == nil {
+		// A checkout header: change every live sub-order not already there.
+		subs, err := lockOrders(tx, "o.parent_id = $1", id)
+		if err != nil {
+			return err
+		}
+		var moving []models.Order
+		for _, sub := range subs {
+			if sub.Status == status || closed(sub.Status) {
+				continue
+			}
+			if err := checkTransition(sub.Status, status, role); err != nil {
+				return err
+			}
+			moving = append(moving, sub)
+		}
+		if len(moving) == 0 {
+			return ErrInvalidTransition
+		}
+		for _, sub := range moving {
+			if err := moveOrder(tx, sub, status, role, actor, note); err != nil {
+				return err
+			}
+		}
+		if err := rollupOrder(tx, order, role, actor); err != nil {
+			return err
+		}
+	} else {
+		if err := checkTransition(order.Status, status, role); err != nil {
+			return err
+		}
+		if len(header) == 1 {
+			if err := checkSubOrder(header[0].Status, status); err != nil {
+				return err
+			}
+		}
+		if err := moveOrder(tx, order, status, role, actor, note); err != nil {
+			return err
+		}
+		if len(header) == 1 {
+			if err := rollupOrder(tx, header[0], role, actor); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// moveOrder sets the status of an order locked by tx and records the
+// change. Cancelling an order expires its pending payments and releases
+// the voucher redeemed on it. For orders with a seller it also puts back
+// stock on cancellation or refund, queues the refund of a paid payment
+// and tells the other party.
+func moveOrder(tx *sql.Tx, o models.Order, status, role string, actor *int, note string) error {
+	_, err := tx.Exec("UPDATE orders SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", status, o.ID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, role, note) VALUES ($1, $2, $3, $4, $5, $6)",
+		o.ID, o.Status, status, actor, role, note,
+	)
+	if err != nil {
+		return err
+	}
+	if status == OrderCancelled && o.ParentID == nil {
+		_, err = tx.Exec(
+			"UPDATE payments SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE order_id = $2 AND status = $3",
+			PaymentExpired, o.ID, PaymentPending,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			`WITH released AS (DELETE FROM voucher_redemptions WHERE order_id = $1 RETURNING voucher_id)
+			UPDATE vouchers SET used_count = used_count - 1 WHERE id IN (SELECT voucher_id FROM released)`,
+			o.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if o.SellerID == nil {
+		return nil
+	}
+
+	if status == OrderRefunded {
+		// The payment is on the order itself or on its checkout header.
+		_, err = tx.Exec(
+			`INSERT INTO payment_refunds (payment_id, order_id, amount)
+			SELECT id, $1, $2 FROM payments WHERE order_id = $3 AND status = $4`,
+			o.ID, o.TotalPrice, paymentOrderID(o), PaymentPaid,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if restocks(status) {
+		// Sold-out listings go back on sale; archived or deleted ones stay
+		// put. Variants only get their stock back.
+		_, err = tx.Exec(
+			`UPDATE animals a SET stock = a.stock + oi.quantity,
+			        status = CASE WHEN a.status = 'sold' THEN 'available' ELSE a.status END,
+			        updated_at = CURRENT_TIMESTAMP
+			FROM (SELECT animal_id, SUM(quantity) AS quantity FROM order_items WHERE order_id = $1 GROUP BY animal_id) oi
+			WHERE a.id = oi.animal_id`,
+			o.ID,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			`UPDATE product_variants v SET stock = v.stock + oi.quantity
+			FROM (SELECT variant_id, SUM(quantity) AS quantity FROM order_items WHERE order_id = $1 GROUP BY variant_id) oi
+			WHERE v.id = oi.variant_id`,
+			o.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	notice := orderNotice(o.ID, status)
+	for _, userID := range noticeRecipients(role, o.BuyerID, *o.SellerID) {
+		_, err = tx.Exec(
+			"INSERT INTO notifications (user_id, title, message, type) VALUES ($1, $2, $3, $4)",
+			userID, notice.Title, notice.Message, notice.Type,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollupOrder brings a checkout header locked by tx in line with its
+// sub-orders after one of them changed.
+func rollupOrder(tx *sql.Tx, header models.Order, role string, actor *int) error {
+	rows, err := tx.Query("SELECT status FROM orders WHERE parent_id = $1", header.ID)
+	if err != nil {
+		return err
+	}
+	var statuses []string
+	for rows.Next() {
+		var status string
+		if err := rows.Scan(&status); err != nil {
+			rows.Close()
+			return err
+		}
+		statuses = append(statuses, status)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if status := rollupStatus(statuses); status != header.Status {
+		return moveOrder(tx, header, status, role, actor, "")
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListSellerOrders(sellerID int, status string, p PageRequest) (Page[models.Order], error) {
+	o := newestFirst("o.created_at")
+	if err := o.check(p); err != nil {
+		return Page[models.Order]{}, err
+	}
+
+	q := &query{}
+	q.add("o.seller_id = ?", sellerID)
+	if status != "" {
+		q.add("o.status = ?", status)
+	}
+	filterArgs := append([]interface{}(nil), q.args...)
+
+	rows, err := s.DB.Query(
+		`SELECT `+orderColumns+`, u.id, u.username, u.fullname, COALESCE(u.avatar_url, '')
+		FROM orders o
+		JOIN users u ON u.id = o.buyer_id
+		`+q.where(o.after(p, "o.id", &q.args))+" "+o.orderBy("o.id")+" "+limit(p, &q.args),
+		q.args...,
+	)
+	if err != nil {
+		return Page[models.Order]{}, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		var buyer models.User
+		order, err := scanOrder(rows, &buyer.ID, &buyer.Username, &buyer.FullName, &buyer.AvatarURL)
+		if err != nil {
+			return Page[models.Order]{}, err
+		}
+		order.Buyer = &buyer
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[models.Order]{}, err
+	}
+
+	page := newPage(orders, p, o, func(row models.Order) (interface{}, int) { return row.CreatedAt, row.ID })
+	if err := s.attachOrderItems(page.Items); err != nil {
+		return page, err
+	}
+	err = fillTotal(s.DB, &page, p, "FROM orders o "+where(q.preds...), filterArgs, false)
+	return page, err
+}
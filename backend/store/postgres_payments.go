@@ -0,0 +1,279 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+const paymentColumns = `id, order_id, provider, reference, COALESCE(charge_id, ''), method, COALESCE(bank, ''),
+	COALESCE(va_number, ''), COALESCE(qr_string, ''), amount, refunded_amount, status, expires_at, paid_at,
+	created_at, updated_at`
+
+func scanPayment(row rowScanner) (models.Payment, error) {
+	var p models.Payment
+	var expiresAt, paidAt sql.NullTime
+	err := row.Scan(
+		&p.ID, &p.OrderID, &p.Provider, &p.Reference, &p.ChargeID, &p.Method, &p.Bank,
+		&p.VANumber, &p.QRString, &p.Amount, &p.RefundedAmount, &p.Status, &expiresAt, &paidAt,
+		&p.CreatedAt, &p.UpdatedAt,
+	)
+	if expiresAt.Valid {
+		p.ExpiresAt = &expiresAt.Time
+	}
+	if paidAt.Valid {
+		p.PaidAt = &paidAt.Time
+	}
+	return p, err
+}
+
+func (s *PostgresStore) CreatePayment(p *models.Payment, buyerID int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	locked, err := lockOrders(tx, "o.id = $1", p.OrderID)
+	if err != nil {
+		return err
+	}
+	if len(locked) == 0 || locked[0].BuyerID != buyerID {
+		return ErrNotFound
+	}
+	order := locked[0]
+	if order.ParentID != nil || order.Status != OrderPending {
+		return ErrInvalidTransition
+	}
+
+	var open bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM payments WHERE order_id = $1 AND status = $2)", order.ID, PaymentPending).Scan(&open)
+	if err != nil {
+		return err
+	}
+	if open {
+		return ErrConflict
+	}
+
+	p.Amount, p.Status = order.TotalPrice, PaymentPending
+	err = tx.QueryRow(
+		`INSERT INTO payments (order_id, provider, method, bank, amount, status, expires_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7) RETURNING id, created_at, updated_at`,
+		p.OrderID, p.Provider, p.Method, p.Bank, p.Amount, p.Status, p.ExpiresAt,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return writeErr(err)
+	}
+	p.Reference = paymentReference(p.ID)
+	if _, err := tx.Exec("UPDATE payments SET reference = $1 WHERE id = $2", p.Reference, p.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) SavePaymentCharge(p *models.Payment) error {
+	res, err := s.DB.Exec(
+		`UPDATE payments SET charge_id = NULLIF($1, ''), va_number = NULLIF($2, ''), qr_string = NULLIF($3, ''),
+		        expires_at = COALESCE($4, expires_at), status = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6 AND status = $7`,
+		p.ChargeID, p.VANumber, p.QRString, p.ExpiresAt, p.Status, p.ID, PaymentPending,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListOrderPayments(orderID, buyerID int) ([]models.Payment, error) {
+	var owner int
+	if err := s.DB.QueryRow("SELECT buyer_id FROM orders WHERE id = $1", orderID).Scan(&owner); err != nil {
+		return nil, notFound(err)
+	}
+	if owner != buyerID {
+		return nil, ErrNotFound
+	}
+
+	rows, err := s.DB.Query("SELECT "+paymentColumns+" FROM payments WHERE order_id = $1 ORDER BY created_at DESC, id DESC", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []models.Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+func (s *PostgresStore) ApplyPaymentEvent(e *models.PaymentEvent) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`INSERT INTO payment_events (provider, event_id, charge_id, status, amount, currency) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		ON CONFLICT (provider, event_id) DO NOTHING`,
+		e.Provider, e.EventID, e.ChargeID, e.Status, e.Amount, e.Currency,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrConflict
+	}
+
+	// Lock the order before the payment, the same order cancelling takes.
+	var orderID int
+	err = tx.QueryRow("SELECT order_id FROM payments WHERE provider = $1 AND charge_id = $2", e.Provider, e.ChargeID).Scan(&orderID)
+	if err != nil {
+		return notFound(err)
+	}
+	if _, err := lockOrders(tx, "o.id = $1", orderID); err != nil {
+		return err
+	}
+	p, err := scanPayment(tx.QueryRow(
+		"SELECT "+paymentColumns+" FROM payments WHERE provider = $1 AND charge_id = $2 FOR UPDATE",
+		e.Provider, e.ChargeID,
+	))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case e.Status == PaymentPaid && p.Status != PaymentPaid && !paymentMatches(p, e):
+		_, err = tx.Exec("UPDATE payments SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", PaymentMismatch, p.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		return ErrPaymentMismatch
+	case e.Status == PaymentPaid && p.Status != PaymentPaid:
+		_, err = tx.Exec(
+			"UPDATE payments SET status = $1, paid_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
+			PaymentPaid, p.ID,
+		)
+		if err != nil {
+			return err
+		}
+		err = updateOrderStatus(tx, p.OrderID, 0, OrderPaid, "Paid by "+p.Method)
+		if err == ErrInvalidTransition {
+			// The order was cancelled before the money arrived.
+			_, err = tx.Exec(
+				"INSERT INTO payment_refunds (payment_id, order_id, amount) VALUES ($1, $2, $3)",
+				p.ID, p.OrderID, p.Amount,
+			)
+		}
+		if err != nil {
+			return err
+		}
+	case (e.Status == PaymentExpired || e.Status == PaymentFailed) && p.Status == PaymentPending:
+		_, err = tx.Exec("UPDATE payments SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", e.Status, p.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) ExpireUnpaidOrders(cutoff time.Time) (int, error) {
+	rows, err := s.DB.Query(
+		"SELECT id FROM orders WHERE parent_id IS NULL AND status = $1 AND created_at < $2 ORDER BY id",
+		OrderPending, cutoff,
+	)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		_, err := s.UpdateOrderStatus(id, 0, OrderCancelled, "Not paid in time")
+		switch {
+		case err == nil:
+			expired++
+		case err == ErrInvalidTransition:
+			// Paid or cancelled since the query.
+		default:
+			return expired, err
+		}
+	}
+	return expired, nil
+}
+
+func (s *PostgresStore) ListPendingRefunds(limit int) ([]models.PaymentRefund, error) {
+	rows, err := s.DB.Query(
+		`SELECT r.id, r.payment_id, r.order_id, r.amount, r.status, p.provider, COALESCE(p.charge_id, ''), r.created_at
+		FROM payment_refunds r
+		JOIN payments p ON p.id = r.payment_id
+		WHERE r.status = 'pending'
+		ORDER BY r.id
+		LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []models.PaymentRefund
+	for rows.Next() {
+		var r models.PaymentRefund
+		if err := rows.Scan(&r.ID, &r.PaymentID, &r.OrderID, &r.Amount, &r.Status, &r.Provider, &r.ChargeID, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, r)
+	}
+	return refunds, rows.Err()
+}
+
+func (s *PostgresStore) CompleteRefund(id int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var paymentID int
+	var amount float64
+	err = tx.QueryRow(
+		`UPDATE payment_refunds SET status = 'done', refunded_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending' RETURNING payment_id, amount`,
+		id,
+	).Scan(&paymentID, &amount)
+	if err != nil {
+		return notFound(err)
+	}
+	_, err = tx.Exec(
+		"UPDATE payments SET refunded_amount = refunded_amount + $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
+		amount, paymentID,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,222 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/TerraPaw/backend/models"
+	"github.com/lib/pq"
+)
+
+const productColumns = `p.id, p.seller_id, p.category_id, p.sku, p.name, p.brand, p.description, p.image_url, p.location,
+	p.status, p.created_at, p.updated_at,
+	u.id, u.username, u.email, u.fullname, COALESCE(u.avatar_url, ''), COALESCE(u.bio, ''),
+	sh.id, sh.slug, sh.name`
+
+const productFrom = `
+	FROM products p
+	JOIN users u ON u.id = p.seller_id
+	LEFT JOIN shops sh ON sh.seller_id = p.seller_id`
+
+// scanProduct reads a row selected with productColumns.
+func scanProduct(row rowScanner) (models.Product, error) {
+	var p models.Product
+	var seller models.User
+	var shopID sql.NullInt64
+	var shopSlug, shopName sql.NullString
+	err := row.Scan(
+		&p.ID, &p.SellerID, &p.CategoryID, &p.SKU, &p.Name, &p.Brand, &p.Description, &p.ImageURL, &p.Location,
+		&p.Status, &p.CreatedAt, &p.UpdatedAt,
+		&seller.ID, &seller.Username, &seller.Email, &seller.FullName, &seller.AvatarURL, &seller.Bio,
+		&shopID, &shopSlug, &shopName,
+	)
+	p.Seller = &seller
+	if shopID.Valid {
+		p.Shop = &models.ShopRef{ID: int(shopID.Int64), Slug: shopSlug.String, Name: shopName.String}
+	}
+	return p, err
+}
+
+// variantRefColumns selects a models.VariantRef from product_variants v
+// joined to products p.
+const variantRefColumns = `v.id, v.product_id, p.seller_id, p.category_id, v.sku, p.name, p.brand, v.size, v.flavour,
+	p.image_url, p.location, p.status, v.price, v.stock`
+
+// scanVariantRef reads a row selected with variantRefColumns, followed by
+// any extra columns.
+func scanVariantRef(row rowScanner, extra ...interface{}) (*models.VariantRef, error) {
+	var v models.VariantRef
+	dest := append([]interface{}{
+		&v.ID, &v.ProductID, &v.SellerID, &v.CategoryID, &v.SKU, &v.Name, &v.Brand, &v.Size, &v.Flavour,
+		&v.ImageURL, &v.Location, &v.Status, &v.Price, &v.Stock,
+	}, extra...)
+	return &v, row.Scan(dest...)
+}
+
+// withVariants loads the variants of products, cheapest first, and fills in
+// their summaries.
+func (s *PostgresStore) withVariants(products []models.Product) error {
+	ids := make([]int64, len(products))
+	index := map[int]int{}
+	for i, p := range products {
+		ids[i], index[p.ID] = int64(p.ID), i
+		products[i].Variants = []models.ProductVariant{}
+	}
+	rows, err := s.DB.Query(
+		`SELECT id, product_id, sku, size, flavour, price, weight_grams, stock
+		FROM product_variants WHERE product_id = ANY($1)
+		ORDER BY product_id, price, id`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v models.ProductVariant
+		if err := rows.Scan(&v.ID, &v.ProductID, &v.SKU, &v.Size, &v.Flavour, &v.Price, &v.WeightGrams, &v.Stock); err != nil {
+			return err
+		}
+		p := &products[index[v.ProductID]]
+		p.Variants = append(p.Variants, v)
+	}
+	for i := range products {
+		summarize(&products[i])
+	}
+	return rows.Err()
+}
+
+func (s *PostgresStore) ListProducts(f ProductFilter, p PageRequest) (Page[models.Product], error) {
+	o := productOrder(f.Sort)
+	if err := o.check(p); err != nil {
+		return Page[models.Product]{}, err
+	}
+
+	q := &query{}
+	q.add("p.status = ?", ProductActive)
+	if f.Search != "" {
+		q.add("(p.name || ' ' || p.brand) ILIKE ?", likePattern(f.Search))
+	}
+	if f.Brand != "" {
+		q.add("LOWER(p.brand) = LOWER(?)", f.Brand)
+	}
+	if f.CategoryID != 0 {
+		q.add("p.category_id IN ("+categorySubtree+")", f.CategoryID)
+	}
+	if f.SellerID != 0 {
+		q.add("p.seller_id = ?", f.SellerID)
+	}
+	if f.InStock {
+		q.add("EXISTS (SELECT 1 FROM product_variants pv WHERE pv.product_id = p.id AND pv.stock > 0)")
+	}
+	filterArgs := append([]interface{}(nil), q.args...)
+	rows, err := s.DB.Query(
+		"SELECT "+productColumns+productFrom+"\n"+
+			q.where(o.after(p, "p.id", &q.args))+" "+o.orderBy("p.id")+" "+limit(p, &q.args),
+		q.args...,
+	)
+	if err != nil {
+		return Page[models.Product]{}, err
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return Page[models.Product]{}, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[models.Product]{}, err
+	}
+	if err := s.withVariants(products); err != nil {
+		return Page[models.Product]{}, err
+	}
+
+	page := newPage(products, p, o, productKey(o))
+	err = fillTotal(s.DB, &page, p, "FROM products p "+where(q.preds...), filterArgs, true)
+	return page, err
+}
+
+func (s *PostgresStore) GetProduct(id int) (*models.Product, error) {
+	product, err := scanProduct(s.DB.QueryRow("SELECT "+productColumns+productFrom+" WHERE p.id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	products := []models.Product{product}
+	if err := s.withVariants(products); err != nil {
+		return nil, err
+	}
+	return &products[0], nil
+}
+
+func (s *PostgresStore) CreateProduct(p *models.Product) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if p.Status == "" {
+		p.Status = ProductActive
+	}
+	err = tx.QueryRow(
+		`INSERT INTO products (seller_id, category_id, sku, name, brand, description, image_url, location, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at, updated_at`,
+		p.SellerID, p.CategoryID, p.SKU, p.Name, p.Brand, p.Description, p.ImageURL, p.Location, p.Status,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return writeErr(err)
+	}
+	for i := range p.Variants {
+		v := &p.Variants[i]
+		v.ProductID = p.ID
+		err = tx.QueryRow(
+			`INSERT INTO product_variants (product_id, sku, size, flavour, price, weight_grams, stock)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			p.ID, v.SKU, v.Size, v.Flavour, v.Price, v.WeightGrams, v.Stock,
+		).Scan(&v.ID)
+		if err != nil {
+			return writeErr(err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) UpdateVariant(productID, variantID, sellerID int, u VariantUpdate) (*models.Product, error) {
+	var owner int
+	err := s.DB.QueryRow("SELECT seller_id FROM products WHERE id = $1", productID).Scan(&owner)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if owner != sellerID {
+		return nil, ErrNotOwner
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(
+		`UPDATE product_variants SET price = COALESCE($3, price), stock = COALESCE($4, stock)
+		WHERE id = $1 AND product_id = $2`,
+		variantID, productID, u.Price, u.Stock,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrNotFound
+	}
+	if _, err := tx.Exec("UPDATE products SET updated_at = CURRENT_TIMESTAMP WHERE id = $1", productID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetProduct(productID)
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"github.com/TerraPaw/backend/models"
+)
+
+func (s *PostgresStore) ListUserPets(ownerID int, p PageRequest) (Page[models.UserPet], error) {
+	o := newestFirst("created_at")
+	if err := o.check(p); err != nil {
+		return Page[models.UserPet]{}, err
+	}
+
+	args := []interface{}{ownerID}
+	rows, err := s.DB.Query("SELECT id, owner_id, name, animal_type, COALESCE(breed, ''), age, COALESCE(image_url, ''), COALESCE(story, ''), created_at FROM user_pets "+
+		where("owner_id = $1", o.after(p, "id", &args))+" "+o.orderBy("id")+" "+limit(p, &args), args...)
+	if err != nil {
+		return Page[models.UserPet]{}, err
+	}
+	defer rows.Close()
+
+	var pets []models.UserPet
+	for rows.Next() {
+		var pet models.UserPet
+		if err := rows.Scan(&pet.ID, &pet.OwnerID, &pet.Name, &pet.AnimalType, &pet.Breed, &pet.Age, &pet.ImageURL, &pet.Story, &pet.CreatedAt); err != nil {
+			continue
+		}
+		pets = append(pets, pet)
+	}
+
+	page := newPage(pets, p, o, func(pet models.UserPet) (interface{}, int) { return pet.CreatedAt, pet.ID })
+	err = fillTotal(s.DB, &page, p, "FROM user_pets WHERE owner_id = $1", []interface{}{ownerID}, false)
+	return page, err
+}
+
+func (s *PostgresStore) CreateUserPet(p *models.UserPet) (int, error) {
+	var petID int
+	err := s.DB.QueryRow(`
+		INSERT INTO user_pets (owner_id, name, animal_type, breed, age, image_url, story)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		p.OwnerID, p.Name, p.AnimalType, p.Breed, p.Age, p.ImageURL, p.Story,
+	).Scan(&petID)
+	return petID, err
+}
+
+func (s *PostgresStore) ListMedicalRecords(ownerID int, p PageRequest) (Page[models.MedicalRecord], error) {
+	o := medicalRecordOrder
+	if err := o.check(p); err != nil {
+		return Page[models.MedicalRecord]{}, err
+	}
+
+	args := []interface{}{ownerID}
+	query := `
+		SELECT mr.id, mr.pet_id, mr.veterinarian_id, mr.record_type, COALESCE(mr.description, ''), COALESCE(mr.treatment, ''), mr.date, COALESCE(mr.notes, ''), mr.created_at,
+		       p.name, p.animal_type,
+		       COALESCE(v.clinic_name, ''), COALESCE(u.fullname, '')
+		FROM medical_records mr
+		JOIN user_pets p ON mr.pet_id = p.id
+		LEFT JOIN veterinarians v ON mr.veterinarian_id = v.id
+		LEFT JOIN users u ON v.user_id = u.id
+		` + where("p.owner_id = $1", o.after(p, "mr.id", &args)) + " " + o.orderBy("mr.id") + " " + limit(p, &args)
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return Page[models.MedicalRecord]{}, err
+	}
+	defer rows.Close()
+
+	var records []models.MedicalRecord
+	for rows.Next() {
+		var mr models.MedicalRecord
+		var pet models.UserPet
+		var vet models.Veterinarian
+		var vetUser models.User
+		var vetID *int // Handle null vet
+
+		if err := rows.Scan(
+			&mr.ID, &mr.PetID, &vetID, &mr.RecordType, &mr.Description, &mr.Treatment, &mr.Date, &mr.Notes, &mr.CreatedAt,
+			&pet.Name, &pet.AnimalType,
+			&vet.ClinicName, &vetUser.FullName,
+		); err != nil {
+			continue
+		}
+
+		if vetID != nil {
+			mr.VeterinarianID = *vetID
+		}
+		pet.ID = mr.PetID
+		vet.User = &vetUser
+		mr.Pet = &pet
+		mr.Veterinarian = &vet
+		records = append(records, mr)
+	}
+
+	page := newPage(records, p, o, func(mr models.MedicalRecord) (interface{}, int) { return mr.Date, mr.ID })
+	err = fillTotal(s.DB, &page, p, "FROM medical_records mr JOIN user_pets p ON mr.pet_id = p.id WHERE p.owner_id = $1", []interface{}{ownerID}, false)
+	return page, err
+}
+
+func (s *PostgresStore) ListNotifications(userID int, p PageRequest) (Page[models.Notification], error) {
+	o := newestFirst("created_at")
+	if err := o.check(p); err != nil {
+		return Page[models.Notification]{}, err
+	}
+
+	args := []interface{}{userID}
+	rows, err := s.DB.Query("SELECT id, user_id, title, message, type, is_read, created_at FROM notifications "+
+		where("user_id = $1", o.after(p, "id", &args))+" "+o.orderBy("id")+" "+limit(p, &args), args...)
+	if err != nil {
+		return Page[models.Notification]{}, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Title, &n.Message, &n.Type, &n.IsRead, &n.CreatedAt); err != nil {
+			continue
+		}
+		notifications = append(notifications, n)
+	}
+
+	page := newPage(notifications, p, o, func(n models.Notification) (interface{}, int) { return n.CreatedAt, n.ID })
+	err = fillTotal(s.DB, &page, p, "FROM notifications WHERE user_id = $1", []interface{}{userID}, false)
+	return page, err
+}
+
+func (s *PostgresStore) CountUserPets(ownerID int) (int, error) {
+	var count int
+	err := s.DB.QueryRow("SELECT COUNT(*) FROM user_pets WHERE owner_id = $1", ownerID).Scan(&count)
+	return count, err
+}
+
+func (s *PostgresStore) CountUserOrders(buyerID int) (int, error) {
+	var count int
+	err := s.DB.QueryRow("SELECT COUNT(*) FROM orders WHERE buyer_id = $1 AND parent_id IS NULL", buyerID).Scan(&count)
+	return count, err
+}
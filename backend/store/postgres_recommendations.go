@@ -0,0 +1,213 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/TerraPaw/backend/models"
+	"github.com/lib/pq"
+)
+
+func (s *PostgresStore) ListRecommendable() ([]models.Animal, error) {
+	rows, err := s.DB.Query(
+		`SELECT id, seller_id, animal_type, COALESCE(breed, ''), COALESCE(location, ''), COALESCE(price, 0), status, COALESCE(stock, 0)
+		FROM animals WHERE status <> 'deleted' ORDER BY id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listings []models.Animal
+	for rows.Next() {
+		var a models.Animal
+		if err := rows.Scan(&a.ID, &a.SellerID, &a.AnimalType, &a.Breed, &a.Location, &a.Price, &a.Status, &a.Stock); err != nil {
+			return nil, err
+		}
+		listings = append(listings, a)
+	}
+	return listings, rows.Err()
+}
+
+// interestsSQL selects (user_id, animal_id, at) for every listing a user
+// ordered or saved.
+const interestsSQL = `
+	SELECT o.buyer_id AS user_id, oi.animal_id, o.created_at AS at
+	FROM order_items oi JOIN orders o ON o.id = oi.order_id
+	WHERE oi.animal_id IS NOT NULL
+	UNION ALL
+	SELECT user_id, animal_id, created_at FROM wishlists`
+
+func (s *PostgresStore) ListInterests(perUser int) ([][]int, error) {
+	rows, err := s.DB.Query(
+		`SELECT user_id, animal_id FROM (
+			SELECT user_id, animal_id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY at DESC) AS n
+			FROM (`+interestsSQL+`) i
+		) ranked
+		WHERE n <= $1
+		ORDER BY user_id, n`,
+		perUser,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var baskets [][]int
+	last := 0
+	for rows.Next() {
+		var userID, animalID int
+		if err := rows.Scan(&userID, &animalID); err != nil {
+			return nil, err
+		}
+		if userID != last || baskets == nil {
+			baskets = append(baskets, nil)
+			last = userID
+		}
+		baskets[len(baskets)-1] = append(baskets[len(baskets)-1], animalID)
+	}
+	return baskets, rows.Err()
+}
+
+// ReplaceSimilarities swaps the table's rows in one transaction, so
+// readers see either the previous batch or the new one.
+func (s *PostgresStore) ReplaceSimilarities(sims []models.Similarity) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM listing_similarities"); err != nil {
+		return err
+	}
+	animalIDs := make([]int64, len(sims))
+	similarIDs := make([]int64, len(sims))
+	scores := make([]float64, len(sims))
+	reasons := make([]string, len(sims))
+	for i, sim := range sims {
+		animalIDs[i], similarIDs[i], scores[i] = int64(sim.AnimalID), int64(sim.SimilarID), sim.Score
+		reasons[i] = strings.Join(sim.Reasons, ",")
+	}
+	_, err = tx.Exec(
+		`INSERT INTO listing_similarities (animal_id, similar_id, score, reasons)
+		SELECT * FROM unnest($1::int[], $2::int[], $3::float8[], $4::text[])`,
+		pq.Array(animalIDs), pq.Array(similarIDs), pq.Array(scores), pq.Array(reasons),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// forSaleSQL keeps the listings that can be recommended.
+const forSaleSQL = "a.status = 'available' AND COALESCE(a.stock, 0) > 0"
+
+// scanRecommendations reads rows of animalColumns followed by the score,
+// the reasons and the listing it is because of.
+func scanRecommendations(rows *sql.Rows) ([]models.Recommendation, error) {
+	defer rows.Close()
+	recs := []models.Recommendation{}
+	for rows.Next() {
+		var rec models.Recommendation
+		var reasons string
+		a, err := scanAnimal(rows, &rec.Score, &reasons, &rec.BecauseOf)
+		if err != nil {
+			return nil, err
+		}
+		rec.Animal, rec.Reasons = &a, strings.Split(reasons, ",")
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
+
+func (s *PostgresStore) SimilarAnimals(animalID, limit int) ([]models.Recommendation, error) {
+	rows, err := s.DB.Query(
+		"SELECT "+animalColumns+", ls.score, ls.reasons, NULL::int"+animalFrom+`
+		JOIN listing_similarities ls ON ls.similar_id = a.id
+		WHERE ls.animal_id = $1 AND `+forSaleSQL+`
+		ORDER BY ls.score DESC, a.id
+		LIMIT $2`,
+		animalID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanRecommendations(rows)
+}
+
+func (s *PostgresStore) RecommendAnimals(userID, limit int) ([]models.Recommendation, error) {
+	seedRows, err := s.DB.Query(
+		`SELECT animal_id FROM (`+interestsSQL+`) i
+		WHERE user_id = $1
+		GROUP BY animal_id
+		ORDER BY MAX(at) DESC
+		LIMIT $2`,
+		userID, maxRecommendationSeeds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	seeds := []int64{} // pq sends a nil slice as NULL, and NOT id = ANY(NULL) keeps nothing
+	for seedRows.Next() {
+		var id int64
+		if err := seedRows.Scan(&id); err != nil {
+			seedRows.Close()
+			return nil, err
+		}
+		seeds = append(seeds, id)
+	}
+	seedRows.Close()
+	if err := seedRows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Sum the matches of every seed; the strongest one explains it.
+	rows, err := s.DB.Query(
+		`WITH scored AS (
+			SELECT similar_id, ROUND(SUM(score)::numeric, 4)::float8 AS score,
+			       (ARRAY_AGG(reasons ORDER BY score DESC, animal_id))[1] AS reasons,
+			       (ARRAY_AGG(animal_id ORDER BY score DESC, animal_id))[1] AS because_of
+			FROM listing_similarities
+			WHERE animal_id = ANY($1) AND NOT similar_id = ANY($1)
+			GROUP BY similar_id
+		)
+		SELECT `+animalColumns+", sc.score, sc.reasons, sc.because_of"+animalFrom+`
+		JOIN scored sc ON sc.similar_id = a.id
+		WHERE `+forSaleSQL+` AND a.seller_id <> $2
+		ORDER BY sc.score DESC, a.id
+		LIMIT $3`,
+		pq.Array(seeds), userID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	recs, err := scanRecommendations(rows)
+	if err != nil || len(recs) >= limit {
+		return recs, err
+	}
+
+	taken := seeds
+	for _, r := range recs {
+		taken = append(taken, int64(r.Animal.ID))
+	}
+	rows, err = s.DB.Query(
+		animalSelect+`
+		WHERE `+forSaleSQL+` AND a.seller_id <> $1 AND NOT a.id = ANY($2)
+		ORDER BY `+animalPopularity+` DESC, a.id DESC
+		LIMIT $3`,
+		userID, pq.Array(taken), limit-len(recs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a, err := scanAnimal(rows)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, popularRecommendation(a))
+	}
+	return recs, rows.Err()
+}
@@ -0,0 +1,389 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+const reviewColumns = `r.id, r.user_id, r.order_id, r.order_item_id, r.animal_id, r.rating, COALESCE(r.comment, ''),
+	COALESCE(r.image_url, ''), r.verified, r.helpful_count, r.removed_at, r.created_at,
+	u.username, COALESCE(u.fullname, ''), COALESCE(u.avatar_url, ''),
+	rp.seller_id, rp.body, rp.created_at, rp.updated_at`
+
+// reviewFrom joins what reviewColumns reads besides the review.
+const reviewFrom = `
+	FROM reviews r
+	JOIN users u ON u.id = r.user_id
+	LEFT JOIN review_replies rp ON rp.review_id = r.id`
+
+// scanReview reads a row selected with reviewColumns. extra receives any
+// columns selected after them.
+func scanReview(row rowScanner, extra ...interface{}) (models.Review, error) {
+	var r models.Review
+	var u models.User
+	var replySeller sql.NullInt64
+	var replyBody sql.NullString
+	var replyCreated, replyUpdated sql.NullTime
+	err := row.Scan(append([]interface{}{
+		&r.ID, &r.UserID, &r.OrderID, &r.OrderItemID, &r.AnimalID, &r.Rating, &r.Comment,
+		&r.ImageURL, &r.Verified, &r.HelpfulCount, &r.RemovedAt, &r.CreatedAt,
+		&u.Username, &u.FullName, &u.AvatarURL,
+		&replySeller, &replyBody, &replyCreated, &replyUpdated,
+	}, extra...)...)
+	r.User = &u
+	if replySeller.Valid {
+		r.Reply = &models.ReviewReply{
+			SellerID: int(replySeller.Int64), Body: replyBody.String, CreatedAt: replyCreated.Time, UpdatedAt: replyUpdated.Time,
+		}
+	}
+	return r, err
+}
+
+// lockReviewedSeller locks the seller of a listing so that reviews of
+// their listings change ratings one after another, and returns them.
+func lockReviewedSeller(tx *sql.Tx, animalID int) (int, error) {
+	var sellerID int
+	err := tx.QueryRow(
+		"SELECT u.id FROM animals a JOIN users u ON u.id = a.seller_id WHERE a.id = $1 FOR UPDATE OF u", animalID,
+	).Scan(&sellerID)
+	return sellerID, notFound(err)
+}
+
+// rateReviewed recomputes, from the reviews still up, a listing's rating
+// and review count and the rating of its seller, locked by tx.
+func rateReviewed(tx *sql.Tx, animalID, sellerID int) error {
+	_, err := tx.Exec(
+		`UPDATE animals SET (rating, review_count) = (
+			SELECT COALESCE(ROUND(AVG(rating), 2), 0), COUNT(*) FROM reviews WHERE animal_id = $1 AND removed_at IS NULL
+		) WHERE id = $1`,
+		animalID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO seller_ratings (seller_id, rating, review_count)
+		SELECT $1, COALESCE(ROUND(AVG(r.rating), 2), 0), COUNT(*)
+		FROM reviews r JOIN animals a ON a.id = r.animal_id
+		WHERE a.seller_id = $1 AND r.removed_at IS NULL
+		ON CONFLICT (seller_id) DO UPDATE SET
+			rating = EXCLUDED.rating, review_count = EXCLUDED.review_count, updated_at = CURRENT_TIMESTAMP`,
+		sellerID,
+	)
+	return err
+}
+
+func (s *PostgresStore) CreateReview(r *models.Review) (int, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	sellerID, err := lockReviewedSeller(tx, r.AnimalID)
+	if err != nil {
+		return 0, err
+	}
+
+	var itemID int
+	var status string
+	err = tx.QueryRow(
+		`SELECT oi.id, o.status
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE oi.animal_id = $1 AND o.buyer_id = $2 AND (o.id = $3 OR o.parent_id = $3)
+		ORDER BY oi.id LIMIT 1`,
+		r.AnimalID, r.UserID, *r.OrderID,
+	).Scan(&itemID, &status)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	if status != OrderCompleted {
+		return 0, ErrNotReviewable
+	}
+
+	err = tx.QueryRow(`
+        INSERT INTO reviews (user_id, order_id, order_item_id, animal_id, rating, comment, image_url, verified)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, TRUE) RETURNING id, created_at
+    `, r.UserID, r.OrderID, itemID, r.AnimalID, r.Rating, r.Comment, r.ImageURL).Scan(&r.ID, &r.CreatedAt)
+	if err != nil {
+		return 0, writeErr(err)
+	}
+	r.OrderItemID, r.Verified = &itemID, true
+
+	if err := rateReviewed(tx, r.AnimalID, sellerID); err != nil {
+		return 0, err
+	}
+	return r.ID, tx.Commit()
+}
+
+func (s *PostgresStore) ListReviews(animalID int, f ReviewFilter, p PageRequest) (Page[models.Review], error) {
+	o := reviewOrder(f.Sort)
+	if err := o.check(p); err != nil {
+		return Page[models.Review]{}, err
+	}
+
+	photos := ""
+	if f.WithPhotos {
+		photos = "COALESCE(r.image_url, '') <> ''"
+	}
+	args := []interface{}{animalID}
+	rows, err := s.DB.Query(
+		"SELECT "+reviewColumns+reviewFrom+`
+		`+where("r.animal_id = $1", "r.removed_at IS NULL", photos, o.after(p, "r.id", &args))+" "+o.orderBy("r.id")+" "+limit(p, &args),
+		args...,
+	)
+	if err != nil {
+		return Page[models.Review]{}, err
+	}
+	defer rows.Close()
+
+	var reviews []models.Review
+	for rows.Next() {
+		r, err := scanReview(rows)
+		if err != nil {
+			return Page[models.Review]{}, err
+		}
+		reviews = append(reviews, r)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[models.Review]{}, err
+	}
+
+	page := newPage(reviews, p, o, reviewKey(o))
+	err = fillTotal(s.DB, &page, p, "FROM reviews r "+where("r.animal_id = $1", "r.removed_at IS NULL", photos), []interface{}{animalID}, false)
+	return page, err
+}
+
+func (s *PostgresStore) ReviewSummary(animalID int) (*models.RatingSummary, error) {
+	rows, err := s.DB.Query(
+		`SELECT rating, COUNT(*), COUNT(*) FILTER (WHERE verified) FROM reviews
+		WHERE animal_id = $1 AND removed_at IS NULL GROUP BY rating`, animalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sum := newRatingSummary()
+	for rows.Next() {
+		var rating, n, verified int
+		if err := rows.Scan(&rating, &n, &verified); err != nil {
+			return nil, err
+		}
+		addRatings(sum, rating, n, verified)
+	}
+	return sum, rows.Err()
+}
+
+// reviewedSeller returns the seller of the listing a review that is still
+// up was written about.
+func (s *PostgresStore) reviewedSeller(reviewID int) (int, error) {
+	var sellerID int
+	err := s.DB.QueryRow(
+		"SELECT a.seller_id FROM reviews r JOIN animals a ON a.id = r.animal_id WHERE r.id = $1 AND r.removed_at IS NULL", reviewID,
+	).Scan(&sellerID)
+	return sellerID, notFound(err)
+}
+
+func (s *PostgresStore) ReplyToReview(reviewID, sellerID int, body string) (*models.ReviewReply, error) {
+	owner, err := s.reviewedSeller(reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if owner != sellerID {
+		return nil, ErrNotOwner
+	}
+	reply := &models.ReviewReply{SellerID: sellerID, Body: body}
+	err = s.DB.QueryRow(
+		`INSERT INTO review_replies (review_id, seller_id, body) VALUES ($1, $2, $3)
+		ON CONFLICT (review_id) DO UPDATE SET body = EXCLUDED.body, updated_at = CURRENT_TIMESTAMP
+		RETURNING created_at, updated_at`,
+		reviewID, sellerID, body,
+	).Scan(&reply.CreatedAt, &reply.UpdatedAt)
+	if err != nil {
+		return nil, writeErr(err)
+	}
+	return reply, nil
+}
+
+func (s *PostgresStore) DeleteReviewReply(reviewID, sellerID int) error {
+	owner, err := s.reviewedSeller(reviewID)
+	if err != nil {
+		return err
+	}
+	if owner != sellerID {
+		return ErrNotOwner
+	}
+	_, err = s.DB.Exec("DELETE FROM review_replies WHERE review_id = $1", reviewID)
+	return err
+}
+
+func (s *PostgresStore) VoteReviewHelpful(reviewID, userID int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var authorID int
+	err = tx.QueryRow("SELECT user_id FROM reviews WHERE id = $1 AND removed_at IS NULL FOR UPDATE", reviewID).Scan(&authorID)
+	if err != nil {
+		return notFound(err)
+	}
+	if authorID == userID {
+		return ErrOwnReview
+	}
+	res, err := tx.Exec("INSERT INTO review_votes (review_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", reviewID, userID)
+	if err != nil {
+		return writeErr(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE reviews SET helpful_count = helpful_count + 1 WHERE id = $1", reviewID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) UnvoteReviewHelpful(reviewID, userID int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM review_votes WHERE review_id = $1 AND user_id = $2", reviewID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE reviews SET helpful_count = helpful_count - 1 WHERE id = $1", reviewID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) ReportReview(r *models.ReviewReport) error {
+	err := s.DB.QueryRow(
+		`INSERT INTO review_reports (review_id, reporter_id, reason, details)
+		SELECT $1, $2, $3, $4 WHERE EXISTS (SELECT 1 FROM reviews WHERE id = $1 AND removed_at IS NULL)
+		RETURNING id, status, created_at`,
+		r.ReviewID, r.ReporterID, r.Reason, r.Details,
+	).Scan(&r.ID, &r.Status, &r.CreatedAt)
+	return notFound(writeErr(err))
+}
+
+// reportColumns are selected after reviewColumns, for the report on the
+// review.
+const reportColumns = `rr.id, rr.review_id, rr.reporter_id, rr.reason, COALESCE(rr.details, ''), rr.status,
+	rr.resolved_by, rr.resolved_at, rr.created_at,
+	ru.id, ru.username, COALESCE(ru.fullname, ''), COALESCE(ru.avatar_url, '')`
+
+const reportFrom = reviewFrom + `
+	JOIN review_reports rr ON rr.review_id = r.id
+	JOIN users ru ON ru.id = rr.reporter_id`
+
+func scanReport(row rowScanner) (models.ReviewReport, error) {
+	var rr models.ReviewReport
+	var reporter models.User
+	review, err := scanReview(row,
+		&rr.ID, &rr.ReviewID, &rr.ReporterID, &rr.Reason, &rr.Details, &rr.Status,
+		&rr.ResolvedBy, &rr.ResolvedAt, &rr.CreatedAt,
+		&reporter.ID, &reporter.Username, &reporter.FullName, &reporter.AvatarURL,
+	)
+	rr.Review, rr.Reporter = &review, &reporter
+	return rr, err
+}
+
+func (s *PostgresStore) ListReviewReports(status string, p PageRequest) (Page[models.ReviewReport], error) {
+	o := oldestFirst("rr.created_at")
+	if err := o.check(p); err != nil {
+		return Page[models.ReviewReport]{}, err
+	}
+
+	args := []interface{}{status}
+	rows, err := s.DB.Query(
+		"SELECT "+reviewColumns+", "+reportColumns+reportFrom+`
+		`+where("rr.status = $1", o.after(p, "rr.id", &args))+" "+o.orderBy("rr.id")+" "+limit(p, &args),
+		args...,
+	)
+	if err != nil {
+		return Page[models.ReviewReport]{}, err
+	}
+	defer rows.Close()
+
+	var reports []models.ReviewReport
+	for rows.Next() {
+		rr, err := scanReport(rows)
+		if err != nil {
+			return Page[models.ReviewReport]{}, err
+		}
+		reports = append(reports, rr)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[models.ReviewReport]{}, err
+	}
+
+	page := newPage(reports, p, o, func(rr models.ReviewReport) (interface{}, int) { return rr.CreatedAt, rr.ID })
+	err = fillTotal(s.DB, &page, p, "FROM review_reports rr WHERE rr.status = $1", []interface{}{status}, false)
+	return page, err
+}
+
+func (s *PostgresStore) ResolveReviewReport(reportID, moderatorID int, status string) (*models.ReviewReport, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var reviewID, animalID int
+	var current string
+	err = tx.QueryRow(
+		`SELECT rr.review_id, r.animal_id, rr.status FROM review_reports rr JOIN reviews r ON r.id = rr.review_id
+		WHERE rr.id = $1 FOR UPDATE OF rr`, reportID,
+	).Scan(&reviewID, &animalID, &current)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if current != ReportOpen {
+		return nil, ErrConflict
+	}
+
+	now := time.Now()
+	if status == ReportRemoved {
+		sellerID, err := lockReviewedSeller(tx, animalID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec("UPDATE reviews SET removed_at = $1 WHERE id = $2 AND removed_at IS NULL", now, reviewID); err != nil {
+			return nil, err
+		}
+		if err := rateReviewed(tx, animalID, sellerID); err != nil {
+			return nil, err
+		}
+	}
+	// Removing the review settles every open report on it.
+	_, err = tx.Exec(
+		`UPDATE review_reports SET status = $1, resolved_by = $2, resolved_at = $3
+		WHERE id = $4 OR ($1 = 'removed' AND review_id = $5 AND status = 'open')`,
+		status, moderatorID, now, reportID, reviewID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	rr, err := scanReport(s.DB.QueryRow("SELECT "+reviewColumns+", "+reportColumns+reportFrom+" WHERE rr.id = $1", reportID))
+	if err != nil {
+		return nil, err
+	}
+	return &rr, nil
+}
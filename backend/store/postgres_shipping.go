@@ -0,0 +1,383 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/TerraPaw/backend/models"
+	"github.com/lib/pq"
+)
+
+const addressColumns = `id, user_id, label, recipient_name, phone, street, city, COALESCE(province, ''), postal_code,
+	is_default, created_at, updated_at`
+
+const shipmentColumns = `s.id, s.order_id, s.carrier, s.service, s.live_animals, s.cost, s.min_days, s.max_days, s.origin,
+	s.recipient_name, s.phone, s.address, s.city, s.postal_code, COALESCE(s.tracking_number, ''), s.status,
+	s.shipped_at, s.delivered_at, s.created_at, s.updated_at`
+
+func scanAddress(row rowScanner) (models.Address, error) {
+	var a models.Address
+	err := row.Scan(
+		&a.ID, &a.UserID, &a.Label, &a.RecipientName, &a.Phone, &a.Street, &a.City, &a.Province, &a.PostalCode,
+		&a.IsDefault, &a.CreatedAt, &a.UpdatedAt,
+	)
+	return a, err
+}
+
+func scanShipment(row rowScanner) (models.Shipment, error) {
+	var s models.Shipment
+	var shippedAt, deliveredAt sql.NullTime
+	err := row.Scan(
+		&s.ID, &s.OrderID, &s.Carrier, &s.Service, &s.LiveAnimals, &s.Cost, &s.MinDays, &s.MaxDays, &s.Origin,
+		&s.RecipientName, &s.Phone, &s.Address, &s.City, &s.PostalCode, &s.TrackingNumber, &s.Status,
+		&shippedAt, &deliveredAt, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if shippedAt.Valid {
+		s.ShippedAt = &shippedAt.Time
+	}
+	if deliveredAt.Valid {
+		s.DeliveredAt = &deliveredAt.Time
+	}
+	return s, err
+}
+
+// insertShipment books s for an order within tx.
+func insertShipment(tx *sql.Tx, orderID int, s models.Shipment) (*models.Shipment, error) {
+	s.OrderID, s.Status = orderID, ShipmentPending
+	err := tx.QueryRow(
+		`INSERT INTO shipments (order_id, carrier, service, live_animals, cost, min_days, max_days, origin,
+			recipient_name, phone, address, city, postal_code, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, created_at, updated_at`,
+		s.OrderID, s.Carrier, s.Service, s.LiveAnimals, s.Cost, s.MinDays, s.MaxDays, s.Origin,
+		s.RecipientName, s.Phone, s.Address, s.City, s.PostalCode, s.Status,
+	).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// attachShipments loads the shipment, with its events, of each order that
+// has one.
+func (s *PostgresStore) attachShipments(orders []models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(orders))
+	index := map[int]int{}
+	for i, o := range orders {
+		ids[i] = int64(o.ID)
+		index[o.ID] = i
+	}
+
+	rows, err := s.DB.Query("SELECT "+shipmentColumns+" FROM shipments s WHERE s.order_id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		shipment, err := scanShipment(rows)
+		if err != nil {
+			return err
+		}
+		if shipment.Events, err = s.shipmentEvents(shipment.ID); err != nil {
+			return err
+		}
+		orders[index[shipment.OrderID]].Shipment = &shipment
+	}
+	return rows.Err()
+}
+
+func (s *PostgresStore) shipmentEvents(shipmentID int) ([]models.ShipmentEvent, error) {
+	rows, err := s.DB.Query(
+		`SELECT id, shipment_id, status, description, COALESCE(location, ''), occurred_at
+		FROM shipment_events WHERE shipment_id = $1 ORDER BY occurred_at, id`,
+		shipmentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.ShipmentEvent
+	for rows.Next() {
+		var e models.ShipmentEvent
+		if err := rows.Scan(&e.ID, &e.ShipmentID, &e.Status, &e.Description, &e.Location, &e.OccurredAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (s *PostgresStore) ListAddresses(userID int) ([]models.Address, error) {
+	rows, err := s.DB.Query("SELECT "+addressColumns+" FROM addresses WHERE user_id = $1 ORDER BY is_default DESC, id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []models.Address
+	for rows.Next() {
+		a, err := scanAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+func (s *PostgresStore) GetAddress(userID, id int) (*models.Address, error) {
+	a, err := scanAddress(s.DB.QueryRow("SELECT "+addressColumns+" FROM addresses WHERE id = $1 AND user_id = $2", id, userID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &a, nil
+}
+
+// resetDefaultAddress makes the user's newest address the default if none
+// is.
+func resetDefaultAddress(tx *sql.Tx, userID int) error {
+	_, err := tx.Exec(
+		`UPDATE addresses SET is_default = TRUE
+		WHERE id = (SELECT id FROM addresses WHERE user_id = $1 ORDER BY id DESC LIMIT 1)
+		AND NOT EXISTS (SELECT 1 FROM addresses WHERE user_id = $1 AND is_default)`,
+		userID,
+	)
+	return err
+}
+
+func (s *PostgresStore) CreateAddress(a *models.Address) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the user so concurrent changes to the address book agree on
+	// the default.
+	if _, err := tx.Exec("SELECT 1 FROM users WHERE id = $1 FOR UPDATE", a.UserID); err != nil {
+		return err
+	}
+	if a.IsDefault {
+		if _, err := tx.Exec("UPDATE addresses SET is_default = FALSE WHERE user_id = $1 AND is_default", a.UserID); err != nil {
+			return err
+		}
+	}
+	err = tx.QueryRow(
+		`INSERT INTO addresses (user_id, label, recipient_name, phone, street, city, province, postal_code, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at, updated_at`,
+		a.UserID, a.Label, a.RecipientName, a.Phone, a.Street, a.City, a.Province, a.PostalCode, a.IsDefault,
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return writeErr(err)
+	}
+	if err := resetDefaultAddress(tx, a.UserID); err != nil {
+		return err
+	}
+	if err := tx.QueryRow("SELECT is_default FROM addresses WHERE id = $1", a.ID).Scan(&a.IsDefault); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) UpdateAddress(a *models.Address) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT 1 FROM users WHERE id = $1 FOR UPDATE", a.UserID); err != nil {
+		return err
+	}
+	if a.IsDefault {
+		_, err := tx.Exec("UPDATE addresses SET is_default = FALSE WHERE user_id = $1 AND is_default AND id <> $2", a.UserID, a.ID)
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.QueryRow(
+		`UPDATE addresses SET label = $1, recipient_name = $2, phone = $3, street = $4, city = $5, province = $6,
+			postal_code = $7, is_default = is_default OR $8, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $9 AND user_id = $10
+		RETURNING is_default, created_at, updated_at`,
+		a.Label, a.RecipientName, a.Phone, a.Street, a.City, a.Province, a.PostalCode, a.IsDefault, a.ID, a.UserID,
+	).Scan(&a.IsDefault, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return notFound(err)
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) DeleteAddress(userID, id int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT 1 FROM users WHERE id = $1 FOR UPDATE", userID); err != nil {
+		return err
+	}
+	res, err := tx.Exec("DELETE FROM addresses WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if err := resetDefaultAddress(tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) GetShipment(orderID, userID int) (*models.Shipment, error) {
+	var buyerID int
+	var sellerID sql.NullInt64
+	if err := s.DB.QueryRow("SELECT buyer_id, seller_id FROM orders WHERE id = $1", orderID).Scan(&buyerID, &sellerID); err != nil {
+		return nil, notFound(err)
+	}
+	var seller *int
+	if sellerID.Valid {
+		id := int(sellerID.Int64)
+		seller = &id
+	}
+	if userID == 0 || orderRole(userID, buyerID, seller) == "" {
+		return nil, ErrNotFound
+	}
+
+	shipment, err := scanShipment(s.DB.QueryRow("SELECT "+shipmentColumns+" FROM shipments s WHERE s.order_id = $1", orderID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if shipment.Events, err = s.shipmentEvents(shipment.ID); err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+func (s *PostgresStore) ShipOrder(orderID, sellerID int, trackingNumber string) (*models.Order, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var shipmentID int
+	var origin string
+	err = tx.QueryRow(
+		`SELECT s.id, s.origin FROM shipments s JOIN orders o ON o.id = s.order_id
+		WHERE s.order_id = $1 AND o.seller_id = $2`,
+		orderID, sellerID,
+	).Scan(&shipmentID, &origin)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := updateOrderStatus(tx, orderID, sellerID, OrderShipped, "Tracking number "+trackingNumber); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(
+		`UPDATE shipments SET tracking_number = $1, status = $2, shipped_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`,
+		trackingNumber, ShipmentLabelled, shipmentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO shipment_events (shipment_id, status, description, location, occurred_at)
+		VALUES ($1, $2, 'Shipping label created', $3, CURRENT_TIMESTAMP)`,
+		shipmentID, ShipmentLabelled, origin,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.orderDetails(orderID)
+}
+
+func (s *PostgresStore) ListActiveShipments(limit int) ([]models.Shipment, error) {
+	rows, err := s.DB.Query(
+		"SELECT "+shipmentColumns+" FROM shipments s WHERE s.tracking_number IS NOT NULL AND s.status <> $1 ORDER BY s.id LIMIT $2",
+		ShipmentDelivered, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shipments []models.Shipment
+	for rows.Next() {
+		shipment, err := scanShipment(rows)
+		if err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, shipment)
+	}
+	return shipments, rows.Err()
+}
+
+func (s *PostgresStore) AddShipmentEvents(shipmentID int, events []models.ShipmentEvent) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var orderID int
+	var carrier string
+	err = tx.QueryRow("SELECT order_id, carrier FROM shipments WHERE id = $1 FOR UPDATE", shipmentID).Scan(&orderID, &carrier)
+	if err != nil {
+		return notFound(err)
+	}
+	added := false
+	for _, e := range events {
+		res, err := tx.Exec(
+			`INSERT INTO shipment_events (shipment_id, status, description, location, occurred_at)
+			VALUES ($1, $2, $3, $4, $5) ON CONFLICT (shipment_id, status) DO NOTHING`,
+			shipmentID, e.Status, e.Description, e.Location, e.OccurredAt,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			added = true
+		}
+	}
+	if !added {
+		return tx.Commit()
+	}
+
+	var latest models.ShipmentEvent
+	err = tx.QueryRow(
+		"SELECT status, occurred_at FROM shipment_events WHERE shipment_id = $1 ORDER BY occurred_at DESC, id DESC LIMIT 1",
+		shipmentID,
+	).Scan(&latest.Status, &latest.OccurredAt)
+	if err != nil {
+		return err
+	}
+	var deliveredAt interface{}
+	if latest.Status == ShipmentDelivered {
+		deliveredAt = latest.OccurredAt
+	}
+	_, err = tx.Exec(
+		"UPDATE shipments SET status = $1, delivered_at = COALESCE($2, delivered_at), updated_at = CURRENT_TIMESTAMP WHERE id = $3",
+		latest.Status, deliveredAt, shipmentID,
+	)
+	if err != nil {
+		return err
+	}
+	if latest.Status == ShipmentDelivered {
+		err := updateOrderStatus(tx, orderID, 0, OrderDelivered, "Delivered by "+carrier)
+		if err != nil && !errors.Is(err, ErrInvalidTransition) {
+			return err
+		}
+	}
+	return tx.Commit()
+}
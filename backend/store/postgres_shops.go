@@ -0,0 +1,178 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+	"github.com/lib/pq"
+)
+
+const shopColumns = `sh.id, sh.seller_id, sh.slug, sh.name, COALESCE(sh.description, ''), COALESCE(sh.logo_url, ''),
+	COALESCE(sh.banner_url, ''), COALESCE(sh.address, ''), COALESCE(sh.city, ''), COALESCE(sh.phone, ''), sh.opening_hours,
+	(SELECT COUNT(*) FROM shop_follows f WHERE f.shop_id = sh.id), sh.created_at, sh.updated_at,
+	u.id, u.username, COALESCE(u.fullname, ''), COALESCE(u.avatar_url, ''), COALESCE(u.bio, ''), u.created_at`
+
+const shopFrom = `
+	FROM shops sh
+	JOIN users u ON u.id = sh.seller_id`
+
+// scanShop reads a row selected with shopColumns. extra receives any
+// columns selected after them.
+func scanShop(row rowScanner, extra ...interface{}) (models.Shop, error) {
+	var s models.Shop
+	var seller models.User
+	var hours []byte
+	err := row.Scan(append([]interface{}{
+		&s.ID, &s.SellerID, &s.Slug, &s.Name, &s.Description, &s.LogoURL,
+		&s.BannerURL, &s.Address, &s.City, &s.Phone, &hours,
+		&s.FollowerCount, &s.CreatedAt, &s.UpdatedAt,
+		&seller.ID, &seller.Username, &seller.FullName, &seller.AvatarURL, &seller.Bio, &seller.CreatedAt,
+	}, extra...)...)
+	if err != nil {
+		return s, err
+	}
+	s.Seller = &seller
+	return s, json.Unmarshal(hours, &s.OpeningHours)
+}
+
+// shopWriteErr tells a second shop for a seller from a taken slug.
+func shopWriteErr(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "shops_seller_id_key" {
+		return ErrHasShop
+	}
+	return writeErr(err)
+}
+
+func (s *PostgresStore) CreateShop(sh *models.Shop) error {
+	hours, err := json.Marshal(sh.OpeningHours)
+	if err != nil {
+		return err
+	}
+	err = s.DB.QueryRow(
+		`INSERT INTO shops (seller_id, slug, name, description, logo_url, banner_url, address, city, phone, opening_hours)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at, updated_at`,
+		sh.SellerID, sh.Slug, sh.Name, sh.Description, sh.LogoURL, sh.BannerURL, sh.Address, sh.City, sh.Phone, hours,
+	).Scan(&sh.ID, &sh.CreatedAt, &sh.UpdatedAt)
+	return shopWriteErr(err)
+}
+
+func (s *PostgresStore) UpdateShop(sh *models.Shop) error {
+	hours, err := json.Marshal(sh.OpeningHours)
+	if err != nil {
+		return err
+	}
+	err = s.DB.QueryRow(
+		`UPDATE shops SET slug = $1, name = $2, description = $3, logo_url = $4, banner_url = $5, address = $6,
+			city = $7, phone = $8, opening_hours = $9, updated_at = CURRENT_TIMESTAMP
+		WHERE seller_id = $10
+		RETURNING id, created_at, updated_at, (SELECT COUNT(*) FROM shop_follows f WHERE f.shop_id = shops.id)`,
+		sh.Slug, sh.Name, sh.Description, sh.LogoURL, sh.BannerURL, sh.Address, sh.City, sh.Phone, hours, sh.SellerID,
+	).Scan(&sh.ID, &sh.CreatedAt, &sh.UpdatedAt, &sh.FollowerCount)
+	return notFound(writeErr(err))
+}
+
+func (s *PostgresStore) GetSellerShop(sellerID int) (*models.Shop, error) {
+	sh, err := scanShop(s.DB.QueryRow("SELECT "+shopColumns+shopFrom+" WHERE sh.seller_id = $1", sellerID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &sh, nil
+}
+
+func (s *PostgresStore) GetShop(slug string) (*models.Shop, error) {
+	sh, err := scanShop(s.DB.QueryRow("SELECT "+shopColumns+shopFrom+" WHERE sh.slug = $1", slug))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &sh, nil
+}
+
+func (s *PostgresStore) ShopStats(sellerID int) (*models.ShopStats, error) {
+	st := &models.ShopStats{}
+	err := s.DB.QueryRow(
+		`SELECT
+			(SELECT COUNT(*) FROM animals WHERE seller_id = $1 AND status = 'available'),
+			(SELECT COALESCE(SUM(oi.quantity), 0) FROM order_items oi JOIN orders o ON o.id = oi.order_id
+			 WHERE oi.seller_id = $1 AND o.status = ANY($2)),
+			COALESCE((SELECT rating FROM seller_ratings WHERE seller_id = $1), 0),
+			COALESCE((SELECT review_count FROM seller_ratings WHERE seller_id = $1), 0)`,
+		sellerID, pq.Array(salesStatuses),
+	).Scan(&st.Listings, &st.Sold, &st.Rating, &st.ReviewCount)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first message each buyer sent in the window opens a chat; the
+	// seller's first message back to them after it answers it.
+	var chats, answered int
+	var waitSeconds float64
+	err = s.DB.QueryRow(
+		`WITH opened AS (
+			SELECT sender_id, MIN(created_at) AS opened_at FROM messages
+			WHERE receiver_id = $1 AND created_at > $2
+			GROUP BY sender_id
+		), answers AS (
+			SELECT o.opened_at, (SELECT MIN(m.created_at) FROM messages m
+			                     WHERE m.sender_id = $1 AND m.receiver_id = o.sender_id AND m.created_at >= o.opened_at) AS answered_at
+			FROM opened o
+		)
+		SELECT COUNT(*), COUNT(answered_at), COALESCE(SUM(EXTRACT(EPOCH FROM answered_at - opened_at)), 0)
+		FROM answers`,
+		sellerID, time.Now().Add(-ResponseWindow),
+	).Scan(&chats, &answered, &waitSeconds)
+	if err != nil {
+		return nil, err
+	}
+	shopResponse(st, chats, answered, time.Duration(waitSeconds*float64(time.Second)))
+	return st, nil
+}
+
+func (s *PostgresStore) FollowShop(shopID, userID int) error {
+	_, err := s.DB.Exec("INSERT INTO shop_follows (shop_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", shopID, userID)
+	return writeErr(err)
+}
+
+func (s *PostgresStore) UnfollowShop(shopID, userID int) error {
+	_, err := s.DB.Exec("DELETE FROM shop_follows WHERE shop_id = $1 AND user_id = $2", shopID, userID)
+	return err
+}
+
+func (s *PostgresStore) ListFollowedShops(userID int, p PageRequest) (Page[models.Shop], error) {
+	o := newestFirst("fw.created_at")
+	if err := o.check(p); err != nil {
+		return Page[models.Shop]{}, err
+	}
+
+	args := []interface{}{userID}
+	rows, err := s.DB.Query(
+		"SELECT "+shopColumns+", fw.created_at"+shopFrom+`
+		JOIN shop_follows fw ON fw.shop_id = sh.id
+		`+where("fw.user_id = $1", o.after(p, "sh.id", &args))+" "+o.orderBy("sh.id")+" "+limit(p, &args),
+		args...,
+	)
+	if err != nil {
+		return Page[models.Shop]{}, err
+	}
+	defer rows.Close()
+
+	var shops []models.Shop
+	for rows.Next() {
+		var followedAt time.Time
+		sh, err := scanShop(rows, &followedAt)
+		if err != nil {
+			return Page[models.Shop]{}, err
+		}
+		sh.FollowedAt = &followedAt
+		shops = append(shops, sh)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[models.Shop]{}, err
+	}
+
+	page := newPage(shops, p, o, func(sh models.Shop) (interface{}, int) { return *sh.FollowedAt, sh.ID })
+	err = fillTotal(s.DB, &page, p, "FROM shop_follows fw WHERE fw.user_id = $1", []interface{}{userID}, false)
+	return page, err
+}
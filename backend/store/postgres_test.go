@@ -0,0 +1,251 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TerraPaw/backend/db"
+	"github.com/TerraPaw/backend/models"
+)
+
+// testDatabaseEnv names a scratch Postgres database the store tests may
+// migrate and write to, as a lib/pq connection string. They are skipped
+// without it.
+const testDatabaseEnv = "TERRAPAW_TEST_DATABASE_URL"
+
+var (
+	testDBOnce sync.Once
+	testDB     *sql.DB
+	testDBErr  error
+)
+
+// testPostgres returns a store on the database named by testDatabaseEnv,
+// migrated once per run. The database is shared between tests and runs, so
+// tests create the users and listings they need under fresh names.
+func testPostgres(t *testing.T) *PostgresStore {
+	t.Helper()
+	dsn := os.Getenv(testDatabaseEnv)
+	if dsn == "" {
+		t.Skip(testDatabaseEnv + " is not set")
+	}
+	testDBOnce.Do(func() {
+		testDB, testDBErr = sql.Open("postgres", dsn)
+		if testDBErr == nil {
+			testDBErr = testDB.Ping()
+		}
+		if testDBErr == nil {
+			db.Migrate(testDB)
+		}
+	})
+	if testDBErr != nil {
+		t.Fatalf("connect to %s: %v", testDatabaseEnv, testDBErr)
+	}
+	return NewPostgres(testDB)
+}
+
+// unique suffixes name so rows from earlier runs don't collide with it.
+func unique(name string) string {
+	return fmt.Sprintf("%s_%d", name, time.Now().UnixNano())
+}
+
+// testUser creates a user whose name starts with name.
+func testUser(t *testing.T, s *PostgresStore, name string) int {
+	t.Helper()
+	name = unique(name)
+	id, err := s.CreateUser(&models.User{Username: name, Email: name + "@example.com", Password: "x", FullName: name})
+	if err != nil {
+		t.Fatalf("create user %s: %v", name, err)
+	}
+	return id
+}
+
+// Orders racing for the last units of a listing sell exactly what is in
+// stock: the listing row is locked FOR UPDATE, takeStock only decrements
+// what is there and animals_stock_check backs both up.
+func TestPostgresConcurrentOrders(t *testing.T) {
+	s := testPostgres(t)
+	sellerID := testUser(t, s, "seller")
+	const stock, buyers = 5, 24
+	animalID, err := s.CreateAnimal(&models.Animal{SellerID: sellerID, AnimalType: "Kucing", Name: "Mochi", Price: 100, Stock: stock})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Half the buyers order directly, half check out a cart holding the
+	// same listing.
+	buyerIDs := make([]int, buyers)
+	for i := range buyerIDs {
+		buyerIDs[i] = testUser(t, s, fmt.Sprintf("buyer%d", i))
+		if i%2 == 1 {
+			if err := s.AddCartItem(buyerIDs[i], animalID, 1); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	errs := make([]error, buyers)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i, buyerID := range buyerIDs {
+		wg.Add(1)
+		go func(i, buyerID int) {
+			defer wg.Done()
+			<-start
+			if i%2 == 0 {
+				_, errs[i] = s.CreateOrder(buyerID, animalID, 1)
+			} else {
+				_, errs[i] = s.Checkout(buyerID, CheckoutOptions{})
+			}
+		}(i, buyerID)
+	}
+	close(start)
+	wg.Wait()
+
+	sold := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			sold++
+		case errors.Is(err, ErrInsufficientStock), errors.Is(err, ErrUnavailable), errors.Is(err, ErrCartChanged):
+		default:
+			t.Errorf("buyer %d: %v", i, err)
+		}
+	}
+	if sold != stock {
+		t.Errorf("%d orders placed for %d in stock", sold, stock)
+	}
+	a, err := s.GetAnimal(animalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Stock != 0 || a.Status != "sold" {
+		t.Errorf("listing after the rush: stock %d, status %s", a.Stock, a.Status)
+	}
+}
+
+// Checkout reads each line's category under lock, so a category voucher
+// the cart quote accepts is redeemed at checkout too, here on a listing in
+// a subcategory of the voucher's.
+func TestPostgresCheckoutCategoryVoucher(t *testing.T) {
+	s := testPostgres(t)
+	sellerID := testUser(t, s, "seller")
+	buyerID := testUser(t, s, "buyer")
+	cats := &models.Category{Name: unique("Kucing"), Type: "animal", Active: true}
+	if err := s.CreateCategory(cats); err != nil {
+		t.Fatal(err)
+	}
+	persians := &models.Category{Name: unique("Kucing Persia"), ParentID: &cats.ID, Type: "animal", Active: true}
+	if err := s.CreateCategory(persians); err != nil {
+		t.Fatal(err)
+	}
+	animalID, err := s.CreateAnimal(&models.Animal{SellerID: sellerID, AnimalType: "Kucing Persia", CategoryID: &persians.ID, Name: "Mochi", Price: 100000, Stock: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &models.Voucher{Code: unique("CAT"), DiscountType: models.VoucherFixed, Value: 10000, PerUserLimit: 1, CategoryID: &cats.ID}
+	if err := s.CreateVoucher(v); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddCartItem(buyerID, animalID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	quote, err := s.QuoteVoucher(buyerID, v.Code)
+	if err != nil || quote.Discount != 10000 {
+		t.Fatalf("quote = %+v, %v", quote, err)
+	}
+	order, err := s.Checkout(buyerID, CheckoutOptions{VoucherCode: v.Code})
+	if err != nil {
+		t.Fatalf("checkout = %v", err)
+	}
+	if order.Discount != 10000 || order.TotalPrice != 90000 {
+		t.Errorf("order discount %v, total %v, want 10000 off 100000", order.Discount, order.TotalPrice)
+	}
+}
+
+// A cart holding a listing and a product variant checks out as one order;
+// the variant's stock is taken under lock and comes back on cancelling.
+func TestPostgresCheckoutVariant(t *testing.T) {
+	s := testPostgres(t)
+	sellerID := testUser(t, s, "seller")
+	buyerID := testUser(t, s, "buyer")
+	food := &models.Category{Name: unique("Makanan Kucing"), Type: "food", Active: true}
+	if err := s.CreateCategory(food); err != nil {
+		t.Fatal(err)
+	}
+	p := &models.Product{SellerID: sellerID, CategoryID: food.ID, SKU: unique("WHK"), Name: "Whiskas Tuna",
+		Variants: []models.ProductVariant{{SKU: "WHK-1KG", Size: "1 kg", Price: 55000, WeightGrams: 1000, Stock: 3}}}
+	if err := s.CreateProduct(p); err != nil {
+		t.Fatal(err)
+	}
+	variantID := p.Variants[0].ID
+	animalID, err := s.CreateAnimal(&models.Animal{SellerID: sellerID, AnimalType: "Kucing", Name: "Mochi", Price: 100000, Stock: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddCartItem(buyerID, animalID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddCartVariant(buyerID, variantID, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddCartVariant(buyerID, variantID, 2); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("adding past stock = %v", err)
+	}
+
+	order, err := s.Checkout(buyerID, CheckoutOptions{})
+	if err != nil {
+		t.Fatalf("checkout = %v", err)
+	}
+	if order.TotalPrice != 210000 || len(order.Items) != 2 || order.Items[1].Variant == nil || order.Items[1].VariantID != variantID {
+		t.Fatalf("order = %+v", order)
+	}
+	stock := func() int {
+		t.Helper()
+		got, err := s.GetProduct(p.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.Variants[0].Stock
+	}
+	if got := stock(); got != 1 {
+		t.Errorf("stock after checkout = %d, want 1", got)
+	}
+	if _, err := s.UpdateOrderStatus(order.ID, buyerID, OrderCancelled, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := stock(); got != 3 {
+		t.Errorf("stock after cancelling = %d, want 3", got)
+	}
+}
+
+// A misspelled search still finds the listing through the trigram
+// operator, which only matches once withSearch has set its threshold.
+func TestPostgresMisspelledSearch(t *testing.T) {
+	s := testPostgres(t)
+	sellerID := testUser(t, s, "seller")
+	_, err := s.CreateAnimal(&models.Animal{SellerID: sellerID, AnimalType: "Kucing", Breed: "Zorbulatan", Name: "Mochi", Price: 100000, Stock: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := s.ListAnimals(AnimalFilter{Search: "zorbulatin", SellerID: sellerID, Sort: "relevance"}, PageRequest{Limit: 10, WithTotal: true})
+	if err != nil {
+		t.Fatalf("search = %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Relevance <= 0 {
+		t.Errorf("misspelled search found %+v", page.Items)
+	}
+	suggestions, err := s.SuggestAnimals("zorbulatin", 5)
+	if err != nil {
+		t.Fatalf("suggest = %v", err)
+	}
+	if len(suggestions) == 0 || suggestions[0].Text != "Zorbulatan" {
+		t.Errorf("suggestions = %+v", suggestions)
+	}
+}
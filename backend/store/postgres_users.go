@@ -0,0 +1,86 @@
+package store
+
+import (
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+func (s *PostgresStore) CreateUser(u *models.User) (int, error) {
+	var userID int
+	err := s.DB.QueryRow(
+		"INSERT INTO users (username, email, password, fullname) VALUES ($1, $2, $3, $4) RETURNING id",
+		u.Username, u.Email, u.Password, u.FullName,
+	).Scan(&userID)
+	if err != nil {
+		return 0, writeErr(err)
+	}
+	return userID, nil
+}
+
+func (s *PostgresStore) GetUser(id int) (*models.User, error) {
+	var user models.User
+	err := s.DB.QueryRow(
+		`SELECT id, username, email, fullname, COALESCE(avatar_url, ''), COALESCE(bio, ''), user_type,
+		        COALESCE(location, ''), latitude, longitude
+		FROM users WHERE id = $1`,
+		id,
+	).Scan(&user.ID, &user.Username, &user.Email, &user.FullName, &user.AvatarURL, &user.Bio, &user.UserType,
+		&user.Location, &user.Latitude, &user.Longitude)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (s *PostgresStore) AuthenticateUser(email, passwordHash string) (*models.User, error) {
+	// Case-insensitive email check for robustness
+	var user models.User
+	err := s.DB.QueryRow(
+		"SELECT id, username, email, fullname, COALESCE(avatar_url, ''), COALESCE(bio, ''), user_type FROM users WHERE LOWER(email) = LOWER($1) AND password = $2",
+		email, passwordHash,
+	).Scan(&user.ID, &user.Username, &user.Email, &user.FullName, &user.AvatarURL, &user.Bio, &user.UserType)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (s *PostgresStore) SetResetToken(email, token string, ttl time.Duration) error {
+	res, err := s.DB.Exec(
+		"UPDATE users SET reset_token = $1, reset_token_expiry = NOW() + $2 * INTERVAL '1 second' WHERE email = $3",
+		token, int(ttl.Seconds()), email,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) ResetPassword(email, token, passwordHash string) error {
+	// Verify token and expiry
+	var id int
+	err := s.DB.QueryRow(
+		"SELECT id FROM users WHERE email = $1 AND reset_token = $2 AND reset_token_expiry > NOW()",
+		email, token,
+	).Scan(&id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	// Update password and clear token
+	_, err = s.DB.Exec(
+		"UPDATE users SET password = $1, reset_token = NULL, reset_token_expiry = NULL WHERE id = $2",
+		passwordHash, id,
+	)
+	return err
+}
+
+func (s *PostgresStore) IsModerator(userID int) (bool, error) {
+	var moderator bool
+	err := s.DB.QueryRow("SELECT COALESCE((SELECT is_moderator FROM users WHERE id = $1), FALSE)", userID).Scan(&moderator)
+	return moderator, err
+}
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+const voucherColumns = `v.id, v.code, COALESCE(v.description, ''), v.discount_type, v.value, v.max_discount, v.min_spend,
+	v.usage_limit, v.per_user_limit, v.used_count, v.seller_id, v.category_id, v.public, v.starts_at, v.ends_at,
+	v.created_at`
+
+// usableVoucher selects vouchers v that can be redeemed now by someone who
+// has not used them yet.
+const usableVoucher = `v.starts_at <= CURRENT_TIMESTAMP AND (v.ends_at IS NULL OR v.ends_at > CURRENT_TIMESTAMP)
+	AND (v.usage_limit IS NULL OR v.used_count < v.usage_limit)`
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scanVoucher reads a row selected with voucherColumns. extra receives any
+// columns selected after them.
+func scanVoucher(row rowScanner, extra ...interface{}) (models.Voucher, error) {
+	var v models.Voucher
+	var maxDiscount sql.NullFloat64
+	var usageLimit, sellerID, categoryID sql.NullInt64
+	var endsAt sql.NullTime
+	err := row.Scan(append([]interface{}{
+		&v.ID, &v.Code, &v.Description, &v.DiscountType, &v.Value, &maxDiscount, &v.MinSpend,
+		&usageLimit, &v.PerUserLimit, &v.UsedCount, &sellerID, &categoryID, &v.Public, &v.StartsAt, &endsAt,
+		&v.CreatedAt,
+	}, extra...)...)
+	if maxDiscount.Valid {
+		v.MaxDiscount = &maxDiscount.Float64
+	}
+	if usageLimit.Valid {
+		n := int(usageLimit.Int64)
+		v.UsageLimit = &n
+	}
+	if sellerID.Valid {
+		id := int(sellerID.Int64)
+		v.SellerID = &id
+	}
+	if categoryID.Valid {
+		id := int(categoryID.Int64)
+		v.CategoryID = &id
+	}
+	if endsAt.Valid {
+		v.EndsAt = &endsAt.Time
+	}
+	return v, err
+}
+
+// quoteVoucherRow prices the voucher with code against items. With lock set
+// the voucher row stays locked until q, which must then be a transaction,
+// ends.
+func quoteVoucherRow(q queryer, userID int, code string, items []models.CartItem, lock bool) (*models.VoucherQuote, error) {
+	query := "SELECT " + voucherColumns + " FROM vouchers v WHERE v.code = $1"
+	if lock {
+		query += " FOR UPDATE"
+	}
+	v, err := scanVoucher(q.QueryRow(query, voucherCode(code)))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	var used int
+	err = q.QueryRow("SELECT COUNT(*) FROM voucher_redemptions WHERE voucher_id = $1 AND user_id = $2", v.ID, userID).Scan(&used)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := voucherCategories(q, v)
+	if err != nil {
+		return nil, err
+	}
+	return quoteVoucher(v, categories, used, items, time.Now())
+}
+
+// voucherCategories returns the categories a voucher limited to one takes
+// in, or nil when it is not limited.
+func voucherCategories(q queryer, v models.Voucher) (map[int]bool, error) {
+	if v.CategoryID == nil {
+		return nil, nil
+	}
+	rows, err := q.Query(strings.Replace(categorySubtree, "?", "$1", 1), *v.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		categories[id] = true
+	}
+	return categories, rows.Err()
+}
+
+func (s *PostgresStore) CreateVoucher(v *models.Voucher) error {
+	v.Code = voucherCode(v.Code)
+	var startsAt *time.Time
+	if !v.StartsAt.IsZero() {
+		startsAt = &v.StartsAt
+	}
+	err := s.DB.QueryRow(
+		`INSERT INTO vouchers (code, description, discount_type, value, max_discount, min_spend, usage_limit,
+		                       per_user_limit, seller_id, category_id, public, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE($12, CURRENT_TIMESTAMP), $13)
+		RETURNING id, starts_at, created_at`,
+		v.Code, v.Description, v.DiscountType, v.Value, v.MaxDiscount, v.MinSpend, v.UsageLimit,
+		v.PerUserLimit, v.SellerID, v.CategoryID, v.Public, startsAt, v.EndsAt,
+	).Scan(&v.ID, &v.StartsAt, &v.CreatedAt)
+	return writeErr(err)
+}
+
+func (s *PostgresStore) ListVouchers(p PageRequest) (Page[models.Voucher], error) {
+	o := newestFirst("v.created_at")
+	if err := o.check(p); err != nil {
+		return Page[models.Voucher]{}, err
+	}
+
+	var args []interface{}
+	rows, err := s.DB.Query("SELECT "+voucherColumns+" FROM vouchers v "+
+		where("v.public", usableVoucher, o.after(p, "v.id", &args))+" "+o.orderBy("v.id")+" "+limit(p, &args), args...)
+	if err != nil {
+		return Page[models.Voucher]{}, err
+	}
+	defer rows.Close()
+
+	var vouchers []models.Voucher
+	for rows.Next() {
+		v, err := scanVoucher(rows)
+		if err != nil {
+			return Page[models.Voucher]{}, err
+		}
+		vouchers = append(vouchers, v)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[models.Voucher]{}, err
+	}
+
+	page := newPage(vouchers, p, o, func(v models.Voucher) (interface{}, int) { return v.CreatedAt, v.ID })
+	err = fillTotal(s.DB, &page, p, "FROM vouchers v "+where("v.public", usableVoucher), nil, false)
+	return page, err
+}
+
+func (s *PostgresStore) ClaimVoucher(userID int, code string) (*models.UserVoucher, error) {
+	var uv models.UserVoucher
+	var err error
+	uv.Voucher, err = scanVoucher(s.DB.QueryRow("SELECT "+voucherColumns+" FROM vouchers v WHERE v.code = $1", voucherCode(code)))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	err = s.DB.QueryRow("SELECT COUNT(*) FROM voucher_redemptions WHERE voucher_id = $1 AND user_id = $2", uv.ID, userID).Scan(&uv.TimesUsed)
+	if err != nil {
+		return nil, err
+	}
+	if reason := voucherUsable(uv.Voucher, uv.TimesUsed, time.Now()); reason != "" {
+		return nil, &VoucherError{Reason: reason}
+	}
+
+	err = s.DB.QueryRow(
+		"INSERT INTO user_vouchers (user_id, voucher_id) VALUES ($1, $2) RETURNING claimed_at",
+		userID, uv.ID,
+	).Scan(&uv.ClaimedAt)
+	if err != nil {
+		return nil, writeErr(err)
+	}
+	uv.Usable = true
+	return &uv, nil
+}
+
+func (s *PostgresStore) ListUserVouchers(userID int, p PageRequest) (Page[models.UserVoucher], error) {
+	o := newestFirst("uv.claimed_at")
+	if err := o.check(p); err != nil {
+		return Page[models.UserVoucher]{}, err
+	}
+
+	args := []interface{}{userID}
+	rows, err := s.DB.Query(
+		`SELECT `+voucherColumns+`, uv.claimed_at,
+		        (SELECT COUNT(*) FROM voucher_redemptions r WHERE r.voucher_id = v.id AND r.user_id = uv.user_id)
+		FROM user_vouchers uv
+		JOIN vouchers v ON v.id = uv.voucher_id
+		`+where("uv.user_id = $1", o.after(p, "v.id", &args))+" "+o.orderBy("v.id")+" "+limit(p, &args),
+		args...,
+	)
+	if err != nil {
+		return Page[models.UserVoucher]{}, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	var vouchers []models.UserVoucher
+	for rows.Next() {
+		var uv models.UserVoucher
+		uv.Voucher, err = scanVoucher(rows, &uv.ClaimedAt, &uv.TimesUsed)
+		if err != nil {
+			return Page[models.UserVoucher]{}, err
+		}
+		uv.Usable = voucherUsable(uv.Voucher, uv.TimesUsed, now) == ""
+		vouchers = append(vouchers, uv)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[models.UserVoucher]{}, err
+	}
+
+	page := newPage(vouchers, p, o, func(v models.UserVoucher) (interface{}, int) { return v.ClaimedAt, v.ID })
+	err = fillTotal(s.DB, &page, p, "FROM user_vouchers uv WHERE uv.user_id = $1", []interface{}{userID}, false)
+	return page, err
+}
+
+func (s *PostgresStore) CountUsableVouchers(userID int) (int, error) {
+	var count int
+	err := s.DB.QueryRow(
+		`SELECT COUNT(*)
+		FROM user_vouchers uv
+		JOIN vouchers v ON v.id = uv.voucher_id
+		WHERE uv.user_id = $1 AND `+usableVoucher+`
+		  AND (SELECT COUNT(*) FROM voucher_redemptions r WHERE r.voucher_id = v.id AND r.user_id = uv.user_id) < v.per_user_limit`,
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
+func (s *PostgresStore) QuoteVoucher(userID int, code string) (*models.VoucherQuote, error) {
+	cart, err := s.GetCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := quoteVoucherRow(s.DB, userID, code, cart.Items, false)
+	return quote, err
+}
@@ -0,0 +1,15 @@
+package store
+
+import "github.com/TerraPaw/backend/models"
+
+// summarize fills in a product's cheapest price and total stock from its
+// variants.
+func summarize(p *models.Product) {
+	p.MinPrice, p.Stock = 0, 0
+	for i, v := range p.Variants {
+		if i == 0 || v.Price < p.MinPrice {
+			p.MinPrice = v.Price
+		}
+		p.Stock += v.Stock
+	}
+}
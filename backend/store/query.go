@@ -0,0 +1,46 @@
+package store
+
+import (
+	"strconv"
+	"strings"
+)
+
+// query collects WHERE predicates and their arguments. Predicates are
+// written with ? for each value and numbered as $n when added, so caller
+// input only ever reaches the database as a bound parameter.
+type query struct {
+	preds []string
+	args  []interface{}
+}
+
+// add appends a predicate, binding one value per ? in order.
+func (q *query) add(pred string, values ...interface{}) {
+	var b strings.Builder
+	for _, v := range values {
+		i := strings.IndexByte(pred, '?')
+		q.args = append(q.args, v)
+		b.WriteString(pred[:i])
+		b.WriteString("$" + strconv.Itoa(len(q.args)))
+		pred = pred[i+1:]
+	}
+	b.WriteString(pred)
+	q.preds = append(q.preds, b.String())
+}
+
+// where renders the predicates collected so far, plus any extra ones, as a
+// WHERE clause.
+func (q *query) where(extra ...string) string {
+	return where(append(append([]string(nil), q.preds...), extra...)...)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePattern escapes LIKE wildcards in s and wraps it for a substring match.
+func likePattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// likePrefix escapes LIKE wildcards in s and wraps it for a prefix match.
+func likePrefix(s string) string {
+	return likeEscaper.Replace(s) + "%"
+}
@@ -0,0 +1,24 @@
+package store
+
+import (
+	"math"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// maxRecommendationSeeds caps the listings a user ordered or saved that
+// RecommendAnimals works from, latest first.
+const maxRecommendationSeeds = 50
+
+// ReasonPopular marks recommendations that fill up the list from the most
+// popular listings, when what the user did gives too little to go on.
+const ReasonPopular = "popular"
+
+func popularRecommendation(a models.Animal) models.Recommendation {
+	return models.Recommendation{Animal: &a, Reasons: []string{ReasonPopular}}
+}
+
+// roundScore keeps a summed score to four decimals, like the stored ones.
+func roundScore(score float64) float64 {
+	return math.Round(score*1e4) / 1e4
+}
@@ -0,0 +1,56 @@
+package store
+
+import "github.com/TerraPaw/backend/models"
+
+// Review report statuses. Reports are open until a moderator dismisses
+// them or removes the review.
+const (
+	ReportOpen      = "open"
+	ReportDismissed = "dismissed"
+	ReportRemoved   = "removed"
+)
+
+// ReportStatuses lists every review report status.
+var ReportStatuses = []string{ReportOpen, ReportDismissed, ReportRemoved}
+
+// reviewOrder maps ReviewFilter.Sort to its keyset order.
+func reviewOrder(sort string) order {
+	switch sort {
+	case "helpful":
+		return order{name: sort, column: "r.helpful_count", cast: "numeric", desc: true}
+	case "rating_desc":
+		return order{name: sort, column: "r.rating", cast: "numeric", desc: true}
+	case "rating_asc":
+		return order{name: sort, column: "r.rating", cast: "numeric"}
+	}
+	return newestFirst("r.created_at")
+}
+
+func reviewKey(o order) func(models.Review) (interface{}, int) {
+	switch o.name {
+	case "helpful":
+		return func(r models.Review) (interface{}, int) { return float64(r.HelpfulCount), r.ID }
+	case "rating_desc", "rating_asc":
+		return func(r models.Review) (interface{}, int) { return float64(r.Rating), r.ID }
+	}
+	return func(r models.Review) (interface{}, int) { return r.CreatedAt, r.ID }
+}
+
+// newRatingSummary returns an empty summary with every rating from 1 to 5
+// in its distribution.
+func newRatingSummary() *models.RatingSummary {
+	return &models.RatingSummary{Distribution: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+}
+
+// addRatings counts n reviews with the given rating, verified of them
+// verified purchases, into the summary.
+func addRatings(sum *models.RatingSummary, rating, n, verified int) {
+	sum.Distribution[rating] += n
+	sum.Count += n
+	sum.Verified += verified
+	total := 0
+	for r, count := range sum.Distribution {
+		total += r * count
+	}
+	sum.Average = averageRating(total, sum.Count)
+}
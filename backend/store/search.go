@@ -0,0 +1,143 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// searchConfig is the text search configuration behind animals.search_vector.
+// db.createTables copies it from the indonesian configuration when the server
+// has one and from simple otherwise.
+const searchConfig = "terrapaw"
+
+// similarityThreshold is the pg_trgm word similarity above which a listing
+// matches a misspelled search.
+const similarityThreshold = 0.4
+
+// animalSearchText is the listing text typo-tolerant matching runs against.
+const animalSearchText = `(a.name || ' ' || COALESCE(a.breed, ''))`
+
+// searchTerms splits a search string into lower-case words, dropping
+// punctuation so the terms are safe to assemble into a tsquery.
+func searchTerms(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// prefixQuery builds a tsquery matching documents that contain every term
+// as a word prefix, so "persia" finds "Persian".
+func prefixQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
+
+// trigrams returns the pg_trgm trigram set of s: each word is lower-cased
+// and padded with two spaces in front and one behind.
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range searchTerms(s) {
+		r := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			set[string(r[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// wordSimilarity approximates pg_trgm's word_similarity(query, text): the
+// share of the query's trigrams found in the best matching word of text.
+func wordSimilarity(query, text string) float64 {
+	q := trigrams(query)
+	if len(q) == 0 {
+		return 0
+	}
+	best := 0
+	for _, w := range searchTerms(text) {
+		t := trigrams(w)
+		n := 0
+		for g := range q {
+			if t[g] {
+				n++
+			}
+		}
+		best = max(best, n)
+	}
+	return float64(best) / float64(len(q))
+}
+
+// searchRelevance scores an in-memory listing against terms the way the
+// Postgres ranking weighs it: name matches count most, then breed and
+// type, then description, plus the typo-tolerant similarity. A zero score
+// means the listing does not match.
+func searchRelevance(a *models.Animal, terms []string, raw string) float64 {
+	fields := []struct {
+		text   string
+		weight float64
+	}{{a.Name, 1}, {a.Breed, 0.4}, {a.AnimalType, 0.4}, {a.Description, 0.1}}
+
+	rank, matched := 0.0, 0
+	for _, term := range terms {
+		best := 0.0
+		for _, f := range fields {
+			for _, w := range searchTerms(f.text) {
+				if strings.HasPrefix(w, term) {
+					best = max(best, f.weight)
+				}
+			}
+		}
+		if best > 0 {
+			matched++
+		}
+		rank += best / float64(len(terms))
+	}
+
+	sim := wordSimilarity(raw, a.Name+" "+a.Breed)
+	switch {
+	case matched == len(terms):
+		return rank + sim
+	case sim >= similarityThreshold:
+		return sim
+	}
+	return 0
+}
+
+// rankSuggestions orders suggestions the way the Postgres query does:
+// prefix matches first, then by similarity, then alphabetically.
+func rankSuggestions(found []models.SearchSuggestion, q string, limit int) []models.SearchSuggestion {
+	lq := strings.ToLower(q)
+	seen := map[string]bool{}
+	var unique []models.SearchSuggestion
+	for _, s := range found {
+		key := s.Kind + "\x00" + strings.ToLower(s.Text)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, s)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		pi := strings.HasPrefix(strings.ToLower(unique[i].Text), lq)
+		pj := strings.HasPrefix(strings.ToLower(unique[j].Text), lq)
+		if pi != pj {
+			return pi
+		}
+		si, sj := wordSimilarity(q, unique[i].Text), wordSimilarity(q, unique[j].Text)
+		if si != sj {
+			return si > sj
+		}
+		return unique[i].Text < unique[j].Text
+	})
+	if len(unique) > limit {
+		unique = unique[:limit]
+	}
+	if unique == nil {
+		unique = []models.SearchSuggestion{}
+	}
+	return unique
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// Shipment statuses before the carrier's own: booked at checkout, no
+// label yet. Later statuses are the carrier's tracking statuses.
+const (
+	ShipmentPending   = "pending"
+	ShipmentLabelled  = "label_created"
+	ShipmentDelivered = "delivered"
+)
+
+// latestEvent returns the most recent of events.
+func latestEvent(events []models.ShipmentEvent) models.ShipmentEvent {
+	sorted := append([]models.ShipmentEvent(nil), events...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].OccurredAt.Before(sorted[j].OccurredAt) })
+	return sorted[len(sorted)-1]
+}
+
+// bookedShipment returns the shipment chosen for a seller's sub-order, or
+// ErrNoShipping when shipping was chosen for the checkout but not for this
+// seller.
+func bookedShipment(opts CheckoutOptions, sellerID int) (*models.Shipment, error) {
+	if opts.Shipping == nil {
+		return nil, nil
+	}
+	s, ok := opts.Shipping[sellerID]
+	if !ok {
+		return nil, ErrNoShipping
+	}
+	return s, nil
+}
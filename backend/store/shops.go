@@ -0,0 +1,26 @@
+package store
+
+import (
+	"math"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// ResponseWindow is how far back ShopStats looks at chats with the seller.
+const ResponseWindow = 90 * 24 * time.Hour
+
+// shopResponse works out the response figures from the chats buyers
+// started, how many of them the seller answered and the total time to
+// those first answers.
+func shopResponse(st *models.ShopStats, chats, answered int, wait time.Duration) {
+	if chats == 0 {
+		return
+	}
+	rate := math.Round(float64(answered)/float64(chats)*100) / 100
+	st.ResponseRate = &rate
+	if answered > 0 {
+		hours := math.Round(wait.Hours()/float64(answered)*10) / 10
+		st.ResponseHours = &hours
+	}
+}
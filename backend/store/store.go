@@ -0,0 +1,659 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+var (
+	ErrNotFound          = errors.New("record not found")
+	ErrConflict          = errors.New("record already exists")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrNotOwner          = errors.New("not the owner")
+	ErrUnavailable       = errors.New("listing is not available")
+	ErrEmptyCart         = errors.New("cart is empty")
+	ErrCartChanged       = errors.New("cart changed")
+	ErrInvalidTransition = errors.New("order status change not allowed")
+	ErrNoShipping        = errors.New("no delivery chosen for a seller")
+	ErrHasShop           = errors.New("seller already has a shop")
+	ErrNotReviewable     = errors.New("order is not completed")
+	ErrOwnReview         = errors.New("review is the user's own")
+	ErrHasSubcategories  = errors.New("category has subcategories")
+	ErrPaymentMismatch   = errors.New("payment amount does not match")
+)
+
+// CartError stops a checkout when an item in the cart changed since the
+// buyer last saw it. Cart is the revalidated cart with the problems found.
+// New prices are confirmed as it is returned, so after reviewing them the
+// buyer can check out again. It matches ErrCartChanged.
+type CartError struct {
+	Cart *models.Cart
+}
+
+func (e *CartError) Error() string { return ErrCartChanged.Error() }
+
+func (e *CartError) Is(target error) bool { return target == ErrCartChanged }
+
+// Default is the store used by the HTTP handlers. It is set in main to the
+// Postgres implementation and replaced with a MemoryStore in tests.
+var Default Store
+
+type Store interface {
+	UserStore
+	ProfileStore
+	CommunityStore
+	MarketplaceStore
+	ReviewStore
+	ListingStore
+	CartStore
+	OrderStore
+	PaymentStore
+	VoucherStore
+	ShippingStore
+	AnalyticsStore
+	ShopStore
+	AlertStore
+	RecommendationStore
+	LocationStore
+	ProductStore
+	CategoryStore
+	ConsultationStore
+	ChatStore
+	ConfigStore
+	IdempotencyStore
+}
+
+type UserStore interface {
+	CreateUser(u *models.User) (int, error)
+	GetUser(id int) (*models.User, error)
+	AuthenticateUser(email, passwordHash string) (*models.User, error)
+	SetResetToken(email, token string, ttl time.Duration) error
+	ResetPassword(email, token, passwordHash string) error
+	// IsModerator reports whether the user may work the moderation queue.
+	IsModerator(userID int) (bool, error)
+}
+
+type ProfileStore interface {
+	ListUserPets(ownerID int, p PageRequest) (Page[models.UserPet], error)
+	CreateUserPet(p *models.UserPet) (int, error)
+	ListMedicalRecords(ownerID int, p PageRequest) (Page[models.MedicalRecord], error)
+	ListNotifications(userID int, p PageRequest) (Page[models.Notification], error)
+	CountUserPets(ownerID int) (int, error)
+	CountUserOrders(buyerID int) (int, error)
+	// CountUsableVouchers counts the vouchers the user has claimed and
+	// can still redeem.
+	CountUsableVouchers(userID int) (int, error)
+}
+
+type CommunityStore interface {
+	CreatePost(p *models.Post) (int, error)
+	ListPosts(viewerID int, p PageRequest) (Page[models.Post], error)
+	GetPost(id, viewerID int) (*models.Post, error)
+	LikePost(postID, userID int) error
+	UnlikePost(postID, userID int) error
+	BookmarkPost(postID, userID int) error
+	UnbookmarkPost(postID, userID int) error
+	SharePost(postID, userID int) error
+	CreateComment(cm *models.Comment) (int, error)
+	LikeComment(commentID, userID int) error
+	UnlikeComment(commentID, userID int) error
+}
+
+// AnimalFilter holds the marketplace search parameters accepted by
+// GetAnimals. Zero values and nil pointers mean "no filter".
+type AnimalFilter struct {
+	AnimalType string // case-insensitive substring
+	Search     string // full-text over name, breed, type and description, with typo tolerance
+	Breed      string // case-insensitive substring
+	Gender     string // case-insensitive exact match
+	Color      string // case-insensitive exact match
+	Location   string // case-insensitive substring
+	MinPrice   *float64
+	MaxPrice   *float64
+	MinAge     *int
+	MaxAge     *int
+	MinRating  *float64
+	SellerID   int
+	CategoryID int // the category or any under it
+	FollowedBy int // only shops this user follows
+	InStock    bool
+	Near       *models.GeoPoint // only listings within RadiusKm of it
+	RadiusKm   float64
+	Sort       string // one of AnimalSorts; newest when empty
+}
+
+// AnimalSorts lists the accepted AnimalFilter.Sort values. relevance only
+// applies when Search is set, and distance when Near is.
+var AnimalSorts = []string{"newest", "oldest", "price_asc", "price_desc", "rating", "popularity", "relevance", "distance"}
+
+// animalOrder maps AnimalFilter.Sort to its keyset order.
+func animalOrder(sort string) order {
+	switch sort {
+	case "price_asc":
+		return order{name: sort, column: "a.price", cast: "numeric"}
+	case "price_desc":
+		return order{name: sort, column: "a.price", cast: "numeric", desc: true}
+	case "rating":
+		return order{name: sort, column: "a.rating", cast: "numeric", desc: true}
+	case "popularity":
+		return order{name: sort, column: animalPopularity, cast: "numeric", desc: true}
+	case "relevance":
+		// The column depends on the search terms; ListAnimals fills it in.
+		return order{name: sort, cast: "float8", desc: true}
+	case "distance":
+		// Likewise for the point searched near.
+		return order{name: sort, cast: "float8"}
+	case "oldest":
+		return oldestFirst("a.created_at")
+	}
+	return newestFirst("a.created_at")
+}
+
+func animalKey(o order) func(models.Animal) (interface{}, int) {
+	switch o.name {
+	case "price_asc", "price_desc":
+		return func(a models.Animal) (interface{}, int) { return a.Price, a.ID }
+	case "rating":
+		return func(a models.Animal) (interface{}, int) { return a.Rating, a.ID }
+	case "popularity":
+		return func(a models.Animal) (interface{}, int) { return float64(a.Popularity), a.ID }
+	case "relevance":
+		return func(a models.Animal) (interface{}, int) { return a.Relevance, a.ID }
+	case "distance":
+		return func(a models.Animal) (interface{}, int) { return *a.DistanceKm, a.ID }
+	}
+	return func(a models.Animal) (interface{}, int) { return a.CreatedAt, a.ID }
+}
+
+type MarketplaceStore interface {
+	// ListCategories returns the active categories in sort order.
+	ListCategories() ([]models.Category, error)
+	ListAnimals(f AnimalFilter, p PageRequest) (Page[models.Animal], error)
+	// AnimalFacets counts the listings matching f by type, breed, color,
+	// gender, location and price bucket.
+	AnimalFacets(f AnimalFilter) (*models.AnimalFacets, error)
+	// SuggestAnimals returns autocomplete entries for a partial search,
+	// drawn from the names, breeds and types of available listings.
+	SuggestAnimals(q string, limit int) ([]models.SearchSuggestion, error)
+	// GetAnimal returns a listing in any status except deleted.
+	GetAnimal(id int) (*models.Animal, error)
+	CreateAnimal(a *models.Animal) (int, error)
+	// CreateOrder returns ErrUnavailable unless the listing is available.
+	CreateOrder(buyerID, animalID, quantity int) (int, error)
+	// CreateVariantOrder orders a product variant the same way. It returns
+	// ErrUnavailable unless the product is active.
+	CreateVariantOrder(buyerID, variantID, quantity int) (int, error)
+	GetOrder(id, buyerID int) (*models.Order, error)
+	// ListOrders returns single-listing orders and checkout headers, each
+	// with all of its lines in Items.
+	ListOrders(buyerID int, p PageRequest) (Page[models.Order], error)
+	AddToWishlist(userID, animalID int) error
+	RemoveFromWishlist(userID, animalID int) error
+	ListWishlist(userID int, p PageRequest) (Page[models.Wishlist], error)
+}
+
+// ReviewFilter narrows and orders a listing's reviews.
+type ReviewFilter struct {
+	WithPhotos bool   // only reviews with an image
+	Sort       string // one of ReviewSorts; newest when empty
+}
+
+// ReviewSorts lists the accepted ReviewFilter.Sort values.
+var ReviewSorts = []string{"newest", "helpful", "rating_desc", "rating_asc"}
+
+// ReviewStore keeps listing reviews and what users do with them: seller
+// replies, helpful votes, and abuse reports for moderators. Reviews a
+// moderator removed are hidden and no longer count toward ratings.
+type ReviewStore interface {
+	// CreateReview records a verified-purchase review of a line on one of
+	// the buyer's orders: a single-listing order, a sub-order or the
+	// checkout it belongs to. It returns ErrNotFound when the listing is not
+	// on the order, ErrNotReviewable until the line's order is completed and
+	// ErrConflict when the line was already reviewed. The listing's rating
+	// and review count and its seller's rating are updated with it.
+	CreateReview(r *models.Review) (int, error)
+	// ListReviews returns a listing's reviews, each with its reply and
+	// helpful count.
+	ListReviews(animalID int, f ReviewFilter, p PageRequest) (Page[models.Review], error)
+	// ReviewSummary breaks a listing's reviews down by rating.
+	ReviewSummary(animalID int) (*models.RatingSummary, error)
+	// ReplyToReview sets the seller's reply to a review of their listing,
+	// replacing any earlier one. It returns ErrNotOwner when sellerID did
+	// not list the reviewed animal.
+	ReplyToReview(reviewID, sellerID int, body string) (*models.ReviewReply, error)
+	DeleteReviewReply(reviewID, sellerID int) error
+	// VoteReviewHelpful marks a review helpful; voting again counts once.
+	// It returns ErrOwnReview when userID wrote the review.
+	VoteReviewHelpful(reviewID, userID int) error
+	UnvoteReviewHelpful(reviewID, userID int) error
+	// ReportReview files r against a review. It returns ErrConflict when
+	// the reporter already reported it.
+	ReportReview(r *models.ReviewReport) error
+	// ListReviewReports is the moderation queue: the reports in status,
+	// oldest first, each with its review and reporter.
+	ListReviewReports(status string, p PageRequest) (Page[models.ReviewReport], error)
+	// ResolveReviewReport closes an open report as ReportDismissed or
+	// ReportRemoved. Removing takes the review down, closes the other open
+	// reports on it and recomputes the ratings it counted toward. It
+	// returns ErrConflict when the report is already resolved.
+	ResolveReviewReport(reportID, moderatorID int, status string) (*models.ReviewReport, error)
+}
+
+// CartStore keeps each buyer's cart of listings and product variants.
+// Adding or updating an item checks it against the listing or variant:
+// ErrNotFound, ErrUnavailable or ErrInsufficientStock. A variant is only
+// for sale while its product is active.
+type CartStore interface {
+	// GetCart returns the buyer's cart with every item checked against its
+	// listing or variant. A buyer without a cart gets an empty one.
+	GetCart(userID int) (*models.Cart, error)
+	// AddCartItem adds quantity to what is already in the cart for the
+	// listing.
+	AddCartItem(userID, animalID, quantity int) error
+	// UpdateCartItem sets the quantity of a listing already in the cart,
+	// or returns ErrNotFound. Both calls confirm the current price.
+	UpdateCartItem(userID, animalID, quantity int) error
+	RemoveCartItem(userID, animalID int) error
+	// AddCartVariant, UpdateCartVariant and RemoveCartVariant are the same
+	// for a product variant.
+	AddCartVariant(userID, variantID, quantity int) error
+	UpdateCartVariant(userID, variantID, quantity int) error
+	RemoveCartVariant(userID, variantID int) error
+	// Checkout turns the cart into a header order with one sub-order per
+	// seller, takes the stock and empties the cart, all or nothing. It
+	// returns ErrEmptyCart, or a *CartError when an item changed. See
+	// CheckoutOptions for vouchers and delivery.
+	Checkout(buyerID int, opts CheckoutOptions) (*models.Order, error)
+}
+
+// CheckoutOptions are the buyer's choices at checkout.
+type CheckoutOptions struct {
+	// VoucherCode, if set, is redeemed on the header and its discount
+	// shared between the sub-orders it applies to. An unknown code
+	// returns ErrNotFound and one that does not apply a *VoucherError.
+	VoucherCode string
+	// Shipping, if set, books delivery for each seller's sub-order, keyed
+	// by seller id, and adds its cost to the totals. It must cover every
+	// seller in the cart, or Checkout returns ErrNoShipping.
+	Shipping map[int]*models.Shipment
+}
+
+// OrderStore drives the order lifecycle; see orderTransitions for who may
+// make which change.
+type OrderStore interface {
+	// GetOrderDetails returns an order the user bought or sells, with its
+	// lines, sub-orders and status history.
+	GetOrderDetails(id, userID int) (*models.Order, error)
+	// UpdateOrderStatus changes an order's status on behalf of userID, or
+	// of the system when userID is 0. Changing a checkout header changes
+	// all of its sub-orders. It returns ErrNotFound when the user is not a
+	// party to the order, ErrNotOwner when their role may not make the
+	// change and ErrInvalidTransition when the current status rules it out.
+	UpdateOrderStatus(id, userID int, status, note string) (*models.Order, error)
+	// ListSellerOrders returns the single-listing orders and checkout
+	// sub-orders for a seller's listings, optionally in one status.
+	ListSellerOrders(sellerID int, status string, p PageRequest) (Page[models.Order], error)
+}
+
+// PaymentStore records payments made through the gateway. Payments belong
+// to single-listing orders and checkout headers, never to sub-orders.
+type PaymentStore interface {
+	// CreatePayment opens a payment for a pending order the buyer placed,
+	// filling in its ID, Reference, Amount and Status. It returns
+	// ErrNotFound for other users' orders, ErrInvalidTransition unless the
+	// order is a pending single-listing order or checkout header, and
+	// ErrConflict while another payment for it is pending.
+	CreatePayment(p *models.Payment, buyerID int) error
+	// SavePaymentCharge stores the gateway charge of a pending payment, or
+	// marks it failed when p.Status is PaymentFailed.
+	SavePaymentCharge(p *models.Payment) error
+	// ListOrderPayments returns the payments for an order the buyer placed,
+	// newest first.
+	ListOrderPayments(orderID, buyerID int) ([]models.Payment, error)
+	// ApplyPaymentEvent records a gateway event and applies it to the
+	// payment for its charge. A paid event moves the order to paid as the
+	// system; if the order was cancelled in the meantime the whole amount
+	// is queued for refund. A paid event for another amount or currency
+	// than charged marks the payment PaymentMismatch instead, leaves the
+	// order unpaid and returns ErrPaymentMismatch once recorded. It returns
+	// ErrConflict for events already applied and ErrNotFound for unknown
+	// charges.
+	ApplyPaymentEvent(e *models.PaymentEvent) error
+	// ExpireUnpaidOrders cancels, as the system, pending single-listing
+	// orders and checkout headers created before cutoff. It returns how
+	// many it cancelled.
+	ExpireUnpaidOrders(cutoff time.Time) (int, error)
+	// ListPendingRefunds returns refunds not yet sent to the gateway,
+	// oldest first.
+	ListPendingRefunds(limit int) ([]models.PaymentRefund, error)
+	// CompleteRefund marks a refund done and adds it to its payment's
+	// refunded amount.
+	CompleteRefund(id int) error
+}
+
+// VoucherStore keeps discount vouchers and the ones users have claimed.
+// Codes are matched case-insensitively. A voucher's redemption is released
+// when the checkout it was used on is cancelled.
+type VoucherStore interface {
+	// CreateVoucher stores v, filling in its ID and CreatedAt. It returns
+	// ErrConflict when the code is taken and ErrNotFound for an unknown
+	// category.
+	CreateVoucher(v *models.Voucher) error
+	// ListVouchers returns the public vouchers that can be redeemed now,
+	// newest first.
+	ListVouchers(p PageRequest) (Page[models.Voucher], error)
+	// ClaimVoucher adds the voucher with code to the user's wallet. It
+	// returns ErrNotFound for unknown codes, a *VoucherError when the
+	// voucher can no longer be used and ErrConflict if already claimed.
+	ClaimVoucher(userID int, code string) (*models.UserVoucher, error)
+	// ListUserVouchers returns the user's claimed vouchers, latest claim
+	// first.
+	ListUserVouchers(userID int, p PageRequest) (Page[models.UserVoucher], error)
+	// QuoteVoucher works out what the voucher with code takes off the
+	// user's cart, with the same errors as Checkout.
+	QuoteVoucher(userID int, code string) (*models.VoucherQuote, error)
+}
+
+// ShippingStore keeps buyers' address books and the shipments of their
+// orders.
+type ShippingStore interface {
+	// ListAddresses returns the user's addresses, the default first.
+	ListAddresses(userID int) ([]models.Address, error)
+	// GetAddress returns one of the user's addresses, or ErrNotFound.
+	GetAddress(userID, id int) (*models.Address, error)
+	// CreateAddress stores a, filling in its ID and timestamps. The first
+	// address, or one marked IsDefault, becomes the default.
+	CreateAddress(a *models.Address) error
+	// UpdateAddress replaces one of a.UserID's addresses, or returns
+	// ErrNotFound. Clearing IsDefault on the default address is ignored.
+	UpdateAddress(a *models.Address) error
+	// DeleteAddress removes one of the user's addresses; if it was the
+	// default, the most recently added remaining one takes over.
+	DeleteAddress(userID, id int) error
+	// GetShipment returns the shipment of an order the user bought or
+	// sells, with its tracking events, or ErrNotFound.
+	GetShipment(orderID, userID int) (*models.Shipment, error)
+	// ShipOrder records the tracking number of a seller's shipment and
+	// moves its order to shipped, with the same errors as
+	// UpdateOrderStatus. Orders without a shipment return ErrNotFound.
+	ShipOrder(orderID, sellerID int, trackingNumber string) (*models.Order, error)
+	// ListActiveShipments returns shipments handed to the carrier and not
+	// yet delivered, oldest first.
+	ListActiveShipments(limit int) ([]models.Shipment, error)
+	// AddShipmentEvents records tracking scans not seen before and moves
+	// the shipment to the latest one. A delivered scan moves a shipped
+	// order to delivered as the system.
+	AddShipmentEvents(shipmentID int, events []models.ShipmentEvent) error
+}
+
+// AnalyticsStore serves seller analytics from rollups of orders, views,
+// wishlists and reviews. RefreshAnalytics rebuilds the rollups; until it
+// runs, SellerAnalytics shows the figures of the previous refresh.
+type AnalyticsStore interface {
+	// RecordAnimalView counts a view of a listing's details.
+	RecordAnimalView(animalID int) error
+	RefreshAnalytics() error
+	// SellerAnalytics returns the seller's sales for q. Stock alerts are
+	// read from the live listings.
+	SellerAnalytics(sellerID int, q AnalyticsQuery) (*models.SellerAnalytics, error)
+}
+
+// ShopStore keeps sellers' shops and the users following them.
+type ShopStore interface {
+	// CreateShop stores s, filling in its ID and timestamps. It returns
+	// ErrHasShop when the seller already has a shop and ErrConflict when
+	// the slug is taken.
+	CreateShop(s *models.Shop) error
+	// UpdateShop replaces the shop of s.SellerID, or returns ErrNotFound.
+	// A slug taken by another shop returns ErrConflict.
+	UpdateShop(s *models.Shop) error
+	// GetSellerShop returns the seller's own shop, or ErrNotFound.
+	GetSellerShop(sellerID int) (*models.Shop, error)
+	// GetShop returns the shop with slug and its seller, or ErrNotFound.
+	GetShop(slug string) (*models.Shop, error)
+	// ShopStats works out the public figures of a seller's shop.
+	ShopStats(sellerID int) (*models.ShopStats, error)
+	// FollowShop returns ErrNotFound for unknown shops; following twice
+	// or unfollowing a shop not followed changes nothing.
+	FollowShop(shopID, userID int) error
+	UnfollowShop(shopID, userID int) error
+	// ListFollowedShops returns the shops the user follows, latest follow
+	// first. New listings from them are found with AnimalFilter.FollowedBy.
+	ListFollowedShops(userID int, p PageRequest) (Page[models.Shop], error)
+}
+
+// AlertStore keeps wishlist alert preferences and matches wishlists
+// against the price and availability of their listings.
+type AlertStore interface {
+	// GetAlertPreferences returns the user's preferences, or
+	// DefaultAlertPreferences when they never set any.
+	GetAlertPreferences(userID int) (*models.AlertPreferences, error)
+	SetAlertPreferences(userID int, p *models.AlertPreferences) error
+	// MatchWishlistAlerts takes up to limit wishlist entries whose listing's
+	// price or availability changed since the user last heard of it, writes
+	// a wishlist notification for each price drop or return to stock the
+	// user wants, and moves the entries on to the listing as it is now.
+	// Notifications whose dedupe key the user already has are skipped. It
+	// returns the notifications written.
+	MatchWishlistAlerts(limit int) ([]WishlistAlert, error)
+}
+
+// RecommendationStore keeps the listing similarities the recommendation
+// job works out (see package recommend) and suggests listings from them.
+type RecommendationStore interface {
+	// ListRecommendable returns every listing that is not deleted, with
+	// the fields similarity is judged on.
+	ListRecommendable() ([]models.Animal, error)
+	// ListInterests returns, for each user, the listings they ordered or
+	// saved, latest first and at most perUser of them.
+	ListInterests(perUser int) ([][]int, error)
+	// ReplaceSimilarities swaps every stored similarity for sims.
+	ReplaceSimilarities(sims []models.Similarity) error
+	// SimilarAnimals returns up to limit stored matches of a listing that
+	// are still for sale, best first.
+	SimilarAnimals(animalID, limit int) ([]models.Recommendation, error)
+	// RecommendAnimals suggests up to limit listings for sale to a user:
+	// the best matches, summed, of what they ordered or saved, leaving out
+	// those and the user's own listings. The most popular listings fill up
+	// what that leaves short.
+	RecommendAnimals(userID, limit int) ([]models.Recommendation, error)
+}
+
+// LocationStore keeps where listings and users are. Coordinates come from
+// the geocoder (see package geo); the store only records them.
+type LocationStore interface {
+	// ListUnlocatedAnimals returns up to limit listings past afterID, by
+	// id, that are not deleted and have a location but no coordinates.
+	ListUnlocatedAnimals(afterID, limit int) ([]models.Animal, error)
+	// LocateAnimal sets the coordinates of a listing still at location
+	// and without any, and reports whether it did.
+	LocateAnimal(id int, location string, p models.GeoPoint) (bool, error)
+	// SetUserLocation records where a user is.
+	SetUserLocation(userID int, location string, p models.GeoPoint) error
+}
+
+// ProductFilter holds the catalog search parameters accepted by
+// GetProducts. Zero values mean "no filter".
+type ProductFilter struct {
+	Search     string // case-insensitive substring of the name or brand
+	Brand      string // case-insensitive exact match
+	CategoryID int    // the category or any under it
+	SellerID   int
+	InStock    bool   // only products with a variant in stock
+	Sort       string // one of ProductSorts; newest when empty
+}
+
+// ProductSorts lists the accepted ProductFilter.Sort values. Prices are
+// those of each product's cheapest variant.
+var ProductSorts = []string{"newest", "oldest", "price_asc", "price_desc"}
+
+// productMinPrice is the price of a product's cheapest variant.
+const productMinPrice = "COALESCE((SELECT MIN(pv.price) FROM product_variants pv WHERE pv.product_id = p.id), 0)"
+
+// productOrder maps ProductFilter.Sort to its keyset order.
+func productOrder(sort string) order {
+	switch sort {
+	case "price_asc":
+		return order{name: sort, column: productMinPrice, cast: "numeric"}
+	case "price_desc":
+		return order{name: sort, column: productMinPrice, cast: "numeric", desc: true}
+	case "oldest":
+		return oldestFirst("p.created_at")
+	}
+	return newestFirst("p.created_at")
+}
+
+func productKey(o order) func(models.Product) (interface{}, int) {
+	if o.name == "price_asc" || o.name == "price_desc" {
+		return func(p models.Product) (interface{}, int) { return p.MinPrice, p.ID }
+	}
+	return func(p models.Product) (interface{}, int) { return p.CreatedAt, p.ID }
+}
+
+// Product statuses. Only active products are listed; archived ones are
+// still found by id.
+const (
+	ProductActive   = "active"
+	ProductArchived = "archived"
+)
+
+// VariantUpdate holds the changes to a product variant; nil fields are
+// left as they are.
+type VariantUpdate struct {
+	Price *float64
+	Stock *int
+}
+
+// ProductStore keeps the supplies catalog: products, such as food, sold in
+// variants apart from live animal listings.
+type ProductStore interface {
+	// ListProducts returns the active products matching f, each with its
+	// variants.
+	ListProducts(f ProductFilter, p PageRequest) (Page[models.Product], error)
+	// GetProduct returns a product in any status, with its variants.
+	GetProduct(id int) (*models.Product, error)
+	// CreateProduct stores p and its variants and fills in their ids. It
+	// returns ErrNotFound when the category does not exist and ErrConflict
+	// when the seller already has a product with p's SKU.
+	CreateProduct(p *models.Product) error
+	// UpdateVariant applies u to a variant of the seller's product. It
+	// returns ErrNotFound for a missing product or variant and ErrNotOwner
+	// when the product is someone else's.
+	UpdateVariant(productID, variantID, sellerID int, u VariantUpdate) (*models.Product, error)
+}
+
+// CategoryStore lets moderators manage the category tree. Categories are
+// never deleted, as listings, products and vouchers point at them; they
+// are deactivated instead.
+type CategoryStore interface {
+	// AllCategories returns every category, inactive ones included, in
+	// sort order.
+	AllCategories() ([]models.Category, error)
+	// CreateCategory stores c and fills in its id. It returns ErrNotFound
+	// when the parent does not exist and ErrConflict when the name is
+	// taken.
+	CreateCategory(c *models.Category) error
+	// UpdateCategory replaces every field of the category with c.ID. It
+	// returns ErrNotFound when the category or its parent does not exist
+	// and ErrConflict when the name is taken.
+	UpdateCategory(c *models.Category) error
+	// DeactivateCategory hides a category from the marketplace. It returns
+	// ErrNotFound when the category does not exist and ErrHasSubcategories
+	// while active categories are under it.
+	DeactivateCategory(id int) error
+}
+
+// Listing statuses. Orders mark a listing sold when its stock runs out;
+// sellers move it between the others. Deleted listings keep their row for
+// order history but are hidden everywhere.
+const (
+	StatusAvailable = "available"
+	StatusReserved  = "reserved"
+	StatusSold      = "sold"
+	StatusArchived  = "archived"
+	StatusDeleted   = "deleted"
+)
+
+// ListingStatuses are the statuses a seller can filter their listings by.
+var ListingStatuses = []string{StatusAvailable, StatusReserved, StatusSold, StatusArchived}
+
+// AnimalUpdate holds the listing fields a seller changes; nil fields are
+// left as they are. Stock only changes through RestockAnimal and orders.
+type AnimalUpdate struct {
+	AnimalType  *string
+	Breed       *string
+	Name        *string
+	Age         *int
+	Description *string
+	Price       *float64
+	ImageURL    *string
+	Location    *string
+	Color       *string
+	Gender      *string
+	Status      *string // available, reserved or archived
+	Attributes  models.AttributeMap
+	// CategoryID replaces the listing's category when Refiled is set; a
+	// nil CategoryID leaves it without one.
+	Refiled    bool
+	CategoryID *int
+	// Position replaces the listing's coordinates when Relocated is set;
+	// a nil Position clears them.
+	Relocated bool
+	Position  *models.GeoPoint
+}
+
+// ListingStore lets sellers manage their own listings. Every method that
+// takes a sellerID returns ErrNotFound for missing or deleted listings and
+// ErrNotOwner when the listing belongs to someone else.
+type ListingStore interface {
+	// UpdateAnimal applies u and records a price change in the listing's
+	// price history. Making a listing without stock available returns
+	// ErrInsufficientStock.
+	UpdateAnimal(id, sellerID int, u AnimalUpdate) (*models.Animal, error)
+	// DeleteAnimal soft-deletes a listing.
+	DeleteAnimal(id, sellerID int) error
+	// RestockAnimal adds quantity to the stock and puts a sold-out listing
+	// back on sale.
+	RestockAnimal(id, sellerID, quantity int) (*models.Animal, error)
+	// ListSellerAnimals lists a seller's listings, newest first, optionally
+	// restricted to one status.
+	ListSellerAnimals(sellerID int, status string, p PageRequest) (Page[models.Animal], error)
+	CountSellerAnimals(sellerID int) (*models.ListingCounts, error)
+	// AddAnimalMedia appends a photo to a listing's gallery and makes it the
+	// main image when the listing has none.
+	AddAnimalMedia(animalID, sellerID int, m *models.AnimalMedia) (int, error)
+	// ListPriceHistory returns a listing's price changes, newest first.
+	ListPriceHistory(animalID int) ([]models.PriceChange, error)
+}
+
+type ConsultationStore interface {
+	CreateVeterinarian(v *models.Veterinarian) (int, error)
+	ListVeterinarians(p PageRequest) (Page[models.Veterinarian], error)
+	GetVeterinarian(id int) (*models.Veterinarian, error)
+	CreateConsultation(co *models.Consultation) (int, error)
+	ListConsultations(userID int, p PageRequest) (Page[models.Consultation], error)
+	GetConsultation(id int) (*models.Consultation, error)
+	UpdateConsultationStatus(id int, status string) error
+}
+
+type ChatStore interface {
+	CreateMessage(m *models.Message) (int, error)
+	ListMessages(userID, partnerID int, p PageRequest) (Page[models.Message], error)
+}
+
+type ConfigStore interface {
+	GetActiveSplash(now time.Time) (*models.SplashEvent, error)
+	CreateSplashEvent(e *models.SplashEvent) (int, error)
+}
+
+type IdempotencyStore interface {
+	// ClaimIdempotencyKey records k as in flight. If the key is already
+	// held by the user and younger than ttl, the stored record is returned
+	// with ErrConflict; older records, and records still in flight after
+	// lease, are discarded and the key reclaimed.
+	ClaimIdempotencyKey(k *models.IdempotencyKey, ttl, lease time.Duration) (*models.IdempotencyKey, error)
+	SaveIdempotentResponse(userID int, key string, status int, body []byte) error
+	ReleaseIdempotencyKey(userID int, key string) error
+}
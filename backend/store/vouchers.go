@@ -0,0 +1,133 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+var ErrVoucherNotApplicable = errors.New("voucher does not apply")
+
+// VoucherError explains why a voucher cannot be used on a cart. It matches
+// ErrVoucherNotApplicable.
+type VoucherError struct {
+	Reason string
+}
+
+func (e *VoucherError) Error() string { return "voucher " + e.Reason }
+
+func (e *VoucherError) Is(target error) bool { return target == ErrVoucherNotApplicable }
+
+// voucherCode normalises a code as typed by a user. Codes are stored in
+// upper case.
+func voucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// voucherUsable reports why v cannot be redeemed at now by a user who has
+// used it timesUsed times, or "" if it can.
+func voucherUsable(v models.Voucher, timesUsed int, now time.Time) string {
+	switch {
+	case now.Before(v.StartsAt):
+		return "is not active yet"
+	case v.EndsAt != nil && !now.Before(*v.EndsAt):
+		return "has expired"
+	case v.UsageLimit != nil && v.UsedCount >= *v.UsageLimit:
+		return "has been fully redeemed"
+	case timesUsed >= v.PerUserLimit:
+		return "has already been used"
+	}
+	return ""
+}
+
+// voucherApplies reports whether v discounts a cart item. categories holds
+// v's category and every category under it, so a voucher on a parent
+// category covers listings and products in its subcategories.
+func voucherApplies(v models.Voucher, categories map[int]bool, item models.CartItem) bool {
+	sellerID, categoryID := itemSeller(item)
+	if v.SellerID != nil && *v.SellerID != sellerID {
+		return false
+	}
+	return v.CategoryID == nil || (categoryID != nil && categories[*categoryID])
+}
+
+// quoteVoucher works out what v takes off items, or returns a
+// *VoucherError. Unavailable items are left out, as in the cart subtotal.
+func quoteVoucher(v models.Voucher, categories map[int]bool, timesUsed int, items []models.CartItem, now time.Time) (*models.VoucherQuote, error) {
+	if reason := voucherUsable(v, timesUsed, now); reason != "" {
+		return nil, &VoucherError{Reason: reason}
+	}
+	q := &models.VoucherQuote{Voucher: v}
+	for _, item := range items {
+		if item.Problem == models.CartUnavailable {
+			continue
+		}
+		q.Subtotal += item.Subtotal
+		if voucherApplies(v, categories, item) {
+			q.Eligible += item.Subtotal
+		}
+	}
+	switch {
+	case q.Eligible == 0:
+		return nil, &VoucherError{Reason: "does not apply to anything in the cart"}
+	case q.Eligible < v.MinSpend:
+		return nil, &VoucherError{Reason: fmt.Sprintf("needs a minimum spend of %s", strconv.FormatFloat(v.MinSpend, 'f', -1, 64))}
+	}
+
+	q.Discount = v.Value
+	if v.DiscountType == models.VoucherPercentage {
+		q.Discount = math.Round(q.Eligible * v.Value / 100)
+		if v.MaxDiscount != nil && q.Discount > *v.MaxDiscount {
+			q.Discount = *v.MaxDiscount
+		}
+	}
+	q.Discount = math.Min(q.Discount, q.Eligible)
+	q.Total = q.Subtotal - q.Discount
+	return q, nil
+}
+
+// splitDiscount shares discount between sub-orders in proportion to their
+// eligible subtotals, rounding to whole rupiah; the last eligible one
+// takes what rounding leaves over.
+func splitDiscount(discount float64, eligible []float64) []float64 {
+	total, last := 0.0, -1
+	for i, e := range eligible {
+		total += e
+		if e > 0 {
+			last = i
+		}
+	}
+	shares := make([]float64, len(eligible))
+	left := discount
+	for i, e := range eligible {
+		if e == 0 {
+			continue
+		}
+		if i == last {
+			shares[i] = left
+			break
+		}
+		shares[i] = math.Round(discount * e / total)
+		left -= shares[i]
+	}
+	return shares
+}
+
+// checkoutDiscounts returns the discount for each seller group of a
+// checkout, with categories as for voucherApplies.
+func checkoutDiscounts(q *models.VoucherQuote, categories map[int]bool, groups [][]checkoutLine) []float64 {
+	eligible := make([]float64, len(groups))
+	for i, group := range groups {
+		for _, line := range group {
+			if voucherApplies(q.Voucher, categories, line.CartItem) {
+				eligible[i] += line.Subtotal
+			}
+		}
+	}
+	return splitDiscount(q.Discount, eligible)
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/TerraPaw/backend/models"
+)
+
+// cartLine is a checked cart item for a listing.
+func cartLine(sellerID int, categoryID *int, price float64, quantity int) models.CartItem {
+	item := models.CartItem{
+		Quantity: quantity, AddedPrice: price,
+		Animal: &models.Animal{SellerID: sellerID, CategoryID: categoryID, Price: price, Stock: quantity},
+	}
+	checkCartItem(&item, true, quantity, price)
+	return item
+}
+
+func TestSplitDiscount(t *testing.T) {
+	for _, c := range []struct {
+		discount float64
+		eligible []float64
+		want     []float64
+	}{
+		{100000, []float64{1500000, 170000}, []float64{89820, 10180}},
+		{100, []float64{1, 1, 1}, []float64{33, 33, 34}},
+		{50000, []float64{0, 200000, 0}, []float64{0, 50000, 0}},
+		{50000, []float64{300000}, []float64{50000}},
+	} {
+		if got := splitDiscount(c.discount, c.eligible); fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Errorf("splitDiscount(%v, %v) = %v, want %v", c.discount, c.eligible, got, c.want)
+		}
+	}
+}
+
+// A voucher on a parent category applies to lines in its subcategories and
+// to product variants; its discount goes to the groups it applied to.
+func TestCheckoutDiscounts(t *testing.T) {
+	cats, persians, food := 1, 2, 3
+	v := models.Voucher{DiscountType: models.VoucherPercentage, Value: 10, PerUserLimit: 1, CategoryID: &cats}
+	categories := map[int]bool{cats: true, persians: true}
+	persian := cartLine(1, &persians, 2000000, 1)
+	kibble := cartLine(2, &food, 85000, 2)
+	variant := models.CartItem{Quantity: 1, Variant: &models.VariantRef{SellerID: 3, CategoryID: cats, Price: 50000}}
+	checkCartItem(&variant, true, 1, 50000)
+
+	q, err := quoteVoucher(v, categories, 0, []models.CartItem{persian, kibble, variant}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Eligible != 2050000 || q.Discount != 205000 || q.Total != 2015000 {
+		t.Fatalf("quote = %+v", q)
+	}
+	groups := bySeller([]checkoutLine{{kibble, 2}, {variant, 3}, {persian, 1}})
+	if got := checkoutDiscounts(q, categories, groups); fmt.Sprint(got) != "[200000 0 5000]" {
+		t.Errorf("discounts = %v", got)
+	}
+}
+
+func TestQuoteVoucher(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	maxDiscount, once := 100000.0, 1
+	seller := 1
+	items := []models.CartItem{cartLine(1, nil, 1500000, 1), cartLine(2, nil, 85000, 2)}
+	gone := cartLine(2, nil, 999999, 1)
+	checkCartItem(&gone, false, 1, 999999)
+	items = append(items, gone)
+
+	for _, c := range []struct {
+		label     string
+		v         models.Voucher
+		timesUsed int
+		discount  float64
+		reason    string
+	}{
+		{"capped percentage", models.Voucher{DiscountType: models.VoucherPercentage, Value: 10, MaxDiscount: &maxDiscount, PerUserLimit: 1}, 0, 100000, ""},
+		{"seller's own listings", models.Voucher{DiscountType: models.VoucherFixed, Value: 50000, SellerID: &seller, PerUserLimit: 1}, 0, 50000, ""},
+		{"fixed above eligible", models.Voucher{DiscountType: models.VoucherFixed, Value: 5000000, PerUserLimit: 1}, 0, 1670000, ""},
+		{"minimum spend", models.Voucher{DiscountType: models.VoucherFixed, Value: 1000, MinSpend: 2000000, PerUserLimit: 1}, 0, 0, "needs a minimum spend of 2000000"},
+		{"expired", models.Voucher{DiscountType: models.VoucherFixed, Value: 1000, PerUserLimit: 1, EndsAt: &past}, 0, 0, "has expired"},
+		{"used up", models.Voucher{DiscountType: models.VoucherFixed, Value: 1000, PerUserLimit: 1, UsageLimit: &once, UsedCount: 1}, 0, 0, "has been fully redeemed"},
+		{"used by the buyer", models.Voucher{DiscountType: models.VoucherFixed, Value: 1000, PerUserLimit: 1}, 1, 0, "has already been used"},
+	} {
+		q, err := quoteVoucher(c.v, nil, c.timesUsed, items, now)
+		var verr *VoucherError
+		switch {
+		case c.reason != "" && (!errors.As(err, &verr) || verr.Reason != c.reason):
+			t.Errorf("%s: error = %v, want %q", c.label, err, c.reason)
+		case c.reason == "" && err != nil:
+			t.Errorf("%s: %v", c.label, err)
+		case c.reason == "" && q.Discount != c.discount:
+			t.Errorf("%s: discount = %v, want %v", c.label, q.Discount, c.discount)
+		}
+	}
+}
@@ -5,14 +5,26 @@ type Response struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"`
+	Details interface{} `json:"details,omitempty"`
 }
 
+// PaginatedResponse is the envelope for list endpoints. NextCursor is
+// passed back as ?cursor= to fetch the following page and is empty on the
+// last one. Total is only present when the client asks for it, and is
+// approximate when TotalEstimated is set. Page echoes the legacy page=
+// parameter. Handlers that return more than the page embed it in a struct
+// of their own.
 type PaginatedResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data"`
-	Total   int         `json:"total"`
-	Page    int         `json:"page"`
-	Limit   int         `json:"limit"`
+	Success        bool        `json:"success"`
+	Message        string      `json:"message"`
+	Data           interface{} `json:"data"`
+	NextCursor     string      `json:"next_cursor,omitempty"`
+	HasMore        bool        `json:"has_more"`
+	Limit          int         `json:"limit"`
+	Page           int         `json:"page,omitempty"`
+	Total          *int        `json:"total,omitempty"`
+	TotalEstimated bool        `json:"total_estimated,omitempty"`
 }
 
 func SuccessResponse(message string, data interface{}) Response {